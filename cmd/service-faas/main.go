@@ -2,17 +2,37 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"service-faas/internal/adapters/clamav"
 	"service-faas/internal/adapters/docker"
+	"service-faas/internal/adapters/ecs"
+	"service-faas/internal/adapters/filestore"
+	"service-faas/internal/adapters/firecracker"
+	"service-faas/internal/adapters/git"
 	"service-faas/internal/adapters/gorm"
+	"service-faas/internal/adapters/knative"
 	"service-faas/internal/adapters/kubernetes"
+	"service-faas/internal/adapters/logsink"
+	"service-faas/internal/adapters/memory"
+	"service-faas/internal/adapters/nats"
+	"service-faas/internal/adapters/objectstore"
+	"service-faas/internal/adapters/ownerdir"
+	"service-faas/internal/adapters/redis"
+	"service-faas/internal/adapters/registry"
+	"service-faas/internal/adapters/webhook"
 	"service-faas/internal/config"
 	"service-faas/internal/core/functions"
 	api "service-faas/internal/delivery/http"
+	"service-faas/pkg/idtoken"
+	"service-faas/pkg/pki"
+	"service-faas/pkg/secretbox"
 
 	_ "service-faas/docs"
 
@@ -25,66 +45,480 @@ import (
 // @host            localhost:8080
 // @BasePath        /
 func main() {
+	rotateSecrets := flag.Bool("rotate-secrets", false,
+		"re-encrypt all encrypted columns with the primary key and exit")
+	migrateOnly := flag.Bool("migrate-only", false,
+		"apply pending database migrations and exit")
+	configFile := flag.String("config", "",
+		"YAML config file; environment variables override its settings (default $CONFIG_FILE)")
+	flag.Parse()
+
 	log := zerolog.New(os.Stdout).With().Timestamp().
 		Str("svc", "service-faas").Logger()
 
-	cfg := config.MustLoad()
+	vaultClient, err := config.ConnectVault(*configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("vault login")
+	}
+	cfg, err := config.Load(*configFile, vaultClient)
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		for _, problem := range invalid.Problems {
+			log.Error().Msg(problem)
+		}
+		log.Fatal().Int("problems", len(invalid.Problems)).Msg("invalid configuration")
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid configuration")
+	}
+	// Load checked the level.
+	level, _ := zerolog.ParseLevel(cfg.LogLevel)
+	zerolog.SetGlobalLevel(level)
 	log.Info().
 		Str("deployment_env", string(cfg.DeploymentEnv)).
 		Msg("bootstrapping service")
 
-	db, err := gorm.New(cfg.DatabaseDSN, log)
+	keyring, err := secretbox.ParseKeyring(cfg.SecretsEncryptionKeys)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid SECRETS_ENCRYPTION_KEYS")
+	}
+
+	var repo functions.FunctionRepository
+	var creds functions.RegistryCredentialRepository
+	var usage functions.UsageRepository
+	var idempotency functions.IdempotencyRepository
+	var invocations functions.InvocationRepository
+	var captures functions.CaptureRepository
+	var gitDeployments functions.GitDeploymentRepository
+	var promotions functions.EnvironmentPromotionRepository
+	var webhooks functions.WebhookRepository
+	var invokeTokens functions.InvokeTokenRepository
+	var leaderLock functions.LeaderLock
+	var dsn *gorm.DSN
+	if cfg.HAMode {
+		warnHAConfig(cfg, log)
+		if cfg.ReplicaID == "" {
+			cfg.ReplicaID, _ = os.Hostname()
+		}
+	}
+	if cfg.DatabaseDriver == "memory" {
+		if *migrateOnly || *rotateSecrets {
+			log.Fatal().Msg("-migrate-only and -rotate-secrets need a database, not DB_DRIVER=memory")
+		}
+		log.Warn().Msg("using in-memory state, functions are lost on restart")
+		repo = memory.NewFunctionRepository()
+		creds = memory.NewRegistryCredentialRepository()
+		usage = memory.NewUsageRepository()
+		idempotency = memory.NewIdempotencyRepository()
+		invocations = memory.NewInvocationRepository()
+		captures = memory.NewCaptureRepository()
+		gitDeployments = memory.NewGitDeploymentRepository()
+		promotions = memory.NewEnvironmentPromotionRepository()
+		webhooks = memory.NewWebhookRepository()
+		invokeTokens = memory.NewInvokeTokenRepository()
+	} else {
+		dsn = gorm.NewDSN(cfg.DatabaseDSN)
+		db, err := gorm.New(cfg.DatabaseDriver, dsn, keyring, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("gorm connect")
+		}
+
+		if *migrateOnly {
+			return
+		}
+
+		if *rotateSecrets {
+			n, err := gorm.RotateEncryptedColumns(db)
+			if err != nil {
+				log.Fatal().Err(err).Int("rotated", n).Msg("rotate encrypted columns")
+			}
+			log.Info().Int("rotated", n).Msg("encrypted columns re-encrypted with primary key")
+			return
+		}
+		repo = gorm.NewFunctionRepository(db)
+		creds = gorm.NewRegistryCredentialRepository(db)
+		usage = gorm.NewUsageRepository(db)
+		idempotency = gorm.NewIdempotencyRepository(db)
+		invocations = gorm.NewInvocationRepository(db)
+		// Captured payloads and responses are encrypted, so debug mode is
+		// only offered with encryption keys.
+		if keyring.Empty() {
+			log.Warn().Msg("debug capture is disabled: it needs SECRETS_ENCRYPTION_KEYS to encrypt captures")
+		} else {
+			captures = gorm.NewCaptureRepository(db)
+		}
+		gitDeployments = gorm.NewGitDeploymentRepository(db)
+		promotions = gorm.NewEnvironmentPromotionRepository(db)
+		webhooks = gorm.NewWebhookRepository(db)
+		invokeTokens = gorm.NewInvokeTokenRepository(db)
+		leaderLock = gorm.NewLeaderLock(db)
+	}
+
+	keychain, err := registry.NewKeychain(context.Background(), cfg)
 	if err != nil {
-		log.Fatal().Err(err).Msg("gorm connect")
+		log.Fatal().Err(err).Msg("registry auth init")
 	}
 
 	// Define an orchestrator interface
 	var orchestrator functions.Orchestrator
+	// keepPullSecret refreshes the pull secret kubernetes workers pull with.
+	var keepPullSecret func(context.Context, func(error))
 
 	if cfg.DeploymentEnv == config.EnvDocker {
-		dcli, err := docker.New(cfg, log)
+		dcli, err := docker.New(cfg, log, keychain)
 		if err != nil {
 			log.Fatal().Err(err).Msg("docker client init")
 		}
 		orchestrator = dcli
 	} else if cfg.DeploymentEnv == config.EnvKubernetes {
-		kcli, err := kubernetes.New(cfg, log)
+		kcli, err := kubernetes.New(cfg, log, keychain)
 		if err != nil {
 			log.Fatal().Err(err).Msg("kubernetes client init")
 		}
 		orchestrator = kcli
+		keepPullSecret = kcli.KeepPullSecret
+	} else if cfg.DeploymentEnv == config.EnvECS {
+		ecli, err := ecs.New(cfg, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("ecs client init")
+		}
+		orchestrator = ecli
+	} else if cfg.DeploymentEnv == config.EnvKnative {
+		kncli, err := knative.New(cfg, log, keychain)
+		if err != nil {
+			log.Fatal().Err(err).Msg("knative client init")
+		}
+		orchestrator = kncli
+		keepPullSecret = kncli.KeepPullSecret
+	} else if cfg.DeploymentEnv == config.EnvFirecracker {
+		fccli, err := firecracker.New(cfg, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("firecracker client init")
+		}
+		orchestrator = fccli
+	}
+
+	if cfg.ImageBuilds {
+		if _, ok := orchestrator.(functions.ImageBuilder); !ok {
+			log.Fatal().Str("deployment_env", string(cfg.DeploymentEnv)).Msg("IMAGE_BUILDS is not supported by this orchestrator")
+		}
 	}
 
-	mgr := functions.NewManager(db, orchestrator, cfg, log)
+	policy, err := functions.NewCodePolicy(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid code policy")
+	}
+	runtimes, err := functions.NewRuntimeCatalog(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid WORKER_RUNTIMES")
+	}
+	opts := []functions.Option{
+		functions.WithCodePolicy(policy),
+		functions.WithRuntimeCatalog(runtimes),
+		functions.WithUsageRepository(usage),
+		functions.WithIdempotencyRepository(idempotency),
+		functions.WithInvocationRepository(invocations),
+		functions.WithCaptureRepository(captures),
+		functions.WithGitDeploymentRepository(gitDeployments),
+		functions.WithEnvironmentPromotionRepository(promotions),
+		functions.WithWebhooks(webhooks, webhook.NewEventSender(cfg.WebhookTimeout)),
+		functions.WithInvokeTokenRepository(invokeTokens),
+	}
 
-	// ... (rest of the main function remains the same) ...
+	switch cfg.CodeStore {
+	case "local":
+		store, err := filestore.NewCodeStore(cfg.FunctionStorageDir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("code store init")
+		}
+		opts = append(opts, functions.WithCodeStore(store))
+	case "s3":
+		ocli, err := objectstore.New(cfg, cfg.CodeBucket, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("code store init")
+		}
+		opts = append(opts, functions.WithCodeStore(objectstore.NewCodeStore(ocli, cfg.CodeURLTTL)))
+	default:
+		log.Fatal().Str("code_store", cfg.CodeStore).Msg("unknown code store")
+	}
+
+	switch cfg.ResultStore {
+	case "local":
+		store, err := filestore.NewResultStore(cfg.ResultStoreDir, cfg.ResultBaseURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("result store init")
+		}
+		opts = append(opts, functions.WithResultStore(store))
+	case "s3":
+		ocli, err := objectstore.New(cfg, cfg.ResultBucket, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("result store init")
+		}
+		opts = append(opts, functions.WithResultStore(objectstore.NewResultStore(ocli, cfg.ResultURLTTL)))
+	}
 
-	if err := mgr.RestartRunningFunctions(context.Background()); err != nil {
-		log.Error().Err(err).Msg("error during function restart")
+	switch cfg.CacheBackend {
+	case "memory":
+		opts = append(opts, functions.WithResponseCache(memory.NewResponseCache(cfg.CacheMaxEntries)))
+	case "redis":
+		cache, err := redis.NewResponseCache(context.Background(), cfg.RedisURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("response cache init")
+		}
+		defer cache.Close()
+		opts = append(opts, functions.WithResponseCache(cache))
+	case "none":
+	default:
+		log.Fatal().Str("cache_backend", cfg.CacheBackend).Msg("unknown cache backend")
 	}
 
-	handler := api.NewHandler(mgr, log)
-	srv := &http.Server{Addr: cfg.ListenAddr, Handler: handler}
+	if cfg.IdentitySigningKey != "" {
+		seed, err := idtoken.ParseSeed(cfg.IdentitySigningKey)
+		if err != nil {
+			log.Fatal().Err(err).Msg("identity signing key")
+		}
+		issuer := idtoken.NewIssuer(seed, cfg.IdentityIssuer, cfg.IdentityAudience, cfg.IdentityTokenTTL)
+		opts = append(opts, functions.WithIdentityIssuer(issuer))
+	}
+
+	if cfg.WorkerTLSCAFile != "" {
+		ca, err := pki.LoadCA(cfg.WorkerTLSCAFile, cfg.WorkerTLSCAKeyFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("worker tls ca")
+		}
+		opts = append(opts, functions.WithWorkerCA(ca))
+	}
+
+	if cfg.OwnerDirectoryURL != "" {
+		opts = append(opts, functions.WithOwnerDirectory(ownerdir.New(cfg.OwnerDirectoryURL, cfg.OwnerDirectoryToken)))
+	}
+
+	if cfg.UsageWebhookURL != "" {
+		opts = append(opts, functions.WithUsageSink(webhook.NewUsageSink(cfg.UsageWebhookURL, cfg.UsageWebhookToken)))
+	}
+
+	switch cfg.CodeScanner {
+	case "clamav":
+		scanner, err := clamav.New(cfg.ClamAVAddress)
+		if err != nil {
+			log.Fatal().Err(err).Msg("clamav scanner")
+		}
+		opts = append(opts, functions.WithCodeScanner(scanner))
+	case "http":
+		opts = append(opts, functions.WithCodeScanner(webhook.NewCodeScanner(cfg.CodeScannerURL, cfg.CodeScannerToken)))
+	case "none":
+	default:
+		log.Fatal().Str("code_scanner", cfg.CodeScanner).Msg("unknown code scanner")
+	}
+
+	switch cfg.EventBus {
+	case "nats":
+		pub, err := nats.New(cfg.NATSURL, cfg.EventBusSubject)
+		if err != nil {
+			log.Fatal().Err(err).Msg("event bus init")
+		}
+		defer pub.Close()
+		opts = append(opts, functions.WithEventPublisher(pub))
+	case "none":
+	default:
+		log.Fatal().Str("event_bus", cfg.EventBus).Msg("unknown event bus")
+	}
+
+	switch cfg.LogShipping {
+	case "loki":
+		opts = append(opts, functions.WithLogSink(logsink.NewLoki(cfg.LogShippingURL, cfg.LogShippingToken)))
+	case "elasticsearch":
+		opts = append(opts, functions.WithLogSink(logsink.NewElasticsearch(cfg.LogShippingURL, cfg.LogShippingIndex, cfg.LogShippingToken)))
+	case "none":
+	default:
+		log.Fatal().Str("log_shipping", cfg.LogShipping).Msg("unknown log shipping backend")
+	}
+
+	if cfg.HAMode {
+		opts = append(opts, functions.WithLeaderLock(leaderLock, cfg.ReplicaID))
+	}
+
+	if cfg.PinImageDigests {
+		opts = append(opts, functions.WithDigestResolver(registry.NewResolver(keychain)))
+	}
+
+	if cfg.GitDeploys {
+		opts = append(opts, functions.WithGitFetcher(git.NewFetcher(cfg)))
+	}
+
+	opts = append(opts, functions.WithConfigSource(func() (config.Config, error) {
+		return config.Load(*configFile, vaultClient)
+	}))
+
+	mgr := functions.NewManager(repo, creds, orchestrator, cfg, log, opts...)
+
+	// ... (rest of the main function remains the same) ...
 
 	ctx, stop := signal.NotifyContext(
 		context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Live workers are kept, so only the missing ones are restarted. In HA
+	// mode workers outlive replicas and are not stopped on shutdown, and code
+	// another replica is uploading may not be in the database yet.
+	if leader := mgr.CampaignLeadership(ctx); leader {
+		if err := mgr.RestartRunningFunctions(context.Background()); err != nil {
+			log.Error().Err(err).Msg("error during function restart")
+		}
+	}
+	if !cfg.HAMode {
+		if n, err := mgr.PruneOrphanedCode(context.Background()); err != nil {
+			log.Error().Err(err).Msg("error pruning orphaned function code")
+		} else if n > 0 {
+			log.Info().Int("pruned", n).Msg("pruned orphaned function code")
+		}
+	}
+
+	handler := api.NewHandler(mgr, log)
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: handler}
+
+	tlsEnabled, redirect, err := configureTLS(cfg, srv)
+	if err != nil {
+		log.Fatal().Err(err).Msg("tls config")
+	}
+	var redirectSrv *http.Server
+	if tlsEnabled && cfg.TLSRedirectAddr != "" {
+		redirectSrv = &http.Server{Addr: cfg.TLSRedirectAddr, Handler: redirect}
+	}
+	var adminSrv *http.Server
+	if cfg.AdminListenAddr != "" {
+		adminSrv = &http.Server{Addr: cfg.AdminListenAddr, Handler: api.NewAdminHandler(mgr, log)}
+	}
+
+	go mgr.RotateIdentityTokens(ctx)
+	go mgr.RenewWorkerCertificates(ctx)
+	go mgr.FlushUsageEvery(ctx, cfg.UsageFlushInterval)
+	go mgr.PushUsageEvery(ctx, cfg.UsageExportInterval)
+	go mgr.PruneExpiredEvery(ctx, time.Hour)
+	go mgr.ReconcileEvery(ctx, cfg.ReconcileInterval)
+	go mgr.WatchWorkers(ctx)
+	go mgr.DeliverWebhooks(ctx)
+	go mgr.PublishEvents(ctx)
+	go mgr.ShipLogs(ctx)
+	go reloadOnSIGHUP(ctx, mgr, log)
+	if vaultClient != nil {
+		// Dynamic database credentials are read again before their lease
+		// ends; new connections use them from then on.
+		vaultClient.OnRotate(func(paths []string) {
+			next, err := config.Load(*configFile, vaultClient)
+			if err != nil {
+				log.Error().Err(err).Strs("paths", paths).Msg("configuration reload after vault secret rotation failed")
+				return
+			}
+			if dsn != nil {
+				dsn.Set(next.DatabaseDSN)
+			}
+			log.Info().Strs("paths", paths).Msg("vault dynamic secrets rotated")
+		})
+		go vaultClient.KeepAlive(ctx, func(err error) {
+			log.Error().Err(err).Msg("vault renewal failed")
+		})
+	}
+	if keepPullSecret != nil {
+		go keepPullSecret(ctx, func(err error) {
+			log.Error().Err(err).Msg("pull secret refresh failed")
+		})
+	}
+
 	go func() {
-		log.Info().Str("listen", cfg.ListenAddr).Msg("HTTP server starting")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info().Str("listen", cfg.ListenAddr).Bool("tls", tlsEnabled).Msg("HTTP server starting")
+		var err error
+		if tlsEnabled {
+			// Empty paths make the server use TLSConfig's certificates (autocert).
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("http server failed")
 		}
 	}()
 
+	if redirectSrv != nil {
+		go func() {
+			log.Info().Str("listen", cfg.TLSRedirectAddr).Msg("HTTP to HTTPS redirect starting")
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("redirect server failed")
+			}
+		}()
+	}
+
+	if adminSrv != nil {
+		go func() {
+			log.Info().Str("listen", cfg.AdminListenAddr).Msg("admin server starting")
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("admin server failed")
+			}
+		}()
+	}
+
 	<-ctx.Done()
 
 	log.Info().Msg("shutting down server...")
-	_ = srv.Shutdown(context.Background())
+	// The server stops accepting connections while executions in flight
+	// finish; executions arriving on open connections are refused.
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownDrainTimeout)
+	defer cancelDrain()
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		if err := srv.Shutdown(drainCtx); err != nil {
+			log.Warn().Err(err).Msg("http server did not shut down within the drain timeout")
+		}
+	}()
+	if err := mgr.Drain(drainCtx); err != nil {
+		log.Warn().Err(err).Msg("stopping workers with executions in flight")
+	}
+	<-serverDone
+	if redirectSrv != nil {
+		_ = redirectSrv.Shutdown(context.Background())
+	}
+	if adminSrv != nil {
+		// Closed rather than shut down, so a running CPU profile or trace
+		// does not hold up the exit.
+		_ = adminSrv.Close()
+	}
+
+	if err := mgr.FlushUsage(context.Background()); err != nil {
+		log.Error().Err(err).Msg("error flushing usage")
+	}
 
-	if err := mgr.CleanupAllFunctions(context.Background()); err != nil {
-		log.Error().Err(err).Msg("error during function cleanup")
+	if !cfg.HAMode {
+		if err := mgr.CleanupAllFunctions(context.Background()); err != nil {
+			log.Error().Err(err).Msg("error during function cleanup")
+		}
 	}
 
 	log.Info().Msg("shutdown complete")
 }
+
+// reloadOnSIGHUP reloads the manager's configuration whenever the process
+// receives SIGHUP.
+func reloadOnSIGHUP(ctx context.Context, mgr *functions.Manager, log zerolog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if _, err := mgr.ReloadConfig(ctx); err != nil {
+				log.Error().Err(err).Msg("configuration reload failed")
+			}
+		}
+	}
+}
+
+// warnHAConfig warns about settings that work in HA mode but behave per
+// replica. Load refuses the settings HA mode cannot work with.
+func warnHAConfig(cfg config.Config, log zerolog.Logger) {
+	if cfg.CacheBackend == "memory" {
+		log.Warn().Msg("CACHE_BACKEND=memory caches per replica; invalidations do not reach other replicas")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"service-faas/internal/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS sets up srv for HTTPS when certificate files or autocert
+// domains are configured. It reports whether TLS is enabled and returns the
+// handler for the plain HTTP redirect listener.
+func configureTLS(cfg config.Config, srv *http.Server) (bool, http.Handler, error) {
+	if len(cfg.TLSAutocertDomains) > 0 {
+		if cfg.TLSCertFile != "" {
+			return false, nil, fmt.Errorf("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		srv.TLSConfig = m.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		// The ACME handler answers HTTP-01 challenges and redirects the rest.
+		return true, m.HTTPHandler(httpsRedirect(cfg.ListenAddr)), nil
+	}
+
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		return false, nil, nil
+	}
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return false, nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	return true, httpsRedirect(cfg.ListenAddr), nil
+}
+
+// httpsRedirect sends every request to the same host and path over HTTPS on
+// the port of listenAddr.
+func httpsRedirect(listenAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(listenAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
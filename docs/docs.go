@@ -15,122 +15,323 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/functions": {
+        "/.well-known/jwks.json": {
             "get": {
-                "description": "Retrieves a list of all registered functions.",
+                "description": "Returns the JSON Web Key Set that verifies function identity tokens.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "functions"
+                    "identity"
                 ],
-                "summary": "List all functions",
+                "summary": "Identity token keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.jwks"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cache": {
+            "get": {
+                "description": "Cache hits and misses per function since this manager replica started.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Response cache report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.CacheReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/capacity": {
+            "get": {
+                "description": "Reports total versus allocated worker resources, functions per node or host, pending placements and the top resource consumers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Worker capacity report",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.CapacityReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the orchestrator",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/code-integrity": {
+            "get": {
+                "description": "Re-hashes the stored code of every function and lists those that no longer match the SHA-256 recorded at upload.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Code drift report",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/functions.Function"
+                                "$ref": "#/definitions/functions.CodeDrift"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/http.apiError"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Uploads a Python file, creates a new FaaS function container, and returns its details.",
-                "consumes": [
-                    "multipart/form-data"
+            }
+        },
+        "/admin/orchestrator": {
+            "get": {
+                "description": "Lists what the orchestrator holds for workers: Docker containers, or Kubernetes Deployments, Services and HorizontalPodAutoscalers carrying the worker labels. Resources of functions that have no record, are deleted, or are stopped are flagged as orphaned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
                 ],
+                "summary": "Worker resources",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "List only orphaned resources",
+                        "name": "orphaned",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.WorkerResource"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the orchestrator",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/orchestrator/{kind}/{name}": {
+            "delete": {
+                "description": "Removes a worker resource straight from the orchestrator, leaving the database alone. Only orphaned resources are removed unless force=true; removing the worker of a live function takes it down until it is redeployed or reconciled.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "functions"
+                    "admin"
                 ],
-                "summary": "Add a new function",
+                "summary": "Delete a worker resource",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "The Python file containing the function handler",
-                        "name": "python_file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "Resource kind: container, deployment, service or hpa",
+                        "name": "kind",
+                        "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "The name of the function to execute (e.g., 'handle')",
-                        "name": "function_name",
-                        "in": "formData",
+                        "description": "Resource name, or container ID",
+                        "name": "name",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete the resource even though a function record accounts for it",
+                        "name": "force",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/functions.Function"
+                            "$ref": "#/definitions/functions.WorkerResource"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Unknown resource kind",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "No such worker resource",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "412": {
+                        "description": "The resource is not orphaned and force is not set",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the orchestrator",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
                         }
                     }
                 }
             }
         },
-        "/functions/{functionID}": {
-            "delete": {
-                "description": "Stops the function's container and removes its record from the database.",
+        "/admin/reload": {
+            "post": {
+                "description": "Re-reads the environment and config file and applies the settings that can change at runtime (log level, admission and concurrency limits, circuit breaker, warm-up limits, Harbor login) without restarting workers. Other changed settings are listed as needing a restart. In HA mode only the replica serving the request is reloaded. SIGHUP does the same.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "functions"
+                    "admin"
                 ],
-                "summary": "Remove a function",
+                "summary": "Reload configuration",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.ReloadReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid configuration",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "No configuration source",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/runtime": {
+            "get": {
+                "description": "Reports the manager process's goroutines, memory and garbage collection, and the executions in flight, in total and per function, and queued for admission. With ADMIN_LISTEN_ADDR set, the admin listener also serves pprof profiles at /debug/pprof/ and expvar variables at /debug/vars.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Runtime stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.RuntimeStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/export": {
+            "get": {
+                "description": "Streams a zip archive of every function for backup or migration: manifest.json holds each function's settings and status, and functions/{id}/ its code. Deleted functions and functions blocked by the malware scan are left out. Git credentials and env var values are only included with secrets=true; without it only the names of env vars are.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "catalog"
+                ],
+                "summary": "Export the function catalog",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Function ID",
-                        "name": "functionID",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Include the Git tokens and deploy keys of functions deployed from Git, and the values of env vars",
+                        "name": "secrets",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "Catalog archive",
                         "schema": {
-                            "type": "string"
+                            "type": "file"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/http.apiError"
                         }
                     }
                 }
             }
         },
-        "/functions/{functionID}/execute": {
+        "/f/{name}": {
             "post": {
-                "description": "Sends a JSON payload to a function and returns the result.",
+                "description": "Executes the public function with this name. The request body is passed to the handler as its payload, as is, and the handler's result is returned as the response body, without the execute envelope; results too large to be returned inline are streamed. No token is needed, even with API_TOKEN. Functions that are not public are not found.",
                 "consumes": [
                     "application/json"
                 ],
@@ -138,95 +339,6745 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "functions"
+                    "public"
                 ],
-                "summary": "Execute a function",
+                "summary": "Call a public function",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Function ID",
-                        "name": "functionID",
+                        "description": "Function name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
                         "description": "Payload for the function",
-                        "name": "body",
+                        "name": "payload",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "type": "string"
+                            "type": "object"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Executes at most once per key; retries with the same key and payload get the stored result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Time out the worker call after this many seconds; capped at the function's timeout_seconds",
+                        "name": "X-Timeout-Seconds",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sends executions with the same key to the same worker replica, for functions with an affinity",
+                        "name": "X-Affinity-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "{\"result\": \"...\"}",
+                        "description": "The function's result",
                         "schema": {
                             "type": "object"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the result was stored by an earlier request with the same Idempotency-Key"
+                            },
+                            "X-Faas-Cache": {
+                                "type": "string",
+                                "description": "hit when the result was served from the response cache"
+                            },
+                            "X-Faas-Degraded": {
+                                "type": "string",
+                                "description": "Set when the fallback function answered: error, timeout, circuit_open or busy"
+                            },
+                            "X-Faas-Invocation-Id": {
+                                "type": "string",
+                                "description": "Identifies the execution"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Bad Request, or the payload does not match the function's schema (INVALID_PAYLOAD)",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/http.apiError"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "No public function has this name",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "The function is not running",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The payload exceeds MAX_PAYLOAD_BYTES or the function's max_payload_bytes",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The handler raised an exception (HANDLER_ERROR, its type and message in details.exception)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "429": {
+                        "description": "The function is at its concurrency limit, or the manager is overloaded",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "502": {
+                        "description": "The worker could not be reached or failed",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "504": {
+                        "description": "The worker did not answer in time",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "options": {
+                "description": "Answers a browser's CORS preflight for /f/{name} from the function's CORS policy. Origins the policy does not allow get no CORS headers, so the browser does not send the call.",
+                "tags": [
+                    "public"
+                ],
+                "summary": "Preflight a public function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Origin of the calling page",
+                        "name": "Origin",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "Access-Control-Allow-Origin": {
+                                "type": "string",
+                                "description": "The calling origin, when allowed"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No public function has this name",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "functions.Function": {
-            "type": "object",
-            "properties": {
+        },
+        "/functions": {
+            "get": {
+                "description": "Retrieves a list of all registered functions, or of the soft-deleted ones.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "List all functions",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "List soft-deleted functions instead",
+                        "name": "deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only functions with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.Function"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Uploads a Python file or a compressed code bundle, or deploys the code from a Git repository, creates a new FaaS function container, and returns its details.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Add a new function",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "The Python file containing the function handler",
+                        "name": "python_file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "A .tar.zst or .tar.gz/.tgz archive with handler.py at its root, instead of python_file (docker mode only)",
+                        "name": "bundle",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the uploaded file or bundle; the upload is rejected if it does not match",
+                        "name": "code_sha256",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Git repository to deploy the code from instead of an upload (with GIT_DEPLOYS); an https or ssh URL",
+                        "name": "git_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Branch, tag or commit to deploy; defaults to the repository's default branch",
+                        "name": "git_ref",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Directory holding handler.py, relative to the repository root. In docker mode the whole directory is deployed as a bundle",
+                        "name": "git_path",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Access token for a private https repository",
+                        "name": "git_token",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Private SSH deploy key for a private ssh repository",
+                        "name": "git_deploy_key",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The name of the function to execute (e.g., 'handle')",
+                        "name": "function_name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique name identifying the function, as in manifests applied with POST /functions/apply; a DNS label",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Serve the function at /f/{name}, without authentication; needs a name",
+                        "name": "public",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Custom domain whose requests the manager serves with the function, e.g. echo.example.com",
+                        "name": "domain",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON CORS policy for the public routes, e.g. {\\",
+                        "name": "cors",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant owning the function",
+                        "name": "tenant",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User or team responsible for the function",
+                        "name": "owner",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Custom worker image, pulled with the tenant's registry credentials; its registry must be in WORKER_IMAGE_REGISTRIES, by default the Harbor registry",
+                        "name": "worker_image",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Runtime whose worker image the function runs (see GET /runtimes); not together with worker_image",
+                        "name": "runtime",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON hook called before each invocation, e.g. {\\",
+                        "name": "pre_hook",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON hook called with each result, e.g. {\\",
+                        "name": "post_hook",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "What the function does; searchable",
+                        "name": "description",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object of labels, e.g. {\\",
+                        "name": "labels",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object of env vars set in the worker's environment, e.g. {\\",
+                        "name": "env_vars",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum executions in flight at once; further calls queue briefly, then get 429. 0 or unset means unlimited",
+                        "name": "max_concurrency",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Execute payload limit for this function; may only lower MAX_PAYLOAD_BYTES",
+                        "name": "max_payload_bytes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cache results of identical payloads for this many seconds; only for idempotent functions",
+                        "name": "cache_ttl_seconds",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Longest a call to the worker may take, and the most X-Timeout-Seconds may ask for. 0 or unset means no limit",
+                        "name": "timeout_seconds",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Worker containers to run and balance executions across (docker mode only); 0 or unset means one",
+                        "name": "replicas",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON replica affinity, e.g. {\\",
+                        "name": "affinity",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON Kubernetes overrides, e.g. {\\",
+                        "name": "kubernetes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON Schema that execute payloads (parsed as JSON) must match",
+                        "name": "payload_schema",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON warm-up settings, e.g. {\\",
+                        "name": "warmup",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON fallback used when the function fails or times out, e.g. {\\",
+                        "name": "fallback",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "ingress",
+                            "httproute"
+                        ],
+                        "type": "string",
+                        "description": "Route external traffic directly to the function (kubernetes mode only)",
+                        "name": "expose",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Host the route matches",
+                        "name": "expose_host",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path prefix the route matches; defaults to /fn/\u003cfunction id\u003e",
+                        "name": "expose_path",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "202": {
+                        "description": "With IMAGE_BUILDS, the function is in the building status until its image is built and its worker started",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the Git repository or ref cannot be fetched, or the handler code does not define function_name at the top level or, in docker mode only, has syntax errors or a function_name without a single payload argument (INVALID_CODE, problems in details), or it violates the code policy (CODE_POLICY_VIOLATION, findings in details)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Another function has the name (NAME_TAKEN)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The upload exceeds MAX_UPLOAD_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The malware scan found a threat; the function is kept in the blocked status",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes every function carrying all the given labels, several at a time, and reports the outcome per function. With purge=true, matching functions that are already deleted are purged too.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Delete functions by label",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Label requirement key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Remove the functions permanently, including their code",
+                        "name": "purge",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.BulkDeleteResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or malformed label selector",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/apply": {
+            "post": {
+                "description": "Creates or updates the functions a YAML or JSON manifest declares, identified by name, so applying the same manifest again changes nothing. A function that does not exist is created and deployed. An existing one is compared with its spec: when they match it is reported as \"unchanged\"; otherwise its settings are updated, new code passes the checks an upload does, and a running worker is restarted when the change needs it. Settings a spec leaves out take their defaults. Each spec is applied on its own and reported as \"created\", \"updated\", \"unchanged\" or \"failed\". Resources and triggers are not function settings in this service, so specs naming them are rejected.",
+                "consumes": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Apply a function manifest",
+                "parameters": [
+                    {
+                        "description": "Functions to apply",
+                        "name": "manifest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/functions.Manifest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.ApplyResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid manifest, no functions, or a name declared twice",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The manifest exceeds MAX_UPLOAD_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/bulk-delete": {
+            "post": {
+                "description": "Removes the listed functions (at most 1000) like DELETE /functions/{functionID}, several at a time, and reports the outcome per function: \"deleted\", \"purged\", \"not_found\" or \"error\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Delete several functions",
+                "parameters": [
+                    {
+                        "description": "Function IDs, and whether to purge them",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.bulkDeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.BulkDeleteResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "No or too many function IDs",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/by-name/{name}": {
+            "get": {
+                "description": "Returns the function with the name, as GET /functions/{functionID} does, with its ETag for PUT /functions/by-name/{name}.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Get a function by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The function's ETag"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Makes the function with the name in the path match the spec in the body, like one spec of POST /functions/apply: a missing function is created (201), a differing one updated (200), a matching one left alone (200). The response carries the function's ETag, which changes whenever its code or settings do. Send it back in If-Match to update only the function you last saw; If-Match: * requires that the function exists, and If-None-Match: * that it does not. A failed precondition changes nothing and gets 412.",
+                "consumes": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Create or update a function by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the function must have, or * for any existing function",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "* to only create the function",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Function spec; its name may be left out",
+                        "name": "spec",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/functions.FunctionSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated, or unchanged",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The function's ETag"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The function's ETag"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "With IMAGE_BUILDS, new code is being built",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The function's ETag"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid spec, or the name in the body differs from the path",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "412": {
+                        "description": "The function does not match If-Match or If-None-Match (PRECONDITION_FAILED)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The spec exceeds MAX_UPLOAD_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The malware scan found a threat in the new code",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes the function with the name, as DELETE /functions/{functionID} does. A deleted function keeps its name until it is purged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Remove a function by name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Remove the function permanently, including its code",
+                        "name": "purge",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/orphans": {
+            "get": {
+                "description": "Lists functions without an owner and functions whose owner no longer exists in the identity provider.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Report orphaned functions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.OrphanedFunction"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Owner directory not configured",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/search": {
+            "get": {
+                "description": "Finds functions whose name, description or labels contain every word of the query as a word prefix, best matches first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Search functions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search words",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (default 20, at most 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.SearchHit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/validate": {
+            "post": {
+                "description": "Dry run of POST /functions: checks the upload and that handler.py parses and defines function_name, without storing the code or deploying a worker. In docker mode the check runs in the worker image. Elsewhere only a top-level definition of function_name is looked for, reported as checked_by=static: syntax is not checked, so valid=true does not mean handler.py parses. Problems are reported with 200 and valid=false.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Validate a function upload",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "The Python file containing the function handler",
+                        "name": "python_file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "A .tar.zst or .tar.gz/.tgz archive with handler.py at its root, instead of python_file (docker mode only)",
+                        "name": "bundle",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the uploaded file or bundle",
+                        "name": "code_sha256",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The name of the function to execute (e.g., 'handle')",
+                        "name": "function_name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant whose registry credentials pull worker_image",
+                        "name": "tenant",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Custom worker image to check the code in",
+                        "name": "worker_image",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Runtime whose worker image to check the code in",
+                        "name": "runtime",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.ValidationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The upload exceeds MAX_UPLOAD_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}": {
+            "get": {
+                "description": "Returns a function. For a running function, \"worker\" holds the live desired and ready replica counts and container restarts, where the orchestrator reports them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Get a function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "The function's ETag, for If-Match on PUT /functions/by-name/{name}"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stops the function's container and marks it deleted; it can be restored until purged. With purge=true the record and code are removed permanently. The function's environments are removed or purged with it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Remove a function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Remove the function permanently, including its code",
+                        "name": "purge",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/affinity": {
+            "put": {
+                "description": "Sends executions with the same key to the same worker replica (docker mode only). The key is the X-Affinity-Key header or, without it, the payload field named by field. Executions without a key are balanced as usual. A null affinity removes it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's replica affinity",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New affinity settings",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.affinityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/build": {
+            "get": {
+                "description": "Returns the status, image and output of the function's latest image build. Only available with IMAGE_BUILDS.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Get a function's image build",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.FunctionBuild"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown function, or it has not been built",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Image builds are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Starts a new image build from the function's stored code. Its worker keeps running the current image until the build succeeds, is then restarted on the new image, and the old image is deleted. Only available with IMAGE_BUILDS.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Rebuild a function's image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "The function is deleted, blocked or already being built",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown function",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Image builds are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/cache": {
+            "put": {
+                "description": "Opts an idempotent function into response caching: results of identical payloads are served from the cache for ttl_seconds without calling the worker. 0 turns caching off.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's cache TTL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New TTL",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.cacheTTLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Drops every cached result of the function, e.g. after the data it reads has changed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Invalidate a function's cached responses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.invalidateCacheResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Response caching is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/captures": {
+            "get": {
+                "description": "Returns the executions captured in debug mode, newest first, with their redacted payload and response, or error.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "List a function's debug captures",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of captures (default 50, at most 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.Capture"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the executions captured in debug mode. Debug mode stays as it is.",
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Clear a function's debug captures",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/code": {
+            "get": {
+                "description": "Returns the code the function is deployed with: its handler.py, or, for a function deployed from a bundle with more files, a zip archive of all of them (including a generated requirements.lock). Deleted functions can be downloaded until they are purged. The code of functions blocked by the malware scan is not handed out.",
+                "produces": [
+                    "text/x-python",
+                    "application/zip"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Download a function's code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "handler.py or a zip archive",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown function",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The function is blocked by the malware scan",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/concurrency": {
+            "put": {
+                "description": "Caps how many executions of the function run at once. Executions beyond the limit wait up to CONCURRENCY_QUEUE_TIMEOUT for a slot and are then rejected with 429. 0 removes the limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's concurrency limit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New limit",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.concurrencyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/cors": {
+            "put": {
+                "description": "Lets browser apps on the allowed origins call the function through its public routes, /f/{name} and its custom domain, and answers their preflight requests. Allowed methods default to POST and allowed headers to Content-Type. The API itself sends no CORS headers. A null policy removes it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's CORS policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New CORS policy",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.corsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/debug": {
+            "put": {
+                "description": "Captures a sample of the function's executions with their payload and response, for GET /functions/{functionID}/captures. Each capture is redacted, capped at max_bytes (default 16 KiB) and kept for ttl_seconds (default a day, at most a week). Redact rules are keys, matched at any depth ignoring case, or JSON Pointers. A null debug mode turns capturing off; captures already taken are kept until they expire. Captures are encrypted at rest, so with a database SECRETS_ENCRYPTION_KEYS must be set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's debug mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New debug mode",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.debugRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "SECRETS_ENCRYPTION_KEYS is not set",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/deployments": {
+            "get": {
+                "description": "Returns the deployments of new commits of a function deployed from Git, by sync or push webhook, newest first, with the commit, the previous commit and the outcome: deployed, building, rolled_back or failed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "List a function's Git deployments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deployments (default 50, at most 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.GitDeployment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/domain": {
+            "put": {
+                "description": "Serves the function for requests whose Host is the domain: a POST to any path executes it with the request body as its payload and answers with the bare result, like /f/{name}, without a token. Point the domain's DNS at the manager. In kubernetes mode with KUBERNETES_DOMAIN_SERVICE, the manager also creates an Ingress for the domain, with a cert-manager certificate when KUBERNETES_CERT_ISSUER is set. An empty domain unbinds it. The API's own host names, from TLS_AUTOCERT_DOMAINS and RESULT_BASE_URL, cannot be bound.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Bind a custom domain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Domain to bind, or empty",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.domainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, the function is deleted, or the domain is the API's",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Another function has the domain (DOMAIN_TAKEN)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error, e.g. the Ingress could not be created",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/envs": {
+            "get": {
+                "description": "Returns the functions deployed as environments of a function, such as staging, sorted by environment name.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environments"
+                ],
+                "summary": "List a function's environments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.Function"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/envs/{env}": {
+            "get": {
+                "description": "Returns the function deployed to an environment of a function.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environments"
+                ],
+                "summary": "Get an environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment, e.g. staging",
+                        "name": "env",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "404": {
+                        "description": "The function or environment does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Deploys code or env vars to an environment of a function, such as staging, without touching the function itself. A missing environment is created as a function of its own, with the function's settings (but not its exposure) and, unless code is sent, its code and env vars. An existing environment gets the code or env vars sent; new code passes the checks an upload does, and a running worker is restarted on the change. Rejected code leaves the environment as it was.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environments"
+                ],
+                "summary": "Deploy to an environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment, a DNS label such as staging",
+                        "name": "env",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "The Python file containing the function handler",
+                        "name": "python_file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "A .tar.zst or .tar.gz/.tgz archive with handler.py at its root, instead of python_file (docker mode only)",
+                        "name": "bundle",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 of the uploaded file or bundle",
+                        "name": "code_sha256",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Git repository to deploy the code from instead of an upload (with GIT_DEPLOYS)",
+                        "name": "git_url",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Branch, tag or commit to deploy",
+                        "name": "git_ref",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Directory holding handler.py, relative to the repository root",
+                        "name": "git_path",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Access token for a private https repository",
+                        "name": "git_token",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Private SSH deploy key for a private ssh repository",
+                        "name": "git_deploy_key",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The name of the function to execute; defaults to the function's",
+                        "name": "function_name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON object of env vars, replacing the environment's",
+                        "name": "env_vars",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The environment was updated, or already had the code and env vars",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "201": {
+                        "description": "The environment was created",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "202": {
+                        "description": "With IMAGE_BUILDS, the environment's code is being built",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the code failed its checks",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "FUNCTION_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The upload exceeds MAX_UPLOAD_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The malware scan found a threat",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stops an environment's worker and removes it permanently, including its code.",
+                "tags": [
+                    "environments"
+                ],
+                "summary": "Remove an environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment",
+                        "name": "env",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "The function or environment does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/envs/{env}/execute": {
+            "post": {
+                "description": "Executes the function deployed to an environment, like POST /functions/{functionID}/execute with the environment's function ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environments"
+                ],
+                "summary": "Execute an environment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment",
+                        "name": "env",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payload for the function",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.executeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Executes at most once per key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Time out the worker call after this many seconds",
+                        "name": "X-Timeout-Seconds",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.ExecutionResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "The function or environment does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "The environment is not running",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The handler raised an exception (HANDLER_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "502": {
+                        "description": "The worker could not be reached or failed",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "504": {
+                        "description": "The worker did not answer in time",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/execute": {
+            "post": {
+                "description": "Sends a JSON payload to a function and returns the result.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Execute a function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payload for the function",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.executeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Executes at most once per key; retries with the same key and payload get the stored result",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Time out the worker call after this many seconds; capped at the function's timeout_seconds",
+                        "name": "X-Timeout-Seconds",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sends executions with the same key to the same worker replica, for functions with an affinity",
+                        "name": "X-Affinity-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Inline result, or a reference when the result was offloaded",
+                        "schema": {
+                            "$ref": "#/definitions/functions.ExecutionResult"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the result was stored by an earlier request with the same Idempotency-Key"
+                            },
+                            "X-Faas-Cache": {
+                                "type": "string",
+                                "description": "hit when the result was served from the response cache"
+                            },
+                            "X-Faas-Degraded": {
+                                "type": "string",
+                                "description": "Set when the fallback function answered: error, timeout, circuit_open or busy"
+                            },
+                            "X-Faas-Invocation-Id": {
+                                "type": "string",
+                                "description": "Identifies the execution; also set on errors once the execution started. Its result can be fetched from /invocations/{invocationID}/result"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the payload does not match the function's schema (INVALID_PAYLOAD, violations in details)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "FUNCTION_NOT_FOUND",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "409": {
+                        "description": "The function is not running, or a request with the same Idempotency-Key is still running",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The payload exceeds MAX_PAYLOAD_BYTES or the function's max_payload_bytes",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The handler raised an exception (HANDLER_ERROR, its type, message and traceback in details.exception), or the Idempotency-Key was used with a different payload",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "429": {
+                        "description": "The function is at its concurrency limit, or the manager is overloaded",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "502": {
+                        "description": "The worker could not be reached (WORKER_UNAVAILABLE) or failed (WORKER_ERROR)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "503": {
+                        "description": "The manager is shutting down (SHUTTING_DOWN)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "504": {
+                        "description": "The worker did not answer in time (WORKER_TIMEOUT)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/execute-stream": {
+            "post": {
+                "description": "Reads NDJSON lines of {\"id\": \"...\", \"payload\": \"...\"} (id is optional) and executes the function once per line, several lines at a time. One NDJSON result line is written per input line as soon as it completes, so results may arrive out of order; \"index\" is the input line number from 0 and \"id\" is echoed. \"status\" is what the execute endpoint would have answered. If the stream itself fails, e.g. a line is too long, a last line holds only \"error\" and \"code\".",
+                "consumes": [
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Execute a function on a stream of payloads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "NDJSON lines of {\\",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "number",
+                        "description": "Time out each line's worker call after this many seconds; capped at the function's timeout_seconds",
+                        "name": "X-Timeout-Seconds",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sends every line to the same worker replica, for functions with an affinity; without it each line's payload field decides",
+                        "name": "X-Affinity-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One line per input line",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/http.streamLine"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "The first line is too long, or X-Timeout-Seconds is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/kubernetes": {
+            "put": {
+                "description": "Runs the function's pods as another service account, or pulls their image with other pull secrets, than the configured ones (kubernetes and knative mode only). Both must exist in the workers' namespace. A running worker is restarted. A null value removes the overrides.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's Kubernetes overrides",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New Kubernetes overrides",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.kubernetesWorkerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/labels": {
+            "put": {
+                "description": "Sets the function's labels, replacing all existing ones. Running workers keep their old labels until they are restarted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Replace a function's labels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New labels",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.labelsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/logs": {
+            "get": {
+                "description": "Returns what the function's worker instances wrote to stdout and stderr, the latest \"tail\" lines of each, one JSON object per line. With follow=true the response stays open and new lines are streamed as the workers write them, until the client disconnects or the workers stop; instances started after the request are not included. Lines of different instances interleave; \"instance\" is the container or pod that wrote the line. Send \"Accept: text/event-stream\" for Server-Sent Events (\"log\" events) instead of NDJSON. If reading fails after lines were sent, a last line (or \"error\" event) holds only \"error\" and \"code\". When logs are shipped to a log backend and LOG_SHIPPING_QUERY_URL is set, the Link header points to the function's logs there. Supported in docker and kubernetes mode.",
+                "produces": [
+                    "application/x-ndjson",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Read a function's logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Lines of each instance to start with (default 100, at most 10000)",
+                        "name": "tail",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Leave out lines written before this, an RFC 3339 time or a duration ago such as 10m",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Keep streaming new lines",
+                        "name": "follow",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.LogLine"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "The function's logs in the log backend, when LOG_SHIPPING_QUERY_URL is set"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the orchestrator",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/metrics": {
+            "get": {
+                "description": "Reports a function's invocation rate, error rate, p50/p95/p99 latency and cold starts over a recent window, counted by this manager replica in whole minutes, and its worker's current CPU and memory use from docker stats or metrics-server where available.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Function metrics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Window, a duration between 1m and 1h (default 5m)",
+                        "name": "window",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.FunctionMetrics"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/promote": {
+            "post": {
+                "description": "Deploys what one environment of a function runs to another, e.g. staging to prod: its exact code, including locked dependencies, its Git commit, its pinned worker image and its settings. The target keeps its own env vars; a missing target is created with the function's. A running worker is restarted on the promoted code. The promotion, with who asked for it and when, is set as the target's promotion, kept in the function's promotion history (GET /functions/{functionID}/promotions), failed promotions included, and sent as a function.promoted event. Who asked for it is the token the caller authenticated with: api_token, or invoke_token: and the token's ID. Rejected code leaves the target as it was.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environments"
+                ],
+                "summary": "Promote one environment to another",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment to promote",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Environment to deploy it to",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who is promoting, recorded with the promotion when no API_TOKEN is set; otherwise the authenticated token is recorded",
+                        "name": "promoted_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The target environment was updated",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "201": {
+                        "description": "The target environment was created",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "202": {
+                        "description": "With IMAGE_BUILDS, the target's code is being built",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the code failed its checks",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "The function or the environment to promote does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The malware scan found a threat",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/promotions": {
+            "get": {
+                "description": "Returns the promotions between a function's environments, newest first, with the environments, the code and commit promoted, who promoted it and the outcome: promoted, building or failed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "environments"
+                ],
+                "summary": "List a function's promotions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of promotions (default 50, at most 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.EnvironmentPromotion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/public": {
+            "put": {
+                "description": "Serves the function at POST /f/{name}, where anyone can call it without a token and without knowing its ID: the request body is the payload and the response body the result. Only a function with a name can be public. false stops serving it there.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Make a function public",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Whether the function is public",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.publicRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the function has no name",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/replicas": {
+            "put": {
+                "description": "Runs this many worker containers for the function (docker mode only). Executions go to the replica with the fewest in flight; a replica that cannot be reached is left out for 30 seconds. A running function's worker is restarted with the new count. 0 or 1 runs a single container.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's worker replicas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New replica count",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.replicasRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/restart": {
+            "post": {
+                "description": "Replaces the worker of a running function with a fresh container or pod and returns the new worker details. If the new worker cannot be started, the function is left stopped.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Restart a function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Function is not running",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/restore": {
+            "post": {
+                "description": "Redeploys a soft-deleted function from its stored code.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Restore a deleted function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Function is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/schema": {
+            "put": {
+                "description": "Replaces the JSON Schema that execute payloads must match. Payloads that do not match are rejected with 400 before the worker is called. A null schema removes validation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's payload schema",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New schema",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.payloadSchemaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/start": {
+            "post": {
+                "description": "Redeploys a stopped function from its stored code.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Start a stopped function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Function is not stopped",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "Function code is blocked",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/status": {
+            "get": {
+                "description": "Asks the orchestrator for the function's worker now: its pods or container with their phases and container states, restart and termination reasons, and recent events. \"status\" is the status the database records, which may lag behind. \"worker\" is null when the orchestrator has no worker for the function. Supported in docker and kubernetes mode.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Get a function's live status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.LiveStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Not supported by the orchestrator",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/stop": {
+            "post": {
+                "description": "Stops the function's worker but keeps its record and code, leaving it in the \"stopped\" status until it is started again. Stopping a stopped function does nothing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Stop a function",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "The function is deleted, blocked or being built",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/sync": {
+            "post": {
+                "description": "Fetches the current commit of the ref a function was deployed from and, when it moved, deploys it: the code passes the same checks as an upload, then a running worker is replaced by one on the new code (with IMAGE_BUILDS, the function's image is rebuilt). In docker mode the new worker is started next to the old one, which keeps serving until the new one answers. If the new code is rejected, the function keeps its previous commit; if its worker does not start, the function is rolled back to the previous commit. Each deployment is recorded, see GET /functions/{functionID}/deployments. Only available with GIT_DEPLOYS.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Sync a function from Git",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deployed, or already at the current commit",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "202": {
+                        "description": "With IMAGE_BUILDS, the new commit is being built",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "The function was not deployed from Git, is deleted or blocked, the repository or ref cannot be fetched, or the new code is invalid (INVALID_CODE) or violates the code policy (CODE_POLICY_VIOLATION)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown function",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "422": {
+                        "description": "The malware scan found a threat in the new commit",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Git deploys are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/timeout": {
+            "put": {
+                "description": "Bounds each call to the function's worker; a call that takes longer fails with 504 WORKER_TIMEOUT. It is also the most a caller may ask for with the X-Timeout-Seconds header. 0 removes the limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's timeout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New timeout",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.timeoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/tokens": {
+            "get": {
+                "description": "Lists a function's invoke tokens, including expired and revoked ones, without the tokens themselves.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "List invoke tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.InvokeToken"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a bearer token that can execute this function, through /execute, /execute-stream and its results, and nothing else: it cannot list, change or delete functions. Hand it to services that only need to call the function. The token is only returned here; the manager keeps a hash of it. Invoke tokens only restrict callers when API_TOKEN is set; without it the whole API is open.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Issue an invoke token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Token settings",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/http.invokeTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/functions.InvokeToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the function is deleted",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/tokens/{tokenID}": {
+            "delete": {
+                "description": "Makes an invoke token unusable at once. The token stays listed, with its revoked_at.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Revoke an invoke token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token ID",
+                        "name": "tokenID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.InvokeToken"
+                        }
+                    },
+                    "404": {
+                        "description": "The function has no such token",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/transfer": {
+            "post": {
+                "description": "Hands the function over to another user or team. When an owner directory is configured the new owner must exist in it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Transfer a function to a new owner",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New owner",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.transferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/upgrade": {
+            "post": {
+                "description": "Resolves the tag of the function's worker image again. If it now points to a new digest, the function is redeployed on it (or, with IMAGE_BUILDS, rebuilt on it). Only available with PIN_IMAGE_DIGESTS.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Upgrade a function's worker image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "The function is deleted or blocked, or its image cannot be found in its registry",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown function",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Image digests are not pinned",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/usage": {
+            "get": {
+                "description": "Reports a function's invocations, errors, total duration, estimated GB-seconds and cold starts per hour (UTC), with totals. Defaults to the last 24 hours; ranges are limited to 92 days.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Function usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the range, RFC 3339; rounded down to the hour",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the range, RFC 3339; defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.UsageReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/warm": {
+            "post": {
+                "description": "Sends priming requests straight to the function's worker and waits for them. Without a body, the function's warm-up settings are used; a body may override the payload and number of requests. Warm-up requests skip hooks, the response cache and usage metering.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Warm a function up",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payload and number of requests for this warm-up",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Warmup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.WarmupReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request, or the function is not running",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/functions/{functionID}/warmup": {
+            "put": {
+                "description": "Replaces the function's warm-up settings: the payload and number of priming requests, and whether they are sent whenever its worker is (re)started. A null warmup removes them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Set a function's warm-up",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Function ID",
+                        "name": "functionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New warm-up settings",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.warmupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Function"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/git/push": {
+            "post": {
+                "description": "Push webhook for GitHub (signed with GIT_WEBHOOK_SECRET, X-Hub-Signature-256) and GitLab (X-Gitlab-Token set to GIT_WEBHOOK_SECRET). Every function deployed from the pushed repository and ref is synced, one after another, in the background; each sync emits a function.synced event. Other events, such as GitHub's ping, are acknowledged and ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "git"
+                ],
+                "summary": "Receive a Git push webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "GitHub event name, e.g. push",
+                        "name": "X-GitHub-Event",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "GitHub's HMAC-SHA256 signature of the body",
+                        "name": "X-Hub-Signature-256",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "GitLab event name, e.g. Push Hook",
+                        "name": "X-Gitlab-Event",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "GitLab's secret token",
+                        "name": "X-Gitlab-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "The functions being redeployed",
+                        "schema": {
+                            "$ref": "#/definitions/functions.GitPushResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Not a GitHub or GitLab delivery, or an invalid payload",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "401": {
+                        "description": "The signature or token does not match",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The delivery exceeds 25 MB",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "GIT_DEPLOYS or GIT_WEBHOOK_SECRET is not set",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/import": {
+            "post": {
+                "description": "Recreates the functions of an archive from GET /export, with their IDs, oldest first so hooks and fallbacks find their targets. Functions whose ID exists already are left alone and reported as \"exists\". Each function's code passes the same checks as an upload; a function that fails them is reported as \"failed\" and the import goes on. Imported functions are stopped unless deploy=true, which deploys those that were running when exported.",
+                "consumes": [
+                    "application/zip"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "catalog"
+                ],
+                "summary": "Import a function catalog",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Deploy the functions that were running when exported",
+                        "name": "deploy",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Catalog archive",
+                        "name": "archive",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.ImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Not a catalog archive, or one that unpacks beyond IMPORT_MAX_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "413": {
+                        "description": "The archive exceeds IMPORT_MAX_BYTES",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/invocations/{invocationID}/replay": {
+            "post": {
+                "description": "Runs a stored invocation's payload again against the function's current code, bypassing the response cache, and compares the outcomes: whether the code changed, whether the replay ended the same, and the JSON Pointer paths where the results differ. The original must have been stored with its payload (INVOCATION_CAPTURE_PAYLOADS). The replay is an invocation of its own, whose ID is also returned in X-Faas-Invocation-Id; its failure is part of the comparison.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Replay an invocation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invocation ID",
+                        "name": "invocationID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.InvocationReplay"
+                        },
+                        "headers": {
+                            "X-Faas-Invocation-Id": {
+                                "type": "string",
+                                "description": "The replay's invocation ID"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "The invocation was stored without its payload",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown or expired invocation, or the function is gone",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "429": {
+                        "description": "The manager is overloaded",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Invocation results are not kept",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "503": {
+                        "description": "The manager is shutting down (SHUTTING_DOWN)",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/invocations/{invocationID}/result": {
+            "get": {
+                "description": "Returns the stored outcome of an execution by the ID from its X-Faas-Invocation-Id header. Outcomes are kept for INVOCATION_RESULT_TTL; results larger than INVOCATION_RESULT_MAX_BYTES are reported as truncated, and offloaded results as a reference.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "Get an invocation's result",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invocation ID",
+                        "name": "invocationID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Invocation"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown or expired invocation",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Invocation results are not kept",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/openapi.json": {
+            "get": {
+                "description": "Returns the API description as an OpenAPI 3 document, e.g. for generating client SDKs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "docs"
+                ],
+                "summary": "OpenAPI 3 document",
+                "responses": {
+                    "200": {
+                        "description": "OpenAPI 3 document",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/results/{key}": {
+            "get": {
+                "description": "Streams a result that was too large to be returned inline by the execute endpoint.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "results"
+                ],
+                "summary": "Fetch an offloaded result",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Result key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The function's JSON result",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid result key",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/runtimes": {
+            "get": {
+                "description": "Lists the runtimes functions can select with the runtime field, with their worker images, as configured by WORKER_RUNTIMES. The default runtime, if any, is marked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "functions"
+                ],
+                "summary": "List runtimes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.Runtime"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{tenant}/registries": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registries"
+                ],
+                "summary": "List a tenant's registry credentials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant",
+                        "name": "tenant",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.RegistryCredential"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registers (or replaces) the tenant's login for a container registry. It is used to pull the tenant's custom worker images. The password is stored encrypted and never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "registries"
+                ],
+                "summary": "Store registry credentials for a tenant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant",
+                        "name": "tenant",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Registry credentials",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.registryCredentialRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.RegistryCredential"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/tenants/{tenant}/registries/{credentialID}": {
+            "delete": {
+                "tags": [
+                    "registries"
+                ],
+                "summary": "Delete registry credentials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tenant",
+                        "name": "tenant",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Credential ID",
+                        "name": "credentialID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/usage/export": {
+            "get": {
+                "description": "Summarizes invocations, errors, total duration, estimated GB-seconds and cold starts per tenant and per function, soft-deleted functions included. Defaults to the last 24 hours; ranges are limited to 92 days. The CSV has one row per tenant (scope \"tenant\") followed by one row per function (scope \"function\").",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Export usage for billing",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the range, RFC 3339; rounded down to the hour",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the range, RFC 3339; defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/functions.UsageExport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Secrets are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/functions.Webhook"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registers a URL that receives lifecycle events (function.created, function.deployed, function.failed, function.stopped, function.deleted, invocation.succeeded, invocation.failed) as signed JSON POSTs, retried with exponential backoff. The secret is only returned here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.webhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/functions.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{webhookID}": {
+            "delete": {
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "webhookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.apiError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "functions.Affinity": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field names the top-level field of JSON object payloads that holds the\nkey. The caller's X-Affinity-Key header takes precedence over it.",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.ApplyResult": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.BulkDeleteResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.CORSPolicy": {
+            "type": "object",
+            "properties": {
+                "allow_credentials": {
+                    "description": "AllowCredentials lets callers send cookies and HTTP authentication.",
+                    "type": "boolean"
+                },
+                "allowed_headers": {
+                    "description": "AllowedHeaders are the request headers callers may send, or \"*\" for\nany; they default to Content-Type.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "allowed_methods": {
+                    "description": "AllowedMethods default to POST, the method public routes serve.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "allowed_origins": {
+                    "description": "AllowedOrigins are origins such as \"https://app.example.com\", or \"*\"\nfor any.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max_age_seconds": {
+                    "description": "MaxAgeSeconds is how long browsers may cache a preflight answer; zero\nleaves it to the browser.",
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.CacheReport": {
+            "type": "object",
+            "properties": {
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.CacheStats"
+                    }
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.CacheStats": {
+            "type": "object",
+            "properties": {
+                "function_id": {
+                    "type": "string"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.CapacityReport": {
+            "type": "object",
+            "properties": {
+                "allocated": {
+                    "$ref": "#/definitions/functions.Resources"
+                },
+                "backend": {
+                    "type": "string"
+                },
+                "nodes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.NodeCapacity"
+                    }
+                },
+                "pending": {
+                    "description": "Pending lists workers that are not placed or not running yet.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.PendingPlacement"
+                    }
+                },
+                "top_consumers": {
+                    "description": "TopConsumers lists the functions using the most memory, largest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.FunctionUsage"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/functions.Resources"
+                }
+            }
+        },
+        "functions.Capture": {
+            "type": "object",
+            "properties": {
+                "capture_id": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "error_class": {
+                    "type": "string"
+                },
+                "exception": {
+                    "$ref": "#/definitions/functions.HandlerError"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "invocation_id": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload and Response are kept as JSON, redacted. Text that is not\nJSON, and JSON larger than the capture's MaxBytes, is kept as a\nstring, cut to MaxBytes with PayloadTruncated or ResponseTruncated\nset. With redact rules, text that is not JSON is redacted whole. Both\nare encrypted at rest.",
+                    "type": "object"
+                },
+                "payload_truncated": {
+                    "type": "boolean"
+                },
+                "response": {
+                    "type": "object"
+                },
+                "response_truncated": {
+                    "type": "boolean"
+                },
+                "result_ref": {
+                    "$ref": "#/definitions/functions.ResultRef"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.CodeDrift": {
+            "type": "object",
+            "properties": {
+                "actual_sha256": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Set when the code could not be read",
+                    "type": "string"
+                },
+                "expected_sha256": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.CodeSpec": {
+            "type": "object",
+            "properties": {
+                "git": {
+                    "$ref": "#/definitions/functions.GitCodeSpec"
+                },
+                "inline": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.ContainerState": {
+            "type": "object",
+            "properties": {
+                "exit_code": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "last_termination": {
+                    "description": "LastTermination is how the container's previous run ended, when it\nwas restarted.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.ContainerTermination"
+                        }
+                    ]
+                },
+                "message": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ready": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "description": "Reason and Message say why a container is waiting or terminated,\ne.g. CrashLoopBackOff or OOMKilled.",
+                    "type": "string"
+                },
+                "restarts": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "state": {
+                    "description": "State is \"running\", \"waiting\" or \"terminated\".",
+                    "type": "string",
+                    "example": "running"
+                }
+            }
+        },
+        "functions.ContainerTermination": {
+            "type": "object",
+            "properties": {
+                "exit_code": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.DebugCapture": {
+            "type": "object",
+            "properties": {
+                "max_bytes": {
+                    "description": "MaxBytes caps the payload and the response kept of each execution;\nzero keeps 16 KiB.",
+                    "type": "integer"
+                },
+                "redact": {
+                    "description": "Redact lists the JSON fields whose values are replaced before a\ncapture is stored: a key such as \"password\" matches at any depth,\nignoring case, and a JSON Pointer such as \"/user/ssn\" matches one\nvalue.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sample_rate": {
+                    "description": "SampleRate is the share of executions captured, above 0 and at most 1.",
+                    "type": "number"
+                },
+                "ttl_seconds": {
+                    "description": "TTLSeconds is how long captures are kept; zero keeps them a day.",
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.EnvironmentPromotion": {
+            "type": "object",
+            "properties": {
+                "code_sha256": {
+                    "type": "string"
+                },
+                "commit": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "from_function_id": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "description": "FunctionID is the function whose environments were promoted.",
+                    "type": "string"
+                },
+                "outcome": {
+                    "description": "Outcome is one of the Promotion constants; Error says what went wrong\nwhen it is PromotionFailed.",
+                    "type": "string"
+                },
+                "promoted_at": {
+                    "type": "string"
+                },
+                "promoted_by": {
+                    "type": "string"
+                },
+                "promotion_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "to_function_id": {
+                    "description": "ToFunctionID is empty when a missing target could not be created.",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.ExecutionResult": {
+            "type": "object",
+            "properties": {
+                "result": {
+                    "type": "object"
+                },
+                "result_ref": {
+                    "$ref": "#/definitions/functions.ResultRef"
+                }
+            }
+        },
+        "functions.Exposure": {
+            "type": "object",
+            "properties": {
+                "host": {
+                    "description": "Optional host to match",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "\"ingress\" or \"httproute\"",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "Path prefix; defaults to /fn/\u003cfunction id\u003e",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.Fallback": {
+            "type": "object",
+            "properties": {
+                "function_id": {
+                    "type": "string"
+                },
+                "timeout_ms": {
+                    "description": "TimeoutMS bounds the primary invocation; zero means no extra timeout.",
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.Function": {
+            "type": "object",
+            "properties": {
+                "affinity": {
+                    "description": "Affinity, when set, sends executions with the same key to the same\nreplica.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Affinity"
+                        }
+                    ]
+                },
+                "blocked_reason": {
+                    "description": "BlockedReason is set when the code was found malicious. Such a\nfunction is never deployed.",
+                    "type": "string"
+                },
+                "build_status": {
+                    "type": "string"
+                },
+                "built_image": {
+                    "description": "With image builds on, BuiltImage is the function's own image, which\nits workers run. BuildStatus and BuildLog describe the latest build.",
+                    "type": "string"
+                },
+                "cache_ttl_seconds": {
+                    "description": "CacheTTLSeconds opts an idempotent function into response caching:\nidentical payloads are answered from the cache for this long.",
+                    "type": "integer"
+                },
+                "code_sha256": {
+                    "type": "string"
+                },
+                "container_id": {
+                    "type": "string"
+                },
+                "container_name": {
+                    "type": "string"
+                },
+                "cors": {
+                    "description": "CORS, when set, lets browser apps on other origins call the function\nthrough its public routes.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.CORSPolicy"
+                        }
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "debug": {
+                    "description": "Debug, when set, captures a sample of the function's executions.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.DebugCapture"
+                        }
+                    ]
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the function is soft-deleted: its worker is\nstopped but the record and code are kept so it can be restored.",
+                    "type": "string"
+                },
+                "dependency_lock_sha256": {
+                    "description": "DependencyLockSHA256 is the checksum of the requirements.lock that\nevery worker of the function installs from.",
+                    "type": "string"
+                },
+                "deployed_at": {
+                    "description": "DeployedAt is when the function's worker was last started.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "Domain, when set, is a host name whose requests the manager serves\nwith the function, like /f/{name}. It is unique across functions.",
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "Worker base URL the manager routes executions to",
+                    "type": "string"
+                },
+                "endpoints": {
+                    "description": "Endpoints lists the base URLs of all replicas when there is more than\none; Endpoint is the first.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "env_var_names": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "environment": {
+                    "type": "string"
+                },
+                "exposure": {
+                    "$ref": "#/definitions/functions.Exposure"
+                },
+                "fallback": {
+                    "$ref": "#/definitions/functions.Fallback"
+                },
+                "function_name": {
+                    "description": "The name of the function in the .py file",
+                    "type": "string"
+                },
+                "git": {
+                    "description": "Git, when set, is the repository the code was deployed from, with the\ncommit deployed. GitToken or GitDeployKey, encrypted at rest by the\nstorage layer, authenticate its clones.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.GitSource"
+                        }
+                    ]
+                },
+                "handler_path": {
+                    "description": "e.g., handler.handle",
+                    "type": "string"
+                },
+                "host_port": {
+                    "description": "The port on the host mapped to the container",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image_digest": {
+                    "description": "ImageDigest pins the worker image's tag to the digest it pointed to\nwhen the function was created or last upgraded.",
+                    "type": "string"
+                },
+                "kubernetes": {
+                    "description": "Kubernetes, when set, overrides the service account and image pull\nsecrets of the function's pods.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.KubernetesWorker"
+                        }
+                    ]
+                },
+                "labels": {
+                    "description": "Labels organize functions, e.g. by team. They are copied onto the\nworker's container or pod labels.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_concurrency": {
+                    "description": "MaxConcurrency caps the executions in flight at once on each manager\nreplica; zero means unlimited.",
+                    "type": "integer"
+                },
+                "max_payload_bytes": {
+                    "description": "MaxPayloadBytes lowers the manager-wide execute payload limit for\nthis function; zero keeps the global limit.",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name, when set, identifies the function for Apply; it is unique\nacross functions, deleted ones included.",
+                    "type": "string"
+                },
+                "next_restart_at": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "ParentID and Environment are set on an environment deployment of\nanother function (see DeployEnvironment), e.g. its \"staging\".",
+                    "type": "string"
+                },
+                "payload_schema": {
+                    "description": "PayloadSchema is a JSON Schema that execute payloads, parsed as JSON,\nmust match before the worker is called.",
+                    "type": "object"
+                },
+                "policy_findings": {
+                    "description": "PolicyFindings lists the code policy violations found when the code\nwas uploaded in flag mode.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "post_hook": {
+                    "$ref": "#/definitions/functions.Hook"
+                },
+                "pre_hook": {
+                    "$ref": "#/definitions/functions.Hook"
+                },
+                "promotion": {
+                    "description": "Promotion records the promotion that deployed the environment's code,\nuntil it is deployed otherwise.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Promotion"
+                        }
+                    ]
+                },
+                "public": {
+                    "description": "Public serves the function at /f/{name}, without authentication or\nthe execute envelope. Only named functions can be public.",
+                    "type": "boolean"
+                },
+                "public_url": {
+                    "description": "Direct URL when the function is exposed outside the manager",
+                    "type": "string"
+                },
+                "replicas": {
+                    "description": "Replicas is how many worker containers serve the function in Docker\nmode, which the manager balances executions across; zero means one.",
+                    "type": "integer"
+                },
+                "restart_attempts": {
+                    "description": "RestartAttempts counts the failed attempts in a row to start the\nworker again after it was lost; the reconciler tries again at\nNextRestartAt.",
+                    "type": "integer"
+                },
+                "runtime": {
+                    "description": "Runtime whose worker image the function runs",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "e.g., \"creating\", \"pulling_image\", \"running\", \"stopped\", \"error\"",
+                    "type": "string"
+                },
+                "status_reason": {
+                    "description": "StatusReason explains the status: why the worker died or its image\ncould not be pulled, or the last disruption a running worker\nrecovered from, e.g. an OOM kill.",
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "timeout_seconds": {
+                    "description": "TimeoutSeconds bounds each call to the worker, and the timeout a\ncaller may ask for; zero means no limit.",
+                    "type": "integer"
+                },
+                "warmup": {
+                    "description": "Warmup, when set, configures the requests that prime the function's\nworkers.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Warmup"
+                        }
+                    ]
+                },
+                "worker": {
+                    "description": "Worker is the worker's live status, only filled in by GetFunction.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.WorkerStatus"
+                        }
+                    ]
+                },
+                "worker_image": {
+                    "description": "Custom worker image; empty means the runtime's or the global default",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.FunctionBuild": {
+            "type": "object",
+            "properties": {
+                "image": {
+                    "type": "string"
+                },
+                "log": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.FunctionMetrics": {
+            "type": "object",
+            "properties": {
+                "cold_start_avg_ms": {
+                    "type": "number"
+                },
+                "cold_starts": {
+                    "description": "ColdStarts counts the invocations that were the first of a worker;\nColdStartAvgMS is their average latency, which LatencyMS includes.",
+                    "type": "integer"
+                },
+                "error_rate": {
+                    "description": "ErrorRate is the share of failed invocations, between 0 and 1.",
+                    "type": "number"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "invocations": {
+                    "description": "Invocations and Errors count the calls to the worker; cached\nresponses are not included. Errors are split into UserErrors, such as\nexceptions the handler raised, and PlatformErrors, such as workers that\ncould not be reached or timed out.",
+                    "type": "integer"
+                },
+                "latency_ms": {
+                    "$ref": "#/definitions/functions.LatencySummary"
+                },
+                "platform_errors": {
+                    "type": "integer"
+                },
+                "rate_per_second": {
+                    "description": "RatePerSecond is the average number of invocations per second.",
+                    "type": "number"
+                },
+                "resources": {
+                    "description": "Resources is the worker's current CPU and memory use, left out when\nthe orchestrator does not report it.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Resources"
+                        }
+                    ]
+                },
+                "to": {
+                    "type": "string"
+                },
+                "user_errors": {
+                    "type": "integer"
+                },
+                "window": {
+                    "type": "string",
+                    "example": "5m0s"
+                }
+            }
+        },
+        "functions.FunctionSpec": {
+            "type": "object",
+            "properties": {
+                "affinity": {
+                    "$ref": "#/definitions/functions.Affinity"
+                },
+                "cache_ttl_seconds": {
+                    "type": "integer"
+                },
+                "code": {
+                    "$ref": "#/definitions/functions.CodeSpec"
+                },
+                "cors": {
+                    "$ref": "#/definitions/functions.CORSPolicy"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "env_vars": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "exposure": {
+                    "$ref": "#/definitions/functions.Exposure"
+                },
+                "fallback": {
+                    "$ref": "#/definitions/functions.Fallback"
+                },
+                "function_name": {
+                    "type": "string"
+                },
+                "kubernetes": {
+                    "$ref": "#/definitions/functions.KubernetesWorker"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_concurrency": {
+                    "type": "integer"
+                },
+                "max_payload_bytes": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "payload_schema": {
+                    "type": "object"
+                },
+                "post_hook": {
+                    "$ref": "#/definitions/functions.Hook"
+                },
+                "pre_hook": {
+                    "$ref": "#/definitions/functions.Hook"
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "replicas": {
+                    "type": "integer"
+                },
+                "runtime": {
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "timeout_seconds": {
+                    "type": "integer"
+                },
+                "warmup": {
+                    "$ref": "#/definitions/functions.Warmup"
+                },
+                "worker_image": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.FunctionUsage": {
+            "type": "object",
+            "properties": {
+                "allocated": {
+                    "$ref": "#/definitions/functions.Resources"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "memory_usage_bytes": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.FunctionUsageSummary": {
+            "type": "object",
+            "properties": {
+                "cold_start_ms": {
+                    "type": "integer"
+                },
+                "cold_starts": {
+                    "description": "ColdStarts counts the invocations that were the first of a worker,\nand ColdStartMS their total duration, also part of DurationMS.",
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "function_name": {
+                    "type": "string"
+                },
+                "gb_seconds": {
+                    "type": "number"
+                },
+                "invocations": {
+                    "type": "integer"
+                },
+                "tenant": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.GitCodeSpec": {
+            "type": "object",
+            "properties": {
+                "deploy_key": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "ref": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.GitDeployment": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "type": "string"
+                },
+                "deployment_id": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "description": "Outcome is one of the GitDeploy constants; Error says what went wrong\nunless it is GitDeployDeployed or GitDeployBuilding.",
+                    "type": "string"
+                },
+                "previous_commit": {
+                    "type": "string"
+                },
+                "ref": {
+                    "description": "Ref is the ref the function follows, empty for the default branch.",
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "trigger": {
+                    "description": "Trigger is SyncTriggerAPI or SyncTriggerPush.",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.GitPushResult": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "type": "string"
+                },
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ref": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.GitSource": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "description": "Commit is the SHA of the commit last deployed.",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "Path is the directory holding handler.py, relative to the repository\nroot; empty means the root.",
+                    "type": "string"
+                },
+                "ref": {
+                    "description": "Ref is the branch, tag or commit to deploy; empty means the\nrepository's default branch.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.HandlerError": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "traceback": {
+                    "description": "Traceback is the traceback as Python prints it, if the worker sent it.",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type is the exception's class, e.g. \"ValueError\" or\n\"requests.exceptions.HTTPError\".",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.Hook": {
+            "type": "object",
+            "properties": {
+                "function_id": {
+                    "type": "string"
+                },
+                "on_failure": {
+                    "description": "\"abort\" (default) or \"continue\"",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.ImportResult": {
+            "type": "object",
+            "properties": {
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.ImportedFunction"
+                    }
+                }
+            }
+        },
+        "functions.ImportedFunction": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "function_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.Invocation": {
+            "type": "object",
+            "properties": {
+                "code_sha256": {
+                    "description": "CodeSHA256 is the checksum of the code the function ran.",
+                    "type": "string"
+                },
+                "degraded": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "error_class": {
+                    "type": "string"
+                },
+                "exception": {
+                    "$ref": "#/definitions/functions.HandlerError"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "invocation_id": {
+                    "type": "string"
+                },
+                "payload_captured": {
+                    "type": "boolean"
+                },
+                "replay_of": {
+                    "description": "ReplayOf is the invocation this one replayed, see ReplayInvocation.",
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "result_ref": {
+                    "$ref": "#/definitions/functions.ResultRef"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "functions.InvocationReplay": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "Changes lists where the results differ, at most 100 of them.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.ResultChange"
+                    }
+                },
+                "code_changed": {
+                    "description": "CodeChanged reports whether the replay ran other code than the\noriginal.",
+                    "type": "boolean"
+                },
+                "compared": {
+                    "description": "Compared is false when a result was stored truncated or offloaded, so\nthe results could not be compared and Same is false.",
+                    "type": "boolean"
+                },
+                "original": {
+                    "$ref": "#/definitions/functions.Invocation"
+                },
+                "replay": {
+                    "$ref": "#/definitions/functions.Invocation"
+                },
+                "same": {
+                    "description": "Same reports whether the replay ended like the original: both with an\nequal result, or both failed with the same error class and exception.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "functions.InvokeToken": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.KubernetesWorker": {
+            "type": "object",
+            "properties": {
+                "image_pull_secrets": {
+                    "description": "ImagePullSecrets replace KUBERNETES_PULL_SECRET. Secrets with tenant\nregistry credentials are still added.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "service_account": {
+                    "description": "ServiceAccount replaces KUBERNETES_SERVICE_ACCOUNT.",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.LatencySummary": {
+            "type": "object",
+            "properties": {
+                "p50": {
+                    "type": "number"
+                },
+                "p95": {
+                    "type": "number"
+                },
+                "p99": {
+                    "type": "number"
+                }
+            }
+        },
+        "functions.LiveStatus": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status and StatusReason are what the database records.",
+                    "type": "string"
+                },
+                "status_reason": {
+                    "type": "string"
+                },
+                "worker": {
+                    "description": "Worker is null when the orchestrator has no worker for the function.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.WorkerDescription"
+                        }
+                    ]
+                }
+            }
+        },
+        "functions.LogLine": {
+            "type": "object",
+            "properties": {
+                "instance": {
+                    "description": "Instance is the container or pod that wrote the line.",
+                    "type": "string"
+                },
+                "line": {
+                    "type": "string"
+                },
+                "stream": {
+                    "description": "Stream is stdout or stderr, when the orchestrator tells them apart.",
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.Manifest": {
+            "type": "object",
+            "properties": {
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.FunctionSpec"
+                    }
+                }
+            }
+        },
+        "functions.NodeCapacity": {
+            "type": "object",
+            "properties": {
+                "allocated": {
+                    "$ref": "#/definitions/functions.Resources"
+                },
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/functions.Resources"
+                }
+            }
+        },
+        "functions.OrphanedFunction": {
+            "type": "object",
+            "properties": {
+                "function": {
+                    "$ref": "#/definitions/functions.Function"
+                },
+                "reason": {
+                    "description": "\"unowned\" or \"owner_missing\"",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.PendingPlacement": {
+            "type": "object",
+            "properties": {
+                "function_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "worker": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.Promotion": {
+            "type": "object",
+            "properties": {
+                "code_sha256": {
+                    "type": "string"
+                },
+                "commit": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "from_function_id": {
+                    "type": "string"
+                },
+                "promoted_at": {
+                    "type": "string"
+                },
+                "promoted_by": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.RegistryCredential": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "server": {
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.ReloadReport": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "Applied were changed in place.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "restart_required": {
+                    "description": "RestartRequired differ from the running configuration but only take\neffect when the manager restarts.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "functions.Resources": {
+            "type": "object",
+            "properties": {
+                "cpu_millis": {
+                    "type": "integer"
+                },
+                "memory_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.ResultChange": {
+            "type": "object",
+            "properties": {
+                "new": {
+                    "type": "object"
+                },
+                "old": {
+                    "description": "Old is absent when the value was added, New when it was removed.",
+                    "type": "object"
+                },
+                "path": {
+                    "description": "Path is the JSON Pointer of the value, e.g. \"/items/0/price\"; \"\" is\nthe whole result.",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.ResultRef": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.Runtime": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.RuntimeStats": {
+            "type": "object",
+            "properties": {
+                "draining": {
+                    "type": "boolean"
+                },
+                "gc_pause_total_ms": {
+                    "type": "number"
+                },
+                "go_version": {
+                    "type": "string"
+                },
+                "gomaxprocs": {
+                    "type": "integer"
+                },
+                "goroutines": {
+                    "type": "integer"
+                },
+                "heap_alloc_bytes": {
+                    "description": "HeapAllocBytes is the memory of live heap objects, HeapInuseBytes of\nthe heap spans in use and SysBytes all the memory taken from the OS.",
+                    "type": "integer"
+                },
+                "heap_inuse_bytes": {
+                    "type": "integer"
+                },
+                "heap_objects": {
+                    "type": "integer"
+                },
+                "in_flight_by_function": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "in_flight_executions": {
+                    "description": "InFlightExecutions counts the executions running, and\nInFlightByFunction the executions of each function that has some.",
+                    "type": "integer"
+                },
+                "last_gc": {
+                    "type": "string"
+                },
+                "leader": {
+                    "type": "boolean"
+                },
+                "num_cpu": {
+                    "type": "integer"
+                },
+                "num_gc": {
+                    "type": "integer"
+                },
+                "queued_executions": {
+                    "description": "QueuedExecutions wait for admission, see MAX_INFLIGHT_EXECUTIONS.",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "sys_bytes": {
+                    "type": "integer"
+                },
+                "uptime_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.SearchHit": {
+            "type": "object",
+            "properties": {
+                "affinity": {
+                    "description": "Affinity, when set, sends executions with the same key to the same\nreplica.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Affinity"
+                        }
+                    ]
+                },
+                "blocked_reason": {
+                    "description": "BlockedReason is set when the code was found malicious. Such a\nfunction is never deployed.",
+                    "type": "string"
+                },
+                "build_status": {
+                    "type": "string"
+                },
+                "built_image": {
+                    "description": "With image builds on, BuiltImage is the function's own image, which\nits workers run. BuildStatus and BuildLog describe the latest build.",
+                    "type": "string"
+                },
+                "cache_ttl_seconds": {
+                    "description": "CacheTTLSeconds opts an idempotent function into response caching:\nidentical payloads are answered from the cache for this long.",
+                    "type": "integer"
+                },
+                "code_sha256": {
+                    "type": "string"
+                },
                 "container_id": {
                     "type": "string"
                 },
-                "container_name": {
+                "container_name": {
+                    "type": "string"
+                },
+                "cors": {
+                    "description": "CORS, when set, lets browser apps on other origins call the function\nthrough its public routes.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.CORSPolicy"
+                        }
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "debug": {
+                    "description": "Debug, when set, captures a sample of the function's executions.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.DebugCapture"
+                        }
+                    ]
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the function is soft-deleted: its worker is\nstopped but the record and code are kept so it can be restored.",
+                    "type": "string"
+                },
+                "dependency_lock_sha256": {
+                    "description": "DependencyLockSHA256 is the checksum of the requirements.lock that\nevery worker of the function installs from.",
+                    "type": "string"
+                },
+                "deployed_at": {
+                    "description": "DeployedAt is when the function's worker was last started.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "Domain, when set, is a host name whose requests the manager serves\nwith the function, like /f/{name}. It is unique across functions.",
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "Worker base URL the manager routes executions to",
+                    "type": "string"
+                },
+                "endpoints": {
+                    "description": "Endpoints lists the base URLs of all replicas when there is more than\none; Endpoint is the first.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "env_var_names": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "environment": {
+                    "type": "string"
+                },
+                "exposure": {
+                    "$ref": "#/definitions/functions.Exposure"
+                },
+                "fallback": {
+                    "$ref": "#/definitions/functions.Fallback"
+                },
+                "function_name": {
+                    "description": "The name of the function in the .py file",
+                    "type": "string"
+                },
+                "git": {
+                    "description": "Git, when set, is the repository the code was deployed from, with the\ncommit deployed. GitToken or GitDeployKey, encrypted at rest by the\nstorage layer, authenticate its clones.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.GitSource"
+                        }
+                    ]
+                },
+                "handler_path": {
+                    "description": "e.g., handler.handle",
+                    "type": "string"
+                },
+                "host_port": {
+                    "description": "The port on the host mapped to the container",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "image_digest": {
+                    "description": "ImageDigest pins the worker image's tag to the digest it pointed to\nwhen the function was created or last upgraded.",
+                    "type": "string"
+                },
+                "kubernetes": {
+                    "description": "Kubernetes, when set, overrides the service account and image pull\nsecrets of the function's pods.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.KubernetesWorker"
+                        }
+                    ]
+                },
+                "labels": {
+                    "description": "Labels organize functions, e.g. by team. They are copied onto the\nworker's container or pod labels.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_concurrency": {
+                    "description": "MaxConcurrency caps the executions in flight at once on each manager\nreplica; zero means unlimited.",
+                    "type": "integer"
+                },
+                "max_payload_bytes": {
+                    "description": "MaxPayloadBytes lowers the manager-wide execute payload limit for\nthis function; zero keeps the global limit.",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name, when set, identifies the function for Apply; it is unique\nacross functions, deleted ones included.",
+                    "type": "string"
+                },
+                "next_restart_at": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "ParentID and Environment are set on an environment deployment of\nanother function (see DeployEnvironment), e.g. its \"staging\".",
+                    "type": "string"
+                },
+                "payload_schema": {
+                    "description": "PayloadSchema is a JSON Schema that execute payloads, parsed as JSON,\nmust match before the worker is called.",
+                    "type": "object"
+                },
+                "policy_findings": {
+                    "description": "PolicyFindings lists the code policy violations found when the code\nwas uploaded in flag mode.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "post_hook": {
+                    "$ref": "#/definitions/functions.Hook"
+                },
+                "pre_hook": {
+                    "$ref": "#/definitions/functions.Hook"
+                },
+                "promotion": {
+                    "description": "Promotion records the promotion that deployed the environment's code,\nuntil it is deployed otherwise.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Promotion"
+                        }
+                    ]
+                },
+                "public": {
+                    "description": "Public serves the function at /f/{name}, without authentication or\nthe execute envelope. Only named functions can be public.",
+                    "type": "boolean"
+                },
+                "public_url": {
+                    "description": "Direct URL when the function is exposed outside the manager",
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "replicas": {
+                    "description": "Replicas is how many worker containers serve the function in Docker\nmode, which the manager balances executions across; zero means one.",
+                    "type": "integer"
+                },
+                "restart_attempts": {
+                    "description": "RestartAttempts counts the failed attempts in a row to start the\nworker again after it was lost; the reconciler tries again at\nNextRestartAt.",
+                    "type": "integer"
+                },
+                "runtime": {
+                    "description": "Runtime whose worker image the function runs",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "e.g., \"creating\", \"pulling_image\", \"running\", \"stopped\", \"error\"",
+                    "type": "string"
+                },
+                "status_reason": {
+                    "description": "StatusReason explains the status: why the worker died or its image\ncould not be pulled, or the last disruption a running worker\nrecovered from, e.g. an OOM kill.",
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "timeout_seconds": {
+                    "description": "TimeoutSeconds bounds each call to the worker, and the timeout a\ncaller may ask for; zero means no limit.",
+                    "type": "integer"
+                },
+                "warmup": {
+                    "description": "Warmup, when set, configures the requests that prime the function's\nworkers.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.Warmup"
+                        }
+                    ]
+                },
+                "worker": {
+                    "description": "Worker is the worker's live status, only filled in by GetFunction.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/functions.WorkerStatus"
+                        }
+                    ]
+                },
+                "worker_image": {
+                    "description": "Custom worker image; empty means the runtime's or the global default",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.TenantUsageSummary": {
+            "type": "object",
+            "properties": {
+                "cold_start_ms": {
+                    "type": "integer"
+                },
+                "cold_starts": {
+                    "description": "ColdStarts counts the invocations that were the first of a worker,\nand ColdStartMS their total duration, also part of DurationMS.",
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "functions": {
+                    "type": "integer"
+                },
+                "gb_seconds": {
+                    "type": "number"
+                },
+                "invocations": {
+                    "type": "integer"
+                },
+                "tenant": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.UsageCounters": {
+            "type": "object",
+            "properties": {
+                "cold_start_ms": {
+                    "type": "integer"
+                },
+                "cold_starts": {
+                    "description": "ColdStarts counts the invocations that were the first of a worker,\nand ColdStartMS their total duration, also part of DurationMS.",
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "gb_seconds": {
+                    "type": "number"
+                },
+                "invocations": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.UsageExport": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "functions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.FunctionUsageSummary"
+                    }
+                },
+                "tenants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.TenantUsageSummary"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.UsageReport": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "function_id": {
+                    "type": "string"
+                },
+                "hours": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.UsageRollup"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/functions.UsageCounters"
+                }
+            }
+        },
+        "functions.UsageRollup": {
+            "type": "object",
+            "properties": {
+                "cold_start_ms": {
+                    "type": "integer"
+                },
+                "cold_starts": {
+                    "description": "ColdStarts counts the invocations that were the first of a worker,\nand ColdStartMS their total duration, also part of DurationMS.",
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "gb_seconds": {
+                    "type": "number"
+                },
+                "hour": {
+                    "type": "string"
+                },
+                "invocations": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.ValidationReport": {
+            "type": "object",
+            "properties": {
+                "checked_by": {
+                    "type": "string"
+                },
+                "policy_findings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "problems": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "functions.Warmup": {
+            "type": "object",
+            "properties": {
+                "on_deploy": {
+                    "description": "OnDeploy warms the function up whenever its worker is (re)started.",
+                    "type": "boolean"
+                },
+                "payload": {
+                    "description": "Payload is sent as is; it defaults to DefaultWarmupPayload.",
+                    "type": "string"
+                },
+                "requests": {
+                    "description": "Requests is the number of requests sent, one after another; it\ndefaults to 1. Behind a load balancer they spread over the replicas.",
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.WarmupReport": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "description": "distinct errors, at most a few",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "description": "Events are the subscribed event types; empty subscribes to all but\ninvocation.succeeded, which is only sent when listed.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "tenant": {
+                    "description": "Tenant, when set, limits the webhook to the events of that tenant's\nfunctions.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.WorkerDescription": {
+            "type": "object",
+            "properties": {
+                "desired_replicas": {
+                    "type": "integer"
+                },
+                "events": {
+                    "description": "Events are what the orchestrator recently reported about the worker,\noldest first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.WorkerLogEntry"
+                    }
+                },
+                "instances": {
+                    "description": "Instances are the pods or containers of the worker.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.WorkerInstance"
+                    }
+                },
+                "ready_replicas": {
+                    "type": "integer"
+                },
+                "restarts": {
+                    "description": "Restarts counts the times the orchestrator restarted the worker's\ncontainers, where it reports them.",
+                    "type": "integer"
+                }
+            }
+        },
+        "functions.WorkerInstance": {
+            "type": "object",
+            "properties": {
+                "containers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/functions.ContainerState"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "node": {
+                    "type": "string"
+                },
+                "phase": {
+                    "description": "Phase is the pod phase, e.g. Pending or Running, or the Docker\ncontainer status, e.g. running or exited.",
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.WorkerLogEntry": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "object": {
+                    "description": "Object is the pod, deployment or container the event is about.",
+                    "type": "string"
+                },
+                "reason": {
                     "type": "string"
                 },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type is \"Normal\" or \"Warning\" for Kubernetes events and the action,\ne.g. \"die\" or \"oom\", for Docker events.",
+                    "type": "string"
+                }
+            }
+        },
+        "functions.WorkerResource": {
+            "type": "object",
+            "properties": {
                 "created_at": {
                     "type": "string"
                 },
-                "function_name": {
-                    "description": "The name of the function in the .py file",
+                "function_id": {
+                    "description": "FunctionID is the function the resource was created for, or empty\nwhen its labels and name do not tell.",
                     "type": "string"
                 },
-                "handler_path": {
-                    "description": "e.g., handler.handle",
+                "id": {
+                    "description": "ID is the container's ID, where it differs from the name.",
                     "type": "string"
                 },
-                "host_port": {
-                    "description": "The port on the host mapped to the container",
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "orphan_reason": {
+                    "type": "string"
+                },
+                "orphaned": {
+                    "description": "Orphaned is set when no function record accounts for the resource;\nOrphanReason says why.",
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "functions.WorkerStatus": {
+            "type": "object",
+            "properties": {
+                "desired_replicas": {
+                    "type": "integer"
+                },
+                "ready_replicas": {
+                    "type": "integer"
+                },
+                "restarts": {
+                    "description": "Restarts counts the times the orchestrator restarted the worker's\ncontainers, where it reports them.",
+                    "type": "integer"
+                }
+            }
+        },
+        "http.affinityRequest": {
+            "type": "object",
+            "properties": {
+                "affinity": {
+                    "$ref": "#/definitions/functions.Affinity"
+                }
+            }
+        },
+        "http.apiError": {
+            "type": "object",
+            "properties": {
+                "class": {
+                    "description": "Class is \"user\" when the request or the function's code is at fault,\nso sending it again fails the same way, and \"platform\" otherwise.",
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "platform"
+                    ],
+                    "example": "user"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "FUNCTION_NOT_FOUND"
+                },
+                "details": {
+                    "type": "object"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "function not found: 'abc'"
+                }
+            }
+        },
+        "http.bulkDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "function_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "purge": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "http.cacheTTLRequest": {
+            "type": "object",
+            "properties": {
+                "ttl_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "http.concurrencyRequest": {
+            "type": "object",
+            "properties": {
+                "max_concurrency": {
+                    "type": "integer"
+                }
+            }
+        },
+        "http.corsRequest": {
+            "type": "object",
+            "properties": {
+                "cors": {
+                    "$ref": "#/definitions/functions.CORSPolicy"
+                }
+            }
+        },
+        "http.debugRequest": {
+            "type": "object",
+            "properties": {
+                "debug": {
+                    "$ref": "#/definitions/functions.DebugCapture"
+                }
+            }
+        },
+        "http.domainRequest": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "type": "string",
+                    "example": "echo.example.com"
+                }
+            }
+        },
+        "http.executeRequest": {
+            "type": "object",
+            "properties": {
+                "payload": {
+                    "description": "Payload is passed to the handler as is; it is usually JSON.",
+                    "type": "string",
+                    "example": "{\"temperature\": 21.5}"
+                }
+            }
+        },
+        "http.invalidateCacheResponse": {
+            "type": "object",
+            "properties": {
+                "invalidated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "http.invokeTokenRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "Description says who the token is for.",
+                    "type": "string",
+                    "example": "billing-service"
+                },
+                "expires_in_seconds": {
+                    "description": "ExpiresInSeconds is how long the token is valid; 0 or unset keeps it\nvalid until it is revoked.",
+                    "type": "integer",
+                    "example": 2592000
+                }
+            }
+        },
+        "http.jwk": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "EdDSA"
+                },
+                "crv": {
+                    "type": "string",
+                    "example": "Ed25519"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "OKP"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.jwks": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/http.jwk"
+                    }
+                }
+            }
+        },
+        "http.kubernetesWorkerRequest": {
+            "type": "object",
+            "properties": {
+                "kubernetes": {
+                    "$ref": "#/definitions/functions.KubernetesWorker"
+                }
+            }
+        },
+        "http.labelsRequest": {
+            "type": "object",
+            "properties": {
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "http.payloadSchemaRequest": {
+            "type": "object",
+            "properties": {
+                "payload_schema": {
+                    "type": "object"
+                }
+            }
+        },
+        "http.publicRequest": {
+            "type": "object",
+            "properties": {
+                "public": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "http.registryCredentialRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "server": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.replicasRequest": {
+            "type": "object",
+            "properties": {
+                "replicas": {
                     "type": "integer"
+                }
+            }
+        },
+        "http.streamLine": {
+            "type": "object",
+            "properties": {
+                "cached": {
+                    "type": "boolean"
+                },
+                "class": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "degraded": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object"
+                },
+                "error": {
+                    "description": "Error is set when the execution failed, with the code, class and\ndetails of the execute endpoint's error response.",
+                    "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "index": {
+                    "type": "integer"
+                },
+                "invocation_id": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "result_ref": {
+                    "$ref": "#/definitions/functions.ResultRef"
+                },
                 "status": {
-                    "description": "e.g., \"creating\", \"running\", \"stopped\", \"error\"",
+                    "description": "Status is the status the execute endpoint would have answered with.",
+                    "type": "integer"
+                }
+            }
+        },
+        "http.timeoutRequest": {
+            "type": "object",
+            "properties": {
+                "timeout_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "http.transferRequest": {
+            "type": "object",
+            "properties": {
+                "owner": {
                     "type": "string"
                 }
             }
+        },
+        "http.warmupRequest": {
+            "type": "object",
+            "properties": {
+                "warmup": {
+                    "$ref": "#/definitions/functions.Warmup"
+                }
+            }
+        },
+        "http.webhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "description": "Events defaults to every event type but invocation.succeeded.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "function.deployed",
+                        "function.failed"
+                    ]
+                },
+                "secret": {
+                    "description": "Secret signs the deliveries; one is generated when empty.",
+                    "type": "string"
+                },
+                "tenant": {
+                    "description": "Tenant limits the webhook to one tenant's functions.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://dashboard.example.com/hooks/faas"
+                }
+            }
         }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
-	Host:             "",
-	BasePath:         "",
+	Version:          "1.0",
+	Host:             "localhost:8080",
+	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "FaaS Manager API",
+	Description:      "API for managing and executing functions as a service.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,102 @@
+// Package clamav scans code uploads with a ClamAV daemon over its INSTREAM
+// protocol.
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"service-faas/internal/core/functions"
+)
+
+// chunkSize is the largest chunk sent per INSTREAM frame.
+const chunkSize = 64 << 10
+
+// Scanner streams uploads to clamd. Each scan uses its own connection.
+type Scanner struct {
+	network string
+	address string
+}
+
+// New parses a clamd address, tcp://host:port or unix:///path/clamd.sock.
+func New(address string) (*Scanner, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamd address %q: %w", address, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		return &Scanner{network: "tcp", address: u.Host}, nil
+	case "unix":
+		return &Scanner{network: "unix", address: u.Path}, nil
+	}
+	return nil, fmt.Errorf("invalid clamd address %q: expected tcp:// or unix://", address)
+}
+
+func (s *Scanner) Scan(ctx context.Context, r io.Reader) (*functions.ScanVerdict, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd connect: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// clamd stops reading and answers early when the stream exceeds its
+	// StreamMaxLength, so a failed write still has a reply to read.
+	writeErr := s.stream(conn, r)
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		if writeErr != nil {
+			return nil, fmt.Errorf("clamd stream: %w", writeErr)
+		}
+		return nil, fmt.Errorf("clamd reply: %w", err)
+	}
+	return parseReply(strings.TrimSuffix(reply, "\x00"))
+}
+
+// stream sends r as INSTREAM chunks followed by the zero-length terminator.
+func (s *Scanner) stream(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseReply interprets "stream: OK", "stream: <name> FOUND" or
+// "<message> ERROR".
+func parseReply(reply string) (*functions.ScanVerdict, error) {
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return &functions.ScanVerdict{Clean: true}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		threat := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return &functions.ScanVerdict{Threat: threat}, nil
+	}
+	return nil, fmt.Errorf("clamd: %s", reply)
+}
@@ -0,0 +1,208 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	registryauth "service-faas/internal/adapters/registry"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// buildDockerfile is the name of the generated Dockerfile in a build context.
+const buildDockerfile = ".faas.Dockerfile"
+
+// BuildImage builds a function image on the Docker daemon from the function's
+// code directory and pushes it with the Harbor login. The base image is
+// pulled with the tenant's credentials or the keychain's login.
+func (c *Client) BuildImage(ctx context.Context, spec functions.BuildSpec) (string, error) {
+	buildCtx, err := buildContext(spec)
+	if err != nil {
+		return "", err
+	}
+
+	login := c.login.Load()
+	auths := map[string]registry.AuthConfig{}
+	if login.header != "" {
+		auths[c.cfg.HarborURL] = registry.AuthConfig{Username: login.user, Password: login.password, ServerAddress: c.cfg.HarborURL}
+	}
+	base := spec.RegistryAuth
+	if base == nil {
+		if base, err = c.keychain.ImageCredentials(ctx, spec.BaseImage); err != nil {
+			return "", err
+		}
+	}
+	if base != nil {
+		key := registryauth.ConfigKey(base.Server)
+		auths[key] = registry.AuthConfig{Username: base.Username, Password: base.Password, ServerAddress: key}
+	}
+
+	var out bytes.Buffer
+	resp, err := c.cli.ImageBuild(ctx, buildCtx, build.ImageBuildOptions{
+		Tags:        []string{spec.Image},
+		Dockerfile:  buildDockerfile,
+		Remove:      true,
+		ForceRemove: true,
+		// Like custom worker images, the base image is always pulled.
+		PullParent:  true,
+		AuthConfigs: auths,
+	})
+	if err != nil {
+		return "", fmt.Errorf("docker build: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := jsonmessage.DisplayJSONMessagesStream(resp.Body, &out, 0, false, nil); err != nil {
+		return out.String(), fmt.Errorf("docker build: %w", err)
+	}
+
+	rc, err := c.cli.ImagePush(ctx, spec.Image, image.PushOptions{RegistryAuth: login.header})
+	if err != nil {
+		return out.String(), fmt.Errorf("docker push: %w", err)
+	}
+	defer rc.Close()
+	if err := jsonmessage.DisplayJSONMessagesStream(rc, &out, 0, false, nil); err != nil {
+		return out.String(), fmt.Errorf("docker push: %w", err)
+	}
+
+	c.lg.Info().Str("image", spec.Image).Msg("function image built and pushed")
+	return out.String(), nil
+}
+
+// RemoveImage removes a function image from this host and deletes it from
+// Harbor.
+func (c *Client) RemoveImage(ctx context.Context, img string) error {
+	_, err := c.cli.ImageRemove(ctx, img, image.RemoveOptions{Force: true, PruneChildren: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		return fmt.Errorf("docker image remove: %w", err)
+	}
+	return c.deleteHarborArtifact(ctx, img)
+}
+
+// buildContext returns a tar of the function's code directory with a
+// generated Dockerfile. The certificates and tokens the manager writes next
+// to the code are left out.
+func buildContext(spec functions.BuildSpec) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := filepath.WalkDir(spec.CodePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(spec.CodePath, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch rel {
+		case tlsDir, filepath.Dir(identityFile):
+			return filepath.SkipDir
+		case buildDockerfile, ".dockerignore":
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker build context: %w", err)
+	}
+
+	install := ""
+	if spec.DependencyLock != "" {
+		install = "RUN python -m pip install --no-cache-dir -r /app/function/" + spec.DependencyLock + "\n"
+	} else if _, err := os.Stat(filepath.Join(spec.CodePath, "requirements.txt")); err == nil {
+		install = "RUN python -m pip install --no-cache-dir -r /app/function/requirements.txt\n"
+	}
+	files := map[string]string{
+		buildDockerfile: "FROM " + spec.BaseImage + "\n" +
+			"COPY . /app/function/\n" +
+			install +
+			"ENV HANDLER_FUNCTION=" + spec.HandlerPath + "\n",
+		".dockerignore": buildDockerfile + "\n.dockerignore\n",
+	}
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()}); err != nil {
+			return nil, fmt.Errorf("docker build context: %w", err)
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			return nil, fmt.Errorf("docker build context: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("docker build context: %w", err)
+	}
+	return &buf, nil
+}
+
+// deleteHarborArtifact deletes a tagged image through Harbor's API. Images
+// in other registries are left alone.
+func (c *Client) deleteHarborArtifact(ctx context.Context, img string) error {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %q: %w", img, err)
+	}
+	tagged, ok := named.(reference.Tagged)
+	base := strings.TrimRight(c.cfg.HarborURL, "/")
+	host := strings.TrimPrefix(strings.TrimPrefix(base, "https://"), "http://")
+	if !ok || reference.Domain(named) != host {
+		c.lg.Warn().Str("image", img).Msg("image is not tagged in Harbor, left in its registry")
+		return nil
+	}
+	project, repo, ok := strings.Cut(reference.Path(named), "/")
+	if !ok {
+		return fmt.Errorf("image %q has no Harbor project", img)
+	}
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+
+	// Harbor expects slashes in repository names to be encoded twice.
+	u := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s",
+		base, url.PathEscape(project), url.PathEscape(url.PathEscape(repo)), url.PathEscape(tagged.Tag()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return fmt.Errorf("harbor request: %w", err)
+	}
+	login := c.login.Load()
+	req.SetBasicAuth(login.user, login.password)
+	hc := &http.Client{Timeout: 30 * time.Second}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("harbor delete artifact: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("harbor delete artifact: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+const workerPrefix = "faas-worker-"
+
+// Capacity reports the Docker host's CPUs and memory against the limits and
+// measured memory usage of worker containers.
+func (c *Client) Capacity(ctx context.Context) (*functions.CapacityReport, error) {
+	info, err := c.cli.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("docker info: %w", err)
+	}
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", workerPrefix)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker list: %w", err)
+	}
+
+	total := functions.Resources{CPUMillis: int64(info.NCPU) * 1000, MemoryBytes: info.MemTotal}
+	node := functions.NodeCapacity{Name: info.Name, Total: total, Functions: []string{}}
+	report := &functions.CapacityReport{
+		Backend:      "docker",
+		Total:        total,
+		Pending:      []functions.PendingPlacement{},
+		TopConsumers: []functions.FunctionUsage{},
+	}
+
+	consumer := map[string]int{} // function ID -> index in TopConsumers
+	for _, ctr := range containers {
+		if len(ctr.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(ctr.Names[0], "/")
+		funcID, ok := workerFunction(name)
+		if !ok {
+			continue
+		}
+
+		switch ctr.State {
+		case container.StateCreated, container.StateRestarting:
+			report.Pending = append(report.Pending, functions.PendingPlacement{FunctionID: funcID, Worker: name, Reason: ctr.Status})
+			continue
+		case container.StateRunning:
+		default:
+			continue
+		}
+
+		// Workers run without limits unless configured otherwise, so
+		// allocated resources are often zero; measured memory is more useful.
+		usage := functions.FunctionUsage{FunctionID: funcID, Workers: 1}
+		if inspect, err := c.cli.ContainerInspect(ctx, ctr.ID); err == nil && inspect.HostConfig != nil {
+			usage.Allocated.CPUMillis = inspect.HostConfig.NanoCPUs / 1e6
+			usage.Allocated.MemoryBytes = inspect.HostConfig.Memory
+		}
+		usage.MemoryUsageBytes = c.memoryUsage(ctx, ctr.ID)
+
+		node.Allocated.CPUMillis += usage.Allocated.CPUMillis
+		node.Allocated.MemoryBytes += usage.Allocated.MemoryBytes
+		// The replicas of a worker add up to one consumer.
+		if i, ok := consumer[funcID]; ok {
+			total := &report.TopConsumers[i]
+			total.Workers++
+			total.Allocated.CPUMillis += usage.Allocated.CPUMillis
+			total.Allocated.MemoryBytes += usage.Allocated.MemoryBytes
+			total.MemoryUsageBytes += usage.MemoryUsageBytes
+			continue
+		}
+		consumer[funcID] = len(report.TopConsumers)
+		node.Functions = append(node.Functions, funcID)
+		report.TopConsumers = append(report.TopConsumers, usage)
+	}
+
+	report.Allocated = node.Allocated
+	report.Nodes = []functions.NodeCapacity{node}
+	report.TopConsumers = functions.RankConsumers(report.TopConsumers)
+	return report, nil
+}
+
+// memoryUsage returns a container's current memory usage, or zero when stats
+// are unavailable.
+func (c *Client) memoryUsage(ctx context.Context, containerID string) int64 {
+	resp, err := c.cli.ContainerStatsOneShot(ctx, containerID)
+	if err != nil {
+		c.lg.Debug().Err(err).Str("container_id", containerID).Msg("container stats")
+		return 0
+	}
+	defer resp.Body.Close()
+
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return 0
+	}
+	return int64(stats.MemoryStats.Usage)
+}
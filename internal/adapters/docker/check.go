@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"service-faas/internal/core/functions"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// checkScript parses handler.py without running it and prints one problem
+// per line: a syntax error, the handler missing from the module's top-level
+// names, or a handler function that cannot be called with just the payload.
+const checkScript = `
+import ast, sys
+name = sys.argv[1]
+try:
+    with open("/app/function/handler.py", encoding="utf-8") as f:
+        tree = ast.parse(f.read(), "handler.py")
+except (SyntaxError, UnicodeDecodeError) as e:
+    print("handler.py line %s: %s" % (getattr(e, "lineno", "?"), getattr(e, "msg", e)))
+    sys.exit(0)
+names = set()
+for node in tree.body:
+    if isinstance(node, (ast.FunctionDef, ast.AsyncFunctionDef)) and node.name == name:
+        a = node.args
+        positional = a.posonlyargs + a.args
+        required = len(positional) - len(a.defaults)
+        kwonly_required = [k.arg for k, d in zip(a.kwonlyargs, a.kw_defaults) if d is None]
+        if (not positional and not a.vararg) or required > 1 or kwonly_required:
+            print("handler.py line %d: '%s' must accept a single payload argument" % (node.lineno, name))
+    if isinstance(node, (ast.FunctionDef, ast.AsyncFunctionDef, ast.ClassDef)):
+        names.add(node.name)
+    elif isinstance(node, ast.Assign):
+        names.update(t.id for t in node.targets if isinstance(t, ast.Name))
+    elif isinstance(node, (ast.Import, ast.ImportFrom)):
+        names.update((a.asname or a.name).split(".")[0] for a in node.names)
+if name not in names:
+    print("handler.py does not define '%s'" % name)
+`
+
+// CheckCode runs the check script in a short-lived container of the worker
+// image, without network access, and removes it afterwards.
+func (c *Client) CheckCode(ctx context.Context, spec functions.CodeCheckSpec) ([]string, error) {
+	// The directory must be visible to the Docker daemon, like worker code.
+	if err := os.MkdirAll(c.cfg.FunctionStorageDir, 0755); err != nil {
+		return nil, fmt.Errorf("docker code dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(c.cfg.FunctionStorageDir, "check-")
+	if err != nil {
+		return nil, fmt.Errorf("docker code dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "handler.py"), spec.Handler, 0644); err != nil {
+		return nil, fmt.Errorf("docker write code: %w", err)
+	}
+
+	run := oneShot{
+		image:      spec.Image,
+		auth:       spec.RegistryAuth,
+		entrypoint: []string{"python", "-c", checkScript, spec.FunctionName},
+		binds:      []string{fmt.Sprintf("%s:/app/function:ro", dir)},
+		noNetwork:  true,
+	}
+	stdout, stderr, exitCode, err := c.runOnce(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("check exited with status %d: %s", exitCode, strings.TrimSpace(stderr))
+	}
+
+	problems := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		if line != "" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, nil
+}
+
+// oneShot describes a short-lived container run to completion.
+type oneShot struct {
+	image      string
+	auth       *functions.RegistryAuth
+	entrypoint []string
+	binds      []string
+	noNetwork  bool
+}
+
+// runOnce runs a container to completion and returns its output and exit
+// code. The container is removed afterwards.
+func (c *Client) runOnce(ctx context.Context, run oneShot) (string, string, int64, error) {
+	if err := c.ensureImage(ctx, run.image, run.auth, run.image != c.cfg.WorkerImage, nil); err != nil {
+		return "", "", 0, err
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:           run.image,
+			Entrypoint:      run.entrypoint,
+			NetworkDisabled: run.noNetwork,
+		},
+		&container.HostConfig{Binds: run.binds},
+		nil, nil, "",
+	)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("docker create: %w", err)
+	}
+	defer func() {
+		rmErr := c.cli.ContainerRemove(context.WithoutCancel(ctx), resp.ID, container.RemoveOptions{Force: true})
+		if rmErr != nil {
+			c.lg.Warn().Err(rmErr).Str("container_id", resp.ID).Msg("failed to remove one-shot container")
+		}
+	}()
+
+	waitC, errC := c.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", "", 0, fmt.Errorf("docker start: %w", err)
+	}
+	var exitCode int64
+	select {
+	case res := <-waitC:
+		exitCode = res.StatusCode
+	case err := <-errC:
+		return "", "", 0, fmt.Errorf("docker wait: %w", err)
+	}
+
+	logs, err := c.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", "", 0, fmt.Errorf("docker logs: %w", err)
+	}
+	defer logs.Close()
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return "", "", 0, fmt.Errorf("docker logs: %w", err)
+	}
+	return stdout.String(), stderr.String(), exitCode, nil
+}
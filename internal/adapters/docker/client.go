@@ -5,13 +5,21 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	registryauth "service-faas/internal/adapters/registry"
 	"service-faas/internal/config"
 	"service-faas/internal/core/functions" // Import the functions package
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
@@ -19,98 +27,265 @@ import (
 )
 
 type Client struct {
-	cli        *client.Client
-	lg         zerolog.Logger
-	cfg        config.Config
-	authHeader string
+	cli      *client.Client
+	lg       zerolog.Logger
+	cfg      config.Config
+	login    atomic.Pointer[harborLogin]
+	keychain *registryauth.Keychain
+	// inContainer is set when the manager itself runs in a container attached
+	// to the worker network, so workers are reachable by container name.
+	inContainer bool
 }
 
 // ✅ FIX: The local RunResult struct is removed.
 
-func New(cfg config.Config, lg zerolog.Logger) (*Client, error) {
-	// ... (constructor remains the same)
+// New connects to the Docker daemon. Images are pulled with the keychain's
+// login for their registry unless a function has tenant credentials.
+func New(cfg config.Config, lg zerolog.Logger, keychain *registryauth.Keychain) (*Client, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
 	}
 
-	c := &Client{cli: cli, cfg: cfg, lg: lg.With().Str("adapter", "docker").Logger()}
+	c := &Client{cli: cli, cfg: cfg, lg: lg.With().Str("adapter", "docker").Logger(), keychain: keychain}
 
+	if err := c.SetRegistryLogin(cfg.HarborUser, cfg.HarborPass); err != nil {
+		return nil, err
+	}
 	if cfg.HarborUser != "" && cfg.HarborPass != "" {
-		authConfig := registry.AuthConfig{
-			Username:      cfg.HarborUser,
-			Password:      cfg.HarborPass,
-			ServerAddress: cfg.HarborURL,
-		}
-		encodedJSON, err := json.Marshal(authConfig)
-		if err != nil {
-			return nil, fmt.Errorf("marshal auth config: %w", err)
-		}
-		c.authHeader = base64.URLEncoding.EncodeToString(encodedJSON)
 		c.lg.Info().Str("registry", cfg.HarborURL).Msg("configured Harbor registry authentication")
 	}
 
+	if err := c.ensureNetwork(context.Background()); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
 // ✅ FIX: The return type is changed to *functions.RunResult
-func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath string) (*functions.RunResult, error) {
-	name := "faas-worker-" + funcID
+func (c *Client) RunWorker(ctx context.Context, spec functions.WorkerSpec) (*functions.RunResult, error) {
+	funcID := spec.FunctionID
 
-	if err := c.ensureImage(ctx, c.cfg.WorkerImage); err != nil {
+	// Custom images are always pulled so a tenant cannot run another tenant's
+	// private image just because it is cached on this host.
+	if err := c.ensureImage(ctx, spec.Image, spec.RegistryAuth, spec.Image != c.cfg.WorkerImage, spec.Pulling); err != nil {
 		return nil, err
 	}
 
-	_ = c.cli.ContainerRemove(ctx, name, container.RemoveOptions{Force: true})
+	if err := c.removeWorkers(ctx, funcID); err != nil {
+		return nil, err
+	}
+	result, err := c.startWorker(ctx, spec, replicaName)
+	if err != nil {
+		_ = c.removeWorkers(ctx, funcID)
+		return nil, err
+	}
+	return result, nil
+}
+
+// startWorker starts the containers of a worker for spec, naming the i-th
+// replica nameOf(functionID, i). The image must be on the host.
+func (c *Client) startWorker(ctx context.Context, spec functions.WorkerSpec, nameOf func(string, int) string) (*functions.RunResult, error) {
+	funcID, codePath, handlerPath := spec.FunctionID, spec.CodePath, spec.HandlerPath
+
+	// Code kept in object storage is cached on this host for the bind mount.
+	if spec.CodeURL != "" {
+		dir, err := c.cacheCode(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		codePath = dir
+	}
+
+	env := []string{"HANDLER_FUNCTION=" + handlerPath, "PORT=" + strconv.Itoa(c.cfg.WorkerPort)}
+	for _, name := range slices.Sorted(maps.Keys(spec.Env)) {
+		env = append(env, name+"="+spec.Env[name])
+	}
+	if spec.Identity {
+		env = append(env, "FAAS_IDENTITY_TOKEN_FILE=/app/function/"+identityFile)
+	}
+	if spec.DependencyLock != "" && !spec.Built {
+		env = append(env, "REQUIREMENTS_LOCK=/app/function/"+spec.DependencyLock)
+	}
+	scheme := "http"
+	if spec.TLS != nil {
+		tlsEnv, err := writeWorkerTLS(codePath, spec.TLS)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, tlsEnv...)
+		scheme = "https"
+	}
+
+	replicas := max(spec.Replicas, 1)
+	result := &functions.RunResult{}
+	for i := range replicas {
+		name := nameOf(funcID, i)
+		id, hostPort, err := c.startReplica(ctx, spec, name, codePath, env)
+		if err != nil {
+			return nil, err
+		}
+		endpoint := c.workerEndpoint(scheme, name, hostPort)
+		c.lg.Info().
+			Str("container_id", id).
+			Str("function_id", funcID).
+			Int("host_port", hostPort).
+			Str("endpoint", endpoint).
+			Msg("worker container started")
+		if i == 0 {
+			result.ContainerID, result.HostPort, result.Endpoint = id, hostPort, endpoint
+		}
+		if replicas > 1 {
+			result.Endpoints = append(result.Endpoints, endpoint)
+		}
+	}
+	return result, nil
+}
 
+// startReplica creates and starts one worker container and returns its ID
+// and the host port its worker port is published on.
+func (c *Client) startReplica(ctx context.Context, spec functions.WorkerSpec, name, codePath string, env []string) (string, int, error) {
 	resp, err := c.cli.ContainerCreate(ctx,
 		&container.Config{
-			Image: c.cfg.WorkerImage,
-			Env: []string{
-				"HANDLER_FUNCTION=" + handlerPath,
-			},
-			ExposedPorts: nat.PortSet{"8000/tcp": struct{}{}},
+			Image:        spec.Image,
+			Env:          env,
+			ExposedPorts: nat.PortSet{c.workerPort(): struct{}{}},
+			Labels:       spec.Labels,
 		},
 		&container.HostConfig{
+			// Built images hold the code too; the mount still delivers the
+			// identity token and TLS files written next to it.
 			Binds: []string{fmt.Sprintf("%s:/app/function", codePath)},
 			PortBindings: nat.PortMap{
-				"8000/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: ""}},
+				c.workerPort(): []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: ""}},
 			},
 		},
-		nil, nil, name,
+		&network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				c.cfg.DockerNetwork: {Aliases: []string{name}},
+			},
+		},
+		nil, name,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("docker create: %w", err)
+		return "", 0, fmt.Errorf("docker create: %w", err)
 	}
 
 	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return nil, fmt.Errorf("docker start: %w", err)
+		return "", 0, fmt.Errorf("docker start: %w", err)
 	}
 
-	inspect, err := c.cli.ContainerInspect(ctx, resp.ID)
+	hostPort, err := c.hostPort(ctx, resp.ID)
 	if err != nil {
-		return nil, fmt.Errorf("docker inspect: %w", err)
+		return "", 0, err
 	}
-	hostPortStr := inspect.NetworkSettings.Ports["8000/tcp"][0].HostPort
+	return resp.ID, hostPort, nil
+}
+
+// hostPort returns the host port a worker container's worker port is
+// published on.
+func (c *Client) hostPort(ctx context.Context, containerID string) (int, error) {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return 0, fmt.Errorf("docker inspect: %w", err)
+	}
+	hostPortStr := inspect.NetworkSettings.Ports[c.workerPort()][0].HostPort
 	hostPort, _ := strconv.Atoi(hostPortStr)
+	return hostPort, nil
+}
+
+// cacheCode downloads the handler into the function's directory under
+// FunctionStorageDir and returns that directory.
+func (c *Client) cacheCode(ctx context.Context, spec functions.WorkerSpec) (string, error) {
+	code, err := spec.HandlerCode(ctx)
+	if err != nil {
+		return "", fmt.Errorf("docker fetch code: %w", err)
+	}
+	dir := filepath.Join(c.cfg.FunctionStorageDir, spec.FunctionID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("docker code dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "handler.py"), code, 0644); err != nil {
+		return "", fmt.Errorf("docker write code: %w", err)
+	}
+	return dir, nil
+}
 
-	c.lg.Info().
-		Str("container_id", resp.ID).
-		Str("function_id", funcID).
-		Int("host_port", hostPort).
-		Msg("worker container started")
+// workerPort is the container port workers listen on.
+func (c *Client) workerPort() nat.Port {
+	return nat.Port(fmt.Sprintf("%d/tcp", c.cfg.WorkerPort))
+}
 
-	// ✅ FIX: Return a *functions.RunResult struct
-	return &functions.RunResult{ContainerID: resp.ID, HostPort: hostPort}, nil
+// workerEndpoint picks how the manager reaches a worker: through an explicit
+// Docker host address, by container name on the shared network when the
+// manager runs in a container itself, or via the published port on localhost.
+func (c *Client) workerEndpoint(scheme, name string, hostPort int) string {
+	switch {
+	case c.cfg.DockerWorkerHost != "":
+		return fmt.Sprintf("%s://%s:%d", scheme, c.cfg.DockerWorkerHost, hostPort)
+	case c.inContainer:
+		return fmt.Sprintf("%s://%s:%d", scheme, name, c.cfg.WorkerPort)
+	default:
+		return fmt.Sprintf("%s://localhost:%d", scheme, hostPort)
+	}
 }
 
-// ... (StopAndRemoveContainer and ensureImage methods remain the same)
+// ensureNetwork creates the user-defined bridge network workers are attached
+// to and, when the manager runs in a container, connects the manager to it.
+func (c *Client) ensureNetwork(ctx context.Context) error {
+	_, err := c.cli.NetworkInspect(ctx, c.cfg.DockerNetwork, network.InspectOptions{})
+	if client.IsErrNotFound(err) {
+		_, err = c.cli.NetworkCreate(ctx, c.cfg.DockerNetwork, network.CreateOptions{
+			Driver: "bridge",
+			Labels: map[string]string{"app": "faas-worker"},
+		})
+		if err == nil {
+			c.lg.Info().Str("network", c.cfg.DockerNetwork).Msg("created worker network")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("docker network %s: %w", c.cfg.DockerNetwork, err)
+	}
+
+	if _, err := os.Stat("/.dockerenv"); err != nil {
+		return nil
+	}
+	// Inside a container the hostname defaults to the container ID.
+	self, err := os.Hostname()
+	if err != nil {
+		return nil
+	}
+	err = c.cli.NetworkConnect(ctx, c.cfg.DockerNetwork, self, nil)
+	if err != nil {
+		inspect, inspectErr := c.cli.ContainerInspect(ctx, self)
+		if inspectErr != nil || inspect.NetworkSettings == nil || inspect.NetworkSettings.Networks[c.cfg.DockerNetwork] == nil {
+			c.lg.Warn().Err(err).Str("network", c.cfg.DockerNetwork).
+				Msg("could not attach manager to worker network, routing via published ports")
+			return nil
+		}
+	}
+	c.inContainer = true
+	return nil
+}
+
+// StopAndRemoveContainer removes a container and, for a worker's first
+// container, the worker's other replicas.
 func (c *Client) StopAndRemoveContainer(ctx context.Context, containerID string) error {
 	if containerID == "" {
 		return nil
 	}
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	if err == nil {
+		if funcID, ok := workerFunction(strings.TrimPrefix(inspect.Name, "/")); ok {
+			return c.removeWorkers(ctx, funcID)
+		}
+	}
 	c.lg.Info().Str("container_id", containerID).Msg("stopping and removing container")
-	err := c.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
+	err = c.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
 		Force:         true,
 		RemoveVolumes: true,
 	})
@@ -120,22 +295,154 @@ func (c *Client) StopAndRemoveContainer(ctx context.Context, containerID string)
 	return nil
 }
 
-func (c *Client) ensureImage(ctx context.Context, img string) error {
-	_, _, err := c.cli.ImageInspectWithRaw(ctx, img)
-	if err == nil {
-		return nil
+// replicaName is the name of a function's i-th worker container. The first
+// keeps the plain worker name, so a single worker is named as before.
+func replicaName(functionID string, i int) string {
+	if i == 0 {
+		return workerPrefix + functionID
+	}
+	return workerPrefix + functionID + "-" + strconv.Itoa(i)
+}
+
+// workerFunction returns the function a worker container belongs to, by the
+// container's name. Function IDs hold no dashes.
+func workerFunction(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, workerPrefix)
+	if !ok {
+		return "", false
 	}
-	if !client.IsErrNotFound(err) {
-		return fmt.Errorf("image inspect: %w", err)
+	funcID, _, _ := strings.Cut(rest, "-")
+	return funcID, true
+}
+
+// listWorkers returns the containers of a function's worker, running or
+// not, ordered by replica.
+func (c *Client) listWorkers(ctx context.Context, functionID string) ([]container.Summary, error) {
+	return c.listReplicas(ctx, functionID, "")
+}
+
+// listReplicas returns the containers of a function whose names end in
+// suffix after the replica number, ordered by replica.
+func (c *Client) listReplicas(ctx context.Context, functionID, suffix string) ([]container.Summary, error) {
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", "^/"+workerPrefix+functionID+"(-[0-9]+)?"+suffix+"$")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker list: %w", err)
+	}
+	replica := func(ctr container.Summary) int {
+		if len(ctr.Names) == 0 {
+			return 0
+		}
+		name := strings.TrimSuffix(ctr.Names[0], suffix)
+		_, i, _ := strings.Cut(strings.TrimPrefix(name, "/"+workerPrefix), "-")
+		n, _ := strconv.Atoi(i)
+		return n
 	}
+	slices.SortFunc(containers, func(a, b container.Summary) int { return replica(a) - replica(b) })
+	return containers, nil
+}
 
+// removeWorkers removes all containers of a function's worker.
+func (c *Client) removeWorkers(ctx context.Context, functionID string) error {
+	containers, err := c.listWorkers(ctx, functionID)
+	if err != nil {
+		return err
+	}
+	for _, ctr := range containers {
+		c.lg.Info().Str("container_id", ctr.ID).Str("function_id", functionID).Msg("stopping and removing container")
+		err := c.cli.ContainerRemove(ctx, ctr.ID, container.RemoveOptions{
+			Force:         true,
+			RemoveVolumes: true,
+		})
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureImage pulls an image that is not on the host yet, or always when
+// alwaysPull is set, calling pulling, if set, when the pull starts. Tenant
+// credentials in auth take precedence over the keychain's login for the
+// image's registry. A failed pull is returned as a *functions.ImagePullError.
+func (c *Client) ensureImage(ctx context.Context, img string, auth *functions.RegistryAuth, alwaysPull bool, pulling func()) error {
+	if !alwaysPull {
+		_, _, err := c.cli.ImageInspectWithRaw(ctx, img)
+		if err == nil {
+			return nil
+		}
+		if !client.IsErrNotFound(err) {
+			return fmt.Errorf("image inspect: %w", err)
+		}
+	}
+
+	if auth == nil {
+		var err error
+		if auth, err = c.keychain.ImageCredentials(ctx, img); err != nil {
+			return &functions.ImagePullError{Image: img, Reason: functions.PullFailed, Err: err}
+		}
+	}
+	var authHeader string
+	if auth != nil {
+		header, err := encodeAuth(auth.Server, auth.Username, auth.Password)
+		if err != nil {
+			return err
+		}
+		authHeader = header
+	}
+
+	if pulling != nil {
+		pulling()
+	}
 	c.lg.Info().Str("image", img).Msg("pulling image from registry")
-	rc, err := c.cli.ImagePull(ctx, img, image.PullOptions{RegistryAuth: c.authHeader})
+	rc, err := c.cli.ImagePull(ctx, img, image.PullOptions{RegistryAuth: authHeader})
+	if err == nil {
+		defer rc.Close()
+		err = c.followPull(img, rc)
+	}
 	if err != nil {
-		return fmt.Errorf("image pull: %w", err)
+		c.lg.Error().Err(err).Str("image", img).Msg("image pull failed")
+		return &functions.ImagePullError{Image: img, Reason: pullFailureReason(err), Err: err}
 	}
-	defer rc.Close()
-	_, _ = io.Copy(io.Discard, rc)
+	return nil
+}
+
+// encodeAuth builds the base64 encoded X-Registry-Auth value for a login.
+func encodeAuth(server, username, password string) (string, error) {
+	encodedJSON, err := json.Marshal(registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: server,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal auth config: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(encodedJSON), nil
+}
 
+// harborLogin is the global Harbor login; header is empty without one.
+type harborLogin struct {
+	user, password string
+	header         string
+}
+
+// SetRegistryLogin replaces the Harbor login used for pulls, pushes and
+// Harbor API calls that start from now on. An empty user or password
+// removes it.
+func (c *Client) SetRegistryLogin(user, password string) error {
+	if err := c.keychain.SetRegistryLogin(user, password); err != nil {
+		return err
+	}
+	login := &harborLogin{user: user, password: password}
+	if user != "" && password != "" {
+		header, err := encodeAuth(c.cfg.HarborURL, user, password)
+		if err != nil {
+			return err
+		}
+		login.header = header
+	}
+	c.login.Store(login)
 	return nil
 }
@@ -0,0 +1,65 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"service-faas/internal/core/functions"
+	"strings"
+)
+
+// ResolveDependencies runs pip's resolver in a short-lived container of the
+// worker image, with network access to reach the package index, and returns
+// its installation report. Nothing is installed.
+func (c *Client) ResolveDependencies(ctx context.Context, spec functions.DependencySpec) ([]byte, error) {
+	if err := os.MkdirAll(c.cfg.FunctionStorageDir, 0755); err != nil {
+		return nil, fmt.Errorf("docker report dir: %w", err)
+	}
+	out, err := os.MkdirTemp(c.cfg.FunctionStorageDir, "resolve-")
+	if err != nil {
+		return nil, fmt.Errorf("docker report dir: %w", err)
+	}
+	defer os.RemoveAll(out)
+	// The container may run as any user.
+	if err := os.Chmod(out, 0777); err != nil {
+		return nil, fmt.Errorf("docker report dir: %w", err)
+	}
+
+	run := oneShot{
+		image: spec.Image,
+		auth:  spec.RegistryAuth,
+		entrypoint: []string{"python", "-m", "pip", "install", "--dry-run", "--ignore-installed", "--quiet",
+			"--report", "/out/report.json", "-r", "/app/function/requirements.txt"},
+		binds: []string{
+			fmt.Sprintf("%s:/app/function:ro", spec.CodePath),
+			fmt.Sprintf("%s:/out", out),
+		},
+	}
+	_, stderr, exitCode, err := c.runOnce(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("%w: pip exited with status %d: %s", functions.ErrInvalidArgument, exitCode, lastLines(stderr, 5))
+	}
+	report, err := os.ReadFile(filepath.Join(out, "report.json"))
+	if err != nil {
+		return nil, fmt.Errorf("read pip report: %w", err)
+	}
+	return report, nil
+}
+
+// lastLines returns the last n non-empty lines of s, joined by "; ".
+func lastLines(s string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "; ")
+}
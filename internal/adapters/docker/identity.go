@@ -0,0 +1,29 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// identityFile is the token path relative to the function's code directory,
+// which is bind-mounted into the worker at /app/function.
+const identityFile = ".identity/token"
+
+// PublishIdentity writes the token into the function's code directory. The
+// file is replaced atomically so workers never read a partial token.
+func (c *Client) PublishIdentity(ctx context.Context, functionID, token string) error {
+	path := filepath.Join(c.cfg.FunctionStorageDir, functionID, identityFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("docker identity dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(token), 0644); err != nil {
+		return fmt.Errorf("docker identity write: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("docker identity rename: %w", err)
+	}
+	return nil
+}
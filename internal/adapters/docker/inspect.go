@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// InspectWorker looks the function's worker container up by name. Workers
+// run without a restart policy, so a container that is not running has
+// died, unless it is being removed.
+func (c *Client) InspectWorker(ctx context.Context, functionID string) (*functions.WorkerState, error) {
+	name := workerPrefix + functionID
+	inspect, err := c.cli.ContainerInspect(ctx, name)
+	if client.IsErrNotFound(err) || (err == nil && inspect.State != nil && inspect.State.Status == "removing") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("docker inspect: %w", err)
+	}
+	state := &functions.WorkerState{
+		ContainerID: inspect.ID,
+		Status:      functions.WorkerStatus{DesiredReplicas: 1, ReadyReplicas: 1, Restarts: inspect.RestartCount},
+	}
+	if s := inspect.State; s != nil && !s.Running {
+		state.Status.ReadyReplicas = 0
+		state.Failed = true
+		state.Reason = fmt.Sprintf("container %s with exit code %d", s.Status, s.ExitCode)
+		if s.OOMKilled {
+			state.Reason += ", out of memory"
+		}
+		return state, nil
+	}
+	if inspect.NetworkSettings != nil {
+		if bindings := inspect.NetworkSettings.Ports[c.workerPort()]; len(bindings) > 0 {
+			state.HostPort, _ = strconv.Atoi(bindings[0].HostPort)
+		}
+	}
+	scheme := "http"
+	if inspect.Config != nil && slices.ContainsFunc(inspect.Config.Env, func(e string) bool {
+		return strings.HasPrefix(e, "WORKER_TLS_CERT_FILE=")
+	}) {
+		scheme = "https"
+	}
+	if state.HostPort != 0 {
+		state.Endpoint = c.workerEndpoint(scheme, name, state.HostPort)
+	}
+	if err := c.inspectReplicas(ctx, functionID, scheme, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// inspectReplicas counts the replicas of a worker started with more than
+// one and lists the endpoints of those still running. Replicas other than
+// the first may die without failing the worker; they drop out of Endpoints.
+func (c *Client) inspectReplicas(ctx context.Context, functionID, scheme string, state *functions.WorkerState) error {
+	containers, err := c.listWorkers(ctx, functionID)
+	if err != nil || len(containers) < 2 {
+		return err
+	}
+	state.Status.DesiredReplicas, state.Status.ReadyReplicas = len(containers), 0
+	state.Endpoints = []string{}
+	for _, ctr := range containers {
+		if ctr.State != container.StateRunning || len(ctr.Names) == 0 {
+			continue
+		}
+		for _, p := range ctr.Ports {
+			if int(p.PrivatePort) == c.cfg.WorkerPort && p.PublicPort != 0 {
+				state.Status.ReadyReplicas++
+				state.Endpoints = append(state.Endpoints, c.workerEndpoint(scheme, strings.TrimPrefix(ctr.Names[0], "/"), int(p.PublicPort)))
+				break
+			}
+		}
+	}
+	return nil
+}
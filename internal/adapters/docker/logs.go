@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// WorkerLogs reads the logs of the function's containers, one per replica,
+// all at once.
+func (c *Client) WorkerLogs(ctx context.Context, functionID string, opts functions.LogOptions, emit func(functions.LogLine) error) error {
+	containers, err := c.listWorkers(ctx, functionID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(containers))
+	for _, ctr := range containers {
+		go func() { errs <- c.containerLogs(ctx, ctr, opts, emit) }()
+	}
+	var first error
+	for range containers {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
+
+func (c *Client) containerLogs(ctx context.Context, ctr container.Summary, opts functions.LogOptions, emit func(functions.LogLine) error) error {
+	name := ctr.ID
+	if len(ctr.Names) > 0 {
+		name = strings.TrimPrefix(ctr.Names[0], "/")
+	}
+	logOpts := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Follow:     opts.Follow,
+		Tail:       strconv.Itoa(opts.Tail),
+	}
+	if !opts.Since.IsZero() {
+		logOpts.Since = opts.Since.Format(time.RFC3339Nano)
+	}
+	logs, err := c.cli.ContainerLogs(ctx, ctr.ID, logOpts)
+	if err != nil {
+		return fmt.Errorf("docker logs: %w", err)
+	}
+	defer logs.Close()
+
+	stdout := &logWriter{instance: name, stream: "stdout", emit: emit}
+	stderr := &logWriter{instance: name, stream: "stderr", emit: emit}
+	if _, err := stdcopy.StdCopy(stdout, stderr, logs); err != nil {
+		return fmt.Errorf("docker logs: %w", err)
+	}
+	if err := stdout.flush(); err != nil {
+		return err
+	}
+	return stderr.flush()
+}
+
+// logWriter splits one output stream of a container into log lines.
+type logWriter struct {
+	instance string
+	stream   string
+	emit     func(functions.LogLine) error
+	buf      []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.emitLine(line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// flush emits a last line that did not end in a newline.
+func (w *logWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := string(w.buf)
+	w.buf = nil
+	return w.emitLine(line)
+}
+
+func (w *logWriter) emitLine(raw string) error {
+	t, text := functions.ParseLogLine(raw)
+	return w.emit(functions.LogLine{Instance: w.instance, Time: t, Stream: w.stream, Line: text})
+}
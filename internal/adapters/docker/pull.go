@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// pullProgressEvery is how often the progress of a pull is logged.
+const pullProgressEvery = 5 * time.Second
+
+// followPull reads the message stream of an image pull to its end, logging
+// its progress, and returns the error the pull failed with, which the daemon
+// reports in the stream rather than as the response's status.
+func (c *Client) followPull(img string, stream io.Reader) error {
+	lg := c.lg.With().Str("image", img).Logger()
+	dec := json.NewDecoder(stream)
+	layers := map[string]jsonmessage.JSONProgress{}
+	lastReport := time.Now()
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		switch {
+		case msg.ID != "" && msg.Progress != nil && msg.Progress.Total > 0:
+			layers[msg.ID] = *msg.Progress
+		case msg.ID != "":
+			// Per-layer steps: waiting, verifying, extracting, complete.
+			lg.Debug().Str("layer", msg.ID).Msg(msg.Status)
+			continue
+		default:
+			// Overall status, e.g. the digest or "Downloaded newer image".
+			if msg.Status != "" {
+				lg.Info().Msg(msg.Status)
+			}
+			continue
+		}
+		if time.Since(lastReport) < pullProgressEvery {
+			continue
+		}
+		lastReport = time.Now()
+		var current, total int64
+		for _, p := range layers {
+			current += p.Current
+			total += p.Total
+		}
+		lg.Info().Int("layers", len(layers)).Int64("bytes", current).Int64("total_bytes", total).
+			Msg("pulling image")
+	}
+}
+
+// pullFailureReason classifies a pull error by the registry's message, the
+// only detail of failures reported in the pull stream.
+func pullFailureReason(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "unauthorized"), strings.Contains(msg, "denied"),
+		strings.Contains(msg, "authentication required"):
+		return functions.PullUnauthorized
+	case client.IsErrNotFound(err), strings.Contains(msg, "not found"), strings.Contains(msg, "manifest unknown"):
+		return functions.PullNotFound
+	}
+	return functions.PullFailed
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// ListWorkerResources lists the worker containers, running or not, by their
+// name prefix.
+func (c *Client) ListWorkerResources(ctx context.Context) ([]functions.WorkerResource, error) {
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", workerPrefix)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker list: %w", err)
+	}
+	resources := []functions.WorkerResource{}
+	for _, ctr := range containers {
+		if len(ctr.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(ctr.Names[0], "/")
+		funcID, ok := workerFunction(name)
+		if !ok {
+			continue
+		}
+		resources = append(resources, functions.WorkerResource{
+			Kind:       functions.ResourceContainer,
+			Name:       name,
+			ID:         ctr.ID,
+			FunctionID: funcID,
+			Status:     ctr.Status,
+			CreatedAt:  time.Unix(ctr.Created, 0).UTC(),
+		})
+	}
+	return resources, nil
+}
+
+// DeleteWorkerResource force-removes a worker container.
+func (c *Client) DeleteWorkerResource(ctx context.Context, res functions.WorkerResource) error {
+	if res.Kind != functions.ResourceContainer {
+		return fmt.Errorf("%w: docker has no worker %s resources", functions.ErrInvalidArgument, res.Kind)
+	}
+	c.lg.Info().Str("container_id", res.ID).Str("function_id", res.FunctionID).Msg("stopping and removing container")
+	err := c.cli.ContainerRemove(ctx, res.ID, container.RemoveOptions{
+		Force:         true,
+		RemoveVolumes: true,
+	})
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// stagedSuffix ends the names of a worker's containers while they are
+// staged, so they run next to the function's current worker.
+const stagedSuffix = "-staged"
+
+// stagedName is the name of a staged worker's i-th container.
+func stagedName(functionID string, i int) string {
+	return replicaName(functionID, i) + stagedSuffix
+}
+
+// StageWorker starts a worker for spec next to the function's current one,
+// replacing a staged worker left over from an earlier rollout.
+func (c *Client) StageWorker(ctx context.Context, spec functions.WorkerSpec) (*functions.RunResult, error) {
+	if err := c.ensureImage(ctx, spec.Image, spec.RegistryAuth, spec.Image != c.cfg.WorkerImage, spec.Pulling); err != nil {
+		return nil, err
+	}
+	if err := c.DiscardWorker(ctx, spec.FunctionID); err != nil {
+		return nil, err
+	}
+	result, err := c.startWorker(ctx, spec, stagedName)
+	if err != nil {
+		_ = c.DiscardWorker(ctx, spec.FunctionID)
+		return nil, err
+	}
+	return result, nil
+}
+
+// PromoteWorker removes the function's current containers and renames the
+// staged ones to take their names.
+func (c *Client) PromoteWorker(ctx context.Context, functionID string) (*functions.RunResult, error) {
+	staged, err := c.listReplicas(ctx, functionID, stagedSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(staged) == 0 {
+		return nil, fmt.Errorf("function %s has no staged worker", functionID)
+	}
+	if err := c.removeWorkers(ctx, functionID); err != nil {
+		return nil, err
+	}
+
+	result := &functions.RunResult{}
+	for i, ctr := range staged {
+		inspect, err := c.cli.ContainerInspect(ctx, ctr.ID)
+		if err != nil {
+			return nil, fmt.Errorf("docker inspect: %w", err)
+		}
+		scheme := "http"
+		if slices.ContainsFunc(inspect.Config.Env, func(e string) bool { return strings.HasPrefix(e, "WORKER_TLS_CERT_FILE=") }) {
+			scheme = "https"
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(inspect.Name, "/"), stagedSuffix)
+		if err := c.cli.ContainerRename(ctx, ctr.ID, name); err != nil {
+			return nil, fmt.Errorf("docker rename: %w", err)
+		}
+		hostPort, err := c.hostPort(ctx, ctr.ID)
+		if err != nil {
+			return nil, err
+		}
+		endpoint := c.workerEndpoint(scheme, name, hostPort)
+		c.lg.Info().
+			Str("container_id", ctr.ID).
+			Str("function_id", functionID).
+			Str("endpoint", endpoint).
+			Msg("staged worker container promoted")
+		if i == 0 {
+			result.ContainerID, result.HostPort, result.Endpoint = ctr.ID, hostPort, endpoint
+		}
+		if len(staged) > 1 {
+			result.Endpoints = append(result.Endpoints, endpoint)
+		}
+	}
+	return result, nil
+}
+
+// DiscardWorker removes the function's staged containers.
+func (c *Client) DiscardWorker(ctx context.Context, functionID string) error {
+	staged, err := c.listReplicas(ctx, functionID, stagedSuffix)
+	if err != nil {
+		return err
+	}
+	for _, ctr := range staged {
+		c.lg.Info().Str("container_id", ctr.ID).Str("function_id", functionID).Msg("removing staged worker container")
+		err := c.cli.ContainerRemove(ctx, ctr.ID, container.RemoveOptions{
+			Force:         true,
+			RemoveVolumes: true,
+		})
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"service-faas/internal/core/functions"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// eventWindow is how far back DescribeWorker looks for container events, and
+// maxEvents how many of the latest it returns.
+const (
+	eventWindow = time.Hour
+	maxEvents   = 20
+)
+
+// DescribeWorker reports the state of the worker's containers, one per
+// replica, and their container events of the last hour.
+func (c *Client) DescribeWorker(ctx context.Context, functionID string) (*functions.WorkerDescription, error) {
+	containers, err := c.listWorkers(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	desc := &functions.WorkerDescription{
+		WorkerStatus: functions.WorkerStatus{DesiredReplicas: len(containers)},
+		Instances:    []functions.WorkerInstance{},
+		Events:       []functions.WorkerLogEntry{},
+	}
+	ids := make([]string, 0, len(containers))
+	for _, ctr := range containers {
+		inspect, err := c.cli.ContainerInspect(ctx, ctr.ID)
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("docker inspect: %w", err)
+		}
+		ids = append(ids, inspect.ID)
+		desc.Restarts += inspect.RestartCount
+		s := inspect.State
+		if s == nil {
+			continue
+		}
+		name := strings.TrimPrefix(inspect.Name, "/")
+		cs := functions.ContainerState{Name: name, Restarts: inspect.RestartCount, StartedAt: dockerTime(s.StartedAt)}
+		switch {
+		case s.Running:
+			cs.State = "running"
+			cs.Ready = s.Health == nil || s.Health.Status == "healthy"
+		case s.Status == "created" || s.Status == "restarting":
+			cs.State = "waiting"
+			cs.Reason = s.Status
+		default:
+			cs.State = "terminated"
+			cs.Reason = "Error"
+			if s.ExitCode == 0 {
+				cs.Reason = "Completed"
+			}
+			if s.OOMKilled {
+				cs.Reason = "OOMKilled"
+			}
+			cs.Message = s.Error
+			cs.ExitCode = &s.ExitCode
+			cs.FinishedAt = dockerTime(s.FinishedAt)
+		}
+		if cs.Ready {
+			desc.ReadyReplicas++
+		}
+		desc.Instances = append(desc.Instances, functions.WorkerInstance{
+			Name:       name,
+			Phase:      s.Status,
+			StartedAt:  cs.StartedAt,
+			Containers: []functions.ContainerState{cs},
+		})
+	}
+	if len(ids) == 0 {
+		return desc, nil
+	}
+
+	desc.Events, err = c.containerEvents(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// containerEvents returns the latest events of containers within
+// eventWindow, oldest first.
+func (c *Client) containerEvents(ctx context.Context, containerIDs ...string) ([]functions.WorkerLogEntry, error) {
+	now := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	containers := filters.NewArgs()
+	for _, id := range containerIDs {
+		containers.Add("container", id)
+	}
+	messages, errs := c.cli.Events(ctx, events.ListOptions{
+		Since:   strconv.FormatInt(now.Add(-eventWindow).Unix(), 10),
+		Until:   strconv.FormatInt(now.Unix(), 10),
+		Filters: containers,
+	})
+	entries := []functions.WorkerLogEntry{}
+	for {
+		select {
+		case err := <-errs:
+			// The stream ends once the events up to Until are sent.
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("docker events: %w", err)
+			}
+			if len(entries) > maxEvents {
+				entries = entries[len(entries)-maxEvents:]
+			}
+			return entries, nil
+		case msg := <-messages:
+			entry := functions.WorkerLogEntry{
+				Time:   time.Unix(0, msg.TimeNano).UTC(),
+				Type:   string(msg.Action),
+				Object: msg.Actor.Attributes["name"],
+			}
+			if code := msg.Actor.Attributes["exitCode"]; code != "" {
+				entry.Message = "exit code " + code
+			}
+			entries = append(entries, entry)
+		}
+	}
+}
+
+// dockerTime parses a timestamp of a container's state. Docker reports
+// times it has not recorded as the zero time.
+func dockerTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// WorkerStats sums the stats of the worker's running containers. Docker
+// samples the CPU twice for them, about a second apart.
+func (c *Client) WorkerStats(ctx context.Context, functionID string) (*functions.Resources, error) {
+	containers, err := c.listWorkers(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	var usage *functions.Resources
+	for _, ctr := range containers {
+		if ctr.State != container.StateRunning {
+			continue
+		}
+		stats, err := c.containerStats(ctx, ctr.ID)
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if usage == nil {
+			usage = &functions.Resources{}
+		}
+		usage.CPUMillis += stats.CPUMillis
+		usage.MemoryBytes += stats.MemoryBytes
+	}
+	return usage, nil
+}
+
+// containerStats reads the CPU and memory use of one container.
+func (c *Client) containerStats(ctx context.Context, containerID string) (*functions.Resources, error) {
+	resp, err := c.cli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, fmt.Errorf("docker stats: %w", err)
+	}
+	defer resp.Body.Close()
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("decode docker stats: %w", err)
+	}
+
+	usage := &functions.Resources{MemoryBytes: int64(stats.MemoryStats.Usage)}
+	// Like `docker stats`, page cache that can be reclaimed is not counted.
+	if cache, ok := stats.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryStats.Usage {
+		usage.MemoryBytes -= int64(cache)
+	}
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		cpus := float64(stats.CPUStats.OnlineCPUs)
+		if cpus == 0 {
+			cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+		}
+		usage.CPUMillis = int64(cpuDelta / systemDelta * cpus * 1000)
+	}
+	return usage, nil
+}
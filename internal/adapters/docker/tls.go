@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"service-faas/internal/core/functions"
+)
+
+// tlsDir holds the worker's certificate material, relative to the function's
+// code directory (mounted at /app/function).
+const tlsDir = ".tls"
+
+// writeWorkerTLS stores the worker certificate next to the handler code and
+// returns the environment telling the worker where to find it.
+func writeWorkerTLS(codePath string, wt *functions.WorkerTLS) ([]string, error) {
+	dir := filepath.Join(codePath, tlsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("docker tls dir: %w", err)
+	}
+	files := map[string][]byte{
+		"tls.crt": wt.CertPEM,
+		"tls.key": wt.KeyPEM,
+		"ca.crt":  wt.CAPEM,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			return nil, fmt.Errorf("docker tls write %s: %w", name, err)
+		}
+	}
+	mounted := "/app/function/" + tlsDir
+	return []string{
+		"WORKER_TLS_CERT_FILE=" + mounted + "/tls.crt",
+		"WORKER_TLS_KEY_FILE=" + mounted + "/tls.key",
+		"WORKER_TLS_CLIENT_CA_FILE=" + mounted + "/ca.crt",
+	}, nil
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// WatchWorkers reports worker containers that exit. Out-of-memory kills end
+// in an exit too, which InspectWorker tells apart.
+func (c *Client) WatchWorkers(ctx context.Context, out chan<- functions.WorkerEvent) error {
+	messages, errs := c.cli.Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("event", string(events.ActionDie)),
+		),
+	})
+	for {
+		select {
+		case err := <-errs:
+			return fmt.Errorf("docker events: %w", err)
+		case msg := <-messages:
+			funcID, ok := workerFunction(msg.Actor.Attributes["name"])
+			if !ok {
+				continue
+			}
+			ev := functions.WorkerEvent{
+				FunctionID:  funcID,
+				ContainerID: msg.Actor.ID,
+				Reason:      "container exited with code " + msg.Actor.Attributes["exitCode"],
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
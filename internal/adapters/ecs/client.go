@@ -0,0 +1,348 @@
+package ecs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"service-faas/internal/config"
+	"service-faas/internal/core/functions"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/rs/zerolog"
+)
+
+const (
+	appName        = "faas-worker"
+	codeVolume     = "function"
+	loaderName     = "code-loader"
+	startTimeout   = 5 * time.Minute
+	pollInterval   = 5 * time.Second
+	taskCPU        = "256"
+	taskMemory     = "512"
+	handlerCodeEnv = "HANDLER_CODE"
+)
+
+type Client struct {
+	ecs *ecs.Client
+	lg  zerolog.Logger
+	cfg config.Config
+}
+
+func New(cfg config.Config, lg zerolog.Logger) (*Client, error) {
+	if len(cfg.ECSSubnets) == 0 {
+		return nil, errors.New("ECS_SUBNETS is required in ecs mode")
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{}
+	if cfg.AWSRegion != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.AWSRegion))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	return &Client{
+		ecs: ecs.NewFromConfig(awsCfg),
+		lg:  lg.With().Str("adapter", "ecs").Logger(),
+		cfg: cfg,
+	}, nil
+}
+
+// RunWorker registers a task definition for the function, runs it as a
+// Fargate service and waits for a task to come up so its ENI address can be
+// used for routing.
+func (c *Client) RunWorker(ctx context.Context, spec functions.WorkerSpec) (*functions.RunResult, error) {
+	serviceName := appName + "-" + spec.FunctionID
+
+	if spec.TLS != nil {
+		return nil, fmt.Errorf("worker mTLS is not supported by the ecs orchestrator")
+	}
+
+	// ECS can only pull private images with credentials kept in Secrets
+	// Manager, so tenant logins cannot be passed through directly.
+	if spec.RegistryAuth != nil {
+		c.lg.Warn().
+			Str("function_id", spec.FunctionID).
+			Str("registry", spec.RegistryAuth.Server).
+			Msg("tenant registry credentials are not supported on ecs, pulling without them")
+	}
+
+	handlerCode, err := spec.HandlerCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handler code: %w", err)
+	}
+
+	taskDefARN, err := c.registerTaskDefinition(ctx, serviceName, spec.Image, spec.HandlerPath, handlerCode, spec.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.upsertService(ctx, serviceName, taskDefARN); err != nil {
+		return nil, err
+	}
+
+	ip, err := c.waitForTaskIP(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lg.Info().
+		Str("service", serviceName).
+		Str("task_definition", taskDefARN).
+		Str("task_ip", ip).
+		Msg("ecs worker service started")
+
+	return &functions.RunResult{
+		ContainerID: serviceName,
+		HostPort:    c.cfg.WorkerPort,
+		Endpoint:    fmt.Sprintf("http://%s:%d", ip, c.cfg.WorkerPort),
+	}, nil
+}
+
+// StopAndRemoveContainer deletes the function's ECS service and deregisters
+// every revision of its task definition family.
+func (c *Client) StopAndRemoveContainer(ctx context.Context, containerID string) error {
+	if containerID == "" {
+		return nil
+	}
+	serviceName := containerID
+
+	_, err := c.ecs.DeleteService(ctx, &ecs.DeleteServiceInput{
+		Cluster: aws.String(c.cfg.ECSCluster),
+		Service: aws.String(serviceName),
+		Force:   aws.Bool(true),
+	})
+	if err != nil && !isServiceMissing(err) {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+
+	paginator := ecs.NewListTaskDefinitionsPaginator(c.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(serviceName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list task definitions: %w", err)
+		}
+		for _, arn := range page.TaskDefinitionArns {
+			if _, err := c.ecs.DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{
+				TaskDefinition: aws.String(arn),
+			}); err != nil {
+				c.lg.Warn().Err(err).Str("task_definition", arn).Msg("failed to deregister task definition")
+			}
+		}
+	}
+
+	c.lg.Info().Str("service", serviceName).Msg("deleted ecs resources")
+	return nil
+}
+
+// registerTaskDefinition creates a new task definition revision. The handler
+// code is shipped to the task through a short-lived loader container that
+// writes it to a volume shared with the worker, since Fargate has no host
+// paths or ConfigMaps to mount from.
+func (c *Client) registerTaskDefinition(ctx context.Context, family, image, handlerPath string, handlerCode []byte, env map[string]string) (string, error) {
+	loader := types.ContainerDefinition{
+		Name:      aws.String(loaderName),
+		Image:     aws.String(c.cfg.ECSCodeLoaderImage),
+		Essential: aws.Bool(false),
+		Command: []string{
+			"sh", "-c",
+			fmt.Sprintf(`echo "$%s" | base64 -d > /app/function/handler.py`, handlerCodeEnv),
+		},
+		Environment: []types.KeyValuePair{
+			{Name: aws.String(handlerCodeEnv), Value: aws.String(base64.StdEncoding.EncodeToString(handlerCode))},
+		},
+		MountPoints: []types.MountPoint{
+			{SourceVolume: aws.String(codeVolume), ContainerPath: aws.String("/app/function")},
+		},
+	}
+
+	worker := types.ContainerDefinition{
+		Name:      aws.String(appName),
+		Image:     aws.String(image),
+		Essential: aws.Bool(true),
+		Environment: []types.KeyValuePair{
+			{Name: aws.String("HANDLER_FUNCTION"), Value: aws.String(handlerPath)},
+			{Name: aws.String("PORT"), Value: aws.String(strconv.Itoa(c.cfg.WorkerPort))},
+		},
+		PortMappings: []types.PortMapping{
+			{ContainerPort: aws.Int32(int32(c.cfg.WorkerPort)), Protocol: types.TransportProtocolTcp},
+		},
+		MountPoints: []types.MountPoint{
+			{SourceVolume: aws.String(codeVolume), ContainerPath: aws.String("/app/function"), ReadOnly: aws.Bool(true)},
+		},
+		DependsOn: []types.ContainerDependency{
+			{ContainerName: aws.String(loaderName), Condition: types.ContainerConditionSuccess},
+		},
+	}
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		worker.Environment = append(worker.Environment, types.KeyValuePair{Name: aws.String(name), Value: aws.String(env[name])})
+	}
+	if c.cfg.ECSRegistryCredentialARN != "" {
+		worker.RepositoryCredentials = &types.RepositoryCredentials{
+			CredentialsParameter: aws.String(c.cfg.ECSRegistryCredentialARN),
+		}
+	}
+
+	input := &ecs.RegisterTaskDefinitionInput{
+		Family:                  aws.String(family),
+		NetworkMode:             types.NetworkModeAwsvpc,
+		RequiresCompatibilities: []types.Compatibility{types.CompatibilityFargate},
+		Cpu:                     aws.String(taskCPU),
+		Memory:                  aws.String(taskMemory),
+		ContainerDefinitions:    []types.ContainerDefinition{loader, worker},
+		Volumes:                 []types.Volume{{Name: aws.String(codeVolume)}},
+	}
+	if c.cfg.ECSExecutionRoleARN != "" {
+		input.ExecutionRoleArn = aws.String(c.cfg.ECSExecutionRoleARN)
+	}
+
+	out, err := c.ecs.RegisterTaskDefinition(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to register task definition: %w", err)
+	}
+	return aws.ToString(out.TaskDefinition.TaskDefinitionArn), nil
+}
+
+// upsertService creates the Fargate service, or points an existing active
+// one at the new task definition (e.g. when the manager restarts functions).
+func (c *Client) upsertService(ctx context.Context, serviceName, taskDefARN string) error {
+	desc, err := c.ecs.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(c.cfg.ECSCluster),
+		Services: []string{serviceName},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	for _, svc := range desc.Services {
+		if aws.ToString(svc.Status) != "ACTIVE" {
+			continue
+		}
+		_, err := c.ecs.UpdateService(ctx, &ecs.UpdateServiceInput{
+			Cluster:            aws.String(c.cfg.ECSCluster),
+			Service:            aws.String(serviceName),
+			TaskDefinition:     aws.String(taskDefARN),
+			DesiredCount:       aws.Int32(1),
+			ForceNewDeployment: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update service: %w", err)
+		}
+		return nil
+	}
+
+	assignPublicIP := types.AssignPublicIpDisabled
+	if c.cfg.ECSAssignPublicIP {
+		assignPublicIP = types.AssignPublicIpEnabled
+	}
+	_, err = c.ecs.CreateService(ctx, &ecs.CreateServiceInput{
+		Cluster:        aws.String(c.cfg.ECSCluster),
+		ServiceName:    aws.String(serviceName),
+		TaskDefinition: aws.String(taskDefARN),
+		DesiredCount:   aws.Int32(1),
+		LaunchType:     types.LaunchTypeFargate,
+		NetworkConfiguration: &types.NetworkConfiguration{
+			AwsvpcConfiguration: &types.AwsVpcConfiguration{
+				Subnets:        c.cfg.ECSSubnets,
+				SecurityGroups: c.cfg.ECSSecurityGroups,
+				AssignPublicIp: assignPublicIP,
+			},
+		},
+		Tags: []types.Tag{
+			{Key: aws.String("app"), Value: aws.String(appName)},
+			{Key: aws.String("func"), Value: aws.String(serviceName[len(appName)+1:])},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	return nil
+}
+
+// waitForTaskIP polls the service until one of its tasks is RUNNING and
+// returns the private address of the task's elastic network interface.
+func (c *Client) waitForTaskIP(ctx context.Context, serviceName string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		ip, err := c.runningTaskIP(ctx, serviceName)
+		if err != nil {
+			return "", err
+		}
+		if ip != "" {
+			return ip, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for ecs task of %s: %w", serviceName, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) runningTaskIP(ctx context.Context, serviceName string) (string, error) {
+	list, err := c.ecs.ListTasks(ctx, &ecs.ListTasksInput{
+		Cluster:       aws.String(c.cfg.ECSCluster),
+		ServiceName:   aws.String(serviceName),
+		DesiredStatus: types.DesiredStatusRunning,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if len(list.TaskArns) == 0 {
+		return "", nil
+	}
+
+	tasks, err := c.ecs.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(c.cfg.ECSCluster),
+		Tasks:   list.TaskArns,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe tasks: %w", err)
+	}
+	for _, task := range tasks.Tasks {
+		if aws.ToString(task.LastStatus) != "RUNNING" {
+			continue
+		}
+		if ip := eniAddress(task); ip != "" {
+			return ip, nil
+		}
+	}
+	return "", nil
+}
+
+// eniAddress extracts the private IPv4 address from a task's ENI attachment.
+func eniAddress(task types.Task) string {
+	for _, att := range task.Attachments {
+		if aws.ToString(att.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		for _, detail := range att.Details {
+			if aws.ToString(detail.Name) == "privateIPv4Address" {
+				return aws.ToString(detail.Value)
+			}
+		}
+	}
+	return ""
+}
+
+func isServiceMissing(err error) bool {
+	var notFound *types.ServiceNotFoundException
+	var notActive *types.ServiceNotActiveException
+	return errors.As(err, &notFound) || errors.As(err, &notActive)
+}
@@ -0,0 +1,53 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// InspectWorker looks the function's service up. ECS replaces stopped tasks,
+// whose addresses change, so the endpoint is that of the running task. The
+// worker only counts as dead when nothing runs and its rollout failed.
+func (c *Client) InspectWorker(ctx context.Context, functionID string) (*functions.WorkerState, error) {
+	serviceName := appName + "-" + functionID
+	desc, err := c.ecs.DescribeServices(ctx, &ecs.DescribeServicesInput{
+		Cluster:  aws.String(c.cfg.ECSCluster),
+		Services: []string{serviceName},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service: %w", err)
+	}
+	for _, svc := range desc.Services {
+		if aws.ToString(svc.Status) != "ACTIVE" {
+			continue
+		}
+		state := &functions.WorkerState{
+			ContainerID: serviceName,
+			Status:      functions.WorkerStatus{DesiredReplicas: int(svc.DesiredCount), ReadyReplicas: int(svc.RunningCount)},
+		}
+		if svc.RunningCount == 0 {
+			for _, d := range svc.Deployments {
+				if d.RolloutState == types.DeploymentRolloutStateFailed {
+					state.Failed = true
+					state.Reason = "rollout failed: " + aws.ToString(d.RolloutStateReason)
+					return state, nil
+				}
+			}
+		}
+		ip, err := c.runningTaskIP(ctx, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		if ip != "" {
+			state.HostPort = c.cfg.WorkerPort
+			state.Endpoint = fmt.Sprintf("http://%s:%d", ip, c.cfg.WorkerPort)
+		}
+		return state, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,142 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"service-faas/internal/core/functions"
+	"strings"
+)
+
+// CodeStore keeps handler code on the manager's local disk, one directory per
+// function. Docker workers bind-mount that directory directly.
+type CodeStore struct {
+	dir string
+}
+
+func NewCodeStore(dir string) (*CodeStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create code dir: %w", err)
+	}
+	return &CodeStore{dir: dir}, nil
+}
+
+func (s *CodeStore) Put(_ context.Context, functionID string, code io.Reader) (string, error) {
+	dir := filepath.Join(s.dir, functionID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create function dir: %w", err)
+	}
+	file, err := os.Create(filepath.Join(dir, "handler.py"))
+	if err != nil {
+		return "", fmt.Errorf("create handler file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, code); err != nil {
+		return "", fmt.Errorf("save handler code: %w", err)
+	}
+	return dir, nil
+}
+
+// PutFile stores an additional file of a code bundle next to handler.py.
+func (s *CodeStore) PutFile(_ context.Context, functionID, name string, r io.Reader) error {
+	rel := filepath.FromSlash(name)
+	if !filepath.IsLocal(rel) {
+		return fmt.Errorf("bundle file %q escapes the function dir", name)
+	}
+	path := filepath.Join(s.dir, functionID, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create bundle dir: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create bundle file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("save bundle file: %w", err)
+	}
+	return nil
+}
+
+// Files lists the function's stored files. Hidden directories at the top of
+// the function dir are left out: the orchestrator keeps the worker's
+// credentials there, next to the code.
+func (s *CodeStore) Files(_ context.Context, functionID string) ([]string, error) {
+	root := filepath.Join(s.dir, functionID)
+	var names []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() {
+			if filepath.Dir(rel) == "." && strings.HasPrefix(rel, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list function files: %w", err)
+	}
+	return names, nil
+}
+
+// GetFile opens one of the function's stored files.
+func (s *CodeStore) GetFile(_ context.Context, functionID, name string) (io.ReadCloser, error) {
+	rel := filepath.FromSlash(name)
+	if !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("bundle file %q escapes the function dir", name)
+	}
+	f, err := os.Open(filepath.Join(s.dir, functionID, rel))
+	if err != nil {
+		return nil, fmt.Errorf("open bundle file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *CodeStore) Get(_ context.Context, functionID string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, functionID, "handler.py"))
+	if err != nil {
+		return nil, fmt.Errorf("open handler file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *CodeStore) Delete(_ context.Context, functionID string) error {
+	if err := os.RemoveAll(filepath.Join(s.dir, functionID)); err != nil {
+		return fmt.Errorf("delete function dir: %w", err)
+	}
+	return nil
+}
+
+func (s *CodeStore) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read code dir: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.dir, e.Name(), "handler.py")); err == nil {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (s *CodeStore) Source(_ context.Context, functionID string) (functions.CodeSource, error) {
+	return functions.CodeSource{Dir: filepath.Join(s.dir, functionID)}, nil
+}
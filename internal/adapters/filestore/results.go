@@ -0,0 +1,80 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+)
+
+// ResultStore keeps offloaded results on the manager's local disk. They are
+// served back through the manager's /results endpoint.
+type ResultStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewResultStore(dir, baseURL string) (*ResultStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create result dir: %w", err)
+	}
+	return &ResultStore{dir: dir, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+func (s *ResultStore) Put(_ context.Context, key string, data []byte) (*functions.ResultRef, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create result dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("write result: %w", err)
+	}
+	url, _, err := s.Link(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	return &functions.ResultRef{
+		Key:  key,
+		URL:  url,
+		Size: len(data),
+	}, nil
+}
+
+// Link returns the manager's URL for a stored result, which does not expire.
+func (s *ResultStore) Link(_ context.Context, key string) (string, *time.Time, error) {
+	return s.baseURL + "/results/" + key, nil, nil
+}
+
+func (s *ResultStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open result: %w", err)
+	}
+	return f, nil
+}
+
+// path resolves a key inside the directory of the function it belongs to,
+// rejecting keys that would escape it.
+func (s *ResultStore) path(key string) (string, error) {
+	cleaned, functionID, err := functions.CleanResultKey(key)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(s.dir, functionID)
+	path := filepath.Join(s.dir, filepath.FromSlash(cleaned))
+	if filepath.Dir(dir) != filepath.Clean(s.dir) || !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: invalid result key %q", functions.ErrInvalidArgument, key)
+	}
+	return path, nil
+}
@@ -0,0 +1,385 @@
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"service-faas/internal/config"
+	"service-faas/internal/core/functions"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	vmPrefix     = "faas-vm-"
+	bootTimeout  = 30 * time.Second
+	socketWait   = 5 * time.Second
+	pollInterval = 200 * time.Millisecond
+)
+
+// Client runs every worker inside its own Firecracker microVM. The guest
+// rootfs is expected to contain the worker and an init that reads the handler
+// from the microVM metadata service (MMDS) before starting it. Worker images
+// do not apply here: every VM boots the configured rootfs.
+type Client struct {
+	lg     zerolog.Logger
+	cfg    config.Config
+	subnet *net.IPNet
+
+	mu    sync.Mutex
+	slots map[string]int // function ID -> network slot
+}
+
+func New(cfg config.Config, lg zerolog.Logger) (*Client, error) {
+	if cfg.FirecrackerKernel == "" || cfg.FirecrackerRootfs == "" {
+		return nil, fmt.Errorf("FIRECRACKER_KERNEL and FIRECRACKER_ROOTFS are required in firecracker mode")
+	}
+	_, subnet, err := net.ParseCIDR(cfg.FirecrackerSubnet)
+	if err != nil || subnet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid FIRECRACKER_SUBNET %q", cfg.FirecrackerSubnet)
+	}
+	if err := os.MkdirAll(cfg.FirecrackerRunDir, 0755); err != nil {
+		return nil, fmt.Errorf("create run dir: %w", err)
+	}
+	return &Client{
+		lg:     lg.With().Str("adapter", "firecracker").Logger(),
+		cfg:    cfg,
+		subnet: subnet,
+		slots:  make(map[string]int),
+	}, nil
+}
+
+func (c *Client) RunWorker(ctx context.Context, spec functions.WorkerSpec) (*functions.RunResult, error) {
+	funcID, handlerPath := spec.FunctionID, spec.HandlerPath
+	vmID := vmPrefix + funcID
+
+	if spec.TLS != nil {
+		return nil, fmt.Errorf("worker mTLS is not supported by the firecracker orchestrator")
+	}
+
+	// A previous VM for the same function (e.g. before a manager restart)
+	// would hold the tap device and socket, so tear it down first.
+	_ = c.StopAndRemoveContainer(ctx, vmID)
+
+	handlerCode, err := spec.HandlerCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handler code: %w", err)
+	}
+
+	slot, err := c.allocateSlot(funcID)
+	if err != nil {
+		return nil, err
+	}
+	hostIP, guestIP := c.slotAddrs(slot)
+	tap := tapName(slot)
+
+	vmDir := filepath.Join(c.cfg.FirecrackerRunDir, funcID)
+	if err := os.MkdirAll(vmDir, 0755); err != nil {
+		c.releaseSlot(funcID)
+		return nil, fmt.Errorf("create vm dir: %w", err)
+	}
+
+	fail := func(err error) (*functions.RunResult, error) {
+		_ = c.StopAndRemoveContainer(context.Background(), vmID)
+		return nil, err
+	}
+
+	// Every VM gets its own writable copy of the root filesystem.
+	rootfs := filepath.Join(vmDir, "rootfs.ext4")
+	if err := copyFile(c.cfg.FirecrackerRootfs, rootfs); err != nil {
+		return fail(fmt.Errorf("copy rootfs: %w", err))
+	}
+
+	if err := setupTap(tap, hostIP); err != nil {
+		return fail(err)
+	}
+
+	socket := filepath.Join(vmDir, "firecracker.sock")
+	if err := c.startProcess(vmDir, socket, funcID); err != nil {
+		return fail(err)
+	}
+
+	api := newAPIClient(socket)
+	bootArgs := fmt.Sprintf("%s ip=%s::%s:255.255.255.252::eth0:off", c.cfg.FirecrackerBootArgs, guestIP, hostIP)
+
+	steps := []struct {
+		path string
+		body any
+	}{
+		{"/machine-config", map[string]any{
+			"vcpu_count":   c.cfg.FirecrackerVCPUs,
+			"mem_size_mib": c.cfg.FirecrackerMemoryMiB,
+		}},
+		{"/boot-source", map[string]any{
+			"kernel_image_path": c.cfg.FirecrackerKernel,
+			"boot_args":         bootArgs,
+		}},
+		{"/drives/rootfs", map[string]any{
+			"drive_id":       "rootfs",
+			"path_on_host":   rootfs,
+			"is_root_device": true,
+			"is_read_only":   false,
+		}},
+		{"/network-interfaces/eth0", map[string]any{
+			"iface_id":      "eth0",
+			"guest_mac":     guestMAC(slot),
+			"host_dev_name": tap,
+		}},
+		{"/mmds/config", map[string]any{
+			"version":            "V2",
+			"network_interfaces": []string{"eth0"},
+		}},
+		{"/mmds", map[string]any{
+			"faas": map[string]any{
+				"function_id":      funcID,
+				"handler_function": handlerPath,
+				"handler_code":     string(handlerCode),
+				"port":             c.cfg.WorkerPort,
+				"env":              spec.Env,
+			},
+		}},
+		{"/actions", map[string]any{"action_type": "InstanceStart"}},
+	}
+	for _, step := range steps {
+		if err := api.put(ctx, step.path, step.body); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := waitForPort(ctx, guestIP.String(), c.cfg.WorkerPort); err != nil {
+		return fail(fmt.Errorf("worker in vm %s did not come up: %w", vmID, err))
+	}
+
+	c.lg.Info().
+		Str("vm_id", vmID).
+		Str("function_id", funcID).
+		Str("guest_ip", guestIP.String()).
+		Msg("firecracker microvm started")
+
+	return &functions.RunResult{
+		ContainerID: vmID,
+		HostPort:    c.cfg.WorkerPort,
+		Endpoint:    fmt.Sprintf("http://%s:%d", guestIP, c.cfg.WorkerPort),
+	}, nil
+}
+
+func (c *Client) StopAndRemoveContainer(ctx context.Context, containerID string) error {
+	if !strings.HasPrefix(containerID, vmPrefix) {
+		return nil
+	}
+	funcID := strings.TrimPrefix(containerID, vmPrefix)
+	vmDir := filepath.Join(c.cfg.FirecrackerRunDir, funcID)
+
+	if pid, err := readPID(filepath.Join(vmDir, "firecracker.pid")); err == nil {
+		if proc, err := os.FindProcess(pid); err == nil {
+			_ = proc.Signal(syscall.SIGKILL)
+			_, _ = proc.Wait()
+		}
+	}
+
+	c.mu.Lock()
+	slot, ok := c.slots[funcID]
+	c.mu.Unlock()
+	if ok {
+		_ = exec.CommandContext(ctx, "ip", "link", "del", tapName(slot)).Run()
+		c.releaseSlot(funcID)
+	}
+
+	if err := os.RemoveAll(vmDir); err != nil {
+		return fmt.Errorf("remove vm dir: %w", err)
+	}
+
+	c.lg.Info().Str("vm_id", containerID).Msg("firecracker microvm removed")
+	return nil
+}
+
+func (c *Client) startProcess(vmDir, socket, funcID string) error {
+	_ = os.Remove(socket)
+
+	logFile, err := os.Create(filepath.Join(vmDir, "firecracker.log"))
+	if err != nil {
+		return fmt.Errorf("create vm log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(c.cfg.FirecrackerBin, "--api-sock", socket, "--id", funcID)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// Keep the VM out of the manager's process group so a Ctrl-C on the
+	// manager does not kill it before cleanup runs.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start firecracker: %w", err)
+	}
+	pidFile := filepath.Join(vmDir, "firecracker.pid")
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		return fmt.Errorf("write pid file: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+
+	deadline := time.Now().Add(socketWait)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(socket); err == nil {
+			return nil
+		}
+		time.Sleep(pollInterval)
+	}
+	return fmt.Errorf("firecracker api socket did not appear")
+}
+
+// allocateSlot reserves the lowest free network slot. Each slot maps to a
+// /30 inside the configured subnet: .1 for the host tap, .2 for the guest.
+func (c *Client) allocateSlot(funcID string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	used := make(map[int]bool, len(c.slots))
+	for _, s := range c.slots {
+		used[s] = true
+	}
+	ones, bits := c.subnet.Mask.Size()
+	capacity := (1 << (bits - ones)) / 4
+	for s := 0; s < capacity; s++ {
+		if !used[s] {
+			c.slots[funcID] = s
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("no free network slots left in %s", c.subnet)
+}
+
+func (c *Client) releaseSlot(funcID string) {
+	c.mu.Lock()
+	delete(c.slots, funcID)
+	c.mu.Unlock()
+}
+
+func (c *Client) slotAddrs(slot int) (host, guest net.IP) {
+	base := binary.BigEndian.Uint32(c.subnet.IP.To4()) + uint32(slot*4)
+	host = make(net.IP, 4)
+	guest = make(net.IP, 4)
+	binary.BigEndian.PutUint32(host, base+1)
+	binary.BigEndian.PutUint32(guest, base+2)
+	return host, guest
+}
+
+func tapName(slot int) string {
+	return fmt.Sprintf("fc-tap%d", slot)
+}
+
+func guestMAC(slot int) string {
+	return fmt.Sprintf("06:00:00:00:%02x:%02x", (slot>>8)&0xff, slot&0xff)
+}
+
+func setupTap(tap string, hostIP net.IP) error {
+	_ = exec.Command("ip", "link", "del", tap).Run()
+	cmds := [][]string{
+		{"ip", "tuntap", "add", "dev", tap, "mode", "tap"},
+		{"ip", "addr", "add", hostIP.String() + "/30", "dev", tap},
+		{"ip", "link", "set", tap, "up"},
+	}
+	for _, args := range cmds {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+func waitForPort(ctx context.Context, host string, port int) error {
+	ctx, cancel := context.WithTimeout(ctx, bootTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func readPID(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(raw)))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// apiClient talks to a Firecracker process over its unix API socket.
+type apiClient struct {
+	http *http.Client
+}
+
+func newAPIClient(socket string) *apiClient {
+	return &apiClient{http: &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}}
+}
+
+func (a *apiClient) put(ctx context.Context, path string, body any) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", path, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "http://localhost"+path, bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("create request %s: %w", path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("firecracker api %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("firecracker api %s: %s - %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package firecracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"service-faas/internal/core/functions"
+	"syscall"
+)
+
+// InspectWorker checks that the function's Firecracker process is alive.
+// VMs are not restarted when they exit, so one whose process is gone has
+// died.
+func (c *Client) InspectWorker(ctx context.Context, functionID string) (*functions.WorkerState, error) {
+	vmDir := filepath.Join(c.cfg.FirecrackerRunDir, functionID)
+	pid, err := readPID(filepath.Join(vmDir, "firecracker.pid"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read vm pid: %w", err)
+	}
+	state := &functions.WorkerState{
+		ContainerID: vmPrefix + functionID,
+		Status:      functions.WorkerStatus{DesiredReplicas: 1, ReadyReplicas: 1},
+	}
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		state.Status.ReadyReplicas = 0
+		state.Failed = true
+		state.Reason = fmt.Sprintf("firecracker process %d exited, see %s", pid, filepath.Join(vmDir, "firecracker.log"))
+	}
+	return state, nil
+}
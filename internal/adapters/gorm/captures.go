@@ -0,0 +1,45 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// CaptureRepository stores debug captures in the captures table.
+type CaptureRepository struct {
+	db *gorm.DB
+}
+
+func NewCaptureRepository(db *gorm.DB) *CaptureRepository {
+	return &CaptureRepository{db: db}
+}
+
+func (r *CaptureRepository) Create(ctx context.Context, c *functions.Capture) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+func (r *CaptureRepository) List(ctx context.Context, functionID string, now time.Time, limit int) ([]functions.Capture, error) {
+	captures := []functions.Capture{}
+	err := r.db.WithContext(ctx).
+		Where("function_id = ? AND expires_at > ?", functionID, now).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&captures).Error
+	if err != nil {
+		return nil, err
+	}
+	return captures, nil
+}
+
+func (r *CaptureRepository) DeleteByFunction(ctx context.Context, functionID string) error {
+	return r.db.WithContext(ctx).Where("function_id = ?", functionID).Delete(&functions.Capture{}).Error
+}
+
+func (r *CaptureRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&functions.Capture{})
+	return res.RowsAffected, res.Error
+}
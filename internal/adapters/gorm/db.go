@@ -1,18 +1,89 @@
 package gorm
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"sync/atomic"
+	"time"
 
-	"service-faas/internal/core/functions"
+	"service-faas/pkg/secretbox"
 
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/rs/zerolog"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlog "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
-// New creates a new GORM database instance and runs migrations.
-func New(dsn string, lg zerolog.Logger) (*gorm.DB, error) {
+// connMaxLifetime bounds how long a Postgres or MySQL connection is reused,
+// so connections opened with replaced credentials are closed well before
+// those credentials expire.
+const connMaxLifetime = 10 * time.Minute
+
+// DSN holds the data source name new database connections are opened with.
+// Set replaces it, e.g. when Vault issues new dynamic credentials; open
+// connections keep using the old one until they are recycled.
+type DSN struct {
+	v atomic.Pointer[string]
+}
+
+// NewDSN returns a DSN holding dsn.
+func NewDSN(dsn string) *DSN {
+	d := &DSN{}
+	d.Set(dsn)
+	return d
+}
+
+// Get returns the current data source name.
+func (d *DSN) Get() string {
+	return *d.v.Load()
+}
+
+// Set replaces the data source name for connections opened from now on.
+func (d *DSN) Set(dsn string) {
+	d.v.Store(&dsn)
+}
+
+// dsnConnector opens each connection with the DSN current at the time.
+type dsnConnector struct {
+	driver driver.DriverContext
+	dsn    *DSN
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := c.driver.OpenConnector(c.dsn.Get())
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver.(driver.Driver)
+}
+
+// openPool opens a connection pool that follows changes to dsn.
+func openPool(drv driver.Driver, dsn *DSN) *sql.DB {
+	pool := sql.OpenDB(dsnConnector{driver: drv.(driver.DriverContext), dsn: dsn})
+	pool.SetConnMaxLifetime(connMaxLifetime)
+	return pool
+}
+
+// New creates a new GORM database instance for the given driver ("postgres",
+// "mysql" or "sqlite") and runs migrations. The keyring backs the "encrypted"
+// serializer used for sensitive columns. Postgres and MySQL connections are
+// opened with the DSN current at the time, so it can be replaced while the
+// database is open.
+func New(driver string, dsn *DSN, keyring *secretbox.Keyring, lg zerolog.Logger) (*gorm.DB, error) {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{Keyring: keyring})
+	schema.RegisterSerializer("nullempty", NullStringSerializer{})
+
 	// Configure GORM's logger to use Zerolog
 	gormLogger := gormlog.New(
 		&lg,
@@ -23,18 +94,28 @@ func New(dsn string, lg zerolog.Logger) (*gorm.DB, error) {
 		},
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	var dialector gorm.Dialector
+	switch driver {
+	case "postgres", "":
+		dialector = postgres.New(postgres.Config{Conn: openPool(stdlib.GetDefaultDriver(), dsn)})
+	case "mysql":
+		dialector = mysql.New(mysql.Config{Conn: openPool(&mysqldriver.MySQLDriver{}, dsn)})
+	case "sqlite":
+		dialector = sqlite.Open(dsn.Get())
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("gorm open: %w", err)
 	}
 
-	// AutoMigrate will create the 'functions' table based on the struct definition.
-	if err := db.AutoMigrate(&functions.Function{}); err != nil {
-		return nil, fmt.Errorf("gorm migrate: %w", err)
+	if err := Migrate(db, lg); err != nil {
+		return nil, err
 	}
-	lg.Info().Msg("database migration successful")
 
 	return db, nil
 }
@@ -0,0 +1,123 @@
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"service-faas/internal/core/functions"
+	"service-faas/pkg/secretbox"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// encryptedModels lists every model with `serializer:encrypted` fields, so
+// RotateEncryptedColumns knows what to re-encrypt.
+var encryptedModels = []any{
+	&functions.Function{},
+	&functions.RegistryCredential{},
+	&functions.Webhook{},
+	&functions.Invocation{},
+	&functions.Capture{},
+}
+
+// EncryptedSerializer transparently encrypts string fields tagged with
+// `gorm:"serializer:encrypted"`: values are plaintext in memory and
+// ciphertext in the database, so a dump alone does not expose them. String
+// map fields are encrypted as JSON, and json.RawMessage fields as they are.
+type EncryptedSerializer struct {
+	Keyring *secretbox.Keyring
+}
+
+func (s EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("unsupported encrypted column type %T", dbValue)
+	}
+
+	plaintext := ""
+	if stored != "" {
+		raw, err := s.Keyring.Decrypt(stored)
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %w", field.Name, err)
+		}
+		plaintext = string(raw)
+	}
+	if field.FieldType.Kind() == reflect.Map {
+		m := reflect.New(field.FieldType)
+		if plaintext != "" {
+			if err := json.Unmarshal([]byte(plaintext), m.Interface()); err != nil {
+				return fmt.Errorf("decode %s: %w", field.Name, err)
+			}
+		}
+		field.ReflectValueOf(ctx, dst).Set(m.Elem())
+		return nil
+	}
+	if field.FieldType == reflect.TypeOf(json.RawMessage(nil)) {
+		var raw json.RawMessage
+		if plaintext != "" {
+			raw = json.RawMessage(plaintext)
+		}
+		field.ReflectValueOf(ctx, dst).Set(reflect.ValueOf(raw))
+		return nil
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (s EncryptedSerializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, fieldValue any) (any, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = v
+	case map[string]string:
+		if len(v) > 0 {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("encode %s: %w", field.Name, err)
+			}
+			plaintext = string(data)
+		}
+	case json.RawMessage:
+		plaintext = string(v)
+	default:
+		return nil, fmt.Errorf("encrypted field %s must be a string, a string map or JSON", field.Name)
+	}
+	if plaintext == "" {
+		return "", nil
+	}
+	sealed, err := s.Keyring.Encrypt([]byte(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt %s: %w", field.Name, err)
+	}
+	return sealed, nil
+}
+
+// RotateEncryptedColumns re-saves every record with encrypted fields so they
+// are re-encrypted with the keyring's primary key. Once it has run, keys
+// other than the primary can be removed from the configuration.
+func RotateEncryptedColumns(db *gorm.DB) (int, error) {
+	total := 0
+	for _, model := range encryptedModels {
+		rows := reflect.New(reflect.SliceOf(reflect.TypeOf(model).Elem()))
+		if err := db.Model(model).Find(rows.Interface()).Error; err != nil {
+			return total, fmt.Errorf("load %T: %w", model, err)
+		}
+		slice := rows.Elem()
+		for i := 0; i < slice.Len(); i++ {
+			if err := db.Save(slice.Index(i).Addr().Interface()).Error; err != nil {
+				return total, fmt.Errorf("re-encrypt %T: %w", model, err)
+			}
+			total++
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,40 @@
+package gorm
+
+import (
+	"context"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// GitDeploymentRepository stores Git deployment records in the
+// git_deployments table.
+type GitDeploymentRepository struct {
+	db *gorm.DB
+}
+
+func NewGitDeploymentRepository(db *gorm.DB) *GitDeploymentRepository {
+	return &GitDeploymentRepository{db: db}
+}
+
+func (r *GitDeploymentRepository) Create(ctx context.Context, d *functions.GitDeployment) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+func (r *GitDeploymentRepository) List(ctx context.Context, functionID string, limit int) ([]functions.GitDeployment, error) {
+	deployments := []functions.GitDeployment{}
+	err := r.db.WithContext(ctx).
+		Where("function_id = ?", functionID).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&deployments).Error
+	if err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (r *GitDeploymentRepository) DeleteByFunction(ctx context.Context, functionID string) error {
+	return r.db.WithContext(ctx).Where("function_id = ?", functionID).Delete(&functions.GitDeployment{}).Error
+}
@@ -0,0 +1,57 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository stores idempotency records in the
+// idempotency_records table. The primary key on (function_id,
+// idempotency_key) makes claims atomic.
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Claim(ctx context.Context, rec *functions.IdempotencyRecord) (*functions.IdempotencyRecord, error) {
+	db := r.db.WithContext(ctx)
+	err := db.Where("function_id = ? AND idempotency_key = ? AND expires_at < ?", rec.FunctionID, rec.Key, time.Now().UTC()).
+		Delete(&functions.IdempotencyRecord{}).Error
+	if err != nil {
+		return nil, err
+	}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil, nil
+	}
+	var existing functions.IdempotencyRecord
+	if err := db.Where("function_id = ? AND idempotency_key = ?", rec.FunctionID, rec.Key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, rec *functions.IdempotencyRecord) error {
+	return r.db.WithContext(ctx).Save(rec).Error
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, functionID, key string) error {
+	return r.db.WithContext(ctx).Where("function_id = ? AND idempotency_key = ?", functionID, key).
+		Delete(&functions.IdempotencyRecord{}).Error
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&functions.IdempotencyRecord{})
+	return res.RowsAffected, res.Error
+}
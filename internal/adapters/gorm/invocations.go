@@ -0,0 +1,42 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// InvocationRepository stores invocation outcomes in the invocations table.
+type InvocationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvocationRepository(db *gorm.DB) *InvocationRepository {
+	return &InvocationRepository{db: db}
+}
+
+func (r *InvocationRepository) Create(ctx context.Context, inv *functions.Invocation) error {
+	return r.db.WithContext(ctx).Create(inv).Error
+}
+
+func (r *InvocationRepository) Get(ctx context.Context, id string) (*functions.Invocation, error) {
+	var inv functions.Invocation
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: invocation '%s'", functions.ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *InvocationRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&functions.Invocation{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,61 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// InvokeTokenRepository stores invoke tokens, by the hash of their secret,
+// in the invoke_tokens table.
+type InvokeTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewInvokeTokenRepository(db *gorm.DB) *InvokeTokenRepository {
+	return &InvokeTokenRepository{db: db}
+}
+
+func (r *InvokeTokenRepository) Create(ctx context.Context, tok *functions.InvokeToken) error {
+	return r.db.WithContext(ctx).Create(tok).Error
+}
+
+func (r *InvokeTokenRepository) Get(ctx context.Context, id string) (*functions.InvokeToken, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *InvokeTokenRepository) FindByHash(ctx context.Context, hash string) (*functions.InvokeToken, error) {
+	return r.first(ctx, "token_hash = ?", hash)
+}
+
+func (r *InvokeTokenRepository) first(ctx context.Context, query, arg string) (*functions.InvokeToken, error) {
+	var tok functions.InvokeToken
+	err := r.db.WithContext(ctx).Where(query, arg).First(&tok).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: invoke token", functions.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tok, nil
+}
+
+func (r *InvokeTokenRepository) ListByFunction(ctx context.Context, functionID string) ([]functions.InvokeToken, error) {
+	toks := []functions.InvokeToken{}
+	if err := r.db.WithContext(ctx).Where("function_id = ?", functionID).Order("created_at").Find(&toks).Error; err != nil {
+		return nil, err
+	}
+	return toks, nil
+}
+
+func (r *InvokeTokenRepository) Update(ctx context.Context, tok *functions.InvokeToken) error {
+	return r.db.WithContext(ctx).Save(tok).Error
+}
+
+func (r *InvokeTokenRepository) DeleteByFunction(ctx context.Context, functionID string) error {
+	return r.db.WithContext(ctx).Where("function_id = ?", functionID).Delete(&functions.InvokeToken{}).Error
+}
@@ -0,0 +1,50 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lease is a row of the leader_leases table.
+type lease struct {
+	Name      string `gorm:"primaryKey;size:64"`
+	Holder    string `gorm:"size:191"`
+	ExpiresAt time.Time
+}
+
+func (lease) TableName() string { return "leader_leases" }
+
+// LeaderLock grants named leases through the leader_leases table. A lease is
+// taken over once it expires, so replicas' clocks should roughly agree.
+type LeaderLock struct {
+	db *gorm.DB
+}
+
+func NewLeaderLock(db *gorm.DB) *LeaderLock {
+	return &LeaderLock{db: db}
+}
+
+func (l *LeaderLock) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	db := l.db.WithContext(ctx)
+	now := time.Now().UTC()
+	res := db.Model(&lease{}).Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]any{"holder": holder, "expires_at": now.Add(ttl)})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return true, nil
+	}
+	res = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (l *LeaderLock) Release(ctx context.Context, name, holder string) error {
+	return l.db.WithContext(ctx).Where("name = ? AND holder = ?", name, holder).Delete(&lease{}).Error
+}
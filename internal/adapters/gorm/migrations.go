@@ -0,0 +1,949 @@
+package gorm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"service-faas/internal/core/functions"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// migrationsTable records the IDs of applied migrations.
+const migrationsTable = "schema_migrations"
+
+// migrations is the ordered schema history. Append new migrations to the end
+// and never edit one that has been released; IDs sort by date.
+//
+// Migrations should use their own snapshot structs rather than the live
+// models, so replaying them later yields the schema of that point in time.
+var migrations = []*gormigrate.Migration{
+	{
+		// Baseline: the schema previously maintained by AutoMigrate. It is a
+		// no-op on databases that already have it.
+		ID: "202610150001_baseline",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functions.Function{}, &functions.RegistryCredential{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("registry_credentials", "functions")
+		},
+	},
+	{
+		ID: "202610150002_function_soft_delete",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionDeletedAt{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&functionDeletedAt{}, "DeletedAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&functionDeletedAt{}, "DeletedAt")
+		},
+	},
+	{
+		ID: "202610150003_function_labels",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionLabels{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionLabels{}, "Labels")
+		},
+	},
+	{
+		ID: "202610150004_function_search",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&functionDescription{}); err != nil {
+				return err
+			}
+			switch tx.Dialector.Name() {
+			case "postgres":
+				return tx.Exec("CREATE INDEX idx_functions_search ON functions USING GIN ((" + pgSearchDocument + "))").Error
+			case "mysql":
+				return tx.Exec("CREATE FULLTEXT INDEX idx_functions_search ON functions (" + mysqlSearchColumns + ")").Error
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			switch tx.Dialector.Name() {
+			case "postgres":
+				if err := tx.Exec("DROP INDEX IF EXISTS idx_functions_search").Error; err != nil {
+					return err
+				}
+			case "mysql":
+				if err := tx.Exec("DROP INDEX idx_functions_search ON functions").Error; err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropColumn(&functionDescription{}, "Description")
+		},
+	},
+	{
+		ID: "202610150005_usage_rollups",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&usageRollup{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("usage_rollups")
+		},
+	},
+	{
+		ID: "202610150006_function_max_concurrency",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionMaxConcurrency{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionMaxConcurrency{}, "MaxConcurrency")
+		},
+	},
+	{
+		ID: "202610150007_function_max_payload_bytes",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionMaxPayloadBytes{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionMaxPayloadBytes{}, "MaxPayloadBytes")
+		},
+	},
+	{
+		ID: "202610150008_function_payload_schema",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionPayloadSchema{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionPayloadSchema{}, "PayloadSchema")
+		},
+	},
+	{
+		ID: "202610150009_function_cache_ttl",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionCacheTTL{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionCacheTTL{}, "CacheTTLSeconds")
+		},
+	},
+	{
+		ID: "202610150010_idempotency_records",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&idempotencyRecord{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("idempotency_records")
+		},
+	},
+	{
+		ID: "202610150011_invocations",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&invocation{}, &idempotencyRecordInvocationID{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&idempotencyRecordInvocationID{}, "InvocationID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("invocations")
+		},
+	},
+	{
+		ID: "202610150012_function_policy_findings",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionPolicyFindings{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionPolicyFindings{}, "PolicyFindings")
+		},
+	},
+	{
+		ID: "202610150013_function_blocked_reason",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionBlockedReason{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionBlockedReason{}, "BlockedReason")
+		},
+	},
+	{
+		ID: "202610150014_function_dependency_lock",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionDependencyLock{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionDependencyLock{}, "DependencyLockSHA256")
+		},
+	},
+	{
+		ID: "202610150015_function_image_build",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionImageBuild{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, col := range []string{"BuiltImage", "BuildStatus", "BuildLog"} {
+				if err := tx.Migrator().DropColumn(&functionImageBuild{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "202610150016_function_image_digest",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionImageDigest{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionImageDigest{}, "ImageDigest")
+		},
+	},
+	{
+		ID: "202610150017_function_runtime",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionRuntime{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionRuntime{}, "Runtime")
+		},
+	},
+	{
+		ID: "202610150018_function_warmup",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionWarmup{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionWarmup{}, "Warmup")
+		},
+	},
+	{
+		ID: "202610150019_webhooks",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&webhook{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("webhooks")
+		},
+	},
+	{
+		ID: "202610150020_leader_leases",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&leaderLease{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("leader_leases")
+		},
+	},
+	{
+		ID: "202610150021_function_status_reason",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionStatusReason{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionStatusReason{}, "StatusReason")
+		},
+	},
+	{
+		ID: "202610150022_function_deployed_at",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionDeployedAt{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionDeployedAt{}, "DeployedAt")
+		},
+	},
+	{
+		ID: "202610150023_usage_cold_starts",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&usageColdStarts{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&usageColdStarts{}, "ColdStarts"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&usageColdStarts{}, "ColdStartMS")
+		},
+	},
+	{
+		ID: "202610150024_function_timeout",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionTimeout{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionTimeout{}, "TimeoutSeconds")
+		},
+	},
+	{
+		ID: "202610150025_function_replicas",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionReplicas{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&functionReplicas{}, "Replicas"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&functionReplicas{}, "Endpoints")
+		},
+	},
+	{
+		ID: "202610150026_function_affinity",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionAffinity{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionAffinity{}, "Affinity")
+		},
+	},
+	{
+		ID: "202610150027_function_kubernetes",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionKubernetes{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionKubernetes{}, "Kubernetes")
+		},
+	},
+	{
+		ID: "202610150028_function_git",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionGit{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, col := range []string{"Git", "GitToken", "GitDeployKey"} {
+				if err := tx.Migrator().DropColumn(&functionGit{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "202610150029_function_name",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionName{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionName{}, "Name")
+		},
+	},
+	{
+		ID: "202610150030_function_environments",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionEnvironments{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, col := range []string{"ParentID", "Environment", "EnvVars"} {
+				if err := tx.Migrator().DropColumn(&functionEnvironments{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "202610150031_function_promotions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionPromotion{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionPromotion{}, "Promotion")
+		},
+	},
+	{
+		ID: "202610150032_invoke_tokens",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&invokeToken{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("invoke_tokens")
+		},
+	},
+	{
+		ID: "202610150033_function_public",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionPublic{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionPublic{}, "Public")
+		},
+	},
+	{
+		ID: "202610150034_function_domains",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionDomain{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionDomain{}, "Domain")
+		},
+	},
+	{
+		ID: "202610150035_function_cors",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionCORS{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&functionCORS{}, "CORS")
+		},
+	},
+	{
+		ID: "202610150036_invocation_exceptions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&invocationException{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&invocationException{}, "Exception")
+		},
+	},
+	{
+		ID: "202610150037_invocation_error_class",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&invocationErrorClass{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&invocationErrorClass{}, "ErrorClass")
+		},
+	},
+	{
+		ID: "202610150038_invocation_replay",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&invocationReplay{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, col := range []string{"CodeSHA256", "ReplayOf", "Payload", "PayloadCaptured"} {
+				if err := tx.Migrator().DropColumn(&invocationReplay{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "202610150039_debug_captures",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionDebug{}, &capture{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("captures"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&functionDebug{}, "Debug")
+		},
+	},
+	{
+		ID: "202610150040_restart_retries",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&functionRestartRetry{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, col := range []string{"RestartAttempts", "NextRestartAt"} {
+				if err := tx.Migrator().DropColumn(&functionRestartRetry{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID: "202610150041_git_deployments",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&gitDeployment{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("git_deployments")
+		},
+	},
+	{
+		// MySQL cannot index unsized strings, so the unique tenant and
+		// server of registry credentials are sized. SQLite ignores sizes,
+		// and altering a column there rebuilds the table without its
+		// indexes.
+		ID: "202610150042_registry_credential_key_sizes",
+		Migrate: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "sqlite" {
+				return nil
+			}
+			for _, col := range []string{"Tenant", "Server"} {
+				if err := tx.Migrator().AlterColumn(&registryCredentialKeys{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// The sized columns hold whatever the unsized ones held.
+			return nil
+		},
+	},
+	{
+		// Captured payloads and responses are encrypted from now on; the
+		// ones stored in plain text are dropped rather than kept next to
+		// them. Captures expire within days anyway.
+		ID: "202610150043_encrypted_captures",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM captures").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM captures").Error
+		},
+	},
+	{
+		// Function names become unique in the database, so replicas cannot
+		// give two functions the same name at once. Unnamed functions store
+		// NULL, which the index ignores.
+		ID: "202610150044_unique_function_names",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE functions SET name = NULL WHERE name = ''").Error; err != nil {
+				return err
+			}
+			var dups []string
+			if err := tx.Raw("SELECT name FROM functions WHERE name IS NOT NULL GROUP BY name HAVING COUNT(*) > 1").Scan(&dups).Error; err != nil {
+				return err
+			}
+			if len(dups) > 0 {
+				return fmt.Errorf("functions share the names %s; rename them before upgrading", strings.Join(dups, ", "))
+			}
+			return uniqueFunctionIndex(tx, "Name", true)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := uniqueFunctionIndex(tx, "Name", false); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE functions SET name = '' WHERE name IS NULL").Error
+		},
+	},
+	{
+		// Domains become unique in the database like names, see
+		// 202610150044_unique_function_names.
+		ID: "202610150045_unique_function_domains",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Exec("UPDATE functions SET domain = NULL WHERE domain = ''").Error; err != nil {
+				return err
+			}
+			var dups []string
+			if err := tx.Raw("SELECT domain FROM functions WHERE domain IS NOT NULL GROUP BY domain HAVING COUNT(*) > 1").Scan(&dups).Error; err != nil {
+				return err
+			}
+			if len(dups) > 0 {
+				return fmt.Errorf("functions share the domains %s; unbind them before upgrading", strings.Join(dups, ", "))
+			}
+			return uniqueFunctionIndex(tx, "Domain", true)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := uniqueFunctionIndex(tx, "Domain", false); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE functions SET domain = '' WHERE domain IS NULL").Error
+		},
+	},
+	{
+		ID: "202610150046_environment_promotions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&environmentPromotion{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("environment_promotions")
+		},
+	},
+}
+
+// uniqueFunctionIndex replaces the index on a functions column with a unique
+// one, or back with a plain one.
+func uniqueFunctionIndex(tx *gorm.DB, field string, unique bool) error {
+	var model any = &functionKeys{}
+	if unique {
+		model = &functionUniqueKeys{}
+	}
+	if tx.Migrator().HasIndex(model, field) {
+		if err := tx.Migrator().DropIndex(model, field); err != nil {
+			return err
+		}
+	}
+	return tx.Migrator().CreateIndex(model, field)
+}
+
+type functionDeletedAt struct {
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (functionDeletedAt) TableName() string { return "functions" }
+
+type functionLabels struct {
+	Labels string `gorm:"type:text"`
+}
+
+func (functionLabels) TableName() string { return "functions" }
+
+type functionDescription struct {
+	Description string
+}
+
+func (functionDescription) TableName() string { return "functions" }
+
+type usageRollup struct {
+	FunctionID  string    `gorm:"primaryKey"`
+	Hour        time.Time `gorm:"primaryKey"`
+	Invocations int64
+	Errors      int64
+	DurationMS  int64
+	GBSeconds   float64
+}
+
+func (usageRollup) TableName() string { return "usage_rollups" }
+
+type functionMaxConcurrency struct {
+	MaxConcurrency int
+}
+
+func (functionMaxConcurrency) TableName() string { return "functions" }
+
+type functionMaxPayloadBytes struct {
+	MaxPayloadBytes int64
+}
+
+func (functionMaxPayloadBytes) TableName() string { return "functions" }
+
+type functionPayloadSchema struct {
+	PayloadSchema string `gorm:"type:text"`
+}
+
+func (functionPayloadSchema) TableName() string { return "functions" }
+
+type functionCacheTTL struct {
+	CacheTTLSeconds int
+}
+
+func (functionCacheTTL) TableName() string { return "functions" }
+
+type idempotencyRecord struct {
+	FunctionID    string `gorm:"primaryKey;size:64"`
+	Key           string `gorm:"primaryKey;size:191;column:idempotency_key"`
+	PayloadSHA256 string `gorm:"size:64"`
+	Done          bool   `gorm:"not null;default:false"`
+	Result        string `gorm:"type:text"`
+	ResultRef     string `gorm:"type:text"`
+	Degraded      string
+	ExpiresAt     time.Time `gorm:"index"`
+}
+
+func (idempotencyRecord) TableName() string { return "idempotency_records" }
+
+type invocation struct {
+	ID         string `gorm:"primaryKey;size:64"`
+	FunctionID string `gorm:"size:64;index"`
+	Status     string
+	Result     string `gorm:"type:text"`
+	ResultRef  string `gorm:"type:text"`
+	Truncated  bool
+	Error      string `gorm:"type:text"`
+	Degraded   string
+	StartedAt  time.Time
+	DurationMS int64
+	ExpiresAt  time.Time `gorm:"index"`
+}
+
+func (invocation) TableName() string { return "invocations" }
+
+type idempotencyRecordInvocationID struct {
+	InvocationID string `gorm:"size:64"`
+}
+
+func (idempotencyRecordInvocationID) TableName() string { return "idempotency_records" }
+
+type functionPolicyFindings struct {
+	PolicyFindings string `gorm:"type:text"`
+}
+
+func (functionPolicyFindings) TableName() string { return "functions" }
+
+type functionBlockedReason struct {
+	BlockedReason string
+}
+
+func (functionBlockedReason) TableName() string { return "functions" }
+
+type functionDependencyLock struct {
+	DependencyLockSHA256 string
+}
+
+func (functionDependencyLock) TableName() string { return "functions" }
+
+type functionImageBuild struct {
+	BuiltImage  string
+	BuildStatus string
+	BuildLog    string `gorm:"type:text"`
+}
+
+func (functionImageBuild) TableName() string { return "functions" }
+
+type functionImageDigest struct {
+	ImageDigest string
+}
+
+func (functionImageDigest) TableName() string { return "functions" }
+
+type functionRuntime struct {
+	Runtime string
+}
+
+func (functionRuntime) TableName() string { return "functions" }
+
+type functionWarmup struct {
+	Warmup string `gorm:"type:text"`
+}
+
+func (functionWarmup) TableName() string { return "functions" }
+
+type webhook struct {
+	ID        string `gorm:"primaryKey;size:64"`
+	Tenant    string `gorm:"size:191;index"`
+	URL       string `gorm:"type:text"`
+	Events    string `gorm:"type:text"`
+	Secret    string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (webhook) TableName() string { return "webhooks" }
+
+type leaderLease struct {
+	Name      string `gorm:"primaryKey;size:64"`
+	Holder    string `gorm:"size:191"`
+	ExpiresAt time.Time
+}
+
+func (leaderLease) TableName() string { return "leader_leases" }
+
+type functionStatusReason struct {
+	StatusReason string `gorm:"type:text"`
+}
+
+func (functionStatusReason) TableName() string { return "functions" }
+
+type functionDeployedAt struct {
+	DeployedAt *time.Time
+}
+
+func (functionDeployedAt) TableName() string { return "functions" }
+
+type usageColdStarts struct {
+	ColdStarts  int64
+	ColdStartMS int64
+}
+
+func (usageColdStarts) TableName() string { return "usage_rollups" }
+
+type functionTimeout struct {
+	TimeoutSeconds int
+}
+
+func (functionTimeout) TableName() string { return "functions" }
+
+type functionReplicas struct {
+	Replicas  int
+	Endpoints string `gorm:"type:text"`
+}
+
+func (functionReplicas) TableName() string { return "functions" }
+
+type functionAffinity struct {
+	Affinity string `gorm:"type:text"`
+}
+
+func (functionAffinity) TableName() string { return "functions" }
+
+type functionKubernetes struct {
+	Kubernetes string `gorm:"type:text"`
+}
+
+func (functionKubernetes) TableName() string { return "functions" }
+
+// GitToken and GitDeployKey hold ciphertext.
+type functionGit struct {
+	Git          string `gorm:"type:text"`
+	GitToken     string `gorm:"type:text"`
+	GitDeployKey string `gorm:"type:text"`
+}
+
+func (functionGit) TableName() string { return "functions" }
+
+type functionName struct {
+	Name string `gorm:"size:191;index"`
+}
+
+func (functionName) TableName() string { return "functions" }
+
+type functionKeys struct {
+	Name   string `gorm:"size:191;index"`
+	Domain string `gorm:"size:191;index"`
+}
+
+func (functionKeys) TableName() string { return "functions" }
+
+type functionUniqueKeys struct {
+	Name   string `gorm:"size:191;uniqueIndex"`
+	Domain string `gorm:"size:191;uniqueIndex"`
+}
+
+func (functionUniqueKeys) TableName() string { return "functions" }
+
+type functionEnvironments struct {
+	ParentID    string `gorm:"size:191;index"`
+	Environment string `gorm:"size:63"`
+	EnvVars     string `gorm:"type:text"`
+}
+
+func (functionEnvironments) TableName() string { return "functions" }
+
+type functionPromotion struct {
+	Promotion string `gorm:"type:text"`
+}
+
+func (functionPromotion) TableName() string { return "functions" }
+
+type invokeToken struct {
+	ID          string `gorm:"primaryKey;size:64"`
+	FunctionID  string `gorm:"size:191;index"`
+	Description string
+	TokenHash   string `gorm:"size:64;uniqueIndex"`
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time
+	RevokedAt   *time.Time
+}
+
+func (invokeToken) TableName() string { return "invoke_tokens" }
+
+type functionPublic struct {
+	Public bool
+}
+
+func (functionPublic) TableName() string { return "functions" }
+
+type functionDomain struct {
+	Domain string `gorm:"size:191;index"`
+}
+
+func (functionDomain) TableName() string { return "functions" }
+
+type functionCORS struct {
+	CORS string `gorm:"type:text"`
+}
+
+func (functionCORS) TableName() string { return "functions" }
+
+type invocationException struct {
+	Exception string `gorm:"type:text"`
+}
+
+func (invocationException) TableName() string { return "invocations" }
+
+type invocationErrorClass struct {
+	ErrorClass string
+}
+
+func (invocationErrorClass) TableName() string { return "invocations" }
+
+type invocationReplay struct {
+	CodeSHA256      string
+	ReplayOf        string `gorm:"size:64"`
+	Payload         string `gorm:"type:text"`
+	PayloadCaptured bool
+}
+
+func (invocationReplay) TableName() string { return "invocations" }
+
+type functionDebug struct {
+	Debug string `gorm:"type:text"`
+}
+
+func (functionDebug) TableName() string { return "functions" }
+
+type capture struct {
+	ID                string `gorm:"primaryKey;size:64"`
+	FunctionID        string `gorm:"size:64;index"`
+	InvocationID      string `gorm:"size:64"`
+	Status            string
+	Payload           string `gorm:"type:text"`
+	PayloadTruncated  bool
+	Response          string `gorm:"type:text"`
+	ResponseTruncated bool
+	ResultRef         string `gorm:"type:text"`
+	Error             string `gorm:"type:text"`
+	Exception         string `gorm:"type:text"`
+	ErrorClass        string
+	StartedAt         time.Time `gorm:"index"`
+	DurationMS        int64
+	ExpiresAt         time.Time `gorm:"index"`
+}
+
+func (capture) TableName() string { return "captures" }
+
+type functionRestartRetry struct {
+	RestartAttempts int
+	NextRestartAt   *time.Time
+}
+
+func (functionRestartRetry) TableName() string { return "functions" }
+
+type gitDeployment struct {
+	ID             string `gorm:"primaryKey;size:64"`
+	FunctionID     string `gorm:"size:64;index"`
+	Trigger        string
+	Ref            string
+	Commit         string
+	PreviousCommit string
+	Outcome        string
+	Error          string    `gorm:"type:text"`
+	StartedAt      time.Time `gorm:"index"`
+	FinishedAt     time.Time
+}
+
+func (gitDeployment) TableName() string { return "git_deployments" }
+
+type environmentPromotion struct {
+	ID             string `gorm:"primaryKey;size:64"`
+	FunctionID     string `gorm:"size:64;index"`
+	From           string
+	To             string
+	FromFunctionID string
+	ToFunctionID   string
+	CodeSHA256     string
+	Commit         string
+	PromotedBy     string
+	Outcome        string
+	Error          string    `gorm:"type:text"`
+	PromotedAt     time.Time `gorm:"index"`
+}
+
+func (environmentPromotion) TableName() string { return "environment_promotions" }
+
+type registryCredentialKeys struct {
+	Tenant string `gorm:"size:191;uniqueIndex:idx_tenant_server"`
+	Server string `gorm:"size:191;uniqueIndex:idx_tenant_server"`
+}
+
+func (registryCredentialKeys) TableName() string { return "registry_credentials" }
+
+// Migrate applies all pending migrations.
+func Migrate(db *gorm.DB, lg zerolog.Logger) error {
+	opts := *gormigrate.DefaultOptions
+	opts.TableName = migrationsTable
+	m := gormigrate.New(db, &opts, migrations)
+	if err := m.Migrate(); err != nil {
+		return fmt.Errorf("gorm migrate: %w", err)
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	lg.Info().Str("schema_version", version).Msg("database migration successful")
+	return nil
+}
+
+// SchemaVersion returns the ID of the latest applied migration.
+func SchemaVersion(db *gorm.DB) (string, error) {
+	var version string
+	err := db.Table(migrationsTable).Select("id").Order("id DESC").Limit(1).Scan(&version).Error
+	if err != nil {
+		return "", fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
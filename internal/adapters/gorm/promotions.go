@@ -0,0 +1,40 @@
+package gorm
+
+import (
+	"context"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// EnvironmentPromotionRepository stores promotion records in the
+// environment_promotions table.
+type EnvironmentPromotionRepository struct {
+	db *gorm.DB
+}
+
+func NewEnvironmentPromotionRepository(db *gorm.DB) *EnvironmentPromotionRepository {
+	return &EnvironmentPromotionRepository{db: db}
+}
+
+func (r *EnvironmentPromotionRepository) Create(ctx context.Context, p *functions.EnvironmentPromotion) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *EnvironmentPromotionRepository) List(ctx context.Context, functionID string, limit int) ([]functions.EnvironmentPromotion, error) {
+	promotions := []functions.EnvironmentPromotion{}
+	err := r.db.WithContext(ctx).
+		Where("function_id = ?", functionID).
+		Order("promoted_at DESC").
+		Limit(limit).
+		Find(&promotions).Error
+	if err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (r *EnvironmentPromotionRepository) DeleteByFunction(ctx context.Context, functionID string) error {
+	return r.db.WithContext(ctx).Where("function_id = ?", functionID).Delete(&functions.EnvironmentPromotion{}).Error
+}
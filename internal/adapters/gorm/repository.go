@@ -0,0 +1,142 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// FunctionRepository stores function records in the database.
+type FunctionRepository struct {
+	db *gorm.DB
+}
+
+func NewFunctionRepository(db *gorm.DB) *FunctionRepository {
+	return &FunctionRepository{db: db}
+}
+
+func (r *FunctionRepository) Create(ctx context.Context, fn *functions.Function) error {
+	return functionError(fn, r.db.WithContext(ctx).Create(fn).Error)
+}
+
+func (r *FunctionRepository) Get(ctx context.Context, id string) (*functions.Function, error) {
+	var fn functions.Function
+	err := r.db.WithContext(ctx).First(&fn, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: '%s'", functions.ErrFunctionNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+func (r *FunctionRepository) FindByName(ctx context.Context, name string) (*functions.Function, error) {
+	var fn functions.Function
+	err := r.db.WithContext(ctx).First(&fn, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: named '%s'", functions.ErrFunctionNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+func (r *FunctionRepository) FindByDomain(ctx context.Context, domain string) (*functions.Function, error) {
+	var fn functions.Function
+	err := r.db.WithContext(ctx).First(&fn, "domain = ?", domain).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: with domain '%s'", functions.ErrFunctionNotFound, domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fn, nil
+}
+
+func (r *FunctionRepository) List(ctx context.Context) ([]functions.Function, error) {
+	var fns []functions.Function
+	if err := r.db.WithContext(ctx).Find(&fns).Error; err != nil {
+		return nil, err
+	}
+	return fns, nil
+}
+
+func (r *FunctionRepository) Update(ctx context.Context, fn *functions.Function) error {
+	return functionError(fn, r.db.WithContext(ctx).Save(fn).Error)
+}
+
+// functionError reports violations of the unique name and domain indexes,
+// which is how another replica claiming the same name or domain at once
+// shows up, as ErrNameTaken and ErrDomainTaken.
+func functionError(fn *functions.Function, err error) error {
+	switch {
+	case uniqueViolation(err, "name"):
+		return fmt.Errorf("%w: another function is named '%s'", functions.ErrNameTaken, fn.Name)
+	case uniqueViolation(err, "domain"):
+		return fmt.Errorf("%w: another function has domain '%s'", functions.ErrDomainTaken, fn.Domain)
+	}
+	return err
+}
+
+func (r *FunctionRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&functions.Function{}, "id = ?", id).Error
+}
+
+func (r *FunctionRepository) FindByStatus(ctx context.Context, status string) ([]functions.Function, error) {
+	var fns []functions.Function
+	if err := r.db.WithContext(ctx).Where("status = ?", status).Find(&fns).Error; err != nil {
+		return nil, err
+	}
+	return fns, nil
+}
+
+// RegistryCredentialRepository stores registry credentials in the database,
+// with passwords encrypted by the "encrypted" serializer.
+type RegistryCredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewRegistryCredentialRepository(db *gorm.DB) *RegistryCredentialRepository {
+	return &RegistryCredentialRepository{db: db}
+}
+
+func (r *RegistryCredentialRepository) Find(ctx context.Context, tenant, server string) (*functions.RegistryCredential, error) {
+	var cred functions.RegistryCredential
+	err := r.db.WithContext(ctx).Where("tenant = ? AND server = ?", tenant, server).First(&cred).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: registry credential for '%s'", functions.ErrNotFound, server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (r *RegistryCredentialRepository) ListByTenant(ctx context.Context, tenant string) ([]functions.RegistryCredential, error) {
+	var creds []functions.RegistryCredential
+	if err := r.db.WithContext(ctx).Where("tenant = ?", tenant).Find(&creds).Error; err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+func (r *RegistryCredentialRepository) Save(ctx context.Context, cred *functions.RegistryCredential) error {
+	return r.db.WithContext(ctx).Save(cred).Error
+}
+
+func (r *RegistryCredentialRepository) Delete(ctx context.Context, tenant, id string) error {
+	res := r.db.WithContext(ctx).Where("tenant = ? AND id = ?", tenant, id).Delete(&functions.RegistryCredential{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: registry credential '%s'", functions.ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package gorm
+
+import (
+	"context"
+	"strings"
+
+	"service-faas/internal/core/functions"
+)
+
+// pgSearchDocument is the weighted text searched on Postgres. The
+// idx_functions_search index is built on exactly this expression, so
+// changing it needs a migration that rebuilds the index.
+const pgSearchDocument = `setweight(to_tsvector('simple', coalesce(function_name, '')), 'A') || ` +
+	`setweight(to_tsvector('simple', coalesce(labels, '')), 'B') || ` +
+	`setweight(to_tsvector('simple', coalesce(description, '')), 'C')`
+
+// mysqlSearchColumns are the columns of the FULLTEXT idx_functions_search
+// index; MATCH must name them all.
+const mysqlSearchColumns = "function_name, description, labels"
+
+type searchScore struct {
+	ID    string
+	Score float64
+}
+
+// Search uses the full-text index on Postgres and MySQL. On SQLite, which
+// has no such index, functions are ranked in memory. MySQL's parser keeps
+// underscores inside words, so there "sensor" does not find "read_sensor".
+func (r *FunctionRepository) Search(ctx context.Context, query string, limit int) ([]functions.SearchHit, error) {
+	terms := functions.SearchTerms(query)
+	db := r.db.WithContext(ctx).Table("functions").Where("deleted_at IS NULL")
+
+	var scores []searchScore
+	switch r.db.Dialector.Name() {
+	case "postgres":
+		// Every term must match a word prefix.
+		tsq := strings.Join(terms, ":* & ") + ":*"
+		db = db.Select("id, ts_rank("+pgSearchDocument+", to_tsquery('simple', ?)) AS score", tsq).
+			Where(pgSearchDocument+" @@ to_tsquery('simple', ?)", tsq)
+	case "mysql":
+		against := "+" + strings.Join(terms, "* +") + "*"
+		db = db.Select("id, MATCH("+mysqlSearchColumns+") AGAINST(? IN BOOLEAN MODE) AS score", against).
+			Where("MATCH("+mysqlSearchColumns+") AGAINST(? IN BOOLEAN MODE)", against)
+	default:
+		fns, err := r.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return functions.RankFunctions(fns, query, limit), nil
+	}
+	if err := db.Order("score DESC").Limit(limit).Scan(&scores).Error; err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return []functions.SearchHit{}, nil
+	}
+
+	ids := make([]string, len(scores))
+	for i, sc := range scores {
+		ids[i] = sc.ID
+	}
+	var fns []functions.Function
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&fns).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]functions.Function, len(fns))
+	for _, fn := range fns {
+		byID[fn.ID] = fn
+	}
+
+	hits := make([]functions.SearchHit, 0, len(scores))
+	for _, sc := range scores {
+		if fn, ok := byID[sc.ID]; ok {
+			hits = append(hits, functions.SearchHit{Function: fn, Rank: sc.Score})
+		}
+	}
+	return hits, nil
+}
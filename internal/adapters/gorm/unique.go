@@ -0,0 +1,60 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm/schema"
+)
+
+// NullStringSerializer stores an empty string field, tagged with
+// `gorm:"serializer:nullempty"`, as NULL. Unique indexes ignore NULLs, so the
+// column can be unique among the rows that set it.
+type NullStringSerializer struct{}
+
+func (NullStringSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported column type %T for %s", dbValue, field.Name)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (NullStringSerializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, fieldValue any) (any, error) {
+	v, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("field %s must be a string", field.Name)
+	}
+	if v == "" {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// uniqueViolation reports whether err violates the unique index on the
+// functions column col.
+func uniqueViolation(err error, col string) bool {
+	index := "idx_functions_" + col
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505" && pgErr.ConstraintName == index
+	}
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062 && strings.Contains(myErr.Message, index)
+	}
+	// SQLite names the column rather than the index.
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: functions."+col)
+}
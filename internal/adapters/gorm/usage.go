@@ -0,0 +1,69 @@
+package gorm
+
+import (
+	"context"
+	"time"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageRepository stores hourly usage rollups in the usage_rollups table.
+type UsageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Add upserts every rollup, incrementing the counters of existing rows, in one
+// transaction.
+func (r *UsageRepository) Add(ctx context.Context, rollups []functions.UsageRollup) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ru := range rollups {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "function_id"}, {Name: "hour"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"invocations":   gorm.Expr("usage_rollups.invocations + ?", ru.Invocations),
+					"errors":        gorm.Expr("usage_rollups.errors + ?", ru.Errors),
+					"duration_ms":   gorm.Expr("usage_rollups.duration_ms + ?", ru.DurationMS),
+					"gb_seconds":    gorm.Expr("usage_rollups.gb_seconds + ?", ru.GBSeconds),
+					"cold_starts":   gorm.Expr("usage_rollups.cold_starts + ?", ru.ColdStarts),
+					"cold_start_ms": gorm.Expr("usage_rollups.cold_start_ms + ?", ru.ColdStartMS),
+				}),
+			}).Create(&ru).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *UsageRepository) Range(ctx context.Context, functionID string, from, to time.Time) ([]functions.UsageRollup, error) {
+	var rollups []functions.UsageRollup
+	err := r.db.WithContext(ctx).
+		Where("function_id = ? AND hour >= ? AND hour < ?", functionID, from, to).
+		Order("hour").Find(&rollups).Error
+	if err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+func (r *UsageRepository) Totals(ctx context.Context, from, to time.Time) ([]functions.UsageRollup, error) {
+	var totals []functions.UsageRollup
+	err := r.db.WithContext(ctx).Model(&functions.UsageRollup{}).
+		Select("function_id, SUM(invocations) AS invocations, SUM(errors) AS errors, "+
+			"SUM(duration_ms) AS duration_ms, SUM(gb_seconds) AS gb_seconds, "+
+			"SUM(cold_starts) AS cold_starts, SUM(cold_start_ms) AS cold_start_ms").
+		Where("hour >= ? AND hour < ?", from, to).
+		Group("function_id").Order("function_id").Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
@@ -0,0 +1,43 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+
+	"service-faas/internal/core/functions"
+
+	"gorm.io/gorm"
+)
+
+// WebhookRepository stores webhook registrations in the webhooks table, with
+// secrets encrypted by the "encrypted" serializer.
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, hook *functions.Webhook) error {
+	return r.db.WithContext(ctx).Create(hook).Error
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]functions.Webhook, error) {
+	var hooks []functions.Webhook
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id string) error {
+	res := r.db.WithContext(ctx).Where("id = ?", id).Delete(&functions.Webhook{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: webhook '%s'", functions.ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package knative
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	k8sadapter "service-faas/internal/adapters/kubernetes"
+	"service-faas/internal/adapters/registry"
+	"service-faas/internal/config"
+	"service-faas/internal/core/functions"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	readyTimeout = 3 * time.Minute
+	pollInterval = 2 * time.Second
+)
+
+var serviceGVR = schema.GroupVersionResource{
+	Group:    "serving.knative.dev",
+	Version:  "v1",
+	Resource: "services",
+}
+
+var revisionGVR = schema.GroupVersionResource{
+	Group:    "serving.knative.dev",
+	Version:  "v1",
+	Resource: "revisions",
+}
+
+// Client deploys each function as a Knative Service, which provides
+// request-based autoscaling (including scale to zero) and revisioning.
+type Client struct {
+	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
+	lg        zerolog.Logger
+	cfg       config.Config
+
+	// namespace holds the workers and appName labels and prefixes their
+	// objects, so several managers can share a cluster.
+	namespace string
+	appName   string
+	keychain  *registry.Keychain
+}
+
+func New(cfg config.Config, lg zerolog.Logger, keychain *registry.Keychain) (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	c := &Client{
+		clientset: clientset,
+		dynamic:   dyn,
+		lg:        lg.With().Str("adapter", "knative").Logger(),
+		cfg:       cfg,
+		namespace: cfg.KubernetesNamespace,
+		appName:   cfg.KubernetesAppName,
+		keychain:  keychain,
+	}
+	if err := c.SetRegistryLogin(cfg.HarborUser, cfg.HarborPass); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetRegistryLogin replaces the keychain's Harbor login and rewrites the
+// KUBERNETES_PULL_SECRET secret when the manager creates that secret.
+func (c *Client) SetRegistryLogin(user, password string) error {
+	if err := c.keychain.SetRegistryLogin(user, password); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := k8sadapter.ApplyKeychainPullSecret(ctx, c.clientset, c.namespace, c.cfg, c.keychain)
+	return err
+}
+
+// KeepPullSecret keeps the KUBERNETES_PULL_SECRET secret the manager creates
+// up to date with the keychain's token logins until ctx is done.
+func (c *Client) KeepPullSecret(ctx context.Context, onError func(error)) {
+	k8sadapter.KeepKeychainPullSecret(ctx, c.clientset, c.namespace, c.cfg, c.keychain, onError)
+}
+
+func (c *Client) RunWorker(ctx context.Context, spec functions.WorkerSpec) (*functions.RunResult, error) {
+	funcID := spec.FunctionID
+	serviceName := c.appName + "-" + funcID
+	configMapName := "handler-code-" + funcID
+
+	// The queue-proxy sidecar talks plain HTTP to the user container.
+	if spec.TLS != nil {
+		return nil, fmt.Errorf("worker mTLS is not supported by the knative orchestrator")
+	}
+
+	handlerCode, err := spec.HandlerCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handler code: %w", err)
+	}
+
+	configMap := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: c.namespace,
+		},
+		Data: map[string]string{
+			"handler.py": string(handlerCode),
+		},
+	}
+	_, err = c.clientset.CoreV1().ConfigMaps(c.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = c.clientset.CoreV1().ConfigMaps(c.namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply configmap: %w", err)
+	}
+
+	if spec.RegistryAuth != nil {
+		if err := k8sadapter.ApplyPullSecret(ctx, c.clientset, c.namespace, funcID, spec.RegistryAuth); err != nil {
+			return nil, err
+		}
+	}
+
+	ksvc := c.serviceManifest(serviceName, configMapName, spec)
+	services := c.dynamic.Resource(serviceGVR).Namespace(c.namespace)
+
+	_, err = services.Create(ctx, ksvc, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// Updating the template rolls out a new revision with the current code.
+		existing, getErr := services.Get(ctx, serviceName, metav1.GetOptions{})
+		if getErr != nil {
+			return nil, fmt.Errorf("failed to get knative service: %w", getErr)
+		}
+		ksvc.SetResourceVersion(existing.GetResourceVersion())
+		_, err = services.Update(ctx, ksvc, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply knative service: %w", err)
+	}
+
+	url, err := c.waitForURL(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lg.Info().Str("service", serviceName).Str("url", url).Msg("knative service ready")
+
+	return &functions.RunResult{
+		ContainerID: serviceName,
+		HostPort:    80,
+		Endpoint:    url,
+	}, nil
+}
+
+func (c *Client) StopAndRemoveContainer(ctx context.Context, containerID string) error {
+	if containerID == "" {
+		return nil
+	}
+	serviceName := containerID
+	funcID := containerID[len(c.appName)+1:]
+	configMapName := "handler-code-" + funcID
+
+	err := c.dynamic.Resource(serviceGVR).Namespace(c.namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err := c.clientset.CoreV1().ConfigMaps(c.namespace).Delete(ctx, configMapName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err := k8sadapter.DeletePullSecret(ctx, c.clientset, c.namespace, funcID); err != nil {
+		return err
+	}
+
+	if err := k8sadapter.DeleteIdentitySecret(ctx, c.clientset, c.namespace, funcID); err != nil {
+		return err
+	}
+
+	c.lg.Info().Str("service", serviceName).Msg("deleted knative resources")
+	return nil
+}
+
+// PublishIdentity stores a rotated identity token for the function's pods.
+func (c *Client) PublishIdentity(ctx context.Context, functionID, token string) error {
+	return k8sadapter.ApplyIdentitySecret(ctx, c.clientset, c.namespace, functionID, token)
+}
+
+func (c *Client) serviceManifest(name, configMapName string, spec functions.WorkerSpec) *unstructured.Unstructured {
+	serviceAccount, secretNames := k8sadapter.PodAccess(c.cfg, spec)
+	pullSecrets := []any{}
+	for _, secret := range secretNames {
+		pullSecrets = append(pullSecrets, map[string]any{"name": secret})
+	}
+	funcID := spec.FunctionID
+	env := []any{
+		map[string]any{"name": "HANDLER_FUNCTION", "value": spec.HandlerPath},
+	}
+	for _, name := range slices.Sorted(maps.Keys(spec.Env)) {
+		env = append(env, map[string]any{"name": name, "value": spec.Env[name]})
+	}
+	volumeMounts := []any{
+		map[string]any{"name": "handler-volume", "mountPath": "/app/function"},
+	}
+	volumes := []any{
+		map[string]any{
+			"name":      "handler-volume",
+			"configMap": map[string]any{"name": configMapName},
+		},
+	}
+	if spec.Identity {
+		env = append(env, map[string]any{"name": "FAAS_IDENTITY_TOKEN_FILE", "value": k8sadapter.IdentityMountPath + "/token"})
+		volumeMounts = append(volumeMounts, map[string]any{"name": "identity-volume", "mountPath": k8sadapter.IdentityMountPath, "readOnly": true})
+		volumes = append(volumes, map[string]any{
+			"name":   "identity-volume",
+			"secret": map[string]any{"secretName": k8sadapter.IdentitySecretName(funcID)},
+		})
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": c.namespace,
+			"labels": map[string]any{
+				"app":  c.appName,
+				"func": funcID,
+				// Only reachable through the manager, not via the public ingress.
+				"networking.knative.dev/visibility": "cluster-local",
+			},
+		},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"labels": map[string]any{
+						"app":  c.appName,
+						"func": funcID,
+					},
+					"annotations": map[string]any{
+						"autoscaling.knative.dev/min-scale": "0",
+						"autoscaling.knative.dev/max-scale": "20",
+					},
+				},
+				"spec": map[string]any{
+					"serviceAccountName": serviceAccount,
+					"imagePullSecrets":   pullSecrets,
+					"containers": []any{
+						map[string]any{
+							"name":  c.appName,
+							"image": spec.Image,
+							"env":   env,
+							"ports": []any{
+								map[string]any{"containerPort": int64(c.cfg.WorkerPort)},
+							},
+							"resources": map[string]any{
+								"requests": map[string]any{"cpu": "100m", "memory": "128Mi"},
+								"limits":   map[string]any{"cpu": "500m", "memory": "512Mi"},
+							},
+							"volumeMounts": volumeMounts,
+						},
+					},
+					"volumes": volumes,
+				},
+			},
+		},
+	}}
+}
+
+// waitForURL polls the Knative Service until it reports Ready and returns its
+// cluster-internal address.
+func (c *Client) waitForURL(ctx context.Context, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		ksvc, err := c.dynamic.Resource(serviceGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get knative service: %w", err)
+		}
+		if err == nil && isReady(ksvc) {
+			if url, _, _ := unstructured.NestedString(ksvc.Object, "status", "address", "url"); url != "" {
+				return url, nil
+			}
+			if url, _, _ := unstructured.NestedString(ksvc.Object, "status", "url"); url != "" {
+				return url, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for knative service %s: %w", name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func isReady(ksvc *unstructured.Unstructured) bool {
+	cond := readyCondition(ksvc)
+	return cond != nil && cond["status"] == "True"
+}
+
+// readyCondition returns the service's Ready condition, or nil while it has
+// none.
+func readyCondition(ksvc *unstructured.Unstructured) map[string]any {
+	conditions, _, _ := unstructured.NestedSlice(ksvc.Object, "status", "conditions")
+	for _, raw := range conditions {
+		if cond, ok := raw.(map[string]any); ok && cond["type"] == "Ready" {
+			return cond
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package knative
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// InspectWorker looks the function's Knative Service up. A service scaled to
+// zero is still a live worker; it only counts as dead when Knative reports
+// it as not ready, e.g. because its revision fails to start. Replica counts
+// are those of the latest ready revision.
+func (c *Client) InspectWorker(ctx context.Context, functionID string) (*functions.WorkerState, error) {
+	serviceName := c.appName + "-" + functionID
+	ksvc, err := c.dynamic.Resource(serviceGVR).Namespace(c.namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knative service: %w", err)
+	}
+	state := &functions.WorkerState{ContainerID: serviceName}
+	if cond := readyCondition(ksvc); cond != nil && cond["status"] == "False" {
+		state.Failed = true
+		state.Reason = fmt.Sprintf("%v: %v", cond["reason"], cond["message"])
+		return state, nil
+	}
+	if isReady(ksvc) {
+		if url, _, _ := unstructured.NestedString(ksvc.Object, "status", "address", "url"); url != "" {
+			state.Endpoint = url
+		} else if url, _, _ := unstructured.NestedString(ksvc.Object, "status", "url"); url != "" {
+			state.Endpoint = url
+		}
+	}
+	if name, _, _ := unstructured.NestedString(ksvc.Object, "status", "latestReadyRevisionName"); name != "" {
+		rev, err := c.dynamic.Resource(revisionGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get knative revision: %w", err)
+		}
+		if err == nil {
+			desired, _, _ := unstructured.NestedInt64(rev.Object, "status", "desiredReplicas")
+			actual, _, _ := unstructured.NestedInt64(rev.Object, "status", "actualReplicas")
+			state.Status.DesiredReplicas, state.Status.ReadyReplicas = int(desired), int(actual)
+		}
+	}
+	return state, nil
+}
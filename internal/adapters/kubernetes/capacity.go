@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Capacity compares node allocatable resources with the requests of worker
+// pods. Usage metrics would need metrics-server, so only requests are shown.
+func (c *Client) Capacity(ctx context.Context) (*functions.CapacityReport, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker pods: %w", err)
+	}
+
+	report := &functions.CapacityReport{
+		Backend:      "kubernetes",
+		Nodes:        []functions.NodeCapacity{},
+		Pending:      []functions.PendingPlacement{},
+		TopConsumers: []functions.FunctionUsage{},
+	}
+	byNode := make(map[string]*functions.NodeCapacity, len(nodes.Items))
+	for _, n := range nodes.Items {
+		total := functions.Resources{
+			CPUMillis:   n.Status.Allocatable.Cpu().MilliValue(),
+			MemoryBytes: n.Status.Allocatable.Memory().Value(),
+		}
+		report.Total.CPUMillis += total.CPUMillis
+		report.Total.MemoryBytes += total.MemoryBytes
+		report.Nodes = append(report.Nodes, functions.NodeCapacity{Name: n.Name, Total: total, Functions: []string{}})
+	}
+	for i := range report.Nodes {
+		byNode[report.Nodes[i].Name] = &report.Nodes[i]
+	}
+
+	usage := make(map[string]*functions.FunctionUsage)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed {
+			continue
+		}
+		funcID := pod.Labels["func"]
+		requests := podRequests(&pod)
+
+		if pod.Spec.NodeName == "" || pod.Status.Phase == apiv1.PodPending {
+			report.Pending = append(report.Pending, functions.PendingPlacement{
+				FunctionID: funcID,
+				Worker:     pod.Name,
+				Reason:     pendingReason(&pod),
+			})
+		}
+		if node, ok := byNode[pod.Spec.NodeName]; ok {
+			node.Allocated.CPUMillis += requests.CPUMillis
+			node.Allocated.MemoryBytes += requests.MemoryBytes
+			node.Functions = appendUnique(node.Functions, funcID)
+			report.Allocated.CPUMillis += requests.CPUMillis
+			report.Allocated.MemoryBytes += requests.MemoryBytes
+		}
+
+		u, ok := usage[funcID]
+		if !ok {
+			u = &functions.FunctionUsage{FunctionID: funcID}
+			usage[funcID] = u
+		}
+		u.Workers++
+		u.Allocated.CPUMillis += requests.CPUMillis
+		u.Allocated.MemoryBytes += requests.MemoryBytes
+	}
+
+	for _, u := range usage {
+		report.TopConsumers = append(report.TopConsumers, *u)
+	}
+	report.TopConsumers = functions.RankConsumers(report.TopConsumers)
+	return report, nil
+}
+
+func podRequests(pod *apiv1.Pod) functions.Resources {
+	var r functions.Resources
+	for _, ctr := range pod.Spec.Containers {
+		r.CPUMillis += ctr.Resources.Requests.Cpu().MilliValue()
+		r.MemoryBytes += ctr.Resources.Requests.Memory().Value()
+	}
+	return r
+}
+
+// pendingReason returns the scheduler's explanation for an unscheduled pod,
+// or the waiting reason of a container that has not started.
+func pendingReason(pod *apiv1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == apiv1.PodScheduled && cond.Status == apiv1.ConditionFalse {
+			return cond.Message
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil {
+			return cs.State.Waiting.Reason
+		}
+	}
+	return ""
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -3,11 +3,13 @@ package kubernetes
 import (
 	"context"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
+	"maps"
+	"service-faas/internal/adapters/registry"
 	"service-faas/internal/config"
 	"service-faas/internal/core/functions" // Import the functions package
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog"
 	appsv1 "k8s.io/api/apps/v1"
@@ -17,24 +19,27 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
-const (
-	faasNamespace = "scadable-faas"
-	appName       = "faas-worker"
-)
-
 type Client struct {
 	clientset *kubernetes.Clientset
+	dynamic   dynamic.Interface
 	lg        zerolog.Logger
 	cfg       config.Config
+
+	// namespace holds the workers and appName labels and prefixes their
+	// objects, so several managers can share a cluster.
+	namespace string
+	appName   string
+	keychain  *registry.Keychain
 }
 
 // ✅ FIX: The local RunResult struct is removed.
 
-func New(cfg config.Config, lg zerolog.Logger) (*Client, error) {
+func New(cfg config.Config, lg zerolog.Logger, keychain *registry.Keychain) (*Client, error) {
 	// ... (constructor remains the same)
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -44,54 +49,163 @@ func New(cfg config.Config, lg zerolog.Logger) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create clientset: %w", err)
 	}
-	return &Client{
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	c := &Client{
 		clientset: clientset,
+		dynamic:   dyn,
 		lg:        lg.With().Str("adapter", "kubernetes").Logger(),
 		cfg:       cfg,
-	}, nil
+		namespace: cfg.KubernetesNamespace,
+		appName:   cfg.KubernetesAppName,
+		keychain:  keychain,
+	}
+	if err := c.SetRegistryLogin(cfg.HarborUser, cfg.HarborPass); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetRegistryLogin replaces the keychain's Harbor login and rewrites the
+// KUBERNETES_PULL_SECRET secret when the manager creates that secret.
+func (c *Client) SetRegistryLogin(user, password string) error {
+	if err := c.keychain.SetRegistryLogin(user, password); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := ApplyKeychainPullSecret(ctx, c.clientset, c.namespace, c.cfg, c.keychain)
+	return err
+}
+
+// KeepPullSecret keeps the KUBERNETES_PULL_SECRET secret the manager creates
+// up to date with the keychain's token logins until ctx is done.
+func (c *Client) KeepPullSecret(ctx context.Context, onError func(error)) {
+	KeepKeychainPullSecret(ctx, c.clientset, c.namespace, c.cfg, c.keychain, onError)
 }
 
 // ✅ FIX: The return type is changed to *functions.RunResult
-func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath string) (*functions.RunResult, error) {
-	deploymentName := appName + "-" + funcID
+func (c *Client) RunWorker(ctx context.Context, spec functions.WorkerSpec) (*functions.RunResult, error) {
+	funcID, handlerPath := spec.FunctionID, spec.HandlerPath
+	deploymentName := c.appName + "-" + funcID
 	labels := map[string]string{
-		"app":  appName,
+		"app":  c.appName,
 		"func": funcID,
 	}
+	podLabels := make(map[string]string, len(spec.Labels)+len(labels))
+	maps.Copy(podLabels, spec.Labels)
+	maps.Copy(podLabels, labels)
 
-	// Read the actual Python code from the file
-	handlerFilePath := filepath.Join(codePath, "handler.py")
-	handlerFile, err := os.Open(handlerFilePath)
+	// Read the actual Python code
+	handlerCode, err := spec.HandlerCode(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open handler file: %w", err)
-	}
-	defer handlerFile.Close()
-	
-	handlerCode, err := io.ReadAll(handlerFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read handler file: %w", err)
+		return nil, fmt.Errorf("failed to read handler code: %w", err)
 	}
 
 	// Create a ConfigMap to store the handler code
 	configMap := &apiv1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "handler-code-" + funcID,
-			Namespace: faasNamespace,
+			Namespace: c.namespace,
 		},
 		Data: map[string]string{
 			"handler.py": string(handlerCode), // Store the actual Python code content
 		},
 	}
-	_, err = c.clientset.CoreV1().ConfigMaps(faasNamespace).Create(ctx, configMap, metav1.CreateOptions{})
+	_, err = c.clientset.CoreV1().ConfigMaps(c.namespace).Create(ctx, configMap, metav1.CreateOptions{})
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return nil, fmt.Errorf("failed to create configmap: %w", err)
 	}
 
+	if spec.RegistryAuth != nil {
+		if err := ApplyPullSecret(ctx, c.clientset, c.namespace, funcID, spec.RegistryAuth); err != nil {
+			return nil, err
+		}
+	}
+	serviceAccount, secretNames := PodAccess(c.cfg, spec)
+	var pullSecrets []apiv1.LocalObjectReference
+	for _, name := range secretNames {
+		pullSecrets = append(pullSecrets, apiv1.LocalObjectReference{Name: name})
+	}
+
+	env := []apiv1.EnvVar{
+		{
+			Name:  "HANDLER_FUNCTION",
+			Value: handlerPath,
+		},
+		{
+			Name:  "PORT",
+			Value: strconv.Itoa(c.cfg.WorkerPort),
+		},
+	}
+	for _, name := range slices.Sorted(maps.Keys(spec.Env)) {
+		env = append(env, apiv1.EnvVar{Name: name, Value: spec.Env[name]})
+	}
+	volumeMounts := []apiv1.VolumeMount{
+		{
+			Name:      "handler-volume",
+			MountPath: "/app/function",
+		},
+	}
+	volumes := []apiv1.Volume{
+		{
+			Name: "handler-volume",
+			VolumeSource: apiv1.VolumeSource{
+				ConfigMap: &apiv1.ConfigMapVolumeSource{
+					LocalObjectReference: apiv1.LocalObjectReference{
+						Name: "handler-code-" + funcID,
+					},
+				},
+			},
+		},
+	}
+	if spec.Identity {
+		env = append(env, apiv1.EnvVar{Name: "FAAS_IDENTITY_TOKEN_FILE", Value: IdentityMountPath + "/token"})
+		volumeMounts = append(volumeMounts, apiv1.VolumeMount{
+			Name:      "identity-volume",
+			MountPath: IdentityMountPath,
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, apiv1.Volume{
+			Name: "identity-volume",
+			VolumeSource: apiv1.VolumeSource{
+				Secret: &apiv1.SecretVolumeSource{SecretName: IdentitySecretName(funcID)},
+			},
+		})
+	}
+
+	scheme := "http"
+	if spec.TLS != nil {
+		if err := c.applyWorkerTLS(ctx, funcID, spec.TLS); err != nil {
+			return nil, err
+		}
+		env = append(env,
+			apiv1.EnvVar{Name: "WORKER_TLS_CERT_FILE", Value: workerTLSMountPath + "/tls.crt"},
+			apiv1.EnvVar{Name: "WORKER_TLS_KEY_FILE", Value: workerTLSMountPath + "/tls.key"},
+			apiv1.EnvVar{Name: "WORKER_TLS_CLIENT_CA_FILE", Value: workerTLSMountPath + "/ca.crt"},
+		)
+		volumeMounts = append(volumeMounts, apiv1.VolumeMount{
+			Name:      "tls-volume",
+			MountPath: workerTLSMountPath,
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, apiv1.Volume{
+			Name: "tls-volume",
+			VolumeSource: apiv1.VolumeSource{
+				Secret: &apiv1.SecretVolumeSource{SecretName: workerTLSSecretName(funcID)},
+			},
+		})
+		scheme = "https"
+	}
+
 	// Create Deployment
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      deploymentName,
-			Namespace: faasNamespace,
+			Namespace: c.namespace,
+			Labels:    podLabels,
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: int32Ptr(1),
@@ -100,26 +214,19 @@ func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath st
 			},
 			Template: apiv1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels: podLabels,
 				},
 				Spec: apiv1.PodSpec{
-					ServiceAccountName: "faas-manager-sa",
-					ImagePullSecrets: []apiv1.LocalObjectReference{
-						{Name: "harbor-registry-secret"},
-					},
+					ServiceAccountName: serviceAccount,
+					ImagePullSecrets:   pullSecrets,
 					Containers: []apiv1.Container{
 						{
-							Name:  appName,
-							Image: c.cfg.WorkerImage,
-							Env: []apiv1.EnvVar{
-								{
-									Name:  "HANDLER_FUNCTION",
-									Value: handlerPath,
-								},
-							},
+							Name:  c.appName,
+							Image: spec.Image,
+							Env:   env,
 							Ports: []apiv1.ContainerPort{
 								{
-									ContainerPort: 8000,
+									ContainerPort: int32(c.cfg.WorkerPort),
 								},
 							},
 							Resources: apiv1.ResourceRequirements{
@@ -132,56 +239,62 @@ func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath st
 									apiv1.ResourceMemory: resource.MustParse("512Mi"),
 								},
 							},
-							VolumeMounts: []apiv1.VolumeMount{
-								{
-									Name:      "handler-volume",
-									MountPath: "/app/function",
-								},
-							},
-						},
-					},
-					Volumes: []apiv1.Volume{
-						{
-							Name: "handler-volume",
-							VolumeSource: apiv1.VolumeSource{
-								ConfigMap: &apiv1.ConfigMapVolumeSource{
-									LocalObjectReference: apiv1.LocalObjectReference{
-										Name: "handler-code-" + funcID,
-									},
-								},
-							},
+							VolumeMounts: volumeMounts,
 						},
 					},
+					Volumes: volumes,
 				},
 			},
 		},
 	}
 
-	_, err = c.clientset.AppsV1().Deployments(faasNamespace).Create(ctx, deployment, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	// An existing Deployment is updated, so a redeploy on a new image is
+	// rolled out.
+	deployments := c.clientset.AppsV1().Deployments(c.namespace)
+	_, err = deployments.Create(ctx, deployment, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		var current *appsv1.Deployment
+		if current, err = deployments.Get(ctx, deploymentName, metav1.GetOptions{}); err == nil {
+			// Keep the replica count the autoscaler chose.
+			deployment.Spec.Replicas = current.Spec.Replicas
+			deployment.ResourceVersion = current.ResourceVersion
+			_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to create deployment: %w", err)
 	}
 
-	// Create Service
+	// Create Service. Workers are reached through cluster DNS, so a ClusterIP
+	// service is enough; NodePort exposure is an explicit opt-in.
+	serviceName := "service-" + funcID
+	serviceType := apiv1.ServiceTypeClusterIP
+	if c.cfg.KubernetesNodePort {
+		serviceType = apiv1.ServiceTypeNodePort
+	}
 	service := &apiv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "service-" + funcID,
-			Namespace: faasNamespace,
+			Name:      serviceName,
+			Namespace: c.namespace,
+			Labels:    labels,
 		},
 		Spec: apiv1.ServiceSpec{
 			Selector: labels,
-			Type:     apiv1.ServiceTypeNodePort,
+			Type:     serviceType,
 			Ports: []apiv1.ServicePort{
 				{
 					Port:       80,
-					TargetPort: intstr.FromInt(8000),
+					TargetPort: intstr.FromInt(c.cfg.WorkerPort),
 				},
 			},
 		},
 	}
 
-	createdService, err := c.clientset.CoreV1().Services(faasNamespace).Create(ctx, service, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	createdService, err := c.clientset.CoreV1().Services(c.namespace).Create(ctx, service, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		createdService, err = c.clientset.CoreV1().Services(c.namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to create service: %w", err)
 	}
 
@@ -189,7 +302,8 @@ func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath st
 	hpa := &autoscalingv2.HorizontalPodAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "hpa-" + funcID,
-			Namespace: faasNamespace,
+			Namespace: c.namespace,
+			Labels:    labels,
 		},
 		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
 			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
@@ -246,7 +360,7 @@ func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath st
 		},
 	}
 
-	_, err = c.clientset.AutoscalingV2().HorizontalPodAutoscalers(faasNamespace).Create(ctx, hpa, metav1.CreateOptions{})
+	_, err = c.clientset.AutoscalingV2().HorizontalPodAutoscalers(c.namespace).Create(ctx, hpa, metav1.CreateOptions{})
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return nil, fmt.Errorf("failed to create HPA: %w", err)
 	}
@@ -254,40 +368,76 @@ func (c *Client) RunWorker(ctx context.Context, funcID, codePath, handlerPath st
 	c.lg.Info().Str("deployment", deploymentName).Msg("created kubernetes deployment, service, and HPA")
 
 	// ✅ FIX: Return a *functions.RunResult struct
+	hostPort := int(createdService.Spec.Ports[0].Port)
+	if createdService.Spec.Type == apiv1.ServiceTypeNodePort {
+		hostPort = int(createdService.Spec.Ports[0].NodePort)
+	}
+
+	var publicURL string
+	if spec.Exposure != nil {
+		publicURL, err = c.applyExposure(ctx, funcID, serviceName, spec.Exposure)
+		if err != nil {
+			return nil, err
+		}
+		c.lg.Info().Str("function_id", funcID).Str("url", publicURL).Msg("exposed function")
+	}
+
 	return &functions.RunResult{
 		ContainerID: deploymentName,
-		HostPort:    int(createdService.Spec.Ports[0].NodePort),
+		HostPort:    hostPort,
+		Endpoint:    fmt.Sprintf("%s://%s.%s.svc.cluster.local:80", scheme, serviceName, c.namespace),
+		PublicURL:   publicURL,
 	}, nil
 }
 
 // ... (StopAndRemoveContainer and int32Ptr methods remain the same) ...
 func (c *Client) StopAndRemoveContainer(ctx context.Context, containerID string) error {
 	deploymentName := containerID
-	funcID := containerID[len(appName)+1:] // Extract function ID from container name
+	funcID := containerID[len(c.appName)+1:] // Extract function ID from container name
 	serviceName := "service-" + funcID
 	configMapName := "handler-code-" + funcID
 	hpaName := "hpa-" + funcID
 
 	// Delete HPA
-	if err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(faasNamespace).Delete(ctx, hpaName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(c.namespace).Delete(ctx, hpaName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 		c.lg.Warn().Err(err).Str("hpa", hpaName).Msg("failed to delete HPA")
 	}
 
 	// Delete Deployment
 	deletePolicy := metav1.DeletePropagationForeground
-	if err := c.clientset.AppsV1().Deployments(faasNamespace).Delete(ctx, deploymentName, metav1.DeleteOptions{
+	if err := c.clientset.AppsV1().Deployments(c.namespace).Delete(ctx, deploymentName, metav1.DeleteOptions{
 		PropagationPolicy: &deletePolicy,
 	}); err != nil && !errors.IsNotFound(err) {
 		return err
 	}
 
 	// Delete Service
-	if err := c.clientset.CoreV1().Services(faasNamespace).Delete(ctx, serviceName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().Services(c.namespace).Delete(ctx, serviceName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 		return err
 	}
 
 	// Delete ConfigMap
-	if err := c.clientset.CoreV1().ConfigMaps(faasNamespace).Delete(ctx, configMapName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().ConfigMaps(c.namespace).Delete(ctx, configMapName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	// Delete Ingress or HTTPRoute
+	if err := c.deleteExposure(ctx, funcID); err != nil {
+		return err
+	}
+
+	// Delete tenant pull secret
+	if err := DeletePullSecret(ctx, c.clientset, c.namespace, funcID); err != nil {
+		return err
+	}
+
+	// Delete identity token secret
+	if err := DeleteIdentitySecret(ctx, c.clientset, c.namespace, funcID); err != nil {
+		return err
+	}
+
+	// Delete worker certificate secret
+	if err := c.deleteWorkerTLS(ctx, funcID); err != nil {
 		return err
 	}
 
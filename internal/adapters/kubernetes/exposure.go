@@ -0,0 +1,243 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var httpRouteGVR = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+func routeName(funcID string) string {
+	return "route-" + funcID
+}
+
+func domainRouteName(funcID string) string {
+	return "domain-" + funcID
+}
+
+// applyExposure creates the Ingress or HTTPRoute that makes the function
+// reachable without going through the manager, and returns its public URL.
+// Requests are rewritten to "/" because workers serve on the root path.
+func (c *Client) applyExposure(ctx context.Context, funcID, serviceName string, exp *functions.Exposure) (string, error) {
+	path := exp.Path
+	if path == "" {
+		path = "/fn/" + funcID
+	}
+
+	switch exp.Kind {
+	case functions.ExposeIngress:
+		if err := c.applyIngress(ctx, funcID, serviceName, exp.Host, path); err != nil {
+			return "", err
+		}
+	case functions.ExposeHTTPRoute:
+		if err := c.applyHTTPRoute(ctx, funcID, serviceName, exp.Host, path); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown exposure kind %q", exp.Kind)
+	}
+
+	if exp.Host == "" {
+		return path, nil
+	}
+	return "http://" + exp.Host + path, nil
+}
+
+func (c *Client) applyIngress(ctx context.Context, funcID, serviceName, host, path string) error {
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      routeName(funcID),
+			Namespace: c.namespace,
+			Labels:    map[string]string{"app": c.appName, "func": funcID},
+			Annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/rewrite-target": "/",
+			},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     path,
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: serviceName,
+									Port: networkingv1.ServiceBackendPort{Number: 80},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if c.cfg.KubernetesIngressClass != "" {
+		ingress.Spec.IngressClassName = &c.cfg.KubernetesIngressClass
+	}
+
+	ingresses := c.clientset.NetworkingV1().Ingresses(c.namespace)
+	_, err := ingresses.Create(ctx, ingress, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = ingresses.Update(ctx, ingress, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply ingress: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) applyHTTPRoute(ctx context.Context, funcID, serviceName, host, path string) error {
+	if c.cfg.KubernetesGateway == "" {
+		return fmt.Errorf("KUBERNETES_GATEWAY must be set to create HTTPRoutes")
+	}
+	gatewayNamespace := c.cfg.KubernetesGatewayNamespace
+	if gatewayNamespace == "" {
+		gatewayNamespace = c.namespace
+	}
+
+	spec := map[string]any{
+		"parentRefs": []any{
+			map[string]any{"name": c.cfg.KubernetesGateway, "namespace": gatewayNamespace},
+		},
+		"rules": []any{
+			map[string]any{
+				"matches": []any{
+					map[string]any{"path": map[string]any{"type": "PathPrefix", "value": path}},
+				},
+				"filters": []any{
+					map[string]any{
+						"type": "URLRewrite",
+						"urlRewrite": map[string]any{
+							"path": map[string]any{"type": "ReplacePrefixMatch", "replacePrefixMatch": "/"},
+						},
+					},
+				},
+				"backendRefs": []any{
+					map[string]any{"name": serviceName, "port": int64(80)},
+				},
+			},
+		},
+	}
+	if host != "" {
+		spec["hostnames"] = []any{host}
+	}
+	route := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata": map[string]any{
+			"name":      routeName(funcID),
+			"namespace": c.namespace,
+			"labels":    map[string]any{"app": c.appName, "func": funcID},
+		},
+		"spec": spec,
+	}}
+
+	routes := c.dynamic.Resource(httpRouteGVR).Namespace(c.namespace)
+	_, err := routes.Create(ctx, route, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		existing, getErr := routes.Get(ctx, routeName(funcID), metav1.GetOptions{})
+		if getErr != nil {
+			return fmt.Errorf("failed to get httproute: %w", getErr)
+		}
+		route.SetResourceVersion(existing.GetResourceVersion())
+		_, err = routes.Update(ctx, route, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply httproute: %w", err)
+	}
+	return nil
+}
+
+// deleteExposure removes whichever route object exists for the function.
+func (c *Client) deleteExposure(ctx context.Context, funcID string) error {
+	err := c.clientset.NetworkingV1().Ingresses(c.namespace).Delete(ctx, routeName(funcID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	err = c.dynamic.Resource(httpRouteGVR).Namespace(c.namespace).Delete(ctx, routeName(funcID), metav1.DeleteOptions{})
+	// Without the Gateway API CRDs installed there is nothing to delete.
+	if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return err
+	}
+	return nil
+}
+
+// RouteDomain creates the Ingress that routes a function's custom domain to
+// the manager's Service, which serves the function for that host, or removes
+// it when domain is empty. With a cert issuer the Ingress asks cert-manager
+// for a certificate. Without KubernetesDomainService it does nothing.
+func (c *Client) RouteDomain(ctx context.Context, funcID, domain string) error {
+	if c.cfg.KubernetesDomainService == "" {
+		return nil
+	}
+	ingresses := c.clientset.NetworkingV1().Ingresses(c.namespace)
+	if domain == "" {
+		err := ingresses.Delete(ctx, domainRouteName(funcID), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete domain ingress: %w", err)
+		}
+		return nil
+	}
+
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      domainRouteName(funcID),
+			Namespace: c.namespace,
+			Labels:    map[string]string{"app": c.appName, "func": funcID},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: domain,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: c.cfg.KubernetesDomainService,
+									Port: networkingv1.ServiceBackendPort{Number: 80},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if c.cfg.KubernetesIngressClass != "" {
+		ingress.Spec.IngressClassName = &c.cfg.KubernetesIngressClass
+	}
+	if c.cfg.KubernetesCertIssuer != "" {
+		ingress.Annotations = map[string]string{"cert-manager.io/cluster-issuer": c.cfg.KubernetesCertIssuer}
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{
+			Hosts:      []string{domain},
+			SecretName: domainRouteName(funcID) + "-tls",
+		}}
+	}
+
+	_, err := ingresses.Create(ctx, ingress, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = ingresses.Update(ctx, ingress, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply domain ingress: %w", err)
+	}
+	return nil
+}
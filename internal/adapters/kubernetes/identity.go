@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// IdentityMountPath is where workers find their identity token. The kubelet
+// refreshes the mounted file when the secret is updated.
+const IdentityMountPath = "/var/run/secrets/faas-identity"
+
+// IdentitySecretName is the name of the secret holding a function's current
+// identity token.
+func IdentitySecretName(funcID string) string {
+	return "identity-" + funcID
+}
+
+// ApplyIdentitySecret creates or updates the function's identity token secret.
+func ApplyIdentitySecret(ctx context.Context, clientset kubernetes.Interface, namespace, funcID, token string) error {
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IdentitySecretName(funcID),
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"token": []byte(token),
+		},
+	}
+	_, err := clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply identity secret: %w", err)
+	}
+	return nil
+}
+
+// DeleteIdentitySecret removes the function's identity secret if there is one.
+func DeleteIdentitySecret(ctx context.Context, clientset kubernetes.Interface, namespace, funcID string) error {
+	err := clientset.CoreV1().Secrets(namespace).Delete(ctx, IdentitySecretName(funcID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// PublishIdentity stores a rotated identity token for the function's pods.
+func (c *Client) PublishIdentity(ctx context.Context, functionID, token string) error {
+	return ApplyIdentitySecret(ctx, c.clientset, c.namespace, functionID, token)
+}
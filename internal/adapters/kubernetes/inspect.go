@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"slices"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// deadReasons are the waiting reasons of containers Kubernetes keeps failing
+// to run.
+var deadReasons = []string{"CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "CreateContainerConfigError", "InvalidImageName"}
+
+// InspectWorker looks the function's Deployment up. Kubernetes replaces
+// crashed pods itself, so the worker only counts as dead when no replica is
+// available and its pods are stuck failing. Restarts are summed over the
+// containers of the current pods.
+func (c *Client) InspectWorker(ctx context.Context, functionID string) (*functions.WorkerState, error) {
+	deploymentName := c.appName + "-" + functionID
+	deployment, err := c.clientset.AppsV1().Deployments(c.namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if errors.IsNotFound(err) || (err == nil && deployment.DeletionTimestamp != nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	state := &functions.WorkerState{
+		ContainerID: deploymentName,
+		Status:      functions.WorkerStatus{ReadyReplicas: int(deployment.Status.ReadyReplicas)},
+	}
+	if deployment.Spec.Replicas != nil {
+		state.Status.DesiredReplicas = int(*deployment.Spec.Replicas)
+	}
+
+	service, err := c.clientset.CoreV1().Services(c.namespace).Get(ctx, "service-"+functionID, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		// Without its Service the worker cannot be reached.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	if len(service.Spec.Ports) > 0 {
+		state.HostPort = int(service.Spec.Ports[0].Port)
+		if service.Spec.Type == apiv1.ServiceTypeNodePort {
+			state.HostPort = int(service.Spec.Ports[0].NodePort)
+		}
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName + ",func=" + functionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			state.Status.Restarts += int(cs.RestartCount)
+			if w := cs.State.Waiting; w != nil && slices.Contains(deadReasons, w.Reason) && deployment.Status.AvailableReplicas == 0 && !state.Failed {
+				state.Failed = true
+				state.Reason = fmt.Sprintf("pod %s: %s: %s", pod.Name, w.Reason, w.Message)
+			}
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxLogLine is the longest worker log line read; longer lines fail the
+// request.
+const maxLogLine = 1 << 20
+
+// WorkerLogs reads the logs of the worker container of the function's pods
+// that have started, all at once. Pods started later are not followed.
+func (c *Client) WorkerLogs(ctx context.Context, functionID string, opts functions.LogOptions, emit func(functions.LogLine) error) error {
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName + ",func=" + functionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list worker pods: %w", err)
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != apiv1.PodPending {
+			names = append(names, pod.Name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		go func() { errs <- c.podLogs(ctx, name, opts, emit) }()
+	}
+	var first error
+	for range names {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
+
+func (c *Client) podLogs(ctx context.Context, pod string, opts functions.LogOptions, emit func(functions.LogLine) error) error {
+	tail := int64(opts.Tail)
+	logOpts := &apiv1.PodLogOptions{
+		Container:  c.appName,
+		Follow:     opts.Follow,
+		TailLines:  &tail,
+		Timestamps: true,
+	}
+	if !opts.Since.IsZero() {
+		since := metav1.NewTime(opts.Since)
+		logOpts.SinceTime = &since
+	}
+	stream, err := c.clientset.CoreV1().Pods(c.namespace).GetLogs(pod, logOpts).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read logs of pod %s: %w", pod, err)
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLine)
+	for scanner.Scan() {
+		t, text := functions.ParseLogLine(scanner.Text())
+		if err := emit(functions.LogLine{Instance: pod, Time: t, Line: text}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read logs of pod %s: %w", pod, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"service-faas/internal/adapters/registry"
+	"service-faas/internal/config"
+	"service-faas/internal/core/functions"
+	"slices"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PullSecretName is the name of the per-function image pull secret holding
+// tenant registry credentials.
+func PullSecretName(funcID string) string {
+	return "pull-secret-" + funcID
+}
+
+// ApplyPullSecret creates or updates the function's dockerconfigjson secret.
+func ApplyPullSecret(ctx context.Context, clientset kubernetes.Interface, namespace, funcID string, auth *functions.RegistryAuth) error {
+	return applyRegistrySecret(ctx, clientset, namespace, PullSecretName(funcID), []functions.RegistryAuth{*auth})
+}
+
+// ApplyKeychainPullSecret writes the keychain's logins to the
+// KUBERNETES_PULL_SECRET secret when the manager is configured to create it,
+// and returns when the keychain fetches the first of them again. Logins the
+// keychain could not fetch are left out and reported in the error.
+func ApplyKeychainPullSecret(ctx context.Context, clientset kubernetes.Interface, namespace string, cfg config.Config, keychain *registry.Keychain) (time.Time, error) {
+	if !cfg.KubernetesCreatePullSecret || cfg.KubernetesPullSecret == "" {
+		return time.Time{}, nil
+	}
+	logins, refresh, err := keychain.Logins(ctx)
+	if len(logins) == 0 {
+		return refresh, err
+	}
+	if applyErr := applyRegistrySecret(ctx, clientset, namespace, cfg.KubernetesPullSecret, logins); applyErr != nil {
+		return refresh, applyErr
+	}
+	return refresh, err
+}
+
+// KeepKeychainPullSecret rewrites the KUBERNETES_PULL_SECRET secret before
+// the token logins in it expire, and every hour to pick up other changes,
+// until ctx is done. onError is called with each failure.
+func KeepKeychainPullSecret(ctx context.Context, clientset kubernetes.Interface, namespace string, cfg config.Config, keychain *registry.Keychain, onError func(error)) {
+	if !cfg.KubernetesCreatePullSecret {
+		return
+	}
+	for {
+		wait := time.Hour
+		refresh, err := ApplyKeychainPullSecret(ctx, clientset, namespace, cfg, keychain)
+		if err != nil {
+			onError(err)
+			wait = time.Minute
+		}
+		if !refresh.IsZero() && time.Until(refresh) < wait {
+			wait = max(time.Until(refresh), time.Minute)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// PodAccess returns the service account and image pull secrets of a
+// function's pods: the configured ones unless the function overrides them,
+// plus its tenant pull secret when it pulls with tenant credentials.
+func PodAccess(cfg config.Config, spec functions.WorkerSpec) (serviceAccount string, pullSecrets []string) {
+	serviceAccount = cfg.KubernetesServiceAccount
+	if cfg.KubernetesPullSecret != "" {
+		pullSecrets = []string{cfg.KubernetesPullSecret}
+	}
+	if k := spec.Kubernetes; k != nil {
+		if k.ServiceAccount != "" {
+			serviceAccount = k.ServiceAccount
+		}
+		if len(k.ImagePullSecrets) > 0 {
+			pullSecrets = k.ImagePullSecrets
+		}
+	}
+	if spec.RegistryAuth != nil {
+		pullSecrets = append(slices.Clip(pullSecrets), PullSecretName(spec.FunctionID))
+	}
+	return serviceAccount, pullSecrets
+}
+
+func applyRegistrySecret(ctx context.Context, clientset kubernetes.Interface, namespace, name string, logins []functions.RegistryAuth) error {
+	auths := make(map[string]any, len(logins))
+	for _, auth := range logins {
+		auths[registry.ConfigKey(auth.Server)] = map[string]string{
+			"username": auth.Username,
+			"password": auth.Password,
+			"auth":     base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password)),
+		}
+	}
+	dockerConfig, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return fmt.Errorf("marshal docker config: %w", err)
+	}
+
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: apiv1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			apiv1.DockerConfigJsonKey: dockerConfig,
+		},
+	}
+	_, err = clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply pull secret: %w", err)
+	}
+	return nil
+}
+
+// DeletePullSecret removes the function's pull secret if there is one.
+func DeletePullSecret(ctx context.Context, clientset kubernetes.Interface, namespace, funcID string) error {
+	err := clientset.CoreV1().Secrets(namespace).Delete(ctx, PullSecretName(funcID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
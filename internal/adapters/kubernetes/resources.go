@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListWorkerResources lists the Deployments, Services and
+// HorizontalPodAutoscalers of workers in the namespace. Services and HPAs
+// created before they were labelled are recognized by their selector and
+// scale target.
+func (c *Client) ListWorkerResources(ctx context.Context) ([]functions.WorkerResource, error) {
+	resources := []functions.WorkerResource{}
+
+	deployments, err := c.clientset.AppsV1().Deployments(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		desired := int32(0)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		resources = append(resources, functions.WorkerResource{
+			Kind:       functions.ResourceDeployment,
+			Name:       d.Name,
+			FunctionID: d.Labels["func"],
+			Status:     fmt.Sprintf("%d/%d ready", d.Status.ReadyReplicas, desired),
+			CreatedAt:  d.CreationTimestamp.UTC(),
+		})
+	}
+
+	services, err := c.clientset.CoreV1().Services(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	for _, s := range services.Items {
+		funcID := s.Labels["func"]
+		switch {
+		case s.Labels["app"] == c.appName:
+		case s.Spec.Selector["app"] == c.appName:
+			funcID = s.Spec.Selector["func"]
+		default:
+			continue
+		}
+		resources = append(resources, functions.WorkerResource{
+			Kind:       functions.ResourceService,
+			Name:       s.Name,
+			FunctionID: funcID,
+			Status:     string(s.Spec.Type),
+			CreatedAt:  s.CreationTimestamp.UTC(),
+		})
+	}
+
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(c.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list HPAs: %w", err)
+	}
+	for _, h := range hpas.Items {
+		funcID := h.Labels["func"]
+		if h.Labels["app"] != c.appName {
+			target := h.Spec.ScaleTargetRef
+			id, ok := strings.CutPrefix(target.Name, c.appName+"-")
+			if target.Kind != "Deployment" || !ok {
+				continue
+			}
+			funcID = id
+		}
+		resources = append(resources, functions.WorkerResource{
+			Kind:       functions.ResourceHPA,
+			Name:       h.Name,
+			FunctionID: funcID,
+			Status:     fmt.Sprintf("%d replicas (%d-%d)", h.Status.CurrentReplicas, minReplicas(h.Spec.MinReplicas), h.Spec.MaxReplicas),
+			CreatedAt:  h.CreationTimestamp.UTC(),
+		})
+	}
+	return resources, nil
+}
+
+// DeleteWorkerResource deletes one Deployment, Service or HPA. Unlike
+// StopAndRemoveContainer it leaves the worker's other resources alone.
+func (c *Client) DeleteWorkerResource(ctx context.Context, res functions.WorkerResource) error {
+	var err error
+	switch res.Kind {
+	case functions.ResourceDeployment:
+		deletePolicy := metav1.DeletePropagationForeground
+		err = c.clientset.AppsV1().Deployments(c.namespace).Delete(ctx, res.Name, metav1.DeleteOptions{
+			PropagationPolicy: &deletePolicy,
+		})
+	case functions.ResourceService:
+		err = c.clientset.CoreV1().Services(c.namespace).Delete(ctx, res.Name, metav1.DeleteOptions{})
+	case functions.ResourceHPA:
+		err = c.clientset.AutoscalingV2().HorizontalPodAutoscalers(c.namespace).Delete(ctx, res.Name, metav1.DeleteOptions{})
+	default:
+		return fmt.Errorf("%w: kubernetes has no worker %s resources", functions.ErrInvalidArgument, res.Kind)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	c.lg.Info().Str("kind", res.Kind).Str("name", res.Name).Str("function_id", res.FunctionID).Msg("deleted kubernetes resource")
+	return nil
+}
+
+// minReplicas is an HPA's minimum replica count, which defaults to one.
+func minReplicas(n *int32) int32 {
+	if n == nil {
+		return 1
+	}
+	return *n
+}
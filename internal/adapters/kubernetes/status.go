@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// maxEvents is how many of the latest events DescribeWorker returns.
+const maxEvents = 20
+
+// DescribeWorker reports the function's Deployment with the phases and
+// container states of its pods, and the events of the Deployment and pods
+// that Kubernetes still keeps, by default those of the last hour.
+func (c *Client) DescribeWorker(ctx context.Context, functionID string) (*functions.WorkerDescription, error) {
+	deploymentName := c.appName + "-" + functionID
+	deployment, err := c.clientset.AppsV1().Deployments(c.namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	desc := &functions.WorkerDescription{
+		WorkerStatus: functions.WorkerStatus{ReadyReplicas: int(deployment.Status.ReadyReplicas)},
+		Instances:    []functions.WorkerInstance{},
+	}
+	if deployment.Spec.Replicas != nil {
+		desc.DesiredReplicas = int(*deployment.Spec.Replicas)
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName + ",func=" + functionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker pods: %w", err)
+	}
+	objects := []string{deploymentName}
+	for _, pod := range pods.Items {
+		objects = append(objects, pod.Name)
+		instance := functions.WorkerInstance{
+			Name:       pod.Name,
+			Phase:      string(pod.Status.Phase),
+			Node:       pod.Spec.NodeName,
+			StartedAt:  timePtr(pod.Status.StartTime),
+			Containers: []functions.ContainerState{},
+		}
+		if pod.Status.Reason != "" {
+			instance.Phase += " (" + pod.Status.Reason + ")"
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			desc.Restarts += int(cs.RestartCount)
+			instance.Containers = append(instance.Containers, containerState(cs))
+		}
+		desc.Instances = append(desc.Instances, instance)
+	}
+
+	desc.Events = []functions.WorkerLogEntry{}
+	for _, name := range objects {
+		events, err := c.clientset.CoreV1().Events(c.namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "involvedObject.name=" + name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events: %w", err)
+		}
+		for _, e := range events.Items {
+			desc.Events = append(desc.Events, functions.WorkerLogEntry{
+				Time:    eventTime(&e),
+				Type:    e.Type,
+				Reason:  e.Reason,
+				Message: e.Message,
+				Object:  e.InvolvedObject.Kind + "/" + name,
+			})
+		}
+	}
+	sort.SliceStable(desc.Events, func(i, j int) bool { return desc.Events[i].Time.Before(desc.Events[j].Time) })
+	if len(desc.Events) > maxEvents {
+		desc.Events = desc.Events[len(desc.Events)-maxEvents:]
+	}
+	return desc, nil
+}
+
+func containerState(cs apiv1.ContainerStatus) functions.ContainerState {
+	state := functions.ContainerState{Name: cs.Name, Ready: cs.Ready, Restarts: int(cs.RestartCount)}
+	switch s := cs.State; {
+	case s.Running != nil:
+		state.State = "running"
+		state.StartedAt = timePtr(&s.Running.StartedAt)
+	case s.Waiting != nil:
+		state.State = "waiting"
+		state.Reason, state.Message = s.Waiting.Reason, s.Waiting.Message
+	case s.Terminated != nil:
+		state.State = "terminated"
+		state.Reason, state.Message = s.Terminated.Reason, s.Terminated.Message
+		code := int(s.Terminated.ExitCode)
+		state.ExitCode = &code
+		state.StartedAt = timePtr(&s.Terminated.StartedAt)
+		state.FinishedAt = timePtr(&s.Terminated.FinishedAt)
+	}
+	if t := cs.LastTerminationState.Terminated; t != nil {
+		state.LastTermination = &functions.ContainerTermination{
+			Reason:     t.Reason,
+			Message:    t.Message,
+			ExitCode:   int(t.ExitCode),
+			FinishedAt: t.FinishedAt.UTC(),
+		}
+	}
+	return state
+}
+
+// eventTime is when an event last occurred, whichever of its timestamps the
+// reporting component set.
+func eventTime(e *apiv1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.UTC()
+	case !e.EventTime.IsZero():
+		return e.EventTime.UTC()
+	default:
+		return e.FirstTimestamp.UTC()
+	}
+}
+
+func timePtr(t *metav1.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+var podMetricsGVR = schema.GroupVersionResource{
+	Group:    "metrics.k8s.io",
+	Version:  "v1beta1",
+	Resource: "pods",
+}
+
+// WorkerStats sums the usage metrics-server reports for the worker's pods.
+// Without metrics-server the request fails and no usage is reported.
+func (c *Client) WorkerStats(ctx context.Context, functionID string) (*functions.Resources, error) {
+	list, err := c.dynamic.Resource(podMetricsGVR).Namespace(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName + ",func=" + functionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	usage := &functions.Resources{}
+	for _, item := range list.Items {
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, container := range containers {
+			m, _ := container.(map[string]any)
+			cpu, _, _ := unstructured.NestedString(m, "usage", "cpu")
+			mem, _, _ := unstructured.NestedString(m, "usage", "memory")
+			if q, err := resource.ParseQuantity(cpu); err == nil {
+				usage.CPUMillis += q.MilliValue()
+			}
+			if q, err := resource.ParseQuantity(mem); err == nil {
+				usage.MemoryBytes += q.Value()
+			}
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/core/functions"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// workerTLSMountPath is where workers find their certificate material.
+const workerTLSMountPath = "/var/run/secrets/faas-tls"
+
+func workerTLSSecretName(funcID string) string {
+	return "worker-tls-" + funcID
+}
+
+// applyWorkerTLS creates or updates the function's worker certificate secret.
+func (c *Client) applyWorkerTLS(ctx context.Context, funcID string, wt *functions.WorkerTLS) error {
+	secret := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workerTLSSecretName(funcID),
+			Namespace: c.namespace,
+		},
+		Type: apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       wt.CertPEM,
+			apiv1.TLSPrivateKeyKey: wt.KeyPEM,
+			"ca.crt":               wt.CAPEM,
+		},
+	}
+	secrets := c.clientset.CoreV1().Secrets(c.namespace)
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply worker tls secret: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) deleteWorkerTLS(ctx context.Context, funcID string) error {
+	err := c.clientset.CoreV1().Secrets(c.namespace).Delete(ctx, workerTLSSecretName(funcID), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
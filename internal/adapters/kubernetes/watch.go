@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"service-faas/internal/core/functions"
+	"slices"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WatchWorkers watches worker pods and reports evictions, container restarts
+// (e.g. after an OOM kill) and containers that keep failing to start. Pods
+// already there when the watch starts only set the baseline restart counts.
+func (c *Client) WatchWorkers(ctx context.Context, out chan<- functions.WorkerEvent) error {
+	w, err := c.clientset.CoreV1().Pods(c.namespace).Watch(ctx, metav1.ListOptions{
+		LabelSelector: "app=" + c.appName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch worker pods: %w", err)
+	}
+	defer w.Stop()
+
+	seen := map[string]*podSeen{}
+	for e := range w.ResultChan() {
+		pod, ok := e.Object.(*apiv1.Pod)
+		if !ok {
+			continue
+		}
+		if e.Type == watch.Deleted {
+			delete(seen, pod.Name)
+			continue
+		}
+		prev, known := seen[pod.Name]
+		cur := observePod(pod)
+		seen[pod.Name] = cur
+		if !known || e.Type == watch.Added {
+			continue
+		}
+		funcID := pod.Labels["func"]
+		for _, reason := range podDisruptions(pod, prev, cur) {
+			ev := functions.WorkerEvent{FunctionID: funcID, ContainerID: c.appName + "-" + funcID, Reason: reason}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.New("worker pod watch closed")
+}
+
+// podSeen is what the watch last saw of a pod.
+type podSeen struct {
+	evicted  bool
+	restarts map[string]int32  // container name -> restart count
+	waiting  map[string]string // container name -> dead waiting reason
+}
+
+func observePod(pod *apiv1.Pod) *podSeen {
+	s := &podSeen{
+		evicted:  pod.Status.Reason == "Evicted",
+		restarts: map[string]int32{},
+		waiting:  map[string]string{},
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		s.restarts[cs.Name] = cs.RestartCount
+		if w := cs.State.Waiting; w != nil && slices.Contains(deadReasons, w.Reason) {
+			s.waiting[cs.Name] = w.Reason
+		}
+	}
+	return s
+}
+
+// podDisruptions describes what happened to a pod since prev.
+func podDisruptions(pod *apiv1.Pod, prev, cur *podSeen) []string {
+	var reasons []string
+	if cur.evicted && !prev.evicted {
+		reasons = append(reasons, fmt.Sprintf("pod %s evicted: %s", pod.Name, pod.Status.Message))
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.RestartCount > prev.restarts[cs.Name] {
+			reason := fmt.Sprintf("container %s of pod %s restarted", cs.Name, pod.Name)
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				reason += fmt.Sprintf(" after %s (exit code %d)", t.Reason, t.ExitCode)
+			}
+			reasons = append(reasons, reason)
+		}
+		if w := cur.waiting[cs.Name]; w != "" && w != prev.waiting[cs.Name] {
+			reasons = append(reasons, fmt.Sprintf("container %s of pod %s: %s: %s", cs.Name, pod.Name, w, cs.State.Waiting.Message))
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,112 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// Elasticsearch indexes lines with the bulk API, one document per line with
+// @timestamp, message, function_id, function, tenant, instance and stream.
+// Documents are created with "create", so the index may be a data stream. A
+// token is sent as an API key; credentials in the URL are sent with basic
+// authentication.
+type Elasticsearch struct {
+	url    string
+	index  string
+	token  string
+	client *http.Client
+}
+
+// NewElasticsearch indexes into index on the cluster at baseURL, e.g.
+// "https://es:9200".
+func NewElasticsearch(baseURL, index, token string) *Elasticsearch {
+	return &Elasticsearch{
+		url:    strings.TrimSuffix(baseURL, "/") + "/_bulk",
+		index:  index,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type esDocument struct {
+	Timestamp  time.Time `json:"@timestamp"`
+	Message    string    `json:"message"`
+	FunctionID string    `json:"function_id"`
+	Function   string    `json:"function,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	Instance   string    `json:"instance"`
+	Stream     string    `json:"stream,omitempty"`
+}
+
+// esBulkAnswer is the part of a bulk answer that tells whether every
+// document was taken.
+type esBulkAnswer struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (e *Elasticsearch) ShipLogs(ctx context.Context, entries []functions.LogEntry) error {
+	action, err := json.Marshal(map[string]any{"create": map[string]string{"_index": e.index}})
+	if err != nil {
+		return fmt.Errorf("encode bulk action: %w", err)
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, entry := range entries {
+		body.Write(action)
+		body.WriteByte('\n')
+		err := enc.Encode(esDocument{
+			Timestamp:  entry.Time,
+			Message:    entry.Line,
+			FunctionID: entry.FunctionID,
+			Function:   entry.Name,
+			Tenant:     entry.Tenant,
+			Instance:   entry.Instance,
+			Stream:     entry.Stream,
+		})
+		if err != nil {
+			return fmt.Errorf("encode log document: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, &body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.token != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.token)
+	}
+	answer, err := send(e.client, req, "elasticsearch bulk")
+	if err != nil {
+		return err
+	}
+	var bulk esBulkAnswer
+	if err := json.Unmarshal(answer, &bulk); err != nil || !bulk.Errors {
+		// A 2xx answer that cannot be read still indexed the request.
+		return nil
+	}
+	failed, reason := 0, ""
+	for _, item := range bulk.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				failed++
+				reason = result.Error.Type + ": " + result.Error.Reason
+			}
+		}
+	}
+	return fmt.Errorf("elasticsearch rejected %d of %d log lines, e.g. %s", failed, len(entries), reason)
+}
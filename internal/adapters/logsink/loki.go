@@ -0,0 +1,103 @@
+// Package logsink ships worker logs to log backends.
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// Loki pushes lines to Grafana Loki's push API, one stream per function
+// instance, labeled with function_id, function, tenant, instance and
+// stream. A token is sent as a bearer token; credentials in the URL are sent
+// with basic authentication.
+type Loki struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewLoki pushes to the Loki server at baseURL, e.g. "http://loki:3100".
+func NewLoki(baseURL, token string) *Loki {
+	return &Loki{
+		url:    strings.TrimSuffix(baseURL, "/") + "/loki/api/v1/push",
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (l *Loki) ShipLogs(ctx context.Context, entries []functions.LogEntry) error {
+	var streams []*lokiStream
+	byLabels := map[[5]string]*lokiStream{}
+	for _, e := range entries {
+		key := [5]string{e.FunctionID, e.Name, e.Tenant, e.Instance, e.Stream}
+		s, ok := byLabels[key]
+		if !ok {
+			labels := map[string]string{"function_id": e.FunctionID, "instance": e.Instance}
+			for name, value := range map[string]string{"function": e.Name, "tenant": e.Tenant, "stream": e.Stream} {
+				if value != "" {
+					labels[name] = value
+				}
+			}
+			s = &lokiStream{Stream: labels}
+			byLabels[key] = s
+			streams = append(streams, s)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Line})
+	}
+	body, err := json.Marshal(map[string]any{"streams": streams})
+	if err != nil {
+		return fmt.Errorf("encode loki push: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.token)
+	}
+	_, err = send(l.client, req, "loki push")
+	return err
+}
+
+// send sends a push request and returns the answer's body, or an error when
+// it is not a 2xx answer.
+func send(client *http.Client, req *http.Request, what string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", what, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAnswer))
+	if err != nil {
+		return nil, fmt.Errorf("%s answer: %w", what, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned %s: %s", what, resp.Status, truncate(bytes.TrimSpace(body), 512))
+	}
+	return body, nil
+}
+
+// maxAnswer caps the answer bodies read.
+const maxAnswer = 4 << 20
+
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
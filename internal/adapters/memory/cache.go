@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ResponseCache is an LRU of execution results, evicting the least recently
+// used entry beyond maxEntries.
+type ResponseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List               // front is most recently used
+	entries    map[string]*list.Element // function ID + key -> *cacheEntry
+}
+
+type cacheEntry struct {
+	id         string
+	functionID string
+	result     json.RawMessage
+	expires    time.Time
+}
+
+func NewResponseCache(maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (c *ResponseCache) Get(_ context.Context, functionID, key string) (json.RawMessage, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[functionID+"/"+key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return e.result, true, nil
+}
+
+func (c *ResponseCache) Set(_ context.Context, functionID, key string, result json.RawMessage, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := functionID + "/" + key
+	if el, ok := c.entries[id]; ok {
+		c.remove(el)
+	}
+	c.entries[id] = c.order.PushFront(&cacheEntry{
+		id:         id,
+		functionID: functionID,
+		result:     result,
+		expires:    time.Now().Add(ttl),
+	})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *ResponseCache) Invalidate(_ context.Context, functionID string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cacheEntry).functionID == functionID {
+			c.remove(el)
+			n++
+		}
+		el = next
+	}
+	return n, nil
+}
+
+func (c *ResponseCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).id)
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// CaptureRepository keeps debug captures in a map.
+type CaptureRepository struct {
+	mu       sync.Mutex
+	captures map[string]functions.Capture
+}
+
+func NewCaptureRepository() *CaptureRepository {
+	return &CaptureRepository{captures: map[string]functions.Capture{}}
+}
+
+func (r *CaptureRepository) Create(_ context.Context, c *functions.Capture) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.captures[c.ID] = *c
+	return nil
+}
+
+func (r *CaptureRepository) List(_ context.Context, functionID string, now time.Time, limit int) ([]functions.Capture, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []functions.Capture{}
+	for _, c := range r.captures {
+		if c.FunctionID == functionID && c.ExpiresAt.After(now) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *CaptureRepository) DeleteByFunction(_ context.Context, functionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, c := range r.captures {
+		if c.FunctionID == functionID {
+			delete(r.captures, id)
+		}
+	}
+	return nil
+}
+
+func (r *CaptureRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for id, c := range r.captures {
+		if c.ExpiresAt.Before(now) {
+			delete(r.captures, id)
+			n++
+		}
+	}
+	return n, nil
+}
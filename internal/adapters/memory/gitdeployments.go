@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"service-faas/internal/core/functions"
+)
+
+// GitDeploymentRepository keeps Git deployment records in a map.
+type GitDeploymentRepository struct {
+	mu          sync.Mutex
+	deployments map[string]functions.GitDeployment
+}
+
+func NewGitDeploymentRepository() *GitDeploymentRepository {
+	return &GitDeploymentRepository{deployments: map[string]functions.GitDeployment{}}
+}
+
+func (r *GitDeploymentRepository) Create(_ context.Context, d *functions.GitDeployment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deployments[d.ID] = *d
+	return nil
+}
+
+func (r *GitDeploymentRepository) List(_ context.Context, functionID string, limit int) ([]functions.GitDeployment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []functions.GitDeployment{}
+	for _, d := range r.deployments {
+		if d.FunctionID == functionID {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *GitDeploymentRepository) DeleteByFunction(_ context.Context, functionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, d := range r.deployments {
+		if d.FunctionID == functionID {
+			delete(r.deployments, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// IdempotencyRepository keeps idempotency records in a map.
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[[2]string]functions.IdempotencyRecord // function ID, key
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: map[[2]string]functions.IdempotencyRecord{}}
+}
+
+func (r *IdempotencyRepository) Claim(_ context.Context, rec *functions.IdempotencyRecord) (*functions.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := [2]string{rec.FunctionID, rec.Key}
+	if existing, ok := r.records[id]; ok && !existing.ExpiresAt.Before(time.Now()) {
+		return &existing, nil
+	}
+	r.records[id] = *rec
+	return nil, nil
+}
+
+func (r *IdempotencyRepository) Complete(_ context.Context, rec *functions.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[[2]string{rec.FunctionID, rec.Key}] = *rec
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(_ context.Context, functionID, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, [2]string{functionID, key})
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for id, rec := range r.records {
+		if rec.ExpiresAt.Before(now) {
+			delete(r.records, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// InvocationRepository keeps invocation outcomes in a map.
+type InvocationRepository struct {
+	mu          sync.Mutex
+	invocations map[string]functions.Invocation
+}
+
+func NewInvocationRepository() *InvocationRepository {
+	return &InvocationRepository{invocations: map[string]functions.Invocation{}}
+}
+
+func (r *InvocationRepository) Create(_ context.Context, inv *functions.Invocation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invocations[inv.ID] = *inv
+	return nil
+}
+
+func (r *InvocationRepository) Get(_ context.Context, id string) (*functions.Invocation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inv, ok := r.invocations[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: invocation '%s'", functions.ErrNotFound, id)
+	}
+	return &inv, nil
+}
+
+func (r *InvocationRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for id, inv := range r.invocations {
+		if inv.ExpiresAt.Before(now) {
+			delete(r.invocations, id)
+			n++
+		}
+	}
+	return n, nil
+}
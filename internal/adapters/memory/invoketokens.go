@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"service-faas/internal/core/functions"
+)
+
+// InvokeTokenRepository keeps invoke tokens in a map.
+type InvokeTokenRepository struct {
+	mu   sync.RWMutex
+	toks map[string]functions.InvokeToken
+}
+
+func NewInvokeTokenRepository() *InvokeTokenRepository {
+	return &InvokeTokenRepository{toks: map[string]functions.InvokeToken{}}
+}
+
+func (r *InvokeTokenRepository) Create(_ context.Context, tok *functions.InvokeToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *tok
+	stored.Token = ""
+	r.toks[tok.ID] = stored
+	return nil
+}
+
+func (r *InvokeTokenRepository) Get(_ context.Context, id string) (*functions.InvokeToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tok, ok := r.toks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: invoke token '%s'", functions.ErrNotFound, id)
+	}
+	return &tok, nil
+}
+
+func (r *InvokeTokenRepository) FindByHash(_ context.Context, hash string) (*functions.InvokeToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, tok := range r.toks {
+		if tok.TokenHash == hash {
+			return &tok, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: invoke token", functions.ErrNotFound)
+}
+
+func (r *InvokeTokenRepository) ListByFunction(_ context.Context, functionID string) ([]functions.InvokeToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	toks := []functions.InvokeToken{}
+	for _, tok := range r.toks {
+		if tok.FunctionID == functionID {
+			toks = append(toks, tok)
+		}
+	}
+	sort.Slice(toks, func(i, j int) bool { return toks[i].CreatedAt.Before(toks[j].CreatedAt) })
+	return toks, nil
+}
+
+func (r *InvokeTokenRepository) Update(_ context.Context, tok *functions.InvokeToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.toks[tok.ID]; !ok {
+		return fmt.Errorf("%w: invoke token '%s'", functions.ErrNotFound, tok.ID)
+	}
+	stored := *tok
+	stored.Token = ""
+	r.toks[tok.ID] = stored
+	return nil
+}
+
+func (r *InvokeTokenRepository) DeleteByFunction(_ context.Context, functionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, tok := range r.toks {
+		if tok.FunctionID == functionID {
+			delete(r.toks, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"service-faas/internal/core/functions"
+)
+
+// EnvironmentPromotionRepository keeps promotion records in a map.
+type EnvironmentPromotionRepository struct {
+	mu         sync.Mutex
+	promotions map[string]functions.EnvironmentPromotion
+}
+
+func NewEnvironmentPromotionRepository() *EnvironmentPromotionRepository {
+	return &EnvironmentPromotionRepository{promotions: map[string]functions.EnvironmentPromotion{}}
+}
+
+func (r *EnvironmentPromotionRepository) Create(_ context.Context, p *functions.EnvironmentPromotion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.promotions[p.ID] = *p
+	return nil
+}
+
+func (r *EnvironmentPromotionRepository) List(_ context.Context, functionID string, limit int) ([]functions.EnvironmentPromotion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []functions.EnvironmentPromotion{}
+	for _, p := range r.promotions {
+		if p.FunctionID == functionID {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PromotedAt.After(out[j].PromotedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *EnvironmentPromotionRepository) DeleteByFunction(_ context.Context, functionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, p := range r.promotions {
+		if p.FunctionID == functionID {
+			delete(r.promotions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package memory keeps manager state in process memory. It backs
+// DB_DRIVER=memory for development and is lost on restart.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"service-faas/internal/core/functions"
+)
+
+// FunctionRepository keeps function records in a map.
+type FunctionRepository struct {
+	mu  sync.RWMutex
+	fns map[string]functions.Function
+}
+
+func NewFunctionRepository() *FunctionRepository {
+	return &FunctionRepository{fns: map[string]functions.Function{}}
+}
+
+func (r *FunctionRepository) Create(_ context.Context, fn *functions.Function) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.fns[fn.ID]; ok {
+		return fmt.Errorf("function '%s' already exists", fn.ID)
+	}
+	r.fns[fn.ID] = *fn
+	return nil
+}
+
+func (r *FunctionRepository) Get(_ context.Context, id string) (*functions.Function, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.fns[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", functions.ErrFunctionNotFound, id)
+	}
+	return &fn, nil
+}
+
+func (r *FunctionRepository) FindByName(_ context.Context, name string) (*functions.Function, error) {
+	if fns := r.filter(func(fn functions.Function) bool { return fn.Name == name }); len(fns) > 0 {
+		return &fns[0], nil
+	}
+	return nil, fmt.Errorf("%w: named '%s'", functions.ErrFunctionNotFound, name)
+}
+
+func (r *FunctionRepository) FindByDomain(_ context.Context, domain string) (*functions.Function, error) {
+	if fns := r.filter(func(fn functions.Function) bool { return fn.Domain == domain }); len(fns) > 0 {
+		return &fns[0], nil
+	}
+	return nil, fmt.Errorf("%w: with domain '%s'", functions.ErrFunctionNotFound, domain)
+}
+
+func (r *FunctionRepository) List(_ context.Context) ([]functions.Function, error) {
+	return r.filter(func(functions.Function) bool { return true }), nil
+}
+
+func (r *FunctionRepository) Update(_ context.Context, fn *functions.Function) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fns[fn.ID] = *fn
+	return nil
+}
+
+func (r *FunctionRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fns, id)
+	return nil
+}
+
+func (r *FunctionRepository) FindByStatus(_ context.Context, status string) ([]functions.Function, error) {
+	return r.filter(func(fn functions.Function) bool { return fn.Status == status }), nil
+}
+
+func (r *FunctionRepository) Search(ctx context.Context, query string, limit int) ([]functions.SearchHit, error) {
+	fns, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return functions.RankFunctions(fns, query, limit), nil
+}
+
+// filter returns matching records in creation order, as the database would
+// typically return them.
+func (r *FunctionRepository) filter(match func(functions.Function) bool) []functions.Function {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fns := []functions.Function{}
+	for _, fn := range r.fns {
+		if match(fn) {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool { return fns[i].CreatedAt.Before(fns[j].CreatedAt) })
+	return fns
+}
+
+// RegistryCredentialRepository keeps registry credentials in a map. Unlike
+// the database store, passwords are not encrypted.
+type RegistryCredentialRepository struct {
+	mu    sync.RWMutex
+	creds map[string]functions.RegistryCredential
+}
+
+func NewRegistryCredentialRepository() *RegistryCredentialRepository {
+	return &RegistryCredentialRepository{creds: map[string]functions.RegistryCredential{}}
+}
+
+func (r *RegistryCredentialRepository) Find(_ context.Context, tenant, server string) (*functions.RegistryCredential, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, cred := range r.creds {
+		if cred.Tenant == tenant && cred.Server == server {
+			return &cred, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: registry credential for '%s'", functions.ErrNotFound, server)
+}
+
+func (r *RegistryCredentialRepository) ListByTenant(_ context.Context, tenant string) ([]functions.RegistryCredential, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	creds := []functions.RegistryCredential{}
+	for _, cred := range r.creds {
+		if cred.Tenant == tenant {
+			creds = append(creds, cred)
+		}
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].CreatedAt.Before(creds[j].CreatedAt) })
+	return creds, nil
+}
+
+func (r *RegistryCredentialRepository) Save(_ context.Context, cred *functions.RegistryCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.creds[cred.ID] = *cred
+	return nil
+}
+
+func (r *RegistryCredentialRepository) Delete(_ context.Context, tenant, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cred, ok := r.creds[id]; !ok || cred.Tenant != tenant {
+		return fmt.Errorf("%w: registry credential '%s'", functions.ErrNotFound, id)
+	}
+	delete(r.creds, id)
+	return nil
+}
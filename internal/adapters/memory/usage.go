@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// UsageRepository keeps usage rollups in a map.
+type UsageRepository struct {
+	mu      sync.RWMutex
+	rollups map[string]map[time.Time]functions.UsageRollup // function ID -> hour
+}
+
+func NewUsageRepository() *UsageRepository {
+	return &UsageRepository{rollups: map[string]map[time.Time]functions.UsageRollup{}}
+}
+
+func (r *UsageRepository) Add(_ context.Context, rollups []functions.UsageRollup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ru := range rollups {
+		hours, ok := r.rollups[ru.FunctionID]
+		if !ok {
+			hours = map[time.Time]functions.UsageRollup{}
+			r.rollups[ru.FunctionID] = hours
+		}
+		hour := ru.Hour.UTC()
+		stored := hours[hour]
+		stored.FunctionID, stored.Hour = ru.FunctionID, hour
+		stored.Invocations += ru.Invocations
+		stored.Errors += ru.Errors
+		stored.DurationMS += ru.DurationMS
+		stored.GBSeconds += ru.GBSeconds
+		stored.ColdStarts += ru.ColdStarts
+		stored.ColdStartMS += ru.ColdStartMS
+		hours[hour] = stored
+	}
+	return nil
+}
+
+func (r *UsageRepository) Range(_ context.Context, functionID string, from, to time.Time) ([]functions.UsageRollup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rollups := []functions.UsageRollup{}
+	for hour, ru := range r.rollups[functionID] {
+		if !hour.Before(from) && hour.Before(to) {
+			rollups = append(rollups, ru)
+		}
+	}
+	sort.Slice(rollups, func(i, j int) bool { return rollups[i].Hour.Before(rollups[j].Hour) })
+	return rollups, nil
+}
+
+func (r *UsageRepository) Totals(_ context.Context, from, to time.Time) ([]functions.UsageRollup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	totals := []functions.UsageRollup{}
+	for functionID, hours := range r.rollups {
+		total := functions.UsageRollup{FunctionID: functionID}
+		for hour, ru := range hours {
+			if hour.Before(from) || !hour.Before(to) {
+				continue
+			}
+			total.Invocations += ru.Invocations
+			total.Errors += ru.Errors
+			total.DurationMS += ru.DurationMS
+			total.GBSeconds += ru.GBSeconds
+			total.ColdStarts += ru.ColdStarts
+			total.ColdStartMS += ru.ColdStartMS
+		}
+		if total.Invocations > 0 {
+			totals = append(totals, total)
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].FunctionID < totals[j].FunctionID })
+	return totals, nil
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"service-faas/internal/core/functions"
+)
+
+// WebhookRepository keeps webhook registrations in a map. Unlike the
+// database store, secrets are not encrypted.
+type WebhookRepository struct {
+	mu    sync.RWMutex
+	hooks map[string]functions.Webhook
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{hooks: map[string]functions.Webhook{}}
+}
+
+func (r *WebhookRepository) Create(_ context.Context, hook *functions.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[hook.ID] = *hook
+	return nil
+}
+
+func (r *WebhookRepository) List(_ context.Context) ([]functions.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hooks := make([]functions.Webhook, 0, len(r.hooks))
+	for _, hook := range r.hooks {
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+	return hooks, nil
+}
+
+func (r *WebhookRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.hooks[id]; !ok {
+		return fmt.Errorf("%w: webhook '%s'", functions.ErrNotFound, id)
+	}
+	delete(r.hooks, id)
+	return nil
+}
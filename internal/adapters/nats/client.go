@@ -0,0 +1,237 @@
+// Package nats publishes events to a NATS server over its core text
+// protocol. Only publishing is implemented.
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// handshakeTimeout bounds connecting, and writes when the caller's context
+// has no deadline.
+const handshakeTimeout = 10 * time.Second
+
+// Publisher publishes each event as JSON to "<subject>.<event type>". It
+// keeps one connection, dialed on first use and redialed after it fails.
+// Delivery is at most once, as with any core NATS publish.
+type Publisher struct {
+	address string
+	useTLS  bool
+	user    *url.Userinfo
+	subject string
+
+	mu         sync.Mutex
+	conn       net.Conn
+	w          *bufio.Writer
+	maxPayload int
+	err        error // -ERR sent by the server since the last publish
+}
+
+// New parses a server URL, nats://[user:pass@|token@]host[:port] or tls://
+// for a TLS connection. Servers that require TLS get it either way.
+func New(serverURL, subject string) (*Publisher, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS url %q: %w", serverURL, err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid NATS url %q: expected nats:// or tls://", serverURL)
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("invalid NATS subject %q", subject)
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &Publisher{address: address, useTLS: u.Scheme == "tls", user: u.User, subject: subject}, nil
+}
+
+func (p *Publisher) Publish(ctx context.Context, event *functions.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	subject := p.subject + "." + event.Type
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.err; err != nil {
+		p.err = nil
+		return err
+	}
+	// A connection that broke since the last publish may only fail on
+	// write, so one failed write is retried on a new connection.
+	for attempt := 0; ; attempt++ {
+		fresh := p.conn == nil
+		if fresh {
+			if err := p.connect(ctx); err != nil {
+				return err
+			}
+		}
+		if p.maxPayload > 0 && len(body) > p.maxPayload {
+			return fmt.Errorf("event of %d bytes exceeds the server's max_payload of %d", len(body), p.maxPayload)
+		}
+		err := p.write(ctx, func(w *bufio.Writer) {
+			fmt.Fprintf(w, "PUB %s %d\r\n", subject, len(body))
+			w.Write(body)
+			w.WriteString("\r\n")
+		})
+		if err == nil {
+			return nil
+		}
+		p.closeLocked()
+		if fresh || attempt > 0 {
+			return fmt.Errorf("nats publish: %w", err)
+		}
+	}
+}
+
+// Close closes the connection after flushing pending publishes.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	err := p.w.Flush()
+	p.closeLocked()
+	return err
+}
+
+// serverInfo is the part of the server's INFO message that is used.
+type serverInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	MaxPayload  int  `json:"max_payload"`
+}
+
+// connect dials the server, upgrades to TLS when asked to, authenticates and
+// waits for the server to acknowledge with PONG. p.mu must be held.
+func (p *Publisher) connect(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.address)
+	if err != nil {
+		return fmt.Errorf("nats connect: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("nats handshake: %w", err)
+	}
+	var info serverInfo
+	if op, arg, _ := strings.Cut(strings.TrimSpace(line), " "); op != "INFO" || json.Unmarshal([]byte(arg), &info) != nil {
+		conn.Close()
+		return fmt.Errorf("nats handshake: unexpected %q", strings.TrimSpace(line))
+	}
+	if p.useTLS || info.TLSRequired {
+		host, _, _ := net.SplitHostPort(p.address)
+		tconn := tls.Client(conn, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err := tconn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("nats tls handshake: %w", err)
+		}
+		conn, r = tconn, bufio.NewReader(tconn)
+	}
+
+	opts := map[string]any{"verbose": false, "pedantic": false, "lang": "go", "name": "service-faas"}
+	if p.user != nil {
+		if pass, ok := p.user.Password(); ok {
+			opts["user"], opts["pass"] = p.user.Username(), pass
+		} else {
+			opts["auth_token"] = p.user.Username()
+		}
+	}
+	connectOpts, _ := json.Marshal(opts)
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connectOpts)
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("nats handshake: %w", err)
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("nats handshake: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return fmt.Errorf("nats connect: %s", serverError(line))
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	p.conn, p.w, p.maxPayload = conn, w, info.MaxPayload
+	go p.read(conn, r)
+	return nil
+}
+
+// write writes and flushes a message within ctx's deadline. p.mu must be
+// held.
+func (p *Publisher) write(ctx context.Context, msg func(w *bufio.Writer)) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(handshakeTimeout)
+	}
+	_ = p.conn.SetWriteDeadline(deadline)
+	msg(p.w)
+	return p.w.Flush()
+}
+
+// read answers the server's PINGs and records its errors until conn fails,
+// then drops conn so the next publish redials.
+func (p *Publisher) read(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimSpace(line)
+		p.mu.Lock()
+		switch {
+		case p.conn != conn:
+		case line == "PING":
+			err = p.write(context.Background(), func(w *bufio.Writer) { w.WriteString("PONG\r\n") })
+		case strings.HasPrefix(line, "-ERR"):
+			p.err = fmt.Errorf("nats server: %s", serverError(line))
+		}
+		p.mu.Unlock()
+		if err != nil {
+			break
+		}
+	}
+	p.mu.Lock()
+	if p.conn == conn {
+		p.closeLocked()
+	}
+	p.mu.Unlock()
+}
+
+func (p *Publisher) closeLocked() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	p.conn, p.w = nil, nil
+}
+
+// serverError extracts the message of "-ERR 'message'".
+func serverError(line string) string {
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+}
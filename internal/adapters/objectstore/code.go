@@ -0,0 +1,70 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+)
+
+const codePrefix = "functions/"
+
+// CodeStore keeps handler code in a bucket so every manager replica sees the
+// same code. Workers download it through presigned URLs valid for ttl.
+type CodeStore struct {
+	client *Client
+	ttl    time.Duration
+}
+
+func NewCodeStore(client *Client, ttl time.Duration) *CodeStore {
+	return &CodeStore{client: client, ttl: ttl}
+}
+
+func codeKey(functionID string) string {
+	return codePrefix + functionID + "/handler.py"
+}
+
+func (s *CodeStore) Put(ctx context.Context, functionID string, code io.Reader) (string, error) {
+	data, err := io.ReadAll(code)
+	if err != nil {
+		return "", fmt.Errorf("read handler code: %w", err)
+	}
+	key := codeKey(functionID)
+	if err := s.client.PutObject(ctx, key, data, "text/x-python"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *CodeStore) Get(ctx context.Context, functionID string) (io.ReadCloser, error) {
+	return s.client.GetObject(ctx, codeKey(functionID))
+}
+
+func (s *CodeStore) Delete(ctx context.Context, functionID string) error {
+	return s.client.DeleteObject(ctx, codeKey(functionID))
+}
+
+func (s *CodeStore) List(ctx context.Context) ([]string, error) {
+	keys, err := s.client.ListKeys(ctx, codePrefix)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, key := range keys {
+		id, ok := strings.CutSuffix(strings.TrimPrefix(key, codePrefix), "/handler.py")
+		if ok && id != "" && !strings.Contains(id, "/") {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *CodeStore) Source(ctx context.Context, functionID string) (functions.CodeSource, error) {
+	url, err := s.client.PresignGet(ctx, codeKey(functionID), s.ttl)
+	if err != nil {
+		return functions.CodeSource{}, err
+	}
+	return functions.CodeSource{URL: url}, nil
+}
@@ -0,0 +1,48 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"service-faas/internal/core/functions"
+	"time"
+)
+
+// ResultStore offloads results to a bucket and hands out presigned URLs.
+type ResultStore struct {
+	client *Client
+	ttl    time.Duration
+}
+
+func NewResultStore(client *Client, ttl time.Duration) *ResultStore {
+	return &ResultStore{client: client, ttl: ttl}
+}
+
+func (s *ResultStore) Put(ctx context.Context, key string, data []byte) (*functions.ResultRef, error) {
+	if err := s.client.PutObject(ctx, key, data, "application/json"); err != nil {
+		return nil, err
+	}
+	url, expiresAt, err := s.Link(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &functions.ResultRef{
+		Key:       key,
+		URL:       url,
+		Size:      len(data),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Link presigns a URL to a stored result, valid for the store's TTL.
+func (s *ResultStore) Link(ctx context.Context, key string) (string, *time.Time, error) {
+	url, err := s.client.PresignGet(ctx, key, s.ttl)
+	if err != nil {
+		return "", nil, err
+	}
+	expiresAt := time.Now().UTC().Add(s.ttl)
+	return url, &expiresAt, nil
+}
+
+func (s *ResultStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.client.GetObject(ctx, key)
+}
@@ -0,0 +1,53 @@
+package ownerdir
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Directory checks owners against an identity provider's HTTP API. An owner
+// exists when GET <baseURL>/<owner> answers 200 and is gone on 404.
+type Directory struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func New(baseURL, token string) *Directory {
+	return &Directory{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (d *Directory) OwnerExists(ctx context.Context, owner string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+"/"+url.PathEscape(owner), nil)
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	if d.token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("owner directory request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusGone:
+		return false, nil
+	default:
+		return false, fmt.Errorf("owner directory returned %s", resp.Status)
+	}
+}
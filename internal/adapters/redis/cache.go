@@ -0,0 +1,86 @@
+// Package redis keeps shared manager state in Redis.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces cached results; keys are
+// faas:cache:<function id>:<payload key>.
+const keyPrefix = "faas:cache:"
+
+// ResponseCache stores execution results in Redis, shared by all manager
+// replicas. Redis expires entries by TTL and evicts under its own memory
+// policy.
+type ResponseCache struct {
+	client *goredis.Client
+}
+
+// NewResponseCache connects to the Redis server at url, e.g.
+// redis://:password@host:6379/0.
+func NewResponseCache(ctx context.Context, url string) (*ResponseCache, error) {
+	opts, err := goredis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	client := goredis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis ping: %w", err)
+	}
+	return &ResponseCache{client: client}, nil
+}
+
+func (c *ResponseCache) Get(ctx context.Context, functionID, key string) (json.RawMessage, bool, error) {
+	result, err := c.client.Get(ctx, keyPrefix+functionID+":"+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+func (c *ResponseCache) Set(ctx context.Context, functionID, key string, result json.RawMessage, ttl time.Duration) error {
+	return c.client.Set(ctx, keyPrefix+functionID+":"+key, []byte(result), ttl).Err()
+}
+
+func (c *ResponseCache) Invalidate(ctx context.Context, functionID string) (int, error) {
+	n := 0
+	iter := c.client.Scan(ctx, 0, keyPrefix+functionID+":*", 500).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			deleted, err := c.client.Del(ctx, batch...).Result()
+			if err != nil {
+				return n, err
+			}
+			n += int(deleted)
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return n, err
+	}
+	if len(batch) > 0 {
+		deleted, err := c.client.Del(ctx, batch...).Result()
+		if err != nil {
+			return n, err
+		}
+		n += int(deleted)
+	}
+	return n, nil
+}
+
+// Close closes the connection pool.
+func (c *ResponseCache) Close() error {
+	return c.client.Close()
+}
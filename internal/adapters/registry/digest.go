@@ -0,0 +1,186 @@
+// Package registry talks to container registries over the Docker Registry
+// HTTP API v2.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// manifestTypes are the manifest media types accepted, multi-platform
+// indexes first so a tag resolves to the same digest docker pull reports.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Resolver resolves image tags to manifest digests. Images are looked up
+// with the keychain's login for their registry unless other credentials are
+// given.
+type Resolver struct {
+	keychain *Keychain
+	client   *http.Client
+}
+
+func NewResolver(keychain *Keychain) *Resolver {
+	return &Resolver{keychain: keychain, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// SetRegistryLogin replaces the keychain's Harbor login; an empty user or
+// password removes it.
+func (r *Resolver) SetRegistryLogin(user, password string) error {
+	return r.keychain.SetRegistryLogin(user, password)
+}
+
+// ResolveDigest asks the image's registry for the digest of its tag.
+func (r *Resolver) ResolveDigest(ctx context.Context, image string, auth *functions.RegistryAuth) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid image reference %q: %v", functions.ErrInvalidArgument, image, err)
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String(), nil
+	}
+	named = reference.TagNameOnly(named)
+	tag := named.(reference.Tagged).Tag()
+
+	domain := reference.Domain(named)
+	if auth == nil {
+		if auth, err = r.keychain.Credentials(ctx, domain); err != nil {
+			return "", err
+		}
+	}
+	host := domain
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	u := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, reference.Path(named), url.PathEscape(tag))
+
+	resp, err := r.manifest(ctx, u, "")
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		var authz string
+		if authz, err = r.authorize(ctx, resp.Header.Get("WWW-Authenticate"), auth); err == nil {
+			resp, err = r.manifest(ctx, u, authz)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("registry request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: image %s not found in its registry", functions.ErrInvalidArgument, image)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", fmt.Errorf("%w: registry denied access to image %s", functions.ErrInvalidArgument, image)
+	default:
+		return "", fmt.Errorf("registry returned %s for %s", resp.Status, image)
+	}
+	if dgst := resp.Header.Get("Docker-Content-Digest"); dgst != "" {
+		return dgst, nil
+	}
+	// Registries need not send the header; the digest is that of the body.
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("read manifest: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *Resolver) manifest(ctx context.Context, u, authz string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if authz != "" {
+		req.Header.Set("Authorization", authz)
+	}
+	return r.client.Do(req)
+}
+
+// authorize answers a registry's authentication challenge: basic auth is
+// sent as is, a bearer challenge is exchanged for a token at its realm.
+func (r *Resolver) authorize(ctx context.Context, challenge string, auth *functions.RegistryAuth) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if auth == nil {
+			return "", fmt.Errorf("%w: registry requires credentials", functions.ErrInvalidArgument)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(auth.Username, auth.Password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported registry challenge %q", challenge)
+	}
+
+	p := parseChallenge(params)
+	q := url.Values{}
+	if p["service"] != "" {
+		q.Set("service", p["service"])
+	}
+	if p["scope"] != "" {
+		q.Set("scope", p["scope"])
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p["realm"]+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: registry token request returned %s", functions.ErrInvalidArgument, resp.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("decode registry token: %w", err)
+	}
+	if tok.Token == "" {
+		tok.Token = tok.AccessToken
+	}
+	return "Bearer " + tok.Token, nil
+}
+
+// parseChallenge parses the key="value" pairs of a WWW-Authenticate header.
+func parseChallenge(params string) map[string]string {
+	out := map[string]string{}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(params, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+			params = strings.TrimPrefix(params, ",")
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		out[key] = value
+	}
+	return out
+}
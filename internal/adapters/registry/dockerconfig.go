@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"service-faas/internal/core/functions"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dockerConfigProvider reads logins from the "auths" of a Docker
+// config.json, such as a mounted kubernetes.io/dockerconfigjson secret. The
+// file is read on every use, so a rewritten file takes effect without a
+// restart. Credential helpers (credsStore, credHelpers) are not run.
+type dockerConfigProvider struct {
+	path string
+}
+
+func (p *dockerConfigProvider) read() (map[string]functions.RegistryAuth, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("read docker config: %w", err)
+	}
+	var file struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse docker config %s: %w", p.path, err)
+	}
+	logins := make(map[string]functions.RegistryAuth, len(file.Auths))
+	for key, entry := range file.Auths {
+		user, password := entry.Username, entry.Password
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("docker config %s: auth of %s: %w", p.path, key, err)
+			}
+			var ok bool
+			if user, password, ok = strings.Cut(string(decoded), ":"); !ok {
+				return nil, fmt.Errorf("docker config %s: auth of %s is not a user:password pair", p.path, key)
+			}
+		}
+		if user == "" || password == "" {
+			continue
+		}
+		server := normalizeServer(key)
+		logins[server] = functions.RegistryAuth{Server: server, Username: user, Password: password}
+	}
+	return logins, nil
+}
+
+func (p *dockerConfigProvider) matches(server string) bool {
+	logins, err := p.read()
+	if err != nil {
+		return false
+	}
+	_, ok := logins[server]
+	return ok
+}
+
+func (p *dockerConfigProvider) login(_ context.Context, server string) (*functions.RegistryAuth, time.Time, error) {
+	logins, err := p.read()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	auth, ok := logins[server]
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("docker config %s has no login for %s", p.path, server)
+	}
+	return &auth, time.Time{}, nil
+}
+
+func (p *dockerConfigProvider) servers(context.Context) ([]string, error) {
+	logins, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]string, 0, len(logins))
+	for server := range logins {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	return servers, nil
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"service-faas/internal/core/functions"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// ecrHost matches the registry hosts of ECR, capturing the account ID, the
+// region and the partition's domain suffix.
+var ecrHost = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+// ecrProvider logs in to ECR registries with authorization tokens, which
+// are valid for 12 hours, from the ECR API.
+type ecrProvider struct {
+	region string
+	creds  aws.CredentialsProvider
+	signer *v4.Signer
+	client *http.Client
+}
+
+func newECRProvider(ctx context.Context, region string) (*ecrProvider, error) {
+	opts := []func(*awsconfig.LoadOptions) error{}
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	return &ecrProvider{
+		region: awsCfg.Region,
+		creds:  awsCfg.Credentials,
+		signer: v4.NewSigner(),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (p *ecrProvider) matches(server string) bool {
+	return ecrHost.MatchString(server)
+}
+
+func (p *ecrProvider) login(ctx context.Context, server string) (*functions.RegistryAuth, time.Time, error) {
+	m := ecrHost.FindStringSubmatch(server)
+	auth, expires, _, err := p.token(ctx, m[2], m[1], m[3] != "")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	auth.Server = server
+	return auth, expires, nil
+}
+
+// servers returns the registry of the account the AWS credentials belong
+// to, in the configured region.
+func (p *ecrProvider) servers(ctx context.Context) ([]string, error) {
+	if p.region == "" {
+		return nil, nil
+	}
+	_, _, endpoint, err := p.token(ctx, p.region, "", strings.HasPrefix(p.region, "cn-"))
+	if err != nil {
+		return nil, fmt.Errorf("registry login for ecr: %w", err)
+	}
+	return []string{normalizeServer(endpoint)}, nil
+}
+
+// token calls GetAuthorizationToken for an account's registry, or that of
+// the caller's account when registryID is empty. It returns the login, when
+// it expires and the registry's address.
+func (p *ecrProvider) token(ctx context.Context, region, registryID string, china bool) (*functions.RegistryAuth, time.Time, string, error) {
+	body := []byte(`{}`)
+	if registryID != "" {
+		body, _ = json.Marshal(map[string][]string{"registryIds": {registryID}})
+	}
+	host := fmt.Sprintf("api.ecr.%s.amazonaws.com", region)
+	if china {
+		host += ".cn"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, time.Time{}, "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+
+	creds, err := p.creds.Retrieve(ctx)
+	if err != nil {
+		return nil, time.Time{}, "", fmt.Errorf("aws credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "ecr", region, time.Now()); err != nil {
+		return nil, time.Time{}, "", fmt.Errorf("sign request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		AuthorizationData []struct {
+			AuthorizationToken string  `json:"authorizationToken"`
+			ExpiresAt          float64 `json:"expiresAt"`
+			ProxyEndpoint      string  `json:"proxyEndpoint"`
+		} `json:"authorizationData"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil && resp.StatusCode < 300 {
+		return nil, time.Time{}, "", fmt.Errorf("decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, time.Time{}, "", fmt.Errorf("ecr GetAuthorizationToken: %s: %s", resp.Status, out.Message)
+	}
+	if len(out.AuthorizationData) == 0 {
+		return nil, time.Time{}, "", fmt.Errorf("ecr GetAuthorizationToken: no authorization data")
+	}
+	data := out.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return nil, time.Time{}, "", fmt.Errorf("decode ecr token: %w", err)
+	}
+	user, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, time.Time{}, "", fmt.Errorf("decode ecr token: not a user:password pair")
+	}
+	expires := time.Unix(int64(data.ExpiresAt), 0)
+	return &functions.RegistryAuth{Username: user, Password: password}, expires, data.ProxyEndpoint, nil
+}
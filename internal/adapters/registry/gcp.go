@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"service-faas/internal/core/functions"
+	"slices"
+	"strings"
+	"time"
+)
+
+// gcpProvider logs in to Google's registries (Container Registry and
+// Artifact Registry) with access tokens of the service account the metadata
+// server hands out, which on GKE is the workload identity of the pod.
+type gcpProvider struct {
+	registries []string
+	tokenURL   string
+	client     *http.Client
+}
+
+func newGCPProvider(registries []string) *gcpProvider {
+	// Google's client libraries read the metadata server's address from
+	// GCE_METADATA_HOST too, e.g. to point it at an emulator.
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	return &gcpProvider{
+		registries: registries,
+		tokenURL:   "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token",
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *gcpProvider) matches(server string) bool {
+	return server == "gcr.io" || strings.HasSuffix(server, ".gcr.io") ||
+		strings.HasSuffix(server, "-docker.pkg.dev") || slices.Contains(p.registries, server)
+}
+
+func (p *gcpProvider) login(ctx context.Context, server string) (*functions.RegistryAuth, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.tokenURL, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("gcp metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, time.Time{}, fmt.Errorf("gcp metadata server: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, time.Time{}, fmt.Errorf("decode gcp token: %w", err)
+	}
+	auth := &functions.RegistryAuth{Server: server, Username: "oauth2accesstoken", Password: token.AccessToken}
+	return auth, time.Now().Add(time.Duration(token.ExpiresIn) * time.Second), nil
+}
+
+func (p *gcpProvider) servers(context.Context) ([]string, error) {
+	return p.registries, nil
+}
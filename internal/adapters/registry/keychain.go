@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"service-faas/internal/config"
+	"service-faas/internal/core/functions"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// Registry authentication providers, as listed in REGISTRY_AUTH.
+const (
+	AuthHarbor       = "harbor"
+	AuthECR          = "ecr"
+	AuthGCP          = "gcp"
+	AuthDockerHub    = "dockerhub"
+	AuthDockerConfig = "dockerconfig"
+)
+
+// refreshMargin is how long before they expire token logins are fetched
+// again, so a login handed out is still good for a pull.
+const refreshMargin = 10 * time.Minute
+
+// Keychain supplies the platform's own registry logins, used to pull images
+// for which a function has no tenant credentials. The first provider in
+// REGISTRY_AUTH with a login for a registry supplies it. Token logins are
+// cached until shortly before they expire. Safe for concurrent use.
+type Keychain struct {
+	providers []provider
+	harbor    *staticProvider
+
+	mu    sync.Mutex
+	cache map[string]cachedLogin // server -> token login
+}
+
+type cachedLogin struct {
+	auth    functions.RegistryAuth
+	expires time.Time
+}
+
+// provider is a source of registry logins.
+type provider interface {
+	// matches reports whether the provider has a login for server.
+	matches(server string) bool
+	// login returns the login for server and when it expires, zero when it
+	// does not.
+	login(ctx context.Context, server string) (*functions.RegistryAuth, time.Time, error)
+	// servers lists the registries the provider has logins for, as far as
+	// it knows them in advance.
+	servers(ctx context.Context) ([]string, error)
+}
+
+// NewKeychain sets up the providers listed in cfg.RegistryAuth.
+func NewKeychain(ctx context.Context, cfg config.Config) (*Keychain, error) {
+	k := &Keychain{
+		harbor: &staticProvider{server: normalizeServer(cfg.HarborURL)},
+		cache:  map[string]cachedLogin{},
+	}
+	_ = k.SetRegistryLogin(cfg.HarborUser, cfg.HarborPass)
+	for _, name := range cfg.RegistryAuth {
+		switch name {
+		case AuthHarbor:
+			k.providers = append(k.providers, k.harbor)
+		case AuthECR:
+			p, err := newECRProvider(ctx, cfg.AWSRegion)
+			if err != nil {
+				return nil, err
+			}
+			k.providers = append(k.providers, p)
+		case AuthGCP:
+			k.providers = append(k.providers, newGCPProvider(cfg.GCPRegistries))
+		case AuthDockerHub:
+			p := &staticProvider{server: dockerHub}
+			p.auth.Store(&functions.RegistryAuth{Server: dockerHub, Username: cfg.DockerHubUser, Password: cfg.DockerHubToken})
+			k.providers = append(k.providers, p)
+		case AuthDockerConfig:
+			p := &dockerConfigProvider{path: cfg.DockerConfigFile}
+			if _, err := p.read(); err != nil {
+				return nil, err
+			}
+			k.providers = append(k.providers, p)
+		default:
+			return nil, fmt.Errorf("unknown registry auth provider %q", name)
+		}
+	}
+	return k, nil
+}
+
+// SetRegistryLogin replaces the Harbor login; an empty user or password
+// removes it.
+func (k *Keychain) SetRegistryLogin(user, password string) error {
+	if user == "" || password == "" {
+		k.harbor.auth.Store(nil)
+		return nil
+	}
+	k.harbor.auth.Store(&functions.RegistryAuth{Server: k.harbor.server, Username: user, Password: password})
+	return nil
+}
+
+// Credentials returns the login for a registry host, or nil when no provider
+// has one.
+func (k *Keychain) Credentials(ctx context.Context, server string) (*functions.RegistryAuth, error) {
+	server = normalizeServer(server)
+	i := slices.IndexFunc(k.providers, func(p provider) bool { return p.matches(server) })
+	if i < 0 {
+		return nil, nil
+	}
+
+	k.mu.Lock()
+	cached, ok := k.cache[server]
+	k.mu.Unlock()
+	if ok && time.Until(cached.expires) > refreshMargin {
+		auth := cached.auth
+		return &auth, nil
+	}
+	auth, expires, err := k.providers[i].login(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("registry login for %s: %w", server, err)
+	}
+	if !expires.IsZero() {
+		k.mu.Lock()
+		k.cache[server] = cachedLogin{auth: *auth, expires: expires}
+		k.mu.Unlock()
+	}
+	return auth, nil
+}
+
+// ImageCredentials returns the login for the registry of an image.
+func (k *Keychain) ImageCredentials(ctx context.Context, image string) (*functions.RegistryAuth, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	return k.Credentials(ctx, reference.Domain(named))
+}
+
+// Logins returns a login for every registry the providers know in advance,
+// for writing pull secrets, and when the first of them is due to be fetched
+// again, shortly before it expires (zero when none expires). Logins that
+// could not be fetched are left out and reported in the error.
+func (k *Keychain) Logins(ctx context.Context) ([]functions.RegistryAuth, time.Time, error) {
+	var (
+		logins  []functions.RegistryAuth
+		refresh time.Time
+		errs    []error
+		seen    = map[string]bool{}
+	)
+	for _, p := range k.providers {
+		servers, err := p.servers(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, server := range servers {
+			if seen[server] {
+				continue
+			}
+			seen[server] = true
+			auth, err := k.Credentials(ctx, server)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if auth == nil {
+				continue
+			}
+			logins = append(logins, *auth)
+			k.mu.Lock()
+			cached, ok := k.cache[server]
+			k.mu.Unlock()
+			if due := cached.expires.Add(-refreshMargin); ok && (refresh.IsZero() || due.Before(refresh)) {
+				refresh = due
+			}
+		}
+	}
+	return logins, refresh, errors.Join(errs...)
+}
+
+// dockerHub is the registry host of Docker Hub images.
+const dockerHub = "docker.io"
+
+// ConfigKey is the key of a registry's login in a Docker config.json or the
+// auth configs of a build, where Docker Hub goes by its legacy address.
+func ConfigKey(server string) string {
+	if server == dockerHub {
+		return "https://index.docker.io/v1/"
+	}
+	return server
+}
+
+// normalizeServer turns a registry URL or config.json key into a host,
+// naming Docker Hub docker.io whichever of its addresses is used.
+func normalizeServer(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server, _, _ = strings.Cut(server, "/")
+	switch server {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHub
+	}
+	return server
+}
+
+// staticProvider holds a fixed login for one registry, such as the Harbor
+// login.
+type staticProvider struct {
+	server string
+	auth   atomic.Pointer[functions.RegistryAuth]
+}
+
+func (p *staticProvider) matches(server string) bool {
+	return server == p.server && p.auth.Load() != nil
+}
+
+func (p *staticProvider) login(context.Context, string) (*functions.RegistryAuth, time.Time, error) {
+	return p.auth.Load(), time.Time{}, nil
+}
+
+func (p *staticProvider) servers(context.Context) ([]string, error) {
+	if p.auth.Load() == nil {
+		return nil, nil
+	}
+	return []string{p.server}, nil
+}
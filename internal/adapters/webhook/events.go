@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// Headers of lifecycle event deliveries.
+const (
+	EventTypeHeader = "X-Faas-Event"
+	EventIDHeader   = "X-Faas-Event-Id"
+	SignatureHeader = "X-Faas-Signature"
+)
+
+// EventSender POSTs lifecycle events as JSON to webhook URLs. Any 2xx answer
+// acknowledges the event.
+//
+// Each delivery is signed in the SignatureHeader as "t=<unix seconds>,
+// v1=<hex HMAC-SHA256>", the HMAC being computed with the webhook's secret
+// over "<t>.<body>". Receivers should recompute it and reject stale
+// timestamps; the event ID stays the same across retries, to drop
+// duplicates.
+type EventSender struct {
+	client *http.Client
+}
+
+func NewEventSender(timeout time.Duration) *EventSender {
+	return &EventSender{client: &http.Client{Timeout: timeout}}
+}
+
+func (s *EventSender) SendEvent(ctx context.Context, hook *functions.Webhook, event *functions.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, event.Type)
+	req.Header.Set(EventIDHeader, event.ID)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("event webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value of a body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"service-faas/internal/core/functions"
+)
+
+// CodeScanner POSTs uploads to an HTTP scanning service as
+// application/octet-stream. The service answers 200 with
+// {"clean": bool, "threat": "..."}.
+type CodeScanner struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewCodeScanner returns a scanner for the service at url. Scans are bounded
+// by the caller's context rather than a client timeout.
+func NewCodeScanner(url, token string) *CodeScanner {
+	return &CodeScanner{url: url, token: token, client: &http.Client{}}
+}
+
+func (s *CodeScanner) Scan(ctx context.Context, r io.Reader) (*functions.ScanVerdict, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, r)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scanner request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("scanner returned %s", resp.Status)
+	}
+
+	var verdict struct {
+		Clean  bool   `json:"clean"`
+		Threat string `json:"threat"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return nil, fmt.Errorf("decode scanner response: %w", err)
+	}
+	if verdict.Clean {
+		return &functions.ScanVerdict{Clean: true}, nil
+	}
+	if verdict.Threat == "" {
+		verdict.Threat = "unnamed threat"
+	}
+	return &functions.ScanVerdict{Threat: verdict.Threat}, nil
+}
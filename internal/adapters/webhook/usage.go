@@ -0,0 +1,57 @@
+// Package webhook connects the manager to external HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"service-faas/internal/core/functions"
+)
+
+// UsageSink POSTs usage exports as JSON to a billing endpoint. Any 2xx
+// answer acknowledges the export.
+type UsageSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewUsageSink(url, token string) *UsageSink {
+	return &UsageSink{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *UsageSink) PushUsage(ctx context.Context, export *functions.UsageExport) error {
+	body, err := json.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("encode usage export: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("usage webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("usage webhook returned %s", resp.Status)
+	}
+	return nil
+}
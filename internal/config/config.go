@@ -2,22 +2,33 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"service-faas/pkg/vault"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
 // ... (DeploymentEnvType constants remain the same) ...
 type DeploymentEnvType string
 
 const (
-	EnvDocker     DeploymentEnvType = "docker"
-	EnvKubernetes DeploymentEnvType = "kubernetes"
+	EnvDocker      DeploymentEnvType = "docker"
+	EnvKubernetes  DeploymentEnvType = "kubernetes"
+	EnvECS         DeploymentEnvType = "ecs"
+	EnvKnative     DeploymentEnvType = "knative"
+	EnvFirecracker DeploymentEnvType = "firecracker"
 )
 
 // Config holds all the configuration for the application.
 type Config struct {
 	ListenAddr         string
+	LogLevel           string // zerolog level: "debug", "info" (default), "warn", ...
+	DatabaseDriver     string // "postgres" (default), "mysql", "sqlite" or "memory"
 	DatabaseDSN        string // We will construct this from other vars
 	HarborURL          string
 	HarborUser         string
@@ -29,50 +40,749 @@ type Config struct {
 	DBPassword         string
 	DBHost             string
 	DBName             string
+
+	// WorkerRuntimes maps runtime identifiers to worker images, as
+	// "id=image" entries; functions select one by its id. DefaultRuntime,
+	// when set, is used for functions that select neither a runtime nor a
+	// custom image, instead of WorkerImage.
+	WorkerRuntimes []string
+	DefaultRuntime string
+
+	// WorkerImageRegistries lists the registry hosts per-function worker
+	// images may come from, by default the Harbor registry; "*" allows any
+	// registry.
+	WorkerImageRegistries []string
+
+	// WorkerPort is the port the worker image's HTTP server listens on in
+	// its container. Workers are also given it in the PORT env var.
+	WorkerPort int
+
+	// RegistryAuth lists, in order, where the logins for pulling images
+	// without tenant credentials come from: "harbor" (HarborUser and
+	// HarborPass), "ecr" (ECR tokens from the AWS credentials), "gcp"
+	// (access tokens of the GCE metadata server's service account, as with
+	// GKE workload identity), "dockerhub" (DockerHubUser and a Docker Hub
+	// access token) and "dockerconfig" (the logins in a Docker config.json).
+	// GCPRegistries are the Google registries written to generated pull
+	// secrets.
+	RegistryAuth     []string
+	DockerHubUser    string
+	DockerHubToken   string
+	DockerConfigFile string
+	GCPRegistries    []string
+
+	// TLS for the API server. Either TLSCertFile/TLSKeyFile or
+	// TLSAutocertDomains (ACME) enables HTTPS on ListenAddr. When
+	// TLSRedirectAddr is set, a plain HTTP listener there redirects to HTTPS
+	// and answers ACME HTTP-01 challenges.
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocertDomains  []string
+	TLSAutocertCacheDir string
+	TLSAutocertEmail    string
+	TLSRedirectAddr     string
+
+	// AdminListenAddr, when set, starts a plain HTTP listener there for
+	// profiling (pprof), expvar and runtime stats, with the API's
+	// authentication.
+	AdminListenAddr string
+
+	// APIToken, when set, must be sent as a bearer token on every API call
+	// except executions authorized by a function's invoke token. Empty
+	// leaves the API open.
+	APIToken string
+
+	// WorkerTLSCAFile and WorkerTLSCAKeyFile name the CA used for mutual TLS
+	// between the manager and workers. Leaf certificates last
+	// WorkerTLSCertTTL and are re-issued whenever a worker is (re)started;
+	// workers are redeployed at half of it to renew theirs.
+	WorkerTLSCAFile    string
+	WorkerTLSCAKeyFile string
+	WorkerTLSCertTTL   time.Duration
+
+	// Calls to workers share a pooled transport that keeps up to
+	// WorkerMaxIdleConnsPerHost idle connections per worker open for
+	// WorkerIdleConnTimeout. WorkerMaxConnsPerHost caps the connections to
+	// one worker; 0 means no cap.
+	WorkerMaxIdleConnsPerHost int
+	WorkerMaxConnsPerHost     int
+	WorkerIdleConnTimeout     time.Duration
+
+	// Circuit breaker for functions with a fallback: after
+	// CircuitFailureThreshold consecutive failures the primary is skipped
+	// for CircuitOpenDuration.
+	CircuitFailureThreshold int
+	CircuitOpenDuration     time.Duration
+
+	// ConcurrencyQueueTimeout is how long an execution of a function at its
+	// max_concurrency waits for a slot before it is rejected.
+	ConcurrencyQueueTimeout time.Duration
+
+	// Response caching for functions with a cache TTL: CacheBackend is
+	// "memory" (an LRU of CacheMaxEntries results per replica), "redis"
+	// (shared, at RedisURL) or "none". Results larger than
+	// CacheMaxEntryBytes are not cached.
+	CacheBackend       string
+	CacheMaxEntries    int
+	CacheMaxEntryBytes int
+	RedisURL           string
+
+	// Executions with an Idempotency-Key keep their result for
+	// IdempotencyTTL. A key whose execution never finished (e.g. the
+	// manager crashed) is freed after IdempotencyLockTimeout.
+	IdempotencyTTL         time.Duration
+	IdempotencyLockTimeout time.Duration
+
+	// Every execution gets an invocation ID. With InvocationResultTTL set,
+	// its outcome is kept that long; inline results larger than
+	// InvocationResultMaxBytes are kept without the result.
+	InvocationResultTTL      time.Duration
+	InvocationResultMaxBytes int
+	// InvocationCapturePayloads keeps each execution's payload with its
+	// outcome, encrypted, so the invocation can be replayed. Payloads larger
+	// than InvocationResultMaxBytes are not kept.
+	InvocationCapturePayloads bool
+
+	// Admission control across all functions: at most MaxInFlightExecutions
+	// executions run at once (0 disables the cap). Up to ExecutionQueueSize
+	// more wait for ExecutionQueueTimeout; the rest are rejected at once.
+	MaxInFlightExecutions int
+	ExecutionQueueSize    int
+	ExecutionQueueTimeout time.Duration
+
+	// StreamExecuteConcurrency is how many items of one execution stream
+	// run at once.
+	StreamExecuteConcurrency int
+
+	// ShutdownDrainTimeout is how long shutdown waits for executions in
+	// flight to complete before stopping the workers.
+	ShutdownDrainTimeout time.Duration
+
+	// HAMode runs the manager as one of several replicas sharing the
+	// database. Tasks that must run once per deployment run on the replica
+	// holding the leader lease, which it renews every LeaderLeaseTTL/3.
+	// ReplicaID names the replica in the lease; it defaults to the
+	// hostname.
+	HAMode         bool
+	ReplicaID      string
+	LeaderLeaseTTL time.Duration
+
+	// ReconcileInterval is how often running functions are compared with
+	// their workers; 0 turns reconciliation off.
+	ReconcileInterval time.Duration
+
+	// OwnerDirectoryURL is the identity provider endpoint used to check that
+	// function owners still exist (GET <url>/<owner>). OwnerDirectoryToken is
+	// sent as a bearer token.
+	OwnerDirectoryURL   string
+	OwnerDirectoryToken string
+
+	// MaxUploadBytes bounds the multipart request that uploads a function's
+	// code; MaxPayloadBytes bounds execute payloads, and functions may lower
+	// it further.
+	MaxUploadBytes  int64
+	MaxPayloadBytes int64
+
+	// Limits applied while unpacking uploaded code bundles, guarding against
+	// decompression bombs: total unpacked size, number of files, and ratio of
+	// unpacked to compressed bytes.
+	BundleMaxBytes int
+	BundleMaxFiles int
+	BundleMaxRatio int
+
+	// ImportMaxBytes bounds a catalog archive posted to /import, both as
+	// uploaded and unpacked.
+	ImportMaxBytes int64
+
+	// Uploaded handlers are checked for a compatible handler definition,
+	// and for syntax errors where the orchestrator checks them in the worker
+	// image, unless CodeCheckOnUpload is off. CodeCheckTimeout bounds a check
+	// in the worker image, including pulling the image.
+	CodeCheckOnUpload bool
+	CodeCheckTimeout  time.Duration
+
+	// Warm-up requests per call are capped at WarmupMaxRequests;
+	// WarmupTimeout bounds a whole warm-up, including waiting for a new
+	// worker to listen.
+	WarmupMaxRequests int
+	WarmupTimeout     time.Duration
+
+	// Bulk deletes remove up to BulkDeleteConcurrency functions at once.
+	BulkDeleteConcurrency int
+
+	// DependencyLockTimeout bounds resolving a bundle's requirements.txt
+	// into a requirements.lock in the worker image.
+	DependencyLockTimeout time.Duration
+
+	// PinImageDigests resolves a function's worker image tag to a digest
+	// when the function is created; its workers run that digest until the
+	// function is explicitly upgraded.
+	PinImageDigests bool
+
+	// With ImageBuilds on, each function's code and dependencies are baked
+	// into an image of its own, pushed under BuildImageRepository, which its
+	// workers run. BuildTimeout bounds one build, including the push.
+	ImageBuilds          bool
+	BuildImageRepository string
+	BuildTimeout         time.Duration
+
+	// With GitDeploys on, functions can be deployed from Git repositories,
+	// cloned with the git CLI over GitAllowProtocols only. SSH host keys are
+	// checked against GitKnownHostsFile; when it is unset, a host's key is
+	// trusted on first use. GitTimeout bounds one clone.
+	GitDeploys        bool
+	GitAllowProtocols []string
+	GitKnownHostsFile string
+	GitTimeout        time.Duration
+	// GitWebhookSecret verifies the push webhooks of GitHub and GitLab, which
+	// redeploy the functions bound to the pushed ref.
+	GitWebhookSecret string
+
+	// Uploaded Python sources are scanned for imports of
+	// CodePolicyBannedModules and lines matching CodePolicyBannedPatterns
+	// (regular expressions, one per line of the env value). CodePolicyMode
+	// is "off", "flag" (deploy, but record findings) or "reject".
+	CodePolicyMode           string
+	CodePolicyBannedModules  []string
+	CodePolicyBannedPatterns []string
+
+	// CodeScanner scans every code upload for malware: "clamav" (a clamd
+	// at ClamAVAddress, tcp://host:port or unix:///path), "http" (POSTs the
+	// upload to CodeScannerURL) or "none". CodeScanTimeout bounds a scan.
+	CodeScanner      string
+	ClamAVAddress    string
+	CodeScannerURL   string
+	CodeScannerToken string
+	CodeScanTimeout  time.Duration
+
+	// UsageMemoryMiB is the worker memory size assumed when estimating
+	// GB-seconds; UsageFlushInterval is how often usage counters are
+	// written to the database.
+	UsageMemoryMiB     int
+	UsageFlushInterval time.Duration
+
+	// UsageWebhookURL, when set, receives a JSON usage export for every
+	// completed UsageExportInterval, authenticated with UsageWebhookToken
+	// as a bearer token.
+	UsageWebhookURL     string
+	UsageWebhookToken   string
+	UsageExportInterval time.Duration
+
+	// Lifecycle webhooks. Events are queued in memory, up to
+	// WebhookQueueSize (further events are dropped), and delivered by
+	// WebhookConcurrency senders. A delivery is attempted up to
+	// WebhookMaxAttempts times, waiting WebhookRetryBackoff after the first
+	// failure and twice as long after each further one, without holding a
+	// sender; WebhookTimeout bounds one attempt.
+	WebhookQueueSize    int
+	WebhookConcurrency  int
+	WebhookMaxAttempts  int
+	WebhookRetryBackoff time.Duration
+	WebhookTimeout      time.Duration
+
+	// EventBus publishes every lifecycle and invocation event: "nats" (to
+	// the subject "<EventBusSubject>.<event type>" on the server at
+	// NATSURL) or "none". Up to EventBusQueueSize events wait to be
+	// published; further events are dropped.
+	EventBus          string
+	NATSURL           string
+	EventBusSubject   string
+	EventBusQueueSize int
+
+	// LogShipping ships the output of every running function's workers to a
+	// log backend: "loki", "elasticsearch" or "none". Lines are pushed to
+	// LogShippingURL, authenticated with LogShippingToken, into the index
+	// LogShippingIndex for Elasticsearch. Up to LogShippingQueueSize lines
+	// wait to be shipped; further lines are dropped. LogShippingQueryURL,
+	// when set, links a function's logs in the backend, with {function_id}
+	// and {tenant} replaced.
+	LogShipping          string
+	LogShippingURL       string
+	LogShippingToken     string
+	LogShippingIndex     string
+	LogShippingQueueSize int
+	LogShippingQueryURL  string
+
+	// DockerNetwork is the user-defined network workers are attached to in
+	// docker mode. DockerWorkerHost, when set, is the address of the Docker
+	// host used to reach published worker ports (e.g. a remote daemon).
+	DockerNetwork    string
+	DockerWorkerHost string
+
+	// KubernetesNodePort exposes worker services as NodePort instead of
+	// ClusterIP. Executions are always routed through cluster DNS.
+	KubernetesNodePort bool
+
+	// KubernetesNamespace holds the worker objects in kubernetes and knative
+	// mode. KubernetesAppName is their "app" label and the prefix of their
+	// names; the manager only sees workers carrying it, so installations
+	// sharing a namespace need distinct names.
+	KubernetesNamespace string
+	KubernetesAppName   string
+
+	// Settings for functions exposed directly to external traffic.
+	// KubernetesIngressClass selects the ingress controller for Ingress
+	// exposures; KubernetesGateway and KubernetesGatewayNamespace name the
+	// Gateway that HTTPRoute exposures attach to.
+	KubernetesIngressClass     string
+	KubernetesGateway          string
+	KubernetesGatewayNamespace string
+
+	// KubernetesDomainService, when set, is the manager's own Service:
+	// functions' custom domains get an Ingress routing them to its port 80.
+	// With KubernetesCertIssuer, cert-manager issues each domain a
+	// certificate from that ClusterIssuer.
+	KubernetesDomainService string
+	KubernetesCertIssuer    string
+
+	// Worker pods in kubernetes and knative mode run as
+	// KubernetesServiceAccount and pull images with KubernetesPullSecret
+	// (none when empty); functions may override both. With
+	// KubernetesCreatePullSecret the manager writes that secret itself
+	// from the Harbor login.
+	KubernetesServiceAccount   string
+	KubernetesPullSecret       string
+	KubernetesCreatePullSecret bool
+
+	// SecretsEncryptionKeys lists the keys used to encrypt sensitive database
+	// columns as "id:base64key,...". The first key encrypts new values; the
+	// others are only used to decrypt values written before a rotation.
+	SecretsEncryptionKeys string
+
+	// IdentitySigningKey is a base64 Ed25519 seed. When set, every function
+	// receives a short-lived identity token signed with it, rotated at half
+	// of IdentityTokenTTL. Verifiers fetch the public key from
+	// /.well-known/jwks.json.
+	IdentitySigningKey  string
+	IdentityIssuer      string
+	IdentityAudience    string
+	IdentityTrustDomain string
+	IdentityTokenTTL    time.Duration
+
+	// Code storage: "local" (default) keeps handler code under
+	// FunctionStorageDir; "s3" keeps it in CodeBucket, shared by all
+	// replicas. Adapters download it through URLs valid for CodeURLTTL.
+	CodeStore  string
+	CodeBucket string
+	CodeURLTTL time.Duration
+
+	// Result offloading: results larger than ResultOffloadThreshold bytes are
+	// written to the configured store ("local" or "s3") instead of inlined.
+	ResultStore            string
+	ResultOffloadThreshold int
+	ResultStoreDir         string
+	ResultBaseURL          string
+	ResultBucket           string
+	ResultURLTTL           time.Duration
+
+	// S3-compatible object storage (AWS S3, MinIO).
+	S3Endpoint     string
+	S3Region       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool
+
+	// AWS ECS/Fargate settings, only used when DeploymentEnv is "ecs".
+	AWSRegion                string
+	ECSCluster               string
+	ECSSubnets               []string
+	ECSSecurityGroups        []string
+	ECSAssignPublicIP        bool
+	ECSExecutionRoleARN      string
+	ECSRegistryCredentialARN string
+	ECSCodeLoaderImage       string
+
+	// Firecracker microVM settings, only used when DeploymentEnv is "firecracker".
+	FirecrackerBin       string
+	FirecrackerKernel    string
+	FirecrackerRootfs    string
+	FirecrackerBootArgs  string
+	FirecrackerRunDir    string
+	FirecrackerSubnet    string
+	FirecrackerVCPUs     int
+	FirecrackerMemoryMiB int
+
+	// HashiCorp Vault, when VaultAddr is set. Any setting may then be given
+	// as "vault:<path>#<key>" to read it from Vault (see ConnectVault).
+	VaultAddr                string
+	VaultNamespace           string
+	VaultAuthMethod          string // "token", "approle" or "kubernetes"
+	VaultAuthMount           string
+	VaultRoleID              string
+	VaultRole                string
+	VaultKubernetesTokenPath string
 }
 
-// MustLoad loads configuration from environment variables.
-func MustLoad() Config {
-	env := getenv("DEPLOYMENT_ENV", "docker")
-	var deploymentEnv DeploymentEnvType
-	switch strings.ToLower(env) {
-	case "kubernetes":
-		deploymentEnv = EnvKubernetes
+// Load loads configuration from environment variables and the YAML config
+// file at path, or at CONFIG_FILE when path is empty. Environment variables
+// override the file's settings. Settings given as "vault:<path>#<key>" are
+// read through vc, which ConnectVault returns.
+func Load(path string, vc *vault.Client) (Config, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	s, err := readFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	s.vault = vc
+
+	env := s.getenv("DEPLOYMENT_ENV", "docker")
+	deploymentEnv := DeploymentEnvType(strings.ToLower(env))
+	switch deploymentEnv {
+	case EnvDocker, EnvKubernetes, EnvECS, EnvKnative, EnvFirecracker:
 	default:
-		deploymentEnv = EnvDocker
+		s.invalid("DEPLOYMENT_ENV", env, "docker, kubernetes, ecs, knative or firecracker")
 	}
 
 	// Load individual database components
-	dbUser := getenv("POSTGRES_USER", "user")
-	dbPassword := getenv("POSTGRES_PASSWORD", "password")
-	dbHost := getenv("POSTGRES_HOST", "localhost")
-	dbName := getenv("POSTGRES_DB", "faasdb")
-	dbPort := getenv("POSTGRES_PORT", "5432")
+	dbUser := s.getenv("POSTGRES_USER", "user")
+	dbPassword := s.getenv("POSTGRES_PASSWORD", "")
+	dbHost := s.getenv("POSTGRES_HOST", "localhost")
+	dbName := s.getenv("POSTGRES_DB", "faasdb")
+	dbPort := s.getenv("POSTGRES_PORT", "5432")
+	// Settings of the other drivers are read either way, so a config file
+	// holding them is not rejected as unknown.
+	mysqlUser := s.getenv("MYSQL_USER", "user")
+	mysqlPassword := s.getenv("MYSQL_PASSWORD", "")
+	mysqlHost := s.getenv("MYSQL_HOST", "localhost")
+	mysqlDB := s.getenv("MYSQL_DATABASE", "faasdb")
+	mysqlPort := s.getenv("MYSQL_PORT", "3306")
+	sqlitePath := s.getenv("SQLITE_PATH", "faas.db")
 
 	// Construct the DSN string with URL encoding for credentials
+	dbDriver := s.getenv("DB_DRIVER", "postgres")
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		url.QueryEscape(dbUser), url.QueryEscape(dbPassword), dbHost, dbPort, dbName,
 	)
+	switch dbDriver {
+	case "postgres":
+		s.checkPort("POSTGRES_PORT", dbPort)
+		if dbPassword == "" {
+			s.problems = append(s.problems, "POSTGRES_PASSWORD must be set with DB_DRIVER=postgres")
+		}
+	case "mysql":
+		s.checkPort("MYSQL_PORT", mysqlPort)
+		if mysqlPassword == "" {
+			s.problems = append(s.problems, "MYSQL_PASSWORD must be set with DB_DRIVER=mysql")
+		}
+		dbUser, dbPassword, dbHost, dbName = mysqlUser, mysqlPassword, mysqlHost, mysqlDB
+		mc := mysql.NewConfig()
+		mc.User = dbUser
+		mc.Passwd = dbPassword
+		mc.Net = "tcp"
+		mc.Addr = net.JoinHostPort(dbHost, mysqlPort)
+		mc.DBName = dbName
+		mc.ParseTime = true
+		mc.Loc = time.UTC
+		mc.Params = map[string]string{"charset": "utf8mb4"}
+		dsn = mc.FormatDSN()
+	case "sqlite":
+		// A single file; WAL and a busy timeout keep concurrent requests
+		// from failing with "database is locked".
+		dsn = sqlitePath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	}
 
-	return Config{
-		ListenAddr:         getenv("LISTEN_ADDR", ":8080"),
+	// A single SECRETS_ENCRYPTION_KEY is accepted as a keyring of one.
+	secretsKeys := s.getenv("SECRETS_ENCRYPTION_KEYS", "")
+	if key := s.getenv("SECRETS_ENCRYPTION_KEY", ""); secretsKeys == "" && key != "" {
+		secretsKeys = "default:" + key
+	}
+
+	harborURL := s.getenv("HARBOR_URL", "harbor.yourdomain.com")
+	vaultAddr, vaultNamespace, vaultAuth := s.vaultSettings()
+
+	cfg := Config{
+		ListenAddr:         s.getenv("LISTEN_ADDR", ":8080"),
+		LogLevel:           s.getenv("LOG_LEVEL", "info"),
+		DatabaseDriver:     dbDriver,
 		DatabaseDSN:        dsn, // Use the constructed DSN
-		HarborURL:          getenv("HARBOR_URL", "harbor.yourdomain.com"),
-		HarborUser:         getenv("HARBOR_USER", "admin"),
-		HarborPass:         getenv("HARBOR_PASS", "Harbor12345"),
-		WorkerImage:        getenv("WORKER_IMAGE", "harbor.yourdomain.com/library/worker-faas:latest"),
-		FunctionStorageDir: getenv("FUNCTION_STORAGE_DIR", "/tmp/faas_functions"),
+		HarborURL:          harborURL,
+		HarborUser:         s.getenv("HARBOR_USER", ""),
+		HarborPass:         s.getenv("HARBOR_PASS", ""),
+		WorkerImage:        s.getenv("WORKER_IMAGE", "harbor.yourdomain.com/library/worker-faas:latest"),
+		FunctionStorageDir: s.getenv("FUNCTION_STORAGE_DIR", "/tmp/faas_functions"),
 		DeploymentEnv:      deploymentEnv,
 		DBUser:             dbUser,
 		DBPassword:         dbPassword,
 		DBHost:             dbHost,
 		DBName:             dbName,
+
+		WorkerRuntimes:        splitList(s.getenv("WORKER_RUNTIMES", "")),
+		DefaultRuntime:        s.getenv("DEFAULT_RUNTIME", ""),
+		WorkerImageRegistries: splitList(s.getenv("WORKER_IMAGE_REGISTRIES", harborURL)),
+		WorkerPort:            s.getenvInt("WORKER_PORT", 8000),
+		RegistryAuth:          splitList(s.getenv("REGISTRY_AUTH", "harbor")),
+		DockerHubUser:         s.getenv("DOCKERHUB_USER", ""),
+		DockerHubToken:        s.getenv("DOCKERHUB_TOKEN", ""),
+		DockerConfigFile:      s.getenv("DOCKER_CONFIG_FILE", ""),
+		GCPRegistries:         splitList(s.getenv("GCP_REGISTRIES", "gcr.io")),
+
+		TLSCertFile:         s.getenv("TLS_CERT_FILE", ""),
+		TLSKeyFile:          s.getenv("TLS_KEY_FILE", ""),
+		TLSAutocertDomains:  splitList(s.getenv("TLS_AUTOCERT_DOMAINS", "")),
+		TLSAutocertCacheDir: s.getenv("TLS_AUTOCERT_CACHE_DIR", "/var/lib/service-faas/autocert"),
+		TLSAutocertEmail:    s.getenv("TLS_AUTOCERT_EMAIL", ""),
+		TLSRedirectAddr:     s.getenv("TLS_REDIRECT_ADDR", ""),
+		AdminListenAddr:     s.getenv("ADMIN_LISTEN_ADDR", ""),
+		APIToken:            s.getenv("API_TOKEN", ""),
+
+		WorkerTLSCAFile:    s.getenv("WORKER_TLS_CA_FILE", ""),
+		WorkerTLSCAKeyFile: s.getenv("WORKER_TLS_CA_KEY_FILE", ""),
+		WorkerTLSCertTTL:   s.getenvDuration("WORKER_TLS_CERT_TTL", 30*24*time.Hour),
+
+		WorkerMaxIdleConnsPerHost: s.getenvInt("WORKER_MAX_IDLE_CONNS_PER_HOST", 100),
+		WorkerMaxConnsPerHost:     s.getenvInt("WORKER_MAX_CONNS_PER_HOST", 0),
+		WorkerIdleConnTimeout:     s.getenvDuration("WORKER_IDLE_CONN_TIMEOUT", 90*time.Second),
+
+		CircuitFailureThreshold: s.getenvInt("CIRCUIT_FAILURE_THRESHOLD", 5),
+		CircuitOpenDuration:     s.getenvDuration("CIRCUIT_OPEN_DURATION", 30*time.Second),
+
+		ConcurrencyQueueTimeout: s.getenvDuration("CONCURRENCY_QUEUE_TIMEOUT", 5*time.Second),
+
+		CacheBackend:       s.getenv("CACHE_BACKEND", "memory"),
+		CacheMaxEntries:    s.getenvInt("CACHE_MAX_ENTRIES", 10000),
+		CacheMaxEntryBytes: s.getenvInt("CACHE_MAX_ENTRY_BYTES", 1<<20),
+		RedisURL:           s.getenv("REDIS_URL", "redis://localhost:6379/0"),
+
+		IdempotencyTTL:         s.getenvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLockTimeout: s.getenvDuration("IDEMPOTENCY_LOCK_TIMEOUT", 5*time.Minute),
+
+		InvocationResultTTL:       s.getenvDuration("INVOCATION_RESULT_TTL", 0),
+		InvocationResultMaxBytes:  s.getenvInt("INVOCATION_RESULT_MAX_BYTES", 64<<10),
+		InvocationCapturePayloads: s.getenvBool("INVOCATION_CAPTURE_PAYLOADS", false),
+
+		MaxInFlightExecutions: s.getenvInt("MAX_INFLIGHT_EXECUTIONS", 0),
+		ExecutionQueueSize:    s.getenvInt("EXECUTION_QUEUE_SIZE", 100),
+		ExecutionQueueTimeout: s.getenvDuration("EXECUTION_QUEUE_TIMEOUT", 5*time.Second),
+
+		StreamExecuteConcurrency: s.getenvInt("STREAM_EXECUTE_CONCURRENCY", 8),
+
+		ShutdownDrainTimeout: s.getenvDuration("SHUTDOWN_DRAIN_TIMEOUT", 30*time.Second),
+
+		HAMode:         s.getenvBool("HA_MODE", false),
+		ReplicaID:      s.getenv("REPLICA_ID", ""),
+		LeaderLeaseTTL: s.getenvDuration("LEADER_LEASE_TTL", 15*time.Second),
+
+		ReconcileInterval: s.getenvDuration("RECONCILE_INTERVAL", time.Minute),
+
+		OwnerDirectoryURL:   s.getenv("OWNER_DIRECTORY_URL", ""),
+		OwnerDirectoryToken: s.getenv("OWNER_DIRECTORY_TOKEN", ""),
+
+		MaxUploadBytes:  int64(s.getenvInt("MAX_UPLOAD_BYTES", 10<<20)),
+		MaxPayloadBytes: int64(s.getenvInt("MAX_PAYLOAD_BYTES", 6<<20)),
+
+		BundleMaxBytes: s.getenvInt("BUNDLE_MAX_BYTES", 512<<20),
+		BundleMaxFiles: s.getenvInt("BUNDLE_MAX_FILES", 10000),
+		BundleMaxRatio: s.getenvInt("BUNDLE_MAX_RATIO", 100),
+		ImportMaxBytes: int64(s.getenvInt("IMPORT_MAX_BYTES", 4<<30)),
+
+		CodeCheckOnUpload: s.getenvBool("CODE_CHECK_ON_UPLOAD", true),
+		CodeCheckTimeout:  s.getenvDuration("CODE_CHECK_TIMEOUT", time.Minute),
+
+		DependencyLockTimeout: s.getenvDuration("DEPENDENCY_LOCK_TIMEOUT", 5*time.Minute),
+
+		WarmupMaxRequests: s.getenvInt("WARMUP_MAX_REQUESTS", 50),
+		WarmupTimeout:     s.getenvDuration("WARMUP_TIMEOUT", 5*time.Minute),
+
+		BulkDeleteConcurrency: s.getenvInt("BULK_DELETE_CONCURRENCY", 8),
+
+		PinImageDigests: s.getenvBool("PIN_IMAGE_DIGESTS", false),
+
+		ImageBuilds:          s.getenvBool("IMAGE_BUILDS", false),
+		BuildImageRepository: s.getenv("BUILD_IMAGE_REPOSITORY", harborURL+"/faas-functions"),
+		BuildTimeout:         s.getenvDuration("BUILD_TIMEOUT", 15*time.Minute),
+
+		GitDeploys:        s.getenvBool("GIT_DEPLOYS", false),
+		GitAllowProtocols: splitList(s.getenv("GIT_ALLOW_PROTOCOLS", "https,ssh")),
+		GitKnownHostsFile: s.getenv("GIT_KNOWN_HOSTS_FILE", ""),
+		GitTimeout:        s.getenvDuration("GIT_TIMEOUT", 2*time.Minute),
+		GitWebhookSecret:  s.getenv("GIT_WEBHOOK_SECRET", ""),
+
+		CodePolicyMode:           s.getenv("CODE_POLICY_MODE", "off"),
+		CodePolicyBannedModules:  splitList(s.getenv("CODE_POLICY_BANNED_MODULES", "")),
+		CodePolicyBannedPatterns: splitLines(s.getenv("CODE_POLICY_BANNED_PATTERNS", "")),
+
+		CodeScanner:      s.getenv("CODE_SCANNER", "none"),
+		ClamAVAddress:    s.getenv("CLAMAV_ADDRESS", "tcp://localhost:3310"),
+		CodeScannerURL:   s.getenv("CODE_SCANNER_URL", ""),
+		CodeScannerToken: s.getenv("CODE_SCANNER_TOKEN", ""),
+		CodeScanTimeout:  s.getenvDuration("CODE_SCAN_TIMEOUT", time.Minute),
+
+		UsageMemoryMiB:     s.getenvInt("USAGE_MEMORY_MIB", 512),
+		UsageFlushInterval: s.getenvDuration("USAGE_FLUSH_INTERVAL", time.Minute),
+
+		UsageWebhookURL:     s.getenv("USAGE_WEBHOOK_URL", ""),
+		UsageWebhookToken:   s.getenv("USAGE_WEBHOOK_TOKEN", ""),
+		UsageExportInterval: s.getenvDuration("USAGE_EXPORT_INTERVAL", 24*time.Hour),
+
+		WebhookQueueSize:    s.getenvInt("WEBHOOK_QUEUE_SIZE", 1000),
+		WebhookConcurrency:  s.getenvInt("WEBHOOK_CONCURRENCY", 4),
+		WebhookMaxAttempts:  s.getenvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookRetryBackoff: s.getenvDuration("WEBHOOK_RETRY_BACKOFF", time.Second),
+		WebhookTimeout:      s.getenvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		EventBus:          s.getenv("EVENT_BUS", "none"),
+		NATSURL:           s.getenv("NATS_URL", "nats://localhost:4222"),
+		EventBusSubject:   s.getenv("EVENT_BUS_SUBJECT", "faas.events"),
+		EventBusQueueSize: s.getenvInt("EVENT_BUS_QUEUE_SIZE", 10000),
+
+		LogShipping:          s.getenv("LOG_SHIPPING", "none"),
+		LogShippingURL:       s.getenv("LOG_SHIPPING_URL", ""),
+		LogShippingToken:     s.getenv("LOG_SHIPPING_TOKEN", ""),
+		LogShippingIndex:     s.getenv("LOG_SHIPPING_INDEX", "faas-logs"),
+		LogShippingQueueSize: s.getenvInt("LOG_SHIPPING_QUEUE_SIZE", 10000),
+		LogShippingQueryURL:  s.getenv("LOG_SHIPPING_QUERY_URL", ""),
+
+		DockerNetwork:    s.getenv("DOCKER_NETWORK", "faas-workers"),
+		DockerWorkerHost: s.getenv("DOCKER_WORKER_HOST", ""),
+
+		KubernetesNodePort:         s.getenvBool("KUBERNETES_NODEPORT", false),
+		KubernetesNamespace:        s.getenv("KUBERNETES_NAMESPACE", "scadable-faas"),
+		KubernetesAppName:          s.getenv("KUBERNETES_APP_NAME", "faas-worker"),
+		KubernetesIngressClass:     s.getenv("KUBERNETES_INGRESS_CLASS", ""),
+		KubernetesGateway:          s.getenv("KUBERNETES_GATEWAY", ""),
+		KubernetesGatewayNamespace: s.getenv("KUBERNETES_GATEWAY_NAMESPACE", ""),
+		KubernetesDomainService:    s.getenv("KUBERNETES_DOMAIN_SERVICE", ""),
+		KubernetesCertIssuer:       s.getenv("KUBERNETES_CERT_ISSUER", ""),
+		KubernetesServiceAccount:   s.getenv("KUBERNETES_SERVICE_ACCOUNT", "faas-manager-sa"),
+		KubernetesPullSecret:       s.getenv("KUBERNETES_PULL_SECRET", "harbor-registry-secret"),
+		KubernetesCreatePullSecret: s.getenvBool("KUBERNETES_CREATE_PULL_SECRET", false),
+
+		SecretsEncryptionKeys: secretsKeys,
+
+		IdentitySigningKey:  s.getenv("IDENTITY_SIGNING_KEY", ""),
+		IdentityIssuer:      s.getenv("IDENTITY_ISSUER", "service-faas"),
+		IdentityAudience:    s.getenv("IDENTITY_AUDIENCE", ""),
+		IdentityTrustDomain: s.getenv("IDENTITY_TRUST_DOMAIN", "service-faas.local"),
+		IdentityTokenTTL:    s.getenvDuration("IDENTITY_TOKEN_TTL", 15*time.Minute),
+
+		CodeStore:  s.getenv("CODE_STORE", "local"),
+		CodeBucket: s.getenv("CODE_BUCKET", ""),
+		CodeURLTTL: s.getenvDuration("CODE_URL_TTL", 15*time.Minute),
+
+		ResultStore:            s.getenv("RESULT_STORE", ""),
+		ResultOffloadThreshold: s.getenvInt("RESULT_OFFLOAD_THRESHOLD", 1<<20),
+		ResultStoreDir:         s.getenv("RESULT_STORE_DIR", "/tmp/faas_results"),
+		ResultBaseURL:          s.getenv("RESULT_BASE_URL", "http://localhost:8080"),
+		ResultBucket:           s.getenv("RESULT_BUCKET", ""),
+		ResultURLTTL:           s.getenvDuration("RESULT_URL_TTL", time.Hour),
+
+		S3Endpoint:     s.getenv("S3_ENDPOINT", ""),
+		S3Region:       s.getenv("S3_REGION", s.getenv("AWS_REGION", "us-east-1")),
+		S3AccessKey:    s.getenv("S3_ACCESS_KEY", ""),
+		S3SecretKey:    s.getenv("S3_SECRET_KEY", ""),
+		S3UsePathStyle: s.getenvBool("S3_USE_PATH_STYLE", false),
+
+		AWSRegion:                s.getenv("AWS_REGION", ""),
+		ECSCluster:               s.getenv("ECS_CLUSTER", "scadable-faas"),
+		ECSSubnets:               splitList(s.getenv("ECS_SUBNETS", "")),
+		ECSSecurityGroups:        splitList(s.getenv("ECS_SECURITY_GROUPS", "")),
+		ECSAssignPublicIP:        s.getenvBool("ECS_ASSIGN_PUBLIC_IP", false),
+		ECSExecutionRoleARN:      s.getenv("ECS_EXECUTION_ROLE_ARN", ""),
+		ECSRegistryCredentialARN: s.getenv("ECS_REGISTRY_CREDENTIAL_ARN", ""),
+		ECSCodeLoaderImage:       s.getenv("ECS_CODE_LOADER_IMAGE", "public.ecr.aws/docker/library/busybox:stable"),
+
+		FirecrackerBin:       s.getenv("FIRECRACKER_BIN", "firecracker"),
+		FirecrackerKernel:    s.getenv("FIRECRACKER_KERNEL", ""),
+		FirecrackerRootfs:    s.getenv("FIRECRACKER_ROOTFS", ""),
+		FirecrackerBootArgs:  s.getenv("FIRECRACKER_BOOT_ARGS", "console=ttyS0 reboot=k panic=1 pci=off"),
+		FirecrackerRunDir:    s.getenv("FIRECRACKER_RUN_DIR", "/var/lib/faas/firecracker"),
+		FirecrackerSubnet:    s.getenv("FIRECRACKER_SUBNET", "172.16.0.0/16"),
+		FirecrackerVCPUs:     s.getenvInt("FIRECRACKER_VCPUS", 1),
+		FirecrackerMemoryMiB: s.getenvInt("FIRECRACKER_MEM_MIB", 512),
+
+		VaultAddr:                vaultAddr,
+		VaultNamespace:           vaultNamespace,
+		VaultAuthMethod:          vaultAuth.Method,
+		VaultAuthMount:           vaultAuth.Mount,
+		VaultRoleID:              vaultAuth.RoleID,
+		VaultRole:                vaultAuth.Role,
+		VaultKubernetesTokenPath: vaultAuth.TokenPath,
+	}
+
+	problems := append(s.problems, cfg.validate()...)
+	if err := s.unknown(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return cfg, &ValidationError{Problems: problems}
 	}
+	return cfg, nil
 }
 
-func getenv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
+func (s *settings) getenv(key, fallback string) string {
+	if value, ok := s.lookup(key); ok {
 		return value
 	}
 	return fallback
 }
+
+// getenvInt, getenvDuration and getenvBool treat an empty value as unset and
+// record a value that does not parse as a problem.
+func (s *settings) getenvInt(key string, fallback int) int {
+	if value, ok := s.lookup(key); ok && value != "" {
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			return n
+		}
+		s.invalid(key, value, "an integer")
+	}
+	return fallback
+}
+
+func (s *settings) getenvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := s.lookup(key); ok && value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			return d
+		}
+		s.invalid(key, value, "a duration such as 30s or 5m")
+	}
+	return fallback
+}
+
+func (s *settings) getenvBool(key string, fallback bool) bool {
+	if value, ok := s.lookup(key); ok && value != "" {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
+			return b
+		}
+		s.invalid(key, value, "true or false")
+	}
+	return fallback
+}
+
+func (s *settings) checkPort(key, value string) {
+	if !validPort(value) {
+		s.invalid(key, value, "a port number")
+	}
+}
+
+func (s *settings) invalid(key, value, want string) {
+	s.problems = append(s.problems, fmt.Sprintf("%s must be %s, got %q", key, want, value))
+}
+
+// splitList parses a comma-separated env value, or a list from the config
+// file, dropping empty entries.
+func splitList(value string) []string {
+	var out []string
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// splitLines parses a newline-separated env value, for entries that may
+// contain commas, dropping empty lines.
+func splitLines(value string) []string {
+	var out []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"service-faas/pkg/vault"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mapSettings are settings whose file value is a map, turned into the
+// "key=value" list their env var holds, e.g. a runtime ID to its image.
+var mapSettings = []string{"WORKER_RUNTIMES"}
+
+// settings looks settings up in the environment, then in the config file.
+type settings struct {
+	path string
+	file map[string]string // env var name -> value
+	used map[string]bool
+
+	problems []string // values that did not parse
+	vault    *vault.Client
+}
+
+// readFile reads a YAML (or JSON) config file into env-style settings. Keys
+// are the env var names in lower case, and nested maps join their keys with
+// underscores, so
+//
+//	cache:
+//	  backend: redis
+//
+// sets CACHE_BACKEND. Lists become the comma- or newline-separated values
+// their env vars take.
+func readFile(path string) (*settings, error) {
+	s := &settings{path: path, file: map[string]string{}, used: map[string]bool{}}
+	if path == "" {
+		return s, nil
+	}
+	if ext := filepath.Ext(path); ext == ".toml" {
+		return nil, fmt.Errorf("config file %s: TOML is not supported, use YAML", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if err := s.flatten("", doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *settings) flatten(prefix string, m map[string]any) error {
+	for key, value := range m {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch v := value.(type) {
+		case nil:
+		case map[string]any:
+			if slices.Contains(mapSettings, name) {
+				entries := make([]string, 0, len(v))
+				for k, item := range v {
+					entries = append(entries, k+"="+fmt.Sprint(item))
+				}
+				sort.Strings(entries)
+				s.file[name] = strings.Join(entries, "\n")
+				continue
+			}
+			if err := s.flatten(name, v); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				if _, ok := item.(map[string]any); ok {
+					return fmt.Errorf("%s: expected a list of values", strings.ToLower(name))
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			s.file[name] = strings.Join(items, "\n")
+		default:
+			s.file[name] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// lookup returns a setting's env var, or else its value in the config file,
+// read from Vault when it references a Vault secret.
+func (s *settings) lookup(key string) (string, bool) {
+	s.used[key] = true
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value, ok = s.file[key]
+	}
+	if ok && strings.HasPrefix(value, vaultPrefix) {
+		value = s.resolve(key, value)
+	}
+	return value, ok
+}
+
+// unknown reports config file settings no setting was looked up for, most
+// likely misspelled.
+func (s *settings) unknown() error {
+	var names []string
+	for name := range s.file {
+		if !s.used[name] {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("config file %s: unknown settings: %s", s.path, strings.Join(names, ", "))
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d configuration problem(s): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// validate checks the loaded configuration and returns a description of each
+// problem with it.
+func (c *Config) validate() []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		if !slices.Contains(allowed, value) {
+			add("%s must be one of %s, got %q", name, orList(allowed), value)
+		}
+	}
+	addr := func(name, value string) {
+		if _, port, err := net.SplitHostPort(value); err != nil || !validPort(port) {
+			add("%s must be a host:port address, got %q", name, value)
+		}
+	}
+	absURL := func(name, value string, schemes ...string) {
+		u, err := url.Parse(value)
+		// unix:// URLs name a socket path rather than a host.
+		if err != nil || (u.Host == "" && u.Scheme != "unix") || !slices.Contains(schemes, u.Scheme) {
+			add("%s must be a %s URL, got %q", name, orList(schemes), value)
+		}
+	}
+	positive := func(name string, d time.Duration) {
+		if d <= 0 {
+			add("%s must be positive, got %s", name, d)
+		}
+	}
+	atLeast := func(name string, d, min time.Duration) {
+		if d < min {
+			add("%s must be at least %s, got %s", name, min, d)
+		}
+	}
+
+	// Values and formats.
+	addr("LISTEN_ADDR", c.ListenAddr)
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		add("LOG_LEVEL %q is not a log level", c.LogLevel)
+	}
+	oneOf("DB_DRIVER", c.DatabaseDriver, "postgres", "mysql", "sqlite", "memory")
+	if c.HarborURL == "" {
+		add("HARBOR_URL is required")
+	}
+	if c.WorkerImage == "" {
+		add("WORKER_IMAGE is required")
+	}
+	oneOf("CACHE_BACKEND", c.CacheBackend, "memory", "redis", "none")
+	if c.CacheBackend == "redis" {
+		absURL("REDIS_URL", c.RedisURL, "redis", "rediss", "unix")
+	}
+	oneOf("CODE_STORE", c.CodeStore, "local", "s3")
+	if c.ResultStore != "" {
+		oneOf("RESULT_STORE", c.ResultStore, "local", "s3")
+	}
+	oneOf("CODE_POLICY_MODE", c.CodePolicyMode, "off", "flag", "reject")
+	oneOf("CODE_SCANNER", c.CodeScanner, "none", "clamav", "http")
+	oneOf("EVENT_BUS", c.EventBus, "none", "nats")
+	if c.EventBus == "nats" {
+		absURL("NATS_URL", c.NATSURL, "nats", "tls")
+	}
+	oneOf("LOG_SHIPPING", c.LogShipping, "none", "loki", "elasticsearch")
+	if c.LogShipping != "none" {
+		absURL("LOG_SHIPPING_URL", c.LogShippingURL, "http", "https")
+		if c.LogShippingQueueSize < 1 {
+			add("LOG_SHIPPING_QUEUE_SIZE must be at least 1, got %d", c.LogShippingQueueSize)
+		}
+		if c.DeploymentEnv != EnvDocker && c.DeploymentEnv != EnvKubernetes {
+			add("LOG_SHIPPING is not supported with DEPLOYMENT_ENV=%s", c.DeploymentEnv)
+		}
+	}
+	if c.InvocationCapturePayloads && c.InvocationResultTTL <= 0 {
+		add("INVOCATION_CAPTURE_PAYLOADS needs INVOCATION_RESULT_TTL")
+	}
+	if c.InvocationCapturePayloads && c.DatabaseDriver != "memory" && c.SecretsEncryptionKeys == "" {
+		add("INVOCATION_CAPTURE_PAYLOADS needs SECRETS_ENCRYPTION_KEYS to encrypt the payloads")
+	}
+	if c.LogShipping == "elasticsearch" && c.LogShippingIndex == "" {
+		add("LOG_SHIPPING=elasticsearch needs LOG_SHIPPING_INDEX")
+	}
+	for _, setting := range [][2]string{
+		{"OWNER_DIRECTORY_URL", c.OwnerDirectoryURL},
+		{"LOG_SHIPPING_QUERY_URL", c.LogShippingQueryURL},
+		{"USAGE_WEBHOOK_URL", c.UsageWebhookURL},
+		{"CODE_SCANNER_URL", c.CodeScannerURL},
+		{"RESULT_BASE_URL", c.ResultBaseURL},
+		{"S3_ENDPOINT", c.S3Endpoint},
+	} {
+		if setting[1] != "" {
+			absURL(setting[0], setting[1], "http", "https")
+		}
+	}
+	if c.MaxInFlightExecutions < 0 || c.ExecutionQueueSize < 0 {
+		add("MAX_INFLIGHT_EXECUTIONS and EXECUTION_QUEUE_SIZE must not be negative")
+	}
+	if c.StreamExecuteConcurrency < 1 || c.BulkDeleteConcurrency < 1 || c.WebhookConcurrency < 1 {
+		add("STREAM_EXECUTE_CONCURRENCY, BULK_DELETE_CONCURRENCY and WEBHOOK_CONCURRENCY must be at least 1")
+	}
+	for _, provider := range c.RegistryAuth {
+		oneOf("REGISTRY_AUTH entry", provider, "harbor", "ecr", "gcp", "dockerhub", "dockerconfig")
+	}
+	if c.WorkerPort < 1 || c.WorkerPort > 65535 {
+		add("WORKER_PORT must be between 1 and 65535, got %d", c.WorkerPort)
+	}
+	if c.DeploymentEnv == EnvKubernetes || c.DeploymentEnv == EnvKnative {
+		for _, setting := range [][2]string{
+			{"KUBERNETES_NAMESPACE", c.KubernetesNamespace},
+			{"KUBERNETES_APP_NAME", c.KubernetesAppName},
+		} {
+			if errs := validation.IsDNS1123Label(setting[1]); len(errs) > 0 {
+				add("%s %q is not a valid name: %s", setting[0], setting[1], strings.Join(errs, "; "))
+			}
+		}
+	}
+	positive("SHUTDOWN_DRAIN_TIMEOUT", c.ShutdownDrainTimeout)
+	positive("WARMUP_TIMEOUT", c.WarmupTimeout)
+	positive("USAGE_FLUSH_INTERVAL", c.UsageFlushInterval)
+	positive("CIRCUIT_OPEN_DURATION", c.CircuitOpenDuration)
+
+	// Identity tokens are rotated at half their lifetime.
+	if c.IdentitySigningKey != "" {
+		atLeast("IDENTITY_TOKEN_TTL", c.IdentityTokenTTL, time.Minute)
+	}
+
+	if c.VaultAddr != "" {
+		absURL("VAULT_ADDR", c.VaultAddr, "http", "https")
+		oneOf("VAULT_AUTH_METHOD", c.VaultAuthMethod, "token", "approle", "kubernetes")
+	}
+
+	// Settings that need or exclude one another.
+	if (c.HarborUser == "") != (c.HarborPass == "") {
+		add("HARBOR_USER and HARBOR_PASS must be set together")
+	}
+	if len(c.TLSAutocertDomains) > 0 && (c.TLSCertFile != "" || c.TLSKeyFile != "") {
+		add("TLS_CERT_FILE/TLS_KEY_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		add("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSRedirectAddr != "" {
+		addr("TLS_REDIRECT_ADDR", c.TLSRedirectAddr)
+		if c.TLSCertFile == "" && len(c.TLSAutocertDomains) == 0 {
+			add("TLS_REDIRECT_ADDR needs TLS to be configured")
+		}
+	}
+	if c.AdminListenAddr != "" {
+		addr("ADMIN_LISTEN_ADDR", c.AdminListenAddr)
+		if c.AdminListenAddr == c.ListenAddr || c.AdminListenAddr == c.TLSRedirectAddr {
+			add("ADMIN_LISTEN_ADDR must differ from LISTEN_ADDR and TLS_REDIRECT_ADDR")
+		}
+	}
+	if (c.WorkerTLSCAFile == "") != (c.WorkerTLSCAKeyFile == "") {
+		add("WORKER_TLS_CA_FILE and WORKER_TLS_CA_KEY_FILE must be set together")
+	}
+	if c.WorkerTLSCAFile != "" {
+		positive("WORKER_TLS_CERT_TTL", c.WorkerTLSCertTTL)
+	}
+	if c.CodeStore == "s3" {
+		if c.CodeBucket == "" {
+			add("CODE_STORE=s3 needs CODE_BUCKET")
+		}
+		positive("CODE_URL_TTL", c.CodeURLTTL)
+	}
+	if c.ResultStore == "s3" && c.ResultBucket == "" {
+		add("RESULT_STORE=s3 needs RESULT_BUCKET")
+	}
+	if c.CodeScanner == "http" && c.CodeScannerURL == "" {
+		add("CODE_SCANNER=http needs CODE_SCANNER_URL")
+	}
+	if c.GitDeploys {
+		positive("GIT_TIMEOUT", c.GitTimeout)
+		if len(c.GitAllowProtocols) == 0 {
+			add("GIT_DEPLOYS needs GIT_ALLOW_PROTOCOLS")
+		}
+	}
+	if c.GitWebhookSecret != "" && !c.GitDeploys {
+		add("GIT_WEBHOOK_SECRET needs GIT_DEPLOYS")
+	}
+	if c.ImageBuilds {
+		if c.CodeStore != "local" {
+			add("IMAGE_BUILDS needs CODE_STORE=local")
+		}
+		positive("BUILD_TIMEOUT", c.BuildTimeout)
+	}
+	// Rollups are hourly, so exports must cover whole hours.
+	if c.UsageWebhookURL != "" && (c.UsageExportInterval < time.Hour || c.UsageExportInterval%time.Hour != 0) {
+		add("USAGE_EXPORT_INTERVAL must be a whole number of hours, got %s", c.UsageExportInterval)
+	}
+	if c.KubernetesCreatePullSecret && c.KubernetesPullSecret == "" {
+		add("KUBERNETES_CREATE_PULL_SECRET needs KUBERNETES_PULL_SECRET")
+	}
+	if slices.Contains(c.RegistryAuth, "dockerhub") && (c.DockerHubUser == "" || c.DockerHubToken == "") {
+		add("REGISTRY_AUTH=dockerhub needs DOCKERHUB_USER and DOCKERHUB_TOKEN")
+	}
+	if slices.Contains(c.RegistryAuth, "dockerconfig") && c.DockerConfigFile == "" {
+		add("REGISTRY_AUTH=dockerconfig needs DOCKER_CONFIG_FILE")
+	}
+	switch {
+	case c.VaultAddr == "":
+	case c.VaultAuthMethod == "approle" && c.VaultRoleID == "":
+		add("VAULT_AUTH_METHOD=approle needs VAULT_ROLE_ID and VAULT_SECRET_ID")
+	case c.VaultAuthMethod == "kubernetes" && c.VaultRole == "":
+		add("VAULT_AUTH_METHOD=kubernetes needs VAULT_ROLE")
+	}
+	switch c.DeploymentEnv {
+	case EnvECS:
+		if len(c.ECSSubnets) == 0 {
+			add("ECS_SUBNETS is required in ecs mode")
+		}
+	case EnvFirecracker:
+		if c.FirecrackerKernel == "" || c.FirecrackerRootfs == "" {
+			add("FIRECRACKER_KERNEL and FIRECRACKER_ROOTFS are required in firecracker mode")
+		}
+	}
+
+	// HA mode refuses settings that keep state on, or reach workers
+	// through, the local machine, since any replica may serve any request.
+	if c.HAMode {
+		if c.DatabaseDriver == "memory" {
+			add("HA_MODE needs a shared database, not DB_DRIVER=memory")
+		}
+		if c.CodeStore == "local" {
+			add("HA_MODE needs CODE_STORE=s3")
+		}
+		if c.ResultStore == "local" {
+			add("HA_MODE needs RESULT_STORE=s3 or unset")
+		}
+		if c.DeploymentEnv == EnvDocker || c.DeploymentEnv == EnvFirecracker {
+			// Docker workers mount code from the manager's disk and
+			// firecracker VMs run on the manager's host.
+			add("HA_MODE is not supported with DEPLOYMENT_ENV=%s", c.DeploymentEnv)
+		}
+		// Leaders renew at a third of the lease.
+		atLeast("LEADER_LEASE_TTL", c.LeaderLeaseTTL, time.Second)
+	}
+	return problems
+}
+
+// orList joins items as "a, b or c".
+func orList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 0 && n <= 65535
+}
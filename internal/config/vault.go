@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"service-faas/pkg/vault"
+	"strings"
+	"time"
+)
+
+// vaultPrefix marks a setting read from Vault: "vault:<path>#<key>".
+const vaultPrefix = "vault:"
+
+// vaultTimeout bounds logging in to Vault and reading one secret.
+const vaultTimeout = 10 * time.Second
+
+// ConnectVault logs in to Vault with the VAULT_* settings of the environment
+// and the config file at path (or CONFIG_FILE), and returns nil when
+// VAULT_ADDR is not set. The client must be kept alive (see
+// vault.Client.KeepAlive) for as long as the secrets it read are used.
+func ConnectVault(path string) (*vault.Client, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	s, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	addr, namespace, auth := s.vaultSettings()
+	if len(s.problems) > 0 {
+		return nil, &ValidationError{Problems: s.problems}
+	}
+	if addr == "" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	return vault.New(ctx, addr, namespace, auth)
+}
+
+func (s *settings) vaultSettings() (addr, namespace string, auth vault.Auth) {
+	auth = vault.Auth{
+		Method:    s.getenv("VAULT_AUTH_METHOD", vault.AuthToken),
+		Mount:     s.getenv("VAULT_AUTH_MOUNT", ""),
+		Token:     s.getenv("VAULT_TOKEN", ""),
+		RoleID:    s.getenv("VAULT_ROLE_ID", ""),
+		SecretID:  s.getenv("VAULT_SECRET_ID", ""),
+		Role:      s.getenv("VAULT_ROLE", ""),
+		TokenPath: s.getenv("VAULT_KUBERNETES_TOKEN_PATH", vault.DefaultKubernetesTokenPath),
+	}
+	return s.getenv("VAULT_ADDR", ""), s.getenv("VAULT_NAMESPACE", ""), auth
+}
+
+// resolve reads a "vault:<path>#<key>" value of setting key from Vault.
+func (s *settings) resolve(key, ref string) string {
+	path, field, ok := strings.Cut(strings.TrimPrefix(ref, vaultPrefix), "#")
+	if !ok || path == "" || field == "" {
+		s.problems = append(s.problems, fmt.Sprintf("%s must reference a Vault secret as vault:<path>#<key>", key))
+		return ""
+	}
+	if s.vault == nil {
+		s.problems = append(s.problems, fmt.Sprintf("%s is read from Vault, which needs VAULT_ADDR", key))
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	value, err := s.vault.Secret(ctx, path, field)
+	if err != nil {
+		s.problems = append(s.problems, fmt.Sprintf("%s: %v", key, err))
+		return ""
+	}
+	return value
+}
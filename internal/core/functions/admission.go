@@ -0,0 +1,64 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded is returned when the manager is running its maximum number of
+// executions and its wait queue is full or the wait timed out.
+var ErrOverloaded = errors.New("too many executions in flight")
+
+// admission caps the executions in flight across all functions. Executions
+// beyond the cap wait in a bounded queue. A cap that is not positive admits
+// everything. The cap and queue can be resized while executions are in
+// flight; those already admitted count against the new cap.
+type admission struct {
+	slots    *semaphore
+	queued   atomic.Int64
+	maxQueue atomic.Int64
+	wait     atomic.Int64 // time.Duration
+}
+
+func newAdmission(maxInFlight, maxQueue int, wait time.Duration) *admission {
+	a := &admission{slots: newSemaphore(maxInFlight)}
+	a.maxQueue.Store(int64(maxQueue))
+	a.wait.Store(int64(wait))
+	return a
+}
+
+// resize changes the cap, queue size and wait of an admission in place.
+func (a *admission) resize(maxInFlight, maxQueue int, wait time.Duration) {
+	a.maxQueue.Store(int64(maxQueue))
+	a.wait.Store(int64(wait))
+	a.slots.resize(maxInFlight)
+}
+
+// admit takes a slot, queueing for up to the configured wait if none is
+// free. The returned func releases the slot.
+func (a *admission) admit(ctx context.Context) (func(), error) {
+	if ok, _ := a.slots.tryAcquire(); ok {
+		return a.slots.release, nil
+	}
+
+	if a.queued.Add(1) > a.maxQueue.Load() {
+		a.queued.Add(-1)
+		return nil, ErrOverloaded
+	}
+	defer a.queued.Add(-1)
+	ok, err := a.slots.acquire(ctx, time.Duration(a.wait.Load()))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrOverloaded
+	}
+	return a.slots.release, nil
+}
+
+// queuedExecutions returns the number of executions waiting for a slot.
+func (a *admission) queuedExecutions() int64 {
+	return a.queued.Load()
+}
@@ -0,0 +1,129 @@
+package functions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"service-faas/internal/config"
+	"sort"
+	"strconv"
+)
+
+// AffinityKeyHeader carries the affinity key of an execution.
+const AffinityKeyHeader = "X-Affinity-Key"
+
+// affinityVirtualNodes is how many points each replica gets on the hash
+// ring, so keys spread evenly over few replicas.
+const affinityVirtualNodes = 64
+
+// Affinity routes executions with the same key to the same worker replica,
+// for handlers that keep per-session state in memory.
+type Affinity struct {
+	// Field names the top-level field of JSON object payloads that holds the
+	// key. The caller's X-Affinity-Key header takes precedence over it.
+	Field string `json:"field,omitempty"`
+}
+
+type affinityKey struct{}
+
+// WithAffinityKey returns a context carrying the affinity key asked for by
+// the caller, e.g. from the X-Affinity-Key header. It is only used for
+// functions with an Affinity.
+func WithAffinityKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+func (m *Manager) validateAffinity(a *Affinity) error {
+	if a != nil && m.cfg.DeploymentEnv != config.EnvDocker {
+		return fmt.Errorf("%w: affinity is only supported in docker mode, where the manager balances replicas", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// SetAffinity replaces a function's affinity settings; nil removes them.
+func (m *Manager) SetAffinity(ctx context.Context, functionID string, a *Affinity) (*Function, error) {
+	if err := m.validateAffinity(a); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.Affinity = a
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update affinity: %w", err)
+	}
+	return fn, nil
+}
+
+// executionAffinityKey returns the affinity key of an execution of fn, or
+// "" when fn has no affinity or the execution carries no key.
+func executionAffinityKey(ctx context.Context, fn *Function, payload string) string {
+	if fn.Affinity == nil {
+		return ""
+	}
+	if key, _ := ctx.Value(affinityKey{}).(string); key != "" {
+		return key
+	}
+	if fn.Affinity.Field == "" {
+		return ""
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal([]byte(payload), &fields) != nil {
+		return ""
+	}
+	raw, ok := fields[fn.Affinity.Field]
+	if !ok || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// hashRing places replicas on a ring of hashes. A key belongs to the first
+// replica at or after its hash, so adding or removing a replica moves only
+// the keys of that replica.
+type hashRing struct {
+	points   []uint64
+	replicas []int // replica of each point
+}
+
+func newHashRing(endpoints []string) *hashRing {
+	r := &hashRing{}
+	for i, endpoint := range endpoints {
+		for v := range affinityVirtualNodes {
+			r.points = append(r.points, ringHash(endpoint+"#"+strconv.Itoa(v)))
+			r.replicas = append(r.replicas, i)
+		}
+	}
+	sort.Sort(r)
+	return r
+}
+
+func (r *hashRing) Len() int           { return len(r.points) }
+func (r *hashRing) Less(i, j int) bool { return r.points[i] < r.points[j] }
+func (r *hashRing) Swap(i, j int) {
+	r.points[i], r.points[j] = r.points[j], r.points[i]
+	r.replicas[i], r.replicas[j] = r.replicas[j], r.replicas[i]
+}
+
+// walk calls visit with the replicas in ring order from key's hash on,
+// until visit returns true.
+func (r *hashRing) walk(key string, visit func(replica int) bool) {
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= ringHash(key) })
+	for k := range r.points {
+		if visit(r.replicas[(start+k)%len(r.points)]) {
+			return
+		}
+	}
+}
+
+func ringHash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
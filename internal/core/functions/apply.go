@@ -0,0 +1,541 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"service-faas/pkg/rand"
+	"slices"
+	"strings"
+)
+
+// namePattern matches function names: DNS labels, so they fit in URLs and
+// resource names.
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+func validateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: name %q must be at most 63 lower case letters, digits and '-', starting and ending with a letter or digit", ErrInvalidArgument, name)
+	}
+	return nil
+}
+
+// checkNameFree checks that a name is valid and no function has it.
+func (m *Manager) checkNameFree(ctx context.Context, name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	fn, err := m.repo.FindByName(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("look up name: %w", err)
+	}
+	return fmt.Errorf("%w: function '%s' is named '%s'", ErrNameTaken, fn.ID, name)
+}
+
+// FunctionByName returns the function with a name, as GetFunction does.
+func (m *Manager) FunctionByName(ctx context.Context, name string) (*Function, error) {
+	fn, err := m.repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetFunction(ctx, fn.ID)
+}
+
+// Manifest declares functions for Apply.
+type Manifest struct {
+	Functions []FunctionSpec `json:"functions"`
+}
+
+// FunctionSpec declares a function: its name, which identifies it, its code
+// and its settings. Settings left out take their defaults, so applying a
+// spec without a setting clears it.
+type FunctionSpec struct {
+	Name         string   `json:"name"`
+	FunctionName string   `json:"function_name"`
+	Code         CodeSpec `json:"code"`
+
+	Public      bool        `json:"public,omitempty"`
+	Domain      string      `json:"domain,omitempty"`
+	CORS        *CORSPolicy `json:"cors,omitempty"`
+	Runtime     string      `json:"runtime,omitempty"`
+	WorkerImage string      `json:"worker_image,omitempty"`
+	Tenant      string      `json:"tenant,omitempty"`
+	Owner       string      `json:"owner,omitempty"`
+	Description string      `json:"description,omitempty"`
+
+	Labels          map[string]string `json:"labels,omitempty"`
+	EnvVars         map[string]string `json:"env_vars,omitempty"`
+	PreHook         *Hook             `json:"pre_hook,omitempty"`
+	PostHook        *Hook             `json:"post_hook,omitempty"`
+	Exposure        *Exposure         `json:"exposure,omitempty"`
+	Fallback        *Fallback         `json:"fallback,omitempty"`
+	MaxConcurrency  int               `json:"max_concurrency,omitempty"`
+	MaxPayloadBytes int64             `json:"max_payload_bytes,omitempty"`
+	PayloadSchema   json.RawMessage   `json:"payload_schema,omitempty" swaggertype:"object"`
+	CacheTTLSeconds int               `json:"cache_ttl_seconds,omitempty"`
+	TimeoutSeconds  int               `json:"timeout_seconds,omitempty"`
+	Replicas        int               `json:"replicas,omitempty"`
+	Affinity        *Affinity         `json:"affinity,omitempty"`
+	Kubernetes      *KubernetesWorker `json:"kubernetes,omitempty"`
+	Warmup          *Warmup           `json:"warmup,omitempty"`
+}
+
+// CodeSpec locates a function's code: a Git repository, or the handler
+// source itself.
+type CodeSpec struct {
+	Git    *GitCodeSpec `json:"git,omitempty"`
+	Inline string       `json:"inline,omitempty"`
+}
+
+// GitCodeSpec is the Git repository a function is deployed from. Without a
+// Token or DeployKey, a function keeps the credentials it has.
+type GitCodeSpec struct {
+	URL       string `json:"url"`
+	Ref       string `json:"ref,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Token     string `json:"token,omitempty"`
+	DeployKey string `json:"deploy_key,omitempty"`
+}
+
+// Outcomes of applying a spec.
+const (
+	ApplyCreated   = "created"
+	ApplyUpdated   = "updated"
+	ApplyUnchanged = "unchanged"
+	ApplyFailed    = "failed"
+)
+
+// ApplyResult is the outcome of applying a spec. Changes lists the settings
+// an update changed, by their JSON names, with "code" for the code. Error
+// explains a failed spec, or why an applied function could not be
+// (re)started.
+type ApplyResult struct {
+	Name    string   `json:"name"`
+	ID      string   `json:"id,omitempty"`
+	Result  string   `json:"result"`
+	Changes []string `json:"changes,omitempty"`
+	Status  string   `json:"status,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Apply applies the specs of a manifest one after another, like
+// ApplyFunction. A spec that fails does not stop the others.
+func (m *Manager) Apply(ctx context.Context, manifest Manifest) ([]ApplyResult, error) {
+	if len(manifest.Functions) == 0 {
+		return nil, fmt.Errorf("%w: the manifest declares no functions", ErrInvalidArgument)
+	}
+	seen := map[string]bool{}
+	for _, spec := range manifest.Functions {
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("%w: the manifest declares '%s' twice", ErrInvalidArgument, spec.Name)
+		}
+		seen[spec.Name] = true
+	}
+
+	results := make([]ApplyResult, 0, len(manifest.Functions))
+	for _, spec := range manifest.Functions {
+		_, result, err := m.ApplyFunction(ctx, spec, Precondition{})
+		if result == nil {
+			result = &ApplyResult{Name: spec.Name, Result: ApplyFailed}
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// Precondition makes ApplyFunction conditional on the function's current
+// state, for optimistic concurrency. The zero value applies unconditionally.
+type Precondition struct {
+	// IfMatch lists ETags, one of which the function must have, or is "*"
+	// for any function that exists.
+	IfMatch []string
+	// IfNoneMatch requires that no function has the name yet.
+	IfNoneMatch bool
+}
+
+func (p Precondition) check(fn *Function, name string) error {
+	switch {
+	case fn == nil && len(p.IfMatch) > 0:
+		return fmt.Errorf("%w: no function is named '%s'", ErrPreconditionFailed, name)
+	case fn == nil:
+		return nil
+	case p.IfNoneMatch:
+		return fmt.Errorf("%w: function '%s' is named '%s'", ErrPreconditionFailed, fn.ID, name)
+	case len(p.IfMatch) == 0 || slices.Contains(p.IfMatch, "*") || slices.Contains(p.IfMatch, fn.ETag()):
+		return nil
+	}
+	return fmt.Errorf("%w: function '%s' has changed", ErrPreconditionFailed, fn.ID)
+}
+
+// ETag identifies a function's declared state: its code and the settings a
+// FunctionSpec sets. It changes when they do, through any endpoint, but not
+// with the function's status.
+func (fn *Function) ETag() string {
+	state := struct {
+		Spec       FunctionSpec `json:"spec"`
+		CodeSHA256 string       `json:"code_sha256"`
+		Git        *GitSource   `json:"git"`
+	}{
+		Spec: FunctionSpec{
+			Name:            fn.Name,
+			FunctionName:    fn.FunctionName,
+			Public:          fn.Public,
+			Domain:          fn.Domain,
+			CORS:            fn.CORS,
+			Runtime:         fn.Runtime,
+			WorkerImage:     fn.WorkerImage,
+			Tenant:          fn.Tenant,
+			Owner:           fn.Owner,
+			Description:     fn.Description,
+			Labels:          fn.Labels,
+			EnvVars:         fn.EnvVars,
+			PreHook:         fn.PreHook,
+			PostHook:        fn.PostHook,
+			Exposure:        fn.Exposure,
+			Fallback:        fn.Fallback,
+			MaxConcurrency:  fn.MaxConcurrency,
+			MaxPayloadBytes: fn.MaxPayloadBytes,
+			PayloadSchema:   fn.PayloadSchema,
+			CacheTTLSeconds: fn.CacheTTLSeconds,
+			TimeoutSeconds:  fn.TimeoutSeconds,
+			Replicas:        fn.Replicas,
+			Affinity:        fn.Affinity,
+			Kubernetes:      fn.Kubernetes,
+			Warmup:          fn.Warmup,
+		},
+		CodeSHA256: fn.CodeSHA256,
+		Git:        fn.Git,
+	}
+	data, _ := json.Marshal(state)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ApplyFunction brings the function named in a spec in line with it. A
+// function that does not exist is created and deployed. An existing one is
+// left alone when it matches the spec; otherwise its settings are updated,
+// new code is stored after passing the checks an upload does, and a running
+// worker is restarted when the change needs it (with image builds, new code
+// is built first). A spec whose new code is rejected changes nothing.
+//
+// Code from Git is only fetched when the repository, ref or path changes;
+// SyncFunction deploys new commits of the same ref. An error is returned with
+// a non-nil result when the function was applied but could not be started.
+// The function found by name must meet cond, or ErrPreconditionFailed is
+// returned and nothing changes.
+func (m *Manager) ApplyFunction(ctx context.Context, spec FunctionSpec, cond Precondition) (*Function, *ApplyResult, error) {
+	opts, err := spec.options()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.validateOptions(ctx, &opts); err != nil {
+		return nil, nil, err
+	}
+
+	m.namesMu.Lock()
+	defer m.namesMu.Unlock()
+	fn, err := m.repo.FindByName(ctx, spec.Name)
+	if errors.Is(err, ErrNotFound) {
+		if err := cond.check(nil, spec.Name); err != nil {
+			return nil, nil, err
+		}
+		return m.applyNew(ctx, spec, opts)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("look up name: %w", err)
+	}
+	if err := cond.check(fn, spec.Name); err != nil {
+		return nil, nil, err
+	}
+	if fn.DeletedAt != nil {
+		return nil, nil, fmt.Errorf("%w: function '%s' named '%s' is deleted; restore or purge it first", ErrInvalidArgument, fn.ID, spec.Name)
+	}
+	if fn.BlockedReason != "" {
+		return nil, nil, fmt.Errorf("%w: function '%s': %s", ErrCodeBlocked, fn.ID, fn.BlockedReason)
+	}
+	if _, running := m.syncs.LoadOrStore(fn.ID, struct{}{}); running {
+		return nil, nil, fmt.Errorf("%w: function '%s' is being synced", ErrInvalidArgument, fn.ID)
+	}
+	defer m.syncs.Delete(fn.ID)
+	return m.applyExisting(ctx, fn, spec, opts)
+}
+
+func (m *Manager) applyNew(ctx context.Context, spec FunctionSpec, opts FunctionOptions) (*Function, *ApplyResult, error) {
+	var code io.Reader
+	if opts.Git == nil {
+		code = strings.NewReader(spec.Code.Inline)
+	}
+	fn, err := m.createFunction(ctx, rand.ID16(), spec.FunctionName, code, opts, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := &ApplyResult{Name: spec.Name, ID: fn.ID, Result: ApplyCreated}
+	err = m.launch(ctx, fn)
+	result.Status = fn.Status
+	return fn, result, err
+}
+
+func (m *Manager) applyExisting(ctx context.Context, fn *Function, spec FunctionSpec, opts FunctionOptions) (*Function, *ApplyResult, error) {
+	result := &ApplyResult{Name: spec.Name, ID: fn.ID, Result: ApplyUnchanged, Status: fn.Status}
+	result.Changes = specChanges(fn, spec, opts)
+	if len(result.Changes) == 0 {
+		return fn, result, nil
+	}
+	changed := func(names ...string) bool {
+		for _, name := range names {
+			for _, c := range result.Changes {
+				if c == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if changed("owner") && opts.Owner != "" {
+		if err := m.checkOwner(ctx, opts.Owner); err != nil {
+			return nil, nil, err
+		}
+	}
+	if changed("domain") && opts.Domain != "" {
+		if err := m.checkDomainFree(ctx, opts.Domain, fn.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	updated := *fn
+	updated.FunctionName = spec.FunctionName
+	updated.HandlerPath = fmt.Sprintf("function.handler.%s", spec.FunctionName)
+	updated.Public = opts.Public
+	updated.Domain = opts.Domain
+	updated.CORS = opts.CORS
+	updated.Tenant = opts.Tenant
+	updated.Owner = opts.Owner
+	updated.WorkerImage = opts.WorkerImage
+	updated.Runtime = opts.Runtime
+	updated.PreHook = opts.PreHook
+	updated.PostHook = opts.PostHook
+	updated.Exposure = opts.Exposure
+	updated.Fallback = opts.Fallback
+	updated.Labels = opts.Labels
+	updated.EnvVars = opts.EnvVars
+	updated.Description = opts.Description
+	updated.MaxConcurrency = opts.MaxConcurrency
+	updated.MaxPayloadBytes = opts.MaxPayloadBytes
+	updated.PayloadSchema = opts.PayloadSchema
+	updated.CacheTTLSeconds = opts.CacheTTLSeconds
+	updated.TimeoutSeconds = opts.TimeoutSeconds
+	updated.Replicas = opts.Replicas
+	updated.Affinity = opts.Affinity
+	updated.Kubernetes = opts.Kubernetes
+	updated.Warmup = opts.Warmup
+	if opts.GitAuth.Token != "" || opts.GitAuth.DeployKey != "" {
+		updated.GitToken, updated.GitDeployKey = opts.GitAuth.Token, opts.GitAuth.DeployKey
+	}
+	if opts.Git == nil {
+		updated.Git, updated.GitToken, updated.GitDeployKey = nil, "", ""
+	}
+	if changed("runtime", "worker_image") {
+		digest, err := m.resolveImageDigest(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		updated.ImageDigest = digest
+	}
+	if changed("domain") {
+		if err := m.routeDomain(ctx, fn.ID, opts.Domain); err != nil {
+			return nil, nil, err
+		}
+	}
+	if changed("code", "function_name", "runtime", "worker_image") {
+		if err := m.applyCode(ctx, fn, &updated, spec); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := m.repo.Update(ctx, &updated); err != nil {
+		return nil, nil, fmt.Errorf("db update function: %w", err)
+	}
+	if changed("domain") {
+		m.domains.forget()
+	}
+	fn = &updated
+	result.Result = ApplyUpdated
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("name", fn.Name).Strs("changes", result.Changes).Msg("function applied")
+
+	var err error
+	switch _, builds := m.imageBuilder(); {
+	case builds && changed("code", "runtime", "worker_image"):
+		if !m.startBuild(*fn) {
+			err = fmt.Errorf("%w: function '%s' is already being built", ErrInvalidArgument, fn.ID)
+		} else {
+			fn.BuildStatus = BuildRunning
+		}
+	case fn.Status == "running" && changed("code", "function_name", "runtime", "worker_image", "tenant", "env_vars", "exposure", "replicas", "kubernetes"):
+		err = m.replaceWorker(ctx, fn)
+	}
+	result.Status = fn.Status
+	return fn, result, err
+}
+
+// applyCode stores the code of a spec for fn, recording it in updated. When
+// the new code is rejected, fn's code is restored. Code from Git is fetched
+// again at the deployed commit unless its source changed.
+func (m *Manager) applyCode(ctx context.Context, fn, updated *Function, spec FunctionSpec) error {
+	backup, err := m.backupCode(ctx, fn.ID)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(backup)
+
+	var code io.Reader
+	var format string
+	var commit string
+	if src := spec.Code.Git; src != nil {
+		git := GitSource{URL: src.URL, Ref: src.Ref, Path: src.Path}
+		if fn.Git != nil && fn.Git.URL == git.URL && fn.Git.Ref == git.Ref && fn.Git.Path == git.Path {
+			git.Ref = fn.Git.Commit
+		}
+		co, err := m.checkoutGit(ctx, git, updated.gitAuth())
+		if err != nil {
+			return err
+		}
+		defer co.Close()
+		rc, f, err := co.code(m.bundlesSupported())
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		code, format, commit = rc, f, co.commit
+	} else {
+		code = strings.NewReader(spec.Code.Inline)
+	}
+
+	if err := m.replaceCode(ctx, updated, code, format, ""); err != nil {
+		if restoreErr := m.restoreCode(ctx, fn.ID, backup); restoreErr != nil {
+			m.log(ctx).Error().Err(restoreErr).Str("function_id", fn.ID).Msg("failed to restore previous code after rejected apply")
+			fn.Status = "error"
+			fn.StatusReason = "apply failed and the previous code could not be restored: " + restoreErr.Error()
+			m.repo.Update(ctx, fn)
+		}
+		return err
+	}
+	if spec.Code.Git != nil {
+		updated.Git = &GitSource{URL: spec.Code.Git.URL, Ref: spec.Code.Git.Ref, Path: spec.Code.Git.Path, Commit: commit}
+	}
+	return nil
+}
+
+// options returns the options a spec creates its function with.
+func (s FunctionSpec) options() (FunctionOptions, error) {
+	if s.Name == "" {
+		return FunctionOptions{}, fmt.Errorf("%w: name is required", ErrInvalidArgument)
+	}
+	if s.FunctionName == "" {
+		return FunctionOptions{}, fmt.Errorf("%w: function '%s': function_name is required", ErrInvalidArgument, s.Name)
+	}
+	if (s.Code.Git == nil) == (s.Code.Inline == "") {
+		return FunctionOptions{}, fmt.Errorf("%w: function '%s': code needs exactly one of git or inline", ErrInvalidArgument, s.Name)
+	}
+	opts := FunctionOptions{
+		Name:            s.Name,
+		Public:          s.Public,
+		Domain:          s.Domain,
+		CORS:            s.CORS,
+		Tenant:          s.Tenant,
+		Owner:           s.Owner,
+		WorkerImage:     s.WorkerImage,
+		Runtime:         s.Runtime,
+		PreHook:         s.PreHook,
+		PostHook:        s.PostHook,
+		Exposure:        s.Exposure,
+		Fallback:        s.Fallback,
+		Labels:          s.Labels,
+		EnvVars:         s.EnvVars,
+		Description:     s.Description,
+		MaxConcurrency:  s.MaxConcurrency,
+		MaxPayloadBytes: s.MaxPayloadBytes,
+		PayloadSchema:   s.PayloadSchema,
+		CacheTTLSeconds: s.CacheTTLSeconds,
+		TimeoutSeconds:  s.TimeoutSeconds,
+		Replicas:        s.Replicas,
+		Affinity:        s.Affinity,
+		Kubernetes:      s.Kubernetes,
+		Warmup:          s.Warmup,
+	}
+	if g := s.Code.Git; g != nil {
+		opts.Git = &GitSource{URL: g.URL, Ref: g.Ref, Path: g.Path}
+		opts.GitAuth = GitAuth{Token: g.Token, DeployKey: g.DeployKey}
+	}
+	return opts, nil
+}
+
+// specChanges lists the settings of fn that differ from a spec, by their
+// JSON names.
+func specChanges(fn *Function, spec FunctionSpec, opts FunctionOptions) []string {
+	var changes []string
+	add := func(name string, changed bool) {
+		if changed {
+			changes = append(changes, name)
+		}
+	}
+	add("code", codeChanged(fn, spec))
+	add("function_name", fn.FunctionName != spec.FunctionName)
+	add("public", fn.Public != opts.Public)
+	add("domain", fn.Domain != opts.Domain)
+	add("cors", !sameJSON(fn.CORS, opts.CORS))
+	add("runtime", fn.Runtime != opts.Runtime)
+	add("worker_image", fn.WorkerImage != opts.WorkerImage)
+	add("tenant", fn.Tenant != opts.Tenant)
+	add("owner", fn.Owner != opts.Owner)
+	add("description", fn.Description != opts.Description)
+	add("labels", !maps.Equal(fn.Labels, opts.Labels))
+	add("env_vars", !maps.Equal(fn.EnvVars, opts.EnvVars))
+	add("pre_hook", !sameJSON(fn.PreHook, opts.PreHook))
+	add("post_hook", !sameJSON(fn.PostHook, opts.PostHook))
+	add("exposure", !sameJSON(fn.Exposure, opts.Exposure))
+	add("fallback", !sameJSON(fn.Fallback, opts.Fallback))
+	add("max_concurrency", fn.MaxConcurrency != opts.MaxConcurrency)
+	add("max_payload_bytes", fn.MaxPayloadBytes != opts.MaxPayloadBytes)
+	add("payload_schema", !sameJSON(fn.PayloadSchema, opts.PayloadSchema))
+	add("cache_ttl_seconds", fn.CacheTTLSeconds != opts.CacheTTLSeconds)
+	add("timeout_seconds", fn.TimeoutSeconds != opts.TimeoutSeconds)
+	add("replicas", fn.Replicas != opts.Replicas)
+	add("affinity", !sameJSON(fn.Affinity, opts.Affinity))
+	add("kubernetes", !sameJSON(fn.Kubernetes, opts.Kubernetes))
+	add("warmup", !sameJSON(fn.Warmup, opts.Warmup))
+	add("git_credentials", (opts.GitAuth.Token != "" || opts.GitAuth.DeployKey != "") &&
+		(opts.GitAuth.Token != fn.GitToken || opts.GitAuth.DeployKey != fn.GitDeployKey))
+	return changes
+}
+
+// codeChanged reports whether a spec's code differs from fn's: other inline
+// source, or another repository, ref or path.
+func codeChanged(fn *Function, spec FunctionSpec) bool {
+	if src := spec.Code.Git; src != nil {
+		return fn.Git == nil || fn.Git.URL != src.URL || fn.Git.Ref != src.Ref || fn.Git.Path != src.Path
+	}
+	sum := sha256.Sum256([]byte(spec.Code.Inline))
+	return fn.Git != nil || !strings.EqualFold(hex.EncodeToString(sum[:]), fn.CodeSHA256)
+}
+
+// sameJSON reports whether two values encode to the same JSON, so that empty
+// and missing lists or objects compare equal.
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
@@ -0,0 +1,281 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ImageBuilder is implemented by orchestrators that can bake a function's
+// code and dependencies into an image of its own.
+type ImageBuilder interface {
+	// BuildImage builds spec.Image and pushes it to its registry. The build
+	// output is returned whether or not the build succeeded.
+	BuildImage(ctx context.Context, spec BuildSpec) (string, error)
+	// RemoveImage deletes a built image from its registry and from the
+	// orchestrator's image cache.
+	RemoveImage(ctx context.Context, image string) error
+}
+
+// BuildSpec describes a function image to build.
+type BuildSpec struct {
+	// Image is the reference to tag and push the result as.
+	Image string
+	// BaseImage is the worker image the function's code is added to;
+	// RegistryAuth, when set, holds the tenant's credentials for pulling it.
+	BaseImage    string
+	RegistryAuth *RegistryAuth
+	// CodePath is the directory holding handler.py and the rest of a bundle.
+	CodePath    string
+	HandlerPath string
+	// DependencyLock, when set, is the requirements file in the code
+	// directory to install dependencies from instead of requirements.txt.
+	DependencyLock string
+}
+
+// Build statuses of a function.
+const (
+	BuildRunning   = "running"
+	BuildSucceeded = "succeeded"
+	BuildFailed    = "failed"
+)
+
+// buildRecordTimeout bounds recording the outcome of a build, which may
+// have used up the build's own deadline.
+const buildRecordTimeout = 10 * time.Second
+
+// maxBuildLog bounds the build output kept on a function; the end of the
+// output, where failures show up, is kept.
+const maxBuildLog = 64 << 10
+
+// FunctionBuild is the latest image build of a function.
+type FunctionBuild struct {
+	Status string `json:"status"`
+	Image  string `json:"image,omitempty"`
+	Log    string `json:"log"`
+}
+
+func (m *Manager) imageBuilder() (ImageBuilder, bool) {
+	if !m.cfg.ImageBuilds {
+		return nil, false
+	}
+	builder, ok := m.orchestrator.(ImageBuilder)
+	return builder, ok
+}
+
+// GetFunctionBuild returns the latest image build of a function.
+func (m *Manager) GetFunctionBuild(ctx context.Context, functionID string) (*FunctionBuild, error) {
+	if _, ok := m.imageBuilder(); !ok {
+		return nil, fmt.Errorf("%w: image builds are disabled", ErrNotConfigured)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.BuildStatus == "" {
+		return nil, fmt.Errorf("%w: function '%s' has no image build", ErrNotFound, functionID)
+	}
+	return &FunctionBuild{Status: fn.BuildStatus, Image: fn.BuiltImage, Log: fn.BuildLog}, nil
+}
+
+// RebuildFunction starts a new image build of a function from its stored
+// code. Its workers keep running the previous image until the build is
+// done; the previous image is then removed.
+func (m *Manager) RebuildFunction(ctx context.Context, functionID string) (*Function, error) {
+	if _, ok := m.imageBuilder(); !ok {
+		return nil, fmt.Errorf("%w: image builds are disabled", ErrNotConfigured)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt != nil || fn.BlockedReason != "" {
+		return nil, fmt.Errorf("%w: function '%s' is %s", ErrInvalidArgument, functionID, fn.Status)
+	}
+	if !m.startBuild(*fn) {
+		return nil, fmt.Errorf("%w: function '%s' is already being built", ErrInvalidArgument, functionID)
+	}
+	fn.BuildStatus = BuildRunning
+	return fn, nil
+}
+
+// startBuild builds and deploys a function in the background, unless a build
+// of it is already running.
+func (m *Manager) startBuild(fn Function) bool {
+	if _, running := m.builds.LoadOrStore(fn.ID, struct{}{}); running {
+		return false
+	}
+	go func() {
+		defer m.builds.Delete(fn.ID)
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.BuildTimeout)
+		defer cancel()
+		if err := m.buildAndDeploy(ctx, &fn); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("function image build failed")
+		}
+	}()
+	return true
+}
+
+// buildAndDeploy builds a function's image, then (re)starts its worker on
+// it and removes the image it replaces.
+func (m *Manager) buildAndDeploy(ctx context.Context, fn *Function) error {
+	previous := fn.BuiltImage
+	if err := m.buildImage(ctx, fn); err != nil {
+		if rerr := m.recordBuildFailure(ctx, fn, err); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+
+	if fn.DeletedAt != nil {
+		// Restoring the function deploys the new image.
+		return nil
+	}
+	if fn.ContainerID != "" {
+		if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
+			m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("failed to stop worker of previous image")
+		}
+	}
+	if err := m.deploy(ctx, fn); err != nil {
+		return err
+	}
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("image", fn.BuiltImage).Msg("function deployed from built image")
+
+	if previous != "" && previous != fn.BuiltImage {
+		m.removeBuiltImage(ctx, fn.ID, previous)
+	}
+	return nil
+}
+
+// buildImage builds and pushes a new image for a function and records the
+// outcome on it. BuiltImage is only replaced when the build succeeds.
+func (m *Manager) buildImage(ctx context.Context, fn *Function) error {
+	builder, _ := m.imageBuilder()
+	src, err := m.code.Source(ctx, fn.ID)
+	if err != nil {
+		return fmt.Errorf("locate code: %w", err)
+	}
+	base, err := m.workerImage(fn)
+	if err != nil {
+		return err
+	}
+	spec := BuildSpec{
+		Image:       fmt.Sprintf("%s/fn-%s:%s", strings.TrimRight(m.cfg.BuildImageRepository, "/"), fn.ID, time.Now().UTC().Format("20060102150405")),
+		BaseImage:   base,
+		CodePath:    src.Dir,
+		HandlerPath: fn.HandlerPath,
+	}
+	if fn.DependencyLockSHA256 != "" {
+		spec.DependencyLock = lockFile
+	}
+	if fn.WorkerImage != "" {
+		if spec.RegistryAuth, err = m.registryAuth(ctx, fn.Tenant, fn.WorkerImage); err != nil {
+			return err
+		}
+	}
+
+	fn.BuildStatus = BuildRunning
+	fn.BuildLog = ""
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return fmt.Errorf("db update build status: %w", err)
+	}
+
+	out, err := builder.BuildImage(ctx, spec)
+	if len(out) > maxBuildLog {
+		out = out[len(out)-maxBuildLog:]
+	}
+
+	// The function may have changed, or been purged, during the build.
+	rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), buildRecordTimeout)
+	defer cancel()
+	cur, gerr := m.repo.Get(rctx, fn.ID)
+	if gerr != nil {
+		if err == nil {
+			m.removeBuiltImage(rctx, fn.ID, spec.Image)
+		}
+		return errors.Join(err, fmt.Errorf("reload function after build: %w", gerr))
+	}
+	*fn = *cur
+	fn.BuildLog = out
+	fn.BuildStatus = BuildSucceeded
+	if err != nil {
+		fn.BuildStatus = BuildFailed
+		fn.BuildLog += "\n" + err.Error()
+	} else {
+		fn.BuiltImage = spec.Image
+	}
+	if uerr := m.repo.Update(rctx, fn); uerr != nil {
+		return errors.Join(err, fmt.Errorf("db update build status: %w", uerr))
+	}
+	if err != nil {
+		return fmt.Errorf("build image %s: %w", spec.Image, err)
+	}
+	return nil
+}
+
+// recordBuildFailure marks a function's build failed, unless buildImage got
+// to record it, and leaves a function waiting for its first build in the
+// error status, as it has no image to run. The build's deadline may have
+// passed, so the record is written under a deadline of its own.
+func (m *Manager) recordBuildFailure(ctx context.Context, fn *Function, buildErr error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), buildRecordTimeout)
+	defer cancel()
+	cur, err := m.repo.Get(ctx, fn.ID)
+	if errors.Is(err, ErrNotFound) {
+		// Purged during the build.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reload function after build: %w", err)
+	}
+	*fn = *cur
+	if fn.BuildStatus != BuildFailed {
+		fn.BuildStatus = BuildFailed
+		fn.BuildLog = buildErr.Error()
+	}
+	firstBuild := fn.Status == StatusBuilding
+	if firstBuild {
+		fn.Status = StatusError
+		fn.StatusReason = "image build failed: " + buildErr.Error()
+	}
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return fmt.Errorf("db update build status: %w", err)
+	}
+	if firstBuild {
+		m.emitFunctionEvent(ctx, EventFunctionFailed, fn, buildErr)
+	}
+	return nil
+}
+
+// removeBuiltImage deletes an image built for a function. Failures only
+// leave garbage in the registry, so they are logged.
+func (m *Manager) removeBuiltImage(ctx context.Context, functionID, image string) {
+	builder, ok := m.imageBuilder()
+	if !ok {
+		return
+	}
+	if err := builder.RemoveImage(ctx, image); err != nil {
+		m.log(ctx).Warn().Err(err).Str("function_id", functionID).Str("image", image).Msg("failed to remove built image")
+		return
+	}
+	m.log(ctx).Info().Str("function_id", functionID).Str("image", image).Msg("built image removed")
+}
+
+// resumeBuilds restarts the first builds of functions whose build was cut
+// short by a restart of the manager.
+func (m *Manager) resumeBuilds(ctx context.Context) error {
+	if _, ok := m.imageBuilder(); !ok {
+		return nil
+	}
+	building, err := m.repo.FindByStatus(ctx, StatusBuilding)
+	if err != nil {
+		return fmt.Errorf("could not query functions being built: %w", err)
+	}
+	for _, fn := range building {
+		m.log(ctx).Info().Str("function_id", fn.ID).Msg("resuming function image build")
+		m.startBuild(fn)
+	}
+	return nil
+}
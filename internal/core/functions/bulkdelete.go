@@ -0,0 +1,108 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MaxBulkDelete is the most functions a single bulk delete may name.
+const MaxBulkDelete = 1000
+
+// Outcomes of removing one function in a bulk delete.
+const (
+	BulkDeleted  = "deleted"
+	BulkPurged   = "purged"
+	BulkNotFound = "not_found"
+	BulkFailed   = "error"
+)
+
+// BulkDeleteResult is the outcome of removing one function in a bulk delete.
+type BulkDeleteResult struct {
+	FunctionID string `json:"function_id"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RemoveFunctions removes the given functions like RemoveFunction, or with
+// purge like PurgeFunction, BulkDeleteConcurrency at a time. The results are
+// in the order of functionIDs, without duplicates.
+func (m *Manager) RemoveFunctions(ctx context.Context, functionIDs []string, purge bool) ([]BulkDeleteResult, error) {
+	if len(functionIDs) == 0 {
+		return nil, fmt.Errorf("%w: no function IDs given", ErrInvalidArgument)
+	}
+	if len(functionIDs) > MaxBulkDelete {
+		return nil, fmt.Errorf("%w: at most %d functions can be deleted at once", ErrInvalidArgument, MaxBulkDelete)
+	}
+	return m.removeFunctions(ctx, functionIDs, purge), nil
+}
+
+func (m *Manager) removeFunctions(ctx context.Context, functionIDs []string, purge bool) []BulkDeleteResult {
+	seen := map[string]bool{}
+	results := make([]BulkDeleteResult, 0, len(functionIDs))
+	for _, id := range functionIDs {
+		if !seen[id] {
+			seen[id] = true
+			results = append(results, BulkDeleteResult{FunctionID: id})
+		}
+	}
+
+	remove, done := m.RemoveFunction, BulkDeleted
+	if purge {
+		remove, done = m.PurgeFunction, BulkPurged
+	}
+	slots := make(chan struct{}, max(m.cfg.BulkDeleteConcurrency, 1))
+	var wg sync.WaitGroup
+	for i := range results {
+		res := &results[i]
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			res.Status, res.Error = BulkFailed, ctx.Err().Error()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			switch err := remove(ctx, res.FunctionID); {
+			case err == nil:
+				res.Status = done
+			case errors.Is(err, ErrNotFound):
+				res.Status = BulkNotFound
+			default:
+				res.Status, res.Error = BulkFailed, err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	m.log(ctx).Info().Int("functions", len(results)).Bool("purge", purge).Msg("bulk delete finished")
+	return results
+}
+
+// RemoveFunctionsByLabel removes the functions carrying every label of the
+// selector, see RemoveFunctions; their number is not capped. With purge,
+// already deleted functions that match are purged too.
+func (m *Manager) RemoveFunctionsByLabel(ctx context.Context, selector map[string]string, purge bool) ([]BulkDeleteResult, error) {
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("%w: a label selector is required", ErrInvalidArgument)
+	}
+	fns, err := m.ListFunctions(ctx, ListFilter{Labels: selector})
+	if err != nil {
+		return nil, err
+	}
+	if purge {
+		deleted, err := m.ListFunctions(ctx, ListFilter{Deleted: true, Labels: selector})
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, deleted...)
+	}
+	ids := make([]string, len(fns))
+	for i, fn := range fns {
+		ids[i] = fn.ID
+	}
+	return m.removeFunctions(ctx, ids, purge), nil
+}
@@ -0,0 +1,136 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"service-faas/internal/config"
+	"service-faas/pkg/bundle"
+	"strings"
+)
+
+// BundleStore is implemented by code stores that can keep files other than
+// handler.py, which code bundles need.
+type BundleStore interface {
+	// PutFile stores one file of a bundle under its slash-separated path.
+	PutFile(ctx context.Context, functionID, name string, r io.Reader) error
+	// Files lists the slash-separated paths of a function's stored files,
+	// handler.py included.
+	Files(ctx context.Context, functionID string) ([]string, error)
+	// GetFile opens one of a function's stored files.
+	GetFile(ctx context.Context, functionID, name string) (io.ReadCloser, error)
+}
+
+// storedCode describes code stored by storeCode.
+type storedCode struct {
+	Path   string
+	SHA256 string // of handler.py
+	// PolicyFindings are the code policy findings in its Python files.
+	PolicyFindings []string
+	// Requirements reports whether a bundle has a requirements.txt at its
+	// root; LockSHA256 is the checksum of its requirements.lock, if any.
+	Requirements bool
+	LockSHA256   string
+}
+
+// storeCode stores uploaded code, a single handler file or a bundle. When the
+// client sent a checksum, it must match the uploaded bytes.
+func (m *Manager) storeCode(ctx context.Context, functionID string, code io.Reader, opts FunctionOptions) (*storedCode, error) {
+	upload := sha256.New()
+	code = io.TeeReader(code, upload)
+
+	var stored *storedCode
+	var err error
+	if opts.BundleFormat != "" {
+		stored, err = m.storeBundle(ctx, functionID, code, opts.BundleFormat)
+	} else {
+		handler := sha256.New()
+		var src bytes.Buffer
+		if m.policy.enabled() {
+			code = io.TeeReader(code, &src)
+		}
+		stored = &storedCode{}
+		stored.Path, err = m.code.Put(ctx, functionID, io.TeeReader(code, handler))
+		stored.SHA256 = hex.EncodeToString(handler.Sum(nil))
+		stored.PolicyFindings = m.policy.Scan("handler.py", src.Bytes())
+	}
+	if err != nil {
+		_ = m.code.Delete(ctx, functionID)
+		return nil, err
+	}
+
+	if want := opts.ExpectedSHA256; want != "" {
+		if got := hex.EncodeToString(upload.Sum(nil)); !strings.EqualFold(got, want) {
+			_ = m.code.Delete(ctx, functionID)
+			return nil, fmt.Errorf("%w: upload checksum %s does not match expected %s", ErrInvalidArgument, got, want)
+		}
+	}
+	return stored, nil
+}
+
+func (m *Manager) bundleLimits() bundle.Limits {
+	return bundle.Limits{
+		MaxBytes: int64(m.cfg.BundleMaxBytes),
+		MaxFiles: m.cfg.BundleMaxFiles,
+		MaxRatio: int64(m.cfg.BundleMaxRatio),
+	}
+}
+
+// storeBundle unpacks a code bundle into the code store. handler.py is stored
+// last through Put, so the store only lists the function once the bundle is
+// complete.
+func (m *Manager) storeBundle(ctx context.Context, functionID string, r io.Reader, format string) (*storedCode, error) {
+	store, ok := m.code.(BundleStore)
+	if !ok || m.cfg.DeploymentEnv != config.EnvDocker {
+		return nil, fmt.Errorf("%w: code bundles are only supported in docker mode with the local code store", ErrInvalidArgument)
+	}
+
+	stored := &storedCode{}
+	var handler []byte
+	var storeErr error
+	err := bundle.Extract(r, format, m.bundleLimits(), func(name string, fr io.Reader) error {
+		switch name {
+		case "handler.py":
+			var err error
+			handler, err = io.ReadAll(fr)
+			stored.PolicyFindings = append(stored.PolicyFindings, m.policy.Scan(name, handler)...)
+			return err
+		case requirementsFile:
+			stored.Requirements = true
+		case lockFile:
+			lock := sha256.New()
+			defer func() { stored.LockSHA256 = hex.EncodeToString(lock.Sum(nil)) }()
+			fr = io.TeeReader(fr, lock)
+		}
+		if m.policy.enabled() && strings.HasSuffix(name, ".py") {
+			src, err := io.ReadAll(fr)
+			if err != nil {
+				return err
+			}
+			stored.PolicyFindings = append(stored.PolicyFindings, m.policy.Scan(name, src)...)
+			fr = bytes.NewReader(src)
+		}
+		storeErr = store.PutFile(ctx, functionID, name, fr)
+		return storeErr
+	})
+	if storeErr != nil {
+		return nil, fmt.Errorf("store bundle file: %w", storeErr)
+	}
+	if err != nil {
+		// Anything else is a malformed or oversized archive.
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("%w: bundle has no handler.py at its root", ErrInvalidArgument)
+	}
+
+	if stored.Path, err = m.code.Put(ctx, functionID, bytes.NewReader(handler)); err != nil {
+		return nil, fmt.Errorf("store handler code: %w", err)
+	}
+	sum := sha256.Sum256(handler)
+	stored.SHA256 = hex.EncodeToString(sum[:])
+	return stored, nil
+}
@@ -0,0 +1,151 @@
+package functions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResponseCache stores worker results of functions that opted into caching.
+// Keys are only unique per function.
+type ResponseCache interface {
+	Get(ctx context.Context, functionID, key string) (json.RawMessage, bool, error)
+	Set(ctx context.Context, functionID, key string, result json.RawMessage, ttl time.Duration) error
+	// Invalidate drops every cached result of a function and returns how
+	// many there were.
+	Invalidate(ctx context.Context, functionID string) (int, error)
+}
+
+// WithResponseCache enables response caching for functions with a cache
+// TTL.
+func WithResponseCache(cache ResponseCache) Option {
+	return func(m *Manager) { m.cache = cache }
+}
+
+// CacheStats counts cache lookups of one function since the manager started.
+type CacheStats struct {
+	FunctionID string `json:"function_id"`
+	Hits       int64  `json:"hits"`
+	Misses     int64  `json:"misses"`
+}
+
+// CacheReport totals the cache lookups of all functions.
+type CacheReport struct {
+	Hits      int64        `json:"hits"`
+	Misses    int64        `json:"misses"`
+	Functions []CacheStats `json:"functions"`
+}
+
+type cacheCounters struct {
+	hits, misses atomic.Int64
+}
+
+type cacheMetrics struct {
+	counters sync.Map // function ID -> *cacheCounters
+}
+
+func (c *cacheMetrics) record(functionID string, hit bool) {
+	v, _ := c.counters.LoadOrStore(functionID, &cacheCounters{})
+	if hit {
+		v.(*cacheCounters).hits.Add(1)
+	} else {
+		v.(*cacheCounters).misses.Add(1)
+	}
+}
+
+// cacheKey identifies a payload sent to a particular version of a function's
+// code, so uploading new code never serves stale results.
+func cacheKey(fn *Function, payload string) string {
+	h := sha256.New()
+	h.Write([]byte(fn.CodeSHA256))
+	h.Write([]byte{0})
+	h.Write([]byte(payload))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedInvoke serves the result from the response cache when the function
+// has a cache TTL, unless ctx skips the cache, and caches fresh results. Results answered by a fallback
+// are never cached. Cache failures are logged and the worker is called.
+// The returned bool reports a cache hit.
+func (m *Manager) cachedInvoke(ctx context.Context, fn *Function, payload string) (json.RawMessage, string, bool, error) {
+	if m.cache == nil || fn.CacheTTLSeconds <= 0 || cacheSkipped(ctx) {
+		result, degraded, err := m.invokeWithFallback(ctx, fn, payload)
+		return result, degraded, false, err
+	}
+
+	key := cacheKey(fn, payload)
+	result, ok, err := m.cache.Get(ctx, fn.ID, key)
+	if err != nil {
+		m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("response cache lookup failed")
+	}
+	m.cacheMetrics.record(fn.ID, ok)
+	if ok {
+		return result, "", true, nil
+	}
+
+	result, degraded, err := m.invokeWithFallback(ctx, fn, payload)
+	if err != nil || degraded != "" {
+		return result, degraded, false, err
+	}
+	if limit := m.cfg.CacheMaxEntryBytes; limit <= 0 || len(result) <= limit {
+		ttl := time.Duration(fn.CacheTTLSeconds) * time.Second
+		if err := m.cache.Set(ctx, fn.ID, key, result, ttl); err != nil {
+			m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("response cache store failed")
+		}
+	}
+	return result, "", false, nil
+}
+
+// SetCacheTTL opts a function into response caching for ttlSeconds; zero
+// turns caching off. Already cached results are kept until they expire or
+// are invalidated.
+func (m *Manager) SetCacheTTL(ctx context.Context, functionID string, ttlSeconds int) (*Function, error) {
+	if ttlSeconds < 0 {
+		return nil, fmt.Errorf("%w: cache ttl must not be negative", ErrInvalidArgument)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.CacheTTLSeconds = ttlSeconds
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update cache ttl: %w", err)
+	}
+	return fn, nil
+}
+
+// InvalidateCache drops the cached results of a function.
+func (m *Manager) InvalidateCache(ctx context.Context, functionID string) (int, error) {
+	if m.cache == nil {
+		return 0, fmt.Errorf("%w: response cache", ErrNotConfigured)
+	}
+	if _, err := m.repo.Get(ctx, functionID); err != nil {
+		return 0, err
+	}
+	n, err := m.cache.Invalidate(ctx, functionID)
+	if err != nil {
+		return 0, fmt.Errorf("invalidate response cache: %w", err)
+	}
+	return n, nil
+}
+
+// CacheReport returns the cache hit and miss counts of this manager.
+func (m *Manager) CacheReport() *CacheReport {
+	report := &CacheReport{Functions: []CacheStats{}}
+	m.cacheMetrics.counters.Range(func(k, v any) bool {
+		c := v.(*cacheCounters)
+		s := CacheStats{FunctionID: k.(string), Hits: c.hits.Load(), Misses: c.misses.Load()}
+		report.Hits += s.Hits
+		report.Misses += s.Misses
+		report.Functions = append(report.Functions, s)
+		return true
+	})
+	sort.Slice(report.Functions, func(i, j int) bool { return report.Functions[i].FunctionID < report.Functions[j].FunctionID })
+	return report
+}
@@ -0,0 +1,80 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// CapacityReporter is implemented by orchestrators that can describe the
+// resources of the cluster or host their workers run on.
+type CapacityReporter interface {
+	Capacity(ctx context.Context) (*CapacityReport, error)
+}
+
+// Resources is an amount of CPU and memory.
+type Resources struct {
+	CPUMillis   int64 `json:"cpu_millis"`
+	MemoryBytes int64 `json:"memory_bytes"`
+}
+
+// CapacityReport summarises total versus allocated worker resources.
+type CapacityReport struct {
+	Backend   string         `json:"backend"`
+	Total     Resources      `json:"total"`
+	Allocated Resources      `json:"allocated"`
+	Nodes     []NodeCapacity `json:"nodes"`
+	// Pending lists workers that are not placed or not running yet.
+	Pending []PendingPlacement `json:"pending"`
+	// TopConsumers lists the functions using the most memory, largest first.
+	TopConsumers []FunctionUsage `json:"top_consumers"`
+}
+
+// NodeCapacity describes one node or Docker host.
+type NodeCapacity struct {
+	Name      string    `json:"name"`
+	Total     Resources `json:"total"`
+	Allocated Resources `json:"allocated"`
+	Functions []string  `json:"functions"`
+}
+
+// PendingPlacement is a worker waiting to be scheduled or started.
+type PendingPlacement struct {
+	FunctionID string `json:"function_id"`
+	Worker     string `json:"worker"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// FunctionUsage is the resources held by all workers of one function.
+// Allocated comes from requests/limits; MemoryUsageBytes is measured usage
+// where the backend reports it.
+type FunctionUsage struct {
+	FunctionID       string    `json:"function_id"`
+	Workers          int       `json:"workers"`
+	Allocated        Resources `json:"allocated"`
+	MemoryUsageBytes int64     `json:"memory_usage_bytes,omitempty"`
+}
+
+// RankConsumers orders usage by measured memory, then allocated memory, and
+// keeps the ten largest, for use as CapacityReport.TopConsumers.
+func RankConsumers(usage []FunctionUsage) []FunctionUsage {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].MemoryUsageBytes != usage[j].MemoryUsageBytes {
+			return usage[i].MemoryUsageBytes > usage[j].MemoryUsageBytes
+		}
+		return usage[i].Allocated.MemoryBytes > usage[j].Allocated.MemoryBytes
+	})
+	if len(usage) > 10 {
+		usage = usage[:10]
+	}
+	return usage
+}
+
+// Capacity reports worker resource usage from the orchestrator.
+func (m *Manager) Capacity(ctx context.Context) (*CapacityReport, error) {
+	reporter, ok := m.orchestrator.(CapacityReporter)
+	if !ok {
+		return nil, fmt.Errorf("%w: capacity reporting for %s", ErrNotConfigured, m.cfg.DeploymentEnv)
+	}
+	return reporter.Capacity(ctx)
+}
@@ -0,0 +1,440 @@
+package functions
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"service-faas/pkg/bundle"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// catalogVersion is the version of the catalog archive format written by
+// ExportCatalog.
+const catalogVersion = 1
+
+// catalogManifest is the name of the manifest in a catalog archive; the code
+// of each function is under functions/<id>/.
+const catalogManifest = "manifest.json"
+
+// Catalog is the manifest of a catalog archive.
+type Catalog struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Functions  []CatalogFunction `json:"functions"`
+}
+
+// CatalogFunction is a function in a catalog archive: the settings it was
+// created with, and its status when it was exported.
+type CatalogFunction struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name,omitempty"`
+	Public       bool              `json:"public,omitempty"`
+	Domain       string            `json:"domain,omitempty"`
+	CORS         *CORSPolicy       `json:"cors,omitempty"`
+	ParentID     string            `json:"parent_id,omitempty"`
+	Environment  string            `json:"environment,omitempty"`
+	FunctionName string            `json:"function_name"`
+	Status       string            `json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	CodeSHA256   string            `json:"code_sha256"`
+	Tenant       string            `json:"tenant,omitempty"`
+	Owner        string            `json:"owner,omitempty"`
+	WorkerImage  string            `json:"worker_image,omitempty"`
+	Runtime      string            `json:"runtime,omitempty"`
+	PreHook      *Hook             `json:"pre_hook,omitempty"`
+	PostHook     *Hook             `json:"post_hook,omitempty"`
+	Exposure     *Exposure         `json:"exposure,omitempty"`
+	Fallback     *Fallback         `json:"fallback,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	// EnvVars is only exported when secrets are asked for; otherwise
+	// EnvVarNames lists the names, and the values must be set again after an
+	// import.
+	EnvVars         map[string]string `json:"env_vars,omitempty"`
+	EnvVarNames     []string          `json:"env_var_names,omitempty"`
+	MaxConcurrency  int               `json:"max_concurrency,omitempty"`
+	MaxPayloadBytes int64             `json:"max_payload_bytes,omitempty"`
+	PayloadSchema   json.RawMessage   `json:"payload_schema,omitempty" swaggertype:"object"`
+	CacheTTLSeconds int               `json:"cache_ttl_seconds,omitempty"`
+	TimeoutSeconds  int               `json:"timeout_seconds,omitempty"`
+	Replicas        int               `json:"replicas,omitempty"`
+	Affinity        *Affinity         `json:"affinity,omitempty"`
+	Kubernetes      *KubernetesWorker `json:"kubernetes,omitempty"`
+	Warmup          *Warmup           `json:"warmup,omitempty"`
+	Git             *GitSource        `json:"git,omitempty"`
+	// GitToken and GitDeployKey are only exported when secrets are asked
+	// for.
+	GitToken     string     `json:"git_token,omitempty"`
+	GitDeployKey string     `json:"git_deploy_key,omitempty"`
+	Promotion    *Promotion `json:"promotion,omitempty"`
+}
+
+// Outcomes of importing a function.
+const (
+	ImportCreated = "created"
+	ImportExists  = "exists"
+	ImportFailed  = "failed"
+)
+
+// ImportResult reports what ImportCatalog did with every function of an
+// archive.
+type ImportResult struct {
+	Functions []ImportedFunction `json:"functions"`
+}
+
+// ImportedFunction is the outcome of importing one function. Error explains
+// a failed import, or why a created function could not be deployed.
+type ImportedFunction struct {
+	ID           string `json:"id"`
+	FunctionName string `json:"function_name"`
+	Result       string `json:"result"`
+	Status       string `json:"status,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ExportCatalog opens a zip archive of every function, with its settings in
+// manifest.json and its code under functions/<id>/. Deleted functions and
+// functions blocked by the malware scan are left out. Git credentials and
+// env var values are only included when secrets is set.
+func (m *Manager) ExportCatalog(ctx context.Context, secrets bool) (io.ReadCloser, error) {
+	all, err := m.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list functions: %w", err)
+	}
+	catalog := Catalog{Version: catalogVersion, ExportedAt: time.Now().UTC(), Functions: []CatalogFunction{}}
+	for _, fn := range all {
+		if fn.DeletedAt != nil || fn.BlockedReason != "" {
+			continue
+		}
+		catalog.Functions = append(catalog.Functions, catalogFunction(fn, secrets))
+	}
+	sort.Slice(catalog.Functions, func(i, j int) bool {
+		return catalog.Functions[i].CreatedAt.Before(catalog.Functions[j].CreatedAt)
+	})
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(m.writeCatalog(ctx, pw, catalog))
+	}()
+	return pr, nil
+}
+
+func catalogFunction(fn Function, secrets bool) CatalogFunction {
+	cf := CatalogFunction{
+		ID:              fn.ID,
+		Name:            fn.Name,
+		Public:          fn.Public,
+		Domain:          fn.Domain,
+		CORS:            fn.CORS,
+		ParentID:        fn.ParentID,
+		Environment:     fn.Environment,
+		FunctionName:    fn.FunctionName,
+		Status:          fn.Status,
+		CreatedAt:       fn.CreatedAt,
+		CodeSHA256:      fn.CodeSHA256,
+		Tenant:          fn.Tenant,
+		Owner:           fn.Owner,
+		WorkerImage:     fn.WorkerImage,
+		Runtime:         fn.Runtime,
+		PreHook:         fn.PreHook,
+		PostHook:        fn.PostHook,
+		Exposure:        fn.Exposure,
+		Fallback:        fn.Fallback,
+		Labels:          fn.Labels,
+		Description:     fn.Description,
+		MaxConcurrency:  fn.MaxConcurrency,
+		MaxPayloadBytes: fn.MaxPayloadBytes,
+		PayloadSchema:   fn.PayloadSchema,
+		CacheTTLSeconds: fn.CacheTTLSeconds,
+		TimeoutSeconds:  fn.TimeoutSeconds,
+		Replicas:        fn.Replicas,
+		Affinity:        fn.Affinity,
+		Kubernetes:      fn.Kubernetes,
+		Warmup:          fn.Warmup,
+		Git:             fn.Git,
+		Promotion:       fn.Promotion,
+	}
+	if secrets {
+		cf.GitToken, cf.GitDeployKey = fn.GitToken, fn.GitDeployKey
+		cf.EnvVars = fn.EnvVars
+	} else {
+		cf.EnvVarNames = slices.Sorted(maps.Keys(fn.EnvVars))
+	}
+	return cf
+}
+
+func (m *Manager) writeCatalog(ctx context.Context, w io.Writer, catalog Catalog) error {
+	zw := zip.NewWriter(w)
+	mw, err := zw.Create(catalogManifest)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(catalog); err != nil {
+		return err
+	}
+	store, bundles := m.code.(BundleStore)
+	for _, fn := range catalog.Functions {
+		files := []string{"handler.py"}
+		if bundles {
+			if files, err = store.Files(ctx, fn.ID); err != nil {
+				return err
+			}
+		}
+		for _, name := range files {
+			var rc io.ReadCloser
+			if name == "handler.py" {
+				rc, err = m.code.Get(ctx, fn.ID)
+			} else {
+				rc, err = store.GetFile(ctx, fn.ID, name)
+			}
+			if err != nil {
+				return fmt.Errorf("export code of function '%s': %w", fn.ID, err)
+			}
+			fw, err := zw.Create(path.Join("functions", fn.ID, name))
+			if err == nil {
+				_, err = io.Copy(fw, rc)
+			}
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("export code of function '%s': %w", fn.ID, err)
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// ImportCatalog recreates the functions of an archive written by
+// ExportCatalog, keeping their IDs, in the order they were created, so hooks
+// and fallbacks find the functions they refer to. Functions whose ID is
+// already taken are left alone. Each function's code passes the same checks
+// as an upload. Imported functions are stopped, unless deploy is set: then
+// those that were running when exported are deployed.
+func (m *Manager) ImportCatalog(ctx context.Context, r io.Reader, deploy bool) (*ImportResult, error) {
+	dir, err := os.MkdirTemp("", "faas-import-")
+	if err != nil {
+		return nil, fmt.Errorf("create import dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	catalog, err := m.unpackCatalog(r, dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(catalog.Functions, func(i, j int) bool {
+		return catalog.Functions[i].CreatedAt.Before(catalog.Functions[j].CreatedAt)
+	})
+
+	result := &ImportResult{Functions: []ImportedFunction{}}
+	for _, cf := range catalog.Functions {
+		imported := ImportedFunction{ID: cf.ID, FunctionName: cf.FunctionName}
+		fn, err := m.importFunction(ctx, cf, filepath.Join(dir, "functions", cf.ID), deploy)
+		switch {
+		case errors.Is(err, errFunctionExists):
+			imported.Result = ImportExists
+		case err != nil:
+			imported.Result, imported.Error = ImportFailed, err.Error()
+		default:
+			imported.Result, imported.Status = ImportCreated, fn.Status
+			if deploy && catalogRunning(cf.Status) {
+				if err := m.launch(ctx, fn); err != nil {
+					imported.Error = err.Error()
+				}
+				imported.Status = fn.Status
+			}
+		}
+		if imported.Result != ImportExists {
+			m.log(ctx).Info().Str("function_id", cf.ID).Str("result", imported.Result).Str("error", imported.Error).Msg("function imported")
+		}
+		result.Functions = append(result.Functions, imported)
+	}
+	return result, nil
+}
+
+var errFunctionExists = errors.New("function exists")
+
+// functionID matches the IDs rand.ID16 generates.
+var functionID = regexp.MustCompile(`^[a-z2-7]{16}$`)
+
+// catalogRunning reports whether a function exported with the given status
+// was running, or on its way to running.
+func catalogRunning(status string) bool {
+	switch status {
+	case "running", "creating", StatusPulling, StatusBuilding:
+		return true
+	}
+	return false
+}
+
+// unpackCatalog reads a catalog archive, unpacks the code in it into dir and
+// returns its manifest.
+func (m *Manager) unpackCatalog(r io.Reader, dir string) (*Catalog, error) {
+	spool, err := os.CreateTemp("", "faas-import-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("create import file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	size, err := io.Copy(spool, r)
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	zr, err := zip.NewReader(spool, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid catalog archive: %v", ErrInvalidArgument, err)
+	}
+
+	var total uint64
+	for _, f := range zr.File {
+		total += f.UncompressedSize64
+		if limit := m.cfg.ImportMaxBytes; limit > 0 && total > uint64(limit) {
+			return nil, fmt.Errorf("%w: catalog archive: %v", ErrInvalidArgument, bundle.ErrLimitExceeded)
+		}
+	}
+	var catalog *Catalog
+	for _, f := range zr.File {
+		if f.Mode().IsDir() {
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return nil, fmt.Errorf("%w: catalog entry %q is not a regular file", ErrInvalidArgument, f.Name)
+		}
+		if f.Name == catalogManifest {
+			if catalog, err = readCatalogManifest(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rel := filepath.FromSlash(f.Name)
+		if !strings.HasPrefix(f.Name, "functions/") || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("%w: unexpected catalog entry %q", ErrInvalidArgument, f.Name)
+		}
+		if err := unpackCatalogFile(f, filepath.Join(dir, rel)); err != nil {
+			return nil, err
+		}
+	}
+	if catalog == nil {
+		return nil, fmt.Errorf("%w: catalog archive has no %s", ErrInvalidArgument, catalogManifest)
+	}
+	return catalog, nil
+}
+
+func readCatalogManifest(f *zip.File) (*Catalog, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArgument, catalogManifest, err)
+	}
+	defer rc.Close()
+	var catalog Catalog
+	if err := json.NewDecoder(rc).Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArgument, catalogManifest, err)
+	}
+	if catalog.Version != catalogVersion {
+		return nil, fmt.Errorf("%w: unsupported catalog version %d", ErrInvalidArgument, catalog.Version)
+	}
+	return &catalog, nil
+}
+
+func unpackCatalogFile(f *zip.File, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: catalog entry %q: %v", ErrInvalidArgument, f.Name, err)
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("create import dir: %w", err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create import file: %w", err)
+	}
+	defer out.Close()
+	// The zip reader checks the declared size and checksum of each entry.
+	if _, err := io.Copy(out, rc); err != nil {
+		return fmt.Errorf("%w: catalog entry %q: %v", ErrInvalidArgument, f.Name, err)
+	}
+	return nil
+}
+
+// importFunction creates one function of a catalog from its unpacked code
+// in dir, which must hold the handler.py it was exported with.
+func (m *Manager) importFunction(ctx context.Context, cf CatalogFunction, dir string, deploy bool) (*Function, error) {
+	if !functionID.MatchString(cf.ID) {
+		return nil, fmt.Errorf("%w: invalid function id %q", ErrInvalidArgument, cf.ID)
+	}
+	if cf.Name != "" || cf.Domain != "" {
+		m.namesMu.Lock()
+		defer m.namesMu.Unlock()
+	}
+	exists, err := m.functionExists(ctx, cf.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errFunctionExists
+	}
+	if cf.ParentID != "" {
+		if exists, err := m.functionExists(ctx, cf.ParentID); err != nil || !exists {
+			return nil, fmt.Errorf("%w: function '%s' is an environment of '%s', which was not imported", ErrInvalidArgument, cf.ID, cf.ParentID)
+		}
+	}
+	handler, err := os.ReadFile(filepath.Join(dir, "handler.py"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: catalog has no handler.py for function '%s'", ErrInvalidArgument, cf.ID)
+	}
+	if sum := sha256.Sum256(handler); !strings.EqualFold(hex.EncodeToString(sum[:]), cf.CodeSHA256) {
+		return nil, fmt.Errorf("%w: handler.py of function '%s' does not match its checksum", ErrInvalidArgument, cf.ID)
+	}
+
+	opts := FunctionOptions{
+		Name:            cf.Name,
+		Public:          cf.Public,
+		Domain:          cf.Domain,
+		CORS:            cf.CORS,
+		Tenant:          cf.Tenant,
+		Owner:           cf.Owner,
+		WorkerImage:     cf.WorkerImage,
+		Runtime:         cf.Runtime,
+		PreHook:         cf.PreHook,
+		PostHook:        cf.PostHook,
+		Exposure:        cf.Exposure,
+		Fallback:        cf.Fallback,
+		Labels:          cf.Labels,
+		Description:     cf.Description,
+		EnvVars:         cf.EnvVars,
+		MaxConcurrency:  cf.MaxConcurrency,
+		MaxPayloadBytes: cf.MaxPayloadBytes,
+		PayloadSchema:   cf.PayloadSchema,
+		CacheTTLSeconds: cf.CacheTTLSeconds,
+		TimeoutSeconds:  cf.TimeoutSeconds,
+		Replicas:        cf.Replicas,
+		Affinity:        cf.Affinity,
+		Kubernetes:      cf.Kubernetes,
+		Warmup:          cf.Warmup,
+		Git:             cf.Git,
+		GitAuth:         GitAuth{Token: cf.GitToken, DeployKey: cf.GitDeployKey},
+		ParentID:        cf.ParentID,
+		Environment:     cf.Environment,
+		Promotion:       cf.Promotion,
+	}
+	code, format, err := openCodeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer code.Close()
+	opts.BundleFormat = format
+	return m.createFunction(ctx, cf.ID, cf.FunctionName, code, opts, deploy && catalogRunning(cf.Status))
+}
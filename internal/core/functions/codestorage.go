@@ -0,0 +1,243 @@
+package functions
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"service-faas/pkg/bundle"
+)
+
+// CodeStore keeps the handler code of every function, keyed by function ID.
+type CodeStore interface {
+	// Put stores the handler and returns its location, kept in
+	// Function.CodePath for reference.
+	Put(ctx context.Context, functionID string, code io.Reader) (string, error)
+	Get(ctx context.Context, functionID string) (io.ReadCloser, error)
+	Delete(ctx context.Context, functionID string) error
+	// List returns the IDs of all functions with stored code.
+	List(ctx context.Context) ([]string, error)
+	// Source tells orchestrators where a worker loads the code from.
+	Source(ctx context.Context, functionID string) (CodeSource, error)
+}
+
+// CodeSource locates handler code for a worker: a directory on the manager's
+// disk containing handler.py, or a URL to download handler.py from.
+type CodeSource struct {
+	Dir string
+	URL string
+}
+
+// WithCodeStore sets where handler code is kept.
+func WithCodeStore(store CodeStore) Option {
+	return func(m *Manager) { m.code = store }
+}
+
+// PruneOrphanedCode deletes stored code that no function record refers to,
+// e.g. left behind by a crash between upload and record creation.
+func (m *Manager) PruneOrphanedCode(ctx context.Context) (int, error) {
+	ids, err := m.code.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, id := range ids {
+		exists, err := m.functionExists(ctx, id)
+		if err != nil {
+			return pruned, err
+		}
+		if exists {
+			continue
+		}
+		if err := m.code.Delete(ctx, id); err != nil {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// ExportCode opens a function's stored code for download: its handler.py, or,
+// when it was deployed as a bundle with more files, a zip archive of them
+// all. The second result reports whether the code is a zip archive. Deleted
+// functions can be exported until they are purged; blocked ones cannot.
+func (m *Manager) ExportCode(ctx context.Context, functionID string) (io.ReadCloser, bool, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, false, err
+	}
+	if fn.BlockedReason != "" {
+		return nil, false, fmt.Errorf("%w: function '%s': %s", ErrCodeBlocked, functionID, fn.BlockedReason)
+	}
+	store, ok := m.code.(BundleStore)
+	var files []string
+	if ok {
+		if files, err = store.Files(ctx, functionID); err != nil {
+			return nil, false, err
+		}
+	}
+	if len(files) <= 1 {
+		rc, err := m.code.Get(ctx, functionID)
+		if err != nil {
+			return nil, false, err
+		}
+		return rc, false, nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeCodeZip(ctx, pw, store, functionID, files))
+	}()
+	return pr, true, nil
+}
+
+func writeCodeZip(ctx context.Context, w io.Writer, store BundleStore, functionID string, files []string) error {
+	zw := zip.NewWriter(w)
+	for _, name := range files {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		rc, err := store.GetFile(ctx, functionID, name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("zip %s: %w", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// replaceCode replaces a function's stored code with new code, which must
+// pass the checks an upload does, and records its checksums on fn. When it
+// fails, the previous code is gone; callers restore it. expectedSHA256, when
+// set, must match the new code's bytes.
+func (m *Manager) replaceCode(ctx context.Context, fn *Function, code io.Reader, format, expectedSHA256 string) error {
+	opts := FunctionOptions{Tenant: fn.Tenant, WorkerImage: fn.WorkerImage, Runtime: fn.Runtime, BundleFormat: format, ExpectedSHA256: expectedSHA256}
+
+	// Files the new code no longer has must not linger next to it.
+	if err := m.code.Delete(ctx, fn.ID); err != nil {
+		return fmt.Errorf("delete previous code: %w", err)
+	}
+	scanned, finishScan := m.startScan(ctx, code)
+	stored, err := m.storeCode(ctx, fn.ID, scanned, opts)
+	verdict, err := finishScan(err)
+	if err != nil {
+		return fmt.Errorf("store handler code: %w", err)
+	}
+	if !verdict.Clean {
+		return fmt.Errorf("%w: %s", ErrCodeBlocked, verdict.Threat)
+	}
+	if len(stored.PolicyFindings) > 0 && m.policy.rejects() {
+		return &PolicyError{Findings: stored.PolicyFindings}
+	}
+	if m.cfg.CodeCheckOnUpload {
+		if err := m.checkStoredCode(ctx, fn.ID, fn.FunctionName, opts); err != nil {
+			return err
+		}
+	}
+	lock, err := m.lockDependencies(ctx, fn.ID, stored, opts)
+	if err != nil {
+		return err
+	}
+
+	fn.CodePath = stored.Path
+	fn.CodeSHA256 = stored.SHA256
+	fn.PolicyFindings = stored.PolicyFindings
+	fn.DependencyLockSHA256 = lock
+	return nil
+}
+
+// backupCode copies a function's stored code into a new temporary directory,
+// for restoreCode. The caller removes the directory.
+func (m *Manager) backupCode(ctx context.Context, functionID string) (string, error) {
+	dir, err := os.MkdirTemp("", "faas-code-backup-")
+	if err != nil {
+		return "", fmt.Errorf("create backup dir: %w", err)
+	}
+	store, bundles := m.code.(BundleStore)
+	files := []string{"handler.py"}
+	if bundles {
+		if files, err = store.Files(ctx, functionID); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	for _, name := range files {
+		if err := copyStoredFile(ctx, m.code, functionID, name, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("back up %s: %w", name, err)
+		}
+	}
+	return dir, nil
+}
+
+func copyStoredFile(ctx context.Context, code CodeStore, functionID, name, dst string) error {
+	var rc io.ReadCloser
+	var err error
+	if name == "handler.py" {
+		rc, err = code.Get(ctx, functionID)
+	} else {
+		rc, err = code.(BundleStore).GetFile(ctx, functionID, name)
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, rc)
+	return err
+}
+
+// restoreCode stores the code backupCode copied to dir again. It was checked
+// when it was first stored, so it is not checked again.
+func (m *Manager) restoreCode(ctx context.Context, functionID, dir string) error {
+	if err := m.code.Delete(ctx, functionID); err != nil {
+		return fmt.Errorf("delete rejected code: %w", err)
+	}
+	code, format, err := openCodeDir(dir)
+	if err != nil {
+		return err
+	}
+	defer code.Close()
+	if format == "" {
+		_, err = m.code.Put(ctx, functionID, code)
+		return err
+	}
+	_, err = m.storeBundle(ctx, functionID, code, format)
+	return err
+}
+
+// openCodeDir opens a directory of code for storeCode: its handler.py when
+// that is all it holds, otherwise a bundle of it.
+func openCodeDir(dir string) (io.ReadCloser, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("read code dir: %w", err)
+	}
+	if len(entries) == 1 {
+		f, err := os.Open(filepath.Join(dir, "handler.py"))
+		if err != nil {
+			return nil, "", fmt.Errorf("open handler.py: %w", err)
+		}
+		return f, "", nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(bundle.Pack(pw, dir))
+	}()
+	return pr, bundle.FormatTarGzip, nil
+}
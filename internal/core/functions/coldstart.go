@@ -0,0 +1,19 @@
+package functions
+
+// WorkerColdStartHeader is set to "true" by a worker answering its first
+// request. It lets the manager count the cold starts of replicas it did not
+// start itself, e.g. after a scale-up or a scale from zero.
+const WorkerColdStartHeader = "X-Worker-Cold-Start"
+
+// markCold records that fn's worker was just started, so its next invocation
+// counts as a cold start.
+func (m *Manager) markCold(fn *Function) {
+	m.coldWorkers.Store(fn.ID, fn.ContainerID)
+}
+
+// takeCold reports whether fn's worker was started and not called since,
+// and clears the mark.
+func (m *Manager) takeCold(fn *Function) bool {
+	containerID, ok := m.coldWorkers.LoadAndDelete(fn.ID)
+	return ok && containerID == fn.ContainerID
+}
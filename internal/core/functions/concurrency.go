@@ -0,0 +1,150 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrConcurrencyLimit is returned when a function is already running its
+// maximum number of executions and no slot freed up in time.
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// semaphore counts holders against a limit that can change while they hold
+// it, so a lowered limit admits no one until enough holders left. A limit
+// that is not positive admits everyone, still counting them.
+type semaphore struct {
+	mu    sync.Mutex
+	limit int
+	held  int
+	freed chan struct{} // closed and replaced when a slot may have freed up
+}
+
+func newSemaphore(limit int) *semaphore {
+	return &semaphore{limit: limit, freed: make(chan struct{})}
+}
+
+// resize changes the limit; holders keep their slots.
+func (s *semaphore) resize(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit == s.limit {
+		return
+	}
+	s.limit = limit
+	s.signal()
+}
+
+// tryAcquire takes a slot if one is free. Otherwise it returns a channel
+// that is closed when one may have freed up.
+func (s *semaphore) tryAcquire() (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limit > 0 && s.held >= s.limit {
+		return false, s.freed
+	}
+	s.held++
+	return true, nil
+}
+
+// acquire takes a slot, waiting up to wait for one to free up. It reports
+// false when none did.
+func (s *semaphore) acquire(ctx context.Context, wait time.Duration) (bool, error) {
+	ok, freed := s.tryAcquire()
+	if ok || wait <= 0 {
+		return ok, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-freed:
+		case <-timer.C:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		if ok, freed = s.tryAcquire(); ok {
+			return true, nil
+		}
+	}
+}
+
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held--
+	s.signal()
+}
+
+func (s *semaphore) signal() {
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
+
+// concurrencyLimiter counts the executions in flight per function against
+// the function's limit. The count survives changes to the limit. Each
+// manager replica counts its own executions only.
+type concurrencyLimiter struct {
+	mu    sync.Mutex
+	slots map[string]*semaphore
+}
+
+// acquire takes an execution slot of the function, waiting up to wait for
+// one to free up. The returned func releases the slot.
+func (l *concurrencyLimiter) acquire(ctx context.Context, functionID string, limit int, wait time.Duration) (func(), error) {
+	l.mu.Lock()
+	if l.slots == nil {
+		l.slots = make(map[string]*semaphore)
+	}
+	sem, ok := l.slots[functionID]
+	if !ok {
+		sem = newSemaphore(limit)
+		l.slots[functionID] = sem
+	}
+	l.mu.Unlock()
+	sem.resize(limit)
+
+	ok, err := sem.acquire(ctx, wait)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: function '%s' allows %d concurrent executions", ErrConcurrencyLimit, functionID, limit)
+	}
+	return sem.release, nil
+}
+
+// forget drops the semaphore of a purged function.
+func (l *concurrencyLimiter) forget(functionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.slots, functionID)
+}
+
+func validateMaxConcurrency(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: max_concurrency must not be negative", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// SetMaxConcurrency changes how many executions of a function may run at
+// once; zero removes the limit. Executions in flight count against the new
+// limit.
+func (m *Manager) SetMaxConcurrency(ctx context.Context, functionID string, n int) (*Function, error) {
+	if err := validateMaxConcurrency(n); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.MaxConcurrency = n
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update max concurrency: %w", err)
+	}
+	return fn, nil
+}
@@ -0,0 +1,113 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CORSPolicy lets browser apps on other origins call a function through its
+// public routes, /f/{name} and its custom domain.
+type CORSPolicy struct {
+	// AllowedOrigins are origins such as "https://app.example.com", or "*"
+	// for any.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedMethods default to POST, the method public routes serve.
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	// AllowedHeaders are the request headers callers may send, or "*" for
+	// any; they default to Content-Type.
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	// AllowCredentials lets callers send cookies and HTTP authentication.
+	AllowCredentials bool `json:"allow_credentials,omitempty"`
+	// MaxAgeSeconds is how long browsers may cache a preflight answer; zero
+	// leaves it to the browser.
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+}
+
+// maxCORSMaxAge caps MaxAgeSeconds at a day; browsers cap it lower anyway.
+const maxCORSMaxAge = 86400
+
+var corsTokenPattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+func validateCORS(p *CORSPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if len(p.AllowedOrigins) == 0 {
+		return fmt.Errorf("%w: cors needs at least one allowed origin", ErrInvalidArgument)
+	}
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				return fmt.Errorf("%w: cors cannot allow credentials for any origin", ErrInvalidArgument)
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return fmt.Errorf("%w: cors origin %q must be a scheme and host such as 'https://app.example.com', or '*'", ErrInvalidArgument, origin)
+		}
+	}
+	for _, method := range p.AllowedMethods {
+		if !corsTokenPattern.MatchString(method) || method == "*" {
+			return fmt.Errorf("%w: invalid cors method %q", ErrInvalidArgument, method)
+		}
+	}
+	for _, header := range p.AllowedHeaders {
+		if !corsTokenPattern.MatchString(header) {
+			return fmt.Errorf("%w: invalid cors header %q", ErrInvalidArgument, header)
+		}
+	}
+	if p.MaxAgeSeconds < 0 || p.MaxAgeSeconds > maxCORSMaxAge {
+		return fmt.Errorf("%w: cors max_age_seconds must be between 0 and %d", ErrInvalidArgument, maxCORSMaxAge)
+	}
+	return nil
+}
+
+// AllowsOrigin reports whether the policy lets a page on origin call the
+// function.
+func (p *CORSPolicy) AllowsOrigin(origin string) bool {
+	if p == nil || origin == "" {
+		return false
+	}
+	return slices.ContainsFunc(p.AllowedOrigins, func(allowed string) bool {
+		return allowed == "*" || strings.EqualFold(allowed, origin)
+	})
+}
+
+// Methods returns the allowed methods, POST when none are set.
+func (p *CORSPolicy) Methods() []string {
+	if len(p.AllowedMethods) == 0 {
+		return []string{"POST"}
+	}
+	return p.AllowedMethods
+}
+
+// Headers returns the allowed request headers, Content-Type when none are
+// set.
+func (p *CORSPolicy) Headers() []string {
+	if len(p.AllowedHeaders) == 0 {
+		return []string{"Content-Type"}
+	}
+	return p.AllowedHeaders
+}
+
+// SetCORS replaces a function's CORS policy; nil removes it, so browsers on
+// other origins can no longer call the function.
+func (m *Manager) SetCORS(ctx context.Context, functionID string, p *CORSPolicy) (*Function, error) {
+	if err := validateCORS(p); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.CORS = p
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update cors: %w", err)
+	}
+	return fn, nil
+}
@@ -0,0 +1,297 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"service-faas/pkg/rand"
+)
+
+// Debug capture limits.
+const (
+	defaultDebugMaxBytes = 16 << 10
+	maxDebugMaxBytes     = 1 << 20
+	defaultDebugTTL      = 24 * time.Hour
+	maxDebugTTL          = 7 * 24 * time.Hour
+	maxDebugRedactRules  = 100
+	defaultCaptureLimit  = 50
+	maxCaptureLimit      = 500
+)
+
+// redactedValue replaces the values of redacted fields.
+const redactedValue = "[REDACTED]"
+
+// DebugCapture puts a function in debug mode: a sample of its executions is
+// captured with their payload and response, see ListCaptures.
+type DebugCapture struct {
+	// SampleRate is the share of executions captured, above 0 and at most 1.
+	SampleRate float64 `json:"sample_rate"`
+	// MaxBytes caps the payload and the response kept of each execution;
+	// zero keeps 16 KiB.
+	MaxBytes int `json:"max_bytes,omitempty"`
+	// TTLSeconds is how long captures are kept; zero keeps them a day.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// Redact lists the JSON fields whose values are replaced before a
+	// capture is stored: a key such as "password" matches at any depth,
+	// ignoring case, and a JSON Pointer such as "/user/ssn" matches one
+	// value.
+	Redact []string `json:"redact,omitempty"`
+}
+
+func validateDebug(d *DebugCapture) error {
+	if d == nil {
+		return nil
+	}
+	if !(d.SampleRate > 0 && d.SampleRate <= 1) {
+		return fmt.Errorf("%w: debug sample_rate must be above 0 and at most 1", ErrInvalidArgument)
+	}
+	if d.MaxBytes < 0 || d.MaxBytes > maxDebugMaxBytes {
+		return fmt.Errorf("%w: debug max_bytes must be between 0 and %d", ErrInvalidArgument, maxDebugMaxBytes)
+	}
+	if d.TTLSeconds < 0 || d.TTLSeconds > int(maxDebugTTL/time.Second) {
+		return fmt.Errorf("%w: debug ttl_seconds must be between 0 and %d", ErrInvalidArgument, int(maxDebugTTL/time.Second))
+	}
+	if len(d.Redact) > maxDebugRedactRules {
+		return fmt.Errorf("%w: debug takes at most %d redact rules", ErrInvalidArgument, maxDebugRedactRules)
+	}
+	for _, rule := range d.Redact {
+		if rule == "" || rule == "/" {
+			return fmt.Errorf("%w: debug redact rules must be a key or a JSON Pointer to a field", ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+func (d *DebugCapture) maxBytes() int {
+	if d.MaxBytes == 0 {
+		return defaultDebugMaxBytes
+	}
+	return d.MaxBytes
+}
+
+func (d *DebugCapture) ttl() time.Duration {
+	if d.TTLSeconds == 0 {
+		return defaultDebugTTL
+	}
+	return time.Duration(d.TTLSeconds) * time.Second
+}
+
+// Capture is an execution captured in debug mode.
+type Capture struct {
+	ID           string `gorm:"primaryKey;size:64" json:"capture_id"`
+	FunctionID   string `gorm:"size:64;index" json:"function_id"`
+	InvocationID string `gorm:"size:64" json:"invocation_id"`
+	Status       string `json:"status"`
+	// Payload and Response are kept as JSON, redacted. Text that is not
+	// JSON, and JSON larger than the capture's MaxBytes, is kept as a
+	// string, cut to MaxBytes with PayloadTruncated or ResponseTruncated
+	// set. With redact rules, text that is not JSON is redacted whole. Both
+	// are encrypted at rest.
+	Payload           json.RawMessage `gorm:"type:text;serializer:encrypted" json:"payload,omitempty" swaggertype:"object"`
+	PayloadTruncated  bool            `json:"payload_truncated,omitempty"`
+	Response          json.RawMessage `gorm:"type:text;serializer:encrypted" json:"response,omitempty" swaggertype:"object"`
+	ResponseTruncated bool            `json:"response_truncated,omitempty"`
+	ResultRef         *ResultRef      `gorm:"serializer:json" json:"result_ref,omitempty"`
+	Error             string          `gorm:"type:text" json:"error,omitempty"`
+	Exception         *HandlerError   `gorm:"serializer:json" json:"exception,omitempty"`
+	ErrorClass        string          `json:"error_class,omitempty"`
+	StartedAt         time.Time       `gorm:"index" json:"started_at"`
+	DurationMS        int64           `json:"duration_ms"`
+	ExpiresAt         time.Time       `gorm:"index" json:"expires_at"`
+}
+
+// CaptureRepository persists debug captures.
+type CaptureRepository interface {
+	Create(ctx context.Context, c *Capture) error
+	// List returns a function's captures that have not expired at now,
+	// newest first.
+	List(ctx context.Context, functionID string, now time.Time, limit int) ([]Capture, error)
+	DeleteByFunction(ctx context.Context, functionID string) error
+	// DeleteExpired deletes captures that expired before now.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// WithCaptureRepository enables debug mode, see SetDebug.
+func WithCaptureRepository(repo CaptureRepository) Option {
+	return func(m *Manager) { m.captures = repo }
+}
+
+// SetDebug puts a function in debug mode, or takes it out with nil. Captures
+// already taken are kept until they expire or are cleared.
+func (m *Manager) SetDebug(ctx context.Context, functionID string, d *DebugCapture) (*Function, error) {
+	if d != nil && m.captures == nil {
+		return nil, fmt.Errorf("%w: debug capture", ErrNotConfigured)
+	}
+	if err := validateDebug(d); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.Debug = d
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update debug: %w", err)
+	}
+	m.log(ctx).Info().Str("function_id", fn.ID).Bool("debug", d != nil).Msg("function debug mode changed")
+	return fn, nil
+}
+
+// ListCaptures returns a function's latest debug captures, at most limit;
+// zero means 50.
+func (m *Manager) ListCaptures(ctx context.Context, functionID string, limit int) ([]Capture, error) {
+	if m.captures == nil {
+		return nil, fmt.Errorf("%w: debug capture", ErrNotConfigured)
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidArgument)
+	}
+	if limit == 0 {
+		limit = defaultCaptureLimit
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	return m.captures.List(ctx, fn.ID, time.Now().UTC(), min(limit, maxCaptureLimit))
+}
+
+// ClearCaptures deletes a function's debug captures.
+func (m *Manager) ClearCaptures(ctx context.Context, functionID string) error {
+	if m.captures == nil {
+		return fmt.Errorf("%w: debug capture", ErrNotConfigured)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return err
+	}
+	if err := m.captures.DeleteByFunction(ctx, fn.ID); err != nil {
+		return fmt.Errorf("delete captures: %w", err)
+	}
+	return nil
+}
+
+// deleteCaptures removes the debug captures of a purged function.
+func (m *Manager) deleteCaptures(ctx context.Context, functionID string) {
+	if m.captures == nil {
+		return
+	}
+	if err := m.captures.DeleteByFunction(ctx, functionID); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", functionID).Msg("failed to delete captures of purged function")
+	}
+}
+
+// captureExecution stores a sample of the executions of a function in debug
+// mode. Failures to store are logged.
+func (m *Manager) captureExecution(ctx context.Context, fn *Function, inv *Invocation, payload string, result *ExecutionResult, err error) {
+	d := fn.Debug
+	if d == nil || m.captures == nil || mrand.Float64() >= d.SampleRate {
+		return
+	}
+	c := &Capture{
+		ID:           rand.ID16(),
+		FunctionID:   fn.ID,
+		InvocationID: inv.ID,
+		StartedAt:    inv.StartedAt,
+		DurationMS:   time.Since(inv.StartedAt).Milliseconds(),
+		ExpiresAt:    time.Now().UTC().Add(d.ttl()),
+	}
+	c.Payload, c.PayloadTruncated = d.captureValue([]byte(payload))
+	if err != nil {
+		c.Status, c.Error, c.ErrorClass = InvocationFailed, err.Error(), ClassifyError(err)
+		var he *HandlerError
+		if errors.As(err, &he) {
+			c.Exception = he
+		}
+	} else {
+		c.Status, c.ResultRef = InvocationSucceeded, result.ResultRef
+		c.Response, c.ResponseTruncated = d.captureValue(result.Result)
+	}
+	if err := m.captures.Create(context.WithoutCancel(ctx), c); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to store debug capture")
+	}
+}
+
+// captureValue redacts a payload or response and caps it at MaxBytes,
+// reporting whether it was cut.
+func (d *DebugCapture) captureValue(raw []byte) (json.RawMessage, bool) {
+	if len(raw) == 0 {
+		return nil, false
+	}
+	v, err := decodeResult(raw)
+	if err != nil {
+		if len(d.Redact) > 0 {
+			// Fields cannot be found in text that is not JSON.
+			return json.RawMessage(strconv.Quote(redactedValue)), false
+		}
+		return cutText(raw, d.maxBytes())
+	}
+	if len(d.Redact) > 0 {
+		if raw, err = json.Marshal(d.redact("", v)); err != nil {
+			return json.RawMessage(strconv.Quote(redactedValue)), false
+		}
+	}
+	if len(raw) > d.maxBytes() {
+		return cutText(raw, d.maxBytes())
+	}
+	return raw, false
+}
+
+// cutText returns text as a JSON string, cut to at most max bytes.
+func cutText(text []byte, max int) (json.RawMessage, bool) {
+	cut := len(text) > max
+	if cut {
+		for max > 0 && !utf8.RuneStart(text[max]) {
+			max--
+		}
+		text = text[:max]
+	}
+	s, _ := json.Marshal(strings.ToValidUTF8(string(text), "�"))
+	return s, cut
+}
+
+// redact replaces the values of the fields the rules match in v, found at
+// the JSON Pointer path.
+func (d *DebugCapture) redact(path string, v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			p := path + "/" + escapePointer(k)
+			if d.redacts(k, p) {
+				t[k] = redactedValue
+			} else {
+				t[k] = d.redact(p, child)
+			}
+		}
+	case []any:
+		for i, child := range t {
+			p := path + "/" + strconv.Itoa(i)
+			if d.redacts("", p) {
+				t[i] = redactedValue
+			} else {
+				t[i] = d.redact(p, child)
+			}
+		}
+	}
+	return v
+}
+
+func (d *DebugCapture) redacts(key, path string) bool {
+	for _, rule := range d.Redact {
+		if strings.HasPrefix(rule, "/") {
+			if rule == path {
+				return true
+			}
+		} else if key != "" && strings.EqualFold(rule, key) {
+			return true
+		}
+	}
+	return false
+}
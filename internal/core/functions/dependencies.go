@@ -0,0 +1,132 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Bundle files naming a function's Python dependencies.
+const (
+	requirementsFile = "requirements.txt"
+	lockFile         = "requirements.lock"
+)
+
+// DependencyResolver is implemented by orchestrators that can resolve a
+// requirements.txt in the worker image.
+type DependencyResolver interface {
+	// ResolveDependencies returns pip's installation report (pip install
+	// --dry-run --report) for the requirements.txt in the code directory.
+	// Requirements that cannot be resolved are reported as ErrInvalidArgument.
+	ResolveDependencies(ctx context.Context, spec DependencySpec) ([]byte, error)
+}
+
+// DependencySpec describes the requirements to resolve.
+type DependencySpec struct {
+	Image        string
+	RegistryAuth *RegistryAuth
+	// CodePath is the directory holding requirements.txt.
+	CodePath string
+}
+
+// pipReport is the part of pip's installation report a lock is built from.
+type pipReport struct {
+	Install []struct {
+		Metadata struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"metadata"`
+		DownloadInfo struct {
+			URL         string `json:"url"`
+			ArchiveInfo *struct {
+				Hashes map[string]string `json:"hashes"`
+			} `json:"archive_info"`
+		} `json:"download_info"`
+	} `json:"install"`
+}
+
+// lockFromReport renders a pip report as a requirements file pinning every
+// package, including transitive ones, to the resolved version. Hashes are
+// included when pip reported one for every package, so installs can use
+// --require-hashes.
+func lockFromReport(raw []byte) ([]byte, error) {
+	var report pipReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, fmt.Errorf("decode pip report: %w", err)
+	}
+	hashed := true
+	for _, pkg := range report.Install {
+		if pkg.DownloadInfo.ArchiveInfo == nil || pkg.DownloadInfo.ArchiveInfo.Hashes["sha256"] == "" {
+			hashed = false
+		}
+	}
+
+	lines := make([]string, 0, len(report.Install))
+	for _, pkg := range report.Install {
+		name := strings.ToLower(pkg.Metadata.Name)
+		line := name + "==" + pkg.Metadata.Version
+		if pkg.DownloadInfo.ArchiveInfo == nil && pkg.DownloadInfo.URL != "" {
+			// Direct references (VCS, local paths) cannot be pinned by version.
+			line = name + " @ " + pkg.DownloadInfo.URL
+		} else if hashed {
+			line += " \\\n    --hash=sha256:" + pkg.DownloadInfo.ArchiveInfo.Hashes["sha256"]
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated from requirements.txt by service-faas; do not edit.\n")
+	for _, line := range lines {
+		buf.WriteString(line + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// lockDependencies makes sure a bundle with a requirements.txt has a
+// requirements.lock next to it and returns the lock's checksum. A lock
+// shipped in the bundle is used as is; otherwise one is resolved in the
+// worker image. Without a resolver, requirements are left to the worker.
+func (m *Manager) lockDependencies(ctx context.Context, functionID string, stored *storedCode, opts FunctionOptions) (string, error) {
+	store, ok := m.code.(BundleStore)
+	if stored.LockSHA256 != "" || !ok || !stored.Requirements {
+		return stored.LockSHA256, nil
+	}
+	resolver, ok := m.orchestrator.(DependencyResolver)
+	if !ok {
+		return "", nil
+	}
+	src, err := m.code.Source(ctx, functionID)
+	if err != nil {
+		return "", fmt.Errorf("locate code: %w", err)
+	}
+	spec := DependencySpec{CodePath: src.Dir}
+	if spec.Image, err = m.baseImage(opts.Runtime, opts.WorkerImage); err != nil {
+		return "", err
+	}
+	if opts.WorkerImage != "" {
+		if spec.RegistryAuth, err = m.registryAuth(ctx, opts.Tenant, opts.WorkerImage); err != nil {
+			return "", err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.DependencyLockTimeout)
+	defer cancel()
+	report, err := resolver.ResolveDependencies(ctx, spec)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", requirementsFile, err)
+	}
+	lock, err := lockFromReport(report)
+	if err != nil {
+		return "", err
+	}
+	if err := store.PutFile(ctx, functionID, lockFile, bytes.NewReader(lock)); err != nil {
+		return "", fmt.Errorf("store %s: %w", lockFile, err)
+	}
+	sum := sha256.Sum256(lock)
+	return hex.EncodeToString(sum[:]), nil
+}
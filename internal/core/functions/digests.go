@@ -0,0 +1,115 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+)
+
+// DigestResolver looks up the digest an image tag currently points to.
+type DigestResolver interface {
+	// ResolveDigest returns the manifest digest of image, e.g.
+	// "sha256:...". auth, when set, holds credentials for its registry.
+	// Images the registry does not know are reported as ErrInvalidArgument.
+	ResolveDigest(ctx context.Context, image string, auth *RegistryAuth) (string, error)
+}
+
+// WithDigestResolver pins every function's worker image to the digest its
+// tag points to when the function is created, so all of its workers run the
+// same image until it is explicitly upgraded.
+func WithDigestResolver(resolver DigestResolver) Option {
+	return func(m *Manager) { m.digests = resolver }
+}
+
+// workerImage returns the image a function's workers run (see baseImage),
+// pinned to the function's digest when it has one.
+func (m *Manager) workerImage(fn *Function) (string, error) {
+	image, err := m.baseImage(fn.Runtime, fn.WorkerImage)
+	if err != nil || fn.ImageDigest == "" {
+		return image, err
+	}
+	return pinImage(image, fn.ImageDigest)
+}
+
+// resolveImageDigest looks up the current digest of the worker image of a
+// function with the given options. Without a resolver it returns "".
+func (m *Manager) resolveImageDigest(ctx context.Context, opts FunctionOptions) (string, error) {
+	if m.digests == nil {
+		return "", nil
+	}
+	image, err := m.baseImage(opts.Runtime, opts.WorkerImage)
+	if err != nil {
+		return "", err
+	}
+	var auth *RegistryAuth
+	if opts.WorkerImage != "" {
+		if auth, err = m.registryAuth(ctx, opts.Tenant, image); err != nil {
+			return "", err
+		}
+	}
+	dgst, err := m.digests.ResolveDigest(ctx, image, auth)
+	if err != nil {
+		return "", fmt.Errorf("resolve digest of %s: %w", image, err)
+	}
+	if _, err := pinImage(image, dgst); err != nil {
+		return "", fmt.Errorf("resolve digest of %s: %w", image, err)
+	}
+	return dgst, nil
+}
+
+// UpgradeFunctionImage re-resolves the tag of a function's worker image and,
+// when it now points to a different image, redeploys the function on it.
+// With image builds, the function's image is rebuilt on the new base instead.
+func (m *Manager) UpgradeFunctionImage(ctx context.Context, functionID string) (*Function, error) {
+	if m.digests == nil {
+		return nil, fmt.Errorf("%w: image digests are not pinned", ErrNotConfigured)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt != nil || fn.BlockedReason != "" {
+		return nil, fmt.Errorf("%w: function '%s' is %s", ErrInvalidArgument, functionID, fn.Status)
+	}
+
+	dgst, err := m.resolveImageDigest(ctx, FunctionOptions{Tenant: fn.Tenant, WorkerImage: fn.WorkerImage, Runtime: fn.Runtime})
+	if err != nil {
+		return nil, err
+	}
+	if dgst == fn.ImageDigest {
+		return fn, nil
+	}
+	previous := fn.ImageDigest
+	fn.ImageDigest = dgst
+	if _, ok := m.imageBuilder(); ok {
+		// The build records the new digest along with its status.
+		if !m.startBuild(*fn) {
+			return nil, fmt.Errorf("%w: function '%s' is already being built", ErrInvalidArgument, functionID)
+		}
+		fn.BuildStatus = BuildRunning
+	} else if err := m.deploy(ctx, fn); err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info().Str("function_id", functionID).Str("from", previous).Str("to", dgst).Msg("function worker image upgraded")
+	return fn, nil
+}
+
+// pinImage returns image with its tag replaced by dgst.
+func pinImage(image, dgst string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	d, err := digest.Parse(dgst)
+	if err != nil {
+		return "", fmt.Errorf("invalid image digest %q: %w", dgst, err)
+	}
+	pinned, err := reference.WithDigest(reference.TrimNamed(named), d)
+	if err != nil {
+		return "", err
+	}
+	return reference.FamiliarString(pinned), nil
+}
@@ -0,0 +1,185 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// domainCacheTTL is how long the set of bound domains is reused before it
+// is read again, so a domain bound on another replica is served within it.
+const domainCacheTTL = 30 * time.Second
+
+// DomainRouter is implemented by orchestrators that can route a function's
+// custom domain to the manager from outside the cluster, e.g. with an
+// Ingress.
+type DomainRouter interface {
+	// RouteDomain routes domain to the manager for a function, replacing the
+	// route it had. An empty domain removes the route.
+	RouteDomain(ctx context.Context, functionID, domain string) error
+}
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// normalizeDomain lowercases a host name and drops a trailing dot, as host
+// names are compared without them.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+func validateDomain(domain string) error {
+	if len(domain) > 253 || !domainPattern.MatchString(domain) {
+		return fmt.Errorf("%w: domain %q must be a fully qualified host name such as 'echo.example.com'", ErrInvalidArgument, domain)
+	}
+	return nil
+}
+
+// checkDomainFree checks that no function but functionID has a domain, and
+// that it is not a host name the API itself is served on.
+func (m *Manager) checkDomainFree(ctx context.Context, domain, functionID string) error {
+	if slices.Contains(m.apiHosts(), domain) {
+		return fmt.Errorf("%w: domain '%s' is a host name of the API", ErrInvalidArgument, domain)
+	}
+	fn, err := m.repo.FindByDomain(ctx, domain)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("look up domain: %w", err)
+	}
+	if fn.ID == functionID {
+		return nil
+	}
+	return fmt.Errorf("%w: function '%s' has domain '%s'", ErrDomainTaken, fn.ID, domain)
+}
+
+// SetDomain binds a custom domain to a function: requests whose Host is the
+// domain are served with it. An empty domain unbinds it. Where the
+// orchestrator routes domains itself, the route is changed first, so a
+// domain that cannot be routed is not bound.
+func (m *Manager) SetDomain(ctx context.Context, functionID, domain string) (*Function, error) {
+	domain = normalizeDomain(domain)
+	if domain != "" {
+		if err := validateDomain(domain); err != nil {
+			return nil, err
+		}
+	}
+
+	m.namesMu.Lock()
+	defer m.namesMu.Unlock()
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: function '%s' is deleted", ErrInvalidArgument, functionID)
+	}
+	if fn.Domain == domain {
+		return fn, nil
+	}
+	if domain != "" {
+		if err := m.checkDomainFree(ctx, domain, fn.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.routeDomain(ctx, fn.ID, domain); err != nil {
+		return nil, err
+	}
+	fn.Domain = domain
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update domain: %w", err)
+	}
+	m.domains.forget()
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("domain", domain).Msg("function domain changed")
+	return fn, nil
+}
+
+// DomainFunction finds the function a custom domain is bound to. Deleted
+// functions are not found. Host names that no function has, such as the
+// API's, are answered from the cached set of bound domains.
+func (m *Manager) DomainFunction(ctx context.Context, domain string) (*Function, error) {
+	domain = normalizeDomain(domain)
+	bound, err := m.domains.has(ctx, m.repo, domain)
+	if err != nil {
+		return nil, err
+	}
+	if !bound {
+		return nil, fmt.Errorf("%w: with domain '%s'", ErrFunctionNotFound, domain)
+	}
+	fn, err := m.repo.FindByDomain(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: function with domain '%s' is deleted", ErrFunctionNotFound, domain)
+	}
+	return fn, nil
+}
+
+// apiHosts returns the host names the API is served on, which functions
+// cannot bind: the TLS_AUTOCERT_DOMAINS and the host of RESULT_BASE_URL.
+func (m *Manager) apiHosts() []string {
+	hosts := make([]string, 0, len(m.cfg.TLSAutocertDomains)+1)
+	for _, host := range m.cfg.TLSAutocertDomains {
+		hosts = append(hosts, normalizeDomain(host))
+	}
+	if u, err := url.Parse(m.cfg.ResultBaseURL); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, normalizeDomain(u.Hostname()))
+	}
+	return hosts
+}
+
+// domainCache is the set of domains bound to functions, read from the
+// repository at most every domainCacheTTL.
+type domainCache struct {
+	mu      sync.Mutex
+	domains map[string]bool
+	loaded  time.Time
+}
+
+// has reports whether a function has domain.
+func (c *domainCache) has(ctx context.Context, repo FunctionRepository, domain string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.domains == nil || time.Since(c.loaded) >= domainCacheTTL {
+		all, err := repo.List(ctx)
+		if err != nil {
+			return false, fmt.Errorf("list domains: %w", err)
+		}
+		c.domains = make(map[string]bool)
+		for _, fn := range all {
+			if fn.Domain != "" {
+				c.domains[fn.Domain] = true
+			}
+		}
+		c.loaded = time.Now()
+	}
+	return c.domains[domain], nil
+}
+
+// forget drops the cached set after a domain was bound here, so it is
+// served at once.
+func (c *domainCache) forget() {
+	c.mu.Lock()
+	c.domains = nil
+	c.mu.Unlock()
+}
+
+// routeDomain has the orchestrator route a function's domain, when it routes
+// domains.
+func (m *Manager) routeDomain(ctx context.Context, functionID, domain string) error {
+	router, ok := m.orchestrator.(DomainRouter)
+	if !ok {
+		return nil
+	}
+	if err := router.RouteDomain(ctx, functionID, domain); err != nil {
+		return fmt.Errorf("route domain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShuttingDown is returned for executions that arrive while the manager
+// drains before shutting down.
+var ErrShuttingDown = errors.New("shutting down")
+
+// inflight counts running executions and, once draining, refuses new ones.
+type inflight struct {
+	mu         sync.Mutex
+	n          int
+	byFunction map[string]int
+	draining   bool
+	idle       chan struct{} // closed when n drops to 0 while draining
+}
+
+// start counts an execution of a function, unless the manager is draining.
+func (t *inflight) start(functionID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return ErrShuttingDown
+	}
+	if t.byFunction == nil {
+		t.byFunction = make(map[string]int)
+	}
+	t.n++
+	t.byFunction[functionID]++
+	return nil
+}
+
+func (t *inflight) finish(functionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.n--
+	if t.byFunction[functionID]--; t.byFunction[functionID] <= 0 {
+		delete(t.byFunction, functionID)
+	}
+	if t.draining && t.n == 0 {
+		close(t.idle)
+	}
+}
+
+// InFlightExecutions returns the number of executions running.
+func (m *Manager) InFlightExecutions() int {
+	m.inflight.mu.Lock()
+	defer m.inflight.mu.Unlock()
+	return m.inflight.n
+}
+
+// Drain refuses new executions with ErrShuttingDown and waits until the
+// executions in flight complete or ctx is done. It is called once, before
+// the workers are stopped on shutdown.
+func (m *Manager) Drain(ctx context.Context) error {
+	t := &m.inflight
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		t.idle = make(chan struct{})
+		if t.n == 0 {
+			close(t.idle)
+		}
+	}
+	n := t.n
+	t.mu.Unlock()
+
+	if n > 0 {
+		m.lg.Info().Int("executions", n).Msg("draining executions in flight")
+	}
+	select {
+	case <-t.idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d executions still in flight: %w", m.InFlightExecutions(), ctx.Err())
+	}
+}
@@ -0,0 +1,363 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"service-faas/pkg/rand"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// envVarName matches the names of env vars functions may set.
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnvVars are set by the orchestrators for the worker itself.
+var reservedEnvVars = []string{"HANDLER_FUNCTION", "HANDLER_CODE", "PORT", "REQUIREMENTS_LOCK"}
+
+func validateEnvVars(vars map[string]string) error {
+	for name := range vars {
+		if !envVarName.MatchString(name) {
+			return fmt.Errorf("%w: env var name %q must be letters, digits and '_', not starting with a digit", ErrInvalidArgument, name)
+		}
+		if slices.Contains(reservedEnvVars, name) || strings.HasPrefix(name, "FAAS_") || strings.HasPrefix(name, "WORKER_TLS_") {
+			return fmt.Errorf("%w: env var %q is reserved for the worker", ErrInvalidArgument, name)
+		}
+	}
+	return nil
+}
+
+// EnvironmentDeployment is what DeployEnvironment deploys to an environment.
+type EnvironmentDeployment struct {
+	// FunctionName is the handler's function; it defaults to the parent's
+	// for a new environment and is kept otherwise.
+	FunctionName string
+	// Code is new code, described by BundleFormat and ExpectedSHA256 as for
+	// an upload; Git deploys it from a repository instead. With neither, a
+	// new environment starts from the parent's code and an existing one
+	// keeps its code.
+	Code           io.Reader
+	BundleFormat   string
+	ExpectedSHA256 string
+	Git            *GitSource
+	GitAuth        GitAuth
+	// EnvVars, when not nil, replace the environment's env vars. A new
+	// environment starts with the parent's.
+	EnvVars map[string]string
+}
+
+// DeployEnvironment deploys an environment of a function, such as "staging":
+// a function of its own, with its own code, env vars, worker and endpoint,
+// that otherwise takes the parent's settings when it is created. A missing
+// environment is created and deployed; created reports that. An existing one
+// gets the new code, which passes the checks an upload does, or the new env
+// vars, and a running worker is restarted on them. Rejected code leaves the
+// environment as it was.
+func (m *Manager) DeployEnvironment(ctx context.Context, functionID, env string, d EnvironmentDeployment) (fn *Function, created bool, err error) {
+	if err := validateName(env); err != nil {
+		return nil, false, fmt.Errorf("environment: %w", err)
+	}
+	if err := validateEnvVars(d.EnvVars); err != nil {
+		return nil, false, err
+	}
+	if d.Git != nil {
+		if err := validateGitSource(d.Git, d.GitAuth); err != nil {
+			return nil, false, err
+		}
+	}
+	parent, err := m.environmentParent(ctx, functionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.envsMu.Lock()
+	defer m.envsMu.Unlock()
+	existing, err := m.findEnvironment(ctx, functionID, env)
+	if errors.Is(err, ErrNotFound) {
+		fn, err := m.createEnvironment(ctx, parent, parent, env, d, nil)
+		return fn, fn != nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	fn, err = m.updateEnvironment(ctx, existing, d)
+	return fn, false, err
+}
+
+// environmentParent returns the function whose environments are deployed,
+// which must not be an environment itself, deleted or blocked.
+func (m *Manager) environmentParent(ctx context.Context, functionID string) (*Function, error) {
+	parent, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case parent.ParentID != "":
+		return nil, fmt.Errorf("%w: function '%s' is itself an environment of '%s'", ErrInvalidArgument, functionID, parent.ParentID)
+	case parent.DeletedAt != nil:
+		return nil, fmt.Errorf("%w: function '%s' is deleted", ErrInvalidArgument, functionID)
+	case parent.BlockedReason != "":
+		return nil, fmt.Errorf("%w: function '%s': %s", ErrCodeBlocked, functionID, parent.BlockedReason)
+	}
+	return parent, nil
+}
+
+// createEnvironment creates and deploys an environment of parent with the
+// settings of base: the parent itself or, for a promotion, the environment
+// promoted. The env vars are the parent's unless d sets them.
+func (m *Manager) createEnvironment(ctx context.Context, parent, base *Function, env string, d EnvironmentDeployment, promotion *Promotion) (*Function, error) {
+	// Exposure is left out: the environment's route would clash with the
+	// parent's.
+	opts := FunctionOptions{
+		Tenant:          parent.Tenant,
+		Owner:           parent.Owner,
+		WorkerImage:     base.WorkerImage,
+		Runtime:         base.Runtime,
+		PreHook:         base.PreHook,
+		PostHook:        base.PostHook,
+		Fallback:        base.Fallback,
+		Labels:          base.Labels,
+		Description:     base.Description,
+		EnvVars:         parent.EnvVars,
+		MaxConcurrency:  base.MaxConcurrency,
+		MaxPayloadBytes: base.MaxPayloadBytes,
+		PayloadSchema:   base.PayloadSchema,
+		CacheTTLSeconds: base.CacheTTLSeconds,
+		TimeoutSeconds:  base.TimeoutSeconds,
+		Replicas:        base.Replicas,
+		Affinity:        base.Affinity,
+		Kubernetes:      base.Kubernetes,
+		Warmup:          base.Warmup,
+
+		BundleFormat:   d.BundleFormat,
+		ExpectedSHA256: d.ExpectedSHA256,
+		Git:            d.Git,
+		GitAuth:        d.GitAuth,
+
+		ParentID:    parent.ID,
+		Environment: env,
+		Promotion:   promotion,
+	}
+	if promotion != nil {
+		opts.ImageDigest = base.ImageDigest
+	}
+	if d.EnvVars != nil {
+		opts.EnvVars = d.EnvVars
+	}
+	functionName := d.FunctionName
+	if functionName == "" {
+		functionName = base.FunctionName
+	}
+
+	code := d.Code
+	if code == nil && d.Git == nil {
+		// Start from base's code, and its Git source so the environment
+		// can be synced.
+		backup, err := m.backupCode(ctx, base.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(backup)
+		rc, format, err := openCodeDir(backup)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		code, opts.BundleFormat = rc, format
+		if base.Git != nil {
+			src := *base.Git
+			opts.Git, opts.GitAuth = &src, base.gitAuth()
+		}
+	}
+
+	fn, err := m.createFunction(ctx, rand.ID16(), functionName, code, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("parent_id", parent.ID).Str("environment", env).Msg("environment created")
+	return fn, m.launch(ctx, fn)
+}
+
+func (m *Manager) updateEnvironment(ctx context.Context, fn *Function, d EnvironmentDeployment) (*Function, error) {
+	if err := m.beginEnvironmentUpdate(fn); err != nil {
+		return nil, err
+	}
+	defer m.syncs.Delete(fn.ID)
+
+	updated := *fn
+	if d.FunctionName != "" {
+		updated.FunctionName = d.FunctionName
+		updated.HandlerPath = fmt.Sprintf("function.handler.%s", d.FunctionName)
+	}
+	envChanged := d.EnvVars != nil && !maps.Equal(d.EnvVars, fn.EnvVars)
+	if envChanged {
+		updated.EnvVars = d.EnvVars
+	}
+	codeChanged := d.Code != nil || d.Git != nil || updated.FunctionName != fn.FunctionName
+	if !codeChanged && !envChanged {
+		return fn, nil
+	}
+	if codeChanged {
+		if err := m.redeployEnvironmentCode(ctx, fn, &updated, d); err != nil {
+			return nil, err
+		}
+		updated.Promotion = nil
+	}
+
+	if err := m.repo.Update(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("db update function: %w", err)
+	}
+	fn = &updated
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("parent_id", fn.ParentID).Str("environment", fn.Environment).Bool("code", codeChanged).Bool("env_vars", envChanged).Msg("environment updated")
+
+	if _, builds := m.imageBuilder(); builds && codeChanged {
+		if !m.startBuild(*fn) {
+			return nil, fmt.Errorf("%w: function '%s' is already being built", ErrInvalidArgument, fn.ID)
+		}
+		fn.BuildStatus = BuildRunning
+		return fn, nil
+	}
+	if fn.Status != "running" {
+		return fn, nil
+	}
+	if err := m.replaceWorker(ctx, fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// beginEnvironmentUpdate refuses updates to deleted or blocked environments
+// and claims fn against syncs; the caller releases it with m.syncs.Delete.
+func (m *Manager) beginEnvironmentUpdate(fn *Function) error {
+	if fn.DeletedAt != nil {
+		return fmt.Errorf("%w: environment '%s' is deleted; restore function '%s' or remove the environment first", ErrInvalidArgument, fn.Environment, fn.ID)
+	}
+	if fn.BlockedReason != "" {
+		return fmt.Errorf("%w: function '%s': %s", ErrCodeBlocked, fn.ID, fn.BlockedReason)
+	}
+	if _, running := m.syncs.LoadOrStore(fn.ID, struct{}{}); running {
+		return fmt.Errorf("%w: function '%s' is being synced", ErrInvalidArgument, fn.ID)
+	}
+	return nil
+}
+
+// redeployEnvironmentCode stores an environment's new code, recording it in
+// updated. When it is rejected, fn's code is restored.
+func (m *Manager) redeployEnvironmentCode(ctx context.Context, fn, updated *Function, d EnvironmentDeployment) error {
+	backup, err := m.backupCode(ctx, fn.ID)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(backup)
+
+	code, format, commit := d.Code, d.BundleFormat, ""
+	switch {
+	case d.Git != nil:
+		auth := d.GitAuth
+		if auth.Token == "" && auth.DeployKey == "" {
+			auth = fn.gitAuth()
+		}
+		co, err := m.checkoutGit(ctx, *d.Git, auth)
+		if err != nil {
+			return err
+		}
+		defer co.Close()
+		rc, f, err := co.code(m.bundlesSupported())
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		code, format, commit = rc, f, co.commit
+		src := *d.Git
+		src.Commit = commit
+		updated.Git, updated.GitToken, updated.GitDeployKey = &src, auth.Token, auth.DeployKey
+	case code == nil:
+		// Only the function name changed; check the code against it.
+		rc, f, err := openCodeDir(backup)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		code, format = rc, f
+	default:
+		updated.Git, updated.GitToken, updated.GitDeployKey = nil, "", ""
+	}
+
+	if err := m.replaceCode(ctx, updated, code, format, d.ExpectedSHA256); err != nil {
+		if restoreErr := m.restoreCode(ctx, fn.ID, backup); restoreErr != nil {
+			m.log(ctx).Error().Err(restoreErr).Str("function_id", fn.ID).Msg("failed to restore previous code after rejected environment deploy")
+			fn.Status = "error"
+			fn.StatusReason = "deploy failed and the previous code could not be restored: " + restoreErr.Error()
+			m.repo.Update(ctx, fn)
+		}
+		return err
+	}
+	return nil
+}
+
+// Environments lists the environments of a function by name, deleted ones
+// included.
+func (m *Manager) Environments(ctx context.Context, functionID string) ([]Function, error) {
+	if _, err := m.repo.Get(ctx, functionID); err != nil {
+		return nil, err
+	}
+	envs, err := m.environmentsOf(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Environment < envs[j].Environment })
+	return envs, nil
+}
+
+// Environment returns the function deployed to an environment of another.
+func (m *Manager) Environment(ctx context.Context, functionID, env string) (*Function, error) {
+	if _, err := m.repo.Get(ctx, functionID); err != nil {
+		return nil, err
+	}
+	return m.findEnvironment(ctx, functionID, env)
+}
+
+// RemoveEnvironment stops an environment's worker and purges it, its code
+// included.
+func (m *Manager) RemoveEnvironment(ctx context.Context, functionID, env string) error {
+	m.envsMu.Lock()
+	defer m.envsMu.Unlock()
+	fn, err := m.Environment(ctx, functionID, env)
+	if err != nil {
+		return err
+	}
+	return m.PurgeFunction(ctx, fn.ID)
+}
+
+func (m *Manager) findEnvironment(ctx context.Context, functionID, env string) (*Function, error) {
+	envs, err := m.environmentsOf(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range envs {
+		if envs[i].Environment == env {
+			return &envs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: function '%s' has no environment '%s'", ErrNotFound, functionID, env)
+}
+
+// environmentsOf returns the environments of a function.
+func (m *Manager) environmentsOf(ctx context.Context, functionID string) ([]Function, error) {
+	all, err := m.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list functions: %w", err)
+	}
+	var envs []Function
+	for _, fn := range all {
+		if fn.ParentID == functionID {
+			envs = append(envs, fn)
+		}
+	}
+	return envs, nil
+}
@@ -0,0 +1,95 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidArgument marks errors caused by bad caller input, as opposed to
+// failures of the manager or its backends.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrNotConfigured is returned when an operation needs an optional backend
+// that this deployment does not have.
+var ErrNotConfigured = errors.New("not configured")
+
+// ErrUnauthorized is returned for webhook deliveries whose signature or
+// token does not verify, and for API calls without a valid token.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrNameTaken is returned when a function is created with the name of
+// another one.
+var ErrNameTaken = errors.New("name is taken")
+
+// ErrDomainTaken is returned when a function is given the domain of another
+// one.
+var ErrDomainTaken = errors.New("domain is taken")
+
+// ErrPreconditionFailed is returned when a function does not match the state
+// a conditional request expects.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrPayloadTooLarge is returned when an execute payload exceeds the limit of
+// the manager or the function.
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// ErrFunctionNotFound is returned when a requested function does not exist.
+// It is an ErrNotFound.
+var ErrFunctionNotFound = fmt.Errorf("function %w", ErrNotFound)
+
+// ErrFunctionNotRunning is returned when a function is executed while it has
+// no running worker.
+var ErrFunctionNotRunning = errors.New("function is not running")
+
+// Worker failures during an execution: the worker did not answer in time,
+// could not be reached, or answered with an error.
+var (
+	ErrWorkerTimeout     = errors.New("worker timed out")
+	ErrWorkerUnavailable = errors.New("worker unavailable")
+	ErrWorkerFailed      = errors.New("worker failed")
+)
+
+// Error classes tell failures the caller can fix from failures of the
+// platform running the function.
+const (
+	// ErrorClassUser is a failure caused by the request or the function's
+	// own code, such as an invalid payload or an exception the handler
+	// raised. Sending the same request again fails the same way.
+	ErrorClassUser = "user"
+	// ErrorClassPlatform is a failure of the platform, such as a worker that
+	// cannot be reached, times out or was killed, or a manager at capacity.
+	// Sending the request again may succeed.
+	ErrorClassPlatform = "platform"
+)
+
+// userErrors are the errors of ErrorClassUser.
+var userErrors = []error{
+	ErrInvalidArgument,
+	ErrNotFound,
+	ErrUnauthorized,
+	ErrPayloadTooLarge,
+	ErrPreconditionFailed,
+	ErrNameTaken,
+	ErrDomainTaken,
+	ErrIdempotencyMismatch,
+	ErrCodeBlocked,
+}
+
+// ClassifyError returns the class of an error, ErrorClassUser or
+// ErrorClassPlatform; errors it does not know are the platform's.
+func ClassifyError(err error) string {
+	var handlerErr *HandlerError
+	var payloadErr *PayloadError
+	if errors.As(err, &handlerErr) || errors.As(err, &payloadErr) {
+		return ErrorClassUser
+	}
+	for _, userErr := range userErrors {
+		if errors.Is(err, userErr) {
+			return ErrorClassUser
+		}
+	}
+	return ErrorClassPlatform
+}
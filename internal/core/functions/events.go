@@ -0,0 +1,181 @@
+package functions
+
+import (
+	"context"
+	"maps"
+	"service-faas/pkg/rand"
+	"slices"
+	"time"
+)
+
+// Event types, for lifecycle transitions and invocation outcomes.
+const (
+	EventFunctionCreated     = "function.created"
+	EventFunctionDeployed    = "function.deployed"
+	EventFunctionFailed      = "function.failed"
+	EventFunctionStopped     = "function.stopped"
+	EventFunctionDeleted     = "function.deleted"
+	EventFunctionSynced      = "function.synced"
+	EventFunctionPromoted    = "function.promoted"
+	EventInvocationSucceeded = "invocation.succeeded"
+	EventInvocationFailed    = "invocation.failed"
+)
+
+// EventTypes lists every event type.
+var EventTypes = []string{
+	EventFunctionCreated,
+	EventFunctionDeployed,
+	EventFunctionFailed,
+	EventFunctionStopped,
+	EventFunctionDeleted,
+	EventFunctionSynced,
+	EventFunctionPromoted,
+	EventInvocationSucceeded,
+	EventInvocationFailed,
+}
+
+// Event is a lifecycle transition or invocation outcome, as delivered to
+// webhooks and published to the event bus. Function is the function's state
+// when the event happened; it is omitted for invocation events.
+type Event struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	CreatedAt    time.Time `json:"created_at"`
+	FunctionID   string    `json:"function_id"`
+	Tenant       string    `json:"tenant,omitempty"`
+	Function     *Function `json:"function,omitempty"`
+	InvocationID string    `json:"invocation_id,omitempty"`
+	DurationMS   int64     `json:"duration_ms,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	// ErrorClass is the class of a failed invocation's error, "user" or
+	// "platform".
+	ErrorClass string `json:"error_class,omitempty"`
+	// Commit and Trigger describe a sync from Git: the commit fetched and
+	// whether the API or a push webhook asked for it.
+	Commit  string `json:"commit,omitempty"`
+	Trigger string `json:"trigger,omitempty"`
+}
+
+// EventPublisher publishes events to a message bus.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// WithEventPublisher enables publishing every event to a message bus. Events
+// are queued for PublishEvents.
+func WithEventPublisher(p EventPublisher) Option {
+	return func(m *Manager) {
+		m.publisher = p
+		m.busEvents = make(chan *Event, max(m.cfg.EventBusQueueSize, 1))
+	}
+}
+
+// eventSnapshot copies fn for an event. Events leave the manager, so env
+// var values are dropped and only their names are kept.
+func eventSnapshot(fn *Function) Function {
+	snapshot := *fn
+	if len(fn.EnvVars) > 0 {
+		snapshot.EnvVarNames = slices.Sorted(maps.Keys(fn.EnvVars))
+	}
+	snapshot.EnvVars = nil
+	return snapshot
+}
+
+// emitFunctionEvent queues a function lifecycle event; cause is recorded as
+// the event's error.
+func (m *Manager) emitFunctionEvent(ctx context.Context, typ string, fn *Function, cause error) {
+	if m.events == nil && m.busEvents == nil {
+		return
+	}
+	snapshot := eventSnapshot(fn)
+	ev := &Event{Type: typ, FunctionID: fn.ID, Tenant: fn.Tenant, Function: &snapshot}
+	if cause != nil {
+		ev.Error = cause.Error()
+	}
+	m.emit(ctx, ev)
+}
+
+// emitDeployOutcome queues function.deployed, or function.failed when err is
+// set, after an attempt to start a function's worker.
+func (m *Manager) emitDeployOutcome(ctx context.Context, fn *Function, err error) {
+	if err != nil {
+		m.emitFunctionEvent(ctx, EventFunctionFailed, fn, err)
+		return
+	}
+	m.emitFunctionEvent(ctx, EventFunctionDeployed, fn, nil)
+}
+
+// emitSyncEvent queues function.synced after fn was synced to commit, with
+// err set when the commit was rejected.
+func (m *Manager) emitSyncEvent(ctx context.Context, fn *Function, commit, trigger string, err error) {
+	if m.events == nil && m.busEvents == nil {
+		return
+	}
+	snapshot := eventSnapshot(fn)
+	if fn.Git != nil {
+		src := *fn.Git
+		snapshot.Git = &src
+	}
+	ev := &Event{Type: EventFunctionSynced, FunctionID: fn.ID, Tenant: fn.Tenant, Function: &snapshot, Commit: commit, Trigger: trigger}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	m.emit(ctx, ev)
+}
+
+// emitInvocationEvent queues the outcome of an execution of fn.
+func (m *Manager) emitInvocationEvent(ctx context.Context, fn *Function, inv *Invocation, err error) {
+	if m.events == nil && m.busEvents == nil {
+		return
+	}
+	ev := &Event{
+		Type:         EventInvocationSucceeded,
+		FunctionID:   fn.ID,
+		Tenant:       fn.Tenant,
+		InvocationID: inv.ID,
+		DurationMS:   time.Since(inv.StartedAt).Milliseconds(),
+	}
+	if err != nil {
+		ev.Type, ev.Error, ev.ErrorClass = EventInvocationFailed, err.Error(), ClassifyError(err)
+	}
+	m.emit(ctx, ev)
+}
+
+// emit queues an event for webhooks and the event bus without blocking.
+// Events are dropped, and logged, when a queue is full.
+func (m *Manager) emit(ctx context.Context, ev *Event) {
+	ev.ID = rand.ID16()
+	ev.CreatedAt = time.Now().UTC()
+	for _, q := range []struct {
+		name string
+		ch   chan *Event
+	}{{"webhook", m.events}, {"event bus", m.busEvents}} {
+		if q.ch == nil {
+			continue
+		}
+		select {
+		case q.ch <- ev:
+		default:
+			m.log(ctx).Warn().Str("event", ev.Type).Str("function_id", ev.FunctionID).Msgf("%s queue full, event dropped", q.name)
+		}
+	}
+}
+
+// PublishEvents publishes queued events, one at a time and in order, until
+// ctx is done. A failed publish is logged and the event dropped; events
+// still queued when ctx is done are lost.
+func (m *Manager) PublishEvents(ctx context.Context) {
+	if m.busEvents == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-m.busEvents:
+			if err := m.publisher.Publish(ctx, ev); err != nil {
+				m.lg.Error().Err(err).Str("event_id", ev.ID).Str("event", ev.Type).Msg("event publish failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxTraceback caps a traceback; longer ones keep their last lines, where
+// the frame that raised is.
+const maxTraceback = 16 << 10
+
+// tracebackStart starts the traceback Python prints for an uncaught
+// exception.
+const tracebackStart = "Traceback (most recent call last):"
+
+// exceptionLine matches the line of a Python traceback naming the exception,
+// such as
+// "ValueError: bad input" or "mymodule.MyError".
+var exceptionLine = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)(?::\s?(.*))?$`)
+
+// HandlerError is an exception the function's handler raised, as its worker
+// reported it. It is a worker failure, so errors.Is(err, ErrWorkerFailed)
+// holds.
+type HandlerError struct {
+	// Type is the exception's class, e.g. "ValueError" or
+	// "requests.exceptions.HTTPError".
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Traceback is the traceback as Python prints it, if the worker sent it.
+	Traceback string `json:"traceback,omitempty"`
+}
+
+func (e *HandlerError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%v: handler raised %s", ErrWorkerFailed, e.Type)
+	}
+	return fmt.Sprintf("%v: handler raised %s: %s", ErrWorkerFailed, e.Type, e.Message)
+}
+
+func (e *HandlerError) Unwrap() error { return ErrWorkerFailed }
+
+// parseHandlerError reads the exception from the body of a worker's error
+// answer. Workers may answer with JSON, either
+//
+//	{"error": {"type": "ValueError", "message": "...", "traceback": "..."}}
+//
+// or the same fields next to "error", with "error_type" also accepted for
+// "type" and the traceback also as a list of lines; or with the traceback
+// as plain text. It returns nil when the body names no exception.
+func parseHandlerError(body []byte) *HandlerError {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return exceptionFromTraceback(string(body))
+	}
+	var nested map[string]json.RawMessage
+	if json.Unmarshal(doc["error"], &nested) == nil && nested != nil {
+		doc = nested
+	}
+	he := &HandlerError{
+		Type:      jsonString(doc["type"]),
+		Message:   jsonString(doc["message"]),
+		Traceback: jsonString(doc["traceback"]),
+	}
+	if he.Type == "" {
+		he.Type = jsonString(doc["error_type"])
+	}
+	if he.Message == "" {
+		he.Message = jsonString(doc["error"])
+	}
+	if he.Type == "" {
+		parsed := exceptionFromTraceback(he.Traceback)
+		if parsed == nil {
+			return nil
+		}
+		he.Type = parsed.Type
+		if he.Message == "" {
+			he.Message = parsed.Message
+		}
+	}
+	he.Traceback = truncateTraceback(he.Traceback)
+	return he
+}
+
+// exceptionFromTraceback reads the exception from a traceback Python
+// printed, or returns nil when text is not one. The exception line follows
+// the last, indented, frame; with chained exceptions that is the one that
+// escaped. Lines after it continue its message.
+func exceptionFromTraceback(text string) *HandlerError {
+	text = strings.TrimSpace(text)
+	start := strings.LastIndex(text, tracebackStart)
+	if start < 0 {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(text[start+len(tracebackStart):], "\r\n", "\n"), "\n")
+	after := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			after = i + 1
+		}
+	}
+	for i := after; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		m := exceptionLine.FindStringSubmatch(lines[i])
+		if m == nil {
+			return nil
+		}
+		message := m[2]
+		if rest := strings.TrimSpace(strings.Join(lines[i+1:], "\n")); rest != "" {
+			message += "\n" + rest
+		}
+		return &HandlerError{Type: m[1], Message: message, Traceback: truncateTraceback(text)}
+	}
+	return nil
+}
+
+// jsonString returns a JSON string, or the lines of a JSON list of strings
+// joined, or "" for anything else.
+func jsonString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	if json.Unmarshal(raw, &lines) == nil {
+		// traceback.format_exception returns lines that end in "\n".
+		return strings.Join(lines, "")
+	}
+	return ""
+}
+
+func truncateTraceback(tb string) string {
+	if len(tb) <= maxTraceback {
+		return tb
+	}
+	tail := tb[len(tb)-maxTraceback:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return "...\n" + tail
+}
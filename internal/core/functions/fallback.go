@@ -0,0 +1,191 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Reasons an execution was answered by the fallback function.
+const (
+	DegradedError       = "error"
+	DegradedTimeout     = "timeout"
+	DegradedCircuitOpen = "circuit_open"
+	DegradedBusy        = "busy"
+)
+
+// Fallback names a function that answers in place of the primary when the
+// primary fails, times out, has failed repeatedly (circuit open), or is at
+// its concurrency limit (busy). Failures of ErrorClassUser are the caller's
+// and are returned as they are.
+type Fallback struct {
+	FunctionID string `json:"function_id"`
+	// TimeoutMS bounds the primary invocation; zero means no extra timeout.
+	TimeoutMS int `json:"timeout_ms,omitempty"`
+}
+
+// validateFallback checks a fallback definition before it is stored.
+func (m *Manager) validateFallback(ctx context.Context, fb *Fallback) error {
+	if fb == nil {
+		return nil
+	}
+	if fb.FunctionID == "" {
+		return fmt.Errorf("%w: fallback needs a function_id", ErrInvalidArgument)
+	}
+	if fb.TimeoutMS < 0 {
+		return fmt.Errorf("%w: fallback timeout_ms must not be negative", ErrInvalidArgument)
+	}
+	exists, err := m.functionExists(ctx, fb.FunctionID)
+	if err != nil {
+		return fmt.Errorf("look up fallback function: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: fallback function '%s' not found", ErrInvalidArgument, fb.FunctionID)
+	}
+	return nil
+}
+
+// invokeWithFallback invokes fn and, if that fails and a fallback is
+// configured, the fallback function. The returned reason is empty unless the
+// result came from the fallback.
+func (m *Manager) invokeWithFallback(ctx context.Context, fn *Function, payload string) (json.RawMessage, string, error) {
+	if fn.Fallback == nil {
+		result, err := m.invoke(ctx, fn, payload)
+		return result, "", err
+	}
+
+	reason := DegradedCircuitOpen
+	if allowed, probe := m.breaker.allow(fn.ID); allowed {
+		primaryCtx := ctx
+		if fn.Fallback.TimeoutMS > 0 {
+			var cancel context.CancelFunc
+			primaryCtx, cancel = context.WithTimeout(ctx, time.Duration(fn.Fallback.TimeoutMS)*time.Millisecond)
+			defer cancel()
+		}
+		result, err := m.invoke(primaryCtx, fn, payload)
+		busy := errors.Is(err, ErrConcurrencyLimit)
+		userErr := err != nil && ClassifyError(err) == ErrorClassUser
+		if busy || (err != nil && ctx.Err() != nil) {
+			// A full function is not a failing one, and a call the caller
+			// gave up on tells nothing about the function.
+			m.breaker.release(fn.ID, probe)
+		} else {
+			// A function that rejects a request works.
+			settings := m.settings()
+			m.breaker.record(fn.ID, probe, err == nil || userErr, settings.CircuitFailureThreshold, settings.CircuitOpenDuration)
+		}
+		if err == nil {
+			return result, "", nil
+		}
+		if userErr {
+			// The fallback would be sent the same request; the caller needs
+			// to know it is at fault.
+			return nil, "", err
+		}
+		if ctx.Err() != nil {
+			// The caller gave up; there is nobody to serve a fallback to.
+			return nil, "", err
+		}
+		reason = DegradedError
+		if busy {
+			reason = DegradedBusy
+		} else if primaryCtx.Err() == context.DeadlineExceeded {
+			reason = DegradedTimeout
+		}
+		m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Str("fallback_id", fn.Fallback.FunctionID).Msg("primary invocation failed, using fallback")
+	}
+
+	target, err := m.repo.Get(ctx, fn.Fallback.FunctionID)
+	if err != nil {
+		return nil, "", fmt.Errorf("fallback function '%s' not found", fn.Fallback.FunctionID)
+	}
+	result, err := m.invoke(ctx, target, payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("fallback invocation failed: %w", err)
+	}
+	return result, reason, nil
+}
+
+// circuitBreaker tracks consecutive failures per function. Once a function
+// reaches the threshold its circuit opens and calls skip straight to the
+// fallback until the open period ends. The circuit is then half-open: a
+// single call probes the primary while the others still go to the fallback.
+// A probe that succeeds closes the circuit; one that fails opens it again.
+type circuitBreaker struct {
+	mu    sync.Mutex
+	state map[string]*circuit
+}
+
+type circuit struct {
+	failures int
+	// openUntil is set once the circuit has opened; it is half-open after.
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may go to the primary, and whether that call
+// is the probe of a half-open circuit.
+func (b *circuitBreaker) allow(functionID string) (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.state[functionID]
+	switch {
+	case !ok || c.openUntil.IsZero():
+		return true, false
+	case time.Now().Before(c.openUntil) || c.probing:
+		return false, false
+	}
+	c.probing = true
+	return true, true
+}
+
+// release ends a call whose outcome is not recorded, letting another call
+// probe a half-open circuit.
+func (b *circuitBreaker) release(functionID string, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.state[functionID]; ok && probe {
+		c.probing = false
+	}
+}
+
+// forget drops the circuit of a function.
+func (b *circuitBreaker) forget(functionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.state, functionID)
+}
+
+func (b *circuitBreaker) record(functionID string, probe, success bool, threshold int, openFor time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		delete(b.state, functionID)
+		return
+	}
+	if b.state == nil {
+		b.state = make(map[string]*circuit)
+	}
+	c, ok := b.state[functionID]
+	if !ok {
+		c = &circuit{}
+		b.state[functionID] = c
+	}
+	if !c.openUntil.IsZero() {
+		// Calls that started before the circuit opened do not count; a
+		// failed probe opens it again.
+		if probe {
+			c.openUntil = time.Now().Add(openFor)
+			c.probing = false
+		}
+		return
+	}
+	c.failures++
+	if threshold > 0 && c.failures >= threshold {
+		c.openUntil = time.Now().Add(openFor)
+		c.failures = 0
+	}
+}
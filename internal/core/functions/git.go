@@ -0,0 +1,339 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"service-faas/internal/config"
+	"service-faas/pkg/bundle"
+	"strings"
+	"time"
+)
+
+// GitSource is the Git repository a function's code is deployed from.
+type GitSource struct {
+	URL string `json:"url"`
+	// Ref is the branch, tag or commit to deploy; empty means the
+	// repository's default branch.
+	Ref string `json:"ref,omitempty"`
+	// Path is the directory holding handler.py, relative to the repository
+	// root; empty means the root.
+	Path string `json:"path,omitempty"`
+	// Commit is the SHA of the commit last deployed.
+	Commit string `json:"commit,omitempty"`
+}
+
+// GitAuth holds the credentials for cloning a private repository: a token
+// for HTTPS URLs or a private deploy key for SSH URLs.
+type GitAuth struct {
+	Token     string
+	DeployKey string
+}
+
+// GitFetcher checks out commits of Git repositories.
+type GitFetcher interface {
+	// Fetch checks out src.Ref of src.URL into dir, an empty directory, and
+	// returns the SHA of the commit checked out. Repositories or refs that
+	// cannot be fetched are reported as ErrInvalidArgument.
+	Fetch(ctx context.Context, src GitSource, auth GitAuth, dir string) (string, error)
+}
+
+// WithGitFetcher lets functions be deployed from Git repositories.
+func WithGitFetcher(fetcher GitFetcher) Option {
+	return func(m *Manager) { m.git = fetcher }
+}
+
+func validateGitSource(src *GitSource, auth GitAuth) error {
+	if src.URL == "" {
+		return fmt.Errorf("%w: git url is required", ErrInvalidArgument)
+	}
+	if strings.HasPrefix(src.URL, "-") || strings.HasPrefix(src.Ref, "-") {
+		return fmt.Errorf("%w: git url and ref must not start with '-'", ErrInvalidArgument)
+	}
+	if src.Path != "" && !filepath.IsLocal(filepath.FromSlash(src.Path)) {
+		return fmt.Errorf("%w: git path %q must be relative to the repository root", ErrInvalidArgument, src.Path)
+	}
+	if auth.Token != "" && auth.DeployKey != "" {
+		return fmt.Errorf("%w: give a git token or a deploy key, not both", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// openGitCode checks out the commit a new function is deployed from, records
+// it in opts and opens its code. The returned func releases the checkout.
+func (m *Manager) openGitCode(ctx context.Context, opts *FunctionOptions) (io.Reader, func(), error) {
+	co, err := m.checkoutGit(ctx, *opts.Git, opts.GitAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, format, err := co.code(m.bundlesSupported())
+	if err != nil {
+		co.Close()
+		return nil, nil, err
+	}
+	src := *opts.Git
+	src.Commit = co.commit
+	opts.Git = &src
+	opts.BundleFormat = format
+	return code, func() { code.Close(); co.Close() }, nil
+}
+
+// gitCheckout is a commit of a function's repository, checked out into a
+// temporary directory that Close removes.
+type gitCheckout struct {
+	root   string
+	dir    string // the function's directory within root
+	commit string
+}
+
+// checkoutGit fetches a commit of a function's repository.
+func (m *Manager) checkoutGit(ctx context.Context, src GitSource, auth GitAuth) (*gitCheckout, error) {
+	if m.git == nil {
+		return nil, fmt.Errorf("%w: git deploys are disabled", ErrNotConfigured)
+	}
+	root, err := os.MkdirTemp("", "faas-git-")
+	if err != nil {
+		return nil, fmt.Errorf("create checkout dir: %w", err)
+	}
+	co := &gitCheckout{root: root, dir: filepath.Join(root, filepath.FromSlash(src.Path))}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.GitTimeout)
+	defer cancel()
+	if co.commit, err = m.git.Fetch(ctx, src, auth, root); err != nil {
+		co.Close()
+		return nil, fmt.Errorf("fetch %s: %w", src.URL, err)
+	}
+	return co, nil
+}
+
+func (co *gitCheckout) Close() {
+	_ = os.RemoveAll(co.root)
+}
+
+// code opens the checked out code for storeCode: the function's directory
+// as a bundle where bundles are supported, otherwise its handler.py alone.
+// Links and the .git directory are left out.
+func (co *gitCheckout) code(bundles bool) (io.ReadCloser, string, error) {
+	dir, err := co.functionDir()
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := os.Lstat(filepath.Join(dir, "handler.py"))
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, "", fmt.Errorf("%w: commit %s has no handler.py in the function's directory", ErrInvalidArgument, co.commit)
+	}
+	if !bundles {
+		f, err := os.Open(filepath.Join(dir, "handler.py"))
+		if err != nil {
+			return nil, "", fmt.Errorf("open handler.py: %w", err)
+		}
+		return f, "", nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(bundle.Pack(pw, dir))
+	}()
+	return pr, bundle.FormatTarGzip, nil
+}
+
+// functionDir resolves the links on the way to the function's directory and
+// checks that it is still inside the checkout, so a linked directory in the
+// repository cannot point at files of the host.
+func (co *gitCheckout) functionDir() (string, error) {
+	root, err := filepath.EvalSymlinks(co.root)
+	if err != nil {
+		return "", fmt.Errorf("resolve checkout dir: %w", err)
+	}
+	dir, err := filepath.EvalSymlinks(co.dir)
+	if err != nil {
+		return "", fmt.Errorf("%w: commit %s has no function directory at the git path", ErrInvalidArgument, co.commit)
+	}
+	if rel, err := filepath.Rel(root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: the git path of commit %s leads out of the repository", ErrInvalidArgument, co.commit)
+	}
+	return dir, nil
+}
+
+// bundlesSupported reports whether the code store can keep code bundles.
+func (m *Manager) bundlesSupported() bool {
+	_, ok := m.code.(BundleStore)
+	return ok && m.cfg.DeploymentEnv == config.EnvDocker
+}
+
+// Sync triggers, recorded on function.synced events.
+const (
+	SyncTriggerAPI  = "api"
+	SyncTriggerPush = "push"
+)
+
+// SyncFunction deploys the current commit of the ref a function was created
+// from. The new code passes the same checks as an upload; if it fails them,
+// the function keeps its previous commit. A running function's worker is
+// replaced by one on the new code, see rollOutWorker, or with image builds
+// its image is rebuilt. When the new worker does not start, the function is
+// rolled back to its previous commit. Each deployment is recorded, see
+// ListGitDeployments.
+func (m *Manager) SyncFunction(ctx context.Context, functionID string) (*Function, error) {
+	return m.syncFunction(ctx, functionID, SyncTriggerAPI)
+}
+
+// syncFunction is SyncFunction, recording what triggered it.
+func (m *Manager) syncFunction(ctx context.Context, functionID, trigger string) (_ *Function, err error) {
+	if m.git == nil {
+		return nil, fmt.Errorf("%w: git deploys are disabled", ErrNotConfigured)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Git == nil {
+		return nil, fmt.Errorf("%w: function '%s' was not deployed from git", ErrInvalidArgument, functionID)
+	}
+	if fn.DeletedAt != nil || fn.BlockedReason != "" {
+		return nil, fmt.Errorf("%w: function '%s' is %s", ErrInvalidArgument, functionID, fn.Status)
+	}
+	if _, running := m.syncs.LoadOrStore(functionID, struct{}{}); running {
+		return nil, fmt.Errorf("%w: function '%s' is already being synced", ErrInvalidArgument, functionID)
+	}
+	defer m.syncs.Delete(functionID)
+
+	before, previous := *fn, fn.Git.Commit
+	record := &GitDeployment{
+		FunctionID:     fn.ID,
+		Trigger:        trigger,
+		Ref:            fn.Git.Ref,
+		PreviousCommit: previous,
+		StartedAt:      time.Now().UTC(),
+	}
+	defer func() {
+		if record != nil {
+			m.recordGitDeployment(ctx, record, err)
+		}
+	}()
+
+	co, err := m.checkoutGit(ctx, *fn.Git, fn.gitAuth())
+	if err != nil {
+		return nil, err
+	}
+	defer co.Close()
+	record.Commit = co.commit
+	if co.commit == previous {
+		record = nil
+		return fn, nil
+	}
+
+	code, format, err := co.code(m.bundlesSupported())
+	if err != nil {
+		return nil, err
+	}
+	defer code.Close()
+	if err := m.replaceGitCode(ctx, fn, co.commit, code, format); err != nil {
+		if restoreErr := m.restoreGitCommit(ctx, fn, previous); restoreErr != nil {
+			m.log(ctx).Error().Err(restoreErr).Str("function_id", functionID).Str("commit", previous).Msg("failed to restore previous commit after failed sync")
+			fn.Status = "error"
+			fn.StatusReason = "git sync failed and the previous commit could not be restored: " + restoreErr.Error()
+			m.repo.Update(ctx, fn)
+		}
+		m.emitSyncEvent(ctx, fn, co.commit, trigger, err)
+		return nil, err
+	}
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update function: %w", err)
+	}
+	m.log(ctx).Info().Str("function_id", functionID).Str("from", previous).Str("to", co.commit).Str("trigger", trigger).Msg("function synced from git")
+	m.emitSyncEvent(ctx, fn, co.commit, trigger, nil)
+
+	if _, ok := m.imageBuilder(); ok {
+		if !m.startBuild(*fn) {
+			return nil, fmt.Errorf("%w: function '%s' is already being built", ErrInvalidArgument, functionID)
+		}
+		fn.BuildStatus = BuildRunning
+		record.Outcome = GitDeployBuilding
+		return fn, nil
+	}
+	if fn.Status != "running" {
+		return fn, nil
+	}
+	if stopped, err := m.rollOutWorker(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", functionID).Str("commit", co.commit).Msg("worker of new commit did not start, rolling back")
+		if rollbackErr := m.rollBackGitSync(ctx, fn, &before, previous, stopped); rollbackErr != nil {
+			return nil, fmt.Errorf("%w; rolling back to commit %s failed: %w", err, previous, rollbackErr)
+		}
+		record.Outcome = GitDeployRolledBack
+		return nil, err
+	}
+	return fn, nil
+}
+
+// rollBackGitSync puts a function whose new commit's worker did not start
+// back on its previous commit, as it was before the sync, and starts the
+// previous commit's worker again when the old one was stopped.
+func (m *Manager) rollBackGitSync(ctx context.Context, fn, before *Function, previous string, stopped bool) error {
+	fn.CodePath = before.CodePath
+	fn.CodeSHA256 = before.CodeSHA256
+	fn.PolicyFindings = before.PolicyFindings
+	fn.Promotion = before.Promotion
+	if err := m.restoreGitCommit(ctx, fn, previous); err != nil {
+		return err
+	}
+	fn.Git.Commit = previous
+	if stopped {
+		return m.restartWorker(ctx, fn)
+	}
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return fmt.Errorf("db update function: %w", err)
+	}
+	return nil
+}
+
+// replaceGitCode replaces a function's stored code with that of a commit,
+// which must pass the checks an upload does, and records the commit.
+func (m *Manager) replaceGitCode(ctx context.Context, fn *Function, commit string, code io.Reader, format string) error {
+	if err := m.replaceCode(ctx, fn, code, format, ""); err != nil {
+		if errors.Is(err, ErrCodeBlocked) {
+			m.log(ctx).Warn().Str("function_id", fn.ID).Str("commit", commit).Msg("git commit blocked by malware scan")
+		}
+		return fmt.Errorf("commit %s: %w", commit, err)
+	}
+	fn.Git.Commit = commit
+	fn.Promotion = nil
+	return nil
+}
+
+// restoreGitCommit stores a function's previously deployed commit again,
+// after the code of a newer one was rejected. The restored handler must
+// match the function's recorded checksum.
+func (m *Manager) restoreGitCommit(ctx context.Context, fn *Function, commit string) error {
+	src := *fn.Git
+	src.Ref = commit
+	co, err := m.checkoutGit(ctx, src, fn.gitAuth())
+	if err != nil {
+		return err
+	}
+	defer co.Close()
+	code, format, err := co.code(m.bundlesSupported())
+	if err != nil {
+		return err
+	}
+	defer code.Close()
+	if err := m.code.Delete(ctx, fn.ID); err != nil {
+		return fmt.Errorf("delete rejected code: %w", err)
+	}
+	opts := FunctionOptions{Tenant: fn.Tenant, WorkerImage: fn.WorkerImage, Runtime: fn.Runtime, BundleFormat: format}
+	stored, err := m.storeCode(ctx, fn.ID, code, opts)
+	if err != nil {
+		return err
+	}
+	if stored.SHA256 != fn.CodeSHA256 {
+		return fmt.Errorf("commit %s no longer has the handler deployed from it", commit)
+	}
+	fn.DependencyLockSHA256, err = m.lockDependencies(ctx, fn.ID, stored, opts)
+	return err
+}
+
+func (fn *Function) gitAuth() GitAuth {
+	return GitAuth{Token: fn.GitToken, DeployKey: fn.GitDeployKey}
+}
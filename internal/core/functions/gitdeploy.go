@@ -0,0 +1,118 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"service-faas/pkg/rand"
+)
+
+// Outcomes of a Git deployment.
+const (
+	// GitDeployDeployed: the commit's code is deployed, and its worker
+	// serves it when the function is running.
+	GitDeployDeployed = "deployed"
+	// GitDeployBuilding: the commit's image is being built; the build
+	// deploys it.
+	GitDeployBuilding = "building"
+	// GitDeployRolledBack: the commit's worker did not start and the
+	// function was put back on its previous commit.
+	GitDeployRolledBack = "rolled_back"
+	// GitDeployFailed: the commit was rejected or could not be checked out,
+	// or rolling back to the previous commit failed too.
+	GitDeployFailed = "failed"
+)
+
+// Git deployment list limits.
+const (
+	defaultGitDeploymentLimit = 50
+	maxGitDeploymentLimit     = 500
+)
+
+// GitDeployment records one attempt to deploy a new commit of a function
+// deployed from Git, by SyncFunction or a push webhook.
+type GitDeployment struct {
+	ID         string `gorm:"primaryKey;size:64" json:"deployment_id"`
+	FunctionID string `gorm:"size:64;index" json:"function_id"`
+	// Trigger is SyncTriggerAPI or SyncTriggerPush.
+	Trigger string `json:"trigger"`
+	// Ref is the ref the function follows, empty for the default branch.
+	Ref            string `json:"ref,omitempty"`
+	Commit         string `json:"commit,omitempty"`
+	PreviousCommit string `json:"previous_commit"`
+	// Outcome is one of the GitDeploy constants; Error says what went wrong
+	// unless it is GitDeployDeployed or GitDeployBuilding.
+	Outcome    string    `json:"outcome"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  time.Time `gorm:"index" json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// GitDeploymentRepository persists Git deployment records.
+type GitDeploymentRepository interface {
+	Create(ctx context.Context, d *GitDeployment) error
+	// List returns a function's deployments, newest first.
+	List(ctx context.Context, functionID string, limit int) ([]GitDeployment, error)
+	DeleteByFunction(ctx context.Context, functionID string) error
+}
+
+// WithGitDeploymentRepository keeps a record of each Git deployment, see
+// ListGitDeployments.
+func WithGitDeploymentRepository(repo GitDeploymentRepository) Option {
+	return func(m *Manager) { m.gitDeployments = repo }
+}
+
+// ListGitDeployments returns the deployments of a function's Git commits,
+// newest first.
+func (m *Manager) ListGitDeployments(ctx context.Context, functionID string, limit int) ([]GitDeployment, error) {
+	if m.gitDeployments == nil {
+		return nil, fmt.Errorf("%w: git deployment records", ErrNotConfigured)
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidArgument)
+	}
+	if limit == 0 {
+		limit = defaultGitDeploymentLimit
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	return m.gitDeployments.List(ctx, fn.ID, min(limit, maxGitDeploymentLimit))
+}
+
+// recordGitDeployment stores the record of a finished Git deployment, with
+// the outcome and error of err unless the outcome is set. Failures to store
+// are logged.
+func (m *Manager) recordGitDeployment(ctx context.Context, d *GitDeployment, err error) {
+	if m.gitDeployments == nil {
+		return
+	}
+	switch {
+	case d.Outcome != "":
+	case err != nil:
+		d.Outcome = GitDeployFailed
+	default:
+		d.Outcome = GitDeployDeployed
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	d.ID = rand.ID16()
+	d.FinishedAt = time.Now().UTC()
+	if err := m.gitDeployments.Create(context.WithoutCancel(ctx), d); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", d.FunctionID).Str("commit", d.Commit).Msg("failed to record git deployment")
+	}
+}
+
+// deleteGitDeployments removes the Git deployment records of a purged
+// function.
+func (m *Manager) deleteGitDeployments(ctx context.Context, functionID string) {
+	if m.gitDeployments == nil {
+		return
+	}
+	if err := m.gitDeployments.DeleteByFunction(ctx, functionID); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", functionID).Msg("failed to delete git deployments of purged function")
+	}
+}
@@ -0,0 +1,179 @@
+package functions
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Git hosts whose push webhooks GitPush understands.
+const (
+	GitHostGitHub = "github"
+	GitHostGitLab = "gitlab"
+)
+
+// GitPushResult lists the functions a push redeploys.
+type GitPushResult struct {
+	Ref       string   `json:"ref"`
+	Commit    string   `json:"commit"`
+	Functions []string `json:"functions"`
+}
+
+// gitPush is the part of a push webhook payload that selects functions.
+type gitPush struct {
+	Ref           string
+	After         string
+	DefaultBranch string
+	URLs          []string // every address of the pushed repository
+}
+
+// GitPush verifies a push webhook delivery of a Git host and redeploys the
+// functions deployed from the pushed ref, one at a time, in the background.
+// For GitHub, signature is the X-Hub-Signature-256 header; for GitLab, the
+// X-Gitlab-Token header. Deliveries of other events, and pushes deleting a
+// ref, redeploy nothing.
+func (m *Manager) GitPush(ctx context.Context, host, event, signature string, body []byte) (*GitPushResult, error) {
+	secret := m.cfg.GitWebhookSecret
+	if m.git == nil || secret == "" {
+		return nil, fmt.Errorf("%w: git push webhooks are disabled", ErrNotConfigured)
+	}
+	push, err := parseGitPush(host, event, secret, signature, body)
+	if err != nil || push == nil {
+		return &GitPushResult{Functions: []string{}}, err
+	}
+
+	all, err := m.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list functions: %w", err)
+	}
+	keys := make(map[string]bool, len(push.URLs))
+	for _, u := range push.URLs {
+		if u != "" {
+			keys[repoKey(u)] = true
+		}
+	}
+	result := &GitPushResult{Ref: push.Ref, Commit: push.After, Functions: []string{}}
+	for _, fn := range all {
+		if fn.Git == nil || fn.DeletedAt != nil || fn.BlockedReason != "" || fn.Git.Commit == push.After {
+			continue
+		}
+		if keys[repoKey(fn.Git.URL)] && refMatches(fn.Git.Ref, push) {
+			result.Functions = append(result.Functions, fn.ID)
+		}
+	}
+	if len(result.Functions) > 0 {
+		m.log(ctx).Info().Str("ref", push.Ref).Str("commit", push.After).Strs("function_ids", result.Functions).Msg("git push, redeploying functions")
+		go m.rollOutPush(context.WithoutCancel(ctx), result.Functions)
+	}
+	return result, nil
+}
+
+// rollOutPush syncs functions one after another, so functions sharing a
+// repository are not all restarted at once. Rollouts of later pushes wait
+// for earlier ones.
+func (m *Manager) rollOutPush(ctx context.Context, functionIDs []string) {
+	m.gitPushMu.Lock()
+	defer m.gitPushMu.Unlock()
+	for _, id := range functionIDs {
+		if _, err := m.syncFunction(ctx, id, SyncTriggerPush); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", id).Msg("git push redeploy failed")
+		}
+	}
+}
+
+// parseGitPush verifies a delivery and extracts its push, or returns nil for
+// deliveries that are not pushes of a ref.
+func parseGitPush(host, event, secret, signature string, body []byte) (*gitPush, error) {
+	var payload struct {
+		Ref        string `json:"ref"`
+		After      string `json:"after"`
+		Deleted    bool   `json:"deleted"`
+		Repository struct {
+			CloneURL      string `json:"clone_url"`
+			SSHURL        string `json:"ssh_url"`
+			HTMLURL       string `json:"html_url"`
+			DefaultBranch string `json:"default_branch"`
+		} `json:"repository"`
+		Project struct {
+			HTTPURL       string `json:"git_http_url"`
+			SSHURL        string `json:"git_ssh_url"`
+			WebURL        string `json:"web_url"`
+			DefaultBranch string `json:"default_branch"`
+		} `json:"project"`
+	}
+	switch host {
+	case GitHostGitHub:
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(signature), []byte(want)) {
+			return nil, fmt.Errorf("%w: signature does not match", ErrUnauthorized)
+		}
+		if event != "push" {
+			return nil, nil
+		}
+	case GitHostGitLab:
+		if subtle.ConstantTimeCompare([]byte(signature), []byte(secret)) != 1 {
+			return nil, fmt.Errorf("%w: token does not match", ErrUnauthorized)
+		}
+		if event != "Push Hook" && event != "Tag Push Hook" {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown git host %q", ErrInvalidArgument, host)
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: invalid push payload: %v", ErrInvalidArgument, err)
+	}
+	// A deleted ref has no commit to deploy.
+	if payload.Deleted || strings.Trim(payload.After, "0") == "" {
+		return nil, nil
+	}
+	push := &gitPush{Ref: payload.Ref, After: payload.After}
+	if host == GitHostGitHub {
+		r := payload.Repository
+		push.DefaultBranch, push.URLs = r.DefaultBranch, []string{r.CloneURL, r.SSHURL, r.HTMLURL}
+	} else {
+		p := payload.Project
+		push.DefaultBranch, push.URLs = p.DefaultBranch, []string{p.HTTPURL, p.SSHURL, p.WebURL}
+	}
+	return push, nil
+}
+
+// refMatches reports whether a function deployed from ref follows the
+// pushed ref: a branch or tag name, a full ref, or empty for the default
+// branch.
+func refMatches(ref string, push *gitPush) bool {
+	switch {
+	case ref == "":
+		return push.DefaultBranch != "" && push.Ref == "refs/heads/"+push.DefaultBranch
+	case strings.HasPrefix(ref, "refs/"):
+		return push.Ref == ref
+	}
+	return push.Ref == "refs/heads/"+ref || push.Ref == "refs/tags/"+ref
+}
+
+// repoKey reduces the addresses of a repository, https or ssh, with or
+// without .git, to the same host/path key.
+func repoKey(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		// scp-like syntax: git@github.com:acme/functions.git
+		if _, rest, ok := strings.Cut(raw, "@"); ok {
+			raw = rest
+		}
+		raw = "ssh://" + strings.Replace(raw, ":", "/", 1)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	path := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	return strings.ToLower(u.Hostname() + "/" + path)
+}
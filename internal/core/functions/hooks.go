@@ -0,0 +1,110 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Hook failure policies.
+const (
+	HookAbort    = "abort"
+	HookContinue = "continue"
+)
+
+// Hook is a call made by the manager before or after an invocation. It
+// targets either another function or an external URL speaking the worker
+// protocol ({"payload": "..."} in, {"result": ...} out).
+type Hook struct {
+	FunctionID string `json:"function_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+	OnFailure  string `json:"on_failure,omitempty"` // "abort" (default) or "continue"
+}
+
+func (h *Hook) validate() error {
+	if (h.FunctionID == "") == (h.URL == "") {
+		return fmt.Errorf("%w: hook needs exactly one of function_id or url", ErrInvalidArgument)
+	}
+	switch h.OnFailure {
+	case "", HookAbort, HookContinue:
+	default:
+		return fmt.Errorf("%w: unknown hook failure policy %q", ErrInvalidArgument, h.OnFailure)
+	}
+	return nil
+}
+
+func (h *Hook) aborts() bool {
+	return h.OnFailure != HookContinue
+}
+
+// callHook runs the hook with the given payload. Function hooks invoke the target
+// worker directly, so hooks configured on the target itself are not chained.
+func (m *Manager) callHook(ctx context.Context, h *Hook, payload string) (json.RawMessage, error) {
+	if h.URL != "" {
+		return postPayload(ctx, http.DefaultClient, h.URL, payload)
+	}
+	target, err := m.repo.Get(ctx, h.FunctionID)
+	if err != nil {
+		return nil, fmt.Errorf("hook function '%s' not found", h.FunctionID)
+	}
+	return m.invoke(ctx, target, payload)
+}
+
+// runPreHook passes the payload through the pre-invoke hook, if any. The
+// hook's result replaces the payload: a JSON string is used verbatim, any
+// other value is forwarded as its JSON encoding.
+func (m *Manager) runPreHook(ctx context.Context, fn *Function, payload string) (string, error) {
+	if fn.PreHook == nil {
+		return payload, nil
+	}
+	result, err := m.callHook(ctx, fn.PreHook, payload)
+	if err != nil {
+		if fn.PreHook.aborts() {
+			return "", fmt.Errorf("pre-invoke hook failed: %w", err)
+		}
+		m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("pre-invoke hook failed, continuing")
+		return payload, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(result, &s); err == nil {
+		return s, nil
+	}
+	return string(result), nil
+}
+
+// runPostHook hands the function's result to the post-invoke hook, if any.
+// The hook's own result is discarded.
+func (m *Manager) runPostHook(ctx context.Context, fn *Function, result json.RawMessage) error {
+	if fn.PostHook == nil {
+		return nil
+	}
+	if _, err := m.callHook(ctx, fn.PostHook, string(result)); err != nil {
+		if fn.PostHook.aborts() {
+			return fmt.Errorf("post-invoke hook failed: %w", err)
+		}
+		m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("post-invoke hook failed, continuing")
+	}
+	return nil
+}
+
+// validateHook checks a hook definition before it is stored.
+func (m *Manager) validateHook(ctx context.Context, h *Hook) error {
+	if h == nil {
+		return nil
+	}
+	if err := h.validate(); err != nil {
+		return err
+	}
+	if h.FunctionID != "" {
+		exists, err := m.functionExists(ctx, h.FunctionID)
+		if err != nil {
+			return fmt.Errorf("look up hook function: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("%w: hook function '%s' not found", ErrInvalidArgument, h.FunctionID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package functions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Idempotency errors. A key is in progress while its first execution runs,
+// and mismatched when reused with a different payload.
+var (
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+	ErrIdempotencyMismatch   = errors.New("idempotency key was used with a different payload")
+)
+
+// maxIdempotencyKey bounds the length of an idempotency key.
+const maxIdempotencyKey = 191
+
+// IdempotencyRecord remembers the outcome of an execution made with an
+// idempotency key. Until Done it only reserves the key.
+type IdempotencyRecord struct {
+	FunctionID    string          `gorm:"primaryKey;size:64"`
+	Key           string          `gorm:"primaryKey;size:191;column:idempotency_key"`
+	PayloadSHA256 string          `gorm:"size:64"`
+	Done          bool            `gorm:"not null;default:false"`
+	Result        json.RawMessage `gorm:"serializer:json"`
+	ResultRef     *ResultRef      `gorm:"serializer:json"`
+	Degraded      string
+	InvocationID  string    `gorm:"size:64"`
+	ExpiresAt     time.Time `gorm:"index"`
+}
+
+// IdempotencyRepository persists idempotency records. Records past their
+// ExpiresAt are treated as absent.
+type IdempotencyRepository interface {
+	// Claim stores rec unless an unexpired record with the same function and
+	// key exists; that record is returned instead. The check and insert are
+	// atomic, so exactly one of several concurrent claims succeeds.
+	Claim(ctx context.Context, rec *IdempotencyRecord) (*IdempotencyRecord, error)
+	// Complete saves the outcome of a claimed record.
+	Complete(ctx context.Context, rec *IdempotencyRecord) error
+	// Release deletes a claimed record so the key can be used again.
+	Release(ctx context.Context, functionID, key string) error
+	// DeleteExpired deletes records that expired before now.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// WithIdempotencyRepository enables idempotency keys on executions.
+func WithIdempotencyRepository(repo IdempotencyRepository) Option {
+	return func(m *Manager) { m.idempotency = repo }
+}
+
+// ExecuteOnce executes a function at most once per idempotency key: a retry
+// with the same key and payload returns the stored result, marked Replayed,
+// for IdempotencyTTL after the first execution succeeded. Failed executions
+// are not stored, so they can be retried. A reservation left behind by a
+// crashed manager expires after IdempotencyLockTimeout.
+func (m *Manager) ExecuteOnce(ctx context.Context, functionID, key, payload string) (*ExecutionResult, error) {
+	if m.idempotency == nil {
+		return nil, fmt.Errorf("%w: idempotency keys", ErrNotConfigured)
+	}
+	if len(key) > maxIdempotencyKey {
+		return nil, fmt.Errorf("%w: idempotency key is longer than %d bytes", ErrInvalidArgument, maxIdempotencyKey)
+	}
+
+	sum := sha256.Sum256([]byte(payload))
+	rec := &IdempotencyRecord{
+		FunctionID:    functionID,
+		Key:           key,
+		PayloadSHA256: hex.EncodeToString(sum[:]),
+		ExpiresAt:     time.Now().UTC().Add(m.cfg.IdempotencyLockTimeout),
+	}
+	existing, err := m.idempotency.Claim(ctx, rec)
+	if err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if existing != nil {
+		switch {
+		case existing.PayloadSHA256 != rec.PayloadSHA256:
+			return nil, ErrIdempotencyMismatch
+		case !existing.Done:
+			return nil, ErrIdempotencyInProgress
+		}
+		ref, err := m.linkResult(ctx, existing.ResultRef)
+		if err != nil {
+			return nil, err
+		}
+		return &ExecutionResult{
+			Result:       existing.Result,
+			ResultRef:    ref,
+			Degraded:     existing.Degraded,
+			Replayed:     true,
+			InvocationID: existing.InvocationID,
+		}, nil
+	}
+
+	// The outcome is recorded even if the caller went away meanwhile; that
+	// is exactly the request it will retry.
+	storeCtx := context.WithoutCancel(ctx)
+	result, err := m.ExecuteFunction(ctx, functionID, payload)
+	if err != nil {
+		if relErr := m.idempotency.Release(storeCtx, functionID, key); relErr != nil {
+			m.log(ctx).Warn().Err(relErr).Str("function_id", functionID).Msg("failed to release idempotency key")
+		}
+		return nil, err
+	}
+
+	rec.Done = true
+	rec.Result, rec.Degraded = result.Result, result.Degraded
+	if result.ResultRef != nil {
+		// The URL may expire before the record does, so only the key is kept
+		// and a new URL is made for each replay.
+		rec.ResultRef = &ResultRef{Key: result.ResultRef.Key, Size: result.ResultRef.Size}
+	}
+	rec.InvocationID = result.InvocationID
+	rec.ExpiresAt = time.Now().UTC().Add(m.cfg.IdempotencyTTL)
+	if err := m.idempotency.Complete(storeCtx, rec); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", functionID).Msg("failed to store idempotent result")
+	}
+	return result, nil
+}
+
+// linkResult returns ref, the stored reference to an offloaded result, with
+// a new URL.
+func (m *Manager) linkResult(ctx context.Context, ref *ResultRef) (*ResultRef, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if m.results == nil {
+		return nil, fmt.Errorf("%w: result offloading", ErrNotConfigured)
+	}
+	url, expiresAt, err := m.results.Link(ctx, ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("link offloaded result: %w", err)
+	}
+	return &ResultRef{Key: ref.Key, URL: url, Size: ref.Size, ExpiresAt: expiresAt}, nil
+}
+
+// PruneExpiredEvery deletes expired idempotency records, invocations and
+// debug captures at the given interval until ctx is done. Only the leader
+// prunes.
+func (m *Manager) PruneExpiredEvery(ctx context.Context, interval time.Duration) {
+	if m.idempotency == nil && m.invocations == nil && m.captures == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !m.IsLeader() {
+			continue
+		}
+		now := time.Now().UTC()
+		if m.idempotency != nil {
+			m.pruneExpired(ctx, "idempotency records", m.idempotency.DeleteExpired, now)
+		}
+		if m.invocations != nil {
+			m.pruneExpired(ctx, "invocations", m.invocations.DeleteExpired, now)
+		}
+		if m.captures != nil {
+			m.pruneExpired(ctx, "debug captures", m.captures.DeleteExpired, now)
+		}
+	}
+}
+
+func (m *Manager) pruneExpired(ctx context.Context, what string, deleteExpired func(context.Context, time.Time) (int64, error), now time.Time) {
+	n, err := deleteExpired(ctx, now)
+	if err != nil {
+		m.log(ctx).Error().Err(err).Msg("failed to prune " + what)
+	} else if n > 0 {
+		m.log(ctx).Debug().Int64("pruned", n).Msg("pruned expired " + what)
+	}
+}
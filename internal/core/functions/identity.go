@@ -0,0 +1,97 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"service-faas/pkg/idtoken"
+	"time"
+)
+
+// IdentityPublisher is implemented by orchestrators that can hand a rotating
+// identity token to a function's running workers.
+type IdentityPublisher interface {
+	PublishIdentity(ctx context.Context, functionID, token string) error
+}
+
+// WithIdentityIssuer enables per-function identity tokens signed by issuer.
+func WithIdentityIssuer(issuer *idtoken.Issuer) Option {
+	return func(m *Manager) { m.identity = issuer }
+}
+
+// IdentityKeys returns the JWKS document that verifies function identity
+// tokens, or false when identity tokens are disabled.
+func (m *Manager) IdentityKeys() ([]byte, bool, error) {
+	if m.identity == nil {
+		return nil, false, nil
+	}
+	jwks, err := m.identity.JWKS()
+	return jwks, true, err
+}
+
+// identitySubject is the SPIFFE-style ID a function authenticates as.
+func (m *Manager) identitySubject(fn *Function) string {
+	return fmt.Sprintf("spiffe://%s/function/%s", m.cfg.IdentityTrustDomain, fn.ID)
+}
+
+// publishIdentity issues a fresh token for fn and hands it to the
+// orchestrator. It is a no-op when identity tokens are disabled.
+func (m *Manager) publishIdentity(ctx context.Context, fn *Function) error {
+	if m.identity == nil {
+		return nil
+	}
+	publisher, ok := m.orchestrator.(IdentityPublisher)
+	if !ok {
+		return nil
+	}
+
+	claims := map[string]any{"function_id": fn.ID, "function_name": fn.FunctionName}
+	if fn.Tenant != "" {
+		claims["tenant"] = fn.Tenant
+	}
+	token, _, err := m.identity.Issue(m.identitySubject(fn), claims)
+	if err != nil {
+		return fmt.Errorf("issue identity token: %w", err)
+	}
+	if err := publisher.PublishIdentity(ctx, fn.ID, token); err != nil {
+		return fmt.Errorf("publish identity token: %w", err)
+	}
+	return nil
+}
+
+// RotateIdentityTokens re-issues identity tokens for all running functions at
+// half the token lifetime, so workers always hold a valid token. Only the
+// leader rotates. It blocks until ctx is cancelled.
+func (m *Manager) RotateIdentityTokens(ctx context.Context) {
+	if m.identity == nil {
+		return
+	}
+	if _, ok := m.orchestrator.(IdentityPublisher); !ok {
+		m.log(ctx).Warn().Str("deployment_env", string(m.cfg.DeploymentEnv)).Msg("orchestrator cannot deliver identity tokens, workers will not receive them")
+		return
+	}
+
+	ticker := time.NewTicker(m.identity.TTL() / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !m.IsLeader() {
+			continue
+		}
+
+		running, err := m.repo.FindByStatus(ctx, "running")
+		if err != nil {
+			m.log(ctx).Error().Err(err).Msg("could not query running functions for identity rotation")
+			continue
+		}
+		for _, fn := range running {
+			if err := m.publishIdentity(ctx, &fn); err != nil {
+				m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to rotate identity token")
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package functions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// CodeDrift reports a function whose stored code does not match the checksum
+// recorded when it was uploaded.
+type CodeDrift struct {
+	FunctionID string `json:"function_id"`
+	Expected   string `json:"expected_sha256"`
+	Actual     string `json:"actual_sha256,omitempty"`
+	Error      string `json:"error,omitempty"` // Set when the code could not be read
+}
+
+// codeChecksum hashes the function's stored handler code.
+func (m *Manager) codeChecksum(ctx context.Context, functionID string) (string, error) {
+	rc, err := m.code.Get(ctx, functionID)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("read handler code: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyCode checks the stored code against the recorded checksum before a
+// worker is deployed from it. Functions created before checksums were
+// recorded are not checked.
+func (m *Manager) verifyCode(ctx context.Context, fn *Function) error {
+	if fn.CodeSHA256 == "" {
+		return nil
+	}
+	sum, err := m.codeChecksum(ctx, fn.ID)
+	if err != nil {
+		return fmt.Errorf("checksum handler code: %w", err)
+	}
+	if sum != fn.CodeSHA256 {
+		return fmt.Errorf("handler code of function '%s' does not match its recorded checksum", fn.ID)
+	}
+	return nil
+}
+
+// CheckCodeIntegrity compares the stored code of every function with its
+// recorded checksum and returns the functions that drifted.
+func (m *Manager) CheckCodeIntegrity(ctx context.Context) ([]CodeDrift, error) {
+	fns, err := m.ListFunctions(ctx, ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	drifted := []CodeDrift{}
+	for _, fn := range fns {
+		if fn.CodeSHA256 == "" {
+			continue
+		}
+		sum, err := m.codeChecksum(ctx, fn.ID)
+		if err != nil {
+			drifted = append(drifted, CodeDrift{FunctionID: fn.ID, Expected: fn.CodeSHA256, Error: err.Error()})
+			continue
+		}
+		if sum != fn.CodeSHA256 {
+			drifted = append(drifted, CodeDrift{FunctionID: fn.ID, Expected: fn.CodeSHA256, Actual: sum})
+		}
+	}
+	if len(drifted) > 0 {
+		m.log(ctx).Warn().Int("functions", len(drifted)).Msg("function code drift detected")
+	}
+	return drifted, nil
+}
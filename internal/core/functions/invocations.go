@@ -0,0 +1,121 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Invocation statuses.
+const (
+	InvocationSucceeded = "succeeded"
+	InvocationFailed    = "failed"
+)
+
+// Invocation is the stored outcome of one execution. Inline results larger
+// than InvocationResultMaxBytes are dropped and Truncated is set.
+type Invocation struct {
+	ID         string `gorm:"primaryKey;size:64" json:"invocation_id"`
+	FunctionID string `gorm:"size:64;index" json:"function_id"`
+	// CodeSHA256 is the checksum of the code the function ran.
+	CodeSHA256 string `json:"code_sha256,omitempty"`
+	// ReplayOf is the invocation this one replayed, see ReplayInvocation.
+	ReplayOf string `gorm:"size:64" json:"replay_of,omitempty"`
+	// Payload is kept, encrypted at rest by the storage layer, when
+	// InvocationCapturePayloads is set; PayloadCaptured tells an empty
+	// payload from none.
+	Payload         string          `gorm:"type:text;serializer:encrypted" json:"-"`
+	PayloadCaptured bool            `json:"payload_captured,omitempty"`
+	Status          string          `json:"status"`
+	Result          json.RawMessage `gorm:"serializer:json" json:"result,omitempty" swaggertype:"object"`
+	ResultRef       *ResultRef      `gorm:"serializer:json" json:"result_ref,omitempty"`
+	Truncated       bool            `json:"truncated,omitempty"`
+	Error           string          `gorm:"type:text" json:"error,omitempty"`
+	Exception       *HandlerError   `gorm:"serializer:json" json:"exception,omitempty"`
+	ErrorClass      string          `json:"error_class,omitempty"`
+	Degraded        string          `json:"degraded,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	DurationMS      int64           `json:"duration_ms"`
+	ExpiresAt       time.Time       `gorm:"index" json:"expires_at"`
+}
+
+// InvocationError is a failed execution, carrying its invocation ID.
+type InvocationError struct {
+	InvocationID string
+	Err          error
+}
+
+func (e *InvocationError) Error() string { return e.Err.Error() }
+
+func (e *InvocationError) Unwrap() error { return e.Err }
+
+// InvocationRepository persists invocation outcomes. Get returns ErrNotFound
+// for unknown or expired invocations.
+type InvocationRepository interface {
+	Create(ctx context.Context, inv *Invocation) error
+	Get(ctx context.Context, id string) (*Invocation, error)
+	// DeleteExpired deletes invocations that expired before now.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// WithInvocationRepository enables storing invocation outcomes for
+// InvocationResultTTL.
+func WithInvocationRepository(repo InvocationRepository) Option {
+	return func(m *Manager) { m.invocations = repo }
+}
+
+// capturePayload keeps the payload with the invocation when payloads are
+// captured and it is not too large.
+func (m *Manager) capturePayload(inv *Invocation, payload string) {
+	if !m.cfg.InvocationCapturePayloads {
+		return
+	}
+	if limit := m.cfg.InvocationResultMaxBytes; limit > 0 && len(payload) > limit {
+		return
+	}
+	inv.Payload, inv.PayloadCaptured = payload, true
+}
+
+// recordInvocation stores the outcome of an execution of an existing
+// function, when invocation results are kept. Failures to store are logged.
+func (m *Manager) recordInvocation(ctx context.Context, inv *Invocation, result *ExecutionResult, err error) {
+	if m.invocations == nil || m.cfg.InvocationResultTTL <= 0 || errors.Is(err, ErrNotFound) {
+		return
+	}
+	inv.DurationMS = time.Since(inv.StartedAt).Milliseconds()
+	inv.ExpiresAt = time.Now().UTC().Add(m.cfg.InvocationResultTTL)
+	if err != nil {
+		inv.Status, inv.Error, inv.ErrorClass = InvocationFailed, err.Error(), ClassifyError(err)
+		var he *HandlerError
+		if errors.As(err, &he) {
+			inv.Exception = he
+		}
+	} else {
+		inv.Status, inv.ResultRef, inv.Degraded = InvocationSucceeded, result.ResultRef, result.Degraded
+		if limit := m.cfg.InvocationResultMaxBytes; limit > 0 && len(result.Result) > limit {
+			inv.Truncated = true
+		} else {
+			inv.Result = result.Result
+		}
+	}
+	if err := m.invocations.Create(context.WithoutCancel(ctx), inv); err != nil {
+		m.log(ctx).Error().Err(err).Str("invocation_id", inv.ID).Msg("failed to store invocation")
+	}
+}
+
+// GetInvocation returns the stored outcome of an execution.
+func (m *Manager) GetInvocation(ctx context.Context, id string) (*Invocation, error) {
+	if m.invocations == nil || m.cfg.InvocationResultTTL <= 0 {
+		return nil, fmt.Errorf("%w: invocation results are not kept", ErrNotConfigured)
+	}
+	inv, err := m.invocations.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("%w: invocation '%s'", ErrNotFound, id)
+	}
+	return inv, nil
+}
@@ -0,0 +1,192 @@
+package functions
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"service-faas/pkg/rand"
+	"strings"
+	"time"
+)
+
+// invokeTokenPrefix starts every invoke token, so leaked ones are easy to
+// recognize.
+const invokeTokenPrefix = "fit_"
+
+// InvokeToken lets a caller execute one function, and nothing else, until it
+// expires or is revoked. Only a hash of the token is stored; the token itself
+// is returned once, when it is issued.
+type InvokeToken struct {
+	ID          string     `gorm:"primaryKey;size:64" json:"id"`
+	FunctionID  string     `gorm:"size:191;index" json:"function_id"`
+	Description string     `json:"description,omitempty"`
+	Token       string     `gorm:"-" json:"token,omitempty"`
+	TokenHash   string     `gorm:"size:64;uniqueIndex" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// InvokeTokenRepository persists invoke tokens. Get and FindByHash return
+// ErrNotFound for unknown tokens.
+type InvokeTokenRepository interface {
+	Create(ctx context.Context, tok *InvokeToken) error
+	Get(ctx context.Context, id string) (*InvokeToken, error)
+	FindByHash(ctx context.Context, hash string) (*InvokeToken, error)
+	ListByFunction(ctx context.Context, functionID string) ([]InvokeToken, error)
+	// Update saves all fields of an existing token.
+	Update(ctx context.Context, tok *InvokeToken) error
+	DeleteByFunction(ctx context.Context, functionID string) error
+}
+
+// WithInvokeTokenRepository enables issuing invoke tokens.
+func WithInvokeTokenRepository(repo InvokeTokenRepository) Option {
+	return func(m *Manager) { m.invokeTokens = repo }
+}
+
+// Access is what a caller's bearer token grants: the whole API, or only
+// executing the function of an invoke token.
+type Access struct {
+	Management bool
+	FunctionID string
+	// Actor names the token the caller authenticated with: ActorAPIToken,
+	// or "invoke_token:" and the token's ID. It is empty when the API is
+	// open and nobody authenticates.
+	Actor string
+}
+
+// ActorAPIToken is the Actor of callers authenticated with the APIToken.
+const ActorAPIToken = "api_token"
+
+// CanInvoke reports whether the access covers executing a function.
+func (a Access) CanInvoke(functionID string) bool {
+	return a.Management || a.FunctionID == functionID
+}
+
+// Authenticate resolves a bearer token to the access it grants. Without an
+// APIToken the API is open and every caller may manage it. Otherwise the
+// token must be the APIToken, or an invoke token that has neither expired
+// nor been revoked.
+func (m *Manager) Authenticate(ctx context.Context, token string) (Access, error) {
+	apiToken := m.settings().APIToken
+	if apiToken == "" {
+		return Access{Management: true}, nil
+	}
+	if token == "" {
+		return Access{}, fmt.Errorf("%w: a bearer token is required", ErrUnauthorized)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) == 1 {
+		return Access{Management: true, Actor: ActorAPIToken}, nil
+	}
+	if m.invokeTokens == nil || !strings.HasPrefix(token, invokeTokenPrefix) {
+		return Access{}, fmt.Errorf("%w: invalid token", ErrUnauthorized)
+	}
+	tok, err := m.invokeTokens.FindByHash(ctx, hashInvokeToken(token))
+	if errors.Is(err, ErrNotFound) {
+		return Access{}, fmt.Errorf("%w: invalid token", ErrUnauthorized)
+	}
+	if err != nil {
+		return Access{}, fmt.Errorf("look up invoke token: %w", err)
+	}
+	switch {
+	case tok.RevokedAt != nil:
+		return Access{}, fmt.Errorf("%w: token '%s' was revoked", ErrUnauthorized, tok.ID)
+	case tok.ExpiresAt != nil && !time.Now().Before(*tok.ExpiresAt):
+		return Access{}, fmt.Errorf("%w: token '%s' expired", ErrUnauthorized, tok.ID)
+	}
+	return Access{FunctionID: tok.FunctionID, Actor: "invoke_token:" + tok.ID}, nil
+}
+
+// IssueInvokeToken creates a token that can only execute a function, valid
+// for ttl, or until it is revoked when ttl is zero. The returned token
+// carries its secret, which cannot be retrieved again.
+func (m *Manager) IssueInvokeToken(ctx context.Context, functionID, description string, ttl time.Duration) (*InvokeToken, error) {
+	if m.invokeTokens == nil {
+		return nil, fmt.Errorf("%w: invoke tokens", ErrNotConfigured)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("%w: token lifetime must not be negative", ErrInvalidArgument)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: function '%s' is deleted", ErrInvalidArgument, functionID)
+	}
+
+	secret := invokeTokenPrefix + rand.Password(40)
+	tok := &InvokeToken{
+		ID:          rand.ID16(),
+		FunctionID:  functionID,
+		Description: description,
+		TokenHash:   hashInvokeToken(secret),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if ttl > 0 {
+		expires := tok.CreatedAt.Add(ttl)
+		tok.ExpiresAt = &expires
+	}
+	if err := m.invokeTokens.Create(ctx, tok); err != nil {
+		return nil, fmt.Errorf("db create invoke token: %w", err)
+	}
+	m.log(ctx).Info().Str("function_id", functionID).Str("token_id", tok.ID).Msg("invoke token issued")
+	tok.Token = secret
+	return tok, nil
+}
+
+// InvokeTokens lists a function's invoke tokens, expired and revoked ones
+// included, without their secrets.
+func (m *Manager) InvokeTokens(ctx context.Context, functionID string) ([]InvokeToken, error) {
+	if m.invokeTokens == nil {
+		return nil, fmt.Errorf("%w: invoke tokens", ErrNotConfigured)
+	}
+	if _, err := m.repo.Get(ctx, functionID); err != nil {
+		return nil, err
+	}
+	return m.invokeTokens.ListByFunction(ctx, functionID)
+}
+
+// RevokeInvokeToken makes a function's invoke token unusable at once.
+// Revoking a revoked token changes nothing.
+func (m *Manager) RevokeInvokeToken(ctx context.Context, functionID, tokenID string) (*InvokeToken, error) {
+	if m.invokeTokens == nil {
+		return nil, fmt.Errorf("%w: invoke tokens", ErrNotConfigured)
+	}
+	tok, err := m.invokeTokens.Get(ctx, tokenID)
+	if err == nil && tok.FunctionID != functionID {
+		err = fmt.Errorf("%w: function '%s' has no invoke token '%s'", ErrNotFound, functionID, tokenID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tok.RevokedAt != nil {
+		return tok, nil
+	}
+	now := time.Now().UTC()
+	tok.RevokedAt = &now
+	if err := m.invokeTokens.Update(ctx, tok); err != nil {
+		return nil, fmt.Errorf("db update invoke token: %w", err)
+	}
+	m.log(ctx).Info().Str("function_id", functionID).Str("token_id", tok.ID).Msg("invoke token revoked")
+	return tok, nil
+}
+
+// deleteInvokeTokens removes the invoke tokens of a purged function, so they
+// cannot outlive it.
+func (m *Manager) deleteInvokeTokens(ctx context.Context, functionID string) {
+	if m.invokeTokens == nil {
+		return
+	}
+	if err := m.invokeTokens.DeleteByFunction(ctx, functionID); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", functionID).Msg("failed to delete invoke tokens of purged function")
+	}
+}
+
+func hashInvokeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"service-faas/internal/config"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// KubernetesWorker overrides, for one function, the service account and image
+// pull secrets its pods run with in kubernetes and knative mode. The secrets
+// and account must exist in the workers' namespace.
+type KubernetesWorker struct {
+	// ServiceAccount replaces KUBERNETES_SERVICE_ACCOUNT.
+	ServiceAccount string `json:"service_account,omitempty"`
+	// ImagePullSecrets replace KUBERNETES_PULL_SECRET. Secrets with tenant
+	// registry credentials are still added.
+	ImagePullSecrets []string `json:"image_pull_secrets,omitempty"`
+}
+
+func (m *Manager) validateKubernetesWorker(k *KubernetesWorker) error {
+	if k == nil {
+		return nil
+	}
+	if m.cfg.DeploymentEnv != config.EnvKubernetes && m.cfg.DeploymentEnv != config.EnvKnative {
+		return fmt.Errorf("%w: kubernetes settings are only supported in kubernetes and knative mode", ErrInvalidArgument)
+	}
+	names := k.ImagePullSecrets
+	if k.ServiceAccount != "" {
+		names = append([]string{k.ServiceAccount}, names...)
+	}
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("%w: kubernetes object name %q: %s", ErrInvalidArgument, name, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// SetKubernetesWorker replaces a function's Kubernetes overrides; nil
+// removes them. A running function's worker is restarted with them.
+func (m *Manager) SetKubernetesWorker(ctx context.Context, functionID string, k *KubernetesWorker) (*Function, error) {
+	if err := m.validateKubernetesWorker(k); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.Kubernetes = k
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update kubernetes settings: %w", err)
+	}
+	if fn.Status != "running" {
+		return fn, nil
+	}
+	if err := m.restartWorker(ctx, fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
@@ -0,0 +1,76 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ListFilter narrows ListFunctions. The zero value lists every function that
+// is not soft-deleted.
+type ListFilter struct {
+	Deleted bool              // List soft-deleted functions instead
+	Labels  map[string]string // Every label must be present with this value
+}
+
+func (f ListFilter) matches(fn *Function) bool {
+	if (fn.DeletedAt != nil) != f.Deleted {
+		return false
+	}
+	for k, v := range f.Labels {
+		if got, ok := fn.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabelSelector parses "key=value" requirements, as given in ?label=
+// query parameters.
+func ParseLabelSelector(reqs []string) (map[string]string, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	sel := make(map[string]string, len(reqs))
+	for _, req := range reqs {
+		k, v, ok := strings.Cut(req, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%w: label selector %q must be key=value", ErrInvalidArgument, req)
+		}
+		sel[k] = v
+	}
+	return sel, nil
+}
+
+// validateLabels applies the Kubernetes label syntax, since labels are copied
+// onto worker pods.
+func validateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("%w: label key %q: %s", ErrInvalidArgument, k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("%w: label %q value %q: %s", ErrInvalidArgument, k, v, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// SetLabels replaces the labels of a function. Workers pick up the new labels
+// the next time they are started.
+func (m *Manager) SetLabels(ctx context.Context, functionID string, labels map[string]string) (*Function, error) {
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.Labels = labels
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update labels: %w", err)
+	}
+	return fn, nil
+}
@@ -0,0 +1,78 @@
+package functions
+
+import (
+	"context"
+	"time"
+)
+
+// leaderLease names the lease held by the leading manager replica.
+const leaderLease = "manager"
+
+// LeaderLock is a lease shared by the manager replicas, so tasks that must
+// run once per deployment run on one replica only.
+type LeaderLock interface {
+	// TryAcquire takes the named lease for holder, or renews it when holder
+	// already has it, until ttl has passed. It reports whether holder holds
+	// the lease.
+	TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// Release gives the lease up if holder holds it.
+	Release(ctx context.Context, name, holder string) error
+}
+
+// WithLeaderLock runs the manager as one of several replicas: leader-only
+// tasks wait until this replica, named holder, holds the lease.
+func WithLeaderLock(lock LeaderLock, holder string) Option {
+	return func(m *Manager) {
+		m.leaderLock = lock
+		m.replicaID = holder
+	}
+}
+
+// IsLeader reports whether this replica runs the leader-only tasks. A manager
+// without a leader lock is always the leader.
+func (m *Manager) IsLeader() bool {
+	return m.leaderLock == nil || m.leading.Load()
+}
+
+// CampaignLeadership tries to take the leader lease once, then keeps
+// renewing or taking it every LeaderLeaseTTL/3 in the background until ctx
+// is done, when the lease is released. It reports whether this replica
+// leads after the first attempt, so startup tasks can be guarded by it.
+func (m *Manager) CampaignLeadership(ctx context.Context) bool {
+	if m.leaderLock == nil {
+		return true
+	}
+	m.renewLeadership(ctx)
+	go func() {
+		ticker := time.NewTicker(m.cfg.LeaderLeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if m.leading.Swap(false) {
+					if err := m.leaderLock.Release(context.WithoutCancel(ctx), leaderLease, m.replicaID); err != nil {
+						m.lg.Warn().Err(err).Msg("failed to release leader lease")
+					}
+				}
+				return
+			case <-ticker.C:
+				m.renewLeadership(ctx)
+			}
+		}
+	}()
+	return m.IsLeader()
+}
+
+// renewLeadership takes or renews the lease. When the lease cannot be
+// checked, leadership is given up, since another replica may take over once
+// the lease expires.
+func (m *Manager) renewLeadership(ctx context.Context) {
+	leading, err := m.leaderLock.TryAcquire(ctx, leaderLease, m.replicaID, m.cfg.LeaderLeaseTTL)
+	if err != nil {
+		m.lg.Error().Err(err).Msg("failed to renew leader lease")
+		leading = false
+	}
+	if was := m.leading.Swap(leading); was != leading {
+		m.lg.Info().Str("replica_id", m.replicaID).Bool("leader", leading).Msg("leadership changed")
+	}
+}
@@ -0,0 +1,40 @@
+package functions
+
+import "fmt"
+
+// MaxUploadBytes is the largest code upload request accepted; zero or less
+// means unlimited.
+func (m *Manager) MaxUploadBytes() int64 {
+	return m.cfg.MaxUploadBytes
+}
+
+// ImportMaxBytes is the largest catalog archive accepted by ImportCatalog,
+// uploaded or unpacked; zero or less means unlimited.
+func (m *Manager) ImportMaxBytes() int64 {
+	return m.cfg.ImportMaxBytes
+}
+
+// MaxPayloadBytes is the largest execute payload accepted for any function;
+// zero or less means unlimited.
+func (m *Manager) MaxPayloadBytes() int64 {
+	return m.cfg.MaxPayloadBytes
+}
+
+// payloadLimit is the payload limit of fn, zero when unlimited.
+func (m *Manager) payloadLimit(fn *Function) int64 {
+	limit := max(m.cfg.MaxPayloadBytes, 0)
+	if fn.MaxPayloadBytes > 0 && (limit == 0 || fn.MaxPayloadBytes < limit) {
+		limit = fn.MaxPayloadBytes
+	}
+	return limit
+}
+
+func (m *Manager) validateMaxPayloadBytes(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("%w: max_payload_bytes must not be negative", ErrInvalidArgument)
+	}
+	if global := m.cfg.MaxPayloadBytes; global > 0 && n > global {
+		return fmt.Errorf("%w: max_payload_bytes cannot exceed the manager's limit of %d bytes", ErrInvalidArgument, global)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxLogTail caps the earlier lines per worker instance a log request may
+// ask for.
+const MaxLogTail = 10000
+
+// WorkerLogReader is implemented by orchestrators that can read the output of
+// a function's workers.
+type WorkerLogReader interface {
+	// WorkerLogs calls emit with the log lines of each instance of the
+	// function's worker, possibly from several goroutines at once. With
+	// opts.Follow it keeps reading new lines until ctx is done or the
+	// instances stop. An error from emit stops it.
+	WorkerLogs(ctx context.Context, functionID string, opts LogOptions, emit func(LogLine) error) error
+}
+
+// LogOptions selects the log lines to read.
+type LogOptions struct {
+	// Tail is how many of the latest lines of each instance to start with;
+	// zero starts with none.
+	Tail int
+	// Since, when set, leaves out lines written before it.
+	Since time.Time
+	// Follow keeps streaming lines as they are written.
+	Follow bool
+}
+
+// LogLine is one line a worker instance wrote.
+type LogLine struct {
+	// Instance is the container or pod that wrote the line.
+	Instance string    `json:"instance"`
+	Time     time.Time `json:"time"`
+	// Stream is stdout or stderr, when the orchestrator tells them apart.
+	Stream string `json:"stream,omitempty"`
+	Line   string `json:"line"`
+}
+
+// FunctionLogs reads the log lines of a function's worker instances, the
+// latest opts.Tail of each and, with opts.Follow, new ones until ctx is done.
+// Lines of different instances interleave. Calls to emit are serialized; an
+// error from emit, e.g. because the caller went away, stops the logs.
+func (m *Manager) FunctionLogs(ctx context.Context, functionID string, opts LogOptions, emit func(LogLine) error) error {
+	reader, ok := m.orchestrator.(WorkerLogReader)
+	if !ok {
+		return fmt.Errorf("%w: worker logs for %s", ErrNotConfigured, m.cfg.DeploymentEnv)
+	}
+	if opts.Tail < 0 || opts.Tail > MaxLogTail {
+		return fmt.Errorf("%w: tail must be between 0 and %d", ErrInvalidArgument, MaxLogTail)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	err = reader.WorkerLogs(ctx, fn.ID, opts, func(line LogLine) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return emit(line)
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("read worker logs: %w", err)
+	}
+	return nil
+}
+
+// ParseLogLine splits a line read with timestamps, as Docker and Kubernetes
+// write them, into its RFC 3339 time and text. Lines without a time are
+// returned whole, with the zero time.
+func ParseLogLine(raw string) (time.Time, string) {
+	raw = strings.TrimSuffix(raw, "\r")
+	stamp, text, found := strings.Cut(raw, " ")
+	if !found {
+		stamp, text = raw, ""
+	}
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return time.Time{}, raw
+	}
+	return t.UTC(), text
+}
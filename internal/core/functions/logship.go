@@ -0,0 +1,235 @@
+package functions
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// logShipBatch and logShipFlush bound how many lines, and for how long,
+	// are collected before they are shipped together.
+	logShipBatch = 1000
+	logShipFlush = 2 * time.Second
+	// logShipSession is how long the logs of a function's workers are
+	// followed before following starts over, to pick up instances started
+	// since.
+	logShipSession = time.Minute
+	// logShipRescan is how often the running functions are listed, and how
+	// long a function without workers waits before its logs are read again.
+	logShipRescan = 15 * time.Second
+)
+
+// LogSink ships worker log lines to a log backend such as Loki or
+// Elasticsearch.
+type LogSink interface {
+	// ShipLogs sends a batch of lines; it must not keep entries after it
+	// returns.
+	ShipLogs(ctx context.Context, entries []LogEntry) error
+}
+
+// LogEntry is a worker log line and the function that wrote it.
+type LogEntry struct {
+	FunctionID string
+	// Name is the function's name, if it has one.
+	Name   string
+	Tenant string
+	LogLine
+}
+
+// WithLogSink enables shipping the logs of running functions, run by
+// ShipLogs.
+func WithLogSink(sink LogSink) Option {
+	return func(m *Manager) { m.logSink = sink }
+}
+
+// LogsURL links a function's logs in the log backend, or is empty when no
+// LogShippingQueryURL is configured.
+func (m *Manager) LogsURL(fn *Function) string {
+	if m.cfg.LogShippingQueryURL == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{function_id}", url.QueryEscape(fn.ID),
+		"{tenant}", url.QueryEscape(fn.Tenant),
+	).Replace(m.cfg.LogShippingQueryURL)
+}
+
+// ShipLogs follows the logs of every running function's workers and ships
+// them to the log sink until ctx is done. Only the leader ships; a replica
+// that takes over starts with the lines written from then on. Lines are
+// shipped at most once: a batch the sink fails to take is logged and
+// dropped, as are lines that find the queue full.
+func (m *Manager) ShipLogs(ctx context.Context) {
+	if m.logSink == nil {
+		return
+	}
+	reader, ok := m.orchestrator.(WorkerLogReader)
+	if !ok {
+		m.lg.Warn().Str("deployment_env", string(m.cfg.DeploymentEnv)).Msg("log shipping is not supported by the orchestrator")
+		return
+	}
+	s := &logShipper{
+		m:         m,
+		reader:    reader,
+		entries:   make(chan LogEntry, max(m.cfg.LogShippingQueueSize, 1)),
+		followers: map[string]context.CancelFunc{},
+	}
+	go s.send(ctx)
+
+	ticker := time.NewTicker(logShipRescan)
+	defer ticker.Stop()
+	for {
+		if m.IsLeader() {
+			s.follow(ctx)
+		} else {
+			s.stopAll()
+		}
+		select {
+		case <-ctx.Done():
+			s.stopAll()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type logShipper struct {
+	m         *Manager
+	reader    WorkerLogReader
+	entries   chan LogEntry
+	dropped   atomic.Int64
+	followers map[string]context.CancelFunc // function ID -> stops following it
+}
+
+// follow starts following the functions that run and stops following the
+// ones that no longer do.
+func (s *logShipper) follow(ctx context.Context) {
+	running, err := s.m.repo.FindByStatus(ctx, "running")
+	if err != nil {
+		s.m.lg.Error().Err(err).Msg("log shipping: could not query running functions")
+		return
+	}
+	ids := make(map[string]bool, len(running))
+	for _, fn := range running {
+		ids[fn.ID] = true
+		if _, ok := s.followers[fn.ID]; ok {
+			continue
+		}
+		fctx, cancel := context.WithCancel(ctx)
+		s.followers[fn.ID] = cancel
+		go s.followFunction(fctx, fn)
+	}
+	for id, cancel := range s.followers {
+		if !ids[id] {
+			cancel()
+			delete(s.followers, id)
+		}
+	}
+}
+
+func (s *logShipper) stopAll() {
+	for id, cancel := range s.followers {
+		cancel()
+		delete(s.followers, id)
+	}
+}
+
+// followFunction queues the lines of a function's workers, written from now
+// on, until ctx is done. Each session starts from the oldest of the last
+// lines its instances wrote, and lines an instance wrote up to its last
+// queued one are skipped, so restarting a session neither loses nor repeats
+// lines.
+func (s *logShipper) followFunction(ctx context.Context, fn Function) {
+	since := time.Now()
+	var mu sync.Mutex
+	last := map[string]time.Time{} // instance -> time of its last queued line
+	for ctx.Err() == nil {
+		sessionCtx, cancel := context.WithTimeout(ctx, logShipSession)
+		seen := map[string]bool{} // instances that wrote lines this session
+		opts := LogOptions{Tail: MaxLogTail, Since: since, Follow: true}
+		err := s.reader.WorkerLogs(sessionCtx, fn.ID, opts, func(line LogLine) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[line.Instance] = true
+			if line.Time.IsZero() {
+				line.Time = time.Now().UTC()
+			} else if !line.Time.After(last[line.Instance]) {
+				return nil
+			}
+			last[line.Instance] = line.Time
+			s.queue(LogEntry{FunctionID: fn.ID, Name: fn.Name, Tenant: fn.Tenant, LogLine: line})
+			return nil
+		})
+		ended := sessionCtx.Err() != nil
+		end := time.Now()
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.m.lg.Warn().Err(err).Str("function_id", fn.ID).Msg("log shipping: failed to read worker logs")
+		}
+
+		mu.Lock()
+		since = end
+		for instance, t := range last {
+			if !seen[instance] {
+				delete(last, instance)
+			} else if t.Before(since) {
+				since = t
+			}
+		}
+		mu.Unlock()
+		// The workers stopped, or have none yet, so wait for new ones.
+		if !ended {
+			select {
+			case <-ctx.Done():
+			case <-time.After(logShipRescan):
+			}
+		}
+	}
+}
+
+func (s *logShipper) queue(entry LogEntry) {
+	select {
+	case s.entries <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// send ships queued lines in batches until ctx is done.
+func (s *logShipper) send(ctx context.Context) {
+	batch := make([]LogEntry, 0, logShipBatch)
+	flush := func() {
+		if n := s.dropped.Swap(0); n > 0 {
+			s.m.lg.Warn().Int64("lines", n).Msg("log shipping queue full, lines dropped")
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.m.logSink.ShipLogs(ctx, batch); err != nil && ctx.Err() == nil {
+			s.m.lg.Error().Err(err).Int("lines", len(batch)).Msg("failed to ship worker logs")
+		}
+		batch = batch[:0]
+	}
+	ticker := time.NewTicker(logShipFlush)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= logShipBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
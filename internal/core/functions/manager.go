@@ -2,191 +2,1020 @@ package functions
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"service-faas/internal/config"
+	"service-faas/pkg/idtoken"
+	"service-faas/pkg/pki"
 	"service-faas/pkg/rand"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
-	"gorm.io/gorm"
 )
 
 type Manager struct {
-	db           *gorm.DB
-	orchestrator Orchestrator
-	cfg          config.Config
-	lg           zerolog.Logger
+	repo              FunctionRepository
+	creds             RegistryCredentialRepository
+	orchestrator      Orchestrator
+	results           ResultStore
+	identity          *idtoken.Issuer
+	owners            OwnerDirectory
+	breaker           circuitBreaker
+	limiter           concurrencyLimiter
+	admission         *admission
+	inflight          inflight
+	cache             ResponseCache
+	cacheMetrics      cacheMetrics
+	invocationMetrics invocationMetrics
+	idempotency       IdempotencyRepository
+	invocations       InvocationRepository
+	captures          CaptureRepository
+	gitDeployments    GitDeploymentRepository
+	promotions        EnvironmentPromotionRepository
+	invokeTokens      InvokeTokenRepository
+	policy            *CodePolicy
+	scanner           CodeScanner
+	digests           DigestResolver
+	git               GitFetcher
+	runtimes          *RuntimeCatalog
+	code              CodeStore
+	usage             *usageMeter
+	usageSink         UsageSink
+	webhooks          WebhookRepository
+	webhookSender     WebhookSender
+	webhookRetries    chan *webhookDelivery
+	events            chan *Event
+	publisher         EventPublisher
+	busEvents         chan *Event
+	logSink           LogSink
+	leaderLock        LeaderLock
+	replicaID         string
+	cfg               config.Config
+	live              atomic.Pointer[config.Config] // cfg with reloaded hot settings
+	configSource      func() (config.Config, error)
+	reloadMu          sync.Mutex
+	gitPushMu         sync.Mutex // held while a push is rolled out
+	namesMu           sync.Mutex // held while a named function is created or applied, or a domain bound
+	envsMu            sync.Mutex // held while an environment is deployed or removed
+	lg                zerolog.Logger
+	startedAt         time.Time
+
+	workerCA       *pki.CA
+	workerHTTP     *http.Client // shared by all functions without worker mTLS
+	workerClients  sync.Map     // function ID -> *http.Client
+	schemas        sync.Map     // function ID -> *compiledSchema
+	builds         sync.Map     // function ID -> struct{} while its image builds
+	syncs          sync.Map     // function ID -> struct{} while it is synced from git
+	missingWorkers sync.Map     // function ID -> container ID found missing by the last reconcile
+	coldWorkers    sync.Map     // function ID -> container ID of a worker started and not called yet
+	balancers      sync.Map     // function ID -> *replicaBalancer
+	domains        domainCache
+	clientCertMu   sync.Mutex
+	clientCert     *tls.Certificate
+	leading        atomic.Bool
 }
 
-func NewManager(db *gorm.DB, orch Orchestrator, cfg config.Config, lg zerolog.Logger) *Manager {
-	return &Manager{
-		db:           db,
+// Option configures optional Manager collaborators.
+type Option func(*Manager)
+
+// WithResultStore enables offloading of results larger than the configured
+// threshold to the given store.
+func WithResultStore(store ResultStore) Option {
+	return func(m *Manager) { m.results = store }
+}
+
+func NewManager(repo FunctionRepository, creds RegistryCredentialRepository, orch Orchestrator, cfg config.Config, lg zerolog.Logger, opts ...Option) *Manager {
+	m := &Manager{
+		repo:         repo,
+		creds:        creds,
 		orchestrator: orch,
 		cfg:          cfg,
 		lg:           lg.With().Str("component", "function-manager").Logger(),
+		startedAt:    time.Now().UTC(),
+	}
+	m.admission = newAdmission(cfg.MaxInFlightExecutions, cfg.ExecutionQueueSize, cfg.ExecutionQueueTimeout)
+	m.live.Store(&cfg)
+	m.workerHTTP = &http.Client{Transport: m.newWorkerTransport()}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
-func (m *Manager) AddFunction(ctx context.Context, functionName string, code io.Reader) (*Function, error) {
-	funcID := rand.ID16()
-	codeDir := filepath.Join(m.cfg.FunctionStorageDir, funcID)
-	if err := os.MkdirAll(codeDir, 0755); err != nil {
-		return nil, fmt.Errorf("create function dir: %w", err)
+func (m *Manager) AddFunction(ctx context.Context, functionName string, code io.Reader, opts FunctionOptions) (*Function, error) {
+	if opts.Name != "" || opts.Domain != "" {
+		m.namesMu.Lock()
+		defer m.namesMu.Unlock()
 	}
-
-	codeFilePath := filepath.Join(codeDir, "handler.py")
-	file, err := os.Create(codeFilePath)
+	fn, err := m.createFunction(ctx, rand.ID16(), functionName, code, opts, true)
 	if err != nil {
-		return nil, fmt.Errorf("create handler file: %w", err)
+		return nil, err
+	}
+	if err := m.launch(ctx, fn); err != nil {
+		return nil, err
 	}
-	defer file.Close()
-	if _, err := io.Copy(file, code); err != nil {
-		return nil, fmt.Errorf("save handler code: %w", err)
+	return fn, nil
+}
+
+// createFunction validates, stores and records a new function under the
+// given ID. Unless deploy is set, it is recorded as stopped; otherwise launch
+// starts it. When opts.Git is set and no code is given, the code is fetched
+// from the repository. namesMu must be held when opts.Name or opts.Domain is
+// set.
+func (m *Manager) createFunction(ctx context.Context, funcID, functionName string, code io.Reader, opts FunctionOptions, deploy bool) (*Function, error) {
+	if err := m.validateOptions(ctx, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Name != "" {
+		if err := m.checkNameFree(ctx, opts.Name); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Domain != "" {
+		if err := m.checkDomainFree(ctx, opts.Domain, funcID); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	imageDigest := opts.ImageDigest
+	if imageDigest == "" {
+		if imageDigest, err = m.resolveImageDigest(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Git != nil && code == nil {
+		var release func()
+		if code, release, err = m.openGitCode(ctx, &opts); err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	code, finishScan := m.startScan(ctx, code)
+	stored, err := m.storeCode(ctx, funcID, code, opts)
+	verdict, err := finishScan(err)
+	if err != nil {
+		_ = m.code.Delete(ctx, funcID)
+		return nil, fmt.Errorf("store handler code: %w", err)
 	}
 
 	fn := &Function{
-		ID:            funcID,
-		FunctionName:  functionName,
-		HandlerPath:   fmt.Sprintf("function.handler.%s", functionName),
-		CodePath:      codeDir,
-		ContainerName: "faas-worker-" + funcID,
-		Status:        "creating",
-		CreatedAt:     time.Now().UTC(),
+		ID:              funcID,
+		Name:            opts.Name,
+		Public:          opts.Public,
+		Domain:          opts.Domain,
+		CORS:            opts.CORS,
+		ParentID:        opts.ParentID,
+		Environment:     opts.Environment,
+		FunctionName:    functionName,
+		HandlerPath:     fmt.Sprintf("function.handler.%s", functionName),
+		CodePath:        stored.Path,
+		CodeSHA256:      stored.SHA256,
+		ContainerName:   "faas-worker-" + funcID,
+		Status:          "creating",
+		Tenant:          opts.Tenant,
+		Owner:           opts.Owner,
+		WorkerImage:     opts.WorkerImage,
+		Runtime:         opts.Runtime,
+		ImageDigest:     imageDigest,
+		PreHook:         opts.PreHook,
+		PostHook:        opts.PostHook,
+		Exposure:        opts.Exposure,
+		Fallback:        opts.Fallback,
+		Labels:          opts.Labels,
+		Description:     opts.Description,
+		EnvVars:         opts.EnvVars,
+		MaxConcurrency:  opts.MaxConcurrency,
+		MaxPayloadBytes: opts.MaxPayloadBytes,
+		PayloadSchema:   opts.PayloadSchema,
+		CacheTTLSeconds: opts.CacheTTLSeconds,
+		TimeoutSeconds:  opts.TimeoutSeconds,
+		Replicas:        opts.Replicas,
+		Affinity:        opts.Affinity,
+		Kubernetes:      opts.Kubernetes,
+		Warmup:          opts.Warmup,
+		PolicyFindings:  stored.PolicyFindings,
+		Git:             opts.Git,
+		GitToken:        opts.GitAuth.Token,
+		GitDeployKey:    opts.GitAuth.DeployKey,
+		Promotion:       opts.Promotion,
+		CreatedAt:       time.Now().UTC(),
+	}
+	if !verdict.Clean {
+		return nil, m.quarantine(ctx, fn, verdict.Threat)
+	}
+	if len(fn.PolicyFindings) > 0 && m.policy.rejects() {
+		_ = m.code.Delete(ctx, funcID)
+		return nil, &PolicyError{Findings: fn.PolicyFindings}
+	}
+	if m.cfg.CodeCheckOnUpload {
+		if err := m.checkStoredCode(ctx, funcID, functionName, opts); err != nil {
+			_ = m.code.Delete(ctx, funcID)
+			return nil, err
+		}
+	}
+	if fn.DependencyLockSHA256, err = m.lockDependencies(ctx, funcID, stored, opts); err != nil {
+		_ = m.code.Delete(ctx, funcID)
+		return nil, err
+	}
+
+	if len(fn.PolicyFindings) > 0 {
+		m.log(ctx).Warn().Str("function_id", funcID).Strs("findings", fn.PolicyFindings).Msg("function code flagged by code policy")
 	}
 
-	if err := m.db.Create(fn).Error; err != nil {
+	if _, ok := m.imageBuilder(); ok {
+		fn.Status = StatusBuilding
+	}
+	if !deploy {
+		fn.Status = StatusStopped
+	}
+	if err := m.repo.Create(ctx, fn); err != nil {
 		return nil, fmt.Errorf("db create function record: %w", err)
 	}
+	if fn.Domain != "" {
+		m.domains.forget()
+		if err := m.routeDomain(ctx, fn.ID, fn.Domain); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Str("domain", fn.Domain).Msg("failed to route function domain")
+		}
+	}
+	m.emitFunctionEvent(ctx, EventFunctionCreated, fn, nil)
+	return fn, nil
+}
 
-	runResult, err := m.orchestrator.RunWorker(ctx, fn.ID, fn.CodePath, fn.HandlerPath)
+// validateOptions checks the settings of a new function, and selects its
+// runtime.
+func (m *Manager) validateOptions(ctx context.Context, opts *FunctionOptions) error {
+	if err := m.validateHook(ctx, opts.PreHook); err != nil {
+		return fmt.Errorf("invalid pre-invoke hook: %w", err)
+	}
+	if err := m.validateHook(ctx, opts.PostHook); err != nil {
+		return fmt.Errorf("invalid post-invoke hook: %w", err)
+	}
+	if opts.WorkerImage != "" {
+		if err := m.validateWorkerImage(opts.WorkerImage); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+	}
+	if opts.Git != nil {
+		if err := validateGitSource(opts.Git, opts.GitAuth); err != nil {
+			return err
+		}
+		if opts.ExpectedSHA256 != "" {
+			return fmt.Errorf("%w: an upload checksum does not apply to code from git", ErrInvalidArgument)
+		}
+	}
+	if err := m.selectRuntime(opts); err != nil {
+		return err
+	}
+	if err := m.validateExposure(opts.Exposure); err != nil {
+		return err
+	}
+	if err := m.validateFallback(ctx, opts.Fallback); err != nil {
+		return err
+	}
+	if opts.Public && opts.Name == "" {
+		return fmt.Errorf("%w: only a function with a name can be public", ErrInvalidArgument)
+	}
+	if opts.Domain = normalizeDomain(opts.Domain); opts.Domain != "" {
+		if err := validateDomain(opts.Domain); err != nil {
+			return err
+		}
+	}
+	if err := validateCORS(opts.CORS); err != nil {
+		return err
+	}
+	if err := validateLabels(opts.Labels); err != nil {
+		return err
+	}
+	if err := validateEnvVars(opts.EnvVars); err != nil {
+		return err
+	}
+	if err := validateMaxConcurrency(opts.MaxConcurrency); err != nil {
+		return err
+	}
+	if err := m.validateMaxPayloadBytes(opts.MaxPayloadBytes); err != nil {
+		return err
+	}
+	if err := m.validateReplicas(opts.Replicas); err != nil {
+		return err
+	}
+	if err := m.validateAffinity(opts.Affinity); err != nil {
+		return err
+	}
+	if err := m.validateKubernetesWorker(opts.Kubernetes); err != nil {
+		return err
+	}
+	if opts.CacheTTLSeconds < 0 {
+		return fmt.Errorf("%w: cache ttl must not be negative", ErrInvalidArgument)
+	}
+	if err := validateTimeout(opts.TimeoutSeconds); err != nil {
+		return err
+	}
+	if err := m.validateWarmup(opts.Warmup); err != nil {
+		return err
+	}
+	if opts.PayloadSchema = normalizeSchema(opts.PayloadSchema); opts.PayloadSchema != nil {
+		if _, err := compileSchema(opts.PayloadSchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// launch starts the worker of a function createFunction recorded, or with
+// image builds its first build, after which the worker is started.
+func (m *Manager) launch(ctx context.Context, fn *Function) error {
+	if fn.Status == StatusBuilding {
+		m.startBuild(*fn)
+		fn.BuildStatus = BuildRunning
+		return nil
+	}
+	return m.deploy(ctx, fn)
+}
+
+// deploy starts the function's worker and records its details. On failure
+// the function is left in the "error" status. Either way a lifecycle event
+// is emitted.
+func (m *Manager) deploy(ctx context.Context, fn *Function) (err error) {
+	defer func() { m.emitDeployOutcome(ctx, fn, err) }()
+	// A deploy replaces any restart the reconciler was retrying.
+	fn.RestartAttempts = 0
+	fn.NextRestartAt = nil
+	if fn.BlockedReason != "" {
+		fn.Status = StatusBlocked
+		m.repo.Update(ctx, fn)
+		return fmt.Errorf("%w: function '%s': %s", ErrCodeBlocked, fn.ID, fn.BlockedReason)
+	}
+
+	spec, err := m.workerSpec(ctx, fn)
 	if err != nil {
-		m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to start container, rolling back")
 		fn.Status = "error"
-		m.db.Save(fn)
-		return nil, fmt.Errorf("start worker container: %w", err)
+		m.repo.Update(ctx, fn)
+		return fmt.Errorf("build worker spec: %w", err)
+	}
+
+	if err := m.verifyCode(ctx, fn); err != nil {
+		fn.Status = "error"
+		m.repo.Update(ctx, fn)
+		return err
+	}
+
+	if err := m.publishIdentity(ctx, fn); err != nil {
+		fn.Status = "error"
+		m.repo.Update(ctx, fn)
+		return err
+	}
+
+	spec.Pulling = func() {
+		fn.Status = StatusPulling
+		if err := m.repo.Update(ctx, fn); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record")
+		}
+	}
+	runResult, err := m.orchestrator.RunWorker(ctx, spec)
+	if err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to start container, rolling back")
+		fn.Status = "error"
+		if reason := pullFailure(err); reason != "" {
+			fn.StatusReason = reason
+		}
+		m.repo.Update(ctx, fn)
+		return fmt.Errorf("start worker container: %w", err)
 	}
 
 	fn.ContainerID = runResult.ContainerID
 	fn.HostPort = runResult.HostPort
+	fn.Endpoint = runResult.Endpoint
+	fn.Endpoints = runResult.Endpoints
+	fn.PublicURL = runResult.PublicURL
+	now := time.Now().UTC()
 	fn.Status = "running"
-	if err := m.db.Save(fn).Error; err != nil {
-		m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to save container details to db")
+	fn.StatusReason = ""
+	fn.DeployedAt = &now
+	if err := m.repo.Update(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to save container details to db")
 		_ = m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID)
+		return err
+	}
+	m.markCold(fn)
+	m.warmAfterDeploy(fn)
+	return nil
+}
+
+// ExecuteFunction runs a function with the payload under a new invocation
+// ID. Errors are returned as *InvocationError carrying that ID.
+func (m *Manager) ExecuteFunction(ctx context.Context, functionID, payload string) (*ExecutionResult, error) {
+	return m.executeInvocation(ctx, &Invocation{ID: rand.ID16(), FunctionID: functionID}, payload)
+}
+
+// executeInvocation runs inv's function with the payload and records the
+// outcome as inv.
+func (m *Manager) executeInvocation(ctx context.Context, inv *Invocation, payload string) (*ExecutionResult, error) {
+	if err := m.inflight.start(inv.FunctionID); err != nil {
 		return nil, err
 	}
+	defer m.inflight.finish(inv.FunctionID)
+	inv.StartedAt = time.Now().UTC()
+	m.capturePayload(inv, payload)
+	fn, result, err := m.execute(ctx, inv.FunctionID, payload)
+	if fn != nil {
+		inv.CodeSHA256 = fn.CodeSHA256
+	}
+	m.recordInvocation(ctx, inv, result, err)
+	if fn != nil {
+		m.captureExecution(ctx, fn, inv, payload, result, err)
+	}
+	if fn != nil {
+		m.emitInvocationEvent(ctx, fn, inv, err)
+	}
+	if err != nil {
+		return nil, &InvocationError{InvocationID: inv.ID, Err: err}
+	}
+	result.InvocationID = inv.ID
+	return result, nil
+}
 
-	return fn, nil
+// execute runs one execution. It returns the function too, or nil when the
+// execution was rejected before the function was looked up.
+func (m *Manager) execute(ctx context.Context, functionID, payload string) (*Function, *ExecutionResult, error) {
+	// One admission covers the hooks and fallback of the execution too.
+	release, err := m.admission.admit(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit := m.payloadLimit(fn); limit > 0 && int64(len(payload)) > limit {
+		return fn, nil, fmt.Errorf("%w: function '%s' accepts at most %d bytes", ErrPayloadTooLarge, functionID, limit)
+	}
+	if err := m.validatePayload(fn, payload); err != nil {
+		return fn, nil, err
+	}
+
+	payload, err = m.runPreHook(ctx, fn, payload)
+	if err != nil {
+		return fn, nil, err
+	}
+
+	result, degraded, cached, err := m.cachedInvoke(ctx, fn, payload)
+	if err != nil {
+		return fn, nil, err
+	}
+
+	if err := m.runPostHook(ctx, fn, result); err != nil {
+		return fn, nil, err
+	}
+
+	if m.results != nil && m.cfg.ResultOffloadThreshold > 0 && len(result) > m.cfg.ResultOffloadThreshold {
+		key := fmt.Sprintf("%s/%s.json", functionID, rand.ID16())
+		ref, err := m.results.Put(ctx, key, result)
+		if err != nil {
+			return fn, nil, fmt.Errorf("offload result: %w", err)
+		}
+		m.log(ctx).Info().Str("function_id", functionID).Int("size", ref.Size).Msg("result offloaded to storage")
+		return fn, &ExecutionResult{ResultRef: ref, Degraded: degraded, Cached: cached}, nil
+	}
+
+	return fn, &ExecutionResult{Result: result, Degraded: degraded, Cached: cached}, nil
 }
 
-func (m *Manager) ExecuteFunction(ctx context.Context, functionID, payload string) (json.RawMessage, error) {
-	var fn Function
-	if err := m.db.First(&fn, "id = ?", functionID).Error; err != nil {
-		return nil, fmt.Errorf("function '%s' not found", functionID)
+// validateExposure checks a requested exposure. Routes are only created by the
+// Kubernetes orchestrator.
+func (m *Manager) validateExposure(exp *Exposure) error {
+	if exp == nil {
+		return nil
+	}
+	if m.cfg.DeploymentEnv != config.EnvKubernetes {
+		return fmt.Errorf("%w: exposure is only supported in kubernetes mode", ErrInvalidArgument)
+	}
+	if m.workerCA != nil {
+		// External callers have no client certificate the worker would accept.
+		return fmt.Errorf("%w: exposure is not available when worker mTLS is enabled", ErrInvalidArgument)
+	}
+	if exp.Kind != ExposeIngress && exp.Kind != ExposeHTTPRoute {
+		return fmt.Errorf("%w: unknown exposure kind %q", ErrInvalidArgument, exp.Kind)
 	}
+	if exp.Path != "" && !strings.HasPrefix(exp.Path, "/") {
+		return fmt.Errorf("%w: exposure path must start with '/'", ErrInvalidArgument)
+	}
+	return nil
+}
 
+// invoke sends the payload to the function's worker and returns the raw result.
+func (m *Manager) invoke(ctx context.Context, fn *Function, payload string) (json.RawMessage, error) {
 	if fn.Status != "running" || fn.HostPort == 0 {
-		return nil, fmt.Errorf("function '%s' is not in a running state", functionID)
+		return nil, fmt.Errorf("%w: '%s' is %s", ErrFunctionNotRunning, fn.ID, fn.Status)
 	}
 
-	// Use Kubernetes service DNS name instead of localhost
-	workerServiceName := fmt.Sprintf("service-%s", functionID)
-	workerURL := fmt.Sprintf("http://%s.scadable-faas.svc.cluster.local:80", workerServiceName)
+	if fn.Endpoint == "" {
+		return nil, fmt.Errorf("%w: '%s' has no worker endpoint", ErrFunctionNotRunning, fn.ID)
+	}
+	client, err := m.workerClient(fn.ID)
+	if err != nil {
+		return nil, err
+	}
+	release, err := m.limiter.acquire(ctx, fn.ID, fn.MaxConcurrency, m.settings().ConcurrencyQueueTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if timeout := callTimeout(ctx, fn); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cold := m.takeCold(fn)
+	endpoint, done := m.pickEndpoint(ctx, fn, executionAffinityKey(ctx, fn, payload))
+	start := time.Now()
+	result, workerCold, err := callWorker(ctx, client, endpoint, payload)
+	done(err)
+	if err != nil && len(fn.Endpoints) > 1 && notSent(err) {
+		// The replica could not be reached, so it never got the call and
+		// another one can take it; the first is ejected by now.
+		endpoint, done = m.pickEndpoint(ctx, fn, executionAffinityKey(ctx, fn, payload))
+		result, workerCold, err = callWorker(ctx, client, endpoint, payload)
+		done(err)
+	}
+	cold = cold || workerCold
+	m.recordUsage(fn.ID, start, err != nil, cold)
+	m.invocationMetrics.record(fn.ID, start, err, cold)
+	if cold {
+		m.log(ctx).Info().Str("function_id", fn.ID).Dur("duration", time.Since(start)).Msg("cold start")
+	}
+	return result, err
+}
+
+// notSent reports whether a failed worker call failed to connect, so the
+// worker never got it and it is safe to send again.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// postPayload calls an endpoint speaking the worker protocol: a JSON body of
+// {"payload": "..."} answered with {"result": ...}. The request ID of ctx is
+// forwarded in the X-Request-ID header.
+func postPayload(ctx context.Context, client *http.Client, url, payload string) (json.RawMessage, error) {
+	result, _, err := callWorker(ctx, client, url, payload)
+	return result, err
+}
+
+// callWorker is postPayload that also reports whether the worker flagged the
+// call as its first with WorkerColdStartHeader.
+func callWorker(ctx context.Context, client *http.Client, url, payload string) (json.RawMessage, bool, error) {
 	reqBody := fmt.Sprintf(`{"payload": %q}`, payload)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", workerURL, strings.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, false, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("execute request to worker: %w", err)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, false, fmt.Errorf("%w: %w", ErrWorkerTimeout, err)
+		}
+		return nil, false, fmt.Errorf("%w: execute request to worker: %w", ErrWorkerUnavailable, err)
 	}
 	defer resp.Body.Close()
+	cold := resp.Header.Get(WorkerColdStartHeader) == "true"
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read worker response: %w", err)
+		return nil, false, fmt.Errorf("read worker response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("worker returned non-200 status: %s - %s", resp.Status, string(bodyBytes))
+		if he := parseHandlerError(bodyBytes); he != nil {
+			return nil, cold, he
+		}
+		return nil, cold, fmt.Errorf("%w: worker returned non-200 status: %s - %s", ErrWorkerFailed, resp.Status, string(bodyBytes))
 	}
 
 	var result struct {
 		Result json.RawMessage `json:"result"`
 	}
 	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return nil, fmt.Errorf("unmarshal worker response: %w", err)
+		return nil, cold, fmt.Errorf("%w: unmarshal worker response: %w", ErrWorkerFailed, err)
 	}
+	return result.Result, cold, nil
+}
 
-	return result.Result, nil
+// OpenResult returns the content of a previously offloaded result.
+func (m *Manager) OpenResult(ctx context.Context, key string) (io.ReadCloser, error) {
+	if m.results == nil {
+		return nil, fmt.Errorf("result storage is not configured")
+	}
+	return m.results.Get(ctx, key)
 }
 
-func (m *Manager) ListFunctions() ([]Function, error) {
-	var functions []Function
-	if err := m.db.Find(&functions).Error; err != nil {
+// GetFunction returns a function. For a running function whose orchestrator
+// reports worker state (see WorkerInspector), Worker holds the live replica
+// counts; it is left out when the orchestrator cannot be reached.
+func (m *Manager) GetFunction(ctx context.Context, functionID string) (*Function, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
 		return nil, err
 	}
-	return functions, nil
+	inspector, ok := m.orchestrator.(WorkerInspector)
+	if !ok || fn.Status != "running" {
+		return fn, nil
+	}
+	state, err := inspector.InspectWorker(ctx, fn.ID)
+	if err != nil {
+		m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("failed to inspect worker")
+		return fn, nil
+	}
+	fn.Worker = &WorkerStatus{}
+	if state != nil {
+		fn.Worker = &state.Status
+	}
+	return fn, nil
+}
+
+// ListFunctions returns the functions matching the filter.
+func (m *Manager) ListFunctions(ctx context.Context, filter ListFilter) ([]Function, error) {
+	all, err := m.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fns := []Function{}
+	for _, fn := range all {
+		if filter.matches(&fn) {
+			fns = append(fns, fn)
+		}
+	}
+	return fns, nil
 }
 
+// RemoveFunction soft-deletes a function: its worker is stopped, while the
+// record and code are kept for RestoreFunction. Removing a deleted function
+// is a no-op.
 func (m *Manager) RemoveFunction(ctx context.Context, functionID string) error {
-	var fn Function
-	if err := m.db.First(&fn, "id = ?", functionID).Error; err != nil {
-		return fmt.Errorf("function '%s' not found", functionID)
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return err
+	}
+	if fn.DeletedAt != nil {
+		return nil
+	}
+
+	if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
+		m.log(ctx).Warn().Err(err).Str("function_id", functionID).Msg("failed to stop container, proceeding with delete")
+	}
+	m.forgetFunction(ctx, functionID)
+
+	now := time.Now().UTC()
+	fn.DeletedAt = &now
+	fn.Status = StatusDeleted
+	fn.ContainerID = ""
+	fn.HostPort = 0
+	fn.Endpoint = ""
+	fn.Endpoints = nil
+	fn.PublicURL = ""
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return fmt.Errorf("db mark function deleted: %w", err)
+	}
+	m.emitFunctionEvent(ctx, EventFunctionDeleted, fn, nil)
+
+	m.log(ctx).Info().Str("function_id", functionID).Msg("function deleted")
+	m.removeEnvironments(ctx, fn, m.RemoveFunction)
+	return nil
+}
+
+// forgetFunction drops what the manager keeps in memory about a function
+// whose worker is torn down: pooled connections, limits, compiled schemas,
+// metrics, circuit and replica state, and its cached responses. A function
+// started again, or created again under the same ID, starts afresh.
+func (m *Manager) forgetFunction(ctx context.Context, functionID string) {
+	m.workerClients.Delete(functionID)
+	m.limiter.forget(functionID)
+	m.schemas.Delete(functionID)
+	m.cacheMetrics.counters.Delete(functionID)
+	m.invocationMetrics.functions.Delete(functionID)
+	m.missingWorkers.Delete(functionID)
+	m.coldWorkers.Delete(functionID)
+	m.balancers.Delete(functionID)
+	m.breaker.forget(functionID)
+	if m.cache != nil {
+		if _, err := m.cache.Invalidate(ctx, functionID); err != nil {
+			m.log(ctx).Warn().Err(err).Str("function_id", functionID).Msg("failed to drop cached responses")
+		}
+	}
+}
+
+// removeEnvironments removes or purges the environments of a function along
+// with it, logging failures.
+func (m *Manager) removeEnvironments(ctx context.Context, fn *Function, remove func(context.Context, string) error) {
+	if fn.ParentID != "" {
+		return
+	}
+	envs, err := m.environmentsOf(ctx, fn.ID)
+	if err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to list environments")
+		return
+	}
+	for _, env := range envs {
+		if err := remove(ctx, env.ID); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", env.ID).Str("environment", env.Environment).Msg("failed to remove environment")
+		}
+	}
+}
+
+// RestoreFunction redeploys a soft-deleted function.
+func (m *Manager) RestoreFunction(ctx context.Context, functionID string) (*Function, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.DeletedAt == nil {
+		return nil, fmt.Errorf("%w: function '%s' is not deleted", ErrInvalidArgument, functionID)
+	}
+
+	fn.DeletedAt = nil
+	fn.Status = "creating"
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db restore function: %w", err)
+	}
+	if err := m.deploy(ctx, fn); err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info().Str("function_id", functionID).Msg("function restored")
+	return fn, nil
+}
+
+// StopFunction takes a function offline without deleting it: its worker is
+// stopped, while the record and code are kept for StartFunction. Stopped
+// functions stay stopped across manager restarts. Stopping a stopped function
+// is a no-op.
+func (m *Manager) StopFunction(ctx context.Context, functionID string) (*Function, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Status == StatusStopped {
+		return fn, nil
+	}
+	if fn.DeletedAt != nil || fn.BlockedReason != "" || fn.BuildStatus == BuildRunning {
+		return nil, fmt.Errorf("%w: function '%s' is %s", ErrInvalidArgument, functionID, fn.Status)
 	}
 
 	if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
-		m.lg.Warn().Err(err).Str("function_id", functionID).Msg("failed to stop container, proceeding with cleanup")
+		return nil, fmt.Errorf("stop worker: %w", err)
 	}
+	m.forgetFunction(ctx, functionID)
 
-	if err := os.RemoveAll(fn.CodePath); err != nil {
-		m.lg.Error().Err(err).Str("path", fn.CodePath).Msg("failed to delete function code directory")
+	fn.Status = StatusStopped
+	fn.StatusReason = ""
+	fn.RestartAttempts = 0
+	fn.NextRestartAt = nil
+	fn.ContainerID = ""
+	fn.HostPort = 0
+	fn.Endpoint = ""
+	fn.Endpoints = nil
+	fn.PublicURL = ""
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db mark function stopped: %w", err)
 	}
+	m.emitFunctionEvent(ctx, EventFunctionStopped, fn, nil)
 
-	if err := m.db.Delete(&fn).Error; err != nil {
+	m.log(ctx).Info().Str("function_id", functionID).Msg("function stopped")
+	return fn, nil
+}
+
+// StartFunction redeploys a stopped function from its stored code.
+func (m *Manager) StartFunction(ctx context.Context, functionID string) (*Function, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Status != StatusStopped {
+		return nil, fmt.Errorf("%w: function '%s' is not stopped", ErrInvalidArgument, functionID)
+	}
+
+	fn.Status = "creating"
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db start function: %w", err)
+	}
+	if err := m.deploy(ctx, fn); err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info().Str("function_id", functionID).Msg("function started")
+	return fn, nil
+}
+
+// PurgeFunction permanently removes a function, deleted or not, together
+// with its code.
+func (m *Manager) PurgeFunction(ctx context.Context, functionID string) error {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return err
+	}
+
+	if fn.DeletedAt == nil {
+		if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
+			m.log(ctx).Warn().Err(err).Str("function_id", functionID).Msg("failed to stop container, proceeding with cleanup")
+		}
+	}
+
+	if err := m.code.Delete(ctx, fn.ID); err != nil {
+		m.log(ctx).Error().Err(err).Str("path", fn.CodePath).Msg("failed to delete function code")
+	}
+	// Adapters may have cached the code or written tokens and certificates
+	// locally, even when the code itself lives elsewhere.
+	if err := os.RemoveAll(filepath.Join(m.cfg.FunctionStorageDir, fn.ID)); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to delete local function directory")
+	}
+
+	if err := m.repo.Delete(ctx, fn.ID); err != nil {
 		return fmt.Errorf("failed to delete function record from db: %w", err)
 	}
+	m.forgetFunction(ctx, functionID)
+	m.deleteInvokeTokens(ctx, fn.ID)
+	m.deleteCaptures(ctx, fn.ID)
+	m.deleteGitDeployments(ctx, fn.ID)
+	m.deletePromotions(ctx, fn.ID)
+	if fn.Domain != "" {
+		if err := m.routeDomain(ctx, fn.ID, ""); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Str("domain", fn.Domain).Msg("failed to remove route of purged function's domain")
+		}
+	}
+	if fn.DeletedAt == nil {
+		m.emitFunctionEvent(ctx, EventFunctionDeleted, fn, nil)
+	}
+	if fn.BuiltImage != "" {
+		m.removeBuiltImage(ctx, fn.ID, fn.BuiltImage)
+	}
 
-	m.lg.Info().Str("function_id", functionID).Msg("function removed successfully")
+	m.log(ctx).Info().Str("function_id", functionID).Msg("function purged")
+	m.removeEnvironments(ctx, fn, m.PurgeFunction)
 	return nil
 }
 
+// RestartRunningFunctions restarts the workers of running functions, e.g.
+// after the manager restarted. Workers the orchestrator reports as alive (see
+// WorkerInspector) are kept, so only missing and dead ones are redeployed.
 func (m *Manager) RestartRunningFunctions(ctx context.Context) error {
-	m.lg.Info().Msg("restarting any previously running functions...")
-	var runningFunctions []Function
-	if err := m.db.Where("status = ?", "running").Find(&runningFunctions).Error; err != nil {
+	m.log(ctx).Info().Msg("restarting any previously running functions...")
+	runningFunctions, err := m.repo.FindByStatus(ctx, "running")
+	if err != nil {
 		return fmt.Errorf("could not query running functions: %w", err)
 	}
 
+	inspector, _ := m.orchestrator.(WorkerInspector)
 	for _, fn := range runningFunctions {
-		m.lg.Info().Str("function_id", fn.ID).Msg("restarting function")
-		runResult, err := m.orchestrator.RunWorker(ctx, fn.ID, fn.CodePath, fn.HandlerPath)
-		if err != nil {
-			m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to restart function container")
-			fn.Status = "stopped"
-		} else {
-			fn.ContainerID = runResult.ContainerID
-			fn.HostPort = runResult.HostPort
+		if inspector != nil {
+			state, err := inspector.InspectWorker(ctx, fn.ID)
+			if err != nil {
+				m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("failed to inspect worker, restarting it")
+			} else if state != nil && !state.Failed {
+				m.log(ctx).Info().Str("function_id", fn.ID).Str("container_id", state.ContainerID).Msg("worker still running, keeping it")
+				m.adoptWorker(ctx, &fn, state)
+				continue
+			}
+		}
+		m.log(ctx).Info().Str("function_id", fn.ID).Msg("restarting function")
+		if err := m.restartWorker(ctx, &fn); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to restart function")
+		}
+	}
+	return m.resumeBuilds(ctx)
+}
+
+// RestartFunction replaces the worker of a running function with a fresh
+// container or pod, e.g. when its handler leaked memory or hung. If the new
+// worker cannot be started the function is left in the error status.
+func (m *Manager) RestartFunction(ctx context.Context, functionID string) (*Function, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Status != "running" {
+		return nil, fmt.Errorf("%w: function '%s' is %s", ErrInvalidArgument, functionID, fn.Status)
+	}
+
+	if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
+		return nil, fmt.Errorf("stop worker: %w", err)
+	}
+	// Pooled connections point at the old worker.
+	m.workerClients.Delete(functionID)
+	if err := m.restartWorker(ctx, fn); err != nil {
+		return nil, err
+	}
+
+	m.log(ctx).Info().Str("function_id", functionID).Str("container_id", fn.ContainerID).Msg("function restarted")
+	return fn, nil
+}
+
+// pullFailure returns the status reason of a worker that could not be
+// started because its image could not be pulled, or "" when err is another
+// failure.
+func pullFailure(err error) string {
+	var pullErr *ImagePullError
+	if errors.As(err, &pullErr) {
+		return pullErr.Error()
+	}
+	return ""
+}
+
+// replaceWorker stops a running function's worker and starts a new one, e.g.
+// on new code.
+func (m *Manager) replaceWorker(ctx context.Context, fn *Function) error {
+	if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
+		return fmt.Errorf("stop worker: %w", err)
+	}
+	m.workerClients.Delete(fn.ID)
+	return m.restartWorker(ctx, fn)
+}
+
+// restartWorker starts a new worker for a running function whose worker is
+// gone and records its details. On failure the function is left in the error
+// status. When the worker itself failed to start, the reconciler tries again
+// after a backoff; a worker spec that cannot be built, e.g. because the code
+// no longer matches its checksum, needs the function to be fixed instead.
+func (m *Manager) restartWorker(ctx context.Context, fn *Function) (err error) {
+	defer func() { m.emitDeployOutcome(ctx, fn, err) }()
+	spec, err := m.workerSpec(ctx, fn)
+	if err == nil {
+		err = m.verifyCode(ctx, fn)
+	}
+	if err != nil {
+		fn.Status = StatusError
+		fn.StatusReason = "build worker spec: " + err.Error()
+		fn.NextRestartAt = nil
+		if err := m.repo.Update(ctx, fn); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record on restart")
 		}
-		if err := m.db.Save(&fn).Error; err != nil {
-			m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record on restart")
+		return fmt.Errorf("build worker spec: %w", err)
+	}
+	if err := m.publishIdentity(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to publish identity token")
+	}
+	runResult, runErr := m.orchestrator.RunWorker(ctx, spec)
+	if runErr != nil {
+		fn.Status = StatusError
+		fn.StatusReason = "start worker: " + runErr.Error()
+		if reason := pullFailure(runErr); reason != "" {
+			fn.StatusReason = reason
 		}
+		fn.RestartAttempts++
+		next := time.Now().UTC().Add(restartBackoff(fn.RestartAttempts))
+		fn.NextRestartAt = &next
+		fn.ContainerID = ""
+		fn.HostPort = 0
+		fn.Endpoint = ""
+		fn.Endpoints = nil
+		fn.PublicURL = ""
+	} else {
+		fn.ContainerID = runResult.ContainerID
+		fn.HostPort = runResult.HostPort
+		fn.Endpoint = runResult.Endpoint
+		fn.Endpoints = runResult.Endpoints
+		fn.PublicURL = runResult.PublicURL
+		fn.Status = "running"
+		fn.StatusReason = ""
+		fn.RestartAttempts = 0
+		fn.NextRestartAt = nil
+		now := time.Now().UTC()
+		fn.DeployedAt = &now
+	}
+	if err := m.repo.Update(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record on restart")
+	}
+	if runErr != nil {
+		return fmt.Errorf("start worker container: %w", runErr)
 	}
+	m.markCold(fn)
+	m.warmAfterDeploy(fn)
 	return nil
 }
 
 func (m *Manager) CleanupAllFunctions(ctx context.Context) error {
-	m.lg.Info().Msg("cleaning up all function containers")
-	functions, err := m.ListFunctions()
+	m.log(ctx).Info().Msg("cleaning up all function containers")
+	functions, err := m.ListFunctions(ctx, ListFilter{})
 	if err != nil {
 		return fmt.Errorf("could not list functions for cleanup: %w", err)
 	}
@@ -194,7 +1023,7 @@ func (m *Manager) CleanupAllFunctions(ctx context.Context) error {
 	for _, fn := range functions {
 		if fn.Status == "running" {
 			if err := m.orchestrator.StopAndRemoveContainer(ctx, fn.ContainerID); err != nil {
-				m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed during cleanup")
+				m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed during cleanup")
 			}
 		}
 	}
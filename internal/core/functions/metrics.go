@@ -0,0 +1,226 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Invocation metrics are kept in memory, per minute, for maxMetricsWindow.
+const (
+	maxMetricsWindow     = time.Hour
+	defaultMetricsWindow = 5 * time.Minute
+	metricsSlots         = int(maxMetricsWindow / time.Minute)
+)
+
+// latencyBounds are the upper bounds, in milliseconds, of the latency
+// histogram buckets. Latencies above the last bound fall in an extra bucket.
+var latencyBounds = [...]float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 30000, 60000, 120000, 300000}
+
+// WorkerStatsReporter is implemented by orchestrators that can report the
+// resources a function's worker uses.
+type WorkerStatsReporter interface {
+	// WorkerStats returns the current usage summed over the worker's
+	// instances, and nil when the function has no worker.
+	WorkerStats(ctx context.Context, functionID string) (*Resources, error)
+}
+
+// FunctionMetrics describes a function's invocations over a recent window.
+type FunctionMetrics struct {
+	FunctionID string    `json:"function_id"`
+	Window     string    `json:"window" example:"5m0s"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	// Invocations and Errors count the calls to the worker; cached
+	// responses are not included. Errors are split into UserErrors, such as
+	// exceptions the handler raised, and PlatformErrors, such as workers that
+	// could not be reached or timed out.
+	Invocations    int64 `json:"invocations"`
+	Errors         int64 `json:"errors"`
+	UserErrors     int64 `json:"user_errors"`
+	PlatformErrors int64 `json:"platform_errors"`
+	// RatePerSecond is the average number of invocations per second.
+	RatePerSecond float64 `json:"rate_per_second"`
+	// ErrorRate is the share of failed invocations, between 0 and 1.
+	ErrorRate float64        `json:"error_rate"`
+	LatencyMS LatencySummary `json:"latency_ms"`
+	// ColdStarts counts the invocations that were the first of a worker;
+	// ColdStartAvgMS is their average latency, which LatencyMS includes.
+	ColdStarts     int64   `json:"cold_starts"`
+	ColdStartAvgMS float64 `json:"cold_start_avg_ms"`
+	// Resources is the worker's current CPU and memory use, left out when
+	// the orchestrator does not report it.
+	Resources *Resources `json:"resources,omitempty"`
+}
+
+// LatencySummary holds latency percentiles, estimated from a histogram.
+type LatencySummary struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+type invocationMetrics struct {
+	functions sync.Map // function ID -> *functionMetrics
+}
+
+// functionMetrics is a ring of per-minute buckets.
+type functionMetrics struct {
+	mu      sync.Mutex
+	buckets [metricsSlots]metricsBucket
+}
+
+type metricsBucket struct {
+	minute      int64 // Unix minute the bucket counts; older counts are stale
+	invocations int64
+	errors      int64
+	userErrors  int64
+	coldStarts  int64
+	coldMS      float64
+	latency     [len(latencyBounds) + 1]int64
+}
+
+// record counts an invocation in the minute it ended, with the error it
+// failed with.
+func (im *invocationMetrics) record(functionID string, start time.Time, err error, cold bool) {
+	now := time.Now()
+	ms := float64(now.Sub(start).Microseconds()) / 1000
+	minute := now.Unix() / 60
+	v, _ := im.functions.LoadOrStore(functionID, &functionMetrics{})
+	fm := v.(*functionMetrics)
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	b := &fm.buckets[minute%int64(metricsSlots)]
+	if b.minute != minute {
+		*b = metricsBucket{minute: minute}
+	}
+	b.invocations++
+	if err != nil {
+		b.errors++
+		if ClassifyError(err) == ErrorClassUser {
+			b.userErrors++
+		}
+	}
+	if cold {
+		b.coldStarts++
+		b.coldMS += ms
+	}
+	i := 0
+	for i < len(latencyBounds) && ms > latencyBounds[i] {
+		i++
+	}
+	b.latency[i]++
+}
+
+// sum adds up the buckets of the minutes from the one of from to the one of
+// to.
+func (im *invocationMetrics) sum(functionID string, from, to time.Time) metricsBucket {
+	var total metricsBucket
+	v, ok := im.functions.Load(functionID)
+	if !ok {
+		return total
+	}
+	fm := v.(*functionMetrics)
+	first, last := from.Unix()/60, to.Unix()/60
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	for _, b := range fm.buckets {
+		if b.minute < first || b.minute > last {
+			continue
+		}
+		total.invocations += b.invocations
+		total.errors += b.errors
+		total.userErrors += b.userErrors
+		total.coldStarts += b.coldStarts
+		total.coldMS += b.coldMS
+		for i, n := range b.latency {
+			total.latency[i] += n
+		}
+	}
+	return total
+}
+
+// percentile estimates the q-th quantile of a histogram, interpolating
+// linearly within the bucket it falls in.
+func percentile(hist []int64, q float64) float64 {
+	var count int64
+	for _, n := range hist {
+		count += n
+	}
+	if count == 0 {
+		return 0
+	}
+	rank := q * float64(count)
+	var seen int64
+	for i, n := range hist {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		if i == len(latencyBounds) {
+			return latencyBounds[len(latencyBounds)-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		return lower + (latencyBounds[i]-lower)*(rank-float64(seen))/float64(n)
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// Metrics reports a function's invocation rate, error rate and latency over
+// the last window, at most an hour, and its worker's current resource use
+// where the orchestrator reports it. Invocations are counted by this
+// manager only.
+func (m *Manager) Metrics(ctx context.Context, functionID string, window time.Duration) (*FunctionMetrics, error) {
+	if window == 0 {
+		window = defaultMetricsWindow
+	}
+	if window < time.Minute || window > maxMetricsWindow {
+		return nil, fmt.Errorf("%w: window must be between 1m and %s", ErrInvalidArgument, maxMetricsWindow)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-window)
+	// Whole minutes are counted, so the window ends with the current one.
+	total := m.invocationMetrics.sum(fn.ID, from.Add(time.Minute), to)
+	report := &FunctionMetrics{
+		FunctionID:     fn.ID,
+		Window:         window.String(),
+		From:           from,
+		To:             to,
+		Invocations:    total.invocations,
+		Errors:         total.errors,
+		UserErrors:     total.userErrors,
+		PlatformErrors: total.errors - total.userErrors,
+		ColdStarts:     total.coldStarts,
+		RatePerSecond:  float64(total.invocations) / window.Seconds(),
+		LatencyMS: LatencySummary{
+			P50: percentile(total.latency[:], 0.50),
+			P95: percentile(total.latency[:], 0.95),
+			P99: percentile(total.latency[:], 0.99),
+		},
+	}
+	if total.invocations > 0 {
+		report.ErrorRate = float64(total.errors) / float64(total.invocations)
+	}
+	if total.coldStarts > 0 {
+		report.ColdStartAvgMS = total.coldMS / float64(total.coldStarts)
+	}
+
+	if reporter, ok := m.orchestrator.(WorkerStatsReporter); ok && fn.Status == "running" {
+		report.Resources, err = reporter.WorkerStats(ctx, fn.ID)
+		if err != nil {
+			m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Msg("failed to read worker stats")
+		}
+	}
+	return report, nil
+}
@@ -1,16 +1,259 @@
 package functions
 
-import "time"
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+)
 
 // Function represents a single FaaS function instance.
 type Function struct {
-	ID            string    `gorm:"primaryKey" json:"id"`
-	FunctionName  string    `json:"function_name"` // The name of the function in the .py file
-	HandlerPath   string    `json:"handler_path"`  // e.g., handler.handle
-	CodePath      string    `json:"-"`             // Host path to the .py file
-	ContainerID   string    `json:"container_id"`
-	ContainerName string    `json:"container_name"`
-	HostPort      int       `json:"host_port"` // The port on the host mapped to the container
-	Status        string    `json:"status"`    // e.g., "creating", "running", "stopped", "error"
-	CreatedAt     time.Time `json:"created_at"`
+	ID string `gorm:"primaryKey" json:"id"`
+	// Name, when set, identifies the function for Apply; it is unique
+	// across functions, deleted ones included.
+	Name string `gorm:"size:191;uniqueIndex;serializer:nullempty" json:"name,omitempty"`
+	// Public serves the function at /f/{name}, without authentication or
+	// the execute envelope. Only named functions can be public.
+	Public bool `json:"public,omitempty"`
+	// Domain, when set, is a host name whose requests the manager serves
+	// with the function, like /f/{name}. It is unique across functions.
+	Domain string `gorm:"size:191;uniqueIndex;serializer:nullempty" json:"domain,omitempty"`
+	// CORS, when set, lets browser apps on other origins call the function
+	// through its public routes.
+	CORS *CORSPolicy `gorm:"serializer:json" json:"cors,omitempty"`
+	// Debug, when set, captures a sample of the function's executions.
+	Debug *DebugCapture `gorm:"serializer:json" json:"debug,omitempty"`
+	// ParentID and Environment are set on an environment deployment of
+	// another function (see DeployEnvironment), e.g. its "staging".
+	ParentID      string `gorm:"size:191;index" json:"parent_id,omitempty"`
+	Environment   string `gorm:"size:63" json:"environment,omitempty"`
+	Tenant        string `gorm:"index" json:"tenant,omitempty"`
+	Owner         string `gorm:"index" json:"owner,omitempty"`
+	FunctionName  string `json:"function_name"`          // The name of the function in the .py file
+	HandlerPath   string `json:"handler_path"`           // e.g., handler.handle
+	CodePath      string `json:"-"`                      // Host path to the .py file
+	WorkerImage   string `json:"worker_image,omitempty"` // Custom worker image; empty means the runtime's or the global default
+	Runtime       string `json:"runtime,omitempty"`      // Runtime whose worker image the function runs
+	CodeSHA256    string `json:"code_sha256,omitempty"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	HostPort      int    `json:"host_port"` // The port on the host mapped to the container
+	Endpoint      string `json:"endpoint"`  // Worker base URL the manager routes executions to
+	Status        string `json:"status"`    // e.g., "creating", "pulling_image", "running", "stopped", "error"
+	// StatusReason explains the status: why the worker died or its image
+	// could not be pulled, or the last disruption a running worker
+	// recovered from, e.g. an OOM kill.
+	StatusReason string    `gorm:"type:text" json:"status_reason,omitempty"`
+	PreHook      *Hook     `gorm:"serializer:json" json:"pre_hook,omitempty"`
+	PostHook     *Hook     `gorm:"serializer:json" json:"post_hook,omitempty"`
+	Exposure     *Exposure `gorm:"serializer:json" json:"exposure,omitempty"`
+	Fallback     *Fallback `gorm:"serializer:json" json:"fallback,omitempty"`
+	PublicURL    string    `json:"public_url,omitempty"` // Direct URL when the function is exposed outside the manager
+	CreatedAt    time.Time `json:"created_at"`
+	// DeployedAt is when the function's worker was last started.
+	DeployedAt *time.Time `json:"deployed_at,omitempty"`
+	// RestartAttempts counts the failed attempts in a row to start the
+	// worker again after it was lost; the reconciler tries again at
+	// NextRestartAt.
+	RestartAttempts int        `json:"restart_attempts,omitempty"`
+	NextRestartAt   *time.Time `json:"next_restart_at,omitempty"`
+	// Worker is the worker's live status, only filled in by GetFunction.
+	Worker *WorkerStatus `gorm:"-" json:"worker,omitempty"`
+
+	// DeletedAt is set while the function is soft-deleted: its worker is
+	// stopped but the record and code are kept so it can be restored.
+	DeletedAt *time.Time `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Labels organize functions, e.g. by team. They are copied onto the
+	// worker's container or pod labels.
+	Labels map[string]string `gorm:"serializer:json" json:"labels,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	// EnvVars are set in the environment of the function's workers. They
+	// are encrypted at rest by the storage layer and never returned; the API
+	// lists their names as EnvVarNames.
+	EnvVars     map[string]string `gorm:"type:text;serializer:encrypted" json:"-"`
+	EnvVarNames []string          `gorm:"-" json:"env_var_names,omitempty"`
+
+	// MaxConcurrency caps the executions in flight at once on each manager
+	// replica; zero means unlimited.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	// MaxPayloadBytes lowers the manager-wide execute payload limit for
+	// this function; zero keeps the global limit.
+	MaxPayloadBytes int64 `json:"max_payload_bytes,omitempty"`
+
+	// PayloadSchema is a JSON Schema that execute payloads, parsed as JSON,
+	// must match before the worker is called.
+	PayloadSchema json.RawMessage `gorm:"serializer:json" json:"payload_schema,omitempty" swaggertype:"object"`
+
+	// Warmup, when set, configures the requests that prime the function's
+	// workers.
+	Warmup *Warmup `gorm:"serializer:json" json:"warmup,omitempty"`
+
+	// CacheTTLSeconds opts an idempotent function into response caching:
+	// identical payloads are answered from the cache for this long.
+	CacheTTLSeconds int `json:"cache_ttl_seconds,omitempty"`
+
+	// TimeoutSeconds bounds each call to the worker, and the timeout a
+	// caller may ask for; zero means no limit.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	// Replicas is how many worker containers serve the function in Docker
+	// mode, which the manager balances executions across; zero means one.
+	Replicas int `json:"replicas,omitempty"`
+	// Endpoints lists the base URLs of all replicas when there is more than
+	// one; Endpoint is the first.
+	Endpoints []string `gorm:"serializer:json" json:"endpoints,omitempty"`
+	// Affinity, when set, sends executions with the same key to the same
+	// replica.
+	Affinity *Affinity `gorm:"serializer:json" json:"affinity,omitempty"`
+
+	// Kubernetes, when set, overrides the service account and image pull
+	// secrets of the function's pods.
+	Kubernetes *KubernetesWorker `gorm:"serializer:json" json:"kubernetes,omitempty"`
+
+	// PolicyFindings lists the code policy violations found when the code
+	// was uploaded in flag mode.
+	PolicyFindings []string `gorm:"serializer:json" json:"policy_findings,omitempty"`
+
+	// BlockedReason is set when the code was found malicious. Such a
+	// function is never deployed.
+	BlockedReason string `json:"blocked_reason,omitempty"`
+
+	// ImageDigest pins the worker image's tag to the digest it pointed to
+	// when the function was created or last upgraded.
+	ImageDigest string `json:"image_digest,omitempty"`
+
+	// DependencyLockSHA256 is the checksum of the requirements.lock that
+	// every worker of the function installs from.
+	DependencyLockSHA256 string `json:"dependency_lock_sha256,omitempty"`
+
+	// With image builds on, BuiltImage is the function's own image, which
+	// its workers run. BuildStatus and BuildLog describe the latest build.
+	BuiltImage  string `json:"built_image,omitempty"`
+	BuildStatus string `json:"build_status,omitempty"`
+	BuildLog    string `gorm:"type:text" json:"-"`
+
+	// Git, when set, is the repository the code was deployed from, with the
+	// commit deployed. GitToken or GitDeployKey, encrypted at rest by the
+	// storage layer, authenticate its clones.
+	Git          *GitSource `gorm:"serializer:json" json:"git,omitempty"`
+	GitToken     string     `gorm:"serializer:encrypted" json:"-"`
+	GitDeployKey string     `gorm:"serializer:encrypted" json:"-"`
+
+	// Promotion records the promotion that deployed the environment's code,
+	// until it is deployed otherwise.
+	Promotion *Promotion `gorm:"serializer:json" json:"promotion,omitempty"`
+}
+
+// MarshalJSON fills EnvVarNames from EnvVars, whose values stay out of the
+// JSON.
+func (fn Function) MarshalJSON() ([]byte, error) {
+	type function Function
+	out := function(fn)
+	if len(fn.EnvVars) > 0 {
+		out.EnvVarNames = slices.Sorted(maps.Keys(fn.EnvVars))
+	}
+	return json.Marshal(out)
+}
+
+// StatusDeleted is the status of a soft-deleted function.
+const StatusDeleted = "deleted"
+
+// StatusError is the status of a function whose worker failed to start or
+// died; StatusReason says why.
+const StatusError = "error"
+
+// StatusBlocked is the status of a function quarantined by the malware scan.
+const StatusBlocked = "blocked"
+
+// StatusStopped is the status of a function whose worker was stopped, by
+// StopFunction or because it could not be restarted.
+const StatusStopped = "stopped"
+
+// StatusPulling is the status of a function whose worker waits for its image
+// to be pulled.
+const StatusPulling = "pulling_image"
+
+// StatusBuilding is the status of a function waiting for its first image
+// build.
+const StatusBuilding = "building"
+
+// FunctionOptions carries the optional settings accepted when a function is
+// created.
+type FunctionOptions struct {
+	Name        string
+	Public      bool
+	Domain      string
+	CORS        *CORSPolicy
+	Tenant      string
+	Owner       string
+	WorkerImage string
+	Runtime     string
+	PreHook     *Hook
+	PostHook    *Hook
+	Exposure    *Exposure
+	Fallback    *Fallback
+	Labels      map[string]string
+	Description string
+	EnvVars     map[string]string
+
+	MaxConcurrency  int
+	MaxPayloadBytes int64
+	PayloadSchema   json.RawMessage
+	CacheTTLSeconds int
+	TimeoutSeconds  int
+	Replicas        int
+	Affinity        *Affinity
+	Kubernetes      *KubernetesWorker
+	Warmup          *Warmup
+
+	// BundleFormat marks the code as a compressed archive (see package
+	// bundle) rather than a single handler file.
+	BundleFormat string
+	// ExpectedSHA256, when set, must match the SHA-256 of the uploaded bytes.
+	ExpectedSHA256 string
+
+	// Git, when set, deploys the code from a Git repository instead of an
+	// upload; GitAuth holds the credentials for private repositories.
+	Git     *GitSource
+	GitAuth GitAuth
+
+	// ParentID and Environment make the function an environment deployment
+	// of another one.
+	ParentID    string
+	Environment string
+	// ImageDigest pins the worker image to this digest instead of the one
+	// its tag points to now.
+	ImageDigest string
+	Promotion   *Promotion
+}
+
+// Exposure kinds supported by the Kubernetes orchestrator.
+const (
+	ExposeIngress   = "ingress"
+	ExposeHTTPRoute = "httproute"
+)
+
+// Exposure asks the orchestrator to route external traffic straight to the
+// function's workers, bypassing the manager's execute endpoint.
+type Exposure struct {
+	Kind string `json:"kind"`           // "ingress" or "httproute"
+	Host string `json:"host,omitempty"` // Optional host to match
+	Path string `json:"path,omitempty"` // Path prefix; defaults to /fn/<function id>
+}
+
+// RegistryCredential is a tenant's login for a container registry, used to
+// pull that tenant's custom worker images. The password is encrypted at rest
+// by the storage layer.
+type RegistryCredential struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Tenant    string    `gorm:"size:191;uniqueIndex:idx_tenant_server" json:"tenant"`
+	Server    string    `gorm:"size:191;uniqueIndex:idx_tenant_server" json:"server"`
+	Username  string    `json:"username"`
+	Password  string    `gorm:"serializer:encrypted" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -1,15 +1,128 @@
 package functions
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
 
 // Orchestrator defines the interface for running and managing FaaS workers.
 type Orchestrator interface {
-	RunWorker(ctx context.Context, funcID, codePath, handlerPath string) (*RunResult, error)
+	RunWorker(ctx context.Context, spec WorkerSpec) (*RunResult, error)
 	StopAndRemoveContainer(ctx context.Context, containerID string) error
 }
 
+// WorkerSpec describes the worker to run for a function.
+type WorkerSpec struct {
+	FunctionID  string
+	CodePath    string
+	HandlerPath string
+	Image       string
+	// CodeURL, when set, is a presigned URL to the handler code in object
+	// storage and CodePath is unused.
+	CodeURL string
+	// RegistryAuth holds tenant credentials for pulling Image. When nil the
+	// adapter falls back to its globally configured registry credentials.
+	RegistryAuth *RegistryAuth
+	// Exposure, when set, requests a route that reaches the worker directly.
+	Exposure *Exposure
+	// Identity tells the adapter to make the function's identity token,
+	// delivered through IdentityPublisher, readable by the worker.
+	Identity bool
+	// Labels are the function's labels, applied to the worker alongside the
+	// orchestrator's own labels, which take precedence.
+	Labels map[string]string
+	// Env holds the function's env vars, set in the worker's environment
+	// alongside the orchestrator's own.
+	Env map[string]string
+	// TLS, when set, makes the worker serve HTTPS with this certificate and
+	// require client certificates; the returned Endpoint must use https.
+	TLS *WorkerTLS
+	// DependencyLock, when set, is the requirements file in the code
+	// directory the worker installs its dependencies from.
+	DependencyLock string
+	// Built marks Image as the function's own image (see ImageBuilder), which
+	// already holds its code and dependencies.
+	Built bool
+	// Replicas is how many worker containers to run, each with its own
+	// endpoint; zero means one. Orchestrators that scale workers themselves
+	// ignore it.
+	Replicas int
+	// Kubernetes, when set, overrides the service account and pull secrets
+	// of the worker's pods. Other orchestrators ignore it.
+	Kubernetes *KubernetesWorker
+	// Pulling, when set, is called by orchestrators that pull Image
+	// themselves when they start pulling it.
+	Pulling func()
+}
+
+// HandlerCode returns the handler source, from CodeURL when the code lives in
+// object storage and from CodePath on the manager's disk otherwise.
+func (s WorkerSpec) HandlerCode(ctx context.Context) ([]byte, error) {
+	if s.CodeURL == "" {
+		return os.ReadFile(filepath.Join(s.CodePath, "handler.py"))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.CodeURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create code request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download code: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download code: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Reasons an image pull failed, in ImagePullError.
+const (
+	PullUnauthorized = "unauthorized" // the registry refused the login
+	PullNotFound     = "not_found"    // no such repository, tag or manifest
+	PullFailed       = "failed"       // anything else
+)
+
+// ImagePullError is returned by orchestrators when a worker image cannot be
+// pulled.
+type ImagePullError struct {
+	Image  string
+	Reason string // PullUnauthorized, PullNotFound or PullFailed
+	Err    error
+}
+
+func (e *ImagePullError) Error() string {
+	switch e.Reason {
+	case PullUnauthorized:
+		return fmt.Sprintf("pull image %s: registry denied access: %v", e.Image, e.Err)
+	case PullNotFound:
+		return fmt.Sprintf("pull image %s: image not found: %v", e.Image, e.Err)
+	}
+	return fmt.Sprintf("pull image %s: %v", e.Image, e.Err)
+}
+
+func (e *ImagePullError) Unwrap() error { return e.Err }
+
+// RegistryAuth is a set of plaintext credentials for one container registry.
+type RegistryAuth struct {
+	Server   string
+	Username string
+	Password string
+}
+
 // RunResult holds the outcome of running a worker.
 type RunResult struct {
 	ContainerID string
 	HostPort    int
+	// Endpoint is the base URL the manager calls to execute the function.
+	Endpoint string
+	// PublicURL is where an exposed function can be reached directly.
+	PublicURL string
+	// Endpoints lists the base URLs of all replicas when more than one was
+	// started; Endpoint is the first.
+	Endpoints []string
 }
@@ -0,0 +1,143 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kinds of worker resources orchestrators hold.
+const (
+	ResourceContainer  = "container"
+	ResourceDeployment = "deployment"
+	ResourceService    = "service"
+	ResourceHPA        = "hpa"
+)
+
+// ResourceLister is implemented by orchestrators that can list the resources
+// they hold for workers, so drift between them and the database can be
+// found and cleaned up.
+type ResourceLister interface {
+	// ListWorkerResources returns the worker resources of every function,
+	// recognized by the orchestrator's labels and names.
+	ListWorkerResources(ctx context.Context) ([]WorkerResource, error)
+	// DeleteWorkerResource removes one resource ListWorkerResources
+	// returned. A resource that is gone already is not an error.
+	DeleteWorkerResource(ctx context.Context, res WorkerResource) error
+}
+
+// WorkerResource is a container, Deployment, Service or HorizontalPodAutoscaler
+// the orchestrator holds for a function's worker.
+type WorkerResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// ID is the container's ID, where it differs from the name.
+	ID string `json:"id,omitempty"`
+	// FunctionID is the function the resource was created for, or empty
+	// when its labels and name do not tell.
+	FunctionID string    `json:"function_id,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Orphaned is set when no function record accounts for the resource;
+	// OrphanReason says why.
+	Orphaned     bool   `json:"orphaned"`
+	OrphanReason string `json:"orphan_reason,omitempty"`
+}
+
+// ListWorkerResources lists what the orchestrator holds for workers and
+// flags the resources no function record accounts for: those of functions
+// that do not exist, are deleted, or are stopped or blocked and so should
+// have no worker. With orphanedOnly only those are returned.
+func (m *Manager) ListWorkerResources(ctx context.Context, orphanedOnly bool) ([]WorkerResource, error) {
+	lister, ok := m.orchestrator.(ResourceLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: listing worker resources for %s", ErrNotConfigured, m.cfg.DeploymentEnv)
+	}
+	resources, err := lister.ListWorkerResources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list worker resources: %w", err)
+	}
+	fns, err := m.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("db list functions: %w", err)
+	}
+	byID := make(map[string]*Function, len(fns))
+	for i := range fns {
+		byID[fns[i].ID] = &fns[i]
+	}
+
+	for i := range resources {
+		res := &resources[i]
+		res.OrphanReason = orphanReason(res, byID[res.FunctionID])
+		res.Orphaned = res.OrphanReason != ""
+	}
+	if orphanedOnly {
+		resources = slices.DeleteFunc(resources, func(res WorkerResource) bool { return !res.Orphaned })
+	}
+	slices.SortFunc(resources, func(a, b WorkerResource) int {
+		if c := strings.Compare(a.FunctionID, b.FunctionID); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Kind, b.Kind); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return resources, nil
+}
+
+// orphanReason says why no function record accounts for a resource, or is
+// empty when fn does.
+func orphanReason(res *WorkerResource, fn *Function) string {
+	switch {
+	case res.FunctionID == "":
+		return "no function label"
+	case fn == nil:
+		return "no function record"
+	case fn.DeletedAt != nil || fn.Status == StatusDeleted:
+		return "function is deleted"
+	case fn.Status == StatusStopped || fn.Status == StatusBlocked:
+		return "function is " + fn.Status
+	}
+	return ""
+}
+
+// DeleteWorkerResource removes a worker resource straight from the
+// orchestrator, without touching the database. Only orphaned resources are
+// removed unless force is set; removing the worker of a live function takes
+// it down until it is redeployed or reconciled.
+func (m *Manager) DeleteWorkerResource(ctx context.Context, kind, name string, force bool) (*WorkerResource, error) {
+	lister, ok := m.orchestrator.(ResourceLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: deleting worker resources for %s", ErrNotConfigured, m.cfg.DeploymentEnv)
+	}
+	if !slices.Contains([]string{ResourceContainer, ResourceDeployment, ResourceService, ResourceHPA}, kind) {
+		return nil, fmt.Errorf("%w: unknown resource kind %q", ErrInvalidArgument, kind)
+	}
+	resources, err := m.ListWorkerResources(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(resources, func(res WorkerResource) bool {
+		return res.Kind == kind && (res.Name == name || (res.ID != "" && res.ID == name))
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no worker %s '%s'", ErrNotFound, kind, name)
+	}
+	res := resources[i]
+	if !res.Orphaned && !force {
+		return nil, fmt.Errorf("%w: %s '%s' belongs to function '%s'; set force to delete it anyway", ErrPreconditionFailed, kind, res.Name, res.FunctionID)
+	}
+	if err := lister.DeleteWorkerResource(ctx, res); err != nil {
+		return nil, fmt.Errorf("delete worker %s: %w", kind, err)
+	}
+	m.log(ctx).Warn().
+		Str("kind", kind).
+		Str("name", res.Name).
+		Str("function_id", res.FunctionID).
+		Bool("orphaned", res.Orphaned).
+		Msg("worker resource force-deleted")
+	return &res, nil
+}
@@ -0,0 +1,112 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OwnerDirectory looks up owners (users or teams) in the identity provider.
+type OwnerDirectory interface {
+	OwnerExists(ctx context.Context, owner string) (bool, error)
+}
+
+// WithOwnerDirectory enables owner validation on transfer and the orphan
+// report.
+func WithOwnerDirectory(dir OwnerDirectory) Option {
+	return func(m *Manager) { m.owners = dir }
+}
+
+// Reasons reported for orphaned functions.
+const (
+	OrphanUnowned      = "unowned"
+	OrphanOwnerMissing = "owner_missing"
+)
+
+// OrphanedFunction is a function that no existing owner is responsible for.
+type OrphanedFunction struct {
+	Function Function `json:"function"`
+	Reason   string   `json:"reason"` // "unowned" or "owner_missing"
+}
+
+// TransferFunction hands a function over to a new owner. When an owner
+// directory is configured the new owner must exist in it.
+func (m *Manager) TransferFunction(ctx context.Context, functionID, owner string) (*Function, error) {
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return nil, fmt.Errorf("%w: owner is required", ErrInvalidArgument)
+	}
+
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("look up function: %w", err)
+	}
+
+	if err := m.checkOwner(ctx, owner); err != nil {
+		return nil, err
+	}
+
+	previous := fn.Owner
+	fn.Owner = owner
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update owner: %w", err)
+	}
+
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("from", previous).Str("to", owner).Msg("function ownership transferred")
+	return fn, nil
+}
+
+// checkOwner checks that an owner exists in the owner directory, when one is
+// configured.
+func (m *Manager) checkOwner(ctx context.Context, owner string) error {
+	if m.owners == nil {
+		return nil
+	}
+	exists, err := m.owners.OwnerExists(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("look up owner: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: owner '%s' does not exist", ErrInvalidArgument, owner)
+	}
+	return nil
+}
+
+// OrphanedFunctions reports functions without an owner and functions whose
+// owner no longer exists in the owner directory.
+func (m *Manager) OrphanedFunctions(ctx context.Context) ([]OrphanedFunction, error) {
+	if m.owners == nil {
+		return nil, fmt.Errorf("%w: owner directory", ErrNotConfigured)
+	}
+
+	fns, err := m.ListFunctions(ctx, ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Many functions share an owner, so each owner is looked up once.
+	exists := make(map[string]bool)
+	orphans := []OrphanedFunction{}
+	for _, fn := range fns {
+		if fn.Owner == "" {
+			orphans = append(orphans, OrphanedFunction{Function: fn, Reason: OrphanUnowned})
+			continue
+		}
+		ok, seen := exists[fn.Owner]
+		if !seen {
+			ok, err = m.owners.OwnerExists(ctx, fn.Owner)
+			if err != nil {
+				return nil, fmt.Errorf("look up owner '%s': %w", fn.Owner, err)
+			}
+			exists[fn.Owner] = ok
+		}
+		if !ok {
+			orphans = append(orphans, OrphanedFunction{Function: fn, Reason: OrphanOwnerMissing})
+		}
+	}
+	return orphans, nil
+}
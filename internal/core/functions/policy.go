@@ -0,0 +1,148 @@
+package functions
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"service-faas/internal/config"
+	"strings"
+)
+
+// Code policy modes: off scans nothing, flag records findings on the
+// function, reject refuses the upload.
+const (
+	PolicyOff    = "off"
+	PolicyFlag   = "flag"
+	PolicyReject = "reject"
+)
+
+// PolicyError lists the policy findings that made AddFunction reject code.
+type PolicyError struct {
+	Findings []string
+}
+
+func (e *PolicyError) Error() string {
+	return "code violates policy: " + strings.Join(e.Findings, "; ")
+}
+
+func (e *PolicyError) Unwrap() error { return ErrInvalidArgument }
+
+// CodePolicy scans uploaded Python sources for banned modules and patterns.
+// It reads source text only, so it catches careless use, not a determined
+// author hiding an import.
+type CodePolicy struct {
+	mode     string
+	modules  []string
+	patterns []*regexp.Regexp
+}
+
+var (
+	importStmt     = regexp.MustCompile(`^\s*import\s+(.+)`)
+	fromImportStmt = regexp.MustCompile(`^\s*from\s+([\w.]+)\s+import\s+(.*)`)
+	dynamicImport  = regexp.MustCompile(`(?:__import__|import_module)\(\s*["']([\w.]+)["']`)
+)
+
+// NewCodePolicy builds the policy configured by CodePolicyMode,
+// CodePolicyBannedModules and CodePolicyBannedPatterns.
+func NewCodePolicy(cfg config.Config) (*CodePolicy, error) {
+	p := &CodePolicy{mode: cfg.CodePolicyMode, modules: cfg.CodePolicyBannedModules}
+	switch p.mode {
+	case PolicyOff, PolicyFlag, PolicyReject:
+	default:
+		return nil, fmt.Errorf("unknown code policy mode %q, expected off, flag or reject", p.mode)
+	}
+	for _, expr := range cfg.CodePolicyBannedPatterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("banned pattern %q: %w", expr, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	return p, nil
+}
+
+// WithCodePolicy scans uploaded code with the policy.
+func WithCodePolicy(p *CodePolicy) Option {
+	return func(m *Manager) { m.policy = p }
+}
+
+// enabled reports whether sources need scanning; nil-receiver safe.
+func (p *CodePolicy) enabled() bool {
+	return p != nil && p.mode != PolicyOff && (len(p.modules) > 0 || len(p.patterns) > 0)
+}
+
+func (p *CodePolicy) rejects() bool {
+	return p.enabled() && p.mode == PolicyReject
+}
+
+// Scan returns one finding per banned import or pattern in a Python file.
+// Other files are not scanned.
+func (p *CodePolicy) Scan(name string, src []byte) []string {
+	if !p.enabled() || !strings.HasSuffix(name, ".py") {
+		return nil
+	}
+	var findings []string
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	sc.Buffer(nil, len(src)+1)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, mod := range importedModules(line) {
+			if banned := p.bannedModule(mod); banned != "" {
+				findings = append(findings, fmt.Sprintf("%s line %d: imports banned module '%s'", name, n, banned))
+			}
+		}
+		for _, re := range p.patterns {
+			if re.MatchString(line) {
+				findings = append(findings, fmt.Sprintf("%s line %d: matches banned pattern `%s`", name, n, re))
+			}
+		}
+	}
+	return findings
+}
+
+// bannedModule returns the banned module that mod is or belongs to.
+func (p *CodePolicy) bannedModule(mod string) string {
+	for _, banned := range p.modules {
+		if mod == banned || strings.HasPrefix(mod, banned+".") {
+			return banned
+		}
+	}
+	return ""
+}
+
+// importedModules lists the modules a line of Python imports. Names
+// imported from a module count as its submodules, since they may be.
+func importedModules(line string) []string {
+	var mods []string
+	if m := fromImportStmt.FindStringSubmatch(line); m != nil {
+		mods = append(mods, m[1])
+		for _, name := range importNames(m[2]) {
+			mods = append(mods, m[1]+"."+name)
+		}
+	} else if m := importStmt.FindStringSubmatch(line); m != nil {
+		mods = append(mods, importNames(m[1])...)
+	}
+	for _, m := range dynamicImport.FindAllStringSubmatch(line, -1) {
+		mods = append(mods, m[1])
+	}
+	return mods
+}
+
+// importNames returns the names in the list after an import keyword,
+// without aliases.
+func importNames(list string) []string {
+	list, _, _ = strings.Cut(list, "#")
+	list, _, _ = strings.Cut(list, ";")
+	list = strings.Trim(strings.TrimSpace(list), "()\\")
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		if fields := strings.Fields(part); len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
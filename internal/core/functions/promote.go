@@ -0,0 +1,156 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Promotion records that an environment was deployed by promoting another:
+// the environment copied, the function deployed there, the code and commit
+// copied, who asked for it and when.
+type Promotion struct {
+	From           string    `json:"from"`
+	FromFunctionID string    `json:"from_function_id"`
+	CodeSHA256     string    `json:"code_sha256"`
+	Commit         string    `json:"commit,omitempty"`
+	PromotedBy     string    `json:"promoted_by,omitempty"`
+	PromotedAt     time.Time `json:"promoted_at"`
+}
+
+// PromoteEnvironment deploys what one environment of a function runs to
+// another, e.g. from "staging" to "prod": the exact code, including its
+// locked dependencies, the Git commit, the pinned worker image and the
+// settings. The target keeps its env vars, which are what tells environments
+// apart; a missing target is created with the function's, and created
+// reports that. The promotion, with by, who asked for it, is set on the
+// target, kept in the function's promotion history whether it succeeded or
+// not, see ListEnvironmentPromotions, and emitted as a function.promoted
+// event.
+func (m *Manager) PromoteEnvironment(ctx context.Context, functionID, from, to, by string) (fn *Function, created bool, err error) {
+	if from == "" || to == "" {
+		return nil, false, fmt.Errorf("%w: both the environment to promote from and the one to promote to are required", ErrInvalidArgument)
+	}
+	if from == to {
+		return nil, false, fmt.Errorf("%w: cannot promote environment '%s' to itself", ErrInvalidArgument, from)
+	}
+	if err := validateName(to); err != nil {
+		return nil, false, fmt.Errorf("environment: %w", err)
+	}
+	parent, err := m.environmentParent(ctx, functionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.envsMu.Lock()
+	defer m.envsMu.Unlock()
+	source, err := m.findEnvironment(ctx, functionID, from)
+	if err != nil {
+		return nil, false, err
+	}
+	if source.DeletedAt != nil || source.BlockedReason != "" {
+		return nil, false, fmt.Errorf("%w: environment '%s' is %s", ErrInvalidArgument, from, source.Status)
+	}
+	promotion := &Promotion{
+		From:           from,
+		FromFunctionID: source.ID,
+		CodeSHA256:     source.CodeSHA256,
+		PromotedBy:     by,
+		PromotedAt:     time.Now().UTC(),
+	}
+	if source.Git != nil {
+		promotion.Commit = source.Git.Commit
+	}
+
+	target, err := m.findEnvironment(ctx, functionID, to)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		fn, err = m.createEnvironment(ctx, parent, source, to, EnvironmentDeployment{}, promotion)
+		created = fn != nil
+		target = fn
+	case err != nil:
+		return nil, false, err
+	default:
+		fn, err = m.promoteTo(ctx, target, source, promotion)
+		if fn != nil {
+			target = fn
+		}
+	}
+	m.recordPromotion(ctx, parent.ID, to, promotion, target, err)
+	if fn != nil {
+		m.log(ctx).Info().Str("function_id", fn.ID).Str("parent_id", functionID).Str("from", from).Str("to", to).Str("code_sha256", promotion.CodeSHA256).Str("promoted_by", by).Msg("environment promoted")
+		m.emitFunctionEvent(ctx, EventFunctionPromoted, fn, nil)
+	}
+	return fn, created, err
+}
+
+// promoteTo copies source's code and settings to the existing environment
+// fn. When the code is rejected, fn keeps its own.
+func (m *Manager) promoteTo(ctx context.Context, fn, source *Function, promotion *Promotion) (*Function, error) {
+	if err := m.beginEnvironmentUpdate(fn); err != nil {
+		return nil, err
+	}
+	defer m.syncs.Delete(fn.ID)
+
+	backup, err := m.backupCode(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(backup)
+	code, format, err := openCodeDir(backup)
+	if err != nil {
+		return nil, err
+	}
+	defer code.Close()
+
+	updated := *fn
+	updated.FunctionName = source.FunctionName
+	updated.HandlerPath = source.HandlerPath
+	updated.WorkerImage = source.WorkerImage
+	updated.Runtime = source.Runtime
+	updated.ImageDigest = source.ImageDigest
+	updated.PreHook = source.PreHook
+	updated.PostHook = source.PostHook
+	updated.Fallback = source.Fallback
+	updated.Labels = source.Labels
+	updated.Description = source.Description
+	updated.MaxConcurrency = source.MaxConcurrency
+	updated.MaxPayloadBytes = source.MaxPayloadBytes
+	updated.PayloadSchema = source.PayloadSchema
+	updated.CacheTTLSeconds = source.CacheTTLSeconds
+	updated.TimeoutSeconds = source.TimeoutSeconds
+	updated.Replicas = source.Replicas
+	updated.Affinity = source.Affinity
+	updated.Kubernetes = source.Kubernetes
+	updated.Warmup = source.Warmup
+	if err := m.redeployEnvironmentCode(ctx, fn, &updated, EnvironmentDeployment{Code: code, BundleFormat: format}); err != nil {
+		return nil, err
+	}
+	if source.Git != nil {
+		src := *source.Git
+		updated.Git, updated.GitToken, updated.GitDeployKey = &src, source.GitToken, source.GitDeployKey
+	}
+	updated.Promotion = promotion
+
+	if err := m.repo.Update(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("db update function: %w", err)
+	}
+	fn = &updated
+
+	if _, builds := m.imageBuilder(); builds {
+		if !m.startBuild(*fn) {
+			return nil, fmt.Errorf("%w: function '%s' is already being built", ErrInvalidArgument, fn.ID)
+		}
+		fn.BuildStatus = BuildRunning
+		return fn, nil
+	}
+	if fn.Status != "running" {
+		return fn, nil
+	}
+	if err := m.replaceWorker(ctx, fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"service-faas/pkg/rand"
+)
+
+// Outcomes of a promotion.
+const (
+	// PromotionPromoted: the target environment runs the promoted code.
+	PromotionPromoted = "promoted"
+	// PromotionBuilding: the target's image is being built; the build
+	// deploys it.
+	PromotionBuilding = "building"
+	// PromotionFailed: the promoted code was rejected or could not be
+	// deployed, and the target kept what it ran.
+	PromotionFailed = "failed"
+)
+
+// Promotion history list limits.
+const (
+	defaultPromotionLimit = 50
+	maxPromotionLimit     = 500
+)
+
+// EnvironmentPromotion records one promotion of an environment of a
+// function to another, by PromoteEnvironment.
+type EnvironmentPromotion struct {
+	ID string `gorm:"primaryKey;size:64" json:"promotion_id"`
+	// FunctionID is the function whose environments were promoted.
+	FunctionID     string `gorm:"size:64;index" json:"function_id"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	FromFunctionID string `json:"from_function_id"`
+	// ToFunctionID is empty when a missing target could not be created.
+	ToFunctionID string `json:"to_function_id,omitempty"`
+	CodeSHA256   string `json:"code_sha256"`
+	Commit       string `json:"commit,omitempty"`
+	PromotedBy   string `json:"promoted_by,omitempty"`
+	// Outcome is one of the Promotion constants; Error says what went wrong
+	// when it is PromotionFailed.
+	Outcome    string    `json:"outcome"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	PromotedAt time.Time `gorm:"index" json:"promoted_at"`
+}
+
+// EnvironmentPromotionRepository persists promotion records.
+type EnvironmentPromotionRepository interface {
+	Create(ctx context.Context, p *EnvironmentPromotion) error
+	// List returns a function's promotions, newest first.
+	List(ctx context.Context, functionID string, limit int) ([]EnvironmentPromotion, error)
+	DeleteByFunction(ctx context.Context, functionID string) error
+}
+
+// WithEnvironmentPromotionRepository keeps a record of each promotion, see
+// ListEnvironmentPromotions.
+func WithEnvironmentPromotionRepository(repo EnvironmentPromotionRepository) Option {
+	return func(m *Manager) { m.promotions = repo }
+}
+
+// ListEnvironmentPromotions returns the promotions between a function's
+// environments, newest first.
+func (m *Manager) ListEnvironmentPromotions(ctx context.Context, functionID string, limit int) ([]EnvironmentPromotion, error) {
+	if m.promotions == nil {
+		return nil, fmt.Errorf("%w: promotion records", ErrNotConfigured)
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidArgument)
+	}
+	if limit == 0 {
+		limit = defaultPromotionLimit
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	return m.promotions.List(ctx, fn.ID, min(limit, maxPromotionLimit))
+}
+
+// recordPromotion stores the record of a finished promotion of parent's
+// environments to target, with the outcome of err. Failures to store are
+// logged.
+func (m *Manager) recordPromotion(ctx context.Context, parentID, to string, promotion *Promotion, target *Function, err error) {
+	if m.promotions == nil {
+		return
+	}
+	p := &EnvironmentPromotion{
+		ID:             rand.ID16(),
+		FunctionID:     parentID,
+		From:           promotion.From,
+		To:             to,
+		FromFunctionID: promotion.FromFunctionID,
+		CodeSHA256:     promotion.CodeSHA256,
+		Commit:         promotion.Commit,
+		PromotedBy:     promotion.PromotedBy,
+		Outcome:        PromotionPromoted,
+		PromotedAt:     promotion.PromotedAt,
+	}
+	if target != nil {
+		p.ToFunctionID = target.ID
+		if target.BuildStatus == BuildRunning {
+			p.Outcome = PromotionBuilding
+		}
+	}
+	if err != nil {
+		p.Outcome, p.Error = PromotionFailed, err.Error()
+	}
+	if err := m.promotions.Create(context.WithoutCancel(ctx), p); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", parentID).Str("to", to).Msg("failed to record promotion")
+	}
+}
+
+// deletePromotions removes the promotion records of a purged function.
+func (m *Manager) deletePromotions(ctx context.Context, functionID string) {
+	if m.promotions == nil {
+		return
+	}
+	if err := m.promotions.DeleteByFunction(ctx, functionID); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", functionID).Msg("failed to delete promotions of purged function")
+	}
+}
@@ -0,0 +1,39 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetPublic serves a function at /f/{name}, or stops serving it there. Only
+// a function with a name can be public.
+func (m *Manager) SetPublic(ctx context.Context, functionID string, public bool) (*Function, error) {
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if public && fn.Name == "" {
+		return nil, fmt.Errorf("%w: only a function with a name can be public", ErrInvalidArgument)
+	}
+	fn.Public = public
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update public: %w", err)
+	}
+	m.log(ctx).Info().Str("function_id", fn.ID).Str("name", fn.Name).Bool("public", public).Msg("function visibility changed")
+	return fn, nil
+}
+
+// PublicFunction finds the public function with a name. Functions that are
+// not public, or deleted, are reported as not found, so the names of private
+// functions are not given away.
+func (m *Manager) PublicFunction(ctx context.Context, name string) (*Function, error) {
+	fn, err := m.repo.FindByName(ctx, name)
+	if errors.Is(err, ErrNotFound) || err == nil && (!fn.Public || fn.DeletedAt != nil) {
+		return nil, fmt.Errorf("%w: no public function '%s'", ErrFunctionNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
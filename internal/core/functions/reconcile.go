@@ -0,0 +1,239 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// WorkerInspector is implemented by orchestrators that can report what
+// actually runs for a function, so the database can be reconciled with it.
+type WorkerInspector interface {
+	// InspectWorker returns nil when the function has no worker.
+	InspectWorker(ctx context.Context, functionID string) (*WorkerState, error)
+}
+
+// WorkerState is a function's worker as the orchestrator sees it.
+type WorkerState struct {
+	ContainerID string
+	// HostPort and Endpoint are left empty when the orchestrator cannot
+	// tell them, e.g. while no task of the worker is running.
+	HostPort int
+	Endpoint string
+	// Endpoints lists the base URLs of the replicas still running when the
+	// worker was started with more than one.
+	Endpoints []string
+	// Failed is set when the worker died and the orchestrator will not bring
+	// it back, e.g. it exited or keeps crashing. Reason says why.
+	Failed bool
+	Reason string
+	Status WorkerStatus
+}
+
+// WorkerStatus is the live state of a function's worker.
+type WorkerStatus struct {
+	DesiredReplicas int `json:"desired_replicas"`
+	ReadyReplicas   int `json:"ready_replicas"`
+	// Restarts counts the times the orchestrator restarted the worker's
+	// containers, where it reports them.
+	Restarts int `json:"restarts"`
+}
+
+// errWorkerDied is the cause recorded for functions whose worker died.
+var errWorkerDied = errors.New("worker died")
+
+// Failed restarts are retried after restartBackoffMin, doubling with each
+// failure up to restartBackoffMax.
+const (
+	restartBackoffMin = 30 * time.Second
+	restartBackoffMax = 30 * time.Minute
+)
+
+// restartBackoff is how long to wait before the next attempt to start a
+// worker that failed to start attempts times in a row.
+func restartBackoff(attempts int) time.Duration {
+	d := restartBackoffMin
+	for i := 1; i < attempts && d < restartBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, restartBackoffMax)
+}
+
+// ReconcileEvery reconciles running functions with their workers at the
+// given interval until ctx is done. Only the leader reconciles.
+func (m *Manager) ReconcileEvery(ctx context.Context, interval time.Duration) {
+	if _, ok := m.orchestrator.(WorkerInspector); !ok || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !m.IsLeader() {
+			continue
+		}
+		if err := m.Reconcile(ctx); err != nil {
+			m.lg.Error().Err(err).Msg("reconcile workers")
+		}
+	}
+}
+
+// Reconcile compares every running function with its worker:
+//   - a worker missing in two passes in a row is started again; a single
+//     pass may run while the manager replaces or stops the worker,
+//   - a worker that died leaves its function in the "error" status,
+//   - a container ID, port or endpoint that changed is recorded,
+//   - a worker that could not be started again is retried, with backoff.
+func (m *Manager) Reconcile(ctx context.Context) error {
+	inspector, ok := m.orchestrator.(WorkerInspector)
+	if !ok {
+		return fmt.Errorf("%w: worker inspection for %s", ErrNotConfigured, m.cfg.DeploymentEnv)
+	}
+	running, err := m.repo.FindByStatus(ctx, "running")
+	if err != nil {
+		return fmt.Errorf("could not query running functions: %w", err)
+	}
+	ids := make(map[string]bool, len(running))
+	for _, fn := range running {
+		ids[fn.ID] = true
+	}
+	m.missingWorkers.Range(func(id, _ any) bool {
+		if !ids[id.(string)] {
+			m.missingWorkers.Delete(id)
+		}
+		return true
+	})
+	for i := range running {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fn := &running[i]
+		state, err := inspector.InspectWorker(ctx, fn.ID)
+		if err != nil {
+			m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to inspect worker")
+			continue
+		}
+		m.reconcileWorker(ctx, fn, state)
+	}
+	return m.retryRestarts(ctx)
+}
+
+// retryRestarts starts the workers of functions left in the error status by
+// a failed restart again, once their backoff is over.
+func (m *Manager) retryRestarts(ctx context.Context) error {
+	failed, err := m.repo.FindByStatus(ctx, StatusError)
+	if err != nil {
+		return fmt.Errorf("could not query failed functions: %w", err)
+	}
+	now := time.Now()
+	for i := range failed {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fn := &failed[i]
+		if fn.NextRestartAt == nil || now.Before(*fn.NextRestartAt) {
+			continue
+		}
+		// The function may have been stopped or redeployed since it was
+		// listed.
+		current, err := m.repo.Get(ctx, fn.ID)
+		if err != nil || current.Status != StatusError || current.NextRestartAt == nil || !current.NextRestartAt.Equal(*fn.NextRestartAt) {
+			continue
+		}
+		lg := m.lg.With().Str("function_id", fn.ID).Int("attempt", current.RestartAttempts+1).Logger()
+		lg.Info().Msg("starting worker again after failed restart")
+		m.workerClients.Delete(fn.ID)
+		if err := m.restartWorker(ctx, current); err != nil {
+			lg.Error().Err(err).Msg("failed to restart worker")
+		}
+	}
+	return nil
+}
+
+func (m *Manager) reconcileWorker(ctx context.Context, fn *Function, state *WorkerState) {
+	if state != nil {
+		m.missingWorkers.Delete(fn.ID)
+	} else if seen, ok := m.missingWorkers.Swap(fn.ID, fn.ContainerID); !ok || seen != fn.ContainerID {
+		return
+	}
+	if state != nil && !state.Failed && !workerChanged(fn, state) {
+		return
+	}
+	// The function may have been stopped, deleted or redeployed since it was
+	// listed; only a record that is still current is changed.
+	current, err := m.repo.Get(ctx, fn.ID)
+	if err != nil || current.Status != "running" || current.ContainerID != fn.ContainerID {
+		return
+	}
+	fn = current
+	lg := m.lg.With().Str("function_id", fn.ID).Str("container_id", fn.ContainerID).Logger()
+
+	switch {
+	case state == nil:
+		lg.Warn().Msg("worker is missing, starting it again")
+		m.missingWorkers.Delete(fn.ID)
+		m.workerClients.Delete(fn.ID)
+		if err := m.restartWorker(ctx, fn); err != nil {
+			lg.Error().Err(err).Msg("failed to restart missing worker")
+		}
+	case state.Failed:
+		m.markWorkerDied(ctx, fn, state.Reason)
+	default:
+		m.adoptWorker(ctx, fn, state)
+	}
+}
+
+// markWorkerDied leaves fn in the "error" status with the reason its worker
+// died.
+func (m *Manager) markWorkerDied(ctx context.Context, fn *Function, reason string) {
+	m.lg.Warn().Str("function_id", fn.ID).Str("container_id", fn.ContainerID).Str("reason", reason).
+		Msg("worker died, marking function as error")
+	fn.Status = StatusError
+	fn.StatusReason = reason
+	fn.NextRestartAt = nil
+	if err := m.repo.Update(ctx, fn); err != nil {
+		m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record")
+		return
+	}
+	m.emitFunctionEvent(ctx, EventFunctionFailed, fn, fmt.Errorf("%w: %s", errWorkerDied, reason))
+}
+
+// workerChanged reports whether a live worker differs from what fn records.
+func workerChanged(fn *Function, state *WorkerState) bool {
+	return state.ContainerID != fn.ContainerID ||
+		(state.HostPort != 0 && state.HostPort != fn.HostPort) ||
+		(state.Endpoint != "" && state.Endpoint != fn.Endpoint) ||
+		(state.Endpoints != nil && !slices.Equal(state.Endpoints, fn.Endpoints))
+}
+
+// adoptWorker records a live worker's container ID, port and endpoint when
+// they differ from fn's.
+func (m *Manager) adoptWorker(ctx context.Context, fn *Function, state *WorkerState) {
+	if !workerChanged(fn, state) {
+		return
+	}
+	m.lg.Info().Str("function_id", fn.ID).Str("container_id", fn.ContainerID).Str("new_container_id", state.ContainerID).
+		Int("host_port", state.HostPort).Str("endpoint", state.Endpoint).Msg("worker changed, updating function record")
+	fn.ContainerID = state.ContainerID
+	if state.HostPort != 0 {
+		fn.HostPort = state.HostPort
+	}
+	if state.Endpoint != "" {
+		fn.Endpoint = state.Endpoint
+	}
+	if state.Endpoints != nil {
+		fn.Endpoints = state.Endpoints
+	}
+	if err := m.repo.Update(ctx, fn); err != nil {
+		m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record")
+		return
+	}
+	// Pooled connections point at the old worker.
+	m.workerClients.Delete(fn.ID)
+}
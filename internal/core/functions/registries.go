@@ -0,0 +1,155 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"service-faas/pkg/rand"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+)
+
+// SetRegistryCredential stores (or replaces) the tenant's credentials for a
+// registry server.
+func (m *Manager) SetRegistryCredential(ctx context.Context, tenant, server, username, password string) (*RegistryCredential, error) {
+	server = normalizeServer(server)
+	if tenant == "" || server == "" || username == "" || password == "" {
+		return nil, fmt.Errorf("%w: tenant, server, username and password are required", ErrInvalidArgument)
+	}
+
+	cred, err := m.creds.Find(ctx, tenant, server)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("look up registry credential: %w", err)
+	}
+	if cred == nil {
+		cred = &RegistryCredential{
+			ID:        rand.ID16(),
+			Tenant:    tenant,
+			Server:    server,
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+	cred.Username = username
+	cred.Password = password
+
+	if err := m.creds.Save(ctx, cred); err != nil {
+		return nil, fmt.Errorf("db save registry credential: %w", err)
+	}
+	m.log(ctx).Info().Str("tenant", tenant).Str("server", server).Msg("registry credential stored")
+	return cred, nil
+}
+
+func (m *Manager) ListRegistryCredentials(ctx context.Context, tenant string) ([]RegistryCredential, error) {
+	return m.creds.ListByTenant(ctx, tenant)
+}
+
+func (m *Manager) DeleteRegistryCredential(ctx context.Context, tenant, id string) error {
+	if err := m.creds.Delete(ctx, tenant, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("db delete registry credential: %w", err)
+	}
+	return nil
+}
+
+// workerSpec builds the orchestrator spec for a function, resolving its image
+// and, for tenant images, the tenant's pull credentials for that registry.
+func (m *Manager) workerSpec(ctx context.Context, fn *Function) (WorkerSpec, error) {
+	image, err := m.workerImage(fn)
+	if err != nil {
+		return WorkerSpec{}, err
+	}
+	workerTLS, err := m.issueWorkerTLS(fn.ID)
+	if err != nil {
+		return WorkerSpec{}, err
+	}
+	spec := WorkerSpec{
+		FunctionID:  fn.ID,
+		HandlerPath: fn.HandlerPath,
+		Image:       image,
+		Exposure:    fn.Exposure,
+		Labels:      fn.Labels,
+		Env:         fn.EnvVars,
+		Identity:    m.identity != nil,
+		TLS:         workerTLS,
+		Replicas:    fn.Replicas,
+		Kubernetes:  fn.Kubernetes,
+	}
+	src, err := m.code.Source(ctx, fn.ID)
+	if err != nil {
+		return spec, fmt.Errorf("locate handler code: %w", err)
+	}
+	spec.CodePath, spec.CodeURL = src.Dir, src.URL
+	if fn.BuiltImage != "" {
+		// Built images are pushed with the global registry login.
+		spec.Image, spec.Built = fn.BuiltImage, true
+		return spec, nil
+	}
+	if fn.DependencyLockSHA256 != "" {
+		spec.DependencyLock = lockFile
+	}
+	if fn.WorkerImage == "" {
+		return spec, nil
+	}
+	spec.RegistryAuth, err = m.registryAuth(ctx, fn.Tenant, fn.WorkerImage)
+	return spec, err
+}
+
+// registryAuth returns the tenant's pull credentials for the registry of a
+// custom image, or nil when the tenant has none for it.
+func (m *Manager) registryAuth(ctx context.Context, tenant, image string) (*RegistryAuth, error) {
+	if tenant == "" {
+		return nil, nil
+	}
+	server, err := imageRegistry(image)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	cred, err := m.creds.Find(ctx, tenant, server)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up registry credential: %w", err)
+	}
+	return &RegistryAuth{
+		Server:   cred.Server,
+		Username: cred.Username,
+		Password: cred.Password,
+	}, nil
+}
+
+// validateWorkerImage checks a per-function worker image reference and that
+// its registry is on the allowlist, which "*" opens to every registry.
+func (m *Manager) validateWorkerImage(image string) error {
+	server, err := imageRegistry(image)
+	if err != nil {
+		return err
+	}
+	for _, allowed := range m.cfg.WorkerImageRegistries {
+		if allowed == "*" || strings.EqualFold(normalizeServer(allowed), server) {
+			return nil
+		}
+	}
+	return fmt.Errorf("worker images from registry %s are not allowed", server)
+}
+
+// imageRegistry returns the registry host of an image reference, e.g.
+// "docker.io" for "python:3.12" or "harbor.example.com" for
+// "harbor.example.com/team/worker:1".
+func imageRegistry(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	return reference.Domain(named), nil
+}
+
+func normalizeServer(server string) string {
+	server = strings.TrimPrefix(server, "https://")
+	server = strings.TrimPrefix(server, "http://")
+	return strings.TrimRight(server, "/")
+}
@@ -0,0 +1,117 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"service-faas/internal/config"
+	"slices"
+
+	"github.com/rs/zerolog"
+)
+
+// hotSettings are the Config fields a reload applies while the manager runs.
+// Changes to any other field are reported as needing a restart.
+var hotSettings = []string{
+	"LogLevel",
+	"MaxInFlightExecutions", "ExecutionQueueSize", "ExecutionQueueTimeout",
+	"ConcurrencyQueueTimeout",
+	"CircuitFailureThreshold", "CircuitOpenDuration",
+	"StreamExecuteConcurrency",
+	"WarmupMaxRequests", "WarmupTimeout",
+	"HarborUser", "HarborPass",
+	"APIToken",
+}
+
+// RegistryLoginSetter is implemented by collaborators that log in to the
+// Harbor registry, so a reload can replace the login.
+type RegistryLoginSetter interface {
+	SetRegistryLogin(user, password string) error
+}
+
+// ReloadReport lists, by Config field name, the settings a reload changed.
+type ReloadReport struct {
+	// Applied were changed in place.
+	Applied []string `json:"applied"`
+	// RestartRequired differ from the running configuration but only take
+	// effect when the manager restarts.
+	RestartRequired []string `json:"restart_required"`
+}
+
+// WithConfigSource sets where ReloadConfig reads the configuration from,
+// usually the environment and config file the manager was started with.
+func WithConfigSource(load func() (config.Config, error)) Option {
+	return func(m *Manager) { m.configSource = load }
+}
+
+// settings returns the configuration with the reloaded hot settings. Code
+// reading a hot setting must read it from here rather than m.cfg.
+func (m *Manager) settings() *config.Config {
+	return m.live.Load()
+}
+
+// ReloadConfig loads the configuration from the config source and applies
+// it with Reload.
+func (m *Manager) ReloadConfig(ctx context.Context) (*ReloadReport, error) {
+	if m.configSource == nil {
+		return nil, fmt.Errorf("%w: no configuration source to reload from", ErrNotConfigured)
+	}
+	cfg, err := m.configSource()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	return m.Reload(ctx, cfg)
+}
+
+// Reload applies the hot settings of cfg without restarting workers:
+// executions already admitted finish under the old limits, later ones use
+// the new. In HA mode only this replica is reloaded.
+func (m *Manager) Reload(ctx context.Context, cfg config.Config) (*ReloadReport, error) {
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid log level %q", ErrInvalidArgument, cfg.LogLevel)
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	current := m.settings()
+	next := *current
+	report := &ReloadReport{Applied: []string{}, RestartRequired: []string{}}
+	cur, want, dst := reflect.ValueOf(*current), reflect.ValueOf(cfg), reflect.ValueOf(&next).Elem()
+	for i := range cur.NumField() {
+		name := cur.Type().Field(i).Name
+		if reflect.DeepEqual(cur.Field(i).Interface(), want.Field(i).Interface()) {
+			continue
+		}
+		if !slices.Contains(hotSettings, name) {
+			report.RestartRequired = append(report.RestartRequired, name)
+			continue
+		}
+		dst.Field(i).Set(want.Field(i))
+		report.Applied = append(report.Applied, name)
+	}
+
+	changed := func(names ...string) bool {
+		return slices.ContainsFunc(names, func(n string) bool { return slices.Contains(report.Applied, n) })
+	}
+	if changed("HarborUser", "HarborPass") {
+		for _, c := range []any{m.orchestrator, m.digests} {
+			if setter, ok := c.(RegistryLoginSetter); ok {
+				if err := setter.SetRegistryLogin(next.HarborUser, next.HarborPass); err != nil {
+					return nil, fmt.Errorf("replace registry login: %w", err)
+				}
+			}
+		}
+	}
+	if changed("MaxInFlightExecutions", "ExecutionQueueSize", "ExecutionQueueTimeout") {
+		m.admission.resize(next.MaxInFlightExecutions, next.ExecutionQueueSize, next.ExecutionQueueTimeout)
+	}
+	if changed("LogLevel") {
+		zerolog.SetGlobalLevel(level)
+	}
+	m.live.Store(&next)
+
+	m.log(ctx).Info().Strs("applied", report.Applied).Strs("restart_required", report.RestartRequired).
+		Msg("configuration reloaded")
+	return report, nil
+}
@@ -0,0 +1,236 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"service-faas/pkg/rand"
+)
+
+// maxResultChanges caps the changes an InvocationReplay lists.
+const maxResultChanges = 100
+
+// InvocationReplay compares an invocation with a replay of its payload
+// against the function's current code.
+type InvocationReplay struct {
+	Original *Invocation `json:"original"`
+	Replay   *Invocation `json:"replay"`
+	// CodeChanged reports whether the replay ran other code than the
+	// original.
+	CodeChanged bool `json:"code_changed"`
+	// Compared is false when a result was stored truncated or offloaded, so
+	// the results could not be compared and Same is false.
+	Compared bool `json:"compared"`
+	// Same reports whether the replay ended like the original: both with an
+	// equal result, or both failed with the same error class and exception.
+	Same bool `json:"same"`
+	// Changes lists where the results differ, at most 100 of them.
+	Changes []ResultChange `json:"changes,omitempty"`
+}
+
+// ResultChange is a value that differs between two results.
+type ResultChange struct {
+	// Path is the JSON Pointer of the value, e.g. "/items/0/price"; "" is
+	// the whole result.
+	Path string `json:"path"`
+	// Old is absent when the value was added, New when it was removed.
+	Old json.RawMessage `json:"old,omitempty" swaggertype:"object"`
+	New json.RawMessage `json:"new,omitempty" swaggertype:"object"`
+}
+
+type skipCacheKey struct{}
+
+// withoutCache returns a context whose executions call the worker even when
+// the response cache holds their result.
+func withoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+func cacheSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+	return skip
+}
+
+// ReplayInvocation runs a stored invocation's payload again against the
+// function's current code, bypassing the response cache, and compares the
+// outcomes. The original must have been stored with its payload, see
+// InvocationCapturePayloads. The replay is an invocation of its own; its
+// failure is reported in the comparison, not as an error.
+func (m *Manager) ReplayInvocation(ctx context.Context, id string) (*InvocationReplay, error) {
+	original, err := m.GetInvocation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !original.PayloadCaptured {
+		return nil, fmt.Errorf("%w: invocation '%s' was stored without its payload", ErrInvalidArgument, id)
+	}
+	replay := &Invocation{ID: rand.ID16(), FunctionID: original.FunctionID, ReplayOf: original.ID}
+	_, execErr := m.executeInvocation(withoutCache(ctx), replay, original.Payload)
+	stored, err := m.invocations.Get(ctx, replay.ID)
+	if err != nil {
+		// Executions rejected before they started, such as those of deleted
+		// functions, are not stored.
+		if execErr != nil {
+			return nil, execErr
+		}
+		return nil, fmt.Errorf("get replay: %w", err)
+	}
+	cmp := &InvocationReplay{
+		Original:    original,
+		Replay:      stored,
+		CodeChanged: original.CodeSHA256 != stored.CodeSHA256,
+	}
+	cmp.compare()
+	return cmp, nil
+}
+
+func (c *InvocationReplay) compare() {
+	a, b := c.Original, c.Replay
+	c.Compared = true
+	switch {
+	case a.Status != b.Status:
+	case a.Status == InvocationFailed:
+		c.Same = a.ErrorClass == b.ErrorClass && sameException(a.Exception, b.Exception)
+	case a.Truncated || b.Truncated || a.ResultRef != nil || b.ResultRef != nil:
+		c.Compared = false
+	default:
+		c.Changes = diffResults(a.Result, b.Result)
+		c.Same = len(c.Changes) == 0
+	}
+}
+
+// sameException reports whether two handler exceptions, or their absence,
+// match. Tracebacks are not compared, as line numbers move with the code.
+func sameException(a, b *HandlerError) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && a.Message == b.Message
+}
+
+// diffResults lists the values that differ between two JSON results. Objects
+// are compared by key and arrays by index; results that are not JSON are
+// compared as a whole.
+func diffResults(before, after json.RawMessage) []ResultChange {
+	oldValue, oldErr := decodeResult(before)
+	newValue, newErr := decodeResult(after)
+	if oldErr != nil || newErr != nil {
+		if bytes.Equal(before, after) {
+			return nil
+		}
+		return []ResultChange{{Old: before, New: after}}
+	}
+	var changes []ResultChange
+	_ = diffValues("", oldValue, newValue, &changes) // errEnoughChanges stops it early
+	return changes
+}
+
+// decodeResult decodes a result keeping numbers as written; an empty result
+// is null.
+func decodeResult(raw json.RawMessage) (any, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data")
+	}
+	return v, nil
+}
+
+// errEnoughChanges stops diffValues once maxResultChanges are listed.
+var errEnoughChanges = errors.New("enough changes")
+
+func diffValues(path string, before, after any, changes *[]ResultChange) error {
+	if len(*changes) >= maxResultChanges {
+		return errEnoughChanges
+	}
+	switch o := before.(type) {
+	case map[string]any:
+		n, ok := after.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(o)+len(n))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		for k := range n {
+			if _, ok := o[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			p := path + "/" + escapePointer(k)
+			var err error
+			if inOld && inNew {
+				err = diffValues(p, ov, nv, changes)
+			} else {
+				err = addChange(p, ov, inOld, nv, inNew, changes)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		n, ok := after.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(o), len(n)); i++ {
+			p := path + "/" + strconv.Itoa(i)
+			var err error
+			if i < len(o) && i < len(n) {
+				err = diffValues(p, o[i], n[i], changes)
+			} else if i < len(o) {
+				err = addChange(p, o[i], true, nil, false, changes)
+			} else {
+				err = addChange(p, nil, false, n[i], true, changes)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	return addChange(path, before, true, after, true, changes)
+}
+
+func addChange(path string, before any, hasBefore bool, after any, hasAfter bool, changes *[]ResultChange) error {
+	if len(*changes) >= maxResultChanges {
+		return errEnoughChanges
+	}
+	change := ResultChange{Path: path}
+	if hasBefore {
+		change.Old, _ = json.Marshal(before)
+	}
+	if hasAfter {
+		change.New, _ = json.Marshal(after)
+	}
+	*changes = append(*changes, change)
+	return nil
+}
+
+// escapePointer escapes a key for a JSON Pointer (RFC 6901).
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
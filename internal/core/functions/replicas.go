@@ -0,0 +1,166 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"service-faas/internal/config"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxReplicas caps the worker containers of one function.
+const maxReplicas = 16
+
+// replicaEjectFor is how long a replica that could not be reached is left
+// out of the rotation.
+const replicaEjectFor = 30 * time.Second
+
+func (m *Manager) validateReplicas(n int) error {
+	if n < 0 || n > maxReplicas {
+		return fmt.Errorf("%w: replicas must be between 0 and %d", ErrInvalidArgument, maxReplicas)
+	}
+	if n > 1 && m.cfg.DeploymentEnv != config.EnvDocker {
+		return fmt.Errorf("%w: replicas are only supported in docker mode; other orchestrators scale workers themselves", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// SetReplicas changes how many worker containers serve a function. A
+// running function's worker is restarted with that many.
+func (m *Manager) SetReplicas(ctx context.Context, functionID string, n int) (*Function, error) {
+	if err := m.validateReplicas(n); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.Replicas = n
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update replicas: %w", err)
+	}
+	if fn.Status != "running" {
+		return fn, nil
+	}
+	m.workerClients.Delete(fn.ID)
+	if err := m.restartWorker(ctx, fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// replicaBalancer spreads a function's executions over its replicas: each
+// call goes to the replica with the fewest calls in flight, ties broken in
+// turn, or with an affinity key to the replica the key hashes to. A replica
+// that cannot be reached is ejected for replicaEjectFor.
+type replicaBalancer struct {
+	endpoints []string
+	ring      *hashRing
+
+	mu       sync.Mutex
+	inFlight []int
+	ejected  []time.Time // until when each replica is left out
+	next     int
+}
+
+func newReplicaBalancer(endpoints []string) *replicaBalancer {
+	return &replicaBalancer{
+		endpoints: endpoints,
+		ring:      newHashRing(endpoints),
+		inFlight:  make([]int, len(endpoints)),
+		ejected:   make([]time.Time, len(endpoints)),
+	}
+}
+
+// pick chooses the replica for a call and counts the call as in flight.
+// When every replica is ejected, the one whose ejection ends first is tried.
+func (b *replicaBalancer) pick(now time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	best, soonest := -1, -1
+	for k := range b.endpoints {
+		i := (b.next + k) % len(b.endpoints)
+		if b.ejected[i].After(now) {
+			if soonest < 0 || b.ejected[i].Before(b.ejected[soonest]) {
+				soonest = i
+			}
+			continue
+		}
+		if best < 0 || b.inFlight[i] < b.inFlight[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		best = soonest
+	}
+	b.next = (best + 1) % len(b.endpoints)
+	b.inFlight[best]++
+	return best
+}
+
+// pickKey chooses the replica for a call with an affinity key: the first
+// replica on the ring from the key that is not ejected. Keys of an ejected
+// replica move to the next one until it is back.
+func (b *replicaBalancer) pickKey(key string, now time.Time) int {
+	b.mu.Lock()
+	chosen := -1
+	b.ring.walk(key, func(i int) bool {
+		if b.ejected[i].After(now) {
+			return false
+		}
+		chosen = i
+		return true
+	})
+	if chosen < 0 {
+		b.mu.Unlock()
+		return b.pick(now)
+	}
+	defer b.mu.Unlock()
+	b.inFlight[chosen]++
+	return chosen
+}
+
+// done ends a call to replica i, ejecting the replica when it could not be
+// reached and taking it back when it answered. It reports whether the
+// replica was ejected.
+func (b *replicaBalancer) done(i int, unreachable bool, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inFlight[i]--
+	if !unreachable {
+		b.ejected[i] = time.Time{}
+		return false
+	}
+	b.ejected[i] = now.Add(replicaEjectFor)
+	return true
+}
+
+// pickEndpoint returns the endpoint to call fn's worker at and the func to
+// call with the outcome. Functions with a single replica always get
+// fn.Endpoint; a non-empty key picks the replica by affinity.
+func (m *Manager) pickEndpoint(ctx context.Context, fn *Function, key string) (string, func(error)) {
+	if len(fn.Endpoints) < 2 {
+		return fn.Endpoint, func(error) {}
+	}
+	v, ok := m.balancers.Load(fn.ID)
+	b, _ := v.(*replicaBalancer)
+	if !ok || !slices.Equal(b.endpoints, fn.Endpoints) {
+		// The worker was started again with other replicas.
+		b = newReplicaBalancer(fn.Endpoints)
+		m.balancers.Store(fn.ID, b)
+	}
+	var i int
+	if key != "" {
+		i = b.pickKey(key, time.Now())
+	} else {
+		i = b.pick(time.Now())
+	}
+	return b.endpoints[i], func(err error) {
+		if b.done(i, errors.Is(err, ErrWorkerUnavailable), time.Now()) {
+			m.log(ctx).Warn().Err(err).Str("function_id", fn.ID).Str("endpoint", b.endpoints[i]).
+				Dur("for", replicaEjectFor).Msg("worker replica unreachable, ejecting it")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"context"
+	"errors"
+)
+
+// FunctionRepository persists function records. Get, FindByName and
+// FindByDomain return ErrNotFound for unknown IDs, names and domains.
+type FunctionRepository interface {
+	Create(ctx context.Context, fn *Function) error
+	Get(ctx context.Context, id string) (*Function, error)
+	FindByName(ctx context.Context, name string) (*Function, error)
+	FindByDomain(ctx context.Context, domain string) (*Function, error)
+	List(ctx context.Context) ([]Function, error)
+	// Update saves all fields of an existing record.
+	Update(ctx context.Context, fn *Function) error
+	Delete(ctx context.Context, id string) error
+	FindByStatus(ctx context.Context, status string) ([]Function, error)
+	// Search returns up to limit functions that are not soft-deleted, ranked
+	// by relevance to the query (see SearchFunctions).
+	Search(ctx context.Context, query string, limit int) ([]SearchHit, error)
+}
+
+// RegistryCredentialRepository persists tenant registry credentials. Find and
+// Delete return ErrNotFound when no credential matches.
+type RegistryCredentialRepository interface {
+	Find(ctx context.Context, tenant, server string) (*RegistryCredential, error)
+	ListByTenant(ctx context.Context, tenant string) ([]RegistryCredential, error)
+	// Save creates or replaces a credential by ID.
+	Save(ctx context.Context, cred *RegistryCredential) error
+	Delete(ctx context.Context, tenant, id string) error
+}
+
+// functionExists reports whether a function record with the given ID exists.
+func (m *Manager) functionExists(ctx context.Context, id string) (bool, error) {
+	_, err := m.repo.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,36 @@
+package functions
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// RequestIDHeader carries the ID of an API request to workers and hooks, and
+// back to the caller.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the API request it
+// serves. Manager logs for the request include it, and it is forwarded to
+// workers so their logs can be correlated.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// log returns the manager's logger, tagged with the request ID ctx carries.
+func (m *Manager) log(ctx context.Context) *zerolog.Logger {
+	id := RequestID(ctx)
+	if id == "" {
+		return &m.lg
+	}
+	lg := m.lg.With().Str("request_id", id).Logger()
+	return &lg
+}
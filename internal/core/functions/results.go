@@ -0,0 +1,63 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResultStore persists execution results that are too large to be returned
+// inline by the execute endpoint.
+type ResultStore interface {
+	Put(ctx context.Context, key string, data []byte) (*ResultRef, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Link returns a new URL to a stored result, and when it expires if it
+	// does.
+	Link(ctx context.Context, key string) (url string, expiresAt *time.Time, err error)
+}
+
+// CleanResultKey cleans a result key, "<function ID>/<name>", and returns it
+// with the function it belongs to. Keys with ".." segments or a leading
+// slash are refused, so a key cannot reach the results of another function.
+func CleanResultKey(key string) (cleaned, functionID string, err error) {
+	if strings.HasPrefix(key, "/") || slices.Contains(strings.Split(key, "/"), "..") {
+		return "", "", fmt.Errorf("%w: invalid result key %q", ErrInvalidArgument, key)
+	}
+	cleaned = path.Clean(key)
+	functionID, name, ok := strings.Cut(cleaned, "/")
+	if !ok || functionID == "" || name == "" {
+		return "", "", fmt.Errorf("%w: invalid result key %q", ErrInvalidArgument, key)
+	}
+	return cleaned, functionID, nil
+}
+
+// ResultRef points at an offloaded result.
+type ResultRef struct {
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	Size      int        `json:"size"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ExecutionResult is the outcome of an execution. Exactly one of Result or
+// ResultRef is set.
+type ExecutionResult struct {
+	Result    json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	ResultRef *ResultRef      `json:"result_ref,omitempty"`
+	// Degraded is set when the fallback function produced the result, to
+	// the reason the primary was skipped. It is reported as a header.
+	Degraded string `json:"-"`
+	// Cached is set when the result came from the response cache. It is
+	// reported as a header.
+	Cached bool `json:"-"`
+	// Replayed is set when the result was stored for an idempotency key by
+	// an earlier execution. It is reported as a header.
+	Replayed bool `json:"-"`
+	// InvocationID identifies the execution. It is reported as a header.
+	InvocationID string `json:"-"`
+}
@@ -0,0 +1,123 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// workerReadyTimeout is how long a staged worker has to answer before the
+// rollout gives up on it.
+const workerReadyTimeout = 2 * time.Minute
+
+// WorkerStager is implemented by orchestrators that can start a function's
+// new worker next to its running one, so a redeploy can check the new worker
+// before the old one is removed.
+type WorkerStager interface {
+	// StageWorker starts a worker for spec without touching the function's
+	// current worker.
+	StageWorker(ctx context.Context, spec WorkerSpec) (*RunResult, error)
+	// PromoteWorker removes the function's current worker and puts the
+	// staged one in its place, returning its details as they are now.
+	PromoteWorker(ctx context.Context, functionID string) (*RunResult, error)
+	// DiscardWorker removes the function's staged worker, if any.
+	DiscardWorker(ctx context.Context, functionID string) error
+}
+
+// rollOutWorker replaces a running function's worker after its code changed.
+// With a WorkerStager the new worker is started next to the old one and must
+// answer within workerReadyTimeout before it takes over; otherwise it is
+// discarded and the old worker keeps serving. Other orchestrators stop the
+// old worker first, see replaceWorker. stopped reports whether the old worker
+// is gone.
+func (m *Manager) rollOutWorker(ctx context.Context, fn *Function) (stopped bool, err error) {
+	stager, ok := m.orchestrator.(WorkerStager)
+	if !ok {
+		return true, m.replaceWorker(ctx, fn)
+	}
+	spec, err := m.workerSpec(ctx, fn)
+	if err == nil {
+		err = m.verifyCode(ctx, fn)
+	}
+	if err != nil {
+		return false, fmt.Errorf("build worker spec: %w", err)
+	}
+	if err := m.publishIdentity(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to publish identity token")
+	}
+
+	discard := func() {
+		if err := stager.DiscardWorker(context.WithoutCancel(ctx), fn.ID); err != nil {
+			m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to remove staged worker")
+		}
+	}
+	staged, err := stager.StageWorker(ctx, spec)
+	if err == nil {
+		err = m.waitWorkerReady(ctx, fn.ID, staged)
+	}
+	if err != nil {
+		discard()
+		return false, fmt.Errorf("start new worker: %w", err)
+	}
+	result, err := stager.PromoteWorker(ctx, fn.ID)
+	if err != nil {
+		// The old worker may be gone already.
+		discard()
+		return true, fmt.Errorf("promote new worker: %w", err)
+	}
+	m.workerClients.Delete(fn.ID)
+
+	fn.ContainerID = result.ContainerID
+	fn.HostPort = result.HostPort
+	fn.Endpoint = result.Endpoint
+	fn.Endpoints = result.Endpoints
+	fn.PublicURL = result.PublicURL
+	fn.Status = "running"
+	fn.StatusReason = ""
+	fn.RestartAttempts = 0
+	fn.NextRestartAt = nil
+	now := time.Now().UTC()
+	fn.DeployedAt = &now
+	if err := m.repo.Update(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record after rollout")
+	}
+	m.emitDeployOutcome(ctx, fn, nil)
+	m.markCold(fn)
+	m.warmAfterDeploy(fn)
+	return true, nil
+}
+
+// waitWorkerReady waits until each endpoint of a staged worker answers HTTP,
+// with any status, or workerReadyTimeout passes.
+func (m *Manager) waitWorkerReady(ctx context.Context, functionID string, w *RunResult) error {
+	client, err := m.workerClient(functionID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, workerReadyTimeout)
+	defer cancel()
+	endpoints := w.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = []string{w.Endpoint}
+	}
+	for _, endpoint := range endpoints {
+		for {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+			if err != nil {
+				return fmt.Errorf("create request: %w", err)
+			}
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("worker at %s did not answer within %s: %w", endpoint, workerReadyTimeout, err)
+			case <-time.After(time.Second):
+			}
+		}
+	}
+	return nil
+}
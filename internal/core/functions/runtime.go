@@ -0,0 +1,67 @@
+package functions
+
+import (
+	"maps"
+	"runtime"
+	"time"
+)
+
+// RuntimeStats is a snapshot of the manager process, to watch it under load.
+type RuntimeStats struct {
+	GoVersion     string    `json:"go_version"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	NumCPU        int       `json:"num_cpu"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	Goroutines    int       `json:"goroutines"`
+	// HeapAllocBytes is the memory of live heap objects, HeapInuseBytes of
+	// the heap spans in use and SysBytes all the memory taken from the OS.
+	HeapAllocBytes uint64     `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64     `json:"heap_inuse_bytes"`
+	SysBytes       uint64     `json:"sys_bytes"`
+	HeapObjects    uint64     `json:"heap_objects"`
+	NumGC          uint32     `json:"num_gc"`
+	GCPauseTotalMS float64    `json:"gc_pause_total_ms"`
+	LastGC         *time.Time `json:"last_gc,omitempty"`
+	// InFlightExecutions counts the executions running, and
+	// InFlightByFunction the executions of each function that has some.
+	InFlightExecutions int            `json:"in_flight_executions"`
+	InFlightByFunction map[string]int `json:"in_flight_by_function,omitempty"`
+	// QueuedExecutions wait for admission, see MAX_INFLIGHT_EXECUTIONS.
+	QueuedExecutions int64 `json:"queued_executions"`
+	Draining         bool  `json:"draining"`
+	Leader           bool  `json:"leader"`
+}
+
+// RuntimeStats reads the manager's runtime and execution counters. Reading
+// the memory statistics briefly stops the world.
+func (m *Manager) RuntimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := RuntimeStats{
+		GoVersion:        runtime.Version(),
+		StartedAt:        m.startedAt,
+		UptimeSeconds:    int64(time.Since(m.startedAt) / time.Second),
+		NumCPU:           runtime.NumCPU(),
+		GOMAXPROCS:       runtime.GOMAXPROCS(0),
+		Goroutines:       runtime.NumGoroutine(),
+		HeapAllocBytes:   mem.HeapAlloc,
+		HeapInuseBytes:   mem.HeapInuse,
+		SysBytes:         mem.Sys,
+		HeapObjects:      mem.HeapObjects,
+		NumGC:            mem.NumGC,
+		GCPauseTotalMS:   float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		QueuedExecutions: m.admission.queuedExecutions(),
+		Leader:           m.IsLeader(),
+	}
+	if mem.LastGC > 0 {
+		last := time.Unix(0, int64(mem.LastGC)).UTC()
+		stats.LastGC = &last
+	}
+	m.inflight.mu.Lock()
+	stats.InFlightExecutions = m.inflight.n
+	stats.InFlightByFunction = maps.Clone(m.inflight.byFunction)
+	stats.Draining = m.inflight.draining
+	m.inflight.mu.Unlock()
+	return stats
+}
@@ -0,0 +1,117 @@
+package functions
+
+import (
+	"fmt"
+	"service-faas/internal/config"
+	"sort"
+	"strings"
+)
+
+// Runtime is a named worker image functions can select instead of naming an
+// image themselves.
+type Runtime struct {
+	ID      string `json:"id"`
+	Image   string `json:"image"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// RuntimeCatalog maps runtime identifiers, e.g. "python3.12", to worker
+// images. A nil catalog has no runtimes.
+type RuntimeCatalog struct {
+	images map[string]string
+	def    string
+}
+
+// NewRuntimeCatalog parses the WORKER_RUNTIMES entries ("id=image") and
+// checks that DEFAULT_RUNTIME is one of them.
+func NewRuntimeCatalog(cfg config.Config) (*RuntimeCatalog, error) {
+	c := &RuntimeCatalog{images: map[string]string{}, def: cfg.DefaultRuntime}
+	for _, entry := range cfg.WorkerRuntimes {
+		id, image, ok := strings.Cut(entry, "=")
+		id, image = strings.TrimSpace(id), strings.TrimSpace(image)
+		if !ok || id == "" || image == "" {
+			return nil, fmt.Errorf("runtime %q: want id=image", entry)
+		}
+		if _, err := imageRegistry(image); err != nil {
+			return nil, fmt.Errorf("runtime %q: %w", id, err)
+		}
+		if _, dup := c.images[id]; dup {
+			return nil, fmt.Errorf("runtime %q is defined twice", id)
+		}
+		c.images[id] = image
+	}
+	if c.def != "" {
+		if _, ok := c.images[c.def]; !ok {
+			return nil, fmt.Errorf("default runtime %q is not defined", c.def)
+		}
+	}
+	return c, nil
+}
+
+// WithRuntimeCatalog lets functions select their worker image by runtime.
+func WithRuntimeCatalog(catalog *RuntimeCatalog) Option {
+	return func(m *Manager) { m.runtimes = catalog }
+}
+
+// List returns the runtimes sorted by ID.
+func (c *RuntimeCatalog) List() []Runtime {
+	out := []Runtime{}
+	if c == nil {
+		return out
+	}
+	for id, image := range c.images {
+		out = append(out, Runtime{ID: id, Image: image, Default: id == c.def})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (c *RuntimeCatalog) image(id string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	image, ok := c.images[id]
+	return image, ok
+}
+
+func (c *RuntimeCatalog) defaultRuntime() string {
+	if c == nil {
+		return ""
+	}
+	return c.def
+}
+
+// ListRuntimes returns the runtimes functions can select.
+func (m *Manager) ListRuntimes() []Runtime {
+	return m.runtimes.List()
+}
+
+// selectRuntime gives options without a custom image or runtime the default
+// runtime and checks that the runtime exists.
+func (m *Manager) selectRuntime(opts *FunctionOptions) error {
+	if opts.WorkerImage != "" && opts.Runtime != "" {
+		return fmt.Errorf("%w: set either a worker image or a runtime, not both", ErrInvalidArgument)
+	}
+	if opts.WorkerImage == "" && opts.Runtime == "" {
+		opts.Runtime = m.runtimes.defaultRuntime()
+	}
+	_, err := m.baseImage(opts.Runtime, opts.WorkerImage)
+	return err
+}
+
+// baseImage returns the worker image of a function with the given runtime
+// and custom image: the custom image, else the runtime's image, else the
+// global worker image.
+func (m *Manager) baseImage(runtime, workerImage string) (string, error) {
+	if workerImage != "" {
+		return workerImage, nil
+	}
+	if runtime == "" {
+		return m.cfg.WorkerImage, nil
+	}
+	image, ok := m.runtimes.image(runtime)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown runtime %q", ErrInvalidArgument, runtime)
+	}
+	return image, nil
+}
@@ -0,0 +1,89 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrCodeBlocked is returned when uploaded code was found malicious. The
+// function is kept in the "blocked" status for investigation and never
+// deployed.
+var ErrCodeBlocked = errors.New("code blocked by malware scan")
+
+// CodeScanner scans uploaded code for malware.
+type CodeScanner interface {
+	// Scan reads the upload from r, usually to the end, and returns the
+	// verdict. An error means no verdict could be reached.
+	Scan(ctx context.Context, r io.Reader) (*ScanVerdict, error)
+}
+
+// ScanVerdict is the outcome of a malware scan.
+type ScanVerdict struct {
+	Clean bool
+	// Threat names what was found when not Clean.
+	Threat string
+}
+
+// WithCodeScanner scans every code upload with scanner.
+func WithCodeScanner(scanner CodeScanner) Option {
+	return func(m *Manager) { m.scanner = scanner }
+}
+
+// startScan streams everything read from code to the code scanner while the
+// upload is stored. The returned finish func must be called once storing is
+// done; it feeds the rest of the upload to the scanner, or aborts the scan
+// when storing failed, and returns the verdict.
+func (m *Manager) startScan(ctx context.Context, code io.Reader) (io.Reader, func(storeErr error) (*ScanVerdict, error)) {
+	if m.scanner == nil {
+		return code, func(error) (*ScanVerdict, error) { return &ScanVerdict{Clean: true}, nil }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.CodeScanTimeout)
+	pr, pw := io.Pipe()
+	type outcome struct {
+		verdict *ScanVerdict
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		verdict, err := m.scanner.Scan(ctx, pr)
+		// A scanner that stops reading early must not block the upload.
+		_, _ = io.Copy(io.Discard, pr)
+		done <- outcome{verdict, err}
+	}()
+
+	tee := io.TeeReader(code, pw)
+	return tee, func(storeErr error) (*ScanVerdict, error) {
+		defer cancel()
+		if storeErr != nil {
+			pw.CloseWithError(storeErr)
+			<-done
+			return nil, storeErr
+		}
+		// Bundles may be stored without reading the archive's trailer.
+		_, err := io.Copy(io.Discard, tee)
+		pw.CloseWithError(err)
+		o := <-done
+		if err != nil {
+			return nil, fmt.Errorf("read upload: %w", err)
+		}
+		if o.err != nil {
+			return nil, fmt.Errorf("malware scan: %w", o.err)
+		}
+		return o.verdict, nil
+	}
+}
+
+// quarantine records a function whose code was found malicious in the
+// "blocked" status, without deploying it, and returns ErrCodeBlocked.
+func (m *Manager) quarantine(ctx context.Context, fn *Function, threat string) error {
+	fn.Status = StatusBlocked
+	fn.BlockedReason = "malware scan: " + threat
+	if err := m.repo.Create(ctx, fn); err != nil {
+		return fmt.Errorf("db create function record: %w", err)
+	}
+	m.log(ctx).Warn().Str("function_id", fn.ID).Str("threat", threat).Msg("function code blocked by malware scan")
+	return fmt.Errorf("%w: function '%s' is quarantined: %s", ErrCodeBlocked, fn.ID, threat)
+}
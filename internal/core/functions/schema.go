@@ -0,0 +1,120 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// PayloadError lists how an execute payload violates its function's schema.
+type PayloadError struct {
+	Violations []string
+}
+
+func (e *PayloadError) Error() string {
+	return "payload does not match the function's schema: " + strings.Join(e.Violations, "; ")
+}
+
+func (e *PayloadError) Unwrap() error { return ErrInvalidArgument }
+
+// compiledSchema caches the compiled form of a function's payload schema.
+type compiledSchema struct {
+	raw    string
+	schema *jsonschema.Schema
+}
+
+// normalizeSchema treats an empty or null schema as no schema.
+func normalizeSchema(raw json.RawMessage) json.RawMessage {
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil
+	}
+	return raw
+}
+
+// compileSchema compiles a payload schema. References are resolved within
+// the schema only; nothing is fetched from files or the network.
+func compileSchema(raw json.RawMessage) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload schema is not valid JSON: %v", ErrInvalidArgument, err)
+	}
+	c := jsonschema.NewCompiler()
+	c.UseLoader(jsonschema.SchemeURLLoader{})
+	// An absolute URL keeps the working directory out of error messages.
+	const loc = "mem:///payload.json"
+	if err := c.AddResource(loc, doc); err != nil {
+		return nil, fmt.Errorf("%w: payload schema: %v", ErrInvalidArgument, err)
+	}
+	schema, err := c.Compile(loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload schema: %v", ErrInvalidArgument, err)
+	}
+	return schema, nil
+}
+
+// validatePayload checks the payload, parsed as JSON, against the function's
+// schema, if it has one.
+func (m *Manager) validatePayload(fn *Function, payload string) error {
+	if fn.PayloadSchema == nil {
+		return nil
+	}
+	var schema *jsonschema.Schema
+	if c, ok := m.schemas.Load(fn.ID); ok && c.(*compiledSchema).raw == string(fn.PayloadSchema) {
+		schema = c.(*compiledSchema).schema
+	} else {
+		var err error
+		if schema, err = compileSchema(fn.PayloadSchema); err != nil {
+			return fmt.Errorf("stored payload schema of function '%s': %w", fn.ID, err)
+		}
+		m.schemas.Store(fn.ID, &compiledSchema{raw: string(fn.PayloadSchema), schema: schema})
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(payload))
+	if err != nil {
+		return &PayloadError{Violations: []string{"payload is not valid JSON"}}
+	}
+	err = schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return fmt.Errorf("validate payload: %w", err)
+	}
+	var violations []string
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		violations = append(violations, location+": "+unit.Error.String())
+	}
+	return &PayloadError{Violations: violations}
+}
+
+// SetPayloadSchema replaces the JSON Schema that execute payloads of a
+// function must match; an empty or null schema removes it.
+func (m *Manager) SetPayloadSchema(ctx context.Context, functionID string, raw json.RawMessage) (*Function, error) {
+	raw = normalizeSchema(raw)
+	if raw != nil {
+		if _, err := compileSchema(raw); err != nil {
+			return nil, err
+		}
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.PayloadSchema = raw
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update payload schema: %w", err)
+	}
+	return fn, nil
+}
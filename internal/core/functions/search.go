@@ -0,0 +1,103 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Search result limits.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchHit is a function matching a search, with its relevance. Ranks are
+// only comparable within one result list.
+type SearchHit struct {
+	Function
+	Rank float64 `json:"rank"`
+}
+
+// SearchFunctions finds functions whose name, description or labels match
+// every term of the query as a word prefix, best matches first. Soft-deleted
+// functions are not searched.
+func (m *Manager) SearchFunctions(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	if len(SearchTerms(query)) == 0 {
+		return nil, fmt.Errorf("%w: search query has no words", ErrInvalidArgument)
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+	return m.repo.Search(ctx, query, limit)
+}
+
+// SearchTerms splits a query into lower-case words, dropping punctuation.
+// Repositories build their backend's query syntax from these terms only.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// RankFunctions searches fns in memory, for repositories without a search
+// index. Name matches weigh most, then labels, then the description; whole
+// words beat prefixes.
+func RankFunctions(fns []Function, query string, limit int) []SearchHit {
+	terms := SearchTerms(query)
+	hits := []SearchHit{}
+	for _, fn := range fns {
+		if fn.DeletedAt != nil {
+			continue
+		}
+		fields := []struct {
+			words  []string
+			weight float64
+		}{
+			{SearchTerms(fn.FunctionName), 1.0},
+			{labelWords(fn.Labels), 0.4},
+			{SearchTerms(fn.Description), 0.2},
+		}
+
+		rank := 0.0
+		for _, term := range terms {
+			best := 0.0
+			for _, f := range fields {
+				for _, w := range f.words {
+					switch {
+					case w == term:
+						best = max(best, f.weight)
+					case strings.HasPrefix(w, term):
+						best = max(best, f.weight/2)
+					}
+				}
+			}
+			if best == 0 {
+				rank = 0
+				break
+			}
+			rank += best
+		}
+		if rank > 0 {
+			hits = append(hits, SearchHit{Function: fn, Rank: rank})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Rank > hits[j].Rank })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+func labelWords(labels map[string]string) []string {
+	var words []string
+	for k, v := range labels {
+		words = append(words, SearchTerms(k)...)
+		words = append(words, SearchTerms(v)...)
+	}
+	return words
+}
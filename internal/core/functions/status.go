@@ -0,0 +1,112 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WorkerDescriber is implemented by orchestrators that can describe a
+// function's worker in detail: its instances, their containers and what the
+// orchestrator recently reported about them.
+type WorkerDescriber interface {
+	// DescribeWorker returns nil when the function has no worker.
+	DescribeWorker(ctx context.Context, functionID string) (*WorkerDescription, error)
+}
+
+// WorkerDescription is a function's worker as the orchestrator sees it now.
+type WorkerDescription struct {
+	WorkerStatus
+	// Instances are the pods or containers of the worker.
+	Instances []WorkerInstance `json:"instances"`
+	// Events are what the orchestrator recently reported about the worker,
+	// oldest first.
+	Events []WorkerLogEntry `json:"events"`
+}
+
+// WorkerInstance is one pod or container of a worker.
+type WorkerInstance struct {
+	Name string `json:"name"`
+	// Phase is the pod phase, e.g. Pending or Running, or the Docker
+	// container status, e.g. running or exited.
+	Phase      string           `json:"phase"`
+	Node       string           `json:"node,omitempty"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	Containers []ContainerState `json:"containers"`
+}
+
+// ContainerState is the state of one container of a worker instance.
+type ContainerState struct {
+	Name string `json:"name"`
+	// State is "running", "waiting" or "terminated".
+	State    string `json:"state" example:"running"`
+	Ready    bool   `json:"ready"`
+	Restarts int    `json:"restarts"`
+	// Reason and Message say why a container is waiting or terminated,
+	// e.g. CrashLoopBackOff or OOMKilled.
+	Reason     string     `json:"reason,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// LastTermination is how the container's previous run ended, when it
+	// was restarted.
+	LastTermination *ContainerTermination `json:"last_termination,omitempty"`
+}
+
+// ContainerTermination is how a container run ended.
+type ContainerTermination struct {
+	Reason     string    `json:"reason,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// WorkerLogEntry is an event the orchestrator reported about a worker, e.g.
+// a Kubernetes event or a Docker container event.
+type WorkerLogEntry struct {
+	Time time.Time `json:"time"`
+	// Type is "Normal" or "Warning" for Kubernetes events and the action,
+	// e.g. "die" or "oom", for Docker events.
+	Type    string `json:"type"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Object is the pod, deployment or container the event is about.
+	Object string `json:"object,omitempty"`
+}
+
+// LiveStatus compares the status recorded for a function with its worker
+// as the orchestrator reports it.
+type LiveStatus struct {
+	FunctionID string `json:"function_id"`
+	// Status and StatusReason are what the database records.
+	Status       string `json:"status"`
+	StatusReason string `json:"status_reason,omitempty"`
+	// Worker is null when the orchestrator has no worker for the function.
+	Worker    *WorkerDescription `json:"worker"`
+	CheckedAt time.Time          `json:"checked_at"`
+}
+
+// GetLiveStatus asks the orchestrator for the state of a function's worker,
+// whatever status the function has recorded.
+func (m *Manager) GetLiveStatus(ctx context.Context, functionID string) (*LiveStatus, error) {
+	describer, ok := m.orchestrator.(WorkerDescriber)
+	if !ok {
+		return nil, fmt.Errorf("%w: live worker status for %s", ErrNotConfigured, m.cfg.DeploymentEnv)
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	worker, err := describer.DescribeWorker(ctx, fn.ID)
+	if err != nil {
+		return nil, fmt.Errorf("describe worker: %w", err)
+	}
+	return &LiveStatus{
+		FunctionID:   fn.ID,
+		Status:       fn.Status,
+		StatusReason: fn.StatusReason,
+		Worker:       worker,
+		CheckedAt:    time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,115 @@
+package functions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxStreamLine bounds a line of an execution stream when payloads are not
+// limited by MaxPayloadBytes.
+const maxStreamLine = 64 << 20
+
+// StreamItem is one line of an execution stream.
+type StreamItem struct {
+	// ID is echoed in the item's result, to correlate results that arrive
+	// out of order.
+	ID      string `json:"id,omitempty"`
+	Payload string `json:"payload"`
+}
+
+// StreamResult is the outcome of one item of an execution stream.
+type StreamResult struct {
+	Index        int              `json:"index"` // line number of the item, from 0
+	ID           string           `json:"id,omitempty"`
+	InvocationID string           `json:"invocation_id,omitempty"`
+	Result       *ExecutionResult `json:"-"`
+	Err          error            `json:"-"`
+}
+
+// ExecuteStream executes the function once per line of lines, each a JSON
+// StreamItem, running up to StreamExecuteConcurrency items at once. emit is
+// called with each result as it completes, so results may be out of order;
+// calls to emit are serialized. Lines are only read as items complete, so
+// neither side buffers the whole batch. An error from emit, e.g. because the
+// caller went away, stops the stream.
+func (m *Manager) ExecuteStream(ctx context.Context, functionID string, lines io.Reader, emit func(StreamResult) error) error {
+	if _, err := m.repo.Get(ctx, functionID); err != nil {
+		return err
+	}
+	maxLine := maxStreamLine
+	if limit := m.cfg.MaxPayloadBytes; limit > 0 {
+		// A JSON string may escape every byte of the payload.
+		maxLine = int(2*limit) + 4096
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		emitMu  sync.Mutex
+		emitErr error
+		wg      sync.WaitGroup
+	)
+	send := func(res StreamResult) {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		if emitErr != nil {
+			return
+		}
+		if emitErr = emit(res); emitErr != nil {
+			cancel()
+		}
+	}
+
+	slots := make(chan struct{}, max(m.settings().StreamExecuteConcurrency, 1))
+	sc := bufio.NewScanner(lines)
+	sc.Buffer(make([]byte, 0, 64<<10), maxLine)
+	index := -1
+	for sc.Scan() {
+		index++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var item StreamItem
+		if err := json.Unmarshal(sc.Bytes(), &item); err != nil {
+			send(StreamResult{Index: index, Err: fmt.Errorf("%w: line %d is not a JSON object with a payload", ErrInvalidArgument, index)})
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			res := StreamResult{Index: index, ID: item.ID}
+			res.Result, res.Err = m.ExecuteFunction(ctx, functionID, item.Payload)
+			var invErr *InvocationError
+			if res.Result != nil {
+				res.InvocationID = res.Result.InvocationID
+			} else if errors.As(res.Err, &invErr) {
+				res.InvocationID = invErr.InvocationID
+			}
+			send(res)
+		}(index)
+	}
+	wg.Wait()
+
+	if emitErr != nil {
+		return emitErr
+	}
+	if err := sc.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("%w: line %d exceeds %d bytes", ErrInvalidArgument, index+1, maxLine)
+	} else if err != nil {
+		return fmt.Errorf("read line %d: %w", index+1, err)
+	}
+	return ctx.Err()
+}
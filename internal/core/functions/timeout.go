@@ -0,0 +1,50 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type timeoutKey struct{}
+
+// WithTimeout returns a context asking for worker calls of the execution it
+// serves to time out after d, e.g. from a caller's X-Timeout-Seconds header.
+// Each called function's own timeout still caps it.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+// callTimeout is how long a call to fn's worker may take: the timeout asked
+// for by ctx, at most fn's timeout. Zero means no limit.
+func callTimeout(ctx context.Context, fn *Function) time.Duration {
+	limit := time.Duration(fn.TimeoutSeconds) * time.Second
+	if asked, _ := ctx.Value(timeoutKey{}).(time.Duration); asked > 0 && (limit == 0 || asked < limit) {
+		return asked
+	}
+	return limit
+}
+
+func validateTimeout(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("%w: timeout_seconds must not be negative", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// SetTimeout changes the longest a call to a function's worker may take;
+// zero removes the limit.
+func (m *Manager) SetTimeout(ctx context.Context, functionID string, seconds int) (*Function, error) {
+	if err := validateTimeout(seconds); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.TimeoutSeconds = seconds
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update timeout: %w", err)
+	}
+	return fn, nil
+}
@@ -0,0 +1,227 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxUsageRange bounds the time range of a usage query.
+const maxUsageRange = 92 * 24 * time.Hour
+
+// UsageCounters are the metered quantities. GBSeconds estimates memory use
+// from the configured worker memory size.
+type UsageCounters struct {
+	Invocations int64   `json:"invocations"`
+	Errors      int64   `json:"errors"`
+	DurationMS  int64   `json:"duration_ms"`
+	GBSeconds   float64 `json:"gb_seconds"`
+	// ColdStarts counts the invocations that were the first of a worker,
+	// and ColdStartMS their total duration, also part of DurationMS.
+	ColdStarts  int64 `json:"cold_starts"`
+	ColdStartMS int64 `json:"cold_start_ms"`
+}
+
+func (c *UsageCounters) add(o UsageCounters) {
+	c.Invocations += o.Invocations
+	c.Errors += o.Errors
+	c.DurationMS += o.DurationMS
+	c.GBSeconds += o.GBSeconds
+	c.ColdStarts += o.ColdStarts
+	c.ColdStartMS += o.ColdStartMS
+}
+
+// UsageRollup aggregates one function's invocations during one hour (UTC).
+type UsageRollup struct {
+	FunctionID string    `gorm:"primaryKey" json:"-"`
+	Hour       time.Time `gorm:"primaryKey" json:"hour"`
+	UsageCounters
+}
+
+// UsageReport is the usage of a function over a time range.
+type UsageReport struct {
+	FunctionID string        `json:"function_id"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Total      UsageCounters `json:"total"`
+	Hours      []UsageRollup `json:"hours"`
+}
+
+// UsageRepository persists hourly usage rollups.
+type UsageRepository interface {
+	// Add adds the counters of each rollup to the stored rollup for the same
+	// function and hour, creating it if needed.
+	Add(ctx context.Context, rollups []UsageRollup) error
+	// Range returns a function's rollups with from <= Hour < to, oldest
+	// first.
+	Range(ctx context.Context, functionID string, from, to time.Time) ([]UsageRollup, error)
+	// Totals sums the rollups with from <= Hour < to per function. Hour is
+	// left zero.
+	Totals(ctx context.Context, from, to time.Time) ([]UsageRollup, error)
+}
+
+// WithUsageRepository enables usage metering. Invocations are counted in
+// memory and written to the repository by FlushUsage.
+func WithUsageRepository(repo UsageRepository) Option {
+	return func(m *Manager) { m.usage = &usageMeter{repo: repo, pending: map[usageKey]*UsageRollup{}} }
+}
+
+type usageKey struct {
+	functionID string
+	hour       time.Time
+}
+
+type usageMeter struct {
+	repo    UsageRepository
+	mu      sync.Mutex
+	pending map[usageKey]*UsageRollup
+}
+
+// recordUsage counts one invocation of a worker.
+func (m *Manager) recordUsage(functionID string, start time.Time, failed, cold bool) {
+	if m.usage == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	key := usageKey{functionID: functionID, hour: start.UTC().Truncate(time.Hour)}
+
+	m.usage.mu.Lock()
+	defer m.usage.mu.Unlock()
+	r, ok := m.usage.pending[key]
+	if !ok {
+		r = &UsageRollup{FunctionID: key.functionID, Hour: key.hour}
+		m.usage.pending[key] = r
+	}
+	r.Invocations++
+	if failed {
+		r.Errors++
+	}
+	r.DurationMS += elapsed.Milliseconds()
+	r.GBSeconds += elapsed.Seconds() * float64(m.cfg.UsageMemoryMiB) / 1024
+	if cold {
+		r.ColdStarts++
+		r.ColdStartMS += elapsed.Milliseconds()
+	}
+}
+
+// FlushUsage writes the counted usage to the repository. On failure the
+// counts are kept for the next flush.
+func (m *Manager) FlushUsage(ctx context.Context) error {
+	if m.usage == nil {
+		return nil
+	}
+	m.usage.mu.Lock()
+	pending := m.usage.pending
+	m.usage.pending = map[usageKey]*UsageRollup{}
+	m.usage.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	rollups := make([]UsageRollup, 0, len(pending))
+	for _, r := range pending {
+		rollups = append(rollups, *r)
+	}
+	if err := m.usage.repo.Add(ctx, rollups); err != nil {
+		m.usage.mu.Lock()
+		for key, r := range pending {
+			merged, ok := m.usage.pending[key]
+			if !ok {
+				m.usage.pending[key] = r
+				continue
+			}
+			merged.add(r.UsageCounters)
+		}
+		m.usage.mu.Unlock()
+		return fmt.Errorf("store usage rollups: %w", err)
+	}
+	return nil
+}
+
+// FlushUsageEvery flushes usage at the given interval until ctx is done.
+func (m *Manager) FlushUsageEvery(ctx context.Context, interval time.Duration) {
+	if m.usage == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.FlushUsage(ctx); err != nil {
+			m.log(ctx).Error().Err(err).Msg("failed to flush usage")
+		}
+	}
+}
+
+// Usage reports a function's hourly usage between from and to, including
+// invocations not yet flushed.
+func (m *Manager) Usage(ctx context.Context, functionID string, from, to time.Time) (*UsageReport, error) {
+	from, to, err := m.usageRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.repo.Get(ctx, functionID); err != nil {
+		return nil, err
+	}
+
+	stored, err := m.usage.repo.Range(ctx, functionID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("read usage rollups: %w", err)
+	}
+	byHour := make(map[time.Time]*UsageRollup, len(stored))
+	for i := range stored {
+		byHour[stored[i].Hour.UTC()] = &stored[i]
+	}
+	for _, r := range m.pendingUsage(from, to) {
+		if r.FunctionID != functionID {
+			continue
+		}
+		if existing, ok := byHour[r.Hour]; ok {
+			existing.add(r.UsageCounters)
+		} else {
+			byHour[r.Hour] = &r
+		}
+	}
+
+	report := &UsageReport{FunctionID: functionID, From: from, To: to, Hours: []UsageRollup{}}
+	for _, r := range byHour {
+		report.Hours = append(report.Hours, *r)
+		report.Total.add(r.UsageCounters)
+	}
+	sort.Slice(report.Hours, func(i, j int) bool { return report.Hours[i].Hour.Before(report.Hours[j].Hour) })
+	return report, nil
+}
+
+// usageRange validates a usage query range, rounding from down to the hour.
+func (m *Manager) usageRange(from, to time.Time) (time.Time, time.Time, error) {
+	if m.usage == nil {
+		return from, to, fmt.Errorf("%w: usage metering", ErrNotConfigured)
+	}
+	from, to = from.UTC().Truncate(time.Hour), to.UTC()
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidArgument)
+	}
+	if to.Sub(from) > maxUsageRange {
+		return from, to, fmt.Errorf("%w: usage range is limited to %d days", ErrInvalidArgument, int(maxUsageRange.Hours()/24))
+	}
+	return from, to, nil
+}
+
+// pendingUsage copies the unflushed rollups with from <= Hour < to.
+func (m *Manager) pendingUsage(from, to time.Time) []UsageRollup {
+	m.usage.mu.Lock()
+	defer m.usage.mu.Unlock()
+	var rollups []UsageRollup
+	for key, r := range m.usage.pending {
+		if !key.hour.Before(from) && key.hour.Before(to) {
+			rollups = append(rollups, *r)
+		}
+	}
+	return rollups
+}
@@ -0,0 +1,151 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FunctionUsageSummary is one function's usage over an export range.
+// Functions that were purged since are reported without tenant and name.
+type FunctionUsageSummary struct {
+	Tenant       string `json:"tenant"`
+	FunctionID   string `json:"function_id"`
+	FunctionName string `json:"function_name"`
+	UsageCounters
+}
+
+// TenantUsageSummary is the usage of all of a tenant's functions over an
+// export range.
+type TenantUsageSummary struct {
+	Tenant    string `json:"tenant"`
+	Functions int    `json:"functions"`
+	UsageCounters
+}
+
+// UsageExport summarizes usage per tenant and per function for billing.
+// Functions without a tenant are summed under the empty tenant.
+type UsageExport struct {
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Tenants   []TenantUsageSummary   `json:"tenants"`
+	Functions []FunctionUsageSummary `json:"functions"`
+}
+
+// UsageSink receives scheduled usage exports, e.g. a billing webhook.
+type UsageSink interface {
+	PushUsage(ctx context.Context, export *UsageExport) error
+}
+
+// WithUsageSink enables the scheduled usage push run by PushUsageEvery.
+func WithUsageSink(sink UsageSink) Option {
+	return func(m *Manager) { m.usageSink = sink }
+}
+
+// ExportUsage summarizes the usage of every function, soft-deleted ones
+// included, between from and to. Unflushed invocations are included.
+func (m *Manager) ExportUsage(ctx context.Context, from, to time.Time) (*UsageExport, error) {
+	from, to, err := m.usageRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := m.usage.repo.Totals(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("read usage totals: %w", err)
+	}
+	fns, err := m.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Function, len(fns))
+	for i := range fns {
+		byID[fns[i].ID] = &fns[i]
+	}
+
+	summaries := map[string]*FunctionUsageSummary{}
+	summary := func(functionID string) *FunctionUsageSummary {
+		s, ok := summaries[functionID]
+		if !ok {
+			s = &FunctionUsageSummary{FunctionID: functionID}
+			if fn, ok := byID[functionID]; ok {
+				s.Tenant, s.FunctionName = fn.Tenant, fn.FunctionName
+			}
+			summaries[functionID] = s
+		}
+		return s
+	}
+	for _, r := range totals {
+		summary(r.FunctionID).add(r.UsageCounters)
+	}
+	for _, r := range m.pendingUsage(from, to) {
+		summary(r.FunctionID).add(r.UsageCounters)
+	}
+
+	export := &UsageExport{From: from, To: to, Tenants: []TenantUsageSummary{}, Functions: []FunctionUsageSummary{}}
+	tenants := map[string]*TenantUsageSummary{}
+	for _, s := range summaries {
+		export.Functions = append(export.Functions, *s)
+		t, ok := tenants[s.Tenant]
+		if !ok {
+			t = &TenantUsageSummary{Tenant: s.Tenant}
+			tenants[s.Tenant] = t
+		}
+		t.Functions++
+		t.add(s.UsageCounters)
+	}
+	for _, t := range tenants {
+		export.Tenants = append(export.Tenants, *t)
+	}
+	sort.Slice(export.Tenants, func(i, j int) bool { return export.Tenants[i].Tenant < export.Tenants[j].Tenant })
+	sort.Slice(export.Functions, func(i, j int) bool {
+		a, b := export.Functions[i], export.Functions[j]
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		return a.FunctionID < b.FunctionID
+	})
+	return export, nil
+}
+
+// PushUsageEvery sends the usage export of each completed interval (aligned
+// to UTC, e.g. calendar days for 24h) to the usage sink until ctx is done.
+// A failed push is retried with the next interval, so one export then covers
+// both, up to the 92 day range limit. Intervals that ended before the
+// manager started are not pushed. Only the leader pushes; other replicas
+// skip the intervals that end while they do not lead.
+func (m *Manager) PushUsageEvery(ctx context.Context, interval time.Duration) {
+	if m.usage == nil || m.usageSink == nil {
+		return
+	}
+	next := time.Now().UTC().Truncate(interval).Add(interval)
+	since := next.Add(-interval)
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if !m.IsLeader() {
+			since, next = next, next.Add(interval)
+			continue
+		}
+
+		if next.Sub(since) > maxUsageRange {
+			m.log(ctx).Warn().Time("from", since).Msg("usage export backlog exceeds the range limit, dropping the oldest usage")
+			since = next.Add(-maxUsageRange)
+		}
+		export, err := m.ExportUsage(ctx, since, next)
+		if err == nil {
+			err = m.usageSink.PushUsage(ctx, export)
+		}
+		if err != nil {
+			m.log(ctx).Error().Err(err).Time("from", since).Time("to", next).Msg("failed to push usage export")
+		} else {
+			since = next
+		}
+		next = next.Add(interval)
+	}
+}
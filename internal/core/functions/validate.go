@@ -0,0 +1,217 @@
+package functions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"service-faas/internal/config"
+	"service-faas/pkg/bundle"
+	"strings"
+)
+
+// CodeChecker is implemented by orchestrators that can check handler code in
+// the worker image without deploying it.
+type CodeChecker interface {
+	// CheckCode reports syntax errors in the handler and whether it defines
+	// FunctionName. An error means the check itself could not run.
+	CheckCode(ctx context.Context, spec CodeCheckSpec) ([]string, error)
+}
+
+// CodeCheckSpec describes handler code to check.
+type CodeCheckSpec struct {
+	Image        string
+	RegistryAuth *RegistryAuth
+	Handler      []byte
+	FunctionName string
+}
+
+// Ways a validation checked the handler code.
+const (
+	CheckedByWorker = "worker" // in the worker image, by the orchestrator
+	CheckedStatic   = "static" // by the manager, without parsing Python
+)
+
+// CodeError lists the problems that made AddFunction reject uploaded code.
+type CodeError struct {
+	Problems []string
+}
+
+func (e *CodeError) Error() string {
+	return "handler code is invalid: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *CodeError) Unwrap() error { return ErrInvalidArgument }
+
+// ValidationReport lists the problems found in an upload. The upload would
+// be accepted by AddFunction if Valid.
+// Code policy findings only make the upload invalid in reject mode.
+type ValidationReport struct {
+	Valid          bool     `json:"valid"`
+	CheckedBy      string   `json:"checked_by,omitempty"`
+	Problems       []string `json:"problems"`
+	PolicyFindings []string `json:"policy_findings,omitempty"`
+}
+
+var pythonIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateFunction checks an upload the way AddFunction would take it, and
+// checks that handler.py defines functionName, and parses where checkHandler
+// can tell, without storing anything or deploying a worker. Problems with
+// the upload are reported, not returned as errors.
+func (m *Manager) ValidateFunction(ctx context.Context, functionName string, code io.Reader, opts FunctionOptions) (*ValidationReport, error) {
+	report := &ValidationReport{Problems: []string{}}
+	if !pythonIdentifier.MatchString(functionName) {
+		report.Problems = append(report.Problems, fmt.Sprintf("function_name %q is not a Python identifier", functionName))
+	}
+	if opts.WorkerImage != "" {
+		if err := m.validateWorkerImage(opts.WorkerImage); err != nil {
+			report.Problems = append(report.Problems, err.Error())
+		}
+	}
+	if err := m.selectRuntime(&opts); err != nil {
+		report.Problems = append(report.Problems, strings.TrimPrefix(err.Error(), ErrInvalidArgument.Error()+": "))
+	}
+
+	code, finishScan := m.startScan(ctx, code)
+	handler, findings, err := m.readHandler(code, opts)
+	verdict, err := finishScan(err)
+	if err == nil && !verdict.Clean {
+		report.Problems = append(report.Problems, "malware scan: "+verdict.Threat)
+		handler = nil
+	}
+	if errors.Is(err, ErrInvalidArgument) {
+		report.Problems = append(report.Problems, strings.TrimPrefix(err.Error(), ErrInvalidArgument.Error()+": "))
+	} else if err != nil {
+		return nil, err
+	}
+
+	if handler != nil && len(report.Problems) == 0 {
+		problems, checkedBy, err := m.checkHandler(ctx, functionName, handler, opts)
+		if err != nil {
+			return nil, err
+		}
+		report.CheckedBy = checkedBy
+		report.Problems = append(report.Problems, problems...)
+	}
+	report.PolicyFindings = findings
+	report.Valid = len(report.Problems) == 0 && !(len(findings) > 0 && m.policy.rejects())
+	return report, nil
+}
+
+// readHandler reads handler.py from an upload, a single file or a bundle,
+// enforcing the same limits and checksum and applying the same code policy
+// as storeCode.
+func (m *Manager) readHandler(code io.Reader, opts FunctionOptions) ([]byte, []string, error) {
+	upload := sha256.New()
+	code = io.TeeReader(code, upload)
+
+	var handler []byte
+	var findings []string
+	if opts.BundleFormat != "" {
+		if _, ok := m.code.(BundleStore); !ok || m.cfg.DeploymentEnv != config.EnvDocker {
+			return nil, nil, fmt.Errorf("%w: code bundles are only supported in docker mode with the local code store", ErrInvalidArgument)
+		}
+		err := bundle.Extract(code, opts.BundleFormat, m.bundleLimits(), func(name string, fr io.Reader) error {
+			var err error
+			switch {
+			case name == "handler.py":
+				handler, err = io.ReadAll(fr)
+				findings = append(findings, m.policy.Scan(name, handler)...)
+			case m.policy.enabled() && strings.HasSuffix(name, ".py"):
+				var src []byte
+				src, err = io.ReadAll(fr)
+				findings = append(findings, m.policy.Scan(name, src)...)
+			default:
+				_, err = io.Copy(io.Discard, fr)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+		if handler == nil {
+			return nil, nil, fmt.Errorf("%w: bundle has no handler.py at its root", ErrInvalidArgument)
+		}
+	} else {
+		var err error
+		if handler, err = io.ReadAll(code); err != nil {
+			return nil, nil, fmt.Errorf("read handler code: %w", err)
+		}
+		findings = m.policy.Scan("handler.py", handler)
+	}
+
+	if want := opts.ExpectedSHA256; want != "" {
+		if got := hex.EncodeToString(upload.Sum(nil)); !strings.EqualFold(got, want) {
+			return nil, nil, fmt.Errorf("%w: upload checksum %s does not match expected %s", ErrInvalidArgument, got, want)
+		}
+	}
+	return handler, findings, nil
+}
+
+// checkHandler has the orchestrator check the handler in the worker image
+// when it can, and otherwise only looks for a top-level definition of
+// functionName: the manager has no Python to parse with, so syntax errors
+// are only found in the worker image.
+func (m *Manager) checkHandler(ctx context.Context, functionName string, handler []byte, opts FunctionOptions) ([]string, string, error) {
+	checker, ok := m.orchestrator.(CodeChecker)
+	if !ok {
+		def := regexp.MustCompile(`(?m)^(?:async\s+def|def|class)\s+` + functionName + `\b|^` + functionName + `\s*=|^(?:from\s+\S+\s+)?import\s.*\b` + functionName + `\b`)
+		if !def.Match(handler) {
+			return []string{fmt.Sprintf("handler.py does not define '%s'", functionName)}, CheckedStatic, nil
+		}
+		return nil, CheckedStatic, nil
+	}
+
+	image, err := m.baseImage(opts.Runtime, opts.WorkerImage)
+	if err != nil {
+		return nil, "", err
+	}
+	spec := CodeCheckSpec{
+		Image:        image,
+		Handler:      handler,
+		FunctionName: functionName,
+	}
+	if opts.WorkerImage != "" {
+		auth, err := m.registryAuth(ctx, opts.Tenant, opts.WorkerImage)
+		if err != nil {
+			return nil, "", err
+		}
+		spec.RegistryAuth = auth
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.CodeCheckTimeout)
+	defer cancel()
+	problems, err := checker.CheckCode(ctx, spec)
+	if err != nil {
+		return nil, "", fmt.Errorf("check handler code: %w", err)
+	}
+	return problems, CheckedByWorker, nil
+}
+
+// checkStoredCode checks the handler AddFunction just stored, so broken code
+// is rejected at upload instead of failing every invocation.
+func (m *Manager) checkStoredCode(ctx context.Context, functionID, functionName string, opts FunctionOptions) error {
+	if !pythonIdentifier.MatchString(functionName) {
+		return &CodeError{Problems: []string{fmt.Sprintf("function_name %q is not a Python identifier", functionName)}}
+	}
+	rc, err := m.code.Get(ctx, functionID)
+	if err != nil {
+		return fmt.Errorf("read handler code: %w", err)
+	}
+	handler, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("read handler code: %w", err)
+	}
+	problems, _, err := m.checkHandler(ctx, functionName, handler, opts)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return &CodeError{Problems: problems}
+	}
+	return nil
+}
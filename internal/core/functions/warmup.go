@@ -0,0 +1,169 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultWarmupPayload is sent by warm-up requests that do not set their own
+// payload, so handlers can tell them from real calls.
+const DefaultWarmupPayload = `{"warmup": true}`
+
+// Warmup primes a function's workers with requests, e.g. so handlers load
+// their models before the first real call.
+type Warmup struct {
+	// Payload is sent as is; it defaults to DefaultWarmupPayload.
+	Payload string `json:"payload,omitempty"`
+	// Requests is the number of requests sent, one after another; it
+	// defaults to 1. Behind a load balancer they spread over the replicas.
+	Requests int `json:"requests,omitempty"`
+	// OnDeploy warms the function up whenever its worker is (re)started.
+	OnDeploy bool `json:"on_deploy,omitempty"`
+}
+
+func (m *Manager) validateWarmup(w *Warmup) error {
+	if w == nil {
+		return nil
+	}
+	if limit := m.settings().WarmupMaxRequests; w.Requests < 0 || w.Requests > limit {
+		return fmt.Errorf("%w: warm-up requests must be between 0 and %d", ErrInvalidArgument, limit)
+	}
+	return nil
+}
+
+// WarmupReport is the outcome of warming a function up.
+type WarmupReport struct {
+	Requests   int      `json:"requests"`
+	Succeeded  int      `json:"succeeded"`
+	Failed     int      `json:"failed"`
+	DurationMS int64    `json:"duration_ms"`
+	Errors     []string `json:"errors,omitempty"` // distinct errors, at most a few
+}
+
+// SetWarmup replaces a function's warm-up settings; nil removes them.
+func (m *Manager) SetWarmup(ctx context.Context, functionID string, w *Warmup) (*Function, error) {
+	if err := m.validateWarmup(w); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	fn.Warmup = w
+	if err := m.repo.Update(ctx, fn); err != nil {
+		return nil, fmt.Errorf("db update warm-up: %w", err)
+	}
+	return fn, nil
+}
+
+// WarmFunction sends warm-up requests to a running function. The override,
+// when set, replaces the function's own payload and number of requests.
+func (m *Manager) WarmFunction(ctx context.Context, functionID string, override *Warmup) (*WarmupReport, error) {
+	if err := m.validateWarmup(override); err != nil {
+		return nil, err
+	}
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Status != "running" {
+		return nil, fmt.Errorf("%w: function '%s' is %s", ErrInvalidArgument, functionID, fn.Status)
+	}
+	w := Warmup{}
+	if fn.Warmup != nil {
+		w = *fn.Warmup
+	}
+	if override != nil {
+		if override.Payload != "" {
+			w.Payload = override.Payload
+		}
+		if override.Requests > 0 {
+			w.Requests = override.Requests
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.settings().WarmupTimeout)
+	defer cancel()
+	return m.warm(ctx, fn, w, false), nil
+}
+
+// warmAfterDeploy warms a freshly started worker up in the background when
+// the function asks for it.
+func (m *Manager) warmAfterDeploy(fn *Function) {
+	if fn.Warmup == nil || !fn.Warmup.OnDeploy {
+		return
+	}
+	target, w := *fn, *fn.Warmup
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.settings().WarmupTimeout)
+		defer cancel()
+		report := m.warm(ctx, &target, w, true)
+		m.log(ctx).Info().Str("function_id", target.ID).Int("succeeded", report.Succeeded).Int("failed", report.Failed).
+			Int64("duration_ms", report.DurationMS).Strs("errors", report.Errors).Msg("function warmed up after deploy")
+	}()
+}
+
+// warm sends the warm-up requests straight to the worker, within the
+// function's concurrency limit but without hooks, caching or usage metering.
+// A worker that was just started may not listen yet, so with waitReady the
+// first request is retried until the worker answers or ctx is done.
+func (m *Manager) warm(ctx context.Context, fn *Function, w Warmup, waitReady bool) *WarmupReport {
+	if w.Payload == "" {
+		w.Payload = DefaultWarmupPayload
+	}
+	if w.Requests == 0 {
+		w.Requests = 1
+	}
+	report := &WarmupReport{Requests: w.Requests}
+	start := time.Now()
+	defer func() { report.DurationMS = time.Since(start).Milliseconds() }()
+
+	client, err := m.workerClient(fn.ID)
+	if err != nil {
+		report.Failed = w.Requests
+		report.Errors = []string{err.Error()}
+		return report
+	}
+	seen := map[string]bool{}
+	for i := 0; i < w.Requests; i++ {
+		err := m.warmOnce(ctx, client, fn, w.Payload)
+		for waitReady && i == 0 && notListening(err) && ctx.Err() == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+				err = m.warmOnce(ctx, client, fn, w.Payload)
+			}
+		}
+		if err == nil {
+			report.Succeeded++
+			continue
+		}
+		report.Failed++
+		if msg := err.Error(); !seen[msg] && len(report.Errors) < 5 {
+			seen[msg] = true
+			report.Errors = append(report.Errors, msg)
+		}
+	}
+	return report
+}
+
+// notListening reports whether a request failed before reaching the worker.
+func notListening(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func (m *Manager) warmOnce(ctx context.Context, client *http.Client, fn *Function, payload string) error {
+	release, err := m.limiter.acquire(ctx, fn.ID, fn.MaxConcurrency, m.settings().ConcurrencyQueueTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+	// The warm-up takes the cold start off the next real call.
+	m.takeCold(fn)
+	_, err = postPayload(ctx, client, fn.Endpoint, payload)
+	return err
+}
@@ -0,0 +1,90 @@
+package functions
+
+import (
+	"context"
+	"time"
+)
+
+// watchRetryDelay is the wait before a failed worker watch is restarted.
+const watchRetryDelay = 5 * time.Second
+
+// WorkerWatcher is implemented by orchestrators that report worker
+// disruptions, such as crashes, OOM kills and evictions, as they happen.
+type WorkerWatcher interface {
+	// WatchWorkers sends worker events until ctx is done or the watch
+	// fails.
+	WatchWorkers(ctx context.Context, events chan<- WorkerEvent) error
+}
+
+// WorkerEvent is a disruption of a function's worker.
+type WorkerEvent struct {
+	FunctionID string
+	// ContainerID identifies the worker as RunResult.ContainerID does, so
+	// events of a replaced worker can be told apart.
+	ContainerID string
+	Reason      string
+}
+
+// WatchWorkers keeps running functions' status up to date with worker
+// events until ctx is done. Every replica watches, but only the leader acts
+// on events.
+func (m *Manager) WatchWorkers(ctx context.Context) {
+	watcher, ok := m.orchestrator.(WorkerWatcher)
+	if !ok {
+		return
+	}
+	events := make(chan WorkerEvent, 64)
+	go func() {
+		for {
+			err := watcher.WatchWorkers(ctx, events)
+			if ctx.Err() != nil {
+				return
+			}
+			m.lg.Warn().Err(err).Msg("worker watch ended, restarting it")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryDelay):
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			if m.IsLeader() {
+				m.handleWorkerEvent(ctx, ev)
+			}
+		}
+	}
+}
+
+// handleWorkerEvent checks the worker an event is about. A worker that died
+// leaves its function in the "error" status; one the orchestrator brought
+// back keeps it running, with the event as its status reason. Missing
+// workers are left to Reconcile.
+func (m *Manager) handleWorkerEvent(ctx context.Context, ev WorkerEvent) {
+	fn, err := m.repo.Get(ctx, ev.FunctionID)
+	if err != nil || fn.Status != "running" || fn.ContainerID != ev.ContainerID {
+		return
+	}
+	state := &WorkerState{ContainerID: fn.ContainerID}
+	if inspector, ok := m.orchestrator.(WorkerInspector); ok {
+		if state, err = inspector.InspectWorker(ctx, fn.ID); err != nil {
+			m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to inspect worker")
+			return
+		}
+	}
+	switch {
+	case state == nil || state.ContainerID != fn.ContainerID:
+	case state.Failed:
+		m.markWorkerDied(ctx, fn, state.Reason)
+	default:
+		m.lg.Warn().Str("function_id", fn.ID).Str("container_id", fn.ContainerID).Str("reason", ev.Reason).Msg("worker disrupted")
+		fn.StatusReason = ev.Reason
+		if err := m.repo.Update(ctx, fn); err != nil {
+			m.lg.Error().Err(err).Str("function_id", fn.ID).Msg("failed to update function record")
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"service-faas/pkg/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Webhook is a URL that receives lifecycle events. Deliveries are signed with
+// Secret, which is only returned when the webhook is created.
+type Webhook struct {
+	ID string `gorm:"primaryKey;size:64" json:"id"`
+	// Tenant, when set, limits the webhook to the events of that tenant's
+	// functions.
+	Tenant string `gorm:"size:191;index" json:"tenant,omitempty"`
+	URL    string `gorm:"type:text" json:"url"`
+	// Events are the subscribed event types; empty subscribes to all but
+	// invocation.succeeded, which is only sent when listed.
+	Events    []string  `gorm:"serializer:json" json:"events,omitempty"`
+	Secret    string    `gorm:"serializer:encrypted" json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *Webhook) wants(ev *Event) bool {
+	if h.Tenant != "" && h.Tenant != ev.Tenant {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return ev.Type != EventInvocationSucceeded
+	}
+	return slices.Contains(h.Events, ev.Type)
+}
+
+// WebhookRepository persists webhook registrations. Delete returns
+// ErrNotFound for unknown webhooks.
+type WebhookRepository interface {
+	Create(ctx context.Context, hook *Webhook) error
+	List(ctx context.Context) ([]Webhook, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// WebhookSender delivers one event to one webhook, signed with its secret.
+type WebhookSender interface {
+	SendEvent(ctx context.Context, hook *Webhook, event *Event) error
+}
+
+// WithWebhooks enables lifecycle webhooks. Events are queued for the
+// senders run by DeliverWebhooks.
+func WithWebhooks(repo WebhookRepository, sender WebhookSender) Option {
+	return func(m *Manager) {
+		m.webhooks = repo
+		m.webhookSender = sender
+		m.events = make(chan *Event, max(m.cfg.WebhookQueueSize, 1))
+		m.webhookRetries = make(chan *webhookDelivery, max(m.cfg.WebhookQueueSize, 1))
+	}
+}
+
+// CreateWebhook registers a webhook. A secret is generated unless one is
+// given.
+func (m *Manager) CreateWebhook(ctx context.Context, hook Webhook) (*Webhook, error) {
+	if m.webhooks == nil {
+		return nil, fmt.Errorf("%w: webhooks", ErrNotConfigured)
+	}
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: webhook url must be an absolute http(s) URL", ErrInvalidArgument)
+	}
+	for _, typ := range hook.Events {
+		if !slices.Contains(EventTypes, typ) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidArgument, typ)
+		}
+	}
+	hook.ID = rand.ID16()
+	if hook.Secret == "" {
+		hook.Secret = rand.Password(32)
+	}
+	hook.CreatedAt = time.Now().UTC()
+	if err := m.webhooks.Create(ctx, &hook); err != nil {
+		return nil, fmt.Errorf("db create webhook: %w", err)
+	}
+	m.log(ctx).Info().Str("webhook_id", hook.ID).Str("url", hook.URL).Msg("webhook registered")
+	return &hook, nil
+}
+
+// ListWebhooks returns the registered webhooks, without their secrets.
+func (m *Manager) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	if m.webhooks == nil {
+		return nil, fmt.Errorf("%w: webhooks", ErrNotConfigured)
+	}
+	hooks, err := m.webhooks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+func (m *Manager) DeleteWebhook(ctx context.Context, id string) error {
+	if m.webhooks == nil {
+		return fmt.Errorf("%w: webhooks", ErrNotConfigured)
+	}
+	if err := m.webhooks.Delete(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("db delete webhook: %w", err)
+	}
+	return nil
+}
+
+// DeliverWebhooks sends queued events to the webhooks subscribed to them,
+// and retries failed deliveries, WebhookConcurrency at a time, until ctx is
+// done. Events and retries still queued then are lost.
+func (m *Manager) DeliverWebhooks(ctx context.Context) {
+	if m.events == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for range max(m.cfg.WebhookConcurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case ev := <-m.events:
+					m.deliverEvent(ctx, ev)
+				case d := <-m.webhookRetries:
+					m.sendWebhook(ctx, d)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (m *Manager) deliverEvent(ctx context.Context, ev *Event) {
+	hooks, err := m.webhooks.List(ctx)
+	if err != nil {
+		m.lg.Error().Err(err).Str("event_id", ev.ID).Msg("list webhooks")
+		return
+	}
+	for i := range hooks {
+		if hooks[i].wants(ev) {
+			m.sendWebhook(ctx, &webhookDelivery{hook: hooks[i], ev: ev, backoff: m.cfg.WebhookRetryBackoff})
+		}
+	}
+}
+
+// webhookDelivery is the delivery of an event to one webhook.
+type webhookDelivery struct {
+	hook     Webhook
+	ev       *Event
+	attempts int           // made so far
+	backoff  time.Duration // wait before the next attempt
+}
+
+// sendWebhook makes the next attempt of a delivery. A failed delivery is
+// attempted up to WebhookMaxAttempts times, doubling the wait between
+// attempts from WebhookRetryBackoff. The wait does not hold up a sender:
+// the retry is queued for the senders once it is over.
+func (m *Manager) sendWebhook(ctx context.Context, d *webhookDelivery) {
+	d.attempts++
+	err := m.webhookSender.SendEvent(ctx, &d.hook, d.ev)
+	if err == nil {
+		return
+	}
+	if d.attempts >= max(m.cfg.WebhookMaxAttempts, 1) || ctx.Err() != nil {
+		m.lg.Error().Err(err).Str("webhook_id", d.hook.ID).Str("event_id", d.ev.ID).Str("event", d.ev.Type).
+			Int("attempts", d.attempts).Msg("webhook delivery failed")
+		return
+	}
+	wait := d.backoff
+	d.backoff *= 2
+	time.AfterFunc(wait, func() {
+		select {
+		case m.webhookRetries <- d:
+		case <-ctx.Done():
+		}
+	})
+}
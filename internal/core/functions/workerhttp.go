@@ -0,0 +1,16 @@
+package functions
+
+import "net/http"
+
+// newWorkerTransport returns the pooled transport for calls to workers. Its
+// connections are kept alive between invocations, so calls do not pay for a
+// new connection, or use up ephemeral ports, each time.
+func (m *Manager) newWorkerTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	// Idle connections are bounded per worker only.
+	t.MaxIdleConns = 0
+	t.MaxIdleConnsPerHost = m.cfg.WorkerMaxIdleConnsPerHost
+	t.MaxConnsPerHost = m.cfg.WorkerMaxConnsPerHost
+	t.IdleConnTimeout = m.cfg.WorkerIdleConnTimeout
+	return t
+}
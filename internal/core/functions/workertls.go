@@ -0,0 +1,150 @@
+package functions
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"service-faas/pkg/pki"
+	"time"
+)
+
+// WorkerTLS is the certificate material an adapter hands to a worker so it
+// serves HTTPS and only accepts callers presenting a certificate from CAPEM.
+type WorkerTLS struct {
+	CertPEM []byte
+	KeyPEM  []byte
+	CAPEM   []byte
+}
+
+// WithWorkerCA enables mutual TLS between the manager and its workers. Each
+// worker gets a server certificate for its own function, and the manager
+// presents a client certificate signed by the same CA.
+func WithWorkerCA(ca *pki.CA) Option {
+	return func(m *Manager) { m.workerCA = ca }
+}
+
+// workerServerName is the name in a worker's server certificate. The manager
+// verifies it instead of the endpoint host, which differs per orchestrator,
+// so a worker cannot impersonate another function.
+func workerServerName(functionID string) string {
+	return functionID + ".worker.service-faas"
+}
+
+// issueWorkerTLS returns fresh server certificate material for a function's
+// worker, or nil when mutual TLS is disabled.
+func (m *Manager) issueWorkerTLS(functionID string) (*WorkerTLS, error) {
+	if m.workerCA == nil {
+		return nil, nil
+	}
+	name := workerServerName(functionID)
+	certPEM, keyPEM, err := m.workerCA.Issue(name, []string{name}, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, m.cfg.WorkerTLSCertTTL)
+	if err != nil {
+		return nil, fmt.Errorf("issue worker certificate: %w", err)
+	}
+	return &WorkerTLS{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: m.workerCA.CertPEM()}, nil
+}
+
+// RenewWorkerCertificates redeploys running workers once their server
+// certificate has passed half its lifetime, since certificates are only
+// issued when a worker starts and workers outlive manager restarts. Workers
+// are rolled out like a Git sync, so with a WorkerStager the old worker
+// serves until the new one answers. Only the leader renews. It blocks until
+// ctx is cancelled.
+func (m *Manager) RenewWorkerCertificates(ctx context.Context) {
+	if m.workerCA == nil {
+		return
+	}
+	ttl := m.cfg.WorkerTLSCertTTL
+	ticker := time.NewTicker(max(min(ttl/8, time.Hour), time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !m.IsLeader() {
+			continue
+		}
+
+		running, err := m.repo.FindByStatus(ctx, "running")
+		if err != nil {
+			m.log(ctx).Error().Err(err).Msg("could not query running functions for certificate renewal")
+			continue
+		}
+		for _, fn := range running {
+			if fn.DeployedAt != nil && time.Since(*fn.DeployedAt) >= ttl/2 {
+				m.renewWorkerCertificate(ctx, fn.ID)
+			}
+		}
+	}
+}
+
+// renewWorkerCertificate redeploys a function's worker with a new
+// certificate, unless the function is being synced or promoted, which
+// redeploys it anyway.
+func (m *Manager) renewWorkerCertificate(ctx context.Context, functionID string) {
+	if _, busy := m.syncs.LoadOrStore(functionID, struct{}{}); busy {
+		return
+	}
+	defer m.syncs.Delete(functionID)
+
+	// The function may have been stopped or redeployed since it was listed.
+	fn, err := m.repo.Get(ctx, functionID)
+	if err != nil || fn.Status != "running" || fn.DeployedAt == nil || time.Since(*fn.DeployedAt) < m.cfg.WorkerTLSCertTTL/2 {
+		return
+	}
+	m.log(ctx).Info().Str("function_id", fn.ID).Time("deployed_at", *fn.DeployedAt).Msg("redeploying worker to renew its certificate")
+	if _, err := m.rollOutWorker(ctx, fn); err != nil {
+		m.log(ctx).Error().Err(err).Str("function_id", fn.ID).Msg("failed to renew worker certificate")
+	}
+}
+
+// workerClient returns the HTTP client used to call a function's worker.
+// With mutual TLS every function gets its own client pinned to the
+// function's server name; clients are cached for connection reuse.
+func (m *Manager) workerClient(functionID string) (*http.Client, error) {
+	if m.workerCA == nil {
+		return m.workerHTTP, nil
+	}
+	if c, ok := m.workerClients.Load(functionID); ok {
+		return c.(*http.Client), nil
+	}
+
+	transport := m.newWorkerTransport()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    m.workerCA.Pool(),
+		ServerName: workerServerName(functionID),
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return m.managerCertificate()
+		},
+	}
+	c, _ := m.workerClients.LoadOrStore(functionID, &http.Client{Transport: transport})
+	return c.(*http.Client), nil
+}
+
+// managerCertificate issues the manager's client certificate and reuses it
+// until three quarters of its lifetime have passed.
+func (m *Manager) managerCertificate() (*tls.Certificate, error) {
+	m.clientCertMu.Lock()
+	defer m.clientCertMu.Unlock()
+
+	if m.clientCert != nil && time.Now().Before(m.clientCert.Leaf.NotAfter.Add(-m.cfg.WorkerTLSCertTTL/4)) {
+		return m.clientCert, nil
+	}
+
+	certPEM, keyPEM, err := m.workerCA.Issue("service-faas-manager", nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, m.cfg.WorkerTLSCertTTL)
+	if err != nil {
+		return nil, fmt.Errorf("issue manager certificate: %w", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("load manager certificate: %w", err)
+	}
+	m.clientCert = &cert
+	return m.clientCert, nil
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"service-faas/internal/core/functions"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+// runtimeVar is the expvar variable holding the manager's runtime stats.
+const runtimeVar = "faas_runtime"
+
+// NewAdminHandler serves the manager's profiles (pprof), expvar variables and
+// runtime stats for the admin listener. Callers authenticate as on the API
+// and need management rights.
+func NewAdminHandler(mgr *functions.Manager, lg zerolog.Logger) http.Handler {
+	if expvar.Get(runtimeVar) == nil {
+		expvar.Publish(runtimeVar, expvar.Func(func() any { return mgr.RuntimeStats() }))
+	}
+	r := chi.NewRouter()
+	r.Use(requestID)
+	r.Use(middleware.Recoverer)
+
+	h := &Handler{mgr: mgr, lg: lg}
+	r.Group(func(r chi.Router) {
+		r.Use(h.authenticate)
+		r.Use(requireManagement)
+		r.Get("/admin/runtime", h.handleRuntimeStats)
+		r.Get("/debug/vars", expvar.Handler().ServeHTTP)
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		// The index lists the profiles and serves the named ones, such as
+		// /debug/pprof/heap and /debug/pprof/goroutine.
+		r.HandleFunc("/debug/pprof/*", pprof.Index)
+	})
+	return r
+}
+
+// @Summary      Runtime stats
+// @Description  Reports the manager process's goroutines, memory and garbage collection, and the executions in flight, in total and per function, and queued for admission. With ADMIN_LISTEN_ADDR set, the admin listener also serves pprof profiles at /debug/pprof/ and expvar variables at /debug/vars.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  functions.RuntimeStats
+// @Router       /admin/runtime [get]
+func (h *Handler) handleRuntimeStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.mgr.RuntimeStats())
+}
+
+// @Summary      Worker capacity report
+// @Description  Reports total versus allocated worker resources, functions per node or host, pending placements and the top resource consumers.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  functions.CapacityReport
+// @Failure      501  {object}  apiError "Not supported by the orchestrator"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /admin/capacity [get]
+func (h *Handler) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	report, err := h.mgr.Capacity(r.Context())
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("capacity report")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// @Summary      Worker resources
+// @Description  Lists what the orchestrator holds for workers: Docker containers, or Kubernetes Deployments, Services and HorizontalPodAutoscalers carrying the worker labels. Resources of functions that have no record, are deleted, or are stopped are flagged as orphaned.
+// @Tags         admin
+// @Produce      json
+// @Param        orphaned query bool false "List only orphaned resources"
+// @Success      200  {array}   functions.WorkerResource
+// @Failure      501  {object}  apiError "Not supported by the orchestrator"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /admin/orchestrator [get]
+func (h *Handler) handleListWorkerResources(w http.ResponseWriter, r *http.Request) {
+	resources, err := h.mgr.ListWorkerResources(r.Context(), r.URL.Query().Get("orphaned") == "true")
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list worker resources")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resources)
+}
+
+// @Summary      Delete a worker resource
+// @Description  Removes a worker resource straight from the orchestrator, leaving the database alone. Only orphaned resources are removed unless force=true; removing the worker of a live function takes it down until it is redeployed or reconciled.
+// @Tags         admin
+// @Produce      json
+// @Param        kind  path  string true  "Resource kind: container, deployment, service or hpa"
+// @Param        name  path  string true  "Resource name, or container ID"
+// @Param        force query bool   false "Delete the resource even though a function record accounts for it"
+// @Success      200  {object}  functions.WorkerResource
+// @Failure      400  {object}  apiError "Unknown resource kind"
+// @Failure      404  {object}  apiError "No such worker resource"
+// @Failure      412  {object}  apiError "The resource is not orphaned and force is not set"
+// @Failure      501  {object}  apiError "Not supported by the orchestrator"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /admin/orchestrator/{kind}/{name} [delete]
+func (h *Handler) handleDeleteWorkerResource(w http.ResponseWriter, r *http.Request) {
+	kind, name := chi.URLParam(r, "kind"), chi.URLParam(r, "name")
+	res, err := h.mgr.DeleteWorkerResource(r.Context(), kind, name, r.URL.Query().Get("force") == "true")
+	if err != nil {
+		h.log(r).Error().Err(err).Str("kind", kind).Str("name", name).Msg("delete worker resource")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// @Summary      Code drift report
+// @Description  Re-hashes the stored code of every function and lists those that no longer match the SHA-256 recorded at upload.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   functions.CodeDrift
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /admin/code-integrity [get]
+func (h *Handler) handleCodeIntegrity(w http.ResponseWriter, r *http.Request) {
+	drifted, err := h.mgr.CheckCodeIntegrity(r.Context())
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("code integrity check")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, drifted)
+}
+
+// @Summary      Reload configuration
+// @Description  Re-reads the environment and config file and applies the settings that can change at runtime (log level, admission and concurrency limits, circuit breaker, warm-up limits, Harbor login) without restarting workers. Other changed settings are listed as needing a restart. In HA mode only the replica serving the request is reloaded. SIGHUP does the same.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  functions.ReloadReport
+// @Failure      400  {object}  apiError "Invalid configuration"
+// @Failure      501  {object}  apiError "No configuration source"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /admin/reload [post]
+func (h *Handler) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	report, err := h.mgr.ReloadConfig(r.Context())
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("reload configuration")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
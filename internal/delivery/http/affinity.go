@@ -0,0 +1,41 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type affinityRequest struct {
+	Affinity *functions.Affinity `json:"affinity"`
+}
+
+// @Summary      Set a function's replica affinity
+// @Description  Sends executions with the same key to the same worker replica (docker mode only). The key is the X-Affinity-Key header or, without it, the payload field named by field. Executions without a key are balanced as usual. A null affinity removes it.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body affinityRequest true "New affinity settings"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/affinity [put]
+func (h *Handler) handleSetAffinity(w http.ResponseWriter, r *http.Request) {
+	var req affinityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetAffinity(r.Context(), chi.URLParam(r, "functionID"), req.Affinity)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set affinity")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
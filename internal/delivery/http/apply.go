@@ -0,0 +1,183 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// @Summary      Apply a function manifest
+// @Description  Creates or updates the functions a YAML or JSON manifest declares, identified by name, so applying the same manifest again changes nothing. A function that does not exist is created and deployed. An existing one is compared with its spec: when they match it is reported as "unchanged"; otherwise its settings are updated, new code passes the checks an upload does, and a running worker is restarted when the change needs it. Settings a spec leaves out take their defaults. Each spec is applied on its own and reported as "created", "updated", "unchanged" or "failed". Resources and triggers are not function settings in this service, so specs naming them are rejected.
+// @Tags         functions
+// @Accept       json
+// @Accept       application/yaml
+// @Produce      json
+// @Param        manifest body functions.Manifest true "Functions to apply"
+// @Success      200  {array}   functions.ApplyResult
+// @Failure      400  {object}  apiError "Invalid manifest, no functions, or a name declared twice"
+// @Failure      413  {object}  apiError "The manifest exceeds MAX_UPLOAD_BYTES"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/apply [post]
+func (h *Handler) handleApply(w http.ResponseWriter, r *http.Request) {
+	var manifest functions.Manifest
+	if !h.readManifest(w, r, &manifest) {
+		return
+	}
+
+	results, err := h.mgr.Apply(r.Context(), manifest)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// @Summary      Create or update a function by name
+// @Description  Makes the function with the name in the path match the spec in the body, like one spec of POST /functions/apply: a missing function is created (201), a differing one updated (200), a matching one left alone (200). The response carries the function's ETag, which changes whenever its code or settings do. Send it back in If-Match to update only the function you last saw; If-Match: * requires that the function exists, and If-None-Match: * that it does not. A failed precondition changes nothing and gets 412.
+// @Tags         functions
+// @Accept       json
+// @Accept       application/yaml
+// @Produce      json
+// @Param        name path string true "Function name"
+// @Param        If-Match header string false "ETag the function must have, or * for any existing function"
+// @Param        If-None-Match header string false "* to only create the function"
+// @Param        spec body functions.FunctionSpec true "Function spec; its name may be left out"
+// @Success      200  {object}  functions.Function "Updated, or unchanged"
+// @Success      201  {object}  functions.Function "Created"
+// @Success      202  {object}  functions.Function "With IMAGE_BUILDS, new code is being built"
+// @Header       200,201,202  {string}  ETag "The function's ETag"
+// @Failure      400  {object}  apiError "Invalid spec, or the name in the body differs from the path"
+// @Failure      412  {object}  apiError "The function does not match If-Match or If-None-Match (PRECONDITION_FAILED)"
+// @Failure      413  {object}  apiError "The spec exceeds MAX_UPLOAD_BYTES"
+// @Failure      422  {object}  apiError "The malware scan found a threat in the new code"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/by-name/{name} [put]
+func (h *Handler) handlePutFunction(w http.ResponseWriter, r *http.Request) {
+	var spec functions.FunctionSpec
+	if !h.readManifest(w, r, &spec) {
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if spec.Name != "" && spec.Name != name {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "the spec's name differs from the one in the path")
+		return
+	}
+	spec.Name = name
+	cond := functions.Precondition{IfNoneMatch: strings.TrimSpace(r.Header.Get("If-None-Match")) == "*"}
+	if raw := r.Header.Get("If-Match"); raw != "" {
+		for _, tag := range strings.Split(raw, ",") {
+			cond.IfMatch = append(cond.IfMatch, strings.TrimSpace(tag))
+		}
+	}
+
+	fn, result, err := h.mgr.ApplyFunction(r.Context(), spec, cond)
+	if err != nil {
+		h.log(r).Error().Err(err).Str("name", name).Msg("put function")
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case fn.BuildStatus == functions.BuildRunning:
+		status = http.StatusAccepted
+	case result.Result == functions.ApplyCreated:
+		status = http.StatusCreated
+	}
+	w.Header().Set("ETag", fn.ETag())
+	writeJSON(w, status, fn)
+}
+
+// @Summary      Get a function by name
+// @Description  Returns the function with the name, as GET /functions/{functionID} does, with its ETag for PUT /functions/by-name/{name}.
+// @Tags         functions
+// @Produce      json
+// @Param        name path string true "Function name"
+// @Success      200  {object}  functions.Function
+// @Header       200  {string}  ETag "The function's ETag"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/by-name/{name} [get]
+func (h *Handler) handleGetFunctionByName(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.FunctionByName(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", fn.ETag())
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Remove a function by name
+// @Description  Removes the function with the name, as DELETE /functions/{functionID} does. A deleted function keeps its name until it is purged.
+// @Tags         functions
+// @Produce      json
+// @Param        name path string true "Function name"
+// @Param        purge query bool false "Remove the function permanently, including its code"
+// @Success      204  "No Content"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/by-name/{name} [delete]
+func (h *Handler) handleRemoveFunctionByName(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.FunctionByName(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	remove := h.mgr.RemoveFunction
+	if r.URL.Query().Get("purge") == "true" {
+		remove = h.mgr.PurgeFunction
+	}
+	if err := remove(r.Context(), fn.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readManifest decodes a YAML or JSON request body into v, rejecting fields v
+// does not have. It writes the error response itself.
+func (h *Handler) readManifest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if limit := h.mgr.MaxUploadBytes(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "manifest exceeds "+strconv.FormatInt(maxErr.Limit, 10)+" bytes")
+			return false
+		}
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "failed to read body")
+		return false
+	}
+	if err := decodeManifest(body, v); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid manifest: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// decodeManifest decodes YAML, and so JSON, through JSON, so that manifests
+// use the JSON field names in either form.
+func decodeManifest(body []byte, v any) error {
+	var doc any
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("keys must be strings: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strings"
+)
+
+type accessKey struct{}
+
+// authenticate resolves the caller's bearer token to the access it grants,
+// answering 401 when API_TOKEN is set and the token is missing or invalid.
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		access, err := h.mgr.Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			h.log(r).Warn().Err(err).Msg("authenticate")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accessKey{}, access)))
+	})
+}
+
+// requireManagement refuses callers whose token only lets them execute a
+// function.
+func requireManagement(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requestAccess(r).Management {
+			writeErrorMessage(w, http.StatusForbidden, codeForbidden, "invoke tokens can only execute their function")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// canInvoke reports whether the caller may execute a function, answering
+// 403 when it may not.
+func canInvoke(w http.ResponseWriter, r *http.Request, functionID string) bool {
+	if !requestAccess(r).CanInvoke(functionID) {
+		writeErrorMessage(w, http.StatusForbidden, codeForbidden, "the token does not cover function '"+functionID+"'")
+		return false
+	}
+	return true
+}
+
+func requestAccess(r *http.Request) functions.Access {
+	access, _ := r.Context().Value(accessKey{}).(functions.Access)
+	return access
+}
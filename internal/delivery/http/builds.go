@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      Get a function's image build
+// @Description  Returns the status, image and output of the function's latest image build. Only available with IMAGE_BUILDS.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.FunctionBuild
+// @Failure      404  {object}  apiError "Unknown function, or it has not been built"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Image builds are disabled"
+// @Router       /functions/{functionID}/build [get]
+func (h *Handler) handleGetBuild(w http.ResponseWriter, r *http.Request) {
+	build, err := h.mgr.GetFunctionBuild(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("get function build")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, build)
+}
+
+// @Summary      Rebuild a function's image
+// @Description  Starts a new image build from the function's stored code. Its worker keeps running the current image until the build succeeds, is then restarted on the new image, and the old image is deleted. Only available with IMAGE_BUILDS.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      202  {object}  functions.Function
+// @Failure      400  {object}  apiError "The function is deleted, blocked or already being built"
+// @Failure      404  {object}  apiError "Unknown function"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Image builds are disabled"
+// @Router       /functions/{functionID}/build [post]
+func (h *Handler) handleRebuildFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.RebuildFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("rebuild function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, fn)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+)
+
+type bulkDeleteRequest struct {
+	FunctionIDs []string `json:"function_ids"`
+	Purge       bool     `json:"purge"`
+}
+
+// @Summary      Delete several functions
+// @Description  Removes the listed functions (at most 1000) like DELETE /functions/{functionID}, several at a time, and reports the outcome per function: "deleted", "purged", "not_found" or "error".
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        body body bulkDeleteRequest true "Function IDs, and whether to purge them"
+// @Success      200  {array}   functions.BulkDeleteResult
+// @Failure      400  {object}  apiError "No or too many function IDs"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/bulk-delete [post]
+func (h *Handler) handleRemoveFunctions(w http.ResponseWriter, r *http.Request) {
+	var req bulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	results, err := h.mgr.RemoveFunctions(r.Context(), req.FunctionIDs, req.Purge)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("bulk delete functions")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// @Summary      Delete functions by label
+// @Description  Removes every function carrying all the given labels, several at a time, and reports the outcome per function. With purge=true, matching functions that are already deleted are purged too.
+// @Tags         functions
+// @Produce      json
+// @Param        label query []string true "Label requirement key=value; repeat to require several" collectionFormat(multi)
+// @Param        purge query bool false "Remove the functions permanently, including their code"
+// @Success      200  {array}   functions.BulkDeleteResult
+// @Failure      400  {object}  apiError "Missing or malformed label selector"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions [delete]
+func (h *Handler) handleRemoveFunctionsByLabel(w http.ResponseWriter, r *http.Request) {
+	selector, err := functions.ParseLabelSelector(r.URL.Query()["label"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	results, err := h.mgr.RemoveFunctionsByLabel(r.Context(), selector, r.URL.Query().Get("purge") == "true")
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("delete functions by label")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
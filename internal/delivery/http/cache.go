@@ -0,0 +1,74 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type cacheTTLRequest struct {
+	TTLSeconds int `json:"ttl_seconds"`
+}
+
+type invalidateCacheResponse struct {
+	Invalidated int `json:"invalidated"`
+}
+
+// @Summary      Set a function's cache TTL
+// @Description  Opts an idempotent function into response caching: results of identical payloads are served from the cache for ttl_seconds without calling the worker. 0 turns caching off.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body cacheTTLRequest true "New TTL"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/cache [put]
+func (h *Handler) handleSetCacheTTL(w http.ResponseWriter, r *http.Request) {
+	var req cacheTTLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetCacheTTL(r.Context(), chi.URLParam(r, "functionID"), req.TTLSeconds)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set cache ttl")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Invalidate a function's cached responses
+// @Description  Drops every cached result of the function, e.g. after the data it reads has changed.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  invalidateCacheResponse
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      501  {object}  apiError "Response caching is disabled"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/cache [delete]
+func (h *Handler) handleInvalidateCache(w http.ResponseWriter, r *http.Request) {
+	n, err := h.mgr.InvalidateCache(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("invalidate cache")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invalidateCacheResponse{Invalidated: n})
+}
+
+// @Summary      Response cache report
+// @Description  Cache hits and misses per function since this manager replica started.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  functions.CacheReport
+// @Router       /admin/cache [get]
+func (h *Handler) handleCacheReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.mgr.CacheReport())
+}
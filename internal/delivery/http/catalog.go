@@ -0,0 +1,65 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// @Summary      Export the function catalog
+// @Description  Streams a zip archive of every function for backup or migration: manifest.json holds each function's settings and status, and functions/{id}/ its code. Deleted functions and functions blocked by the malware scan are left out. Git credentials and env var values are only included with secrets=true; without it only the names of env vars are.
+// @Tags         catalog
+// @Produce      application/zip
+// @Param        secrets query bool false "Include the Git tokens and deploy keys of functions deployed from Git, and the values of env vars"
+// @Success      200  {file}    file "Catalog archive"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /export [get]
+func (h *Handler) handleExportCatalog(w http.ResponseWriter, r *http.Request) {
+	rc, err := h.mgr.ExportCatalog(r.Context(), r.URL.Query().Get("secrets") == "true")
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("export catalog")
+		writeError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	name := "faas-catalog-" + time.Now().UTC().Format("20060102T150405Z") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, rc); err != nil {
+		h.log(r).Error().Err(err).Msg("stream catalog")
+	}
+}
+
+// @Summary      Import a function catalog
+// @Description  Recreates the functions of an archive from GET /export, with their IDs, oldest first so hooks and fallbacks find their targets. Functions whose ID exists already are left alone and reported as "exists". Each function's code passes the same checks as an upload; a function that fails them is reported as "failed" and the import goes on. Imported functions are stopped unless deploy=true, which deploys those that were running when exported.
+// @Tags         catalog
+// @Accept       application/zip
+// @Produce      json
+// @Param        deploy query bool false "Deploy the functions that were running when exported"
+// @Param        archive body string true "Catalog archive"
+// @Success      200  {object}  functions.ImportResult
+// @Failure      400  {object}  apiError "Not a catalog archive, or one that unpacks beyond IMPORT_MAX_BYTES"
+// @Failure      413  {object}  apiError "The archive exceeds IMPORT_MAX_BYTES"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /import [post]
+func (h *Handler) handleImportCatalog(w http.ResponseWriter, r *http.Request) {
+	if limit := h.mgr.ImportMaxBytes(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	result, err := h.mgr.ImportCatalog(r.Context(), r.Body, r.URL.Query().Get("deploy") == "true")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "archive exceeds "+strconv.FormatInt(maxErr.Limit, 10)+" bytes")
+			return
+		}
+		h.log(r).Error().Err(err).Msg("import catalog")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      Download a function's code
+// @Description  Returns the code the function is deployed with: its handler.py, or, for a function deployed from a bundle with more files, a zip archive of all of them (including a generated requirements.lock). Deleted functions can be downloaded until they are purged. The code of functions blocked by the malware scan is not handed out.
+// @Tags         functions
+// @Produce      text/x-python
+// @Produce      application/zip
+// @Param        functionID path string true "Function ID"
+// @Success      200  {file}    file "handler.py or a zip archive"
+// @Failure      404  {object}  apiError "Unknown function"
+// @Failure      422  {object}  apiError "The function is blocked by the malware scan"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/code [get]
+func (h *Handler) handleGetCode(w http.ResponseWriter, r *http.Request) {
+	functionID := chi.URLParam(r, "functionID")
+	rc, zipped, err := h.mgr.ExportCode(r.Context(), functionID)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("export code")
+		writeError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	if zipped {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+functionID+`.zip"`)
+	} else {
+		w.Header().Set("Content-Type", "text/x-python; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="handler.py"`)
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, rc); err != nil {
+		h.log(r).Error().Err(err).Str("function_id", functionID).Msg("stream code")
+	}
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type concurrencyRequest struct {
+	MaxConcurrency int `json:"max_concurrency"`
+}
+
+// @Summary      Set a function's concurrency limit
+// @Description  Caps how many executions of the function run at once. Executions beyond the limit wait up to CONCURRENCY_QUEUE_TIMEOUT for a slot and are then rejected with 429. 0 removes the limit.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body concurrencyRequest true "New limit"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/concurrency [put]
+func (h *Handler) handleSetConcurrency(w http.ResponseWriter, r *http.Request) {
+	var req concurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetMaxConcurrency(r.Context(), chi.URLParam(r, "functionID"), req.MaxConcurrency)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set max concurrency")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
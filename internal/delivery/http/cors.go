@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// corsExposedHeaders are the response headers of public routes that browser
+// apps may read.
+var corsExposedHeaders = strings.Join([]string{
+	"X-Faas-Invocation-Id", "X-Faas-Degraded", "X-Faas-Cache", "Idempotent-Replayed", functions.RequestIDHeader,
+}, ", ")
+
+type corsRequest struct {
+	CORS *functions.CORSPolicy `json:"cors"`
+}
+
+// @Summary      Set a function's CORS policy
+// @Description  Lets browser apps on the allowed origins call the function through its public routes, /f/{name} and its custom domain, and answers their preflight requests. Allowed methods default to POST and allowed headers to Content-Type. The API itself sends no CORS headers. A null policy removes it.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body corsRequest true "New CORS policy"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/cors [put]
+func (h *Handler) handleSetCORS(w http.ResponseWriter, r *http.Request) {
+	var req corsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetCORS(r.Context(), chi.URLParam(r, "functionID"), req.CORS)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set cors")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Preflight a public function
+// @Description  Answers a browser's CORS preflight for /f/{name} from the function's CORS policy. Origins the policy does not allow get no CORS headers, so the browser does not send the call.
+// @Tags         public
+// @Param        name path string true "Function name"
+// @Param        Origin header string true "Origin of the calling page"
+// @Success      204
+// @Header       204  {string}  Access-Control-Allow-Origin "The calling origin, when allowed"
+// @Failure      404  {object}  apiError "No public function has this name"
+// @Router       /f/{name} [options]
+func (h *Handler) handlePublicPreflight(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.PublicFunction(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	preflight(w, r, fn.CORS)
+}
+
+// allowCORS adds the CORS headers of a policy to the response to a call
+// from an origin the policy allows, and reports whether it did.
+func allowCORS(w http.ResponseWriter, r *http.Request, p *functions.CORSPolicy) bool {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if !p.AllowsOrigin(origin) {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// preflight answers a CORS preflight request from a policy.
+func preflight(w http.ResponseWriter, r *http.Request, p *functions.CORSPolicy) {
+	if allowCORS(w, r, p) {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.Methods(), ", "))
+		headers := p.Headers()
+		if slices.Contains(headers, "*") {
+			// A literal * is not honoured with credentials, so the
+			// requested headers are echoed instead.
+			headers = []string{r.Header.Get("Access-Control-Request-Headers")}
+		}
+		if allowed := strings.Join(headers, ", "); allowed != "" {
+			w.Header().Set("Access-Control-Allow-Headers", allowed)
+		}
+		if p.MaxAgeSeconds > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAgeSeconds))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type debugRequest struct {
+	Debug *functions.DebugCapture `json:"debug"`
+}
+
+// @Summary      Set a function's debug mode
+// @Description  Captures a sample of the function's executions with their payload and response, for GET /functions/{functionID}/captures. Each capture is redacted, capped at max_bytes (default 16 KiB) and kept for ttl_seconds (default a day, at most a week). Redact rules are keys, matched at any depth ignoring case, or JSON Pointers. A null debug mode turns capturing off; captures already taken are kept until they expire. Captures are encrypted at rest, so with a database SECRETS_ENCRYPTION_KEYS must be set.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body debugRequest true "New debug mode"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "SECRETS_ENCRYPTION_KEYS is not set"
+// @Router       /functions/{functionID}/debug [put]
+func (h *Handler) handleSetDebug(w http.ResponseWriter, r *http.Request) {
+	var req debugRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetDebug(r.Context(), chi.URLParam(r, "functionID"), req.Debug)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set debug")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      List a function's debug captures
+// @Description  Returns the executions captured in debug mode, newest first, with their redacted payload and response, or error.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        limit query int false "Maximum number of captures (default 50, at most 500)"
+// @Success      200  {array}   functions.Capture
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/captures [get]
+func (h *Handler) handleListCaptures(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'limit'")
+			return
+		}
+		limit = n
+	}
+
+	captures, err := h.mgr.ListCaptures(r.Context(), chi.URLParam(r, "functionID"), limit)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list captures")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, captures)
+}
+
+// @Summary      Clear a function's debug captures
+// @Description  Deletes the executions captured in debug mode. Debug mode stays as it is.
+// @Tags         functions
+// @Param        functionID path string true "Function ID"
+// @Success      204  "No Content"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/captures [delete]
+func (h *Handler) handleClearCaptures(w http.ResponseWriter, r *http.Request) {
+	if err := h.mgr.ClearCaptures(r.Context(), chi.URLParam(r, "functionID")); err != nil {
+		h.log(r).Error().Err(err).Msg("clear captures")
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
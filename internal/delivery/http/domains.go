@@ -0,0 +1,83 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type domainRequest struct {
+	Domain string `json:"domain" example:"echo.example.com"`
+}
+
+// @Summary      Bind a custom domain
+// @Description  Serves the function for requests whose Host is the domain: a POST to any path executes it with the request body as its payload and answers with the bare result, like /f/{name}, without a token. Point the domain's DNS at the manager. In kubernetes mode with KUBERNETES_DOMAIN_SERVICE, the manager also creates an Ingress for the domain, with a cert-manager certificate when KUBERNETES_CERT_ISSUER is set. An empty domain unbinds it. The API's own host names, from TLS_AUTOCERT_DOMAINS and RESULT_BASE_URL, cannot be bound.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body domainRequest true "Domain to bind, or empty"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request, the function is deleted, or the domain is the API's"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      409  {object}  apiError "Another function has the domain (DOMAIN_TAKEN)"
+// @Failure      500  {object}  apiError "Internal Server Error, e.g. the Ingress could not be created"
+// @Router       /functions/{functionID}/domain [put]
+func (h *Handler) handleSetDomain(w http.ResponseWriter, r *http.Request) {
+	var req domainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetDomain(r.Context(), chi.URLParam(r, "functionID"), req.Domain)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set domain")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// routeDomains serves requests for a function's custom domain with that
+// function; all others go on to the API. Only host names with a dot can be
+// domains, so requests to an IP address or e.g. localhost skip the lookup,
+// and other hosts are checked against the manager's cached set of bound
+// domains before the database is asked.
+func (h *Handler) routeDomains(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		if !strings.Contains(host, ".") || net.ParseIP(host) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fn, err := h.mgr.DomainFunction(r.Context(), host)
+		if errors.Is(err, functions.ErrNotFound) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			h.log(r).Error().Err(err).Str("host", host).Msg("look up domain")
+			writeError(w, err)
+			return
+		}
+		if r.Method == http.MethodOptions {
+			preflight(w, r, fn.CORS)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeErrorMessage(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "functions are called with POST")
+			return
+		}
+		h.servePublic(w, r, fn)
+	})
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      List a function's environments
+// @Description  Returns the functions deployed as environments of a function, such as staging, sorted by environment name.
+// @Tags         environments
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {array}   functions.Function
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/envs [get]
+func (h *Handler) handleListEnvironments(w http.ResponseWriter, r *http.Request) {
+	envs, err := h.mgr.Environments(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if envs == nil {
+		envs = []functions.Function{}
+	}
+	writeJSON(w, http.StatusOK, envs)
+}
+
+// @Summary      Get an environment
+// @Description  Returns the function deployed to an environment of a function.
+// @Tags         environments
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        env        path string true "Environment, e.g. staging"
+// @Success      200  {object}  functions.Function
+// @Failure      404  {object}  apiError "The function or environment does not exist"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/envs/{env} [get]
+func (h *Handler) handleGetEnvironment(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.Environment(r.Context(), chi.URLParam(r, "functionID"), chi.URLParam(r, "env"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Deploy to an environment
+// @Description  Deploys code or env vars to an environment of a function, such as staging, without touching the function itself. A missing environment is created as a function of its own, with the function's settings (but not its exposure) and, unless code is sent, its code and env vars. An existing environment gets the code or env vars sent; new code passes the checks an upload does, and a running worker is restarted on the change. Rejected code leaves the environment as it was.
+// @Tags         environments
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        functionID     path      string true   "Function ID"
+// @Param        env            path      string true   "Environment, a DNS label such as staging"
+// @Param        python_file    formData  file   false  "The Python file containing the function handler"
+// @Param        bundle         formData  file   false  "A .tar.zst or .tar.gz/.tgz archive with handler.py at its root, instead of python_file (docker mode only)"
+// @Param        code_sha256    formData  string false  "SHA-256 of the uploaded file or bundle"
+// @Param        git_url        formData  string false  "Git repository to deploy the code from instead of an upload (with GIT_DEPLOYS)"
+// @Param        git_ref        formData  string false  "Branch, tag or commit to deploy"
+// @Param        git_path       formData  string false  "Directory holding handler.py, relative to the repository root"
+// @Param        git_token      formData  string false  "Access token for a private https repository"
+// @Param        git_deploy_key formData  string false  "Private SSH deploy key for a private ssh repository"
+// @Param        function_name  formData  string false  "The name of the function to execute; defaults to the function's"
+// @Param        env_vars       formData  string false  "JSON object of env vars, replacing the environment's"
+// @Success      200  {object}  functions.Function "The environment was updated, or already had the code and env vars"
+// @Success      201  {object}  functions.Function "The environment was created"
+// @Success      202  {object}  functions.Function "With IMAGE_BUILDS, the environment's code is being built"
+// @Failure      400  {object}  apiError "Bad Request, or the code failed its checks"
+// @Failure      404  {object}  apiError "FUNCTION_NOT_FOUND"
+// @Failure      413  {object}  apiError "The upload exceeds MAX_UPLOAD_BYTES"
+// @Failure      422  {object}  apiError "The malware scan found a threat"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/envs/{env} [put]
+func (h *Handler) handleDeployEnvironment(w http.ResponseWriter, r *http.Request) {
+	file, bundleFormat, ok := h.readUpload(w, r, uploadOptional)
+	if !ok {
+		return
+	}
+	d := functions.EnvironmentDeployment{
+		FunctionName:   r.FormValue("function_name"),
+		BundleFormat:   bundleFormat,
+		ExpectedSHA256: r.FormValue("code_sha256"),
+	}
+	if file != nil {
+		defer file.Close()
+		d.Code = file
+	}
+	if gitURL := r.FormValue("git_url"); gitURL != "" {
+		d.Git = &functions.GitSource{URL: gitURL, Ref: r.FormValue("git_ref"), Path: r.FormValue("git_path")}
+		d.GitAuth = functions.GitAuth{Token: r.FormValue("git_token"), DeployKey: r.FormValue("git_deploy_key")}
+	}
+	if raw := r.FormValue("env_vars"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &d.EnvVars); err != nil || d.EnvVars == nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'env_vars' json, expected an object of strings")
+			return
+		}
+	}
+
+	fn, created, err := h.mgr.DeployEnvironment(r.Context(), chi.URLParam(r, "functionID"), chi.URLParam(r, "env"), d)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("deploy environment")
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case fn.BuildStatus == functions.BuildRunning:
+		status = http.StatusAccepted
+	case created:
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, fn)
+}
+
+// @Summary      Promote one environment to another
+// @Description  Deploys what one environment of a function runs to another, e.g. staging to prod: its exact code, including locked dependencies, its Git commit, its pinned worker image and its settings. The target keeps its own env vars; a missing target is created with the function's. A running worker is restarted on the promoted code. The promotion, with who asked for it and when, is set as the target's promotion, kept in the function's promotion history (GET /functions/{functionID}/promotions), failed promotions included, and sent as a function.promoted event. Who asked for it is the token the caller authenticated with: api_token, or invoke_token: and the token's ID. Rejected code leaves the target as it was.
+// @Tags         environments
+// @Produce      json
+// @Param        functionID  path   string true  "Function ID"
+// @Param        from        query  string true  "Environment to promote"
+// @Param        to          query  string true  "Environment to deploy it to"
+// @Param        promoted_by query  string false "Who is promoting, recorded with the promotion when no API_TOKEN is set; otherwise the authenticated token is recorded"
+// @Success      200  {object}  functions.Function "The target environment was updated"
+// @Success      201  {object}  functions.Function "The target environment was created"
+// @Success      202  {object}  functions.Function "With IMAGE_BUILDS, the target's code is being built"
+// @Failure      400  {object}  apiError "Bad Request, or the code failed its checks"
+// @Failure      404  {object}  apiError "The function or the environment to promote does not exist"
+// @Failure      422  {object}  apiError "The malware scan found a threat"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/promote [post]
+func (h *Handler) handlePromoteEnvironment(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	by := requestAccess(r).Actor
+	if by == "" {
+		// The API is open: nobody authenticated, so the caller's own word
+		// is all there is.
+		by = q.Get("promoted_by")
+	}
+	fn, created, err := h.mgr.PromoteEnvironment(r.Context(), chi.URLParam(r, "functionID"), q.Get("from"), q.Get("to"), by)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("promote environment")
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case fn.BuildStatus == functions.BuildRunning:
+		status = http.StatusAccepted
+	case created:
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, fn)
+}
+
+// @Summary      List a function's promotions
+// @Description  Returns the promotions between a function's environments, newest first, with the environments, the code and commit promoted, who promoted it and the outcome: promoted, building or failed.
+// @Tags         environments
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        limit query int false "Maximum number of promotions (default 50, at most 500)"
+// @Success      200  {array}   functions.EnvironmentPromotion
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/promotions [get]
+func (h *Handler) handleListPromotions(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'limit'")
+			return
+		}
+		limit = n
+	}
+
+	promotions, err := h.mgr.ListEnvironmentPromotions(r.Context(), chi.URLParam(r, "functionID"), limit)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list promotions")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, promotions)
+}
+
+// @Summary      Remove an environment
+// @Description  Stops an environment's worker and removes it permanently, including its code.
+// @Tags         environments
+// @Param        functionID path string true "Function ID"
+// @Param        env        path string true "Environment"
+// @Success      204  "No Content"
+// @Failure      404  {object}  apiError "The function or environment does not exist"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/envs/{env} [delete]
+func (h *Handler) handleRemoveEnvironment(w http.ResponseWriter, r *http.Request) {
+	if err := h.mgr.RemoveEnvironment(r.Context(), chi.URLParam(r, "functionID"), chi.URLParam(r, "env")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Execute an environment
+// @Description  Executes the function deployed to an environment, like POST /functions/{functionID}/execute with the environment's function ID.
+// @Tags         environments
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        env        path string true "Environment"
+// @Param        body body executeRequest true "Payload for the function"
+// @Param        Idempotency-Key header string false "Executes at most once per key"
+// @Param        X-Timeout-Seconds header number false "Time out the worker call after this many seconds"
+// @Success      200  {object}  functions.ExecutionResult
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "The function or environment does not exist"
+// @Failure      409  {object}  apiError "The environment is not running"
+// @Failure      422  {object}  apiError "The handler raised an exception (HANDLER_ERROR)"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      502  {object}  apiError "The worker could not be reached or failed"
+// @Failure      504  {object}  apiError "The worker did not answer in time"
+// @Router       /functions/{functionID}/envs/{env}/execute [post]
+func (h *Handler) handleExecuteEnvironment(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.Environment(r.Context(), chi.URLParam(r, "functionID"), chi.URLParam(r, "env"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.execute(w, r, fn.ID)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"service-faas/internal/core/functions"
+)
+
+// Error codes of error responses. Codes are stable; messages may change.
+const (
+	codeInvalidArgument       = "INVALID_ARGUMENT"
+	codeInvalidPayload        = "INVALID_PAYLOAD"
+	codeInvalidCode           = "INVALID_CODE"
+	codePolicyViolation       = "CODE_POLICY_VIOLATION"
+	codeNotFound              = "NOT_FOUND"
+	codeUnauthorized          = "UNAUTHORIZED"
+	codeForbidden             = "FORBIDDEN"
+	codeFunctionNotFound      = "FUNCTION_NOT_FOUND"
+	codeFunctionNotRunning    = "FUNCTION_NOT_RUNNING"
+	codeNameTaken             = "NAME_TAKEN"
+	codeDomainTaken           = "DOMAIN_TAKEN"
+	codeMethodNotAllowed      = "METHOD_NOT_ALLOWED"
+	codePreconditionFailed    = "PRECONDITION_FAILED"
+	codePayloadTooLarge       = "PAYLOAD_TOO_LARGE"
+	codeConcurrencyLimit      = "CONCURRENCY_LIMIT_EXCEEDED"
+	codeOverloaded            = "OVERLOADED"
+	codeIdempotencyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	codeIdempotencyMismatch   = "IDEMPOTENCY_KEY_MISMATCH"
+	codeCodeBlocked           = "CODE_BLOCKED"
+	codeNotConfigured         = "NOT_CONFIGURED"
+	codeWorkerTimeout         = "WORKER_TIMEOUT"
+	codeWorkerUnavailable     = "WORKER_UNAVAILABLE"
+	codeWorkerFailed          = "WORKER_ERROR"
+	codeHandlerError          = "HANDLER_ERROR"
+	codeImagePullFailed       = "IMAGE_PULL_FAILED"
+	codeShuttingDown          = "SHUTTING_DOWN"
+	codeInternal              = "INTERNAL"
+)
+
+// apiError is the body of every error response.
+type apiError struct {
+	Code    string `json:"code" example:"FUNCTION_NOT_FOUND"`
+	Message string `json:"message" example:"function not found: 'abc'"`
+	// Class is "user" when the request or the function's code is at fault,
+	// so sending it again fails the same way, and "platform" otherwise.
+	Class   string `json:"class" enums:"user,platform" example:"user"`
+	Details any    `json:"details,omitempty" swaggertype:"object"`
+}
+
+// errorCodes maps manager errors to a status and code, most specific first.
+var errorCodes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{functions.ErrFunctionNotFound, http.StatusNotFound, codeFunctionNotFound},
+	{functions.ErrNotFound, http.StatusNotFound, codeNotFound},
+	{functions.ErrInvalidArgument, http.StatusBadRequest, codeInvalidArgument},
+	{functions.ErrUnauthorized, http.StatusUnauthorized, codeUnauthorized},
+	{functions.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, codePayloadTooLarge},
+	{functions.ErrConcurrencyLimit, http.StatusTooManyRequests, codeConcurrencyLimit},
+	{functions.ErrOverloaded, http.StatusTooManyRequests, codeOverloaded},
+	{functions.ErrIdempotencyInProgress, http.StatusConflict, codeIdempotencyInProgress},
+	{functions.ErrIdempotencyMismatch, http.StatusUnprocessableEntity, codeIdempotencyMismatch},
+	{functions.ErrCodeBlocked, http.StatusUnprocessableEntity, codeCodeBlocked},
+	{functions.ErrNotConfigured, http.StatusNotImplemented, codeNotConfigured},
+	{functions.ErrFunctionNotRunning, http.StatusConflict, codeFunctionNotRunning},
+	{functions.ErrNameTaken, http.StatusConflict, codeNameTaken},
+	{functions.ErrDomainTaken, http.StatusConflict, codeDomainTaken},
+	{functions.ErrPreconditionFailed, http.StatusPreconditionFailed, codePreconditionFailed},
+	{functions.ErrWorkerTimeout, http.StatusGatewayTimeout, codeWorkerTimeout},
+	{functions.ErrWorkerUnavailable, http.StatusBadGateway, codeWorkerUnavailable},
+	{functions.ErrWorkerFailed, http.StatusBadGateway, codeWorkerFailed},
+	{functions.ErrShuttingDown, http.StatusServiceUnavailable, codeShuttingDown},
+}
+
+// toAPIError returns the status and body an error is answered with.
+func toAPIError(err error) (int, apiError) {
+	status, body := errorBody(err)
+	body.Class = functions.ClassifyError(err)
+	return status, body
+}
+
+func errorBody(err error) (int, apiError) {
+	var payloadErr *functions.PayloadError
+	if errors.As(err, &payloadErr) {
+		return http.StatusBadRequest, apiError{
+			Code:    codeInvalidPayload,
+			Message: "payload does not match the function's schema",
+			Details: map[string]any{"violations": payloadErr.Violations},
+		}
+	}
+	var codeErr *functions.CodeError
+	if errors.As(err, &codeErr) {
+		return http.StatusBadRequest, apiError{
+			Code:    codeInvalidCode,
+			Message: "handler code is invalid",
+			Details: map[string]any{"problems": codeErr.Problems},
+		}
+	}
+	var policyErr *functions.PolicyError
+	if errors.As(err, &policyErr) {
+		return http.StatusBadRequest, apiError{
+			Code:    codePolicyViolation,
+			Message: "code violates policy",
+			Details: map[string]any{"findings": policyErr.Findings},
+		}
+	}
+	var handlerErr *functions.HandlerError
+	if errors.As(err, &handlerErr) {
+		return http.StatusUnprocessableEntity, apiError{
+			Code:    codeHandlerError,
+			Message: err.Error(),
+			Details: map[string]any{"exception": handlerErr},
+		}
+	}
+	var pullErr *functions.ImagePullError
+	if errors.As(err, &pullErr) {
+		return http.StatusBadGateway, apiError{
+			Code:    codeImagePullFailed,
+			Message: err.Error(),
+			Details: map[string]any{"image": pullErr.Image, "reason": pullErr.Reason},
+		}
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.status, apiError{Code: c.code, Message: err.Error()}
+		}
+	}
+	return http.StatusInternalServerError, apiError{Code: codeInternal, Message: err.Error()}
+}
+
+// writeError answers with the status and code of a manager error.
+func writeError(w http.ResponseWriter, err error) {
+	status, body := toAPIError(err)
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	writeJSON(w, status, body)
+}
+
+// writeErrorMessage answers with an error the handler detected itself, e.g.
+// a malformed request.
+func writeErrorMessage(w http.ResponseWriter, status int, code, message string) {
+	class := functions.ErrorClassUser
+	if status >= http.StatusInternalServerError {
+		class = functions.ErrorClassPlatform
+	}
+	writeJSON(w, status, apiError{Code: code, Message: message, Class: class})
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      Sync a function from Git
+// @Description  Fetches the current commit of the ref a function was deployed from and, when it moved, deploys it: the code passes the same checks as an upload, then a running worker is replaced by one on the new code (with IMAGE_BUILDS, the function's image is rebuilt). In docker mode the new worker is started next to the old one, which keeps serving until the new one answers. If the new code is rejected, the function keeps its previous commit; if its worker does not start, the function is rolled back to the previous commit. Each deployment is recorded, see GET /functions/{functionID}/deployments. Only available with GIT_DEPLOYS.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function "Deployed, or already at the current commit"
+// @Success      202  {object}  functions.Function "With IMAGE_BUILDS, the new commit is being built"
+// @Failure      400  {object}  apiError "The function was not deployed from Git, is deleted or blocked, the repository or ref cannot be fetched, or the new code is invalid (INVALID_CODE) or violates the code policy (CODE_POLICY_VIOLATION)"
+// @Failure      404  {object}  apiError "Unknown function"
+// @Failure      422  {object}  apiError "The malware scan found a threat in the new commit"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Git deploys are disabled"
+// @Router       /functions/{functionID}/sync [post]
+func (h *Handler) handleSyncFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.SyncFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("sync function")
+		writeError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if fn.BuildStatus == functions.BuildRunning {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, fn)
+}
+
+// @Summary      List a function's Git deployments
+// @Description  Returns the deployments of new commits of a function deployed from Git, by sync or push webhook, newest first, with the commit, the previous commit and the outcome: deployed, building, rolled_back or failed.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        limit query int false "Maximum number of deployments (default 50, at most 500)"
+// @Success      200  {array}   functions.GitDeployment
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/deployments [get]
+func (h *Handler) handleListGitDeployments(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'limit'")
+			return
+		}
+		limit = n
+	}
+
+	deployments, err := h.mgr.ListGitDeployments(r.Context(), chi.URLParam(r, "functionID"), limit)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list git deployments")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deployments)
+}
+
+// maxPushPayload bounds push webhook deliveries; GitHub caps its own at
+// 25 MB.
+const maxPushPayload = 25 << 20
+
+// @Summary      Receive a Git push webhook
+// @Description  Push webhook for GitHub (signed with GIT_WEBHOOK_SECRET, X-Hub-Signature-256) and GitLab (X-Gitlab-Token set to GIT_WEBHOOK_SECRET). Every function deployed from the pushed repository and ref is synced, one after another, in the background; each sync emits a function.synced event. Other events, such as GitHub's ping, are acknowledged and ignored.
+// @Tags         git
+// @Accept       json
+// @Produce      json
+// @Param        X-GitHub-Event header string false "GitHub event name, e.g. push"
+// @Param        X-Hub-Signature-256 header string false "GitHub's HMAC-SHA256 signature of the body"
+// @Param        X-Gitlab-Event header string false "GitLab event name, e.g. Push Hook"
+// @Param        X-Gitlab-Token header string false "GitLab's secret token"
+// @Success      202  {object}  functions.GitPushResult "The functions being redeployed"
+// @Failure      400  {object}  apiError "Not a GitHub or GitLab delivery, or an invalid payload"
+// @Failure      401  {object}  apiError "The signature or token does not match"
+// @Failure      413  {object}  apiError "The delivery exceeds 25 MB"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "GIT_DEPLOYS or GIT_WEBHOOK_SECRET is not set"
+// @Router       /git/push [post]
+func (h *Handler) handleGitPush(w http.ResponseWriter, r *http.Request) {
+	var host, event, signature string
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		host, event, signature = functions.GitHostGitHub, r.Header.Get("X-GitHub-Event"), r.Header.Get("X-Hub-Signature-256")
+	case r.Header.Get("X-Gitlab-Event") != "":
+		host, event, signature = functions.GitHostGitLab, r.Header.Get("X-Gitlab-Event"), r.Header.Get("X-Gitlab-Token")
+	default:
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "missing X-GitHub-Event or X-Gitlab-Event header")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushPayload))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "delivery exceeds 25 MB")
+			return
+		}
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "failed to read body")
+		return
+	}
+
+	result, err := h.mgr.GitPush(r.Context(), host, event, signature, body)
+	if err != nil {
+		h.log(r).Error().Err(err).Str("git_host", host).Msg("git push webhook")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, result)
+}
@@ -2,8 +2,14 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"service-faas/internal/core/functions"
+	"service-faas/pkg/bundle"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -18,65 +24,393 @@ type Handler struct {
 
 func NewHandler(mgr *functions.Manager, lg zerolog.Logger) http.Handler {
 	r := chi.NewRouter()
-	r.Use(middleware.RequestID)
+	r.Use(requestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	h := &Handler{mgr: mgr, lg: lg}
+	r.Use(h.routeDomains)
 
 	// --- API Routes ---
-	r.Route("/functions", func(r chi.Router) {
-		r.Post("/", h.handleAddFunction)
-		r.Get("/", h.handleListFunctions)
-		r.Post("/{functionID}/execute", h.handleExecuteFunction)
-		r.Delete("/{functionID}", h.handleRemoveFunction)
+	r.Group(func(r chi.Router) {
+		r.Use(h.authenticate)
+
+		// Invoke tokens may call these for their own function; the
+		// handlers check that.
+		r.Get("/results/*", h.handleGetResult)
+		r.Get("/invocations/{invocationID}/result", h.handleGetInvocationResult)
+		r.Post("/invocations/{invocationID}/replay", h.handleReplayInvocation)
+		r.Route("/functions", func(r chi.Router) {
+			r.Post("/{functionID}/execute", h.handleExecuteFunction)
+			r.Post("/{functionID}/execute-stream", h.handleExecuteStream)
+			r.Post("/{functionID}/envs/{env}/execute", h.handleExecuteEnvironment)
+
+			r.Group(func(r chi.Router) {
+				r.Use(requireManagement)
+				r.Post("/", h.handleAddFunction)
+				r.Post("/validate", h.handleValidateFunction)
+				r.Post("/apply", h.handleApply)
+				r.Get("/", h.handleListFunctions)
+				r.Delete("/", h.handleRemoveFunctionsByLabel)
+				r.Post("/bulk-delete", h.handleRemoveFunctions)
+				r.Get("/by-name/{name}", h.handleGetFunctionByName)
+				r.Put("/by-name/{name}", h.handlePutFunction)
+				r.Delete("/by-name/{name}", h.handleRemoveFunctionByName)
+				r.Get("/orphans", h.handleOrphanedFunctions)
+				r.Get("/search", h.handleSearchFunctions)
+				r.Post("/{functionID}/transfer", h.handleTransferFunction)
+				r.Post("/{functionID}/restore", h.handleRestoreFunction)
+				r.Post("/{functionID}/stop", h.handleStopFunction)
+				r.Post("/{functionID}/start", h.handleStartFunction)
+				r.Post("/{functionID}/restart", h.handleRestartFunction)
+				r.Put("/{functionID}/labels", h.handleSetLabels)
+				r.Put("/{functionID}/concurrency", h.handleSetConcurrency)
+				r.Put("/{functionID}/timeout", h.handleSetTimeout)
+				r.Put("/{functionID}/public", h.handleSetPublic)
+				r.Put("/{functionID}/domain", h.handleSetDomain)
+				r.Put("/{functionID}/cors", h.handleSetCORS)
+				r.Put("/{functionID}/replicas", h.handleSetReplicas)
+				r.Put("/{functionID}/affinity", h.handleSetAffinity)
+				r.Put("/{functionID}/kubernetes", h.handleSetKubernetesWorker)
+				r.Put("/{functionID}/schema", h.handleSetPayloadSchema)
+				r.Put("/{functionID}/cache", h.handleSetCacheTTL)
+				r.Put("/{functionID}/warmup", h.handleSetWarmup)
+				r.Post("/{functionID}/warm", h.handleWarmFunction)
+				r.Delete("/{functionID}/cache", h.handleInvalidateCache)
+				r.Get("/{functionID}/usage", h.handleUsage)
+				r.Get("/{functionID}/metrics", h.handleFunctionMetrics)
+				r.Get("/{functionID}/status", h.handleGetLiveStatus)
+				r.Get("/{functionID}/logs", h.handleFunctionLogs)
+				r.Put("/{functionID}/debug", h.handleSetDebug)
+				r.Get("/{functionID}/captures", h.handleListCaptures)
+				r.Delete("/{functionID}/captures", h.handleClearCaptures)
+				r.Get("/{functionID}/build", h.handleGetBuild)
+				r.Post("/{functionID}/build", h.handleRebuildFunction)
+				r.Post("/{functionID}/upgrade", h.handleUpgradeImage)
+				r.Post("/{functionID}/sync", h.handleSyncFunction)
+				r.Get("/{functionID}/deployments", h.handleListGitDeployments)
+				r.Get("/{functionID}/code", h.handleGetCode)
+				r.Post("/{functionID}/tokens", h.handleIssueInvokeToken)
+				r.Get("/{functionID}/tokens", h.handleListInvokeTokens)
+				r.Delete("/{functionID}/tokens/{tokenID}", h.handleRevokeInvokeToken)
+				r.Get("/{functionID}/envs", h.handleListEnvironments)
+				r.Get("/{functionID}/envs/{env}", h.handleGetEnvironment)
+				r.Put("/{functionID}/envs/{env}", h.handleDeployEnvironment)
+				r.Delete("/{functionID}/envs/{env}", h.handleRemoveEnvironment)
+				r.Post("/{functionID}/promote", h.handlePromoteEnvironment)
+				r.Get("/{functionID}/promotions", h.handleListPromotions)
+				r.Get("/{functionID}", h.handleGetFunction)
+				r.Delete("/{functionID}", h.handleRemoveFunction)
+			})
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireManagement)
+			r.Get("/usage/export", h.handleUsageExport)
+			r.Get("/runtimes", h.handleListRuntimes)
+			r.Get("/admin/capacity", h.handleCapacity)
+			r.Get("/admin/orchestrator", h.handleListWorkerResources)
+			r.Delete("/admin/orchestrator/{kind}/{name}", h.handleDeleteWorkerResource)
+			r.Get("/admin/code-integrity", h.handleCodeIntegrity)
+			r.Get("/admin/cache", h.handleCacheReport)
+			r.Get("/admin/runtime", h.handleRuntimeStats)
+			r.Post("/admin/reload", h.handleReloadConfig)
+			r.Get("/export", h.handleExportCatalog)
+			r.Post("/import", h.handleImportCatalog)
+
+			r.Route("/tenants/{tenant}/registries", func(r chi.Router) {
+				r.Post("/", h.handleSetRegistryCredential)
+				r.Get("/", h.handleListRegistryCredentials)
+				r.Delete("/{credentialID}", h.handleDeleteRegistryCredential)
+			})
+
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Post("/", h.handleCreateWebhook)
+				r.Get("/", h.handleListWebhooks)
+				r.Delete("/{webhookID}", h.handleDeleteWebhook)
+			})
+		})
 	})
 
+	// Public, or authenticated by the handler itself.
+	r.Post("/f/{name}", h.handlePublicExecute)
+	r.Options("/f/{name}", h.handlePublicPreflight)
+	r.Get("/.well-known/jwks.json", h.handleJWKS)
+	r.Post("/git/push", h.handleGitPush)
+
 	// --- Swagger Docs Route ---
 	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/docs/index.html", http.StatusMovedPermanently)
 	})
 	r.Get("/docs/*", httpSwagger.WrapHandler)
+	r.Get("/openapi.json", h.handleOpenAPI)
 	return r
 }
 
 // @Summary      Add a new function
-// @Description  Uploads a Python file, creates a new FaaS function container, and returns its details.
+// @Description  Uploads a Python file or a compressed code bundle, or deploys the code from a Git repository, creates a new FaaS function container, and returns its details.
 // @Tags         functions
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        python_file    formData  file   true   "The Python file containing the function handler"
+// @Param        python_file    formData  file   false  "The Python file containing the function handler"
+// @Param        bundle         formData  file   false  "A .tar.zst or .tar.gz/.tgz archive with handler.py at its root, instead of python_file (docker mode only)"
+// @Param        code_sha256    formData  string false  "SHA-256 of the uploaded file or bundle; the upload is rejected if it does not match"
+// @Param        git_url        formData  string false  "Git repository to deploy the code from instead of an upload (with GIT_DEPLOYS); an https or ssh URL"
+// @Param        git_ref        formData  string false  "Branch, tag or commit to deploy; defaults to the repository's default branch"
+// @Param        git_path       formData  string false  "Directory holding handler.py, relative to the repository root. In docker mode the whole directory is deployed as a bundle"
+// @Param        git_token      formData  string false  "Access token for a private https repository"
+// @Param        git_deploy_key formData  string false  "Private SSH deploy key for a private ssh repository"
 // @Param        function_name  formData  string true   "The name of the function to execute (e.g., 'handle')"
+// @Param        name           formData  string false  "Unique name identifying the function, as in manifests applied with POST /functions/apply; a DNS label"
+// @Param        public         formData  bool   false  "Serve the function at /f/{name}, without authentication; needs a name"
+// @Param        domain         formData  string false  "Custom domain whose requests the manager serves with the function, e.g. echo.example.com"
+// @Param        cors           formData  string false  "JSON CORS policy for the public routes, e.g. {\"allowed_origins\": [\"https://app.example.com\"]}"
+// @Param        tenant         formData  string false  "Tenant owning the function"
+// @Param        owner          formData  string false  "User or team responsible for the function"
+// @Param        worker_image   formData  string false  "Custom worker image, pulled with the tenant's registry credentials; its registry must be in WORKER_IMAGE_REGISTRIES, by default the Harbor registry"
+// @Param        runtime        formData  string false  "Runtime whose worker image the function runs (see GET /runtimes); not together with worker_image"
+// @Param        pre_hook       formData  string false  "JSON hook called before each invocation, e.g. {\"function_id\": \"...\", \"on_failure\": \"abort\"}"
+// @Param        post_hook      formData  string false  "JSON hook called with each result, e.g. {\"url\": \"https://...\", \"on_failure\": \"continue\"}"
+// @Param        description    formData  string false  "What the function does; searchable"
+// @Param        labels         formData  string false  "JSON object of labels, e.g. {\"team\": \"iot\"}; copied onto the worker's container or pod"
+// @Param        env_vars       formData  string false  "JSON object of env vars set in the worker's environment, e.g. {\"LOG_LEVEL\": \"debug\"}"
+// @Param        max_concurrency  formData  int    false  "Maximum executions in flight at once; further calls queue briefly, then get 429. 0 or unset means unlimited"
+// @Param        max_payload_bytes formData  int  false  "Execute payload limit for this function; may only lower MAX_PAYLOAD_BYTES"
+// @Param        cache_ttl_seconds formData int  false  "Cache results of identical payloads for this many seconds; only for idempotent functions"
+// @Param        timeout_seconds formData  int    false  "Longest a call to the worker may take, and the most X-Timeout-Seconds may ask for. 0 or unset means no limit"
+// @Param        replicas       formData  int    false  "Worker containers to run and balance executions across (docker mode only); 0 or unset means one"
+// @Param        affinity       formData  string false  "JSON replica affinity, e.g. {\"field\": \"session_id\"}; executions with the same X-Affinity-Key header or payload field go to the same replica (docker mode only)"
+// @Param        kubernetes     formData  string false  "JSON Kubernetes overrides, e.g. {\"service_account\": \"payments\", \"image_pull_secrets\": [\"payments-registry\"]} (kubernetes and knative mode only)"
+// @Param        payload_schema formData  string false  "JSON Schema that execute payloads (parsed as JSON) must match"
+// @Param        warmup         formData  string false  "JSON warm-up settings, e.g. {\"requests\": 3, \"payload\": \"...\", \"on_deploy\": true}"
+// @Param        fallback       formData  string false  "JSON fallback used when the function fails or times out, e.g. {\"function_id\": \"...\", \"timeout_ms\": 2000}"
+// @Param        expose         formData  string false  "Route external traffic directly to the function (kubernetes mode only)" Enums(ingress, httproute)
+// @Param        expose_host    formData  string false  "Host the route matches"
+// @Param        expose_path    formData  string false  "Path prefix the route matches; defaults to /fn/<function id>"
 // @Success      201  {object}  functions.Function
-// @Failure      400  {string}  string "Bad Request"
-// @Failure      500  {string}  string "Internal Server Error"
+// @Success      202  {object}  functions.Function "With IMAGE_BUILDS, the function is in the building status until its image is built and its worker started"
+// @Failure      400  {object}  apiError "Bad Request, or the Git repository or ref cannot be fetched, or the handler code does not define function_name at the top level or, in docker mode only, has syntax errors or a function_name without a single payload argument (INVALID_CODE, problems in details), or it violates the code policy (CODE_POLICY_VIOLATION, findings in details)"
+// @Failure      409  {object}  apiError "Another function has the name (NAME_TAKEN)"
+// @Failure      413  {object}  apiError "The upload exceeds MAX_UPLOAD_BYTES"
+// @Failure      422  {object}  apiError "The malware scan found a threat; the function is kept in the blocked status"
+// @Failure      500  {object}  apiError "Internal Server Error"
 // @Router       /functions [post]
 func (h *Handler) handleAddFunction(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max
-		http.Error(w, `{"error": "invalid form data"}`, http.StatusBadRequest)
+	file, bundleFormat, ok := h.readUpload(w, r, uploadOrGit)
+	if !ok {
 		return
 	}
-	file, _, err := r.FormFile("python_file")
-	if err != nil {
-		http.Error(w, `{"error": "missing 'python_file' in form"}`, http.StatusBadRequest)
-		return
+	var code io.Reader
+	if file != nil {
+		defer file.Close()
+		code = file
 	}
-	defer file.Close()
 
 	functionName := r.FormValue("function_name")
 	if functionName == "" {
-		http.Error(w, `{"error": "missing 'function_name' in form"}`, http.StatusBadRequest)
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "missing 'function_name' in form")
 		return
 	}
 
-	fn, err := h.mgr.AddFunction(r.Context(), functionName, file)
+	opts := functions.FunctionOptions{
+		Name:        r.FormValue("name"),
+		Domain:      r.FormValue("domain"),
+		Tenant:      r.FormValue("tenant"),
+		Owner:       r.FormValue("owner"),
+		WorkerImage: r.FormValue("worker_image"),
+		Runtime:     r.FormValue("runtime"),
+		Description: r.FormValue("description"),
+
+		BundleFormat:   bundleFormat,
+		ExpectedSHA256: r.FormValue("code_sha256"),
+	}
+	if gitURL := r.FormValue("git_url"); gitURL != "" {
+		opts.Git = &functions.GitSource{URL: gitURL, Ref: r.FormValue("git_ref"), Path: r.FormValue("git_path")}
+		opts.GitAuth = functions.GitAuth{Token: r.FormValue("git_token"), DeployKey: r.FormValue("git_deploy_key")}
+	}
+	var err error
+	if raw := r.FormValue("public"); raw != "" {
+		if opts.Public, err = strconv.ParseBool(raw); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'public', expected true or false")
+			return
+		}
+	}
+	if raw := r.FormValue("labels"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Labels); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'labels' json")
+			return
+		}
+	}
+	if raw := r.FormValue("env_vars"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.EnvVars); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'env_vars' json, expected an object of strings")
+			return
+		}
+	}
+	if raw := r.FormValue("max_concurrency"); raw != "" {
+		if opts.MaxConcurrency, err = strconv.Atoi(raw); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'max_concurrency', expected an integer")
+			return
+		}
+	}
+	if raw := r.FormValue("max_payload_bytes"); raw != "" {
+		if opts.MaxPayloadBytes, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'max_payload_bytes', expected an integer")
+			return
+		}
+	}
+	if raw := r.FormValue("cache_ttl_seconds"); raw != "" {
+		if opts.CacheTTLSeconds, err = strconv.Atoi(raw); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'cache_ttl_seconds', expected an integer")
+			return
+		}
+	}
+	if raw := r.FormValue("timeout_seconds"); raw != "" {
+		if opts.TimeoutSeconds, err = strconv.Atoi(raw); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'timeout_seconds', expected an integer")
+			return
+		}
+	}
+	if raw := r.FormValue("replicas"); raw != "" {
+		if opts.Replicas, err = strconv.Atoi(raw); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'replicas', expected an integer")
+			return
+		}
+	}
+	if raw := r.FormValue("payload_schema"); raw != "" {
+		if !json.Valid([]byte(raw)) {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'payload_schema' json")
+			return
+		}
+		opts.PayloadSchema = json.RawMessage(raw)
+	}
+	if raw := r.FormValue("cors"); raw != "" {
+		opts.CORS = &functions.CORSPolicy{}
+		if err := json.Unmarshal([]byte(raw), opts.CORS); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'cors' json")
+			return
+		}
+	}
+	if raw := r.FormValue("warmup"); raw != "" {
+		opts.Warmup = &functions.Warmup{}
+		if err := json.Unmarshal([]byte(raw), opts.Warmup); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'warmup' json")
+			return
+		}
+	}
+	if raw := r.FormValue("affinity"); raw != "" {
+		opts.Affinity = &functions.Affinity{}
+		if err := json.Unmarshal([]byte(raw), opts.Affinity); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'affinity' json")
+			return
+		}
+	}
+	if raw := r.FormValue("kubernetes"); raw != "" {
+		opts.Kubernetes = &functions.KubernetesWorker{}
+		if err := json.Unmarshal([]byte(raw), opts.Kubernetes); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'kubernetes' json")
+			return
+		}
+	}
+	if raw := r.FormValue("fallback"); raw != "" {
+		opts.Fallback = &functions.Fallback{}
+		if err := json.Unmarshal([]byte(raw), opts.Fallback); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'fallback' json")
+			return
+		}
+	}
+	if kind := r.FormValue("expose"); kind != "" {
+		opts.Exposure = &functions.Exposure{
+			Kind: kind,
+			Host: r.FormValue("expose_host"),
+			Path: r.FormValue("expose_path"),
+		}
+	}
+	if opts.PreHook, err = parseHook(r.FormValue("pre_hook")); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'pre_hook' json")
+		return
+	}
+	if opts.PostHook, err = parseHook(r.FormValue("post_hook")); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'post_hook' json")
+		return
+	}
+
+	fn, err := h.mgr.AddFunction(r.Context(), functionName, code, opts)
 	if err != nil {
-		h.lg.Error().Err(err).Msg("add function")
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		h.log(r).Error().Err(err).Msg("add function")
+		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, fn)
+	status := http.StatusCreated
+	if fn.Status == functions.StatusBuilding {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, fn)
+}
+
+// What readUpload accepts instead of a python_file or bundle.
+type uploadMode int
+
+const (
+	uploadRequired uploadMode = iota
+	uploadOrGit               // a git_url
+	uploadOptional            // a git_url, or no code at all
+)
+
+// readUpload parses a function upload form and opens its python_file or
+// bundle, with the bundle's format. Depending on mode, the file is nil when
+// the form names a git_url instead, or has no code. It writes the error
+// response itself.
+func (h *Handler) readUpload(w http.ResponseWriter, r *http.Request, mode uploadMode) (multipart.File, string, bool) {
+	if limit := h.mgr.MaxUploadBytes(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // parts beyond 10 MB are buffered on disk
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "upload exceeds "+strconv.FormatInt(maxErr.Limit, 10)+" bytes")
+			return nil, "", false
+		}
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid form data")
+		return nil, "", false
+	}
+	var bundleFormat string
+	file, _, err := r.FormFile("python_file")
+	if errors.Is(err, http.ErrMissingFile) {
+		var hdr *multipart.FileHeader
+		file, hdr, err = r.FormFile("bundle")
+		if err == nil {
+			var ok bool
+			if bundleFormat, ok = bundle.FormatFromName(hdr.Filename); !ok {
+				file.Close()
+				writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "'bundle' must be a .tar.zst, .tar.gz or .tgz file")
+				return nil, "", false
+			}
+		}
+	}
+	allowGit := mode != uploadRequired
+	gitURL := allowGit && r.FormValue("git_url") != ""
+	switch {
+	case errors.Is(err, http.ErrMissingFile) && (gitURL || mode == uploadOptional):
+		return nil, "", true
+	case err == nil && gitURL:
+		file.Close()
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "give an upload or a 'git_url', not both")
+		return nil, "", false
+	case err != nil && allowGit:
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "missing 'python_file', 'bundle' or 'git_url' in form")
+		return nil, "", false
+	case err != nil:
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "missing 'python_file' or 'bundle' in form")
+		return nil, "", false
+	}
+	return file, bundleFormat, true
+}
+
+type executeRequest struct {
+	// Payload is passed to the handler as is; it is usually JSON.
+	Payload string `json:"payload" example:"{\"temperature\": 21.5}"`
 }
 
 // @Summary      Execute a function
@@ -85,64 +419,395 @@ func (h *Handler) handleAddFunction(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        functionID path string true "Function ID"
-// @Param        body body string true "Payload for the function"
-// @Success      200  {object}  object "{"result": "..."}"
-// @Failure      400  {string}  string "Bad Request"
-// @Failure      500  {string}  string "Internal Server Error"
+// @Param        body body executeRequest true "Payload for the function"
+// @Param        Idempotency-Key header string false "Executes at most once per key; retries with the same key and payload get the stored result"
+// @Param        X-Timeout-Seconds header number false "Time out the worker call after this many seconds; capped at the function's timeout_seconds"
+// @Param        X-Affinity-Key header string false "Sends executions with the same key to the same worker replica, for functions with an affinity"
+// @Success      200  {object}  functions.ExecutionResult "Inline result, or a reference when the result was offloaded"
+// @Header       200  {string}  X-Faas-Invocation-Id "Identifies the execution; also set on errors once the execution started. Its result can be fetched from /invocations/{invocationID}/result"
+// @Header       200  {string}  X-Faas-Degraded "Set when the fallback function answered: error, timeout, circuit_open or busy"
+// @Header       200  {string}  X-Faas-Cache "hit when the result was served from the response cache"
+// @Header       200  {string}  Idempotent-Replayed "true when the result was stored by an earlier request with the same Idempotency-Key"
+// @Failure      400  {object}  apiError "Bad Request, or the payload does not match the function's schema (INVALID_PAYLOAD, violations in details)"
+// @Failure      404  {object}  apiError "FUNCTION_NOT_FOUND"
+// @Failure      409  {object}  apiError "The function is not running, or a request with the same Idempotency-Key is still running"
+// @Failure      413  {object}  apiError "The payload exceeds MAX_PAYLOAD_BYTES or the function's max_payload_bytes"
+// @Failure      422  {object}  apiError "The handler raised an exception (HANDLER_ERROR, its type, message and traceback in details.exception), or the Idempotency-Key was used with a different payload"
+// @Failure      429  {object}  apiError "The function is at its concurrency limit, or the manager is overloaded"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      502  {object}  apiError "The worker could not be reached (WORKER_UNAVAILABLE) or failed (WORKER_ERROR)"
+// @Failure      503  {object}  apiError "The manager is shutting down (SHUTTING_DOWN)"
+// @Failure      504  {object}  apiError "The worker did not answer in time (WORKER_TIMEOUT)"
 // @Router       /functions/{functionID}/execute [post]
 func (h *Handler) handleExecuteFunction(w http.ResponseWriter, r *http.Request) {
-	functionID := chi.URLParam(r, "functionID")
-	var req struct {
-		Payload string `json:"payload"`
+	h.execute(w, r, chi.URLParam(r, "functionID"))
+}
+
+// execute runs an execute request against a function.
+func (h *Handler) execute(w http.ResponseWriter, r *http.Request, functionID string) {
+	if !canInvoke(w, r, functionID) {
+		return
+	}
+	timeout, ok := requestTimeout(r)
+	if !ok {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid '"+timeoutHeader+"', expected a positive number of seconds")
+		return
+	}
+	var req executeRequest
+	if limit := h.mgr.MaxPayloadBytes(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, envelopeLimit(limit))
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error": "invalid json body"}`, http.StatusBadRequest)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "request body exceeds "+strconv.FormatInt(maxErr.Limit, 10)+" bytes")
+			return
+		}
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
 		return
 	}
 
-	result, err := h.mgr.ExecuteFunction(r.Context(), functionID, req.Payload)
+	result, ok := h.run(w, r, functionID, req.Payload, timeout)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// envelopeLimit bounds the body of an execute request whose payload may be
+// up to limit bytes. JSON escapes a byte as at most six, and the rest of the
+// envelope is small; the payload itself is checked once decoded.
+func envelopeLimit(limit int64) int64 {
+	return 6*limit + 4096
+}
+
+// run executes a function with the execution headers of the request, and
+// sets the result's headers. On failure it writes the error response itself.
+func (h *Handler) run(w http.ResponseWriter, r *http.Request, functionID, payload string, timeout time.Duration) (*functions.ExecutionResult, bool) {
+	ctx := r.Context()
+	if timeout > 0 {
+		ctx = functions.WithTimeout(ctx, timeout)
+	}
+	if key := r.Header.Get(functions.AffinityKeyHeader); key != "" {
+		ctx = functions.WithAffinityKey(ctx, key)
+	}
+	var result *functions.ExecutionResult
+	var err error
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		result, err = h.mgr.ExecuteOnce(ctx, functionID, key, payload)
+	} else {
+		result, err = h.mgr.ExecuteFunction(ctx, functionID, payload)
+	}
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("execute function")
+		var invErr *functions.InvocationError
+		if errors.As(err, &invErr) {
+			w.Header().Set("X-Faas-Invocation-Id", invErr.InvocationID)
+		}
+		var handlerErr *functions.HandlerError
+		if errors.As(err, &handlerErr) && !requestAccess(r).CanInvoke(functionID) {
+			// Callers of public routes are not shown the handler's code.
+			public := *handlerErr
+			public.Traceback = ""
+			err = &public
+		}
+		writeError(w, err)
+		return nil, false
+	}
+	w.Header().Set("X-Faas-Invocation-Id", result.InvocationID)
+	if result.Degraded != "" {
+		w.Header().Set("X-Faas-Degraded", result.Degraded)
+	}
+	if result.Cached {
+		w.Header().Set("X-Faas-Cache", "hit")
+	}
+	if result.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	return result, true
+}
+
+// @Summary      Fetch an offloaded result
+// @Description  Streams a result that was too large to be returned inline by the execute endpoint.
+// @Tags         results
+// @Produce      json
+// @Param        key path string true "Result key"
+// @Success      200  {object}  object "The function's JSON result"
+// @Failure      400  {object}  apiError "Invalid result key"
+// @Failure      404  {object}  apiError "Not Found"
+// @Router       /results/{key} [get]
+func (h *Handler) handleGetResult(w http.ResponseWriter, r *http.Request) {
+	// Keys start with the ID of the function that produced the result.
+	key, functionID, err := functions.CleanResultKey(chi.URLParam(r, "*"))
 	if err != nil {
-		h.lg.Error().Err(err).Msg("execute function")
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		writeError(w, err)
+		return
+	}
+	if !canInvoke(w, r, functionID) {
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]json.RawMessage{"result": result})
+	rc, err := h.mgr.OpenResult(r.Context(), key)
+	if err != nil {
+		h.log(r).Warn().Err(err).Str("key", key).Msg("open result")
+		writeErrorMessage(w, http.StatusNotFound, codeNotFound, "result not found")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// jwks describes the JSON Web Key Set served for identity tokens, which the
+// issuer encodes itself.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty" example:"OKP"`
+	Crv string `json:"crv" example:"Ed25519"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"EdDSA"`
+	Kid string `json:"kid"`
+	X   string `json:"x"`
+}
+
+// @Summary      Identity token keys
+// @Description  Returns the JSON Web Key Set that verifies function identity tokens.
+// @Tags         identity
+// @Produce      json
+// @Success      200  {object}  jwks
+// @Failure      404  {object}  apiError "Not Found"
+// @Router       /.well-known/jwks.json [get]
+func (h *Handler) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, enabled, err := h.mgr.IdentityKeys()
+	if !enabled {
+		writeErrorMessage(w, http.StatusNotFound, codeNotFound, "identity tokens are not enabled")
+		return
+	}
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("identity keys")
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(jwks)
 }
 
 // @Summary      List all functions
-// @Description  Retrieves a list of all registered functions.
+// @Description  Retrieves a list of all registered functions, or of the soft-deleted ones.
 // @Tags         functions
 // @Produce      json
+// @Param        deleted query bool   false "List soft-deleted functions instead"
+// @Param        label   query []string false "Only functions with this label, as key=value; repeat to require several" collectionFormat(multi)
 // @Success      200  {array}   functions.Function
-// @Failure      500  {string}  string "Internal Server Error"
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      500  {object}  apiError "Internal Server Error"
 // @Router       /functions [get]
 func (h *Handler) handleListFunctions(w http.ResponseWriter, r *http.Request) {
-	list, err := h.mgr.ListFunctions()
+	filter := functions.ListFilter{Deleted: r.URL.Query().Get("deleted") == "true"}
+	var err error
+	if filter.Labels, err = functions.ParseLabelSelector(r.URL.Query()["label"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	fns, err := h.mgr.ListFunctions(r.Context(), filter)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list functions")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fns)
+}
+
+// @Summary      Get a function
+// @Description  Returns a function. For a running function, "worker" holds the live desired and ready replica counts and container restarts, where the orchestrator reports them.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function
+// @Header       200  {string}  ETag "The function's ETag, for If-Match on PUT /functions/by-name/{name}"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID} [get]
+func (h *Handler) handleGetFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.GetFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", fn.ETag())
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Get a function's live status
+// @Description  Asks the orchestrator for the function's worker now: its pods or container with their phases and container states, restart and termination reasons, and recent events. "status" is the status the database records, which may lag behind. "worker" is null when the orchestrator has no worker for the function. Supported in docker and kubernetes mode.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.LiveStatus
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Not supported by the orchestrator"
+// @Router       /functions/{functionID}/status [get]
+func (h *Handler) handleGetLiveStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.mgr.GetLiveStatus(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// @Summary      Search functions
+// @Description  Finds functions whose name, description or labels contain every word of the query as a word prefix, best matches first.
+// @Tags         functions
+// @Produce      json
+// @Param        q     query string true  "Search words"
+// @Param        limit query int    false "Maximum number of results (default 20, at most 100)"
+// @Success      200  {array}   functions.SearchHit
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/search [get]
+func (h *Handler) handleSearchFunctions(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'limit'")
+			return
+		}
+		limit = n
+	}
+
+	hits, err := h.mgr.SearchFunctions(r.Context(), r.URL.Query().Get("q"), limit)
 	if err != nil {
-		h.lg.Error().Err(err).Msg("list functions")
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		h.log(r).Error().Err(err).Msg("search functions")
+		writeError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, list)
+	writeJSON(w, http.StatusOK, hits)
 }
 
 // @Summary      Remove a function
-// @Description  Stops the function's container and removes its record from the database.
+// @Description  Stops the function's container and marks it deleted; it can be restored until purged. With purge=true the record and code are removed permanently. The function's environments are removed or purged with it.
 // @Tags         functions
 // @Produce      json
 // @Param        functionID path string true "Function ID"
-// @Success      204  {string}  string "No Content"
-// @Failure      500  {string}  string "Internal Server Error"
+// @Param        purge query bool false "Remove the function permanently, including its code"
+// @Success      204  "No Content"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
 // @Router       /functions/{functionID} [delete]
 func (h *Handler) handleRemoveFunction(w http.ResponseWriter, r *http.Request) {
 	functionID := chi.URLParam(r, "functionID")
-	if err := h.mgr.RemoveFunction(r.Context(), functionID); err != nil {
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+	remove := h.mgr.RemoveFunction
+	if r.URL.Query().Get("purge") == "true" {
+		remove = h.mgr.PurgeFunction
+	}
+	if err := remove(r.Context(), functionID); err != nil {
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary      Restore a deleted function
+// @Description  Redeploys a soft-deleted function from its stored code.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Function is not deleted"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/restore [post]
+func (h *Handler) handleRestoreFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.RestoreFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("restore function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Stop a function
+// @Description  Stops the function's worker but keeps its record and code, leaving it in the "stopped" status until it is started again. Stopping a stopped function does nothing.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "The function is deleted, blocked or being built"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/stop [post]
+func (h *Handler) handleStopFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.StopFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("stop function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Start a stopped function
+// @Description  Redeploys a stopped function from its stored code.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Function is not stopped"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      422  {object}  apiError "Function code is blocked"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/start [post]
+func (h *Handler) handleStartFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.StartFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("start function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Restart a function
+// @Description  Replaces the worker of a running function with a fresh container or pod and returns the new worker details. If the new worker cannot be started, the function is left stopped.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Function is not running"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/restart [post]
+func (h *Handler) handleRestartFunction(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.RestartFunction(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("restart function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+func parseHook(raw string) (*functions.Hook, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var hook functions.Hook
+	if err := json.Unmarshal([]byte(raw), &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      Upgrade a function's worker image
+// @Description  Resolves the tag of the function's worker image again. If it now points to a new digest, the function is redeployed on it (or, with IMAGE_BUILDS, rebuilt on it). Only available with PIN_IMAGE_DIGESTS.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "The function is deleted or blocked, or its image cannot be found in its registry"
+// @Failure      404  {object}  apiError "Unknown function"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Image digests are not pinned"
+// @Router       /functions/{functionID}/upgrade [post]
+func (h *Handler) handleUpgradeImage(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.UpgradeFunctionImage(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("upgrade function image")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      Get an invocation's result
+// @Description  Returns the stored outcome of an execution by the ID from its X-Faas-Invocation-Id header. Outcomes are kept for INVOCATION_RESULT_TTL; results larger than INVOCATION_RESULT_MAX_BYTES are reported as truncated, and offloaded results as a reference.
+// @Tags         functions
+// @Produce      json
+// @Param        invocationID path string true "Invocation ID"
+// @Success      200  {object}  functions.Invocation
+// @Failure      404  {object}  apiError "Unknown or expired invocation"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Invocation results are not kept"
+// @Router       /invocations/{invocationID}/result [get]
+func (h *Handler) handleGetInvocationResult(w http.ResponseWriter, r *http.Request) {
+	inv, err := h.mgr.GetInvocation(r.Context(), chi.URLParam(r, "invocationID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("get invocation")
+		writeError(w, err)
+		return
+	}
+	if !canInvoke(w, r, inv.FunctionID) {
+		return
+	}
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// @Summary      Replay an invocation
+// @Description  Runs a stored invocation's payload again against the function's current code, bypassing the response cache, and compares the outcomes: whether the code changed, whether the replay ended the same, and the JSON Pointer paths where the results differ. The original must have been stored with its payload (INVOCATION_CAPTURE_PAYLOADS). The replay is an invocation of its own, whose ID is also returned in X-Faas-Invocation-Id; its failure is part of the comparison.
+// @Tags         functions
+// @Produce      json
+// @Param        invocationID path string true "Invocation ID"
+// @Success      200  {object}  functions.InvocationReplay
+// @Header       200  {string}  X-Faas-Invocation-Id "The replay's invocation ID"
+// @Failure      400  {object}  apiError "The invocation was stored without its payload"
+// @Failure      404  {object}  apiError "Unknown or expired invocation, or the function is gone"
+// @Failure      429  {object}  apiError "The manager is overloaded"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Invocation results are not kept"
+// @Failure      503  {object}  apiError "The manager is shutting down (SHUTTING_DOWN)"
+// @Router       /invocations/{invocationID}/replay [post]
+func (h *Handler) handleReplayInvocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	inv, err := h.mgr.GetInvocation(ctx, chi.URLParam(r, "invocationID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("get invocation")
+		writeError(w, err)
+		return
+	}
+	if !canInvoke(w, r, inv.FunctionID) {
+		return
+	}
+	replay, err := h.mgr.ReplayInvocation(ctx, inv.ID)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("replay invocation")
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("X-Faas-Invocation-Id", replay.Replay.ID)
+	writeJSON(w, http.StatusOK, replay)
+}
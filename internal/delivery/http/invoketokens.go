@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type invokeTokenRequest struct {
+	// Description says who the token is for.
+	Description string `json:"description,omitempty" example:"billing-service"`
+	// ExpiresInSeconds is how long the token is valid; 0 or unset keeps it
+	// valid until it is revoked.
+	ExpiresInSeconds int64 `json:"expires_in_seconds,omitempty" example:"2592000"`
+}
+
+// @Summary      Issue an invoke token
+// @Description  Creates a bearer token that can execute this function, through /execute, /execute-stream and its results, and nothing else: it cannot list, change or delete functions. Hand it to services that only need to call the function. The token is only returned here; the manager keeps a hash of it. Invoke tokens only restrict callers when API_TOKEN is set; without it the whole API is open.
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body invokeTokenRequest false "Token settings"
+// @Success      201  {object}  functions.InvokeToken
+// @Failure      400  {object}  apiError "Bad Request, or the function is deleted"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/tokens [post]
+func (h *Handler) handleIssueInvokeToken(w http.ResponseWriter, r *http.Request) {
+	var req invokeTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+			return
+		}
+	}
+	ttl := time.Duration(req.ExpiresInSeconds) * time.Second
+	tok, err := h.mgr.IssueInvokeToken(r.Context(), chi.URLParam(r, "functionID"), req.Description, ttl)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("issue invoke token")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tok)
+}
+
+// @Summary      List invoke tokens
+// @Description  Lists a function's invoke tokens, including expired and revoked ones, without the tokens themselves.
+// @Tags         tokens
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Success      200  {array}   functions.InvokeToken
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/tokens [get]
+func (h *Handler) handleListInvokeTokens(w http.ResponseWriter, r *http.Request) {
+	toks, err := h.mgr.InvokeTokens(r.Context(), chi.URLParam(r, "functionID"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toks)
+}
+
+// @Summary      Revoke an invoke token
+// @Description  Makes an invoke token unusable at once. The token stays listed, with its revoked_at.
+// @Tags         tokens
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        tokenID    path string true "Token ID"
+// @Success      200  {object}  functions.InvokeToken
+// @Failure      404  {object}  apiError "The function has no such token"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/tokens/{tokenID} [delete]
+func (h *Handler) handleRevokeInvokeToken(w http.ResponseWriter, r *http.Request) {
+	tok, err := h.mgr.RevokeInvokeToken(r.Context(), chi.URLParam(r, "functionID"), chi.URLParam(r, "tokenID"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("revoke invoke token")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tok)
+}
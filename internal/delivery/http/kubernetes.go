@@ -0,0 +1,41 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type kubernetesWorkerRequest struct {
+	Kubernetes *functions.KubernetesWorker `json:"kubernetes"`
+}
+
+// @Summary      Set a function's Kubernetes overrides
+// @Description  Runs the function's pods as another service account, or pulls their image with other pull secrets, than the configured ones (kubernetes and knative mode only). Both must exist in the workers' namespace. A running worker is restarted. A null value removes the overrides.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body kubernetesWorkerRequest true "New Kubernetes overrides"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/kubernetes [put]
+func (h *Handler) handleSetKubernetesWorker(w http.ResponseWriter, r *http.Request) {
+	var req kubernetesWorkerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetKubernetesWorker(r.Context(), chi.URLParam(r, "functionID"), req.Kubernetes)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set kubernetes settings")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
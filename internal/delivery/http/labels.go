@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type labelsRequest struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// @Summary      Replace a function's labels
+// @Description  Sets the function's labels, replacing all existing ones. Running workers keep their old labels until they are restarted.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body labelsRequest true "New labels"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/labels [put]
+func (h *Handler) handleSetLabels(w http.ResponseWriter, r *http.Request) {
+	var req labelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetLabels(r.Context(), chi.URLParam(r, "functionID"), req.Labels)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set labels")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultLogTail is how many lines of each instance a logs request starts
+// with when it does not say.
+const defaultLogTail = 100
+
+// @Summary      Read a function's logs
+// @Description  Returns what the function's worker instances wrote to stdout and stderr, the latest "tail" lines of each, one JSON object per line. With follow=true the response stays open and new lines are streamed as the workers write them, until the client disconnects or the workers stop; instances started after the request are not included. Lines of different instances interleave; "instance" is the container or pod that wrote the line. Send "Accept: text/event-stream" for Server-Sent Events ("log" events) instead of NDJSON. If reading fails after lines were sent, a last line (or "error" event) holds only "error" and "code". When logs are shipped to a log backend and LOG_SHIPPING_QUERY_URL is set, the Link header points to the function's logs there. Supported in docker and kubernetes mode.
+// @Tags         functions
+// @Produce      application/x-ndjson
+// @Produce      text/event-stream
+// @Param        functionID path  string  true  "Function ID"
+// @Param        tail       query int     false "Lines of each instance to start with (default 100, at most 10000)"
+// @Param        since      query string  false "Leave out lines written before this, an RFC 3339 time or a duration ago such as 10m"
+// @Param        follow     query bool    false "Keep streaming new lines"
+// @Success      200  {array}   functions.LogLine
+// @Header       200  {string}  Link "The function's logs in the log backend, when LOG_SHIPPING_QUERY_URL is set"
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Failure      501  {object}  apiError "Not supported by the orchestrator"
+// @Router       /functions/{functionID}/logs [get]
+func (h *Handler) handleFunctionLogs(w http.ResponseWriter, r *http.Request) {
+	functionID := chi.URLParam(r, "functionID")
+	opts, msg := logOptions(r)
+	if msg != "" {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, msg)
+		return
+	}
+	fn, err := h.mgr.GetFunction(r.Context(), functionID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	contentType := "application/x-ndjson"
+	if sse {
+		contentType = "text/event-stream"
+	}
+	rc := http.NewResponseController(w)
+
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		// Keeps proxies such as nginx from buffering followed lines.
+		w.Header().Set("X-Accel-Buffering", "no")
+		if link := h.mgr.LogsURL(fn); link != "" {
+			w.Header().Set("Link", "<"+link+`>; rel="alternate"`)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	write := func(event string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if sse {
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		} else {
+			_, err = fmt.Fprintf(w, "%s\n", data)
+		}
+		return err
+	}
+
+	err = h.mgr.FunctionLogs(r.Context(), functionID, opts, func(line functions.LogLine) error {
+		if !started {
+			start()
+		}
+		if err := write("log", line); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err == nil {
+		if !started {
+			start()
+		}
+		return
+	}
+	h.log(r).Error().Err(err).Str("function_id", functionID).Msg("function logs")
+	if !started {
+		writeError(w, err)
+		return
+	}
+	_, apiErr := toAPIError(err)
+	_ = write("error", map[string]string{"error": apiErr.Message, "code": apiErr.Code})
+}
+
+// logOptions reads the query of a logs request, or returns why it is
+// invalid.
+func logOptions(r *http.Request) (functions.LogOptions, string) {
+	q := r.URL.Query()
+	opts := functions.LogOptions{Tail: defaultLogTail}
+	if v := q.Get("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, "invalid 'tail', expected a number of lines"
+		}
+		opts.Tail = n
+	}
+	if v := q.Get("since"); v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			opts.Since = t
+		} else if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts.Since = time.Now().Add(-d)
+		} else {
+			return opts, "invalid 'since', expected an RFC 3339 time or a duration such as 10m"
+		}
+	}
+	if v := q.Get("follow"); v != "" {
+		follow, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, "invalid 'follow', expected true or false"
+		}
+		opts.Follow = follow
+	}
+	return opts, ""
+}
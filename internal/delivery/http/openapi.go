@@ -0,0 +1,273 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/swaggo/swag"
+)
+
+// openAPIVersion is the version of the document served at /openapi.json.
+const openAPIVersion = "3.0.3"
+
+// openAPISpec converts the Swagger 2.0 document generated from the handler
+// annotations once, so new endpoints show up in both documents.
+var openAPISpec = sync.OnceValues(func() ([]byte, error) {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		return nil, fmt.Errorf("read swagger document: %w", err)
+	}
+	return convertToOpenAPI3([]byte(doc))
+})
+
+// @Summary      OpenAPI 3 document
+// @Description  Returns the API description as an OpenAPI 3 document, e.g. for generating client SDKs.
+// @Tags         docs
+// @Produce      json
+// @Success      200  {object}  object "OpenAPI 3 document"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /openapi.json [get]
+func (h *Handler) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	spec, err := openAPISpec()
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("openapi document")
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(spec)
+}
+
+// swagger2 is the part of a Swagger 2.0 document that is converted.
+type swagger2 struct {
+	Info        json.RawMessage                         `json:"info"`
+	BasePath    string                                  `json:"basePath"`
+	Consumes    []string                                `json:"consumes"`
+	Produces    []string                                `json:"produces"`
+	Tags        json.RawMessage                         `json:"tags"`
+	Paths       map[string]map[string]swagger2Operation `json:"paths"`
+	Definitions map[string]json.RawMessage              `json:"definitions"`
+}
+
+type swagger2Operation struct {
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationID string                      `json:"operationId,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Consumes    []string                    `json:"consumes"`
+	Produces    []string                    `json:"produces"`
+	Parameters  []swagger2Parameter         `json:"parameters"`
+	Responses   map[string]swagger2Response `json:"responses"`
+}
+
+type swagger2Parameter struct {
+	Name             string          `json:"name"`
+	In               string          `json:"in"`
+	Description      string          `json:"description"`
+	Required         bool            `json:"required"`
+	Schema           json.RawMessage `json:"schema"`
+	Type             string          `json:"type"`
+	Format           string          `json:"format"`
+	Items            json.RawMessage `json:"items"`
+	CollectionFormat string          `json:"collectionFormat"`
+	Enum             json.RawMessage `json:"enum"`
+	Default          json.RawMessage `json:"default"`
+	Minimum          *float64        `json:"minimum"`
+	Maximum          *float64        `json:"maximum"`
+}
+
+type swagger2Response struct {
+	Description string                    `json:"description"`
+	Schema      json.RawMessage           `json:"schema"`
+	Headers     map[string]swagger2Header `json:"headers"`
+}
+
+type swagger2Header struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// convertToOpenAPI3 converts the Swagger 2.0 constructs the annotations
+// produce: path, query and header parameters, JSON bodies, multipart forms,
+// typed responses with headers, and definitions.
+func convertToOpenAPI3(doc []byte) ([]byte, error) {
+	var in swagger2
+	if err := json.Unmarshal(doc, &in); err != nil {
+		return nil, fmt.Errorf("parse swagger document: %w", err)
+	}
+	basePath := in.BasePath
+	if basePath == "" {
+		basePath = "/"
+	}
+	out := map[string]any{
+		"openapi": openAPIVersion,
+		"info":    in.Info,
+		// Relative, so clients use the host that served the document.
+		"servers":    []map[string]string{{"url": basePath}},
+		"paths":      map[string]any{},
+		"components": map[string]any{"schemas": in.Definitions},
+	}
+	if len(in.Tags) > 0 {
+		out["tags"] = in.Tags
+	}
+
+	paths := out["paths"].(map[string]any)
+	for path, ops := range in.Paths {
+		item := map[string]any{}
+		for method, op := range ops {
+			consumes := firstNonEmpty(op.Consumes, in.Consumes, []string{"application/json"})
+			produces := firstNonEmpty(op.Produces, in.Produces, []string{"application/json"})
+			item[method] = convertOperation(op, consumes, produces)
+		}
+		paths[path] = item
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("encode openapi document: %w", err)
+	}
+	return bytes.ReplaceAll(b, []byte(`"#/definitions/`), []byte(`"#/components/schemas/`)), nil
+}
+
+func convertOperation(op swagger2Operation, consumes, produces []string) map[string]any {
+	out := map[string]any{"responses": map[string]any{}}
+	for k, v := range map[string]string{"summary": op.Summary, "description": op.Description, "operationId": op.OperationID} {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+	if op.Deprecated {
+		out["deprecated"] = true
+	}
+
+	var params []map[string]any
+	form := map[string]any{"type": "object", "properties": map[string]any{}}
+	var formRequired []string
+	formType := "application/x-www-form-urlencoded"
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "body":
+			body := map[string]any{"content": content(consumes, p.Schema)}
+			if p.Description != "" {
+				body["description"] = p.Description
+			}
+			if p.Required {
+				body["required"] = true
+			}
+			out["requestBody"] = body
+		case "formData":
+			schema := parameterSchema(p)
+			if p.Type == "file" {
+				formType = "multipart/form-data"
+			}
+			if p.Description != "" {
+				schema["description"] = p.Description
+			}
+			form["properties"].(map[string]any)[p.Name] = schema
+			if p.Required {
+				formRequired = append(formRequired, p.Name)
+			}
+		default:
+			param := map[string]any{"name": p.Name, "in": p.In, "schema": parameterSchema(p)}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			if p.Required {
+				param["required"] = true
+			}
+			if p.Type == "array" {
+				// OpenAPI 3 defaults to form style with explode, Swagger's multi.
+				param["explode"] = p.CollectionFormat == "multi"
+			}
+			params = append(params, param)
+		}
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+	if props := form["properties"].(map[string]any); len(props) > 0 {
+		if len(formRequired) > 0 {
+			sort.Strings(formRequired)
+			form["required"] = formRequired
+		}
+		for _, c := range consumes {
+			if c == "multipart/form-data" {
+				formType = c
+			}
+		}
+		out["requestBody"] = map[string]any{
+			"content": map[string]any{formType: map[string]any{"schema": form}},
+		}
+	}
+
+	responses := out["responses"].(map[string]any)
+	for code, r := range op.Responses {
+		resp := map[string]any{"description": r.Description}
+		if len(r.Schema) > 0 {
+			resp["content"] = content(produces, r.Schema)
+		}
+		if len(r.Headers) > 0 {
+			headers := map[string]any{}
+			for name, h := range r.Headers {
+				headers[name] = map[string]any{"description": h.Description, "schema": map[string]string{"type": h.Type}}
+			}
+			resp["headers"] = headers
+		}
+		responses[code] = resp
+	}
+	return out
+}
+
+// parameterSchema returns the schema of a non-body parameter, whose type
+// information Swagger 2.0 keeps on the parameter itself.
+func parameterSchema(p swagger2Parameter) map[string]any {
+	schema := map[string]any{"type": p.Type}
+	switch {
+	case p.Type == "file":
+		schema["type"], schema["format"] = "string", "binary"
+	case p.Format != "":
+		schema["format"] = p.Format
+	}
+	if len(p.Items) > 0 {
+		schema["items"] = p.Items
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	if len(p.Default) > 0 {
+		schema["default"] = p.Default
+	}
+	if p.Minimum != nil {
+		schema["minimum"] = *p.Minimum
+	}
+	if p.Maximum != nil {
+		schema["maximum"] = *p.Maximum
+	}
+	return schema
+}
+
+func content(types []string, schema json.RawMessage) map[string]any {
+	c := map[string]any{}
+	for _, t := range types {
+		c[t] = map[string]any{"schema": schema}
+	}
+	return c
+}
+
+func firstNonEmpty(lists ...[]string) []string {
+	for _, l := range lists {
+		if len(l) > 0 {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type transferRequest struct {
+	Owner string `json:"owner"`
+}
+
+// @Summary      Transfer a function to a new owner
+// @Description  Hands the function over to another user or team. When an owner directory is configured the new owner must exist in it.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body transferRequest true "New owner"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/transfer [post]
+func (h *Handler) handleTransferFunction(w http.ResponseWriter, r *http.Request) {
+	var req transferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.TransferFunction(r.Context(), chi.URLParam(r, "functionID"), req.Owner)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("transfer function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Report orphaned functions
+// @Description  Lists functions without an owner and functions whose owner no longer exists in the identity provider.
+// @Tags         functions
+// @Produce      json
+// @Success      200  {array}   functions.OrphanedFunction
+// @Failure      501  {object}  apiError "Owner directory not configured"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/orphans [get]
+func (h *Handler) handleOrphanedFunctions(w http.ResponseWriter, r *http.Request) {
+	orphans, err := h.mgr.OrphanedFunctions(r.Context())
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("orphaned functions")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, orphans)
+}
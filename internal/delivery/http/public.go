@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type publicRequest struct {
+	Public bool `json:"public"`
+}
+
+// @Summary      Make a function public
+// @Description  Serves the function at POST /f/{name}, where anyone can call it without a token and without knowing its ID: the request body is the payload and the response body the result. Only a function with a name can be public. false stops serving it there.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body publicRequest true "Whether the function is public"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request, or the function has no name"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/public [put]
+func (h *Handler) handleSetPublic(w http.ResponseWriter, r *http.Request) {
+	var req publicRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetPublic(r.Context(), chi.URLParam(r, "functionID"), req.Public)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set public")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Call a public function
+// @Description  Executes the public function with this name. The request body is passed to the handler as its payload, as is, and the handler's result is returned as the response body, without the execute envelope; results too large to be returned inline are streamed. No token is needed, even with API_TOKEN. Functions that are not public are not found.
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        name path string true "Function name"
+// @Param        payload body object false "Payload for the function"
+// @Param        Idempotency-Key header string false "Executes at most once per key; retries with the same key and payload get the stored result"
+// @Param        X-Timeout-Seconds header number false "Time out the worker call after this many seconds; capped at the function's timeout_seconds"
+// @Param        X-Affinity-Key header string false "Sends executions with the same key to the same worker replica, for functions with an affinity"
+// @Success      200  {object}  object "The function's result"
+// @Header       200  {string}  X-Faas-Invocation-Id "Identifies the execution"
+// @Header       200  {string}  X-Faas-Degraded "Set when the fallback function answered: error, timeout, circuit_open or busy"
+// @Header       200  {string}  X-Faas-Cache "hit when the result was served from the response cache"
+// @Header       200  {string}  Idempotent-Replayed "true when the result was stored by an earlier request with the same Idempotency-Key"
+// @Failure      400  {object}  apiError "Bad Request, or the payload does not match the function's schema (INVALID_PAYLOAD)"
+// @Failure      404  {object}  apiError "No public function has this name"
+// @Failure      409  {object}  apiError "The function is not running"
+// @Failure      413  {object}  apiError "The payload exceeds MAX_PAYLOAD_BYTES or the function's max_payload_bytes"
+// @Failure      422  {object}  apiError "The handler raised an exception (HANDLER_ERROR, its type and message in details.exception)"
+// @Failure      429  {object}  apiError "The function is at its concurrency limit, or the manager is overloaded"
+// @Failure      502  {object}  apiError "The worker could not be reached or failed"
+// @Failure      504  {object}  apiError "The worker did not answer in time"
+// @Router       /f/{name} [post]
+func (h *Handler) handlePublicExecute(w http.ResponseWriter, r *http.Request) {
+	fn, err := h.mgr.PublicFunction(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.servePublic(w, r, fn)
+}
+
+// servePublic executes a function with the request body as its payload and
+// answers with its bare result.
+func (h *Handler) servePublic(w http.ResponseWriter, r *http.Request, fn *functions.Function) {
+	if allowCORS(w, r, fn.CORS) {
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+	}
+	timeout, ok := requestTimeout(r)
+	if !ok {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid '"+timeoutHeader+"', expected a positive number of seconds")
+		return
+	}
+	if limit := h.mgr.MaxPayloadBytes(); limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "request body exceeds "+strconv.FormatInt(maxErr.Limit, 10)+" bytes")
+			return
+		}
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "failed to read body")
+		return
+	}
+
+	result, ok := h.run(w, r, fn.ID, string(payload), timeout)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if ref := result.ResultRef; ref != nil {
+		rc, err := h.mgr.OpenResult(r.Context(), ref.Key)
+		if err != nil {
+			h.log(r).Error().Err(err).Str("key", ref.Key).Msg("open offloaded result")
+			writeError(w, err)
+			return
+		}
+		defer rc.Close()
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, rc)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(result.Result)
+}
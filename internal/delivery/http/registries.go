@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type registryCredentialRequest struct {
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// @Summary      Store registry credentials for a tenant
+// @Description  Registers (or replaces) the tenant's login for a container registry. It is used to pull the tenant's custom worker images. The password is stored encrypted and never returned.
+// @Tags         registries
+// @Accept       json
+// @Produce      json
+// @Param        tenant path string true "Tenant"
+// @Param        body body registryCredentialRequest true "Registry credentials"
+// @Success      200  {object}  functions.RegistryCredential
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /tenants/{tenant}/registries [post]
+func (h *Handler) handleSetRegistryCredential(w http.ResponseWriter, r *http.Request) {
+	tenant := chi.URLParam(r, "tenant")
+	var req registryCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	cred, err := h.mgr.SetRegistryCredential(r.Context(), tenant, req.Server, req.Username, req.Password)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set registry credential")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cred)
+}
+
+// @Summary      List a tenant's registry credentials
+// @Tags         registries
+// @Produce      json
+// @Param        tenant path string true "Tenant"
+// @Success      200  {array}   functions.RegistryCredential
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /tenants/{tenant}/registries [get]
+func (h *Handler) handleListRegistryCredentials(w http.ResponseWriter, r *http.Request) {
+	creds, err := h.mgr.ListRegistryCredentials(r.Context(), chi.URLParam(r, "tenant"))
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list registry credentials")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, creds)
+}
+
+// @Summary      Delete registry credentials
+// @Tags         registries
+// @Param        tenant       path string true "Tenant"
+// @Param        credentialID path string true "Credential ID"
+// @Success      204  "No Content"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /tenants/{tenant}/registries/{credentialID} [delete]
+func (h *Handler) handleDeleteRegistryCredential(w http.ResponseWriter, r *http.Request) {
+	err := h.mgr.DeleteRegistryCredential(r.Context(), chi.URLParam(r, "tenant"), chi.URLParam(r, "credentialID"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
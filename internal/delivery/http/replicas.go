@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type replicasRequest struct {
+	Replicas int `json:"replicas"`
+}
+
+// @Summary      Set a function's worker replicas
+// @Description  Runs this many worker containers for the function (docker mode only). Executions go to the replica with the fewest in flight; a replica that cannot be reached is left out for 30 seconds. A running function's worker is restarted with the new count. 0 or 1 runs a single container.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body replicasRequest true "New replica count"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/replicas [put]
+func (h *Handler) handleSetReplicas(w http.ResponseWriter, r *http.Request) {
+	var req replicasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetReplicas(r.Context(), chi.URLParam(r, "functionID"), req.Replicas)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set replicas")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"service-faas/pkg/rand"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+// maxRequestIDLen bounds request IDs taken from callers.
+const maxRequestIDLen = 128
+
+// requestID honors the caller's X-Request-ID or generates one, and hands it
+// to the manager, to chi's request logger and back to the caller.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(functions.RequestIDHeader)
+		if !validRequestID(id) {
+			id = rand.ID16()
+		}
+		w.Header().Set(functions.RequestIDHeader, id)
+		ctx := functions.WithRequestID(r.Context(), id)
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts IDs of printable ASCII, which are safe to log and to
+// forward as a header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// log returns the handler's logger, tagged with the request's ID.
+func (h *Handler) log(r *http.Request) *zerolog.Logger {
+	lg := h.lg.With().Str("request_id", functions.RequestID(r.Context())).Logger()
+	return &lg
+}
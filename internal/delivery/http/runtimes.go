@@ -0,0 +1,15 @@
+package http
+
+import (
+	"net/http"
+)
+
+// @Summary      List runtimes
+// @Description  Lists the runtimes functions can select with the runtime field, with their worker images, as configured by WORKER_RUNTIMES. The default runtime, if any, is marked.
+// @Tags         functions
+// @Produce      json
+// @Success      200  {array}  functions.Runtime
+// @Router       /runtimes [get]
+func (h *Handler) handleListRuntimes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.mgr.ListRuntimes())
+}
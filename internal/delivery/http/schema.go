@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type payloadSchemaRequest struct {
+	PayloadSchema json.RawMessage `json:"payload_schema" swaggertype:"object"`
+}
+
+// @Summary      Set a function's payload schema
+// @Description  Replaces the JSON Schema that execute payloads must match. Payloads that do not match are rejected with 400 before the worker is called. A null schema removes validation.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body payloadSchemaRequest true "New schema"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/schema [put]
+func (h *Handler) handleSetPayloadSchema(w http.ResponseWriter, r *http.Request) {
+	var req payloadSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetPayloadSchema(r.Context(), chi.URLParam(r, "functionID"), req.PayloadSchema)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set payload schema")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
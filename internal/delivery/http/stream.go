@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"service-faas/internal/core/functions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// streamLine is one line of an execution stream's response.
+type streamLine struct {
+	Index        int    `json:"index"`
+	ID           string `json:"id,omitempty"`
+	InvocationID string `json:"invocation_id,omitempty"`
+	// Status is the status the execute endpoint would have answered with.
+	Status    int                  `json:"status"`
+	Result    json.RawMessage      `json:"result,omitempty" swaggertype:"object"`
+	ResultRef *functions.ResultRef `json:"result_ref,omitempty"`
+	Degraded  string               `json:"degraded,omitempty"`
+	Cached    bool                 `json:"cached,omitempty"`
+	// Error is set when the execution failed, with the code, class and
+	// details of the execute endpoint's error response.
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Class   string `json:"class,omitempty"`
+	Details any    `json:"details,omitempty" swaggertype:"object"`
+}
+
+// @Summary      Execute a function on a stream of payloads
+// @Description  Reads NDJSON lines of {"id": "...", "payload": "..."} (id is optional) and executes the function once per line, several lines at a time. One NDJSON result line is written per input line as soon as it completes, so results may arrive out of order; "index" is the input line number from 0 and "id" is echoed. "status" is what the execute endpoint would have answered. If the stream itself fails, e.g. a line is too long, a last line holds only "error" and "code".
+// @Tags         functions
+// @Accept       application/x-ndjson
+// @Produce      application/x-ndjson
+// @Param        functionID path string true "Function ID"
+// @Param        body body string true "NDJSON lines of {\"id\": \"...\", \"payload\": \"...\"}"
+// @Param        X-Timeout-Seconds header number false "Time out each line's worker call after this many seconds; capped at the function's timeout_seconds"
+// @Param        X-Affinity-Key header string false "Sends every line to the same worker replica, for functions with an affinity; without it each line's payload field decides"
+// @Success      200  {array}   streamLine "One line per input line"
+// @Failure      400  {object}  apiError "The first line is too long, or X-Timeout-Seconds is invalid"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/execute-stream [post]
+func (h *Handler) handleExecuteStream(w http.ResponseWriter, r *http.Request) {
+	functionID := chi.URLParam(r, "functionID")
+	if !canInvoke(w, r, functionID) {
+		return
+	}
+	timeout, ok := requestTimeout(r)
+	if !ok {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid '"+timeoutHeader+"', expected a positive number of seconds")
+		return
+	}
+	ctx := r.Context()
+	if timeout > 0 {
+		ctx = functions.WithTimeout(ctx, timeout)
+	}
+	if key := r.Header.Get(functions.AffinityKeyHeader); key != "" {
+		ctx = functions.WithAffinityKey(ctx, key)
+	}
+	rc := http.NewResponseController(w)
+	// HTTP/1.1 requests are read while results are written.
+	_ = rc.EnableFullDuplex()
+
+	enc := json.NewEncoder(w)
+	started := false
+	err := h.mgr.ExecuteStream(ctx, functionID, r.Body, func(res functions.StreamResult) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		line := streamLine{Index: res.Index, ID: res.ID, InvocationID: res.InvocationID, Status: http.StatusOK}
+		if res.Err != nil {
+			var apiErr apiError
+			line.Status, apiErr = toAPIError(res.Err)
+			line.Error, line.Code, line.Class, line.Details = apiErr.Message, apiErr.Code, apiErr.Class, apiErr.Details
+		} else {
+			line.Result = res.Result.Result
+			line.ResultRef = res.Result.ResultRef
+			line.Degraded = res.Result.Degraded
+			line.Cached = res.Result.Cached
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err == nil {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+	h.log(r).Error().Err(err).Str("function_id", functionID).Msg("execute stream")
+	if !started {
+		writeError(w, err)
+		return
+	}
+	_, apiErr := toAPIError(err)
+	_ = enc.Encode(map[string]string{"error": apiErr.Message, "code": apiErr.Code})
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// timeoutHeader lets a caller shorten or, up to the function's timeout,
+// lengthen the worker calls of one execution.
+const timeoutHeader = "X-Timeout-Seconds"
+
+type timeoutRequest struct {
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// @Summary      Set a function's timeout
+// @Description  Bounds each call to the function's worker; a call that takes longer fails with 504 WORKER_TIMEOUT. It is also the most a caller may ask for with the X-Timeout-Seconds header. 0 removes the limit.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body timeoutRequest true "New timeout"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/timeout [put]
+func (h *Handler) handleSetTimeout(w http.ResponseWriter, r *http.Request) {
+	var req timeoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetTimeout(r.Context(), chi.URLParam(r, "functionID"), req.TimeoutSeconds)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set timeout")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// requestTimeout reads the X-Timeout-Seconds header: a positive number of
+// seconds, fractions allowed. It returns zero when the header is not set.
+func requestTimeout(r *http.Request) (time.Duration, bool) {
+	raw := r.Header.Get(timeoutHeader)
+	if raw == "" {
+		return 0, true
+	}
+	seconds, err := strconv.ParseFloat(raw, 64)
+	if err != nil || seconds <= 0 || seconds > (24*time.Hour).Seconds() {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
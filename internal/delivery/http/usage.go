@@ -0,0 +1,156 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-faas/internal/core/functions"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// @Summary      Function usage
+// @Description  Reports a function's invocations, errors, total duration, estimated GB-seconds and cold starts per hour (UTC), with totals. Defaults to the last 24 hours; ranges are limited to 92 days.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path  string true  "Function ID"
+// @Param        from       query string false "Start of the range, RFC 3339; rounded down to the hour"
+// @Param        to         query string false "End of the range, RFC 3339; defaults to now"
+// @Success      200  {object}  functions.UsageReport
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/usage [get]
+func (h *Handler) handleUsage(w http.ResponseWriter, r *http.Request) {
+	from, to, err := usageRange(r)
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, err.Error())
+		return
+	}
+
+	report, err := h.mgr.Usage(r.Context(), chi.URLParam(r, "functionID"), from, to)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("function usage")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// @Summary      Function metrics
+// @Description  Reports a function's invocation rate, error rate, p50/p95/p99 latency and cold starts over a recent window, counted by this manager replica in whole minutes, and its worker's current CPU and memory use from docker stats or metrics-server where available.
+// @Tags         functions
+// @Produce      json
+// @Param        functionID path  string true  "Function ID"
+// @Param        window     query string false "Window, a duration between 1m and 1h (default 5m)"
+// @Success      200  {object}  functions.FunctionMetrics
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/metrics [get]
+func (h *Handler) handleFunctionMetrics(w http.ResponseWriter, r *http.Request) {
+	var window time.Duration
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'window', expected a duration such as 15m")
+			return
+		}
+		window = d
+	}
+
+	report, err := h.mgr.Metrics(r.Context(), chi.URLParam(r, "functionID"), window)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// @Summary      Export usage for billing
+// @Description  Summarizes invocations, errors, total duration, estimated GB-seconds and cold starts per tenant and per function, soft-deleted functions included. Defaults to the last 24 hours; ranges are limited to 92 days. The CSV has one row per tenant (scope "tenant") followed by one row per function (scope "function").
+// @Tags         usage
+// @Produce      json
+// @Produce      text/csv
+// @Param        from   query string false "Start of the range, RFC 3339; rounded down to the hour"
+// @Param        to     query string false "End of the range, RFC 3339; defaults to now"
+// @Param        format query string false "json (default) or csv"
+// @Success      200  {object}  functions.UsageExport
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /usage/export [get]
+func (h *Handler) handleUsageExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid 'format', expected json or csv")
+		return
+	}
+	from, to, err := usageRange(r)
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, err.Error())
+		return
+	}
+
+	export, err := h.mgr.ExportUsage(r.Context(), from, to)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("usage export")
+		writeError(w, err)
+		return
+	}
+	if format != "csv" {
+		writeJSON(w, http.StatusOK, export)
+		return
+	}
+
+	filename := fmt.Sprintf("usage-%s-%s.csv", export.From.Format("20060102T15"), export.To.Format("20060102T15"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"scope", "tenant", "function_id", "function_name", "functions", "invocations", "errors", "duration_ms", "gb_seconds", "cold_starts", "cold_start_ms"})
+	for _, t := range export.Tenants {
+		_ = cw.Write(append([]string{"tenant", t.Tenant, "", "", strconv.Itoa(t.Functions)}, usageColumns(t.UsageCounters)...))
+	}
+	for _, f := range export.Functions {
+		_ = cw.Write(append([]string{"function", f.Tenant, f.FunctionID, f.FunctionName, ""}, usageColumns(f.UsageCounters)...))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		h.log(r).Error().Err(err).Msg("write usage csv")
+	}
+}
+
+func usageColumns(c functions.UsageCounters) []string {
+	return []string{
+		strconv.FormatInt(c.Invocations, 10),
+		strconv.FormatInt(c.Errors, 10),
+		strconv.FormatInt(c.DurationMS, 10),
+		strconv.FormatFloat(c.GBSeconds, 'f', 3, 64),
+		strconv.FormatInt(c.ColdStarts, 10),
+		strconv.FormatInt(c.ColdStartMS, 10),
+	}
+}
+
+// usageRange reads the from/to query parameters, defaulting to the last 24
+// hours.
+func usageRange(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now()
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'to', expected RFC 3339")
+		}
+		to = t
+	}
+	from := to.Add(-24 * time.Hour)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'from', expected RFC 3339")
+		}
+		from = t
+	}
+	return from, to, nil
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"service-faas/internal/core/functions"
+)
+
+// @Summary      Validate a function upload
+// @Description  Dry run of POST /functions: checks the upload and that handler.py parses and defines function_name, without storing the code or deploying a worker. In docker mode the check runs in the worker image. Elsewhere only a top-level definition of function_name is looked for, reported as checked_by=static: syntax is not checked, so valid=true does not mean handler.py parses. Problems are reported with 200 and valid=false.
+// @Tags         functions
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        python_file    formData  file   false  "The Python file containing the function handler"
+// @Param        bundle         formData  file   false  "A .tar.zst or .tar.gz/.tgz archive with handler.py at its root, instead of python_file (docker mode only)"
+// @Param        code_sha256    formData  string false  "SHA-256 of the uploaded file or bundle"
+// @Param        function_name  formData  string true   "The name of the function to execute (e.g., 'handle')"
+// @Param        tenant         formData  string false  "Tenant whose registry credentials pull worker_image"
+// @Param        worker_image   formData  string false  "Custom worker image to check the code in"
+// @Param        runtime        formData  string false  "Runtime whose worker image to check the code in"
+// @Success      200  {object}  functions.ValidationReport
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      413  {object}  apiError "The upload exceeds MAX_UPLOAD_BYTES"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/validate [post]
+func (h *Handler) handleValidateFunction(w http.ResponseWriter, r *http.Request) {
+	file, bundleFormat, ok := h.readUpload(w, r, uploadRequired)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	functionName := r.FormValue("function_name")
+	if functionName == "" {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "missing 'function_name' in form")
+		return
+	}
+
+	opts := functions.FunctionOptions{
+		Tenant:      r.FormValue("tenant"),
+		WorkerImage: r.FormValue("worker_image"),
+		Runtime:     r.FormValue("runtime"),
+
+		BundleFormat:   bundleFormat,
+		ExpectedSHA256: r.FormValue("code_sha256"),
+	}
+	report, err := h.mgr.ValidateFunction(r.Context(), functionName, file, opts)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("validate function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"service-faas/internal/core/functions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type warmupRequest struct {
+	Warmup *functions.Warmup `json:"warmup"`
+}
+
+// @Summary      Set a function's warm-up
+// @Description  Replaces the function's warm-up settings: the payload and number of priming requests, and whether they are sent whenever its worker is (re)started. A null warmup removes them.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body warmupRequest true "New warm-up settings"
+// @Success      200  {object}  functions.Function
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/warmup [put]
+func (h *Handler) handleSetWarmup(w http.ResponseWriter, r *http.Request) {
+	var req warmupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	fn, err := h.mgr.SetWarmup(r.Context(), chi.URLParam(r, "functionID"), req.Warmup)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("set warm-up")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fn)
+}
+
+// @Summary      Warm a function up
+// @Description  Sends priming requests straight to the function's worker and waits for them. Without a body, the function's warm-up settings are used; a body may override the payload and number of requests. Warm-up requests skip hooks, the response cache and usage metering.
+// @Tags         functions
+// @Accept       json
+// @Produce      json
+// @Param        functionID path string true "Function ID"
+// @Param        body body functions.Warmup false "Payload and number of requests for this warm-up"
+// @Success      200  {object}  functions.WarmupReport
+// @Failure      400  {object}  apiError "Bad Request, or the function is not running"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /functions/{functionID}/warm [post]
+func (h *Handler) handleWarmFunction(w http.ResponseWriter, r *http.Request) {
+	override := &functions.Warmup{}
+	if err := json.NewDecoder(r.Body).Decode(override); errors.Is(err, io.EOF) {
+		override = nil
+	} else if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	report, err := h.mgr.WarmFunction(r.Context(), chi.URLParam(r, "functionID"), override)
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("warm function")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"service-faas/internal/core/functions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type webhookRequest struct {
+	URL string `json:"url" example:"https://dashboard.example.com/hooks/faas"`
+	// Events defaults to every event type but invocation.succeeded.
+	Events []string `json:"events,omitempty" example:"function.deployed,function.failed"`
+	// Tenant limits the webhook to one tenant's functions.
+	Tenant string `json:"tenant,omitempty"`
+	// Secret signs the deliveries; one is generated when empty.
+	Secret string `json:"secret,omitempty"`
+}
+
+// @Summary      Register a webhook
+// @Description  Registers a URL that receives lifecycle events (function.created, function.deployed, function.failed, function.stopped, function.deleted, invocation.succeeded, invocation.failed) as signed JSON POSTs, retried with exponential backoff. The secret is only returned here.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        body body webhookRequest true "Webhook"
+// @Success      201  {object}  functions.Webhook
+// @Failure      400  {object}  apiError "Bad Request"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /webhooks [post]
+func (h *Handler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, codeInvalidArgument, "invalid json body")
+		return
+	}
+
+	hook, err := h.mgr.CreateWebhook(r.Context(), functions.Webhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Tenant: req.Tenant,
+		Secret: req.Secret,
+	})
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("create webhook")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// @Summary      List webhooks
+// @Description  Secrets are not returned.
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}   functions.Webhook
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /webhooks [get]
+func (h *Handler) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.mgr.ListWebhooks(r.Context())
+	if err != nil {
+		h.log(r).Error().Err(err).Msg("list webhooks")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+// @Summary      Delete a webhook
+// @Tags         webhooks
+// @Param        webhookID path string true "Webhook ID"
+// @Success      204  "No Content"
+// @Failure      404  {object}  apiError "Not Found"
+// @Failure      500  {object}  apiError "Internal Server Error"
+// @Router       /webhooks/{webhookID} [delete]
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.mgr.DeleteWebhook(r.Context(), chi.URLParam(r, "webhookID")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,203 @@
+// Package bundle unpacks compressed tar archives of function code, refusing
+// archives that would expand beyond configured limits, and packs directories
+// into them.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported archive formats.
+const (
+	FormatTarZstd = "tar.zst"
+	FormatTarGzip = "tar.gz"
+)
+
+// ErrLimitExceeded is returned when an archive exceeds one of its Limits.
+var ErrLimitExceeded = errors.New("bundle exceeds unpack limits")
+
+// ratioFloor is the unpacked size below which the ratio limit is not
+// enforced; tar padding alone compresses far beyond any sensible ratio.
+const ratioFloor = 16 << 20
+
+// Limits bounds what an archive may expand to. Zero values disable a limit.
+type Limits struct {
+	MaxBytes int64 // Total unpacked size of all files
+	MaxFiles int
+	MaxRatio int64 // Unpacked bytes per compressed byte
+}
+
+// FormatFromName infers the archive format from a file name.
+func FormatFromName(name string) (string, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return FormatTarZstd, true
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTarGzip, true
+	}
+	return "", false
+}
+
+// Extract unpacks the archive and calls fn for every regular file, with its
+// cleaned slash-separated path relative to the archive root. Directories are
+// implied by file paths; links and special files are rejected. The whole
+// archive, including any trailing bytes, is consumed before Extract returns
+// successfully.
+func Extract(r io.Reader, format string, limits Limits, fn func(name string, r io.Reader) error) error {
+	compressed := &countingReader{r: r}
+
+	var raw io.Reader
+	switch format {
+	case FormatTarZstd:
+		zr, err := zstd.NewReader(compressed, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return fmt.Errorf("open zstd stream: %w", err)
+		}
+		defer zr.Close()
+		raw = zr
+	case FormatTarGzip:
+		gr, err := gzip.NewReader(compressed)
+		if err != nil {
+			return fmt.Errorf("open gzip stream: %w", err)
+		}
+		defer gr.Close()
+		raw = gr
+	default:
+		return fmt.Errorf("unsupported bundle format %q", format)
+	}
+
+	guarded := &guardReader{r: raw, compressed: compressed, limits: limits}
+	tr := tar.NewReader(guarded)
+	files := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read bundle: %w", err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return fmt.Errorf("bundle entry %q: only regular files and directories are allowed", hdr.Name)
+		}
+
+		name, err := cleanName(hdr.Name)
+		if err != nil {
+			return err
+		}
+		files++
+		if limits.MaxFiles > 0 && files > limits.MaxFiles {
+			return fmt.Errorf("%w: more than %d files", ErrLimitExceeded, limits.MaxFiles)
+		}
+		if err := fn(name, tr); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(io.Discard, compressed); err != nil {
+		return fmt.Errorf("read bundle: %w", err)
+	}
+	return nil
+}
+
+// Pack writes the regular files under dir to w as a FormatTarGzip archive.
+// Links, special files and .git directories are left out.
+func Pack(w io.Writer, dir string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: filepath.ToSlash(rel), Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, info.Size())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("pack %s: %w", dir, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("pack %s: %w", dir, err)
+	}
+	return gw.Close()
+}
+
+// cleanName validates an entry name and returns it relative to the archive
+// root.
+func cleanName(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("bundle entry %q escapes the bundle root", name)
+	}
+	return cleaned, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// guardReader fails the unpacked stream as soon as it outgrows the limits.
+type guardReader struct {
+	r          io.Reader
+	compressed *countingReader
+	limits     Limits
+	n          int64
+}
+
+func (g *guardReader) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	g.n += int64(n)
+	if g.limits.MaxBytes > 0 && g.n > g.limits.MaxBytes {
+		return n, fmt.Errorf("%w: more than %d bytes unpacked", ErrLimitExceeded, g.limits.MaxBytes)
+	}
+	if g.limits.MaxRatio > 0 && g.n > ratioFloor && g.n > g.limits.MaxRatio*g.compressed.n {
+		return n, fmt.Errorf("%w: compression ratio above %d", ErrLimitExceeded, g.limits.MaxRatio)
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+// Package idtoken issues short-lived EdDSA-signed JWTs and publishes the
+// matching verification key as a JWKS document.
+package idtoken
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"service-faas/pkg/rand"
+	"time"
+)
+
+// ParseSeed decodes a base64 encoded 32-byte Ed25519 seed.
+func ParseSeed(encoded string) ([]byte, error) {
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode seed: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("seed must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return seed, nil
+}
+
+// Issuer signs tokens with a single Ed25519 key.
+type Issuer struct {
+	key      ed25519.PrivateKey
+	keyID    string
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// NewIssuer creates an issuer from a seed. Tokens carry the given iss and aud
+// claims and expire after ttl.
+func NewIssuer(seed []byte, issuer, audience string, ttl time.Duration) *Issuer {
+	key := ed25519.NewKeyFromSeed(seed)
+	sum := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return &Issuer{
+		key:      key,
+		keyID:    base64.RawURLEncoding.EncodeToString(sum[:8]),
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
+	}
+}
+
+// TTL returns the lifetime of issued tokens.
+func (i *Issuer) TTL() time.Duration { return i.ttl }
+
+// Issue returns a signed token for subject. Extra claims are merged in but
+// cannot override the registered ones.
+func (i *Issuer) Issue(subject string, extra map[string]any) (string, time.Time, error) {
+	now := time.Now().UTC()
+	exp := now.Add(i.ttl)
+
+	claims := make(map[string]any, len(extra)+7)
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["iss"] = i.issuer
+	claims["sub"] = subject
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = exp.Unix()
+	claims["jti"] = rand.ID16()
+	if i.audience != "" {
+		claims["aud"] = i.audience
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": i.keyID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sig := ed25519.Sign(i.key, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), exp, nil
+}
+
+// JWKS returns the JSON Web Key Set that verifies the issuer's tokens.
+func (i *Issuer) JWKS() ([]byte, error) {
+	pub := i.key.Public().(ed25519.PublicKey)
+	return json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"use": "sig",
+			"alg": "EdDSA",
+			"kid": i.keyID,
+			"x":   base64.RawURLEncoding.EncodeToString(pub),
+		}},
+	})
+}
@@ -0,0 +1,152 @@
+// Package secretbox encrypts small secrets (passwords, tokens) with
+// AES-256-GCM for storage at rest.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	cr "crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseKey decodes a base64 encoded 32-byte key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext and returns base64(nonce || ciphertext).
+func Seal(key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := cr.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open reverses Seal.
+func Open(key []byte, sealed string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("decode sealed value: %w", err)
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	nonce, ciphertext := raw[:gcm.NonceSize()], raw[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Prefix of values produced by Keyring.Encrypt: "enc:v1:<key id>:<sealed>".
+const keyringPrefix = "enc:v1:"
+
+// Keyring holds every key that may have encrypted a stored value. New values
+// are always encrypted with the primary key, so rotating means adding a new
+// primary in front and re-encrypting; old keys stay around for decryption
+// until no value references them.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+	order   []string
+}
+
+// ParseKeyring parses "id:base64key,id2:base64key". The first entry is the
+// primary key.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key entry must be id:base64key")
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		if k.primary == "" {
+			k.primary = id
+		}
+		k.keys[id] = key
+		k.order = append(k.order, id)
+	}
+	return k, nil
+}
+
+// Empty reports whether the keyring has no keys.
+func (k *Keyring) Empty() bool {
+	return k == nil || k.primary == ""
+}
+
+// Encrypt seals plaintext with the primary key.
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	if k.Empty() {
+		return "", errors.New("no encryption key configured")
+	}
+	sealed, err := Seal(k.keys[k.primary], plaintext)
+	if err != nil {
+		return "", err
+	}
+	return keyringPrefix + k.primary + ":" + sealed, nil
+}
+
+// Decrypt opens a value produced by Encrypt. Bare Seal output without a key
+// id is accepted too and tried against every key.
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	if k.Empty() {
+		return nil, errors.New("no encryption key configured")
+	}
+	if rest, ok := strings.CutPrefix(value, keyringPrefix); ok {
+		id, sealed, ok := strings.Cut(rest, ":")
+		if !ok {
+			return nil, errors.New("malformed encrypted value")
+		}
+		key, found := k.keys[id]
+		if !found {
+			return nil, fmt.Errorf("unknown encryption key %q", id)
+		}
+		return Open(key, sealed)
+	}
+	for _, id := range k.order {
+		if plaintext, err := Open(k.keys[id], value); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("value could not be decrypted with any configured key")
+}
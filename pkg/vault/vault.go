@@ -0,0 +1,394 @@
+// Package vault reads secrets from HashiCorp Vault over its HTTP API and keeps
+// the client token and the leases of dynamic secrets renewed.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth methods.
+const (
+	AuthToken      = "token"
+	AuthAppRole    = "approle"
+	AuthKubernetes = "kubernetes"
+)
+
+// DefaultKubernetesTokenPath is where pods find their service account token.
+const DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Auth says how the client logs in.
+type Auth struct {
+	Method string // AuthToken, AuthAppRole or AuthKubernetes
+	// Mount is the path the auth method is enabled at; it defaults to the
+	// method's name.
+	Mount string
+
+	Token string // AuthToken
+
+	RoleID   string // AuthAppRole
+	SecretID string
+
+	Role      string // AuthKubernetes
+	TokenPath string // service account token, DefaultKubernetesTokenPath if empty
+}
+
+// Client reads secrets with a token it renews, logging in again when the
+// token can no longer be renewed. Safe for concurrent use.
+type Client struct {
+	addr      string
+	namespace string
+	auth      Auth
+	http      *http.Client
+
+	mu      sync.Mutex
+	token   string
+	renewAt time.Time // zero when the token does not expire
+	// Dynamic secrets (those with a lease) are read once and renewed, so
+	// settings that name the same secret get the same credentials.
+	leased   map[string]*leasedSecret // path -> secret
+	onRotate func(paths []string)
+}
+
+type leasedSecret struct {
+	id        string
+	data      map[string]any
+	ttl       time.Duration // lease duration when read
+	renewable bool
+	renewAt   time.Time
+}
+
+// New logs in to the Vault server at addr. namespace is only used with Vault
+// Enterprise.
+func New(ctx context.Context, addr, namespace string, auth Auth) (*Client, error) {
+	if auth.Mount == "" {
+		auth.Mount = auth.Method
+	}
+	if auth.TokenPath == "" {
+		auth.TokenPath = DefaultKubernetesTokenPath
+	}
+	c := &Client{
+		addr:      strings.TrimRight(addr, "/"),
+		namespace: namespace,
+		auth:      auth,
+		http:      &http.Client{Timeout: 10 * time.Second},
+		leased:    map[string]*leasedSecret{},
+	}
+	if err := c.login(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// response is the envelope of Vault API responses.
+type response struct {
+	LeaseID       string         `json:"lease_id"`
+	Renewable     bool           `json:"renewable"`
+	LeaseDuration int            `json:"lease_duration"`
+	Data          map[string]any `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func (c *Client) login(ctx context.Context) error {
+	var body map[string]string
+	switch c.auth.Method {
+	case AuthToken:
+		c.mu.Lock()
+		c.token = c.auth.Token
+		c.mu.Unlock()
+		// The token's own TTL decides whether it needs renewing.
+		resp, err := c.do(ctx, http.MethodGet, "auth/token/lookup-self", nil)
+		if err != nil {
+			return fmt.Errorf("vault token lookup: %w", err)
+		}
+		// Root and other tokens without a TTL never expire; a token that is
+		// not renewable cannot be kept alive and is used until it expires.
+		ttl, _ := resp.Data["ttl"].(float64)
+		if renewable, _ := resp.Data["renewable"].(bool); !renewable {
+			ttl = 0
+		}
+		c.setToken(c.auth.Token, time.Duration(ttl)*time.Second)
+		return nil
+	case AuthAppRole:
+		body = map[string]string{"role_id": c.auth.RoleID, "secret_id": c.auth.SecretID}
+	case AuthKubernetes:
+		jwt, err := os.ReadFile(c.auth.TokenPath)
+		if err != nil {
+			return fmt.Errorf("read service account token: %w", err)
+		}
+		body = map[string]string{"role": c.auth.Role, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return fmt.Errorf("unknown vault auth method %q", c.auth.Method)
+	}
+	resp, err := c.do(ctx, http.MethodPost, "auth/"+c.auth.Mount+"/login", body)
+	if err != nil {
+		return fmt.Errorf("vault %s login: %w", c.auth.Method, err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault %s login: no token in response", c.auth.Method)
+	}
+	c.setToken(resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration)*time.Second)
+	return nil
+}
+
+// setToken stores a token, to be renewed at half its TTL.
+func (c *Client) setToken(token string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.renewAt = time.Time{}
+	if ttl > 0 {
+		c.renewAt = time.Now().Add(ttl / 2)
+	}
+}
+
+// Secret returns the value of key in the secret at path, e.g.
+// "secret/data/faas" for a KV version 2 engine mounted at secret/, or
+// "database/creds/faas" for dynamic database credentials.
+func (c *Client) Secret(ctx context.Context, path, key string) (string, error) {
+	path = strings.Trim(path, "/")
+	c.mu.Lock()
+	cached := c.leased[path]
+	c.mu.Unlock()
+
+	var data map[string]any
+	if cached != nil {
+		data = cached.data
+	} else {
+		secret, err := c.read(ctx, path)
+		if err != nil {
+			return "", err
+		}
+		data = secret.data
+		if secret.id != "" {
+			c.mu.Lock()
+			c.leased[path] = secret
+			c.mu.Unlock()
+		}
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// read reads the secret at path. Its lease, if any, is due for renewal at
+// half its duration.
+func (c *Client) read(ctx context.Context, path string) (*leasedSecret, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("vault read %s: %w", path, err)
+	}
+	data := resp.Data
+	// KV version 2 nests the secret's fields under data.
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = inner
+		}
+	}
+	ttl := time.Duration(resp.LeaseDuration) * time.Second
+	return &leasedSecret{
+		id:        resp.LeaseID,
+		data:      data,
+		ttl:       ttl,
+		renewable: resp.Renewable,
+		renewAt:   time.Now().Add(ttl / 2),
+	}, nil
+}
+
+// OnRotate sets a function KeepAlive calls with the paths of dynamic secrets
+// it read again, after which Secret returns their new values. Whatever was
+// set up with the old values, such as database connections, should be
+// opened again before the old lease expires. Unless it was revoked, that is
+// at least half a lease duration later.
+func (c *Client) OnRotate(fn func(paths []string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onRotate = fn
+}
+
+// KeepAlive renews the token and the leases of dynamic secrets at half their
+// TTL until ctx is done. A token that cannot be renewed is replaced by
+// logging in again. A lease that cannot be renewed for its full duration
+// again, because it is not renewable, nears its max TTL or was revoked, is
+// replaced by reading the secret again and reported to the OnRotate
+// function. onError is called with each failure.
+func (c *Client) KeepAlive(ctx context.Context, onError func(error)) {
+	for {
+		next := c.renewDue(ctx, time.Now(), onError)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// renewDue renews what is due at now and returns when to look again.
+func (c *Client) renewDue(ctx context.Context, now time.Time, onError func(error)) time.Time {
+	c.mu.Lock()
+	tokenDue := !c.renewAt.IsZero() && !now.Before(c.renewAt)
+	var due []string
+	for path, s := range c.leased {
+		if !now.Before(s.renewAt) {
+			due = append(due, path)
+		}
+	}
+	c.mu.Unlock()
+
+	if tokenDue {
+		resp, err := c.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]string{})
+		switch {
+		case err == nil && resp.Auth != nil && resp.Auth.Renewable:
+			c.setToken(c.tokenValue(), time.Duration(resp.Auth.LeaseDuration)*time.Second)
+		case c.auth.Method == AuthToken && err != nil:
+			onError(fmt.Errorf("vault token renewal: %w", err))
+			c.retryTokenIn(time.Minute)
+		case c.auth.Method == AuthToken:
+			// No longer renewable: it is used until it expires.
+			c.setToken(c.tokenValue(), 0)
+		default:
+			// At its max TTL, or revoked: log in again.
+			if err := c.login(ctx); err != nil {
+				onError(err)
+				c.retryTokenIn(time.Minute)
+			}
+		}
+	}
+	var rotated []string
+	for _, path := range due {
+		c.mu.Lock()
+		s := c.leased[path]
+		c.mu.Unlock()
+		if s.renewable {
+			// Vault caps the renewal at the lease's max TTL, so a shorter
+			// duration than asked for means the lease ends soon.
+			resp, err := c.do(ctx, http.MethodPut, "sys/leases/renew", map[string]any{
+				"lease_id":  s.id,
+				"increment": int(s.ttl.Seconds()),
+			})
+			if err != nil {
+				onError(fmt.Errorf("vault lease renewal for %s: %w", path, err))
+			}
+			if err == nil && time.Duration(resp.LeaseDuration)*time.Second >= s.ttl {
+				c.mu.Lock()
+				s.renewAt = time.Now().Add(s.ttl / 2)
+				c.mu.Unlock()
+				continue
+			}
+		}
+		secret, err := c.read(ctx, path)
+		c.mu.Lock()
+		switch {
+		case err != nil:
+			s.renewAt = time.Now().Add(time.Minute)
+		case secret.id == "":
+			delete(c.leased, path)
+		default:
+			c.leased[path] = secret
+		}
+		c.mu.Unlock()
+		if err != nil {
+			onError(err)
+			continue
+		}
+		rotated = append(rotated, path)
+	}
+	if len(rotated) > 0 {
+		c.mu.Lock()
+		onRotate := c.onRotate
+		c.mu.Unlock()
+		if onRotate != nil {
+			onRotate(rotated)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := now.Add(5 * time.Minute)
+	if !c.renewAt.IsZero() && c.renewAt.Before(next) {
+		next = c.renewAt
+	}
+	for _, s := range c.leased {
+		if s.renewAt.Before(next) {
+			next = s.renewAt
+		}
+	}
+	return next
+}
+
+func (c *Client) retryTokenIn(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.renewAt = time.Now().Add(d)
+}
+
+func (c *Client) tokenValue() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// do calls the Vault API at /v1/<path>.
+func (c *Client) do(ctx context.Context, method, path string, body any) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+"/v1/"+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if token := c.tokenValue(); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out response
+	if resp.StatusCode == http.StatusNoContent {
+		return &out, nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil && resp.StatusCode < 300 {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(out.Errors) > 0 {
+			return nil, fmt.Errorf("%s: %s", resp.Status, strings.Join(out.Errors, "; "))
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return &out, nil
+}